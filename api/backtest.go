@@ -0,0 +1,280 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/backtest"
+	"nofx/decision"
+	"nofx/mcp"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 回测任务状态
+const (
+	backtestStatusRunning   = "running"
+	backtestStatusCompleted = "completed"
+	backtestStatusFailed    = "failed"
+)
+
+// maxBacktestJobs 每个服务进程最多保留的回测任务数量（超出后淘汰最早完成的任务）
+const maxBacktestJobs = 50
+
+// 同时运行的回测任务上限（回测会持续调用AI并占用CPU，避免单个用户或所有用户同时发起过多任务）
+const (
+	maxRunningBacktestsPerUser = 2
+	maxRunningBacktests        = 8
+)
+
+// BacktestRequest 回测请求
+type BacktestRequest struct {
+	Symbols              []string            `json:"symbols" binding:"required"`
+	StartTime            time.Time           `json:"start_time"`
+	EndTime              time.Time           `json:"end_time"`
+	ScanIntervalMinutes  int                 `json:"scan_interval_minutes"`
+	MaxCycles            int                 `json:"max_cycles"`
+	InitialBalance       float64             `json:"initial_balance"`
+	BTCETHLeverage       int                 `json:"btc_eth_leverage"`
+	AltcoinLeverage      int                 `json:"altcoin_leverage"`
+	TakerFeeRate         float64             `json:"taker_fee_rate"`
+	CustomPrompt         string              `json:"custom_prompt"`
+	OverrideBasePrompt   bool                `json:"override_base_prompt"`
+	SystemPromptTemplate string              `json:"system_prompt_template"`
+	RiskLimits           decision.RiskLimits `json:"risk_limits"`
+	AIModelID            string              `json:"ai_model_id"`    // 为空或 "mock" 时使用离线模拟AI
+	MockResponses        []string            `json:"mock_responses"` // 模拟AI响应（按顺序循环返回）
+}
+
+// backtestJob 异步回测任务
+type backtestJob struct {
+	ID         string           `json:"id"`
+	UserID     string           `json:"-"`
+	Status     string           `json:"status"`
+	Error      string           `json:"error,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Config     backtest.Config  `json:"config"`
+	Result     *backtest.Result `json:"result,omitempty"`
+}
+
+// backtestJobs 内存中的回测任务表
+type backtestJobs struct {
+	mu   sync.RWMutex
+	jobs map[string]*backtestJob
+}
+
+func newBacktestJobs() *backtestJobs {
+	return &backtestJobs{jobs: make(map[string]*backtestJob)}
+}
+
+// add 添加任务，超出上限时淘汰最早完成的任务；用户或全局运行中的任务达到上限时拒绝
+func (b *backtestJobs) add(job *backtestJob) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	running, userRunning := 0, 0
+	for _, j := range b.jobs {
+		if j.Status != backtestStatusRunning {
+			continue
+		}
+		running++
+		if j.UserID == job.UserID {
+			userRunning++
+		}
+	}
+	if userRunning >= maxRunningBacktestsPerUser {
+		return fmt.Errorf("每个用户最多同时运行%d个回测任务，请等待已有任务完成", maxRunningBacktestsPerUser)
+	}
+	if running >= maxRunningBacktests {
+		return fmt.Errorf("当前运行中的回测任务已达上限（%d个），请稍后再试", maxRunningBacktests)
+	}
+
+	if len(b.jobs) >= maxBacktestJobs {
+		var oldest *backtestJob
+		for _, j := range b.jobs {
+			if j.Status == backtestStatusRunning {
+				continue
+			}
+			if oldest == nil || j.CreatedAt.Before(oldest.CreatedAt) {
+				oldest = j
+			}
+		}
+		if oldest != nil {
+			delete(b.jobs, oldest.ID)
+		}
+	}
+	b.jobs[job.ID] = job
+	return nil
+}
+
+// finish 记录任务结果
+func (b *backtestJobs) finish(id string, result *backtest.Result, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	job, ok := b.jobs[id]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = backtestStatusFailed
+		job.Error = err.Error()
+		return
+	}
+	job.Status = backtestStatusCompleted
+	job.Result = result
+}
+
+// get 获取用户的任务快照
+func (b *backtestJobs) get(userID, id string) (backtestJob, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	job, ok := b.jobs[id]
+	if !ok || job.UserID != userID {
+		return backtestJob{}, false
+	}
+	return *job, true
+}
+
+// list 获取用户的任务列表（不含结果，按创建时间倒序）
+func (b *backtestJobs) list(userID string) []backtestJob {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	jobs := make([]backtestJob, 0)
+	for _, job := range b.jobs {
+		if job.UserID != userID {
+			continue
+		}
+		snapshot := *job
+		snapshot.Result = nil
+		jobs = append(jobs, snapshot)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// handleCreateBacktest 创建回测任务（异步执行，通过 GET /backtests/:id 查询结果）
+func (s *Server) handleCreateBacktest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req BacktestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, symbol := range req.Symbols {
+		if err := backtest.ValidateSymbol(symbol); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	client, err := s.backtestAIClient(userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 历史K线目录由服务端配置，不接受客户端指定路径
+	dataDir := os.Getenv("NOFX_BACKTEST_DATA_DIR")
+	if dataDir == "" {
+		dataDir = backtest.DefaultDataDir
+	}
+
+	cfg := backtest.Config{
+		Symbols:            req.Symbols,
+		DataDir:            dataDir,
+		StartTime:          req.StartTime,
+		EndTime:            req.EndTime,
+		ScanInterval:       time.Duration(req.ScanIntervalMinutes) * time.Minute,
+		MaxCycles:          req.MaxCycles,
+		InitialBalance:     req.InitialBalance,
+		BTCETHLeverage:     req.BTCETHLeverage,
+		AltcoinLeverage:    req.AltcoinLeverage,
+		TakerFeeRate:       req.TakerFeeRate,
+		CustomPrompt:       req.CustomPrompt,
+		OverrideBasePrompt: req.OverrideBasePrompt,
+		TemplateName:       req.SystemPromptTemplate,
+		RiskLimits:         req.RiskLimits,
+	}
+
+	// 加载K线失败直接返回，避免创建注定失败的任务
+	runner, err := backtest.NewRunner(cfg, client)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("初始化回测失败: %v", err)})
+		return
+	}
+
+	job := &backtestJob{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Status:    backtestStatusRunning,
+		CreatedAt: time.Now(),
+		Config:    cfg,
+	}
+	if err := s.backtests.add(job); err != nil {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+
+	go func(id string) {
+		log.Printf("🧪 [回测] 用户 %s 启动回测任务 %s", userID, id)
+		result, err := runner.Run()
+		if err != nil {
+			log.Printf("❌ [回测] 任务 %s 失败: %v", id, err)
+		}
+		s.backtests.finish(id, result, err)
+	}(job.ID)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":     job.ID,
+		"status": job.Status,
+	})
+}
+
+// handleGetBacktest 获取回测任务状态和结果
+func (s *Server) handleGetBacktest(c *gin.Context) {
+	job, ok := s.backtests.get(c.GetString("user_id"), c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "回测任务不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// handleListBacktests 获取当前用户的回测任务列表
+func (s *Server) handleListBacktests(c *gin.Context) {
+	c.JSON(http.StatusOK, s.backtests.list(c.GetString("user_id")))
+}
+
+// backtestAIClient 根据请求选择回测AI客户端（使用用户已配置的AI模型，或离线模拟AI）
+func (s *Server) backtestAIClient(userID string, req BacktestRequest) (mcp.AIClient, error) {
+	if req.AIModelID == "" || req.AIModelID == "mock" {
+		return backtest.NewAIClient("mock", "", "", "", req.MockResponses...)
+	}
+
+	models, err := s.database.GetAIModels(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取AI模型配置失败: %w", err)
+	}
+	for _, model := range models {
+		if model.ID != req.AIModelID {
+			continue
+		}
+		if !model.Enabled {
+			return nil, fmt.Errorf("AI模型 %s 未启用", model.Name)
+		}
+		return backtest.NewAIClient(model.Provider, model.APIKey, model.CustomAPIURL, model.CustomModelName)
+	}
+	return nil, fmt.Errorf("AI模型 %s 不存在", req.AIModelID)
+}
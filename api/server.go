@@ -9,7 +9,10 @@ import (
 	"nofx/auth"
 	"nofx/config"
 	"nofx/decision"
+	"nofx/logger"
 	"nofx/manager"
+	"nofx/market"
+	"nofx/pool"
 	"nofx/trader"
 	"strconv"
 	"strings"
@@ -25,6 +28,8 @@ type Server struct {
 	traderManager *manager.TraderManager
 	database      *config.Database
 	port          int
+
+	backtests *backtestJobs // 回测任务（内存保存）
 }
 
 // NewServer 创建API服务器
@@ -42,6 +47,7 @@ func NewServer(traderManager *manager.TraderManager, database *config.Database,
 		traderManager: traderManager,
 		database:      database,
 		port:          port,
+		backtests:     newBacktestJobs(),
 	}
 
 	// 设置路由
@@ -88,6 +94,9 @@ func (s *Server) setupRoutes() {
 		api.GET("/prompt-templates", s.handleGetPromptTemplates)
 		api.GET("/prompt-templates/:name", s.handleGetPromptTemplate)
 
+		// 可用技术指标列表（无需认证，用于交易员/模板配置 indicators）
+		api.GET("/indicators", s.handleGetIndicators)
+
 		// 公开的竞赛数据（无需认证）
 		api.GET("/traders", s.handlePublicTraderList)
 		api.GET("/competition", s.handlePublicCompetition)
@@ -148,6 +157,13 @@ func (s *Server) setupRoutes() {
 			protected.GET("/decisions/latest", s.handleLatestDecisions)
 			protected.GET("/statistics", s.handleStatistics)
 			protected.GET("/performance", s.handlePerformance)
+			protected.GET("/trades", s.handleTrades)
+			protected.GET("/usage", s.handleUsage)
+
+			// 回测（异步任务）
+			protected.POST("/backtests", s.handleCreateBacktest)
+			protected.GET("/backtests", s.handleListBacktests)
+			protected.GET("/backtests/:id", s.handleGetBacktest)
 		}
 	}
 }
@@ -381,6 +397,34 @@ type CreateTraderRequest struct {
 	IsCrossMargin        *bool   `json:"is_cross_margin"`        // 指针类型，nil表示使用默认值true
 	UseCoinPool          bool    `json:"use_coin_pool"`
 	UseOITop             bool    `json:"use_oi_top"`
+
+	// 开仓前风控（0表示不启用）
+	RiskMaxPositionPct    float64 `json:"risk_max_position_pct"`
+	RiskMaxMarginUsagePct float64 `json:"risk_max_margin_usage_pct"`
+	RiskMaxPositions      int     `json:"risk_max_positions"`
+	RiskMinRiskReward     float64 `json:"risk_min_risk_reward"`
+	RiskUSDTolerancePct   float64 `json:"risk_usd_tolerance_pct"`
+	RiskClampOversize     bool    `json:"risk_clamp_oversize"`
+	RiskMaxSlippagePct    float64 `json:"risk_max_slippage_pct"`
+
+	// 保本止损（浮盈达到初始风险R倍或入场价百分比后移动止损到保本价，0表示不启用）
+	BreakEvenTriggerR   float64 `json:"break_even_trigger_r"`
+	BreakEvenTriggerPct float64 `json:"break_even_trigger_pct"`
+
+	// AI备用链：备用AI模型ID列表（逗号分隔，按顺序切换）
+	FallbackAIModelIDs string `json:"fallback_ai_model_ids"`
+
+	// 可插拔信号源（为空表示使用默认币种/AI500+OI Top）
+	SignalProviders []pool.ProviderConfig `json:"signal_providers"`
+
+	// K线周期（逗号分隔，如 "15m,1h,1d"，为空表示使用默认 3m,4h）
+	Timeframes string `json:"timeframes"`
+
+	// 注册指标（如 "adx(14), supertrend(10,3)@1h"，为空表示使用提示词模板声明的指标）
+	Indicators string `json:"indicators"`
+
+	// 模拟盘行情来源交易所（binance/okx/bybit/bitget/gate，为空表示binance）
+	PaperPriceExchange string `json:"paper_price_exchange"`
 }
 
 type ModelConfig struct {
@@ -452,6 +496,33 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	}
 	log.Printf("✓ [创建交易员] 杠杆校验通过: BTC/ETH=%d, Altcoin=%d", req.BTCETHLeverage, req.AltcoinLeverage)
 
+	signalProviders, err := encodeSignalProviders(req.SignalProviders)
+	if err != nil {
+		log.Printf("❌ [创建交易员] 信号源配置校验失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	timeframes, err := normalizeTimeframes(req.Timeframes)
+	if err != nil {
+		log.Printf("❌ [创建交易员] K线周期校验失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	indicators, err := normalizeIndicators(req.Indicators)
+	if err != nil {
+		log.Printf("❌ [创建交易员] 指标配置校验失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paperPriceExchange, err := trader.NormalizePaperPriceExchange(req.PaperPriceExchange)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 校验交易币种格式
 	if req.TradingSymbols != "" {
 		symbols := strings.Split(req.TradingSymbols, ",")
@@ -578,6 +649,15 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 			)
 		case "okx":
 			tempTrader = trader.NewOKXTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, exchangeCfg.OKXPassphrase, exchangeCfg.Testnet)
+		case "bybit":
+			tempTrader = trader.NewBybitTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, exchangeCfg.Testnet)
+		case "bitget":
+			tempTrader = trader.NewBitgetTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, exchangeCfg.OKXPassphrase, exchangeCfg.Testnet)
+		case "gate":
+			tempTrader = trader.NewGateTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, exchangeCfg.Testnet)
+		case "paper":
+			// 模拟盘没有真实账户，直接使用用户输入的初始资金作为模拟账户本金
+			log.Printf("🧪 模拟盘交易员，使用用户输入的初始资金: %.2f USDT", req.InitialBalance)
 		default:
 			log.Printf("⚠️ 不支持的交易所类型: %s，使用用户输入的初始资金", req.ExchangeID)
 		}
@@ -591,12 +671,12 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 				log.Printf("⚠️ 查询交易所余额失败，使用用户输入的初始资金: %v", balanceErr)
 			} else {
 				// 提取可用余额
-				if availableBalance, ok := balanceInfo["available_balance"].(float64); ok && availableBalance > 0 {
-					actualBalance = availableBalance
+				if balanceInfo.AvailableBalance > 0 {
+					actualBalance = balanceInfo.AvailableBalance
 					log.Printf("✓ 查询到交易所实际余额: %.2f USDT (用户输入: %.2f USDT)", actualBalance, req.InitialBalance)
-				} else if totalBalance, ok := balanceInfo["balance"].(float64); ok && totalBalance > 0 {
-					// 有些交易所可能只返回 balance 字段
-					actualBalance = totalBalance
+				} else if balanceInfo.TotalWalletBalance > 0 {
+					// 可用余额为0时（如全部资金已占用保证金）回退到钱包余额
+					actualBalance = balanceInfo.TotalWalletBalance
 					log.Printf("✓ 查询到交易所实际余额: %.2f USDT (用户输入: %.2f USDT)", actualBalance, req.InitialBalance)
 				} else {
 					log.Printf("⚠️ 无法从余额信息中提取可用余额，使用用户输入的初始资金")
@@ -643,6 +723,23 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		IsCrossMargin:        isCrossMargin,
 		ScanIntervalMinutes:  scanIntervalMinutes,
 		IsRunning:            false,
+
+		RiskMaxPositionPct:    req.RiskMaxPositionPct,
+		RiskMaxMarginUsagePct: req.RiskMaxMarginUsagePct,
+		RiskMaxPositions:      req.RiskMaxPositions,
+		RiskMinRiskReward:     req.RiskMinRiskReward,
+		RiskUSDTolerancePct:   req.RiskUSDTolerancePct,
+		RiskClampOversize:     req.RiskClampOversize,
+		RiskMaxSlippagePct:    req.RiskMaxSlippagePct,
+
+		BreakEvenTriggerR:   req.BreakEvenTriggerR,
+		BreakEvenTriggerPct: req.BreakEvenTriggerPct,
+
+		FallbackAIModelIDs: normalizeModelIDList(req.FallbackAIModelIDs),
+		SignalProviders:    signalProviders,
+		Timeframes:         timeframes,
+		Indicators:         indicators,
+		PaperPriceExchange: paperPriceExchange,
 	}
 
 	log.Printf("📝 [创建交易员] 准备保存到数据库: ID=%s, UserID=%s, Name=%s, AIModelID=%s, ExchangeID=%s, InitialBalance=%.2f", 
@@ -699,6 +796,90 @@ type UpdateTraderRequest struct {
 	CustomPrompt        string  `json:"custom_prompt"`
 	OverrideBasePrompt  bool    `json:"override_base_prompt"`
 	IsCrossMargin       *bool   `json:"is_cross_margin"`
+
+	// 开仓前风控（指针类型，nil表示保持原值）
+	RiskMaxPositionPct    *float64 `json:"risk_max_position_pct"`
+	RiskMaxMarginUsagePct *float64 `json:"risk_max_margin_usage_pct"`
+	RiskMaxPositions      *int     `json:"risk_max_positions"`
+	RiskMinRiskReward     *float64 `json:"risk_min_risk_reward"`
+	RiskUSDTolerancePct   *float64 `json:"risk_usd_tolerance_pct"`
+	RiskClampOversize     *bool    `json:"risk_clamp_oversize"`
+	RiskMaxSlippagePct    *float64 `json:"risk_max_slippage_pct"`
+
+	// 保本止损（nil表示保持原值）
+	BreakEvenTriggerR   *float64 `json:"break_even_trigger_r"`
+	BreakEvenTriggerPct *float64 `json:"break_even_trigger_pct"`
+
+	// AI备用链（nil表示保持原值，空字符串表示清空）
+	FallbackAIModelIDs *string `json:"fallback_ai_model_ids"`
+
+	// 可插拔信号源（nil表示保持原值，空数组表示清空）
+	SignalProviders *[]pool.ProviderConfig `json:"signal_providers"`
+
+	// K线周期（nil表示保持原值，空字符串表示恢复默认）
+	Timeframes *string `json:"timeframes"`
+
+	// 注册指标（nil表示保持原值，空字符串表示使用模板声明）
+	Indicators *string `json:"indicators"`
+
+	// 模拟盘行情来源（nil表示保持原值，空字符串表示恢复默认binance）
+	PaperPriceExchange *string `json:"paper_price_exchange"`
+}
+
+// normalizeModelIDList 规范化逗号分隔的AI模型ID列表（去空格、去空项、去重，保持顺序）
+func normalizeModelIDList(ids string) string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return strings.Join(result, ",")
+}
+
+// encodeSignalProviders 校验信号源配置并序列化为JSON（空列表返回空字符串）
+func encodeSignalProviders(providers []pool.ProviderConfig) (string, error) {
+	if len(providers) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(providers)
+	if err != nil {
+		return "", fmt.Errorf("序列化信号源配置失败: %w", err)
+	}
+	normalized, err := pool.ParseProviderConfigs(string(data))
+	if err != nil {
+		return "", err
+	}
+	data, err = json.Marshal(normalized)
+	if err != nil {
+		return "", fmt.Errorf("序列化信号源配置失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// normalizeTimeframes 校验并规范化逗号分隔的K线周期（为空返回空字符串，表示使用默认周期）
+func normalizeTimeframes(timeframes string) (string, error) {
+	if strings.TrimSpace(timeframes) == "" {
+		return "", nil
+	}
+	parsed, err := market.ParseTimeframes(timeframes)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(parsed, ","), nil
+}
+
+// normalizeIndicators 校验并规范化指标列表（为空返回空字符串，表示使用模板声明的指标）
+func normalizeIndicators(indicators string) (string, error) {
+	requests, err := market.ParseIndicatorSpecs(indicators)
+	if err != nil {
+		return "", err
+	}
+	return market.FormatIndicatorSpecs(requests), nil
 }
 
 // handleUpdateTrader 更新交易员配置
@@ -757,6 +938,16 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		scanIntervalMinutes = 3
 	}
 
+	// 模拟盘行情来源：只更新请求中提供的字段
+	paperPriceExchange := existingTrader.PaperPriceExchange
+	if req.PaperPriceExchange != nil {
+		paperPriceExchange, err = trader.NormalizePaperPriceExchange(*req.PaperPriceExchange)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// 更新交易员配置（使用traderUserID，因为交易员是用这个user_id创建的）
 	trader := &config.TraderRecord{
 		ID:                   traderID,
@@ -776,6 +967,79 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		IsRunning:            existingTrader.IsRunning, // 保持原值
 		UseCoinPool:          existingTrader.UseCoinPool, // 保持原值
 		UseOITop:             existingTrader.UseOITop, // 保持原值
+
+		RiskMaxPositionPct:    existingTrader.RiskMaxPositionPct,
+		RiskMaxMarginUsagePct: existingTrader.RiskMaxMarginUsagePct,
+		RiskMaxPositions:      existingTrader.RiskMaxPositions,
+		RiskMinRiskReward:     existingTrader.RiskMinRiskReward,
+		RiskUSDTolerancePct:   existingTrader.RiskUSDTolerancePct,
+		RiskClampOversize:     existingTrader.RiskClampOversize,
+		RiskMaxSlippagePct:    existingTrader.RiskMaxSlippagePct,
+
+		BreakEvenTriggerR:   existingTrader.BreakEvenTriggerR,
+		BreakEvenTriggerPct: existingTrader.BreakEvenTriggerPct,
+
+		FallbackAIModelIDs: existingTrader.FallbackAIModelIDs,
+		SignalProviders:    existingTrader.SignalProviders,
+		Timeframes:         existingTrader.Timeframes,
+		Indicators:         existingTrader.Indicators,
+		PaperPriceExchange: paperPriceExchange,
+	}
+
+	// 风控参数：只更新请求中提供的字段
+	if req.RiskMaxPositionPct != nil {
+		trader.RiskMaxPositionPct = *req.RiskMaxPositionPct
+	}
+	if req.RiskMaxMarginUsagePct != nil {
+		trader.RiskMaxMarginUsagePct = *req.RiskMaxMarginUsagePct
+	}
+	if req.RiskMaxPositions != nil {
+		trader.RiskMaxPositions = *req.RiskMaxPositions
+	}
+	if req.RiskMinRiskReward != nil {
+		trader.RiskMinRiskReward = *req.RiskMinRiskReward
+	}
+	if req.RiskUSDTolerancePct != nil {
+		trader.RiskUSDTolerancePct = *req.RiskUSDTolerancePct
+	}
+	if req.RiskClampOversize != nil {
+		trader.RiskClampOversize = *req.RiskClampOversize
+	}
+	if req.RiskMaxSlippagePct != nil {
+		trader.RiskMaxSlippagePct = *req.RiskMaxSlippagePct
+	}
+	if req.BreakEvenTriggerR != nil {
+		trader.BreakEvenTriggerR = *req.BreakEvenTriggerR
+	}
+	if req.BreakEvenTriggerPct != nil {
+		trader.BreakEvenTriggerPct = *req.BreakEvenTriggerPct
+	}
+	if req.FallbackAIModelIDs != nil {
+		trader.FallbackAIModelIDs = normalizeModelIDList(*req.FallbackAIModelIDs)
+	}
+	if req.SignalProviders != nil {
+		signalProviders, err := encodeSignalProviders(*req.SignalProviders)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		trader.SignalProviders = signalProviders
+	}
+	if req.Timeframes != nil {
+		timeframes, err := normalizeTimeframes(*req.Timeframes)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		trader.Timeframes = timeframes
+	}
+	if req.Indicators != nil {
+		indicators, err := normalizeIndicators(*req.Indicators)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		trader.Indicators = indicators
 	}
 
 	// 更新数据库
@@ -814,14 +1078,8 @@ func (s *Server) handleDeleteTrader(c *gin.Context) {
 		return
 	}
 
-	// 如果交易员正在运行，先停止它
-	if trader, err := s.traderManager.GetTrader(traderID); err == nil {
-		status := trader.GetStatus()
-		if isRunning, ok := status["is_running"].(bool); ok && isRunning {
-			trader.Stop()
-			log.Printf("⏹  已停止运行中的交易员: %s", traderID)
-		}
-	}
+	// 停止运行中的交易员并从内存移除（释放决策日志数据库等资源）
+	s.traderManager.RemoveTrader(traderID)
 
 	log.Printf("✓ 交易员已删除: %s", traderID)
 	c.JSON(http.StatusOK, gin.H{"message": "交易员已删除"})
@@ -984,6 +1242,16 @@ func (s *Server) handleSyncBalance(c *gin.Context) {
 		)
 	case "okx":
 		tempTrader = trader.NewOKXTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, exchangeCfg.OKXPassphrase, exchangeCfg.Testnet)
+	case "bybit":
+		tempTrader = trader.NewBybitTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, exchangeCfg.Testnet)
+	case "bitget":
+		tempTrader = trader.NewBitgetTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, exchangeCfg.OKXPassphrase, exchangeCfg.Testnet)
+	case "gate":
+		tempTrader = trader.NewGateTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, exchangeCfg.Testnet)
+	case "paper":
+		// 模拟盘余额由本地模拟账户维护（随成交自动更新），不需要从交易所同步
+		c.JSON(http.StatusBadRequest, gin.H{"error": "模拟盘余额由本地模拟账户维护，无需同步"})
+		return
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的交易所类型"})
 		return
@@ -1005,12 +1273,10 @@ func (s *Server) handleSyncBalance(c *gin.Context) {
 
 	// 提取可用余额
 	var actualBalance float64
-	if availableBalance, ok := balanceInfo["available_balance"].(float64); ok && availableBalance > 0 {
-		actualBalance = availableBalance
-	} else if availableBalance, ok := balanceInfo["availableBalance"].(float64); ok && availableBalance > 0 {
-		actualBalance = availableBalance
-	} else if totalBalance, ok := balanceInfo["balance"].(float64); ok && totalBalance > 0 {
-		actualBalance = totalBalance
+	if balanceInfo.AvailableBalance > 0 {
+		actualBalance = balanceInfo.AvailableBalance
+	} else if balanceInfo.TotalWalletBalance > 0 {
+		actualBalance = balanceInfo.TotalWalletBalance
 	} else {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法获取可用余额"})
 		return
@@ -1248,6 +1514,20 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 	// 返回完整的模型ID，不做转换，保持与前端模型列表一致
 	aiModelID := traderConfig.AIModelID
 
+	signalProviders, err := pool.ParseProviderConfigs(traderConfig.SignalProviders)
+	if err != nil {
+		log.Printf("⚠️ 交易员 %s 的信号源配置无效: %v", traderConfig.ID, err)
+	}
+	if signalProviders == nil {
+		signalProviders = []pool.ProviderConfig{}
+	}
+
+	timeframes, err := market.ParseTimeframes(traderConfig.Timeframes)
+	if err != nil {
+		log.Printf("⚠️ 交易员 %s 的K线周期配置无效: %v", traderConfig.ID, err)
+		timeframes = market.DefaultTimeframes
+	}
+
 	result := map[string]interface{}{
 		"trader_id":             traderConfig.ID,
 		"trader_name":           traderConfig.Name,
@@ -1264,6 +1544,23 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 		"use_coin_pool":         traderConfig.UseCoinPool,
 		"use_oi_top":            traderConfig.UseOITop,
 		"is_running":            isRunning,
+
+		"risk_max_position_pct":     traderConfig.RiskMaxPositionPct,
+		"risk_max_margin_usage_pct": traderConfig.RiskMaxMarginUsagePct,
+		"risk_max_positions":        traderConfig.RiskMaxPositions,
+		"risk_min_risk_reward":      traderConfig.RiskMinRiskReward,
+		"risk_usd_tolerance_pct":    traderConfig.RiskUSDTolerancePct,
+		"risk_clamp_oversize":       traderConfig.RiskClampOversize,
+		"risk_max_slippage_pct":     traderConfig.RiskMaxSlippagePct,
+
+		"break_even_trigger_r":   traderConfig.BreakEvenTriggerR,
+		"break_even_trigger_pct": traderConfig.BreakEvenTriggerPct,
+
+		"fallback_ai_model_ids": traderConfig.FallbackAIModelIDs,
+		"signal_providers":      signalProviders,
+		"timeframes":            timeframes,
+		"indicators":            traderConfig.Indicators,
+		"paper_price_exchange":  traderConfig.PaperPriceExchange,
 	}
 
 	c.JSON(http.StatusOK, result)
@@ -1313,10 +1610,10 @@ func (s *Server) handleAccount(c *gin.Context) {
 
 	log.Printf("✓ 返回账户信息 [%s]: 净值=%.2f, 可用=%.2f, 盈亏=%.2f (%.2f%%)",
 		trader.GetName(),
-		account["total_equity"],
-		account["available_balance"],
-		account["total_pnl"],
-		account["total_pnl_pct"])
+		account.TotalEquity,
+		account.AvailableBalance,
+		account.TotalPnL,
+		account.TotalPnLPct)
 	c.JSON(http.StatusOK, account)
 }
 
@@ -1345,7 +1642,8 @@ func (s *Server) handlePositions(c *gin.Context) {
 	c.JSON(http.StatusOK, positions)
 }
 
-// handleDecisions 决策日志列表
+// handleDecisions 决策日志分页查询
+// ?trader_id=xxx&start=2025-01-01&end=2025-01-02&symbol=BTCUSDT&action=open_long&success=true&offset=0&limit=50&order=asc
 func (s *Server) handleDecisions(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
@@ -1359,8 +1657,13 @@ func (s *Server) handleDecisions(c *gin.Context) {
 		return
 	}
 
-	// 获取所有历史决策记录（无限制）
-	records, err := trader.GetDecisionLogger().GetLatestRecords(10000)
+	query, err := parseDecisionQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := trader.GetDecisionLogger().QueryRecords(query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取决策日志失败: %v", err),
@@ -1368,10 +1671,99 @@ func (s *Server) handleDecisions(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, records)
+	c.JSON(http.StatusOK, page)
+}
+
+// parseDecisionQuery 解析决策日志查询参数
+func parseDecisionQuery(c *gin.Context) (logger.DecisionQuery, error) {
+	query := logger.DecisionQuery{
+		Symbol: strings.ToUpper(strings.TrimSpace(c.Query("symbol"))),
+		Action: strings.TrimSpace(c.Query("action")),
+		Limit:  50,
+	}
+
+	if v := c.Query("start"); v != "" {
+		t, err := parseQueryTime(v)
+		if err != nil {
+			return query, fmt.Errorf("无效的start参数: %w", err)
+		}
+		query.StartTime = t
+	}
+	if v := c.Query("end"); v != "" {
+		t, err := parseQueryTime(v)
+		if err != nil {
+			return query, fmt.Errorf("无效的end参数: %w", err)
+		}
+		// 仅日期时包含当天
+		if len(v) == len("2006-01-02") {
+			t = t.AddDate(0, 0, 1)
+		}
+		query.EndTime = t
+	}
+	if v := c.Query("success"); v != "" {
+		success, err := strconv.ParseBool(v)
+		if err != nil {
+			return query, fmt.Errorf("无效的success参数: %w", err)
+		}
+		query.Success = &success
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return query, fmt.Errorf("无效的offset参数: %s", v)
+		}
+		query.Offset = offset
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return query, fmt.Errorf("无效的limit参数: %s", v)
+		}
+		if limit > 1000 {
+			limit = 1000
+		}
+		query.Limit = limit
+	}
+	query.Ascending = strings.EqualFold(c.Query("order"), "asc")
+
+	return query, nil
 }
 
-// handleLatestDecisions 最新决策日志（返回所有记录，最新的在前）
+// parseQueryTime 解析时间参数（RFC3339、日期或Unix秒）
+func parseQueryTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return t, nil
+	}
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("无法识别的时间格式: %s", v)
+}
+
+// 最新决策和净值曲线接口的条数限制（完整历史通过 /decisions 分页查询）
+const (
+	defaultLatestDecisions = 20
+	maxLatestDecisions     = 200
+	defaultEquityPoints    = 2000
+	maxEquityPoints        = 10000
+)
+
+// queryLimit 解析 limit 查询参数（缺省或无效时使用默认值，超出上限时截断）
+func queryLimit(c *gin.Context, defaultLimit, maxLimit int) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return defaultLimit
+	}
+	if limit > maxLimit {
+		return maxLimit
+	}
+	return limit
+}
+
+// handleLatestDecisions 最新决策日志（?limit=N，默认20条，最多200条，最新的在前）
 func (s *Server) handleLatestDecisions(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
@@ -1385,8 +1777,7 @@ func (s *Server) handleLatestDecisions(c *gin.Context) {
 		return
 	}
 
-	// V1.67版本：返回所有历史记录（最多10000条），而不是只返回5条
-	records, err := trader.GetDecisionLogger().GetLatestRecords(10000)
+	records, err := trader.GetDecisionLogger().GetLatestRecords(queryLimit(c, defaultLatestDecisions, maxLatestDecisions))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取决策日志失败: %v", err),
@@ -1417,7 +1808,7 @@ func (s *Server) handleStatistics(c *gin.Context) {
 		return
 	}
 
-	stats, err := trader.GetDecisionLogger().GetStatistics()
+	stats, err := trader.GetStatistics()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取统计信息失败: %v", err),
@@ -1449,7 +1840,7 @@ func (s *Server) handleCompetition(c *gin.Context) {
 	c.JSON(http.StatusOK, competition)
 }
 
-// handleEquityHistory 收益率历史数据
+// handleEquityHistory 收益率历史数据（?limit=N，默认最近2000个周期，最多10000个，只读取账户快照）
 func (s *Server) handleEquityHistory(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
@@ -1463,9 +1854,7 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 		return
 	}
 
-	// 获取尽可能多的历史数据（几天的数据）
-	// 每3分钟一个周期：10000条 = 约20天的数据
-	records, err := trader.GetDecisionLogger().GetLatestRecords(10000)
+	records, err := trader.GetDecisionLogger().GetEquityHistory(queryLimit(c, defaultEquityPoints, maxEquityPoints))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取历史数据失败: %v", err),
@@ -1549,9 +1938,8 @@ func (s *Server) handlePerformance(c *gin.Context) {
 		return
 	}
 
-	// 分析最近100个周期的交易表现（避免长期持仓的交易记录丢失）
-	// 假设每3分钟一个周期，100个周期 = 5小时，足够覆盖大部分交易
-	performance, err := trader.GetDecisionLogger().AnalyzePerformance(100)
+	// 优先使用交易台账（含手续费、资金费和交易所侧止盈止损），否则从决策日志重建
+	performance, err := trader.AnalyzePerformance()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("分析历史表现失败: %v", err),
@@ -1562,6 +1950,66 @@ func (s *Server) handlePerformance(c *gin.Context) {
 	c.JSON(http.StatusOK, performance)
 }
 
+// handleTrades 交易台账（?trader_id=xxx&limit=50）
+func (s *Server) handleTrades(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	trades, err := trader.GetTrades(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取交易台账失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, trades)
+}
+
+// handleUsage AI用量与费用统计（?trader_id=xxx&days=30，days=0 表示全部）
+func (s *Server) handleUsage(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	days := 30
+	if d, err := strconv.Atoi(c.Query("days")); err == nil && d >= 0 {
+		days = d
+	}
+
+	usage, err := trader.GetAIUsage(days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取AI用量失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
 // authMiddleware JWT认证中间件
 func (s *Server) authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -2011,10 +2459,12 @@ func (s *Server) Start() error {
 	log.Printf("  • GET  /api/status?trader_id=xxx     - 指定trader的系统状态")
 	log.Printf("  • GET  /api/account?trader_id=xxx    - 指定trader的账户信息")
 	log.Printf("  • GET  /api/positions?trader_id=xxx  - 指定trader的持仓列表")
-	log.Printf("  • GET  /api/decisions?trader_id=xxx  - 指定trader的决策日志")
+	log.Printf("  • GET  /api/decisions?trader_id=xxx  - 指定trader的决策日志（支持 start/end/symbol/action/success/offset/limit 过滤分页）")
 	log.Printf("  • GET  /api/decisions/latest?trader_id=xxx - 指定trader的最新决策")
 	log.Printf("  • GET  /api/statistics?trader_id=xxx - 指定trader的统计信息")
 	log.Printf("  • GET  /api/performance?trader_id=xxx - 指定trader的AI学习表现分析")
+	log.Printf("  • GET  /api/usage?trader_id=xxx&days=30 - 指定trader的AI token用量与费用（days=0 表示全部）")
+	log.Printf("  • GET  /api/indicators       - 可用技术指标及参数（无需认证）")
 	log.Println()
 
 	return s.router.Run(addr)
@@ -2049,11 +2499,31 @@ func (s *Server) handleGetPromptTemplate(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"name":    template.Name,
-		"content": template.Content,
+		"name":       template.Name,
+		"content":    template.Content,
+		"indicators": template.Indicators,
 	})
 }
 
+// handleGetIndicators 获取已注册的技术指标（名称、参数、输出序列）
+func (s *Server) handleGetIndicators(c *gin.Context) {
+	indicators := market.Indicators()
+	response := make([]map[string]interface{}, 0, len(indicators))
+	for _, ind := range indicators {
+		params := ind.Params()
+		if params == nil {
+			params = []market.IndicatorParam{}
+		}
+		response = append(response, map[string]interface{}{
+			"name":        ind.Name(),
+			"description": ind.Description(),
+			"params":      params,
+			"outputs":     ind.Outputs(),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"indicators": response})
+}
+
 // handleUpdatePromptTemplate 更新指定名称的提示词模板
 func (s *Server) handleUpdatePromptTemplate(c *gin.Context) {
 	templateName := c.Param("name")
@@ -2071,6 +2541,13 @@ func (s *Server) handleUpdatePromptTemplate(c *gin.Context) {
 		return
 	}
 
+	// 校验模板中的 #!indicators: 指令
+	if err := decision.ValidateTemplateDirectives(req.Content); err != nil {
+		log.Printf("❌ [更新提示词模板] 指标声明无效: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 更新模板
 	err := decision.UpdatePromptTemplate(templateName, req.Content)
 	if err != nil {
@@ -2238,7 +2715,7 @@ func (s *Server) getEquityHistoryForTraders(traderIDs []string) map[string]inter
 		}
 
 		// 获取历史数据（用于对比展示，限制数据量）
-		records, err := trader.GetDecisionLogger().GetLatestRecords(500)
+		records, err := trader.GetDecisionLogger().GetEquityHistory(500)
 		if err != nil {
 			errors[traderID] = fmt.Sprintf("获取历史数据失败: %v", err)
 			continue
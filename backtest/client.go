@@ -0,0 +1,18 @@
+package backtest
+
+import (
+	"nofx/mcp"
+)
+
+// NewAIClient 根据AI提供商创建回测使用的AI客户端
+// provider 为 "mock" 或空时返回离线模拟客户端（mockResponses 为按顺序循环返回的响应）
+func NewAIClient(provider, apiKey, customURL, customModel string, mockResponses ...string) (mcp.AIClient, error) {
+	if provider == "" || provider == "mock" {
+		return mcp.NewMockClient(mockResponses...), nil
+	}
+	client, err := mcp.NewClientForProvider(provider, apiKey, customURL, customModel)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
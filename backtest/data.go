@@ -0,0 +1,168 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultDataDir 默认历史K线目录
+const DefaultDataDir = "backtest_data"
+
+// symbolPattern 合法的回测币种（规范化后只允许大写字母和数字，防止路径穿越）
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9]{2,20}$`)
+
+// ValidateSymbol 校验回测币种（规范化后检查）
+func ValidateSymbol(symbol string) error {
+	if !symbolPattern.MatchString(market.Normalize(symbol)) {
+		return fmt.Errorf("无效的回测币种: %q", symbol)
+	}
+	return nil
+}
+
+// KlineFile 返回K线文件路径（不含扩展名）：<dataDir>/<SYMBOL>_<interval>
+func KlineFile(dataDir, symbol, interval string) string {
+	return filepath.Join(dataDir, fmt.Sprintf("%s_%s", market.Normalize(symbol), interval))
+}
+
+// LoadKlines 加载历史K线（按开盘时间升序）
+// 支持两种格式：
+//   - <SYMBOL>_<interval>.csv：币安历史数据格式（open_time,open,high,low,close,volume,close_time,...），可带表头
+//   - <SYMBOL>_<interval>.json：market.Kline 数组
+func LoadKlines(dataDir, symbol, interval string) ([]market.Kline, error) {
+	if err := ValidateSymbol(symbol); err != nil {
+		return nil, err
+	}
+	base := KlineFile(dataDir, symbol, interval)
+	if rel, err := filepath.Rel(dataDir, base); err != nil || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("K线文件路径超出数据目录: %s", base)
+	}
+
+	var klines []market.Kline
+	var err error
+	if _, statErr := os.Stat(base + ".csv"); statErr == nil {
+		klines, err = loadKlinesCSV(base + ".csv")
+	} else if _, statErr := os.Stat(base + ".json"); statErr == nil {
+		klines, err = loadKlinesJSON(base + ".json")
+	} else {
+		return nil, fmt.Errorf("未找到 %s %s 的K线文件（%s.csv 或 %s.json）", symbol, interval, base, base)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(klines) == 0 {
+		return nil, fmt.Errorf("%s %s K线数据为空", symbol, interval)
+	}
+
+	sort.Slice(klines, func(i, j int) bool { return klines[i].OpenTime < klines[j].OpenTime })
+	return klines, nil
+}
+
+// loadKlinesJSON 加载JSON格式K线
+func loadKlinesJSON(path string) ([]market.Kline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取K线文件失败: %w", err)
+	}
+	var klines []market.Kline
+	if err := json.Unmarshal(data, &klines); err != nil {
+		return nil, fmt.Errorf("解析K线文件 %s 失败: %w", path, err)
+	}
+	return klines, nil
+}
+
+// loadKlinesCSV 加载币安历史数据格式的CSV K线
+func loadKlinesCSV(path string) ([]market.Kline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取K线文件失败: %w", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+
+	var klines []market.Kline
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析K线文件 %s 失败: %w", path, err)
+		}
+		line++
+		if len(record) < 7 {
+			return nil, fmt.Errorf("K线文件 %s 第%d行字段不足", path, line)
+		}
+		// 跳过表头
+		if _, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 64); err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("K线文件 %s 第%d行开盘时间无效: %s", path, line, record[0])
+		}
+
+		kline, err := parseCSVKline(record)
+		if err != nil {
+			return nil, fmt.Errorf("K线文件 %s 第%d行: %w", path, line, err)
+		}
+		klines = append(klines, kline)
+	}
+	return klines, nil
+}
+
+// parseCSVKline 解析单行CSV K线
+func parseCSVKline(record []string) (market.Kline, error) {
+	var kline market.Kline
+	fields := make([]float64, len(record))
+	for i, v := range record {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return kline, fmt.Errorf("字段%d无效: %s", i+1, v)
+		}
+		fields[i] = f
+	}
+
+	kline.OpenTime = normalizeTimestamp(int64(fields[0]))
+	kline.Open = fields[1]
+	kline.High = fields[2]
+	kline.Low = fields[3]
+	kline.Close = fields[4]
+	kline.Volume = fields[5]
+	kline.CloseTime = normalizeTimestamp(int64(fields[6]))
+	if len(fields) > 7 {
+		kline.QuoteVolume = fields[7]
+	}
+	if len(fields) > 8 {
+		kline.Trades = int(fields[8])
+	}
+	if len(fields) > 9 {
+		kline.TakerBuyBaseVolume = fields[9]
+	}
+	if len(fields) > 10 {
+		kline.TakerBuyQuoteVolume = fields[10]
+	}
+	return kline, nil
+}
+
+// normalizeTimestamp 统一为毫秒时间戳（币安部分历史数据使用微秒）
+func normalizeTimestamp(ts int64) int64 {
+	if ts > 1e14 {
+		return ts / 1000
+	}
+	return ts
+}
@@ -0,0 +1,54 @@
+package backtest
+
+import (
+	"nofx/logger"
+	"time"
+)
+
+// Result 回测结果
+type Result struct {
+	Symbols        []string                    `json:"symbols"`
+	StartTime      time.Time                   `json:"start_time"`
+	EndTime        time.Time                   `json:"end_time"`
+	Cycles         int                         `json:"cycles"`           // 决策周期数
+	FailedCycles   int                         `json:"failed_cycles"`    // 失败的决策周期数（AI调用/解析失败）
+	InitialBalance float64                     `json:"initial_balance"`  // 初始资金
+	FinalEquity    float64                     `json:"final_equity"`     // 最终净值
+	TotalPnL       float64                     `json:"total_pnl"`        // 总盈亏（USDT）
+	TotalPnLPct    float64                     `json:"total_pnl_pct"`    // 总盈亏百分比
+	MaxDrawdownPct float64                     `json:"max_drawdown_pct"` // 最大回撤百分比（按每根3分钟K线净值计算）
+	TotalFees      float64                     `json:"total_fees"`       // 总手续费
+	EquityCurve    []EquityPoint               `json:"equity_curve"`     // 净值曲线（每个决策周期一个点）
+	Trades         []logger.TradeOutcome       `json:"trades"`           // 全部已完成交易
+	Performance    *logger.PerformanceAnalysis `json:"performance"`      // 交易表现统计（与实盘相同结构）
+	CycleLogs      []CycleLog                  `json:"cycle_logs"`       // 每个周期的决策记录
+}
+
+// EquityPoint 净值曲线数据点
+type EquityPoint struct {
+	Time          time.Time `json:"time"`
+	Cycle         int       `json:"cycle"`
+	Equity        float64   `json:"equity"`         // 净值（钱包余额+未实现盈亏）
+	WalletBalance float64   `json:"wallet_balance"` // 钱包余额
+	UnrealizedPnL float64   `json:"unrealized_pnl"` // 未实现盈亏
+	PositionCount int       `json:"position_count"` // 持仓数量
+}
+
+// CycleLog 单个决策周期记录
+type CycleLog struct {
+	Cycle     int                     `json:"cycle"`
+	Time      time.Time               `json:"time"`
+	Equity    float64                 `json:"equity"`
+	CoTTrace  string                  `json:"cot_trace"`
+	Decisions []logger.DecisionAction `json:"decisions"`
+	Error     string                  `json:"error,omitempty"`
+}
+
+// analyzePerformance 根据已完成交易和净值曲线计算交易表现（统计口径与实盘一致）
+func (r *Runner) analyzePerformance() *logger.PerformanceAnalysis {
+	equities := make([]float64, 0, len(r.equityCurve))
+	for _, point := range r.equityCurve {
+		equities = append(equities, point.Equity)
+	}
+	return logger.AnalyzeTrades(r.trades, equities)
+}
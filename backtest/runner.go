@@ -0,0 +1,681 @@
+package backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/mcp"
+	"nofx/trader"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 回测默认参数
+const (
+	defaultScanInterval   = 3 * time.Minute
+	defaultInitialBalance = 1000.0
+	defaultLeverage       = 5
+	minWarmupBars         = 60 // 开始决策前至少需要的3分钟K线数量（保证指标可计算）
+)
+
+// Config 回测配置
+type Config struct {
+	Symbols        []string      `json:"symbols"`         // 回测币种
+	DataDir        string        `json:"-"`               // 历史K线目录
+	StartTime      time.Time     `json:"start_time"`      // 开始时间（零值表示从数据开始）
+	EndTime        time.Time     `json:"end_time"`        // 结束时间（零值表示到数据结束）
+	ScanInterval   time.Duration `json:"scan_interval"`   // 模拟扫描间隔（默认3分钟）
+	MaxCycles      int           `json:"max_cycles"`      // 最大决策周期数（0表示不限制）
+	InitialBalance float64       `json:"initial_balance"` // 初始资金
+
+	BTCETHLeverage  int     `json:"btc_eth_leverage"`
+	AltcoinLeverage int     `json:"altcoin_leverage"`
+	TakerFeeRate    float64 `json:"taker_fee_rate"` // Taker费率（0表示使用模拟盘默认费率）
+
+	CustomPrompt       string              `json:"custom_prompt"`
+	OverrideBasePrompt bool                `json:"override_base_prompt"`
+	TemplateName       string              `json:"template_name"`
+	RiskLimits         decision.RiskLimits `json:"risk_limits"`
+}
+
+// Runner 回测运行器：按模拟时间回放历史K线，调用决策引擎并在模拟盘中撮合
+type Runner struct {
+	config Config
+	client mcp.AIClient
+
+	klines3m map[string][]market.Kline
+	klines4h map[string][]market.Kline
+	cursor3m map[string]int // 已收盘的3分钟K线数量
+	cursor4h map[string]int // 已收盘的4小时K线数量
+
+	now    time.Time
+	prices map[string]float64
+	trader *trader.PaperTrader
+
+	openTrades   map[string]*logger.TradeOutcome // symbol_side -> 部分平仓累计
+	trades       []logger.TradeOutcome
+	totalFees    float64
+	peakEquity   float64
+	maxDrawdown  float64
+	firstSeen    map[string]int64
+	history      []*decision.HistoryDecision
+	equityCurve  []EquityPoint
+	cycleLogs    []CycleLog
+	failedCycles int
+}
+
+// NewRunner 创建回测运行器并加载历史K线
+func NewRunner(config Config, client mcp.AIClient) (*Runner, error) {
+	if len(config.Symbols) == 0 {
+		return nil, fmt.Errorf("回测币种不能为空")
+	}
+	if client == nil {
+		client = mcp.NewMockClient()
+	}
+	if config.DataDir == "" {
+		config.DataDir = DefaultDataDir
+	}
+	if config.ScanInterval <= 0 {
+		config.ScanInterval = defaultScanInterval
+	}
+	if config.InitialBalance <= 0 {
+		config.InitialBalance = defaultInitialBalance
+	}
+	if config.BTCETHLeverage <= 0 {
+		config.BTCETHLeverage = defaultLeverage
+	}
+	if config.AltcoinLeverage <= 0 {
+		config.AltcoinLeverage = defaultLeverage
+	}
+	for i, symbol := range config.Symbols {
+		if err := ValidateSymbol(symbol); err != nil {
+			return nil, err
+		}
+		config.Symbols[i] = market.Normalize(symbol)
+	}
+
+	r := &Runner{
+		config:     config,
+		client:     client,
+		klines3m:   make(map[string][]market.Kline),
+		klines4h:   make(map[string][]market.Kline),
+		cursor3m:   make(map[string]int),
+		cursor4h:   make(map[string]int),
+		prices:     make(map[string]float64),
+		openTrades: make(map[string]*logger.TradeOutcome),
+		firstSeen:  make(map[string]int64),
+	}
+
+	for _, symbol := range config.Symbols {
+		k3m, err := LoadKlines(config.DataDir, symbol, "3m")
+		if err != nil {
+			return nil, err
+		}
+		k4h, err := LoadKlines(config.DataDir, symbol, "4h")
+		if err != nil {
+			return nil, err
+		}
+		r.klines3m[symbol] = k3m
+		r.klines4h[symbol] = k4h
+		log.Printf("📂 [回测] 加载 %s K线: 3m %d 根, 4h %d 根", symbol, len(k3m), len(k4h))
+	}
+
+	r.trader = trader.NewBacktestPaperTrader(config.InitialBalance, r.currentPrice, func() time.Time { return r.now })
+	if config.TakerFeeRate > 0 {
+		r.trader.SetTakerFeeRate(config.TakerFeeRate)
+	}
+	r.trader.SetTradeHandler(r.onTrade)
+	return r, nil
+}
+
+// Run 执行回测
+func (r *Runner) Run() (*Result, error) {
+	// 以第一个币种的3分钟K线作为模拟时钟
+	clock := r.klines3m[r.config.Symbols[0]]
+	startIdx := minWarmupBars
+	if !r.config.StartTime.IsZero() {
+		for startIdx < len(clock) && time.UnixMilli(clock[startIdx].CloseTime).Before(r.config.StartTime) {
+			startIdx++
+		}
+	}
+	if startIdx >= len(clock) {
+		return nil, fmt.Errorf("历史K线不足：至少需要 %d 根3分钟K线作为预热数据", minWarmupBars+1)
+	}
+
+	log.Printf("🚀 [回测] 开始: 币种 %v, 扫描间隔 %v, 初始资金 %.2f USDT", r.config.Symbols, r.config.ScanInterval, r.config.InitialBalance)
+
+	startTime := time.UnixMilli(clock[startIdx].CloseTime)
+	var lastCycle time.Time
+	cycle := 0
+	for i := 0; i < len(clock); i++ {
+		barClose := clock[i].CloseTime
+		if !r.config.EndTime.IsZero() && time.UnixMilli(barClose).After(r.config.EndTime) {
+			break
+		}
+		r.now = time.UnixMilli(barClose)
+		r.advance(barClose, i >= startIdx)
+
+		if i < startIdx {
+			continue
+		}
+		r.trackDrawdown()
+
+		if lastCycle.IsZero() || r.now.Sub(lastCycle) >= r.config.ScanInterval {
+			lastCycle = r.now
+			cycle++
+			r.runCycle(cycle, startTime)
+			if r.config.MaxCycles > 0 && cycle >= r.config.MaxCycles {
+				break
+			}
+		}
+	}
+
+	// 回测结束：按最后价格平掉所有持仓，计入交易统计
+	r.flatten()
+	balance, err := r.trader.GetBalance()
+	if err != nil {
+		return nil, fmt.Errorf("获取回测账户余额失败: %w", err)
+	}
+	finalEquity := balance.TotalWalletBalance + balance.TotalUnrealizedProfit
+	r.recordEquity(cycle, balance, 0)
+	r.trackDrawdown()
+
+	result := &Result{
+		Symbols:        r.config.Symbols,
+		StartTime:      startTime,
+		EndTime:        r.now,
+		Cycles:         cycle,
+		FailedCycles:   r.failedCycles,
+		InitialBalance: r.config.InitialBalance,
+		FinalEquity:    finalEquity,
+		TotalPnL:       finalEquity - r.config.InitialBalance,
+		TotalPnLPct:    (finalEquity - r.config.InitialBalance) / r.config.InitialBalance * 100,
+		MaxDrawdownPct: r.maxDrawdown,
+		TotalFees:      r.totalFees,
+		EquityCurve:    r.equityCurve,
+		Trades:         r.trades,
+		Performance:    r.analyzePerformance(),
+		CycleLogs:      r.cycleLogs,
+	}
+
+	log.Printf("🏁 [回测] 完成: %d 个周期, 最终净值 %.2f USDT (%+.2f%%), 最大回撤 %.2f%%, 交易 %d 笔",
+		result.Cycles, result.FinalEquity, result.TotalPnLPct, result.MaxDrawdownPct, len(result.Trades))
+	return result, nil
+}
+
+// advance 推进所有币种到指定时间，回放期间的K线价格以触发止盈止损和强平
+func (r *Runner) advance(until int64, replay bool) {
+	for _, symbol := range r.config.Symbols {
+		k3m := r.klines3m[symbol]
+		for r.cursor3m[symbol] < len(k3m) && k3m[r.cursor3m[symbol]].CloseTime <= until {
+			bar := k3m[r.cursor3m[symbol]]
+			r.cursor3m[symbol]++
+			if replay {
+				r.replayBar(symbol, bar)
+			} else {
+				r.prices[symbol] = bar.Close
+			}
+		}
+		k4h := r.klines4h[symbol]
+		for r.cursor4h[symbol] < len(k4h) && k4h[r.cursor4h[symbol]].CloseTime <= until {
+			r.cursor4h[symbol]++
+		}
+	}
+}
+
+// replayBar 按 开→低→高→收（阳线）或 开→高→低→收（阴线）的路径推送价格
+func (r *Runner) replayBar(symbol string, bar market.Kline) {
+	path := []float64{bar.Open, bar.Low, bar.High, bar.Close}
+	if bar.Close < bar.Open {
+		path = []float64{bar.Open, bar.High, bar.Low, bar.Close}
+	}
+	for _, price := range path {
+		r.prices[symbol] = price
+		r.trader.OnPrice(symbol, price)
+	}
+}
+
+// currentPrice 当前模拟时刻的成交价格
+func (r *Runner) currentPrice(symbol string) (float64, error) {
+	price, ok := r.prices[market.Normalize(symbol)]
+	if !ok || price <= 0 {
+		return 0, fmt.Errorf("%s 在 %s 没有历史价格", symbol, r.now.Format("2006-01-02 15:04"))
+	}
+	return price, nil
+}
+
+// marketData 使用截至当前模拟时刻已收盘的K线构建市场数据快照
+func (r *Runner) marketData(symbol string) (*market.Data, error) {
+	symbol = market.Normalize(symbol)
+	n3m, n4h := r.cursor3m[symbol], r.cursor4h[symbol]
+	if n3m == 0 {
+		return nil, fmt.Errorf("%s 没有历史K线数据", symbol)
+	}
+	k3m := r.klines3m[symbol][max(0, n3m-market.DefaultKlineLimit):n3m]
+	k4h := r.klines4h[symbol][max(0, n4h-market.DefaultKlineLimit):n4h]
+	return market.BuildData(symbol, k3m, k4h, r.prices[symbol], nil, 0), nil
+}
+
+// runCycle 执行一个模拟决策周期
+func (r *Runner) runCycle(cycle int, startTime time.Time) {
+	cycleLog := CycleLog{Cycle: cycle, Time: r.now}
+	defer func() {
+		r.cycleLogs = append(r.cycleLogs, cycleLog)
+	}()
+
+	ctx, balance, err := r.buildContext(cycle, startTime)
+	if err != nil {
+		r.failedCycles++
+		cycleLog.Error = err.Error()
+		return
+	}
+	cycleLog.Equity = ctx.Account.TotalEquity
+	r.recordEquity(cycle, balance, len(ctx.Positions))
+
+	fullDecision, err := decision.GetFullDecisionWithCustomPrompt(ctx, r.client, r.config.CustomPrompt, r.config.OverrideBasePrompt, r.config.TemplateName)
+	if err != nil {
+		r.failedCycles++
+		cycleLog.Error = fmt.Sprintf("获取AI决策失败: %v", err)
+		log.Printf("⚠️ [回测] 周期 #%d %s", cycle, cycleLog.Error)
+		return
+	}
+	cycleLog.CoTTrace = fullDecision.CoTTrace
+
+	decisions := sortDecisions(fullDecision.Decisions)
+	for i := range decisions {
+		d := &decisions[i]
+		action := logger.DecisionAction{
+			Action:    d.Action,
+			Symbol:    d.Symbol,
+			Leverage:  d.Leverage,
+			Timestamp: r.now,
+		}
+		for _, rc := range d.RiskChecks {
+			action.RiskChecks = append(action.RiskChecks, logger.RiskCheck{
+				Rule:    rc.Rule,
+				Action:  rc.Action,
+				Message: rc.Message,
+				Limit:   rc.Limit,
+				Value:   rc.Value,
+			})
+		}
+
+		if rejection := d.RiskRejection(); rejection != nil {
+			action.Error = fmt.Sprintf("风控拒绝[%s]: %s", rejection.Rule, rejection.Message)
+		} else if err := r.execute(d, &action); err != nil {
+			action.Error = err.Error()
+		} else {
+			action.Success = true
+		}
+		cycleLog.Decisions = append(cycleLog.Decisions, action)
+	}
+
+	r.history = append(r.history, &decision.HistoryDecision{
+		CycleNumber: cycle,
+		Timestamp:   r.now.Format("2006-01-02 15:04:05"),
+		Decisions:   fullDecision.Decisions,
+		CoTTrace:    fullDecision.CoTTrace,
+	})
+	if len(r.history) > 3 {
+		r.history = r.history[len(r.history)-3:]
+	}
+}
+
+// buildContext 构建模拟时刻的交易上下文（与实盘 AutoTrader.buildTradingContext 保持一致）
+func (r *Runner) buildContext(cycle int, startTime time.Time) (*decision.Context, *trader.Balance, error) {
+	balance, err := r.trader.GetBalance()
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取账户余额失败: %w", err)
+	}
+	positions, err := r.trader.GetPositions()
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	totalEquity := balance.TotalWalletBalance + balance.TotalUnrealizedProfit
+
+	var positionInfos []decision.PositionInfo
+	totalMarginUsed := 0.0
+	currentKeys := make(map[string]bool)
+	for _, pos := range positions {
+		if pos.Quantity == 0 || pos.EntryPrice <= 0 {
+			continue
+		}
+		pnlPct := (pos.MarkPrice - pos.EntryPrice) / pos.EntryPrice * 100
+		if pos.Side == "short" {
+			pnlPct = -pnlPct
+		}
+		leverage := pos.Leverage
+		if leverage <= 0 {
+			leverage = defaultLeverage
+		}
+		marginUsed := pos.Quantity * pos.MarkPrice / float64(leverage)
+		totalMarginUsed += marginUsed
+
+		key := pos.Symbol + "_" + pos.Side
+		currentKeys[key] = true
+		if _, ok := r.firstSeen[key]; !ok {
+			r.firstSeen[key] = r.now.UnixMilli()
+		}
+
+		positionInfos = append(positionInfos, decision.PositionInfo{
+			Symbol:           pos.Symbol,
+			Side:             pos.Side,
+			EntryPrice:       pos.EntryPrice,
+			MarkPrice:        pos.MarkPrice,
+			Quantity:         pos.Quantity,
+			Leverage:         leverage,
+			UnrealizedPnL:    pos.UnrealizedProfit,
+			UnrealizedPnLPct: pnlPct,
+			LiquidationPrice: pos.LiquidationPrice,
+			MarginUsed:       marginUsed,
+			UpdateTime:       r.firstSeen[key],
+		})
+	}
+	for key := range r.firstSeen {
+		if !currentKeys[key] {
+			delete(r.firstSeen, key)
+		}
+	}
+
+	candidateCoins := make([]decision.CandidateCoin, 0, len(r.config.Symbols))
+	for _, symbol := range r.config.Symbols {
+		candidateCoins = append(candidateCoins, decision.CandidateCoin{Symbol: symbol, Sources: []string{"backtest"}})
+	}
+
+	totalPnL := totalEquity - r.config.InitialBalance
+	marginUsedPct := 0.0
+	if totalEquity > 0 {
+		marginUsedPct = totalMarginUsed / totalEquity * 100
+	}
+
+	beijingTZ, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		beijingTZ = time.FixedZone("CST", 8*3600)
+	}
+
+	ctx := &decision.Context{
+		Exchange:        "binance",
+		CurrentTime:     r.now.In(beijingTZ).Format("2006-01-02 15:04:05"),
+		RuntimeMinutes:  int(r.now.Sub(startTime).Minutes()),
+		CallCount:       cycle,
+		BTCETHLeverage:  r.config.BTCETHLeverage,
+		AltcoinLeverage: r.config.AltcoinLeverage,
+		Account: decision.AccountInfo{
+			TotalEquity:      totalEquity,
+			AvailableBalance: balance.AvailableBalance,
+			TotalPnL:         totalPnL,
+			TotalPnLPct:      totalPnL / r.config.InitialBalance * 100,
+			MarginUsed:       totalMarginUsed,
+			MarginUsedPct:    marginUsedPct,
+			PositionCount:    len(positionInfos),
+		},
+		Positions:        positionInfos,
+		CandidateCoins:   candidateCoins,
+		Performance:      r.analyzePerformance(),
+		HistoryDecisions: r.history,
+		RiskLimits:       r.config.RiskLimits,
+		MarketDataFunc:   r.marketData,
+	}
+	return ctx, balance, nil
+}
+
+// execute 在模拟盘中执行决策
+func (r *Runner) execute(d *decision.Decision, action *logger.DecisionAction) error {
+	symbol := market.Normalize(d.Symbol)
+
+	switch d.Action {
+	case "open_long", "open_short":
+		// 回测统一按市价成交（限价委托的挂单跟踪只在 AutoTrader 中进行）
+		side := strings.TrimPrefix(d.Action, "open_")
+		positions, err := r.trader.GetPositions()
+		if err != nil {
+			return err
+		}
+		if _, exists := trader.FindPosition(positions, symbol, side); exists {
+			return fmt.Errorf("%s 已有%s仓，拒绝开仓以防止仓位叠加", symbol, side)
+		}
+		price, err := r.currentPrice(symbol)
+		if err != nil {
+			return err
+		}
+		quantity := d.PositionSizeUSD / price
+		if err := r.trader.SetLeverage(symbol, d.Leverage); err != nil {
+			return err
+		}
+
+		// 分批止盈在成交后按档位挂出
+		takeProfit := d.TakeProfit
+		if len(d.TakeProfitLevels) > 0 {
+			takeProfit = 0
+		}
+		var order *trader.OrderResult
+		if side == "long" {
+			order, err = r.trader.OpenLong(symbol, quantity, d.Leverage, d.StopLoss, takeProfit)
+		} else {
+			order, err = r.trader.OpenShort(symbol, quantity, d.Leverage, d.StopLoss, takeProfit)
+		}
+		if err != nil {
+			return err
+		}
+		r.totalFees += order.Fee
+		r.fillAction(action, order)
+		if len(d.TakeProfitLevels) > 0 {
+			return r.placeTakeProfitLadder(symbol, strings.ToUpper(side), action.Quantity, d.TakeProfitLevels)
+		}
+		return nil
+
+	case "close_long", "close_short":
+		var order *trader.OrderResult
+		var err error
+		if d.Action == "close_long" {
+			order, err = r.trader.CloseLong(symbol, 0)
+		} else {
+			order, err = r.trader.CloseShort(symbol, 0)
+		}
+		if err != nil {
+			return err
+		}
+		r.fillAction(action, order)
+		return nil
+
+	case "update_stop_loss", "update_take_profit", "partial_close", "set_trailing_stop":
+		pos, err := r.findAnyPosition(symbol)
+		if err != nil {
+			return err
+		}
+		positionSide := strings.ToUpper(pos.Side)
+		switch d.Action {
+		case "update_stop_loss":
+			if err := r.trader.CancelStopLossOrders(symbol); err != nil {
+				return err
+			}
+			return r.trader.SetStopLoss(symbol, positionSide, pos.Quantity, d.NewStopLoss)
+		case "update_take_profit":
+			if err := r.trader.CancelTakeProfitOrders(symbol); err != nil {
+				return err
+			}
+			if len(d.TakeProfitLevels) > 0 {
+				d.SortTakeProfitLevels(pos.Side == "long")
+				return r.placeTakeProfitLadder(symbol, positionSide, pos.Quantity, d.TakeProfitLevels)
+			}
+			return r.trader.SetTakeProfit(symbol, positionSide, pos.Quantity, d.NewTakeProfit)
+		case "set_trailing_stop":
+			// 模拟盘原生跟踪止损：K线回放的价格路径维护极值并触发
+			if err := r.trader.CancelTrailingStopOrders(symbol); err != nil {
+				return err
+			}
+			return r.trader.SetTrailingStop(symbol, positionSide, pos.Quantity, d.CallbackRate, d.ActivationPrice)
+		default:
+			quantity := pos.Quantity * d.ClosePercentage / 100
+			var order *trader.OrderResult
+			if pos.Side == "long" {
+				order, err = r.trader.CloseLong(symbol, quantity)
+			} else {
+				order, err = r.trader.CloseShort(symbol, quantity)
+			}
+			if err != nil {
+				return err
+			}
+			r.fillAction(action, order)
+			return nil
+		}
+
+	case "hold", "wait":
+		return nil
+	}
+	return fmt.Errorf("未知的action: %s", d.Action)
+}
+
+// findAnyPosition 查找币种的持仓（不区分方向）
+func (r *Runner) findAnyPosition(symbol string) (trader.Position, error) {
+	positions, err := r.trader.GetPositions()
+	if err != nil {
+		return trader.Position{}, err
+	}
+	for _, pos := range positions {
+		if pos.Symbol == symbol && pos.Quantity > 0 {
+			return pos, nil
+		}
+	}
+	return trader.Position{}, fmt.Errorf("%s 没有持仓", symbol)
+}
+
+// placeTakeProfitLadder 按分批止盈档位在模拟盘挂出多个止盈单
+func (r *Runner) placeTakeProfitLadder(symbol, positionSide string, quantity float64, levels []decision.TakeProfitLevel) error {
+	inst, err := r.trader.GetInstrument(symbol)
+	if err != nil {
+		return err
+	}
+	for _, level := range trader.SplitTakeProfitLevels(inst, quantity, levels) {
+		if err := r.trader.SetTakeProfit(symbol, positionSide, level.Quantity, level.Price); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillAction 将成交结果写入决策动作记录
+func (r *Runner) fillAction(action *logger.DecisionAction, order *trader.OrderResult) {
+	action.Price = order.AvgPrice
+	action.Quantity = order.ExecutedQty
+	action.OrderID = order.NumericOrderID()
+}
+
+// flatten 回测结束时平掉所有持仓
+func (r *Runner) flatten() {
+	positions, err := r.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️ [回测] 结束平仓获取持仓失败: %v", err)
+		return
+	}
+	for _, pos := range positions {
+		if pos.Side == "long" {
+			_, err = r.trader.CloseLong(pos.Symbol, 0)
+		} else {
+			_, err = r.trader.CloseShort(pos.Symbol, 0)
+		}
+		if err != nil {
+			log.Printf("⚠️ [回测] 结束平仓 %s %s 失败: %v", pos.Symbol, pos.Side, err)
+		}
+	}
+}
+
+// onTrade 模拟盘平仓回调：累计部分平仓，全部平仓后生成一笔交易结果
+// 注意：回调在模拟盘持锁时执行，不能再调用交易器方法
+func (r *Runner) onTrade(fill trader.PaperTrade) {
+	r.totalFees += fill.Fee
+
+	key := fill.Symbol + "_" + fill.Side
+	outcome, ok := r.openTrades[key]
+	if !ok {
+		outcome = &logger.TradeOutcome{
+			Symbol:    fill.Symbol,
+			Side:      fill.Side,
+			Leverage:  fill.Leverage,
+			OpenPrice: fill.EntryPrice,
+			OpenTime:  fill.OpenTime,
+		}
+		r.openTrades[key] = outcome
+	}
+	outcome.Quantity += fill.Quantity
+	outcome.PnL += fill.PnL
+	outcome.ClosePrice = fill.ExitPrice
+	outcome.CloseTime = fill.CloseTime
+	if fill.Reason == "stop_loss" {
+		outcome.WasStopLoss = true
+	}
+	if !fill.FullyClosed {
+		return
+	}
+
+	outcome.PositionValue = outcome.Quantity * outcome.OpenPrice
+	if outcome.Leverage > 0 {
+		outcome.MarginUsed = outcome.PositionValue / float64(outcome.Leverage)
+	}
+	if outcome.MarginUsed > 0 {
+		outcome.PnLPct = outcome.PnL / outcome.MarginUsed * 100
+	}
+	outcome.Duration = outcome.CloseTime.Sub(outcome.OpenTime).String()
+	r.trades = append(r.trades, *outcome)
+	delete(r.openTrades, key)
+}
+
+// recordEquity 记录净值曲线
+func (r *Runner) recordEquity(cycle int, balance *trader.Balance, positionCount int) {
+	r.equityCurve = append(r.equityCurve, EquityPoint{
+		Time:          r.now,
+		Cycle:         cycle,
+		Equity:        balance.TotalWalletBalance + balance.TotalUnrealizedProfit,
+		WalletBalance: balance.TotalWalletBalance,
+		UnrealizedPnL: balance.TotalUnrealizedProfit,
+		PositionCount: positionCount,
+	})
+}
+
+// trackDrawdown 按每根K线的净值更新最大回撤
+func (r *Runner) trackDrawdown() {
+	balance, err := r.trader.GetBalance()
+	if err != nil {
+		return
+	}
+	equity := balance.TotalWalletBalance + balance.TotalUnrealizedProfit
+	if equity > r.peakEquity {
+		r.peakEquity = equity
+	}
+	if r.peakEquity > 0 {
+		r.maxDrawdown = math.Max(r.maxDrawdown, (r.peakEquity-equity)/r.peakEquity*100)
+	}
+}
+
+// sortDecisions 决策排序：先平仓，再调整止盈止损，再开仓（与实盘执行顺序一致）
+func sortDecisions(decisions []decision.Decision) []decision.Decision {
+	priority := func(action string) int {
+		switch action {
+		case "close_long", "close_short", "partial_close":
+			return 1
+		case "update_stop_loss", "update_take_profit", "set_trailing_stop":
+			return 2
+		case "open_long", "open_short":
+			return 3
+		default:
+			return 4
+		}
+	}
+	sorted := make([]decision.Decision, len(decisions))
+	copy(sorted, decisions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return priority(sorted[i].Action) < priority(sorted[j].Action)
+	})
+	return sorted
+}
+
+// MarshalResult 序列化回测结果（用于CLI输出）
+func MarshalResult(result *Result) ([]byte, error) {
+	return json.MarshalIndent(result, "", "  ")
+}
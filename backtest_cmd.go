@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"nofx/backtest"
+	"os"
+	"strings"
+	"time"
+)
+
+// runBacktestCommand 执行回测子命令: nofx backtest [flags]
+func runBacktestCommand(args []string) int {
+	fs := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	symbols := fs.String("symbols", "BTCUSDT", "回测币种，逗号分隔")
+	dataDir := fs.String("data", backtest.DefaultDataDir, "历史K线目录（<SYMBOL>_3m.csv/.json 和 <SYMBOL>_4h.csv/.json）")
+	start := fs.String("start", "", "开始时间（2006-01-02 或 RFC3339，默认数据开始）")
+	end := fs.String("end", "", "结束时间（2006-01-02 或 RFC3339，默认数据结束）")
+	interval := fs.Duration("interval", 3*time.Minute, "模拟扫描间隔")
+	balance := fs.Float64("balance", 1000, "初始资金（USDT）")
+	btcEthLeverage := fs.Int("btc-eth-leverage", 5, "BTC/ETH杠杆倍数")
+	altcoinLeverage := fs.Int("altcoin-leverage", 5, "山寨币杠杆倍数")
+	feeRate := fs.Float64("fee-rate", 0, "Taker费率（0表示使用默认费率）")
+	templateName := fs.String("template", "", "系统提示词模板名称")
+	promptFile := fs.String("prompt-file", "", "自定义提示词文件")
+	overridePrompt := fs.Bool("override-prompt", false, "自定义提示词覆盖基础提示词")
+	provider := fs.String("provider", "mock", "AI提供商: mock/deepseek/qwen/googleai/chatgpt/anthropic/custom")
+	apiKey := fs.String("api-key", "", "AI API Key")
+	apiURL := fs.String("api-url", "", "自定义AI API URL")
+	model := fs.String("model", "", "自定义AI模型名称")
+	mockFile := fs.String("mock-file", "", "模拟AI响应文件（多个响应用单独一行 --- 分隔，按顺序循环返回）")
+	out := fs.String("out", "", "回测结果输出文件（JSON），为空时只打印汇总")
+	maxCycles := fs.Int("max-cycles", 0, "最大决策周期数（0表示不限制）")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	startTime, err := parseBacktestTime(*start)
+	if err != nil {
+		log.Printf("❌ 开始时间无效: %v", err)
+		return 2
+	}
+	endTime, err := parseBacktestTime(*end)
+	if err != nil {
+		log.Printf("❌ 结束时间无效: %v", err)
+		return 2
+	}
+
+	customPrompt := ""
+	if *promptFile != "" {
+		data, err := os.ReadFile(*promptFile)
+		if err != nil {
+			log.Printf("❌ 读取提示词文件失败: %v", err)
+			return 1
+		}
+		customPrompt = string(data)
+	}
+
+	var mockResponses []string
+	if *mockFile != "" {
+		data, err := os.ReadFile(*mockFile)
+		if err != nil {
+			log.Printf("❌ 读取模拟AI响应文件失败: %v", err)
+			return 1
+		}
+		for _, resp := range strings.Split(string(data), "\n---\n") {
+			if strings.TrimSpace(resp) != "" {
+				mockResponses = append(mockResponses, resp)
+			}
+		}
+	}
+
+	client, err := backtest.NewAIClient(*provider, *apiKey, *apiURL, *model, mockResponses...)
+	if err != nil {
+		log.Printf("❌ 创建AI客户端失败: %v", err)
+		return 1
+	}
+
+	var symbolList []string
+	for _, s := range strings.Split(*symbols, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			symbolList = append(symbolList, s)
+		}
+	}
+
+	runner, err := backtest.NewRunner(backtest.Config{
+		Symbols:            symbolList,
+		DataDir:            *dataDir,
+		StartTime:          startTime,
+		EndTime:            endTime,
+		ScanInterval:       *interval,
+		MaxCycles:          *maxCycles,
+		InitialBalance:     *balance,
+		BTCETHLeverage:     *btcEthLeverage,
+		AltcoinLeverage:    *altcoinLeverage,
+		TakerFeeRate:       *feeRate,
+		CustomPrompt:       customPrompt,
+		OverrideBasePrompt: *overridePrompt,
+		TemplateName:       *templateName,
+	}, client)
+	if err != nil {
+		log.Printf("❌ 初始化回测失败: %v", err)
+		return 1
+	}
+
+	result, err := runner.Run()
+	if err != nil {
+		log.Printf("❌ 回测失败: %v", err)
+		return 1
+	}
+
+	fmt.Println()
+	fmt.Printf("📊 回测结果 %s ~ %s\n", result.StartTime.Format("2006-01-02 15:04"), result.EndTime.Format("2006-01-02 15:04"))
+	fmt.Printf("   决策周期: %d (失败 %d)\n", result.Cycles, result.FailedCycles)
+	fmt.Printf("   净值: %.2f → %.2f USDT (%+.2f%%)\n", result.InitialBalance, result.FinalEquity, result.TotalPnLPct)
+	fmt.Printf("   最大回撤: %.2f%%  手续费: %.4f USDT\n", result.MaxDrawdownPct, result.TotalFees)
+	if perf := result.Performance; perf != nil {
+		fmt.Printf("   交易: %d 笔  胜率: %.1f%%  盈亏比: %.2f  夏普比率: %.2f\n", perf.TotalTrades, perf.WinRate, perf.ProfitFactor, perf.SharpeRatio)
+	}
+
+	if *out != "" {
+		data, err := backtest.MarshalResult(result)
+		if err != nil {
+			log.Printf("❌ 序列化回测结果失败: %v", err)
+			return 1
+		}
+		if err := os.WriteFile(*out, data, 0644); err != nil {
+			log.Printf("❌ 写入回测结果失败: %v", err)
+			return 1
+		}
+		fmt.Printf("   结果已保存: %s\n", *out)
+	}
+	return 0
+}
+
+// parseBacktestTime 解析回测时间参数（空字符串返回零值）
+func parseBacktestTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// AIUsageEntry 单次AI调用用量
+type AIUsageEntry struct {
+	Time             time.Time
+	Provider         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	CachedTokens     int
+	CostUSD          float64
+	LatencyMs        int64
+}
+
+// AIUsageRow AI用量汇总行（按模型或按日期）
+type AIUsageRow struct {
+	Date             string  `json:"date,omitempty"`
+	Provider         string  `json:"provider,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CachedTokens     int     `json:"cached_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	AvgLatencyMs     int64   `json:"avg_latency_ms"`
+}
+
+// AIUsageSummary 交易员AI用量汇总
+type AIUsageSummary struct {
+	TraderID string       `json:"trader_id"`
+	Since    string       `json:"since,omitempty"` // 统计起始日期（为空表示全部）
+	Total    AIUsageRow   `json:"total"`
+	ByModel  []AIUsageRow `json:"by_model"`
+	Daily    []AIUsageRow `json:"daily"`
+}
+
+// RecordAIUsage 累加一次AI调用用量（按交易员/日期/提供商/模型聚合）
+func (d *Database) RecordAIUsage(traderID string, entry AIUsageEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	_, err := d.db.Exec(`
+		INSERT INTO ai_usage (trader_id, date, provider, model, calls, prompt_tokens, completion_tokens, cached_tokens, cost_usd, latency_ms, updated_at)
+		VALUES (?, ?, ?, ?, 1, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(trader_id, date, provider, model) DO UPDATE SET
+			calls = calls + 1,
+			prompt_tokens = prompt_tokens + excluded.prompt_tokens,
+			completion_tokens = completion_tokens + excluded.completion_tokens,
+			cached_tokens = cached_tokens + excluded.cached_tokens,
+			cost_usd = cost_usd + excluded.cost_usd,
+			latency_ms = latency_ms + excluded.latency_ms,
+			updated_at = CURRENT_TIMESTAMP
+	`, traderID, entry.Time.Format("2006-01-02"), entry.Provider, entry.Model,
+		entry.PromptTokens, entry.CompletionTokens, entry.CachedTokens, entry.CostUSD, entry.LatencyMs)
+	if err != nil {
+		return fmt.Errorf("记录AI用量失败: %w", err)
+	}
+	return nil
+}
+
+// GetAIUsage 获取交易员AI用量汇总（days<=0 表示全部）
+func (d *Database) GetAIUsage(traderID string, days int) (*AIUsageSummary, error) {
+	summary := &AIUsageSummary{TraderID: traderID, ByModel: []AIUsageRow{}, Daily: []AIUsageRow{}}
+	since := "0000-00-00"
+	if days > 0 {
+		since = time.Now().AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+		summary.Since = since
+	}
+
+	const columns = `COALESCE(SUM(calls), 0), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0),
+		COALESCE(SUM(cached_tokens), 0), COALESCE(SUM(cost_usd), 0), COALESCE(SUM(latency_ms), 0)`
+
+	scanRow := func(scanner interface{ Scan(dest ...any) error }, row *AIUsageRow, prefix ...any) error {
+		var latency int64
+		dest := append(prefix, &row.Calls, &row.PromptTokens, &row.CompletionTokens, &row.CachedTokens, &row.CostUSD, &latency)
+		if err := scanner.Scan(dest...); err != nil {
+			return err
+		}
+		if row.Calls > 0 {
+			row.AvgLatencyMs = latency / int64(row.Calls)
+		}
+		return nil
+	}
+
+	if err := scanRow(d.db.QueryRow(`SELECT `+columns+` FROM ai_usage WHERE trader_id = ? AND date >= ?`, traderID, since), &summary.Total); err != nil {
+		return nil, fmt.Errorf("统计AI用量失败: %w", err)
+	}
+
+	rows, err := d.db.Query(`SELECT provider, model, `+columns+` FROM ai_usage WHERE trader_id = ? AND date >= ?
+		GROUP BY provider, model ORDER BY SUM(cost_usd) DESC`, traderID, since)
+	if err != nil {
+		return nil, fmt.Errorf("按模型统计AI用量失败: %w", err)
+	}
+	for rows.Next() {
+		var row AIUsageRow
+		if err := scanRow(rows, &row, &row.Provider, &row.Model); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("读取AI用量失败: %w", err)
+		}
+		summary.ByModel = append(summary.ByModel, row)
+	}
+	rows.Close()
+
+	rows, err = d.db.Query(`SELECT date, `+columns+` FROM ai_usage WHERE trader_id = ? AND date >= ?
+		GROUP BY date ORDER BY date ASC`, traderID, since)
+	if err != nil {
+		return nil, fmt.Errorf("按日期统计AI用量失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row AIUsageRow
+		if err := scanRow(rows, &row, &row.Date); err != nil {
+			return nil, fmt.Errorf("读取AI用量失败: %w", err)
+		}
+		summary.Daily = append(summary.Daily, row)
+	}
+	return summary, rows.Err()
+}
@@ -137,6 +137,93 @@ func (d *Database) createTables() error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
 
+		// 交易台账表（每笔持仓一条记录，执行时写入并与交易所成交历史对账）
+		`CREATE TABLE IF NOT EXISTS trades (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			side TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'open',
+			leverage INTEGER DEFAULT 0,
+			quantity REAL DEFAULT 0,
+			remaining_quantity REAL DEFAULT 0,
+			entry_price REAL DEFAULT 0,
+			exit_price REAL DEFAULT 0,
+			stop_loss REAL DEFAULT 0,
+			initial_stop_loss REAL DEFAULT 0,
+			take_profit REAL DEFAULT 0,
+			trailing_callback REAL DEFAULT 0,
+			trailing_activate REAL DEFAULT 0,
+			trailing_peak REAL DEFAULT 0,
+			trailing_native BOOLEAN DEFAULT 0,
+			take_profit_levels TEXT DEFAULT '',
+			partial_exits INTEGER DEFAULT 0,
+			open_fee REAL DEFAULT 0,
+			close_fee REAL DEFAULT 0,
+			funding_fee REAL DEFAULT 0,
+			realized_pnl REAL DEFAULT 0,
+			exit_reason TEXT DEFAULT '',
+			open_time DATETIME NOT NULL,
+			close_time DATETIME DEFAULT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_trades_trader_status ON trades(trader_id, status)`,
+
+		// 交易成交明细表（开仓、部分平仓、平仓）
+		`CREATE TABLE IF NOT EXISTS trade_fills (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trade_id INTEGER NOT NULL,
+			trader_id TEXT NOT NULL,
+			type TEXT NOT NULL,
+			order_id TEXT DEFAULT '',
+			price REAL DEFAULT 0,
+			quantity REAL DEFAULT 0,
+			fee REAL DEFAULT 0,
+			realized_pnl REAL DEFAULT 0,
+			reason TEXT DEFAULT '',
+			time DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_trade_fills_trade ON trade_fills(trade_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_trade_fills_order ON trade_fills(trader_id, order_id)`,
+
+		// 未成交的限价开仓挂单（进程重启后恢复跟踪，成交后补挂止盈止损）
+		`CREATE TABLE IF NOT EXISTS pending_entries (
+			trader_id TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			side TEXT NOT NULL,
+			order_id TEXT NOT NULL,
+			data TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (trader_id, symbol, side)
+		)`,
+
+		// AI调用用量表（按交易员/日期/模型累计token和费用）
+		`CREATE TABLE IF NOT EXISTS ai_usage (
+			trader_id TEXT NOT NULL,
+			date TEXT NOT NULL,
+			provider TEXT NOT NULL,
+			model TEXT NOT NULL,
+			calls INTEGER DEFAULT 0,
+			prompt_tokens INTEGER DEFAULT 0,
+			completion_tokens INTEGER DEFAULT 0,
+			cached_tokens INTEGER DEFAULT 0,
+			cost_usd REAL DEFAULT 0,
+			latency_ms INTEGER DEFAULT 0,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (trader_id, date, provider, model)
+		)`,
+
+		// 风控熔断状态（日盈亏基准、峰值净值、熔断暂停，进程重启后恢复）
+		`CREATE TABLE IF NOT EXISTS trader_risk_state (
+			trader_id TEXT PRIMARY KEY,
+			day_start TEXT NOT NULL,
+			day_start_equity REAL DEFAULT 0,
+			peak_equity REAL DEFAULT 0,
+			risk_tripped BOOLEAN DEFAULT 0,
+			stop_until TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
 		// 触发器：自动更新 updated_at
 		`CREATE TRIGGER IF NOT EXISTS update_users_updated_at
 			AFTER UPDATE ON users
@@ -199,6 +286,27 @@ func (d *Database) createTables() error {
 		`ALTER TABLE traders ADD COLUMN use_coin_pool BOOLEAN DEFAULT 0`,               // 是否使用COIN POOL信号源
 		`ALTER TABLE traders ADD COLUMN use_oi_top BOOLEAN DEFAULT 0`,                  // 是否使用OI TOP信号源
 		`ALTER TABLE traders ADD COLUMN system_prompt_template TEXT DEFAULT 'default'`, // 系统提示词模板名称
+		`ALTER TABLE traders ADD COLUMN risk_max_position_pct REAL DEFAULT 0`,          // 单笔仓位价值上限（占净值%，0=不限制）
+		`ALTER TABLE traders ADD COLUMN risk_max_margin_usage_pct REAL DEFAULT 0`,      // 总保证金使用率上限（%，0=不限制）
+		`ALTER TABLE traders ADD COLUMN risk_max_positions INTEGER DEFAULT 0`,          // 同时持仓数量上限（0=不限制）
+		`ALTER TABLE traders ADD COLUMN risk_min_risk_reward REAL DEFAULT 0`,           // 最小风险回报比（0=不限制）
+		`ALTER TABLE traders ADD COLUMN risk_usd_tolerance_pct REAL DEFAULT 0`,         // 止损隐含亏损与risk_usd允许偏差（%，0=不检查）
+		`ALTER TABLE traders ADD COLUMN risk_clamp_oversize BOOLEAN DEFAULT 0`,         // 超限时缩减仓位而不是拒绝
+		`ALTER TABLE traders ADD COLUMN fallback_ai_model_ids TEXT DEFAULT ''`,         // 备用AI模型ID列表（逗号分隔，按顺序切换）
+		`ALTER TABLE traders ADD COLUMN signal_providers TEXT DEFAULT ''`,              // 可插拔信号源配置（JSON数组）
+		`ALTER TABLE traders ADD COLUMN timeframes TEXT DEFAULT ''`,                    // K线周期（逗号分隔，为空使用默认3m,4h）
+		`ALTER TABLE traders ADD COLUMN indicators TEXT DEFAULT ''`,                    // 注册指标列表（如 adx(14),vwap，为空使用模板声明）
+		`ALTER TABLE traders ADD COLUMN risk_max_slippage_pct REAL DEFAULT 0`,          // 按盘口估算的市价滑点上限（%，0=不检查）
+		`ALTER TABLE traders ADD COLUMN break_even_trigger_r REAL DEFAULT 0`,           // 浮盈达到初始风险R倍时移动止损到保本价（0=不按R触发）
+		`ALTER TABLE traders ADD COLUMN break_even_trigger_pct REAL DEFAULT 0`,         // 浮盈达到入场价百分比时移动止损到保本价（0=不按百分比触发）
+		`ALTER TABLE traders ADD COLUMN paper_price_exchange TEXT DEFAULT ''`,          // 模拟盘行情来源交易所（为空使用binance）
+		`ALTER TABLE trades ADD COLUMN trailing_callback REAL DEFAULT 0`,               // 跟踪止损回调比例（%）
+		`ALTER TABLE trades ADD COLUMN trailing_activate REAL DEFAULT 0`,               // 跟踪止损激活价
+		`ALTER TABLE trades ADD COLUMN trailing_peak REAL DEFAULT 0`,                   // 本地跟踪止损极值价
+		`ALTER TABLE trades ADD COLUMN trailing_native BOOLEAN DEFAULT 0`,              // 是否为交易所原生跟踪单
+		`ALTER TABLE trades ADD COLUMN take_profit_levels TEXT DEFAULT ''`,             // 分批止盈档位（JSON数组）
+		`ALTER TABLE trades ADD COLUMN partial_exits INTEGER DEFAULT 0`,                // 部分平仓次数（不含最终平仓）
+		`ALTER TABLE trades ADD COLUMN initial_stop_loss REAL DEFAULT 0`,               // 开仓时的止损价（保本止损按R倍数触发时使用）
 		`ALTER TABLE ai_models ADD COLUMN custom_api_url TEXT DEFAULT ''`,              // 自定义API地址
 		`ALTER TABLE ai_models ADD COLUMN custom_model_name TEXT DEFAULT ''`,           // 自定义模型名称
 	}
@@ -241,6 +349,7 @@ func (d *Database) initDefaultData() error {
 		{"googleai", "Google AI", "googleai"},
 		{"chatgpt", "ChatGPT", "chatgpt"},
 		{"gpts", "GPTs (Assistant API)", "gpts"},
+		{"anthropic", "Anthropic Claude", "anthropic"},
 	}
 
 	for _, model := range aiModels {
@@ -262,6 +371,10 @@ func (d *Database) initDefaultData() error {
 		{"hyperliquid", "Hyperliquid", "dex"},
 		{"aster", "Aster DEX", "dex"},
 		{"okx", "OKX Futures", "cex"},
+		{"bybit", "Bybit Futures", "cex"},
+		{"bitget", "Bitget Futures", "cex"},
+		{"gate", "Gate.io Futures", "cex"},
+		{"paper", "Paper Trading", "cex"}, // 模拟盘（本地撮合，无需API密钥）
 	}
 
 	for _, exchange := range exchanges {
@@ -285,9 +398,15 @@ func (d *Database) initDefaultData() error {
 		"max_daily_loss":       "10.0",                                                                                // 最大日损失百分比
 		"max_drawdown":         "20.0",                                                                                // 最大回撤百分比
 		"stop_trading_minutes": "60",                                                                                  // 停止交易时间（分钟）
+		"flatten_on_risk_trip": "false",                                                                               // 触发风控熔断时是否强制平仓
 		"btc_eth_leverage":     "5",                                                                                   // BTC/ETH杠杆倍数
 		"altcoin_leverage":     "5",                                                                                   // 山寨币杠杆倍数
 		"jwt_secret":           "",                                                                                    // JWT密钥，默认为空，由config.json或系统生成
+		"ai_price_table":       "",                                                                                    // AI模型价格覆盖（JSON，美元/百万token），为空使用内置价格表
+		"local_screener":       "true",                                                                                // 未配置币种池API且关闭默认币种时，使用本地筛选器生成候选币种
+		"screener_min_volume":  "50000000",                                                                            // 本地筛选器最小24h成交额（USDT）
+		"screener_min_days":    "30",                                                                                  // 本地筛选器最小上线天数
+		"screener_exclude":     "",                                                                                    // 本地筛选器排除的币种（逗号分隔）
 	}
 
 	for key, value := range systemConfigs {
@@ -454,6 +573,34 @@ type TraderRecord struct {
 	IsCrossMargin        bool      `json:"is_cross_margin"`        // 是否为全仓模式（true=全仓，false=逐仓）
 	CreatedAt            time.Time `json:"created_at"`
 	UpdatedAt            time.Time `json:"updated_at"`
+
+	// 开仓前风控（0表示不启用）
+	RiskMaxPositionPct    float64 `json:"risk_max_position_pct"`     // 单笔仓位价值上限（占净值%）
+	RiskMaxMarginUsagePct float64 `json:"risk_max_margin_usage_pct"` // 总保证金使用率上限（%）
+	RiskMaxPositions      int     `json:"risk_max_positions"`        // 同时持仓数量上限
+	RiskMinRiskReward     float64 `json:"risk_min_risk_reward"`      // 最小风险回报比
+	RiskUSDTolerancePct   float64 `json:"risk_usd_tolerance_pct"`    // 止损隐含亏损与risk_usd允许偏差（%）
+	RiskClampOversize     bool    `json:"risk_clamp_oversize"`       // 超限时缩减仓位而不是拒绝
+	RiskMaxSlippagePct    float64 `json:"risk_max_slippage_pct"`     // 按盘口估算的市价滑点上限（%）
+
+	// 保本止损（浮盈达到阈值后自动将止损移到扣除手续费的保本价，两项均为0表示不启用）
+	BreakEvenTriggerR   float64 `json:"break_even_trigger_r"`   // 浮盈达到初始风险的R倍
+	BreakEvenTriggerPct float64 `json:"break_even_trigger_pct"` // 浮盈达到入场价的百分比（价格变动%，不含杠杆）
+
+	// AI备用链（主模型失败或熔断时按顺序切换，空表示不启用）
+	FallbackAIModelIDs string `json:"fallback_ai_model_ids"` // 备用AI模型ID列表（逗号分隔）
+
+	// 可插拔信号源（JSON数组，为空表示使用默认币种/AI500+OI Top）
+	SignalProviders string `json:"signal_providers"`
+
+	// K线周期（逗号分隔，如 "15m,1h,1d"，为空表示使用默认 3m,4h）
+	Timeframes string `json:"timeframes"`
+
+	// 注册指标（如 "adx(14), supertrend(10,3)@1h"，为空表示使用提示词模板声明的指标）
+	Indicators string `json:"indicators"`
+
+	// 模拟盘行情来源交易所（binance/okx/bybit/bitget/gate，为空表示binance，仅 paper 交易所使用）
+	PaperPriceExchange string `json:"paper_price_exchange"`
 }
 
 // UserSignalSource 用户信号源配置
@@ -654,7 +801,7 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 
 	// 没有找到任何现有配置，创建新的
 	// 推断 provider（从 id 中提取，或者直接使用 id）
-	if provider == id && (provider == "deepseek" || provider == "qwen" || provider == "googleai" || provider == "chatgpt") {
+	if provider == id && (provider == "deepseek" || provider == "qwen" || provider == "googleai" || provider == "chatgpt" || provider == "anthropic") {
 		// id 本身就是 provider
 		provider = id
 	} else {
@@ -684,6 +831,8 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 			name = "ChatGPT"
 		} else if provider == "gpts" {
 			name = "GPTs (Assistant API)"
+		} else if provider == "anthropic" {
+			name = "Anthropic Claude"
 		} else {
 			name = provider + " AI"
 		}
@@ -786,6 +935,18 @@ func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secre
 		} else if id == "okx" {
 			name = "OKX Futures"
 			typ = "cex"
+		} else if id == "bybit" {
+			name = "Bybit Futures"
+			typ = "cex"
+		} else if id == "bitget" {
+			name = "Bitget Futures"
+			typ = "cex"
+		} else if id == "gate" {
+			name = "Gate.io Futures"
+			typ = "cex"
+		} else if id == "paper" {
+			name = "Paper Trading"
+			typ = "cex"
 		} else {
 			name = id + " Exchange"
 			typ = "cex"
@@ -903,9 +1064,15 @@ func (d *Database) CreateTrader(trader *TraderRecord) error {
 		trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance)
 	
 	_, err = d.db.Exec(`
-		INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin)
+		INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin,
+			risk_max_position_pct, risk_max_margin_usage_pct, risk_max_positions, risk_min_risk_reward, risk_usd_tolerance_pct, risk_clamp_oversize,
+			fallback_ai_model_ids, signal_providers, timeframes, indicators, risk_max_slippage_pct,
+			break_even_trigger_r, break_even_trigger_pct, paper_price_exchange)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin,
+		trader.RiskMaxPositionPct, trader.RiskMaxMarginUsagePct, trader.RiskMaxPositions, trader.RiskMinRiskReward, trader.RiskUSDTolerancePct, trader.RiskClampOversize,
+		trader.FallbackAIModelIDs, trader.SignalProviders, trader.Timeframes, trader.Indicators, trader.RiskMaxSlippagePct,
+		trader.BreakEvenTriggerR, trader.BreakEvenTriggerPct, trader.PaperPriceExchange)
 	
 	if err != nil {
 		log.Printf("❌ [数据库] INSERT失败: ID=%s, UserID=%s, error=%v", trader.ID, trader.UserID, err)
@@ -930,7 +1097,14 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 		       COALESCE(use_coin_pool, 0) as use_coin_pool, COALESCE(use_oi_top, 0) as use_oi_top,
 		       COALESCE(custom_prompt, '') as custom_prompt, COALESCE(override_base_prompt, 0) as override_base_prompt,
 		       COALESCE(system_prompt_template, 'default') as system_prompt_template,
-		       COALESCE(is_cross_margin, 1) as is_cross_margin, created_at, updated_at
+		       COALESCE(is_cross_margin, 1) as is_cross_margin,
+		       COALESCE(risk_max_position_pct, 0), COALESCE(risk_max_margin_usage_pct, 0), COALESCE(risk_max_positions, 0),
+		       COALESCE(risk_min_risk_reward, 0), COALESCE(risk_usd_tolerance_pct, 0), COALESCE(risk_clamp_oversize, 0),
+		       COALESCE(fallback_ai_model_ids, ''), COALESCE(signal_providers, ''), COALESCE(timeframes, ''),
+		       COALESCE(indicators, ''), COALESCE(risk_max_slippage_pct, 0),
+		       COALESCE(break_even_trigger_r, 0), COALESCE(break_even_trigger_pct, 0),
+		       COALESCE(paper_price_exchange, ''),
+		       created_at, updated_at
 		FROM traders WHERE user_id = ? ORDER BY created_at DESC
 	`, userID)
 	if err != nil {
@@ -948,6 +1122,12 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 			&trader.UseCoinPool, &trader.UseOITop,
 			&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
 			&trader.IsCrossMargin,
+			&trader.RiskMaxPositionPct, &trader.RiskMaxMarginUsagePct, &trader.RiskMaxPositions,
+			&trader.RiskMinRiskReward, &trader.RiskUSDTolerancePct, &trader.RiskClampOversize,
+			&trader.FallbackAIModelIDs, &trader.SignalProviders, &trader.Timeframes,
+			&trader.Indicators, &trader.RiskMaxSlippagePct,
+			&trader.BreakEvenTriggerR, &trader.BreakEvenTriggerPct,
+			&trader.PaperPriceExchange,
 			&trader.CreatedAt, &trader.UpdatedAt,
 		)
 		if err != nil {
@@ -973,13 +1153,27 @@ func (d *Database) UpdateTrader(trader *TraderRecord) error {
 			scan_interval_minutes = ?, btc_eth_leverage = ?, altcoin_leverage = ?,
 			trading_symbols = ?, custom_prompt = ?, override_base_prompt = ?,
 			system_prompt_template = ?, is_cross_margin = ?, 
-			use_coin_pool = ?, use_oi_top = ?, updated_at = CURRENT_TIMESTAMP
+			use_coin_pool = ?, use_oi_top = ?,
+			risk_max_position_pct = ?, risk_max_margin_usage_pct = ?, risk_max_positions = ?,
+			risk_min_risk_reward = ?, risk_usd_tolerance_pct = ?, risk_clamp_oversize = ?,
+			fallback_ai_model_ids = ?, signal_providers = ?, timeframes = ?,
+			indicators = ?, risk_max_slippage_pct = ?,
+			break_even_trigger_r = ?, break_even_trigger_pct = ?,
+			paper_price_exchange = ?,
+			updated_at = CURRENT_TIMESTAMP
 		WHERE id = ? AND user_id = ?
 	`, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance,
 		trader.ScanIntervalMinutes, trader.BTCETHLeverage, trader.AltcoinLeverage,
 		trader.TradingSymbols, trader.CustomPrompt, trader.OverrideBasePrompt,
 		trader.SystemPromptTemplate, trader.IsCrossMargin,
-		trader.UseCoinPool, trader.UseOITop, trader.ID, trader.UserID)
+		trader.UseCoinPool, trader.UseOITop,
+		trader.RiskMaxPositionPct, trader.RiskMaxMarginUsagePct, trader.RiskMaxPositions,
+		trader.RiskMinRiskReward, trader.RiskUSDTolerancePct, trader.RiskClampOversize,
+		trader.FallbackAIModelIDs, trader.SignalProviders, trader.Timeframes,
+		trader.Indicators, trader.RiskMaxSlippagePct,
+		trader.BreakEvenTriggerR, trader.BreakEvenTriggerPct,
+		trader.PaperPriceExchange,
+		trader.ID, trader.UserID)
 	return err
 }
 
@@ -1019,6 +1213,12 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 			COALESCE(t.override_base_prompt, 0) as override_base_prompt,
 			COALESCE(t.system_prompt_template, 'default') as system_prompt_template,
 			COALESCE(t.is_cross_margin, 1) as is_cross_margin,
+			COALESCE(t.risk_max_position_pct, 0), COALESCE(t.risk_max_margin_usage_pct, 0), COALESCE(t.risk_max_positions, 0),
+			COALESCE(t.risk_min_risk_reward, 0), COALESCE(t.risk_usd_tolerance_pct, 0), COALESCE(t.risk_clamp_oversize, 0),
+			COALESCE(t.fallback_ai_model_ids, ''), COALESCE(t.signal_providers, ''), COALESCE(t.timeframes, ''),
+			COALESCE(t.indicators, ''), COALESCE(t.risk_max_slippage_pct, 0),
+			COALESCE(t.break_even_trigger_r, 0), COALESCE(t.break_even_trigger_pct, 0),
+			COALESCE(t.paper_price_exchange, ''),
 			t.created_at, t.updated_at,
 			a.id, a.user_id, a.name, a.provider, a.enabled, a.api_key,
 			COALESCE(a.custom_api_url, '') as custom_api_url,
@@ -1041,6 +1241,12 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 		&trader.UseCoinPool, &trader.UseOITop,
 		&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
 		&trader.IsCrossMargin,
+		&trader.RiskMaxPositionPct, &trader.RiskMaxMarginUsagePct, &trader.RiskMaxPositions,
+		&trader.RiskMinRiskReward, &trader.RiskUSDTolerancePct, &trader.RiskClampOversize,
+		&trader.FallbackAIModelIDs, &trader.SignalProviders, &trader.Timeframes,
+		&trader.Indicators, &trader.RiskMaxSlippagePct,
+		&trader.BreakEvenTriggerR, &trader.BreakEvenTriggerPct,
+		&trader.PaperPriceExchange,
 		&trader.CreatedAt, &trader.UpdatedAt,
 		&aiModel.ID, &aiModel.UserID, &aiModel.Name, &aiModel.Provider, &aiModel.Enabled, &aiModel.APIKey,
 		&aiModel.CustomAPIURL, &aiModel.CustomModelName,
@@ -1136,6 +1342,30 @@ func (d *Database) GetCustomCoins() []string {
 	return symbols
 }
 
+// GetTimeframes 获取所有交易员使用的K线周期（包含默认周期，按周期从短到长排序）
+func (d *Database) GetTimeframes() []string {
+	var joined string
+	_ = d.db.QueryRow(`
+		SELECT COALESCE(GROUP_CONCAT(timeframes, ','), '')
+		FROM traders WHERE timeframes != ''
+	`).Scan(&joined)
+
+	timeframes := append([]string(nil), market.DefaultTimeframes...)
+	for _, tf := range strings.Split(joined, ",") {
+		tf = strings.ToLower(strings.TrimSpace(tf))
+		if _, ok := market.IntervalDuration(tf); !ok || slices.Contains(timeframes, tf) {
+			continue
+		}
+		timeframes = append(timeframes, tf)
+	}
+	slices.SortFunc(timeframes, func(a, b string) int {
+		durA, _ := market.IntervalDuration(a)
+		durB, _ := market.IntervalDuration(b)
+		return int(durA - durB)
+	})
+	return timeframes
+}
+
 // Close 关闭数据库连接
 func (d *Database) Close() error {
 	return d.db.Close()
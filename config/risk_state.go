@@ -0,0 +1,47 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RiskState 交易员风控熔断状态（进程重启后恢复，避免熔断暂停失效、回撤基准被重置）
+type RiskState struct {
+	DayStart       time.Time // 日盈亏统计周期起点（UTC零点）
+	DayStartEquity float64   // 当日起始净值
+	PeakEquity     float64   // 峰值净值（回撤基准）
+	RiskTripped    bool      // 是否处于风控熔断暂停中
+	StopUntil      time.Time // 熔断暂停至
+}
+
+// SaveRiskState 保存交易员风控状态
+func (d *Database) SaveRiskState(traderID string, state RiskState) error {
+	_, err := d.db.Exec(`INSERT OR REPLACE INTO trader_risk_state
+		(trader_id, day_start, day_start_equity, peak_equity, risk_tripped, stop_until, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, datetime('now'))`,
+		traderID, state.DayStart.UTC().Format(time.RFC3339), state.DayStartEquity, state.PeakEquity,
+		state.RiskTripped, state.StopUntil.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("保存风控状态失败: %w", err)
+	}
+	return nil
+}
+
+// GetRiskState 获取交易员风控状态（没有记录时返回nil）
+func (d *Database) GetRiskState(traderID string) (*RiskState, error) {
+	var state RiskState
+	var dayStart, stopUntil string
+	err := d.db.QueryRow(`SELECT day_start, day_start_equity, peak_equity, risk_tripped, stop_until
+		FROM trader_risk_state WHERE trader_id = ?`, traderID).
+		Scan(&dayStart, &state.DayStartEquity, &state.PeakEquity, &state.RiskTripped, &stopUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询风控状态失败: %w", err)
+	}
+	state.DayStart, _ = time.Parse(time.RFC3339, dayStart)
+	state.StopUntil, _ = time.Parse(time.RFC3339, stopUntil)
+	return &state, nil
+}
@@ -0,0 +1,525 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 交易状态
+const (
+	TradeStatusOpen   = "open"
+	TradeStatusClosed = "closed"
+)
+
+// 成交类型
+const (
+	TradeFillOpen         = "open"
+	TradeFillPartialClose = "partial_close"
+	TradeFillClose        = "close"
+)
+
+// 平仓原因
+const (
+	TradeExitAI          = "ai"            // AI决策平仓
+	TradeExitStopLoss    = "stop_loss"     // 交易所止损单触发
+	TradeExitTakeProfit  = "take_profit"   // 交易所止盈单触发
+	TradeExitLiquidation = "liquidation"   // 强平
+	TradeExitDrawdown    = "drawdown"      // 持仓回撤监控平仓
+	TradeExitTrailing    = "trailing_stop" // 跟踪止损触发（交易所原生跟踪单或本地跟踪引擎）
+	TradeExitRiskFlatten = "risk_flatten"  // 风控熔断强制平仓
+	TradeExitExchange    = "exchange"      // 交易所侧平仓（原因未知，对账发现）
+)
+
+// tradeQtyEpsilon 剩余数量小于该比例视为全部平仓（避免浮点误差）
+const tradeQtyEpsilon = 1e-6
+
+// TradeRecord 交易台账记录（一笔完整持仓：开仓→部分平仓→平仓）
+type TradeRecord struct {
+	ID                int64             `json:"id"`
+	TraderID          string            `json:"trader_id"`
+	Symbol            string            `json:"symbol"`
+	Side              string            `json:"side"`   // long / short
+	Status            string            `json:"status"` // open / closed
+	Leverage          int               `json:"leverage"`
+	Quantity          float64           `json:"quantity"`                     // 累计开仓数量
+	RemainingQuantity float64           `json:"remaining_quantity"`           // 未平仓数量
+	EntryPrice        float64           `json:"entry_price"`                  // 开仓均价
+	ExitPrice         float64           `json:"exit_price"`                   // 平仓均价
+	StopLoss          float64           `json:"stop_loss"`                    // 当前止损价（用于推断交易所侧平仓原因）
+	InitialStopLoss   float64           `json:"initial_stop_loss"`            // 开仓时的止损价（计算初始风险R，保本止损使用）
+	TakeProfit        float64           `json:"take_profit"`                  // 当前止盈价
+	TrailingCallback  float64           `json:"trailing_callback"`            // 跟踪止损回调比例（%），0表示未设置
+	TrailingActivate  float64           `json:"trailing_activate"`            // 跟踪止损激活价，0表示立即激活
+	TrailingPeak      float64           `json:"trailing_peak"`                // 本地跟踪引擎记录的极值价（多单最高价/空单最低价）
+	TrailingNative    bool              `json:"trailing_native"`              // 是否为交易所原生跟踪止损单
+	TakeProfitLevels  []TakeProfitLevel `json:"take_profit_levels,omitempty"` // 分批止盈档位（未使用分批止盈时为空）
+	PartialExits      int               `json:"partial_exits"`                // 部分平仓次数（分批止盈、部分平仓，不含最终平仓）
+	OpenFee           float64           `json:"open_fee"`                     // 开仓手续费
+	CloseFee          float64           `json:"close_fee"`                    // 平仓手续费
+	FundingFee        float64           `json:"funding_fee"`                  // 资金费（正数为收入，负数为支出）
+	RealizedPnL       float64           `json:"realized_pnl"`                 // 已实现价差盈亏（不含手续费和资金费）
+	ExitReason        string            `json:"exit_reason"`
+	OpenTime          time.Time         `json:"open_time"`
+	CloseTime         *time.Time        `json:"close_time,omitempty"`
+	Fills             []TradeFill       `json:"fills,omitempty"`
+}
+
+// NetPnL 净盈亏 = 价差盈亏 - 手续费 + 资金费
+func (t *TradeRecord) NetPnL() float64 {
+	return t.RealizedPnL - t.OpenFee - t.CloseFee + t.FundingFee
+}
+
+// TakeProfitLevel 分批止盈档位（实际挂出的价格和数量）
+type TakeProfitLevel struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+// TradeFill 成交明细
+type TradeFill struct {
+	ID          int64     `json:"id"`
+	TradeID     int64     `json:"trade_id"`
+	Type        string    `json:"type"` // open / partial_close / close
+	OrderID     string    `json:"order_id"`
+	Price       float64   `json:"price"`
+	Quantity    float64   `json:"quantity"`
+	Fee         float64   `json:"fee"`
+	RealizedPnL float64   `json:"realized_pnl"`
+	Reason      string    `json:"reason"`
+	Time        time.Time `json:"time"`
+}
+
+// TradeStats 交易台账汇总统计
+type TradeStats struct {
+	OpenedTrades int     `json:"opened_trades"`
+	ClosedTrades int     `json:"closed_trades"`
+	RealizedPnL  float64 `json:"realized_pnl"`
+	TotalFees    float64 `json:"total_fees"`
+	TotalFunding float64 `json:"total_funding"`
+
+	PartialExits   int     `json:"partial_exits"`    // 部分平仓成交次数（分批止盈、部分平仓）
+	PartialExitPnL float64 `json:"partial_exit_pnl"` // 部分平仓已实现价差盈亏
+}
+
+const tradeColumns = `id, trader_id, symbol, side, status, leverage, quantity, remaining_quantity,
+	entry_price, exit_price, stop_loss, initial_stop_loss, take_profit, trailing_callback, trailing_activate, trailing_peak, trailing_native,
+	take_profit_levels, partial_exits, open_fee, close_fee, funding_fee, realized_pnl, exit_reason, open_time, close_time`
+
+func scanTrade(scanner interface{ Scan(dest ...any) error }) (*TradeRecord, error) {
+	var t TradeRecord
+	var closeTime sql.NullTime
+	var levels sql.NullString
+	err := scanner.Scan(&t.ID, &t.TraderID, &t.Symbol, &t.Side, &t.Status, &t.Leverage, &t.Quantity, &t.RemainingQuantity,
+		&t.EntryPrice, &t.ExitPrice, &t.StopLoss, &t.InitialStopLoss, &t.TakeProfit, &t.TrailingCallback, &t.TrailingActivate, &t.TrailingPeak, &t.TrailingNative,
+		&levels, &t.PartialExits, &t.OpenFee, &t.CloseFee, &t.FundingFee, &t.RealizedPnL, &t.ExitReason, &t.OpenTime, &closeTime)
+	if err != nil {
+		return nil, err
+	}
+	if levels.String != "" {
+		if err := json.Unmarshal([]byte(levels.String), &t.TakeProfitLevels); err != nil {
+			return nil, fmt.Errorf("解析分批止盈档位失败: %w", err)
+		}
+	}
+	if closeTime.Valid {
+		t.CloseTime = &closeTime.Time
+	}
+	return &t, nil
+}
+
+// GetOpenTrade 获取交易员指定币种方向的未平仓交易，不存在返回nil
+func (d *Database) GetOpenTrade(traderID, symbol, side string) (*TradeRecord, error) {
+	row := d.db.QueryRow(`SELECT `+tradeColumns+` FROM trades
+		WHERE trader_id = ? AND symbol = ? AND side = ? AND status = ?
+		ORDER BY id DESC LIMIT 1`, traderID, symbol, side, TradeStatusOpen)
+	trade, err := scanTrade(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询未平仓交易失败: %w", err)
+	}
+	return trade, nil
+}
+
+// GetOpenTrades 获取交易员所有未平仓交易
+func (d *Database) GetOpenTrades(traderID string) ([]*TradeRecord, error) {
+	return d.queryTrades(`SELECT `+tradeColumns+` FROM trades
+		WHERE trader_id = ? AND status = ? ORDER BY open_time`, traderID, TradeStatusOpen)
+}
+
+// GetClosedTrades 获取交易员已平仓交易（按平仓时间升序，limit<=0 表示全部，否则返回最近limit笔）
+func (d *Database) GetClosedTrades(traderID string, limit int) ([]*TradeRecord, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	trades, err := d.queryTrades(`SELECT `+tradeColumns+` FROM trades
+		WHERE trader_id = ? AND status = ? ORDER BY close_time DESC, id DESC LIMIT ?`, traderID, TradeStatusClosed, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(trades)-1; i < j; i, j = i+1, j-1 {
+		trades[i], trades[j] = trades[j], trades[i]
+	}
+	return trades, nil
+}
+
+// GetTrades 获取交易员最近的交易（含未平仓，按开仓时间倒序），并加载成交明细
+func (d *Database) GetTrades(traderID string, limit int) ([]*TradeRecord, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	trades, err := d.queryTrades(`SELECT `+tradeColumns+` FROM trades
+		WHERE trader_id = ? ORDER BY open_time DESC, id DESC LIMIT ?`, traderID, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, trade := range trades {
+		if trade.Fills, err = d.GetTradeFills(trade.ID); err != nil {
+			return nil, err
+		}
+	}
+	return trades, nil
+}
+
+func (d *Database) queryTrades(query string, args ...any) ([]*TradeRecord, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询交易台账失败: %w", err)
+	}
+	defer rows.Close()
+
+	var trades []*TradeRecord
+	for rows.Next() {
+		trade, err := scanTrade(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取交易台账失败: %w", err)
+		}
+		trades = append(trades, trade)
+	}
+	return trades, rows.Err()
+}
+
+// GetTradeFills 获取交易的成交明细（按时间升序）
+func (d *Database) GetTradeFills(tradeID int64) ([]TradeFill, error) {
+	rows, err := d.db.Query(`SELECT id, trade_id, type, order_id, price, quantity, fee, realized_pnl, reason, time
+		FROM trade_fills WHERE trade_id = ? ORDER BY time, id`, tradeID)
+	if err != nil {
+		return nil, fmt.Errorf("查询成交明细失败: %w", err)
+	}
+	defer rows.Close()
+
+	var fills []TradeFill
+	for rows.Next() {
+		var f TradeFill
+		if err := rows.Scan(&f.ID, &f.TradeID, &f.Type, &f.OrderID, &f.Price, &f.Quantity, &f.Fee, &f.RealizedPnL, &f.Reason, &f.Time); err != nil {
+			return nil, fmt.Errorf("读取成交明细失败: %w", err)
+		}
+		fills = append(fills, f)
+	}
+	return fills, rows.Err()
+}
+
+// TradeFillExists 订单是否已记入台账（对账去重）
+func (d *Database) TradeFillExists(traderID, orderID string) (bool, error) {
+	if orderID == "" {
+		return false, nil
+	}
+	var count int
+	err := d.db.QueryRow(`SELECT COUNT(*) FROM trade_fills WHERE trader_id = ? AND order_id = ?`, traderID, orderID).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("查询成交明细失败: %w", err)
+	}
+	return count > 0, nil
+}
+
+// RecordTradeOpen 记录开仓成交（已有同方向未平仓交易时视为加仓，更新开仓均价）
+func (d *Database) RecordTradeOpen(traderID, symbol, side string, leverage int, stopLoss, takeProfit float64, fill TradeFill) (*TradeRecord, error) {
+	if fill.Quantity <= 0 || fill.Price <= 0 {
+		return nil, fmt.Errorf("开仓成交数量和价格必须大于0")
+	}
+	if fill.Time.IsZero() {
+		fill.Time = time.Now()
+	}
+	fill.Type = TradeFillOpen
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	trade, err := scanTrade(tx.QueryRow(`SELECT `+tradeColumns+` FROM trades
+		WHERE trader_id = ? AND symbol = ? AND side = ? AND status = ?
+		ORDER BY id DESC LIMIT 1`, traderID, symbol, side, TradeStatusOpen))
+	switch {
+	case err == sql.ErrNoRows:
+		trade = &TradeRecord{
+			TraderID:          traderID,
+			Symbol:            symbol,
+			Side:              side,
+			Status:            TradeStatusOpen,
+			Leverage:          leverage,
+			Quantity:          fill.Quantity,
+			RemainingQuantity: fill.Quantity,
+			EntryPrice:        fill.Price,
+			StopLoss:          stopLoss,
+			InitialStopLoss:   stopLoss,
+			TakeProfit:        takeProfit,
+			OpenFee:           fill.Fee,
+			OpenTime:          fill.Time,
+		}
+		result, err := tx.Exec(`INSERT INTO trades (trader_id, symbol, side, status, leverage, quantity, remaining_quantity,
+			entry_price, stop_loss, initial_stop_loss, take_profit, open_fee, open_time, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))`,
+			trade.TraderID, trade.Symbol, trade.Side, trade.Status, trade.Leverage, trade.Quantity, trade.RemainingQuantity,
+			trade.EntryPrice, trade.StopLoss, trade.InitialStopLoss, trade.TakeProfit, trade.OpenFee, trade.OpenTime)
+		if err != nil {
+			return nil, fmt.Errorf("写入交易台账失败: %w", err)
+		}
+		if trade.ID, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("写入交易台账失败: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("查询未平仓交易失败: %w", err)
+	default:
+		// 加仓：按数量加权更新开仓均价
+		totalQty := trade.RemainingQuantity + fill.Quantity
+		trade.EntryPrice = (trade.EntryPrice*trade.RemainingQuantity + fill.Price*fill.Quantity) / totalQty
+		trade.Quantity += fill.Quantity
+		trade.RemainingQuantity = totalQty
+		trade.OpenFee += fill.Fee
+		if leverage > 0 {
+			trade.Leverage = leverage
+		}
+		if stopLoss > 0 {
+			trade.StopLoss = stopLoss
+			if trade.InitialStopLoss <= 0 {
+				trade.InitialStopLoss = stopLoss
+			}
+		}
+		if takeProfit > 0 {
+			trade.TakeProfit = takeProfit
+		}
+		_, err := tx.Exec(`UPDATE trades SET leverage = ?, quantity = ?, remaining_quantity = ?, entry_price = ?,
+			stop_loss = ?, initial_stop_loss = ?, take_profit = ?, open_fee = ?, updated_at = datetime('now') WHERE id = ?`,
+			trade.Leverage, trade.Quantity, trade.RemainingQuantity, trade.EntryPrice,
+			trade.StopLoss, trade.InitialStopLoss, trade.TakeProfit, trade.OpenFee, trade.ID)
+		if err != nil {
+			return nil, fmt.Errorf("更新交易台账失败: %w", err)
+		}
+	}
+
+	fill.TradeID = trade.ID
+	if err := insertTradeFill(tx, traderID, &fill); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return trade, nil
+}
+
+// RecordTradeClose 记录平仓成交（fill.Quantity<=0 表示平掉剩余全部数量）
+// fill.RealizedPnL 为0时按开仓均价计算价差盈亏；没有未平仓交易时返回nil
+func (d *Database) RecordTradeClose(traderID, symbol, side string, fill TradeFill) (*TradeRecord, error) {
+	if fill.Time.IsZero() {
+		fill.Time = time.Now()
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	trade, err := scanTrade(tx.QueryRow(`SELECT `+tradeColumns+` FROM trades
+		WHERE trader_id = ? AND symbol = ? AND side = ? AND status = ?
+		ORDER BY id DESC LIMIT 1`, traderID, symbol, side, TradeStatusOpen))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询未平仓交易失败: %w", err)
+	}
+
+	if fill.Quantity <= 0 || fill.Quantity > trade.RemainingQuantity {
+		fill.Quantity = trade.RemainingQuantity
+	}
+	if fill.RealizedPnL == 0 && fill.Price > 0 {
+		if side == "long" {
+			fill.RealizedPnL = (fill.Price - trade.EntryPrice) * fill.Quantity
+		} else {
+			fill.RealizedPnL = (trade.EntryPrice - fill.Price) * fill.Quantity
+		}
+	}
+
+	closedBefore := trade.Quantity - trade.RemainingQuantity
+	if closedBefore+fill.Quantity > 0 {
+		trade.ExitPrice = (trade.ExitPrice*closedBefore + fill.Price*fill.Quantity) / (closedBefore + fill.Quantity)
+	}
+	trade.RemainingQuantity -= fill.Quantity
+	trade.RealizedPnL += fill.RealizedPnL
+	trade.CloseFee += fill.Fee
+
+	fill.Type = TradeFillPartialClose
+	if trade.RemainingQuantity <= trade.Quantity*tradeQtyEpsilon {
+		fill.Type = TradeFillClose
+		trade.RemainingQuantity = 0
+		trade.Status = TradeStatusClosed
+		trade.ExitReason = fill.Reason
+		closeTime := fill.Time
+		trade.CloseTime = &closeTime
+	} else {
+		trade.PartialExits++
+	}
+
+	_, err = tx.Exec(`UPDATE trades SET status = ?, remaining_quantity = ?, exit_price = ?, close_fee = ?,
+		realized_pnl = ?, exit_reason = ?, close_time = ?, partial_exits = ?, updated_at = datetime('now') WHERE id = ?`,
+		trade.Status, trade.RemainingQuantity, trade.ExitPrice, trade.CloseFee,
+		trade.RealizedPnL, trade.ExitReason, trade.CloseTime, trade.PartialExits, trade.ID)
+	if err != nil {
+		return nil, fmt.Errorf("更新交易台账失败: %w", err)
+	}
+
+	fill.TradeID = trade.ID
+	if err := insertTradeFill(tx, traderID, &fill); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return trade, nil
+}
+
+func insertTradeFill(tx *sql.Tx, traderID string, fill *TradeFill) error {
+	result, err := tx.Exec(`INSERT INTO trade_fills (trade_id, trader_id, type, order_id, price, quantity, fee, realized_pnl, reason, time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		fill.TradeID, traderID, fill.Type, fill.OrderID, fill.Price, fill.Quantity, fill.Fee, fill.RealizedPnL, fill.Reason, fill.Time)
+	if err != nil {
+		return fmt.Errorf("写入成交明细失败: %w", err)
+	}
+	fill.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// UpdateTradeStops 更新未平仓交易的止盈止损价（<=0 表示不修改，开仓时没有止损的交易以首次设置的止损作为初始止损）
+func (d *Database) UpdateTradeStops(traderID, symbol, side string, stopLoss, takeProfit float64) error {
+	_, err := d.db.Exec(`UPDATE trades SET
+			initial_stop_loss = CASE WHEN initial_stop_loss <= 0 AND ? > 0 THEN ? ELSE initial_stop_loss END,
+			stop_loss = CASE WHEN ? > 0 THEN ? ELSE stop_loss END,
+			take_profit = CASE WHEN ? > 0 THEN ? ELSE take_profit END,
+			updated_at = datetime('now')
+		WHERE trader_id = ? AND symbol = ? AND side = ? AND status = ?`,
+		stopLoss, stopLoss, stopLoss, stopLoss, takeProfit, takeProfit, traderID, symbol, side, TradeStatusOpen)
+	if err != nil {
+		return fmt.Errorf("更新止盈止损失败: %w", err)
+	}
+	return nil
+}
+
+// UpdateTradeTrailing 更新未平仓交易的跟踪止损参数（callback<=0 表示清除跟踪止损）
+func (d *Database) UpdateTradeTrailing(traderID, symbol, side string, callback, activate, peak float64, native bool) error {
+	_, err := d.db.Exec(`UPDATE trades SET
+			trailing_callback = ?, trailing_activate = ?, trailing_peak = ?, trailing_native = ?,
+			updated_at = datetime('now')
+		WHERE trader_id = ? AND symbol = ? AND side = ? AND status = ?`,
+		callback, activate, peak, native, traderID, symbol, side, TradeStatusOpen)
+	if err != nil {
+		return fmt.Errorf("更新跟踪止损失败: %w", err)
+	}
+	return nil
+}
+
+// UpdateTradeTakeProfitLevels 更新未平仓交易的分批止盈档位（levels为空表示改回单一止盈）
+func (d *Database) UpdateTradeTakeProfitLevels(traderID, symbol, side string, levels []TakeProfitLevel) error {
+	encoded := ""
+	if len(levels) > 0 {
+		data, err := json.Marshal(levels)
+		if err != nil {
+			return fmt.Errorf("序列化分批止盈档位失败: %w", err)
+		}
+		encoded = string(data)
+	}
+	_, err := d.db.Exec(`UPDATE trades SET take_profit_levels = ?, updated_at = datetime('now')
+		WHERE trader_id = ? AND symbol = ? AND side = ? AND status = ?`,
+		encoded, traderID, symbol, side, TradeStatusOpen)
+	if err != nil {
+		return fmt.Errorf("更新分批止盈失败: %w", err)
+	}
+	return nil
+}
+
+// SetTradeCosts 用交易所成交历史和资金费流水校正交易的手续费和资金费（重复设置是幂等的）
+func (d *Database) SetTradeCosts(tradeID int64, openFee, closeFee, funding float64) error {
+	_, err := d.db.Exec(`UPDATE trades SET open_fee = ?, close_fee = ?, funding_fee = ?, updated_at = datetime('now') WHERE id = ?`,
+		openFee, closeFee, funding, tradeID)
+	if err != nil {
+		return fmt.Errorf("更新交易费用失败: %w", err)
+	}
+	return nil
+}
+
+// GetTradeStats 获取交易员台账汇总统计
+func (d *Database) GetTradeStats(traderID string) (*TradeStats, error) {
+	var stats TradeStats
+	err := d.db.QueryRow(`SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(realized_pnl), 0),
+			COALESCE(SUM(open_fee + close_fee), 0),
+			COALESCE(SUM(funding_fee), 0)
+		FROM trades WHERE trader_id = ?`, TradeStatusClosed, traderID).
+		Scan(&stats.OpenedTrades, &stats.ClosedTrades, &stats.RealizedPnL, &stats.TotalFees, &stats.TotalFunding)
+	if err != nil {
+		return nil, fmt.Errorf("统计交易台账失败: %w", err)
+	}
+
+	err = d.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(realized_pnl), 0)
+		FROM trade_fills WHERE trader_id = ? AND type = ?`, traderID, TradeFillPartialClose).
+		Scan(&stats.PartialExits, &stats.PartialExitPnL)
+	if err != nil {
+		return nil, fmt.Errorf("统计部分平仓失败: %w", err)
+	}
+	return &stats, nil
+}
+
+// SavePendingEntry 保存未成交的开仓挂单（data 为挂单参数JSON，同币种同方向只保留最新挂单）
+func (d *Database) SavePendingEntry(traderID, symbol, side, orderID, data string) error {
+	_, err := d.db.Exec(`INSERT OR REPLACE INTO pending_entries (trader_id, symbol, side, order_id, data, updated_at)
+		VALUES (?, ?, ?, ?, ?, datetime('now'))`, traderID, symbol, side, orderID, data)
+	if err != nil {
+		return fmt.Errorf("保存开仓挂单失败: %w", err)
+	}
+	return nil
+}
+
+// DeletePendingEntry 删除开仓挂单（订单ID不一致时说明已被重新挂单，不删除）
+func (d *Database) DeletePendingEntry(traderID, symbol, side, orderID string) error {
+	_, err := d.db.Exec(`DELETE FROM pending_entries WHERE trader_id = ? AND symbol = ? AND side = ? AND order_id = ?`,
+		traderID, symbol, side, orderID)
+	if err != nil {
+		return fmt.Errorf("删除开仓挂单失败: %w", err)
+	}
+	return nil
+}
+
+// GetPendingEntries 获取交易员所有未成交的开仓挂单参数JSON
+func (d *Database) GetPendingEntries(traderID string) ([]string, error) {
+	rows, err := d.db.Query(`SELECT data FROM pending_entries WHERE trader_id = ?`, traderID)
+	if err != nil {
+		return nil, fmt.Errorf("查询开仓挂单失败: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []string
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("读取开仓挂单失败: %w", err)
+		}
+		entries = append(entries, data)
+	}
+	return entries, rows.Err()
+}
@@ -4,11 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"math"
 	"nofx/market"
 	"nofx/mcp"
 	"nofx/pool"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -37,6 +37,12 @@ type PositionInfo struct {
 	LiquidationPrice float64 `json:"liquidation_price"`
 	MarginUsed       float64 `json:"margin_used"`
 	UpdateTime       int64   `json:"update_time"` // 持仓更新时间戳（毫秒）
+
+	// 跟踪止损（未设置时为0）
+	TrailingCallbackRate    float64 `json:"trailing_callback_rate,omitempty"`    // 回调比例（%）
+	TrailingActivationPrice float64 `json:"trailing_activation_price,omitempty"` // 激活价
+	TrailingStopPrice       float64 `json:"trailing_stop_price,omitempty"`       // 本地跟踪的当前触发价（原生跟踪单或未激活时为0）
+	TrailingNative          bool    `json:"trailing_native,omitempty"`           // 是否为交易所原生跟踪单
 }
 
 // AccountInfo 账户信息
@@ -54,6 +60,9 @@ type AccountInfo struct {
 type CandidateCoin struct {
 	Symbol  string   `json:"symbol"`
 	Sources []string `json:"sources"` // 来源: "ai500" 和/或 "oi_top"
+
+	Score        float64            `json:"score,omitempty"`         // 多信号源综合评分（按权重合并）
+	SourceScores map[string]float64 `json:"source_scores,omitempty"` // 各信号源的原始评分
 }
 
 // OITopData 持仓量增长Top数据（用于AI决策参考）
@@ -73,6 +82,7 @@ type Context struct {
 	CallCount       int                     `json:"call_count"`
 	Account         AccountInfo             `json:"account"`
 	Positions       []PositionInfo          `json:"positions"`
+	PendingOrders   []PendingOrderInfo      `json:"pending_orders,omitempty"` // 未成交的限价开仓挂单
 	CandidateCoins  []CandidateCoin         `json:"candidate_coins"`
 	MarketDataMap   map[string]*market.Data `json:"-"` // 不序列化，但内部使用
 	OITopDataMap    map[string]*OITopData   `json:"-"` // OI Top数据映射
@@ -81,6 +91,20 @@ type Context struct {
 	AltcoinLeverage int                     `json:"-"` // 山寨币杠杆倍数（从配置读取）
 	Exchange        string                  `json:"-"` // 交易所ID（binance/okx等）
 	HistoryDecisions []*HistoryDecision     `json:"-"` // 历史决策记录（最近3-5次，用于连续性分析）
+	RiskLimits      RiskLimits              `json:"-"` // 开仓前风控参数（从交易员配置读取）
+	MaxLeverage     map[string]int          `json:"-"` // 交易所允许的最大杠杆（symbol -> 倍数，来自合约规则，缺失表示未知）
+
+	// SignalSource 候选币种信号源（为空时使用全局默认信号源）
+	SignalSource *pool.SignalSource `json:"-"`
+
+	// Timeframes 市场数据K线周期（为空时使用默认 3m,4h）
+	Timeframes []string `json:"-"`
+
+	// Indicators 需要计算并展示的注册指标（为空时使用提示词模板 #!indicators: 声明的指标）
+	Indicators []market.IndicatorRequest `json:"-"`
+
+	// MarketDataFunc 市场数据来源（为空时实时获取；回测时使用历史K线构建的快照，且不拉取OI Top数据）
+	MarketDataFunc func(symbol string) (*market.Data, error) `json:"-"`
 }
 
 // HistoryDecision 历史决策记录（简化版，用于传递给AI）
@@ -94,23 +118,101 @@ type HistoryDecision struct {
 // Decision AI的交易决策
 type Decision struct {
 	Symbol          string  `json:"symbol"`
-	Action          string  `json:"action"` // "open_long", "open_short", "close_long", "close_short", "update_stop_loss", "update_take_profit", "partial_close", "hold", "wait"
+	Action          string  `json:"action"` // "open_long", "open_short", "close_long", "close_short", "update_stop_loss", "update_take_profit", "partial_close", "set_trailing_stop", "hold", "wait"
 
 	// 开仓参数
 	Leverage        int     `json:"leverage,omitempty"`
 	PositionSizeUSD float64 `json:"position_size_usd,omitempty"`
 	StopLoss        float64 `json:"stop_loss,omitempty"`
 	TakeProfit      float64 `json:"take_profit,omitempty"`
+	OrderType       string  `json:"order_type,omitempty"`  // 委托类型: market(默认) / limit / post_only / ioc
+	EntryPrice      float64 `json:"entry_price,omitempty"` // 限价委托价格（非市价单必填）
 
 	// 调整参数（新增）
 	NewStopLoss     float64 `json:"new_stop_loss,omitempty"`     // 用于 update_stop_loss
 	NewTakeProfit   float64 `json:"new_take_profit,omitempty"`   // 用于 update_take_profit
+	TakeProfitLevels []TakeProfitLevel `json:"take_profit_levels,omitempty"` // 分批止盈阶梯（用于开仓和 update_take_profit，设置后代替单一止盈价）
 	ClosePercentage float64 `json:"close_percentage,omitempty"`  // 用于 partial_close (0-100)
+	CallbackRate    float64 `json:"callback_rate,omitempty"`     // 用于 set_trailing_stop，回调比例% (0.1-10)
+	ActivationPrice float64 `json:"activation_price,omitempty"`  // 用于 set_trailing_stop，激活价（可选，0表示立即跟踪）
+	PositionSide    string  `json:"position_side,omitempty"`     // 用于 set_trailing_stop，持仓方向 long/short（同币种双向持仓时必填）
 
 	// 通用参数
 	Confidence      int     `json:"confidence,omitempty"` // 信心度 (0-100)
 	RiskUSD         float64 `json:"risk_usd,omitempty"`   // 最大美元风险
 	Reasoning       string  `json:"reasoning"`
+
+	// 开仓前风控检查结果（不从AI响应解析）
+	RiskChecks []RiskCheck `json:"-"`
+}
+
+// 开仓委托类型
+const (
+	OrderTypeMarket   = "market"    // 市价单
+	OrderTypeLimit    = "limit"     // 限价单（GTC）
+	OrderTypePostOnly = "post_only" // 只做Maker的限价单，会立即成交时被交易所拒绝
+	OrderTypeIOC      = "ioc"       // 立即成交剩余撤销的限价单
+)
+
+// TakeProfitLevel 分批止盈档位
+type TakeProfitLevel struct {
+	Price      float64 `json:"price"`      // 止盈触发价
+	Percentage float64 `json:"percentage"` // 该档平仓比例（%，相对当前持仓数量）
+}
+
+// MaxTakeProfitLevels 分批止盈最多档位数
+const MaxTakeProfitLevels = 5
+
+// 跟踪止损回调比例范围（%，与币安 TRAILING_STOP_MARKET 限制一致）
+const (
+	MinTrailingCallbackRate     = 0.1
+	MaxTrailingCallbackRate     = 10.0
+	DefaultTrailingCallbackRate = 1.0 // 只给激活价时使用
+)
+
+// IsMarketOrder 是否为市价开仓（未指定委托类型时按市价处理）
+func (d *Decision) IsMarketOrder() bool {
+	return d.OrderType == "" || d.OrderType == OrderTypeMarket
+}
+
+// AverageTakeProfit 止盈加权平均价（按各档比例加权，未设置阶梯时返回单一止盈价，用于风险回报比）
+func (d *Decision) AverageTakeProfit() float64 {
+	if len(d.TakeProfitLevels) == 0 {
+		return d.TakeProfit
+	}
+	var weighted, total float64
+	for _, level := range d.TakeProfitLevels {
+		weighted += level.Price * level.Percentage
+		total += level.Percentage
+	}
+	if total <= 0 {
+		return d.TakeProfit
+	}
+	return weighted / total
+}
+
+// SortTakeProfitLevels 按离入场价由近到远排序止盈档位（多单价格升序，空单价格降序）
+func (d *Decision) SortTakeProfitLevels(isLong bool) {
+	sort.SliceStable(d.TakeProfitLevels, func(i, j int) bool {
+		if isLong {
+			return d.TakeProfitLevels[i].Price < d.TakeProfitLevels[j].Price
+		}
+		return d.TakeProfitLevels[i].Price > d.TakeProfitLevels[j].Price
+	})
+}
+
+// PendingOrderInfo 未成交的限价开仓挂单
+type PendingOrderInfo struct {
+	Symbol          string  `json:"symbol"`
+	Side            string  `json:"side"` // "long" or "short"
+	OrderType       string  `json:"order_type"`
+	Price           float64 `json:"price"`
+	Quantity        float64 `json:"quantity"`
+	Leverage        int     `json:"leverage"`
+	PositionSizeUSD float64 `json:"position_size_usd"`
+	StopLoss        float64 `json:"stop_loss"`
+	TakeProfit      float64 `json:"take_profit"`
+	AgeMinutes      int     `json:"age_minutes"` // 挂单时长（分钟）
 }
 
 // FullDecision AI的完整决策（包含思维链）
@@ -123,13 +225,17 @@ type FullDecision struct {
 }
 
 // GetFullDecision 获取AI的完整交易决策（批量分析所有币种和持仓）
-func GetFullDecision(ctx *Context, mcpClient *mcp.Client) (*FullDecision, error) {
+func GetFullDecision(ctx *Context, mcpClient mcp.AIClient) (*FullDecision, error) {
 	return GetFullDecisionWithCustomPrompt(ctx, mcpClient, "", false, "")
 }
 
 // GetFullDecisionWithCustomPrompt 获取AI的完整交易决策（支持自定义prompt和模板选择）
-func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient *mcp.Client, customPrompt string, overrideBase bool, templateName string) (*FullDecision, error) {
-	// 1. 为所有币种获取市场数据
+// mcpClient 可以是真实的 *mcp.Client，也可以是回测使用的 *mcp.MockClient
+func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient mcp.AIClient, customPrompt string, overrideBase bool, templateName string) (*FullDecision, error) {
+	// 1. 为所有币种获取市场数据（交易员未配置指标时使用模板声明的指标）
+	if len(ctx.Indicators) == 0 {
+		ctx.Indicators = templateIndicators(templateName)
+	}
 	if err := fetchMarketDataForContext(ctx); err != nil {
 		return nil, fmt.Errorf("获取市场数据失败: %w", err)
 	}
@@ -139,22 +245,22 @@ func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient *mcp.Client, custom
 	userPrompt := buildUserPrompt(ctx)
 
 	// V1.70版本：输出详细的输入提示词（用于调试和查看）
-	log.Printf("\n" + strings.Repeat("=", 80))
+	log.Print("\n" + strings.Repeat("=", 80))
 	log.Printf("📋 【系统提示词】 (System Prompt)")
-	log.Printf(strings.Repeat("=", 80))
+	log.Print(strings.Repeat("=", 80))
 	log.Printf("%s", systemPrompt)
-	log.Printf(strings.Repeat("=", 80))
+	log.Print(strings.Repeat("=", 80))
 	log.Printf("📊 【用户提示词】 (User Prompt)")
-	log.Printf(strings.Repeat("=", 80))
+	log.Print(strings.Repeat("=", 80))
 	log.Printf("%s", userPrompt)
-	log.Printf(strings.Repeat("=", 80))
+	log.Print(strings.Repeat("=", 80))
 	
 	// 计算token数量（粗略估算：中文字符数 * 1.3 + 英文字符数 * 0.25）
 	systemPromptTokens := estimateTokenCount(systemPrompt)
 	userPromptTokens := estimateTokenCount(userPrompt)
 	totalTokens := systemPromptTokens + userPromptTokens
 	log.Printf("📊 Token估算: System=%d, User=%d, Total=%d", systemPromptTokens, userPromptTokens, totalTokens)
-	log.Printf(strings.Repeat("=", 80) + "\n")
+	log.Print(strings.Repeat("=", 80) + "\n")
 
 	// 3. 调用AI API（使用 system + user prompt）
 	aiResponse, err := mcpClient.CallWithMessages(systemPrompt, userPrompt)
@@ -163,7 +269,7 @@ func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient *mcp.Client, custom
 	}
 
 	// 4. 解析AI响应
-	decision, err := parseFullDecisionResponse(aiResponse, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, ctx.MarketDataMap)
+	decision, err := parseFullDecisionResponse(aiResponse, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, ctx.MaxLeverage, ctx.MarketDataMap, ctx.MarketDataFunc != nil, newRiskEngine(ctx))
 	if err != nil {
 		return decision, fmt.Errorf("解析AI响应失败: %w", err)
 	}
@@ -208,9 +314,18 @@ func fetchMarketDataForContext(ctx *Context) error {
 		var err error
 		maxRetries := 3
 		
+		// 回测等离线场景：使用注入的数据源，不重试
+		if ctx.MarketDataFunc != nil {
+			maxRetries = 1
+		}
+
 		// 重试获取市场数据
 		for attempt := 1; attempt <= maxRetries; attempt++ {
-			data, err = market.GetWithExchange(symbol, exchangeID)
+			if ctx.MarketDataFunc != nil {
+				data, err = ctx.MarketDataFunc(symbol)
+			} else {
+				data, err = market.GetWithTimeframes(symbol, exchangeID, ctx.Timeframes)
+			}
 			if err == nil {
 				break
 			}
@@ -228,11 +343,20 @@ func fetchMarketDataForContext(ctx *Context) error {
 		}
 
 		// V1.63版本：移除流动性过滤，让AI自由选择币种
+		data.ComputeIndicators(ctx.Indicators)
 		ctx.MarketDataMap[symbol] = data
 	}
 
+	if ctx.MarketDataFunc != nil {
+		return nil
+	}
+
 	// 加载OI Top数据（不影响主流程）
-	oiPositions, err := pool.GetOITopPositions()
+	getOITopPositions := pool.GetOITopPositions
+	if ctx.SignalSource != nil {
+		getOITopPositions = ctx.SignalSource.GetOITopPositions
+	}
+	oiPositions, err := getOITopPositions()
 	if err == nil {
 		for _, pos := range oiPositions {
 			// 标准化符号匹配
@@ -251,6 +375,90 @@ func fetchMarketDataForContext(ctx *Context) error {
 	return nil
 }
 
+// formatSourceScores 按来源顺序格式化各信号源评分（如 "ai500=87.50, oi_top=12.30"）
+func formatSourceScores(coin CandidateCoin) string {
+	parts := make([]string, 0, len(coin.Sources))
+	for _, source := range coin.Sources {
+		if score, ok := coin.SourceScores[source]; ok {
+			parts = append(parts, fmt.Sprintf("%s=%.2f", source, score))
+		} else {
+			parts = append(parts, source)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatTimeframe 格式化单个K线周期的关键指标（如 "[1h] EMA20=... EMA50=... RSI14=... MACD=... ATR14=..."）
+func formatTimeframe(tf *market.TimeframeData) string {
+	parts := []string{fmt.Sprintf("[%s]", tf.Interval)}
+	if tf.Context != nil {
+		parts = append(parts, fmt.Sprintf("EMA20=%.4f EMA50=%.4f", tf.Context.EMA20, tf.Context.EMA50))
+	}
+	if tf.Series != nil {
+		if n := len(tf.Series.RSI14Values); n > 0 {
+			parts = append(parts, fmt.Sprintf("RSI14=%.1f", tf.Series.RSI14Values[n-1]))
+		}
+		if n := len(tf.Series.MACDValues); n > 0 {
+			parts = append(parts, fmt.Sprintf("MACD=%.4f", tf.Series.MACDValues[n-1]))
+		}
+	}
+	if tf.Context != nil && tf.Context.ATR14 > 0 {
+		parts = append(parts, fmt.Sprintf("ATR14=%.4f", tf.Context.ATR14))
+	}
+	return strings.Join(parts, " ")
+}
+
+// templateIndicators 解析提示词模板声明的指标（模板不存在或声明无效时返回空）
+func templateIndicators(templateName string) []market.IndicatorRequest {
+	if templateName == "" {
+		templateName = "default"
+	}
+	template, err := GetPromptTemplate(templateName)
+	if err != nil || template.Indicators == "" {
+		return nil
+	}
+	requests, err := market.ParseIndicatorSpecs(template.Indicators)
+	if err != nil {
+		log.Printf("⚠️  提示词模板 '%s' 的指标声明无效: %v", templateName, err)
+		return nil
+	}
+	return requests
+}
+
+// formatIndicator 格式化单个注册指标的最新值（如 "supertrend(10,3)@1h: supertrend=... direction=1.0000"）
+func formatIndicator(ind *market.IndicatorResult) string {
+	parts := make([]string, 0, len(ind.Outputs))
+	for _, output := range ind.Outputs {
+		if v, ok := ind.Latest(output); ok {
+			parts = append(parts, fmt.Sprintf("%s=%.4f", output, v))
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("%s: 数据不足", ind.Label)
+	}
+	return fmt.Sprintf("%s: %s", ind.Label, strings.Join(parts, " "))
+}
+
+// formatDepth 格式化盘口流动性（价差、±1%深度、失衡、各金额市价成交滑点）
+func formatDepth(depth *market.DepthFeatures) string {
+	parts := []string{
+		fmt.Sprintf("价差%.3f%%", depth.SpreadPct),
+		fmt.Sprintf("±1%%深度 买%.0f/卖%.0f USDT", depth.BidDepth1Pct, depth.AskDepth1Pct),
+		fmt.Sprintf("失衡%+.2f", depth.Imbalance),
+	}
+	for _, fill := range depth.Fills {
+		buy, sell := fmt.Sprintf("%.3f%%", fill.BuySlippagePct), fmt.Sprintf("%.3f%%", fill.SellSlippagePct)
+		if !fill.BuyFilled {
+			buy = "深度不足"
+		}
+		if !fill.SellFilled {
+			sell = "深度不足"
+		}
+		parts = append(parts, fmt.Sprintf("%.0fU滑点 买%s/卖%s", fill.Notional, buy, sell))
+	}
+	return strings.Join(parts, " | ")
+}
+
 // calculateMaxCandidates 根据账户状态计算需要分析的候选币种数量
 func calculateMaxCandidates(ctx *Context) int {
 	// ⚠️ 重要：限制候选币种数量，避免 Prompt 过大
@@ -326,11 +534,11 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 			log.Printf("❌ 无法加载任何提示词模板，使用内置简化版本")
 			sb.WriteString("你是专业的加密货币交易AI。请根据市场数据做出交易决策。\n\n")
 		} else {
-			sb.WriteString(template.Content)
+			sb.WriteString(template.Prompt())
 			sb.WriteString("\n\n")
 		}
 	} else {
-		sb.WriteString(template.Content)
+		sb.WriteString(template.Prompt())
 		sb.WriteString("\n\n")
 	}
 
@@ -343,11 +551,15 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 	sb.WriteString("- 止损必须在爆仓价上方，否则止损失效\n\n")
 	
 	sb.WriteString("# 可用动作\n\n")
-	sb.WriteString("open_long/open_short/close_long/close_short/partial_close/update_stop_loss/update_take_profit/hold/wait\n\n")
+	sb.WriteString("open_long/open_short/close_long/close_short/partial_close/update_stop_loss/update_take_profit/set_trailing_stop/hold/wait\n\n")
 	
 	sb.WriteString("# 输出格式\n\n")
 	sb.WriteString("JSON: action, symbol, leverage, position_size_usd, stop_loss, take_profit, confidence(0-100), reasoning\n")
 	sb.WriteString("开仓必填: leverage, position_size_usd, stop_loss, take_profit, confidence, reasoning\n")
+	sb.WriteString("开仓可选: order_type(market/limit/post_only/ioc，默认market), entry_price(非market必填的限价)\n")
+	sb.WriteString("💡 post_only只做Maker享受更低手续费，但价格会立即成交时被拒绝；未成交的挂单超时自动撤销\n")
+	sb.WriteString("分批止盈(可选): take_profit_levels=[{price, percentage}]，最多5档，比例合计≤100%%，开仓时代替take_profit，update_take_profit时代替new_take_profit；未分配的仓位由止损/后续决策管理\n")
+	sb.WriteString("set_trailing_stop: callback_rate(回调比例%%，0.1-10), activation_price(可选，价格到达后才开始跟踪), position_side(long/short，同币种双向持仓时必填)，从最高/最低价回撤callback_rate时平仓\n")
 	sb.WriteString("wait/hold/close操作: 可省略开仓字段或设为null\n")
 	sb.WriteString("💡 position_size_usd是仓位价值，保证金=position_size_usd/leverage\n\n")
 
@@ -429,12 +641,28 @@ func buildUserPrompt(ctx *Context) string {
 				pos.Quantity, positionValue, pos.Leverage, marginUsed))
 			sb.WriteString(fmt.Sprintf("   未实现盈亏: %+.2f USDT (%+.2f%%)\n", pos.UnrealizedPnL, pos.UnrealizedPnLPct))
 			sb.WriteString(fmt.Sprintf("   爆仓价: %.4f USDT | 持仓时长: %s\n", pos.LiquidationPrice, holdingDuration))
+			if pos.TrailingCallbackRate > 0 {
+				sb.WriteString(fmt.Sprintf("   跟踪止损: 回调 %.2f%%", pos.TrailingCallbackRate))
+				if pos.TrailingActivationPrice > 0 {
+					sb.WriteString(fmt.Sprintf(" | 激活价 %.4f", pos.TrailingActivationPrice))
+				}
+				if pos.TrailingStopPrice > 0 {
+					sb.WriteString(fmt.Sprintf(" | 当前触发价 %.4f", pos.TrailingStopPrice))
+				}
+				if pos.TrailingNative {
+					sb.WriteString(" | 交易所挂单")
+				}
+				sb.WriteString("\n")
+			}
 			
 			// 显示该币种的市场数据
 			if marketData, ok := ctx.MarketDataMap[pos.Symbol]; ok {
 				sb.WriteString(fmt.Sprintf("   市场数据: EMA20=%.2f MACD=%.4f RSI=%.1f | 1h:%+.2f%% 4h:%+.2f%%\n",
 					marketData.CurrentEMA20, marketData.CurrentMACD, marketData.CurrentRSI7,
 					marketData.PriceChange1h, marketData.PriceChange4h))
+				for _, ind := range marketData.Indicators {
+					sb.WriteString(fmt.Sprintf("   %s\n", formatIndicator(ind)))
+				}
 			}
 			sb.WriteString("\n")
 		}
@@ -442,6 +670,17 @@ func buildUserPrompt(ctx *Context) string {
 		sb.WriteString("【当前持仓】无\n\n")
 	}
 
+	// ========== 4.1 未成交挂单 ==========
+	if len(ctx.PendingOrders) > 0 {
+		sb.WriteString("【未成交挂单】（同币种同方向有挂单时不要重复开仓）\n")
+		for i, order := range ctx.PendingOrders {
+			sb.WriteString(fmt.Sprintf("%d. %s %s %s | 限价 %.4f | 数量 %.4f | 仓位 %.2f USDT | %dx | 止损 %.4f 止盈 %.4f | 已挂%d分钟\n",
+				i+1, order.Symbol, strings.ToUpper(order.Side), order.OrderType, order.Price, order.Quantity,
+				order.PositionSizeUSD, order.Leverage, order.StopLoss, order.TakeProfit, order.AgeMinutes))
+		}
+		sb.WriteString("\n")
+	}
+
 	// ========== 5. 候选币种市场数据 ==========
 	sb.WriteString(fmt.Sprintf("【候选币种市场数据】（%d个）\n", len(ctx.MarketDataMap)))
 	displayedCount := 0
@@ -461,19 +700,36 @@ func buildUserPrompt(ctx *Context) string {
 
 		// 显示更详细的市场数据
 		sb.WriteString(fmt.Sprintf("%d. %s %s\n", displayedCount, coin.Symbol, sourceTag))
+		if len(coin.SourceScores) > 0 {
+			sb.WriteString(fmt.Sprintf("   信号源: %s | 综合评分: %.2f\n", formatSourceScores(coin), coin.Score))
+		}
 		sb.WriteString(fmt.Sprintf("   价格: %.4f USDT | EMA20: %.4f | MACD: %.4f | RSI: %.1f\n",
 			marketData.CurrentPrice, marketData.CurrentEMA20, marketData.CurrentMACD, marketData.CurrentRSI7))
 		sb.WriteString(fmt.Sprintf("   1小时: %+.2f%% | 4小时: %+.2f%%\n",
 			marketData.PriceChange1h, marketData.PriceChange4h))
+		// 交易所杠杆上限低于配置上限时提示（超过会被拒绝）
+		if exchangeMax := ctx.MaxLeverage[coin.Symbol]; exchangeMax > 0 && exchangeMax < configuredMaxLeverage(coin.Symbol, ctx.BTCETHLeverage, ctx.AltcoinLeverage) {
+			sb.WriteString(fmt.Sprintf("   ⚠️ 交易所最大杠杆: %dx（杠杆不得超过此值）\n", exchangeMax))
+		}
 		
-		// 显示更多技术指标（如果可用）
-		if marketData.LongerTermContext != nil && marketData.LongerTermContext.ATR14 > 0 {
+		// 显示更多技术指标（如果可用）：按配置的K线周期逐个输出
+		if len(marketData.Timeframes) > 0 {
+			for _, tf := range marketData.Timeframes {
+				sb.WriteString(fmt.Sprintf("   %s\n", formatTimeframe(tf)))
+			}
+		} else if marketData.LongerTermContext != nil && marketData.LongerTermContext.ATR14 > 0 {
 			sb.WriteString(fmt.Sprintf("   ATR14: %.4f\n", marketData.LongerTermContext.ATR14))
 		}
+		for _, ind := range marketData.Indicators {
+			sb.WriteString(fmt.Sprintf("   %s\n", formatIndicator(ind)))
+		}
 		if marketData.BollingerBands != nil {
 			sb.WriteString(fmt.Sprintf("   布林带: 上轨=%.4f 中轨=%.4f 下轨=%.4f\n",
 				marketData.BollingerBands.Upper, marketData.BollingerBands.Middle, marketData.BollingerBands.Lower))
 		}
+		if marketData.Depth != nil {
+			sb.WriteString(fmt.Sprintf("   盘口: %s\n", formatDepth(marketData.Depth)))
+		}
 		sb.WriteString("\n")
 	}
 	sb.WriteString("\n")
@@ -600,7 +856,8 @@ func estimateTokenCount(text string) int {
 
 // parseFullDecisionResponse 解析AI的完整决策响应
 // V1.59版本：添加marketDataMap参数，用于验证高价币种
-func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthLeverage, altcoinLeverage int, marketDataMap map[string]*market.Data) (*FullDecision, error) {
+// historical 为true表示使用历史行情（回测），此时不回退到实时行情
+func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthLeverage, altcoinLeverage int, exchangeMaxLeverage map[string]int, marketDataMap map[string]*market.Data, historical bool, risk *riskEngine) (*FullDecision, error) {
 	// 1. 提取思维链
 	cotTrace := extractCoTTrace(aiResponse)
 
@@ -614,7 +871,7 @@ func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthL
 	}
 
 	// 3. 验证决策
-	if err := validateDecisions(decisions, accountEquity, btcEthLeverage, altcoinLeverage, marketDataMap); err != nil {
+	if err := validateDecisions(decisions, accountEquity, btcEthLeverage, altcoinLeverage, exchangeMaxLeverage, marketDataMap, historical, risk); err != nil {
 		return &FullDecision{
 			CoTTrace:  cotTrace,
 			Decisions: decisions,
@@ -905,8 +1162,13 @@ func compactArrayOpen(s string) string {
 
 // validateDecisions 验证所有决策（需要账户信息和杠杆配置）
 // V1.59版本：添加marketDataMap参数，根据价格判断高价币种
-func validateDecisions(decisions []Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int, marketDataMap map[string]*market.Data) error {
-	for i, decision := range decisions {
+// 风控拒绝不会使整批决策失败，只标记在对应决策的RiskChecks中（risk为nil时不做风控检查）
+func validateDecisions(decisions []Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int, exchangeMaxLeverage map[string]int, marketDataMap map[string]*market.Data, historical bool, risk *riskEngine) error {
+	if risk != nil {
+		risk.releaseClosedPositions(decisions)
+	}
+	for i := range decisions {
+		decision := &decisions[i]
 		// 获取当前价格（如果可用）
 		currentPrice := 0.0
 		if marketDataMap != nil {
@@ -916,19 +1178,31 @@ func validateDecisions(decisions []Decision, accountEquity float64, btcEthLevera
 		}
 		
 		// 如果无法获取价格，尝试从market包获取（fallback）
+		// 回测时不能混入实时价格，没有历史行情的币种直接拒绝
 		if currentPrice <= 0 && (decision.Action == "open_long" || decision.Action == "open_short") {
+			if historical {
+				return fmt.Errorf("决策 #%d 验证失败: %s 没有历史行情数据", i+1, decision.Symbol)
+			}
 			if data, err := market.Get(decision.Symbol); err == nil {
 				currentPrice = data.CurrentPrice
 			}
 		}
 		
-		if err := validateDecision(&decision, accountEquity, btcEthLeverage, altcoinLeverage, currentPrice); err != nil {
+		if err := validateDecision(decision, accountEquity, btcEthLeverage, altcoinLeverage, exchangeMaxLeverage[decision.Symbol], currentPrice, risk); err != nil {
 			return fmt.Errorf("决策 #%d 验证失败: %w", i+1, err)
 		}
 	}
 	return nil
 }
 
+// configuredMaxLeverage 配置的杠杆上限（BTC/ETH和山寨币分别配置）
+func configuredMaxLeverage(symbol string, btcEthLeverage, altcoinLeverage int) int {
+	if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+		return btcEthLeverage
+	}
+	return altcoinLeverage
+}
+
 // findMatchingBracket 查找匹配的右括号
 func findMatchingBracket(s string, start int) int {
 	if start >= len(s) || s[start] != '[' {
@@ -953,7 +1227,8 @@ func findMatchingBracket(s string, start int) int {
 
 // validateDecision 验证单个决策的有效性
 // V1.59版本：添加currentPrice参数，根据价格判断高价币种（价格>500 USDT）
-func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int, currentPrice float64) error {
+// exchangeMaxLeverage 为交易所允许的最大杠杆（<=0 表示未知，只按配置限制）
+func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage, exchangeMaxLeverage int, currentPrice float64, risk *riskEngine) error {
 	// 验证action
 	validActions := map[string]bool{
 		"open_long":          true,
@@ -963,6 +1238,7 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		"update_stop_loss":   true,
 		"update_take_profit": true,
 		"partial_close":      true,
+		"set_trailing_stop":  true,
 		"hold":               true,
 		"wait":               true,
 	}
@@ -975,11 +1251,7 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 	if d.Action == "open_long" || d.Action == "open_short" {
 		// V1.48版本：移除仓位价值上限限制 - 让AI自由决策杠杆和仓位大小
 		// 根据币种使用配置的杠杆上限（仅限制杠杆倍数，不限制仓位价值）
-		maxLeverage := altcoinLeverage          // 山寨币使用配置的杠杆
-		
-		if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
-			maxLeverage = btcEthLeverage          // BTC和ETH使用配置的杠杆
-		}
+		maxLeverage := configuredMaxLeverage(d.Symbol, btcEthLeverage, altcoinLeverage)
 		
 		// V1.64版本：进一步简化验证逻辑
 		// 只保留杠杆倍数验证，其他验证交给AI和交易所
@@ -987,7 +1259,41 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		if d.Leverage <= 0 || d.Leverage > maxLeverage {
 			return fmt.Errorf("杠杆必须在1-%d之间（%s，当前配置上限%d倍）: %d", maxLeverage, d.Symbol, maxLeverage, d.Leverage)
 		}
+		if exchangeMaxLeverage > 0 && d.Leverage > exchangeMaxLeverage {
+			return fmt.Errorf("杠杆必须在1-%d之间（%s，交易所上限%d倍）: %d", exchangeMaxLeverage, d.Symbol, exchangeMaxLeverage, d.Leverage)
+		}
+
+		// 委托类型：限价类委托以委托价格作为风控参考价
+		d.OrderType = strings.ToLower(strings.TrimSpace(d.OrderType))
+		switch d.OrderType {
+		case "", OrderTypeMarket:
+			d.OrderType = OrderTypeMarket
+		case OrderTypeLimit, OrderTypePostOnly, OrderTypeIOC:
+			if d.EntryPrice <= 0 {
+				return fmt.Errorf("%s 委托必须提供 entry_price: %.4f", d.OrderType, d.EntryPrice)
+			}
+			currentPrice = d.EntryPrice
+		default:
+			return fmt.Errorf("无效的order_type: %s", d.OrderType)
+		}
 		
+		// 分批止盈：档位按离入场价由近到远排序，最远一档作为止盈价（用于台账和挂单展示）
+		if len(d.TakeProfitLevels) > 0 {
+			if err := validateTakeProfitLevels(d.TakeProfitLevels); err != nil {
+				return err
+			}
+			d.SortTakeProfitLevels(d.Action == "open_long")
+			d.TakeProfit = d.TakeProfitLevels[len(d.TakeProfitLevels)-1].Price
+		}
+
+		// 开仓前风控：仓位价值、保证金使用率、持仓数量、止损方向、风险回报比
+		if risk != nil {
+			risk.check(d, currentPrice)
+			if d.RiskRejection() != nil {
+				return nil
+			}
+		}
+
 		// 计算保证金（用于日志记录）
 		marginRequired := d.PositionSizeUSD / float64(d.Leverage)
 		log.Printf("  ✓ 验证通过：仓位价值%.2f USDT，杠杆%d倍，保证金%.2f USDT", 
@@ -1001,9 +1307,13 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		}
 	}
 
-	// 动态调整止盈验证
+	// 动态调整止盈验证（持仓方向在执行时确定，由执行层排序档位并检查价格方向）
 	if d.Action == "update_take_profit" {
-		if d.NewTakeProfit <= 0 {
+		if len(d.TakeProfitLevels) > 0 {
+			if err := validateTakeProfitLevels(d.TakeProfitLevels); err != nil {
+				return err
+			}
+		} else if d.NewTakeProfit <= 0 {
 			return fmt.Errorf("新止盈价格必须大于0: %.2f", d.NewTakeProfit)
 		}
 	}
@@ -1015,48 +1325,61 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		}
 	}
 
+	// 跟踪止损验证：只给激活价时使用默认回调比例
+	if d.Action == "set_trailing_stop" {
+		if d.ActivationPrice < 0 {
+			return fmt.Errorf("激活价不能为负数: %.4f", d.ActivationPrice)
+		}
+		if d.CallbackRate == 0 && d.ActivationPrice > 0 {
+			d.CallbackRate = DefaultTrailingCallbackRate
+		}
+		if d.CallbackRate < MinTrailingCallbackRate || d.CallbackRate > MaxTrailingCallbackRate {
+			return fmt.Errorf("回调比例必须在%.1f-%.0f%%之间: %.2f", MinTrailingCallbackRate, MaxTrailingCallbackRate, d.CallbackRate)
+		}
+		d.PositionSide = strings.ToLower(strings.TrimSpace(d.PositionSide))
+		if d.PositionSide != "" && d.PositionSide != "long" && d.PositionSide != "short" {
+			return fmt.Errorf("持仓方向必须是 long 或 short: %s", d.PositionSide)
+		}
+	}
+
 	return nil
 }
 
-// calculateBreakEvenPrice 计算盈亏平衡价格（考虑开仓和平仓手续费）
-// entryPrice: 入场价格
-// positionSizeUSD: 名义价值（USDT）
-// leverage: 杠杆倍数（用于计算，但实际不影响盈亏平衡价）
-// isLong: true=做多, false=做空
-// 返回: 盈亏平衡的出场价格（OKX普通用户一档Taker费率0.10%）
-func calculateBreakEvenPrice(entryPrice, positionSizeUSD float64, leverage int, isLong bool) float64 {
-	// OKX普通用户一档Taker费率（市价单）
-	const takerFeeRate = 0.0010 // 0.10%
-	
-	// 计算开仓手续费
-	openFee := positionSizeUSD * takerFeeRate
-	
-	// 计算持仓数量
-	quantity := positionSizeUSD / entryPrice
-	if quantity <= 0 {
-		return entryPrice // 避免除零
+// validateTakeProfitLevels 验证分批止盈档位（价格和比例必须大于0，比例合计不超过100%）
+func validateTakeProfitLevels(levels []TakeProfitLevel) error {
+	if len(levels) > MaxTakeProfitLevels {
+		return fmt.Errorf("分批止盈最多%d档: %d", MaxTakeProfitLevels, len(levels))
+	}
+	total := 0.0
+	for i, level := range levels {
+		if level.Price <= 0 {
+			return fmt.Errorf("第%d档止盈价格必须大于0: %.4f", i+1, level.Price)
+		}
+		if level.Percentage <= 0 {
+			return fmt.Errorf("第%d档止盈比例必须大于0: %.1f", i+1, level.Percentage)
+		}
+		total += level.Percentage
+	}
+	if total > 100.01 {
+		return fmt.Errorf("分批止盈比例合计不能超过100%%: %.1f", total)
+	}
+	return nil
+}
+
+// CalculateBreakEvenPrice 计算扣除开仓和平仓手续费后的盈亏平衡价格
+// openFeeRate/closeFeeRate: 开仓、平仓手续费率（小数，如 0.0005 表示 0.05%），由交易所费率表提供
+// 做多: 出场价×(1-平仓费率) = 入场价×(1+开仓费率)
+// 做空: 出场价×(1+平仓费率) = 入场价×(1-开仓费率)
+// 返回未取整的价格，由调用方按合约价格步进取整
+func CalculateBreakEvenPrice(entryPrice float64, isLong bool, openFeeRate, closeFeeRate float64) float64 {
+	if entryPrice <= 0 {
+		return entryPrice
 	}
-	
-	// 计算平仓时的名义价值（假设价格不变）
-	closePositionSizeUSD := positionSizeUSD
-	
-	// 计算平仓手续费
-	closeFee := closePositionSizeUSD * takerFeeRate
-	
-	// 总手续费
-	totalFee := openFee + closeFee
-	
-	// 计算盈亏平衡价格
 	if isLong {
 		// 做多：需要价格上涨以覆盖手续费
-		// 盈亏平衡价 = 入场价 + (总手续费 / 数量)
-		breakEvenPrice := entryPrice + (totalFee / quantity)
-		return math.Ceil(breakEvenPrice*10000) / 10000 // 保留4位小数，向上取整
-	} else {
-		// 做空：需要价格下跌以覆盖手续费
-		// 盈亏平衡价 = 入场价 - (总手续费 / 数量)
-		breakEvenPrice := entryPrice - (totalFee / quantity)
-		return math.Floor(breakEvenPrice*10000) / 10000 // 保留4位小数，向下取整
+		return entryPrice * (1 + openFeeRate) / (1 - closeFeeRate)
 	}
+	// 做空：需要价格下跌以覆盖手续费
+	return entryPrice * (1 - openFeeRate) / (1 + closeFeeRate)
 }
 
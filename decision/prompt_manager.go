@@ -3,16 +3,65 @@ package decision
 import (
 	"fmt"
 	"log"
+	"nofx/market"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 )
 
+// indicatorsDirective 模板中声明所需指标的指令行前缀（如 "#!indicators: adx(14), vwap, supertrend(10,3)@1h"）
+const indicatorsDirective = "#!indicators:"
+
 // PromptTemplate 系统提示词模板
 type PromptTemplate struct {
-	Name    string // 模板名称（文件名，不含扩展名）
-	Content string // 模板内容
+	Name       string // 模板名称（文件名，不含扩展名）
+	Content    string // 模板内容（含指令行，用于编辑）
+	Indicators string // 模板声明的指标（来自 #!indicators: 指令行，逗号分隔）
+}
+
+// newPromptTemplate 创建模板并解析指令行
+func newPromptTemplate(name, content string) *PromptTemplate {
+	_, indicators := parseTemplateDirectives(content)
+	return &PromptTemplate{
+		Name:       name,
+		Content:    content,
+		Indicators: indicators,
+	}
+}
+
+// Prompt 返回去掉指令行后的提示词正文
+func (t *PromptTemplate) Prompt() string {
+	body, _ := parseTemplateDirectives(t.Content)
+	return body
+}
+
+// ValidateTemplateDirectives 校验模板内容中的指令行（指标名称、参数和周期）
+func ValidateTemplateDirectives(content string) error {
+	_, indicators := parseTemplateDirectives(content)
+	if indicators == "" {
+		return nil
+	}
+	if _, err := market.ParseIndicatorSpecs(indicators); err != nil {
+		return fmt.Errorf("模板指标声明无效: %w", err)
+	}
+	return nil
+}
+
+// parseTemplateDirectives 分离模板正文与指令行（多个 #!indicators: 行会合并）
+func parseTemplateDirectives(content string) (body string, indicators string) {
+	var bodyLines, specs []string
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, indicatorsDirective) {
+			if spec := strings.TrimSpace(strings.TrimPrefix(trimmed, indicatorsDirective)); spec != "" {
+				specs = append(specs, spec)
+			}
+			continue
+		}
+		bodyLines = append(bodyLines, line)
+	}
+	return strings.Join(bodyLines, "\n"), strings.Join(specs, ", ")
 }
 
 // PromptManager 提示词管理器
@@ -80,10 +129,7 @@ func (pm *PromptManager) LoadTemplates(dir string) error {
 		templateName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
 
 		// 存储模板
-		pm.templates[templateName] = &PromptTemplate{
-			Name:    templateName,
-			Content: string(content),
-		}
+		pm.templates[templateName] = newPromptTemplate(templateName, string(content))
 
 		log.Printf("  📄 加载提示词模板: %s (%s)", templateName, fileName)
 	}
@@ -186,10 +232,7 @@ func (pm *PromptManager) UpdateTemplate(name string, content string) error {
 	}
 
 	// 更新内存中的模板
-	pm.templates[name] = &PromptTemplate{
-		Name:    name,
-		Content: content,
-	}
+	pm.templates[name] = newPromptTemplate(name, content)
 
 	log.Printf("✓ 提示词模板已更新: %s", name)
 	return nil
@@ -0,0 +1,251 @@
+package decision
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/market"
+)
+
+// 风控规则
+const (
+	RiskRuleStopLossSide    = "stop_loss_side"    // 止损价在入场价错误一侧
+	RiskRuleTakeProfitSide  = "take_profit_side"  // 止盈价在入场价错误一侧
+	RiskRuleMinRiskReward   = "min_risk_reward"   // 风险回报比低于下限
+	RiskRuleRiskUSDMismatch = "risk_usd_mismatch" // 止损隐含亏损与risk_usd不一致
+	RiskRuleMaxPositionPct  = "max_position_pct"  // 单笔仓位价值超过净值百分比上限
+	RiskRuleMaxMarginUsage  = "max_margin_usage"  // 总保证金使用率超过上限
+	RiskRuleMaxPositions    = "max_positions"     // 同时持仓数量超过上限
+	RiskRuleMaxSlippage     = "max_slippage"      // 按盘口估算的市价成交滑点超过上限
+)
+
+// 风控处理结果
+const (
+	RiskActionReject = "reject" // 拒绝执行
+	RiskActionClamp  = "clamp"  // 缩减仓位后执行
+)
+
+// RiskLimits 开仓前风控参数（按交易员配置，<=0 表示不启用对应检查）
+type RiskLimits struct {
+	MaxPositionPct      float64 // 单笔仓位价值上限（占账户净值百分比）
+	MaxMarginUsagePct   float64 // 总保证金使用率上限（百分比）
+	MaxPositions        int     // 同时持仓数量上限
+	MinRiskReward       float64 // 最小风险回报比（止盈距离/止损距离）
+	RiskUSDTolerancePct float64 // 止损隐含亏损与risk_usd允许的偏差（百分比）
+	ClampOversize       bool    // 仓位价值/保证金超限时缩减仓位，而不是直接拒绝
+	MaxSlippagePct      float64 // 按盘口估算的市价成交滑点上限（百分比，相对中间价）
+}
+
+// RiskCheck 风控检查结果（拒绝或缩减）
+type RiskCheck struct {
+	Rule    string  `json:"rule"`    // 风控规则
+	Action  string  `json:"action"`  // reject / clamp
+	Message string  `json:"message"` // 说明
+	Limit   float64 `json:"limit"`   // 配置的阈值
+	Value   float64 `json:"value"`   // 实际值
+}
+
+// RiskRejection 返回导致决策被拒绝的风控检查，未被拒绝返回nil
+func (d *Decision) RiskRejection() *RiskCheck {
+	for i := range d.RiskChecks {
+		if d.RiskChecks[i].Action == RiskActionReject {
+			return &d.RiskChecks[i]
+		}
+	}
+	return nil
+}
+
+// riskEngine 批量决策的开仓前风控（跟踪本批次平仓释放和开仓占用的保证金、持仓数）
+type riskEngine struct {
+	limits     RiskLimits
+	equity     float64
+	marginUsed float64
+	positions  map[string]float64           // symbol_side -> 占用保证金
+	books      map[string]*market.OrderBook // symbol -> 盘口快照（用于滑点检查）
+}
+
+// newRiskEngine 根据交易上下文创建风控引擎
+func newRiskEngine(ctx *Context) *riskEngine {
+	r := &riskEngine{
+		limits:     ctx.RiskLimits,
+		equity:     ctx.Account.TotalEquity,
+		marginUsed: ctx.Account.MarginUsed,
+		positions:  make(map[string]float64),
+		books:      make(map[string]*market.OrderBook),
+	}
+	for _, pos := range ctx.Positions {
+		r.positions[pos.Symbol+"_"+pos.Side] = pos.MarginUsed
+	}
+	// 未成交的限价挂单成交后会占用保证金，按已占用计算
+	for _, order := range ctx.PendingOrders {
+		if order.Leverage <= 0 {
+			continue
+		}
+		margin := order.PositionSizeUSD / float64(order.Leverage)
+		r.positions[order.Symbol+"_"+order.Side] += margin
+		r.marginUsed += margin
+	}
+	for symbol, data := range ctx.MarketDataMap {
+		if data != nil && data.OrderBook != nil {
+			r.books[symbol] = data.OrderBook
+		}
+	}
+	return r
+}
+
+// releaseClosedPositions 扣除本批次全部平仓的持仓（执行时先平仓后开仓）
+func (r *riskEngine) releaseClosedPositions(decisions []Decision) {
+	for _, d := range decisions {
+		side := ""
+		switch d.Action {
+		case "close_long":
+			side = "long"
+		case "close_short":
+			side = "short"
+		default:
+			continue
+		}
+		key := d.Symbol + "_" + side
+		if margin, ok := r.positions[key]; ok {
+			r.marginUsed -= margin
+			delete(r.positions, key)
+		}
+	}
+	if r.marginUsed < 0 {
+		r.marginUsed = 0
+	}
+}
+
+// check 对开仓决策执行风控检查，结果写入 d.RiskChecks（缩减时直接修改仓位价值）
+func (r *riskEngine) check(d *Decision, currentPrice float64) {
+	isLong := d.Action == "open_long"
+
+	// 1. 止损/止盈方向（始终检查）
+	if currentPrice > 0 {
+		if d.StopLoss > 0 && ((isLong && d.StopLoss >= currentPrice) || (!isLong && d.StopLoss <= currentPrice)) {
+			r.reject(d, RiskRuleStopLossSide, fmt.Sprintf("止损价 %.4f 在入场价 %.4f 错误一侧", d.StopLoss, currentPrice), currentPrice, d.StopLoss)
+			return
+		}
+		if d.TakeProfit > 0 && ((isLong && d.TakeProfit <= currentPrice) || (!isLong && d.TakeProfit >= currentPrice)) {
+			r.reject(d, RiskRuleTakeProfitSide, fmt.Sprintf("止盈价 %.4f 在入场价 %.4f 错误一侧", d.TakeProfit, currentPrice), currentPrice, d.TakeProfit)
+			return
+		}
+		for i, level := range d.TakeProfitLevels {
+			if (isLong && level.Price <= currentPrice) || (!isLong && level.Price >= currentPrice) {
+				r.reject(d, RiskRuleTakeProfitSide, fmt.Sprintf("第%d档止盈价 %.4f 在入场价 %.4f 错误一侧", i+1, level.Price, currentPrice), currentPrice, level.Price)
+				return
+			}
+		}
+	}
+
+	// 2. 风险回报比（分批止盈按各档比例加权平均止盈价计算）
+	if takeProfit := d.AverageTakeProfit(); r.limits.MinRiskReward > 0 && currentPrice > 0 && d.StopLoss > 0 && takeProfit > 0 {
+		riskDist := math.Abs(currentPrice - d.StopLoss)
+		rewardDist := math.Abs(takeProfit - currentPrice)
+		if riskDist > 0 {
+			rr := rewardDist / riskDist
+			if rr < r.limits.MinRiskReward {
+				r.reject(d, RiskRuleMinRiskReward, fmt.Sprintf("风险回报比 %.2f 低于下限 %.2f", rr, r.limits.MinRiskReward), r.limits.MinRiskReward, rr)
+				return
+			}
+		}
+	}
+
+	// 3. 止损隐含亏损与risk_usd一致性（使用AI给出的原始仓位，缩减前检查）
+	if r.limits.RiskUSDTolerancePct > 0 && d.RiskUSD > 0 && currentPrice > 0 && d.StopLoss > 0 {
+		impliedLoss := d.PositionSizeUSD * math.Abs(currentPrice-d.StopLoss) / currentPrice
+		deviationPct := math.Abs(impliedLoss-d.RiskUSD) / d.RiskUSD * 100
+		if deviationPct > r.limits.RiskUSDTolerancePct {
+			r.reject(d, RiskRuleRiskUSDMismatch, fmt.Sprintf("止损隐含亏损 %.2f USDT 与 risk_usd %.2f USDT 偏差 %.1f%%", impliedLoss, d.RiskUSD, deviationPct), r.limits.RiskUSDTolerancePct, deviationPct)
+			return
+		}
+	}
+
+	// 4. 同时持仓数量
+	key := d.Symbol + "_" + d.Action[len("open_"):]
+	if _, exists := r.positions[key]; !exists && r.limits.MaxPositions > 0 && len(r.positions) >= r.limits.MaxPositions {
+		r.reject(d, RiskRuleMaxPositions, fmt.Sprintf("持仓数量已达上限 %d", r.limits.MaxPositions), float64(r.limits.MaxPositions), float64(len(r.positions)+1))
+		return
+	}
+
+	if r.equity <= 0 {
+		r.commit(key, d)
+		return
+	}
+
+	// 5. 单笔仓位价值占净值比例
+	if r.limits.MaxPositionPct > 0 {
+		maxSize := r.equity * r.limits.MaxPositionPct / 100
+		if d.PositionSizeUSD > maxSize {
+			pct := d.PositionSizeUSD / r.equity * 100
+			if !r.limits.ClampOversize {
+				r.reject(d, RiskRuleMaxPositionPct, fmt.Sprintf("仓位价值 %.2f USDT 占净值 %.1f%%，超过上限 %.1f%%", d.PositionSizeUSD, pct, r.limits.MaxPositionPct), r.limits.MaxPositionPct, pct)
+				return
+			}
+			r.clamp(d, RiskRuleMaxPositionPct, maxSize, fmt.Sprintf("仓位价值 %.2f USDT 占净值 %.1f%%，缩减至 %.2f USDT", d.PositionSizeUSD, pct, maxSize), r.limits.MaxPositionPct, pct)
+		}
+	}
+
+	// 6. 总保证金使用率
+	if r.limits.MaxMarginUsagePct > 0 && d.Leverage > 0 {
+		margin := d.PositionSizeUSD / float64(d.Leverage)
+		usagePct := (r.marginUsed + margin) / r.equity * 100
+		if usagePct > r.limits.MaxMarginUsagePct {
+			allowedMargin := r.equity*r.limits.MaxMarginUsagePct/100 - r.marginUsed
+			if !r.limits.ClampOversize || allowedMargin <= 0 {
+				r.reject(d, RiskRuleMaxMarginUsage, fmt.Sprintf("开仓后保证金使用率 %.1f%%，超过上限 %.1f%%", usagePct, r.limits.MaxMarginUsagePct), r.limits.MaxMarginUsagePct, usagePct)
+				return
+			}
+			newSize := allowedMargin * float64(d.Leverage)
+			r.clamp(d, RiskRuleMaxMarginUsage, newSize, fmt.Sprintf("开仓后保证金使用率 %.1f%%，仓位缩减至 %.2f USDT", usagePct, newSize), r.limits.MaxMarginUsagePct, usagePct)
+		}
+	}
+
+	// 7. 按盘口估算的滑点（没有盘口数据或限价委托时跳过）
+	if book := r.books[d.Symbol]; r.limits.MaxSlippagePct > 0 && book != nil && d.PositionSizeUSD > 0 && d.IsMarketOrder() {
+		side := "buy"
+		if !isLong {
+			side = "sell"
+		}
+		_, slippage, filled := book.EstimateFill(side, d.PositionSizeUSD)
+		if !filled || slippage > r.limits.MaxSlippagePct {
+			maxSize := book.MaxNotionalWithinSlippage(side, r.limits.MaxSlippagePct)
+			message := fmt.Sprintf("%.2f USDT 市价成交预计滑点 %.3f%%，超过上限 %.3f%%", d.PositionSizeUSD, slippage, r.limits.MaxSlippagePct)
+			if !filled {
+				message = fmt.Sprintf("盘口深度不足以成交 %.2f USDT（滑点上限 %.3f%%）", d.PositionSizeUSD, r.limits.MaxSlippagePct)
+			}
+			if !r.limits.ClampOversize || maxSize <= 0 {
+				r.reject(d, RiskRuleMaxSlippage, message, r.limits.MaxSlippagePct, slippage)
+				return
+			}
+			r.clamp(d, RiskRuleMaxSlippage, maxSize, fmt.Sprintf("%s，仓位缩减至 %.2f USDT", message, maxSize), r.limits.MaxSlippagePct, slippage)
+		}
+	}
+
+	r.commit(key, d)
+}
+
+// commit 记录通过风控的开仓占用
+func (r *riskEngine) commit(key string, d *Decision) {
+	margin := 0.0
+	if d.Leverage > 0 {
+		margin = d.PositionSizeUSD / float64(d.Leverage)
+	}
+	r.positions[key] += margin
+	r.marginUsed += margin
+}
+
+func (r *riskEngine) reject(d *Decision, rule, message string, limit, value float64) {
+	log.Printf("  🛡️ 风控拒绝 %s %s: %s", d.Symbol, d.Action, message)
+	d.RiskChecks = append(d.RiskChecks, RiskCheck{Rule: rule, Action: RiskActionReject, Message: message, Limit: limit, Value: value})
+}
+
+// clamp 缩减仓位价值，risk_usd按相同比例缩减
+func (r *riskEngine) clamp(d *Decision, rule string, newSize float64, message string, limit, value float64) {
+	log.Printf("  🛡️ 风控缩减 %s %s: %s", d.Symbol, d.Action, message)
+	if d.PositionSizeUSD > 0 {
+		d.RiskUSD *= newSize / d.PositionSizeUSD
+	}
+	d.PositionSizeUSD = newSize
+	d.RiskChecks = append(d.RiskChecks, RiskCheck{Rule: rule, Action: RiskActionClamp, Message: message, Limit: limit, Value: value})
+}
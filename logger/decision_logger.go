@@ -1,30 +1,58 @@
 package logger
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
 // DecisionRecord 决策记录
 type DecisionRecord struct {
-	Timestamp      time.Time          `json:"timestamp"`       // 决策时间
-	CycleNumber    int                `json:"cycle_number"`    // 周期编号
-	SystemPrompt   string             `json:"system_prompt"`   // 系统提示词（发送给AI的系统prompt）
-	InputPrompt    string             `json:"input_prompt"`    // 发送给AI的输入prompt
-	CoTTrace       string             `json:"cot_trace"`       // AI思维链（输出）
-	DecisionJSON   string             `json:"decision_json"`   // 决策JSON
-	AccountState   AccountSnapshot    `json:"account_state"`   // 账户状态快照
-	Positions      []PositionSnapshot `json:"positions"`       // 持仓快照
-	CandidateCoins []string           `json:"candidate_coins"` // 候选币种列表
-	Decisions      []DecisionAction   `json:"decisions"`       // 执行的决策
-	ExecutionLog   []string           `json:"execution_log"`   // 执行日志
-	Success        bool               `json:"success"`         // 是否成功
-	ErrorMessage   string             `json:"error_message"`   // 错误信息（如果有）
+	Timestamp      time.Time          `json:"timestamp"`           // 决策时间
+	CycleNumber    int                `json:"cycle_number"`        // 周期编号
+	SystemPrompt   string             `json:"system_prompt"`       // 系统提示词（发送给AI的系统prompt）
+	InputPrompt    string             `json:"input_prompt"`        // 发送给AI的输入prompt
+	CoTTrace       string             `json:"cot_trace"`           // AI思维链（输出）
+	DecisionJSON   string             `json:"decision_json"`       // 决策JSON
+	AccountState   AccountSnapshot    `json:"account_state"`       // 账户状态快照
+	Positions      []PositionSnapshot `json:"positions"`           // 持仓快照
+	CandidateCoins []string           `json:"candidate_coins"`     // 候选币种列表
+	Decisions      []DecisionAction   `json:"decisions"`           // 执行的决策
+	ExecutionLog   []string           `json:"execution_log"`       // 执行日志
+	Success        bool               `json:"success"`             // 是否成功
+	ErrorMessage   string             `json:"error_message"`       // 错误信息（如果有）
+	RiskTrip       *RiskTrip          `json:"risk_trip,omitempty"` // 风控熔断记录（触发熔断的周期才有）
+
+	AIProvider string   `json:"ai_provider,omitempty"` // 实际应答的AI提供商（provider/model，配置备用链时可能不是主模型）
+	AIUsage    *AIUsage `json:"ai_usage,omitempty"`    // 本周期AI调用的token用量、耗时和费用
+}
+
+// AIUsage AI调用用量
+type AIUsage struct {
+	Model            string  `json:"model"`
+	PromptTokens     int     `json:"prompt_tokens"`     // 输入token（含缓存命中部分）
+	CompletionTokens int     `json:"completion_tokens"` // 输出token
+	CachedTokens     int     `json:"cached_tokens"`     // 缓存命中的输入token
+	TotalTokens      int     `json:"total_tokens"`
+	LatencyMs        int64   `json:"latency_ms"` // 成功请求的耗时
+	Attempts         int     `json:"attempts"`   // 尝试次数（含重试）
+	CostUSD          float64 `json:"cost_usd"`   // 按价格表估算的费用（美元）
+}
+
+// RiskTrip 风控熔断记录
+type RiskTrip struct {
+	Reason          string    `json:"reason"`           // 触发原因: max_daily_loss / max_drawdown
+	LimitPct        float64   `json:"limit_pct"`        // 配置的阈值（百分比）
+	ValuePct        float64   `json:"value_pct"`        // 触发时的实际值（百分比）
+	Equity          float64   `json:"equity"`           // 触发时账户净值
+	ReferenceEquity float64   `json:"reference_equity"` // 参考净值（日初净值或峰值净值）
+	StopUntil       time.Time `json:"stop_until"`       // 暂停交易至
+	Flattened       bool      `json:"flattened"`        // 是否已强制平掉所有持仓
+	Timestamp       time.Time `json:"timestamp"`        // 触发时间
 }
 
 // AccountSnapshot 账户状态快照
@@ -50,24 +78,47 @@ type PositionSnapshot struct {
 
 // DecisionAction 决策动作
 type DecisionAction struct {
-	Action    string    `json:"action"`    // open_long, open_short, close_long, close_short, update_stop_loss, update_take_profit, partial_close
+	Action    string    `json:"action"`    // open_long, open_short, close_long, close_short, update_stop_loss, update_take_profit, partial_close, set_trailing_stop, pending_open_long/short（限价挂单）, cancel_open_long/short（挂单撤销）, auto_update_stop_loss（保本止损自动上移）
 	Symbol    string    `json:"symbol"`    // 币种
 	Quantity  float64   `json:"quantity"`  // 数量（部分平仓时使用）
 	Leverage  int       `json:"leverage"`  // 杠杆（开仓时）
-	Price     float64   `json:"price"`     // 执行价格
+	Price     float64   `json:"price"`     // 实际成交均价（未成交时为下单参考价）
 	OrderID   int64     `json:"order_id"`  // 订单ID
 	Timestamp time.Time `json:"timestamp"` // 执行时间
 	Success   bool      `json:"success"`   // 是否成功
 	Error     string    `json:"error"`     // 错误信息
+
+	RiskChecks []RiskCheck `json:"risk_checks,omitempty"` // 开仓前风控检查结果（拒绝/缩减）
+
+	OrderType string `json:"order_type,omitempty"` // 开仓委托类型（limit/post_only/ioc，市价单为空）
+
+	ExpectedPrice float64 `json:"expected_price,omitempty"` // 下单参考价（市价单为下单前的市场价，限价单为委托价）
+	Fee           float64 `json:"fee,omitempty"`            // 实际手续费（USDT）
+	SlippagePct   float64 `json:"slippage_pct,omitempty"`   // 成交滑点（%），正数表示成交价比参考价更差
+
+	StopLoss float64 `json:"stop_loss,omitempty"` // 新止损价（自动调整止损时记录）
+}
+
+// RiskCheck 开仓前风控检查记录
+type RiskCheck struct {
+	Rule    string  `json:"rule"`    // 风控规则: max_position_pct / max_margin_usage / max_positions / stop_loss_side / take_profit_side / min_risk_reward / risk_usd_mismatch
+	Action  string  `json:"action"`  // reject / clamp
+	Message string  `json:"message"` // 说明
+	Limit   float64 `json:"limit"`   // 配置的阈值
+	Value   float64 `json:"value"`   // 实际值
 }
 
 // DecisionLogger 决策日志记录器
 type DecisionLogger struct {
 	logDir      string
+	mu          sync.Mutex // 保护 cycleNumber（AI周期和监控goroutine可能同时写入）
 	cycleNumber int
+	store       DecisionStore
 }
 
 // NewDecisionLogger 创建决策日志记录器
+// 默认使用日志目录下的 SQLite 库（首次打开时导入旧版JSON文件），
+// 设置 NOFX_DECISION_STORE=file 或打开失败时使用每周期一个JSON文件的旧格式
 func NewDecisionLogger(logDir string) *DecisionLogger {
 	if logDir == "" {
 		logDir = "decision_logs"
@@ -81,127 +132,104 @@ func NewDecisionLogger(logDir string) *DecisionLogger {
 	return &DecisionLogger{
 		logDir:      logDir,
 		cycleNumber: 0,
+		store:       openDecisionStore(logDir),
 	}
 }
 
-// LogDecision 记录决策
-func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
-	l.cycleNumber++
-	record.CycleNumber = l.cycleNumber
-	record.Timestamp = time.Now()
-
-	// 生成文件名：decision_YYYYMMDD_HHMMSS_cycleN.json
-	filename := fmt.Sprintf("decision_%s_cycle%d.json",
-		record.Timestamp.Format("20060102_150405"),
-		record.CycleNumber)
+// NewDecisionLoggerWithStore 使用指定存储后端创建决策日志记录器
+func NewDecisionLoggerWithStore(logDir string, store DecisionStore) *DecisionLogger {
+	return &DecisionLogger{
+		logDir:      logDir,
+		cycleNumber: 0,
+		store:       store,
+	}
+}
 
-	filepath := filepath.Join(l.logDir, filename)
+// openDecisionStore 根据配置选择存储后端
+func openDecisionStore(logDir string) DecisionStore {
+	if strings.EqualFold(os.Getenv("NOFX_DECISION_STORE"), "file") {
+		return NewFileDecisionStore(logDir)
+	}
 
-	// 序列化为JSON（带缩进，方便阅读）
-	data, err := json.MarshalIndent(record, "", "  ")
+	store, err := NewSQLiteDecisionStore(filepath.Join(logDir, DecisionDBFile))
 	if err != nil {
-		return fmt.Errorf("序列化决策记录失败: %w", err)
+		fmt.Printf("⚠ 打开决策数据库失败，使用JSON文件存储: %v\n", err)
+		return NewFileDecisionStore(logDir)
 	}
 
-	// 写入文件
-	if err := ioutil.WriteFile(filepath, data, 0644); err != nil {
-		return fmt.Errorf("写入决策记录失败: %w", err)
+	imported, err := store.ImportJSONFiles(logDir)
+	if err != nil {
+		fmt.Printf("⚠ 导入旧版决策日志失败，使用JSON文件存储: %v\n", err)
+		store.Close()
+		return NewFileDecisionStore(logDir)
 	}
-
-	fmt.Printf("📝 决策记录已保存: %s\n", filename)
-	return nil
+	if imported > 0 {
+		fmt.Printf("📦 已将 %d 条旧版决策日志导入 %s\n", imported, filepath.Join(logDir, DecisionDBFile))
+	}
+	return store
 }
 
-// GetLatestRecords 获取最近N条记录（按时间正序：从旧到新）
-func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
-	files, err := ioutil.ReadDir(l.logDir)
-	if err != nil {
-		return nil, fmt.Errorf("读取日志目录失败: %w", err)
-	}
+// Store 返回底层存储后端
+func (l *DecisionLogger) Store() DecisionStore {
+	return l.store
+}
 
-	// 先按修改时间倒序收集（最新的在前）
-	var records []*DecisionRecord
-	count := 0
-	for i := len(files) - 1; i >= 0 && count < n; i-- {
-		file := files[i]
-		if file.IsDir() {
-			continue
-		}
+// Close 关闭存储后端
+func (l *DecisionLogger) Close() error {
+	return l.store.Close()
+}
 
-		filepath := filepath.Join(l.logDir, file.Name())
-		data, err := ioutil.ReadFile(filepath)
-		if err != nil {
-			continue
-		}
+// LogDecision 记录决策
+func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-		var record DecisionRecord
-		if err := json.Unmarshal(data, &record); err != nil {
-			continue
-		}
+	l.cycleNumber++
+	record.CycleNumber = l.cycleNumber
+	record.Timestamp = time.Now()
 
-		records = append(records, &record)
-		count++
+	if err := l.store.Save(record); err != nil {
+		return err
 	}
 
-	// 反转数组，让时间从旧到新排列（用于图表显示）
-	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
-		records[i], records[j] = records[j], records[i]
-	}
+	fmt.Printf("📝 决策记录已保存: cycle #%d\n", record.CycleNumber)
+	return nil
+}
+
+// GetLatestRecords 获取最近N条记录（按时间正序：从旧到新）
+func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
+	return l.store.Latest(n)
+}
 
-	return records, nil
+// GetEquityHistory 获取最近N个周期的账户快照（按时间正序：从旧到新）
+func (l *DecisionLogger) GetEquityHistory(n int) ([]EquityPoint, error) {
+	return l.store.EquityHistory(n)
+}
+
+// QueryRecords 按时间范围/币种/动作/成功与否分页查询
+func (l *DecisionLogger) QueryRecords(query DecisionQuery) (*DecisionPage, error) {
+	return l.store.Query(query)
 }
 
 // GetRecordByDate 获取指定日期的所有记录
 func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, error) {
-	dateStr := date.Format("20060102")
-	pattern := filepath.Join(l.logDir, fmt.Sprintf("decision_%s_*.json", dateStr))
-
-	files, err := filepath.Glob(pattern)
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	page, err := l.store.Query(DecisionQuery{
+		StartTime: start,
+		EndTime:   start.AddDate(0, 0, 1),
+		Ascending: true,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("查找日志文件失败: %w", err)
+		return nil, err
 	}
-
-	var records []*DecisionRecord
-	for _, filepath := range files {
-		data, err := ioutil.ReadFile(filepath)
-		if err != nil {
-			continue
-		}
-
-		var record DecisionRecord
-		if err := json.Unmarshal(data, &record); err != nil {
-			continue
-		}
-
-		records = append(records, &record)
-	}
-
-	return records, nil
+	return page.Records, nil
 }
 
 // CleanOldRecords 清理N天前的旧记录
 func (l *DecisionLogger) CleanOldRecords(days int) error {
-	cutoffTime := time.Now().AddDate(0, 0, -days)
-
-	files, err := ioutil.ReadDir(l.logDir)
+	removedCount, err := l.store.DeleteBefore(time.Now().AddDate(0, 0, -days))
 	if err != nil {
-		return fmt.Errorf("读取日志目录失败: %w", err)
-	}
-
-	removedCount := 0
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		if file.ModTime().Before(cutoffTime) {
-			filepath := filepath.Join(l.logDir, file.Name())
-			if err := os.Remove(filepath); err != nil {
-				fmt.Printf("⚠ 删除旧记录失败 %s: %v\n", file.Name(), err)
-				continue
-			}
-			removedCount++
-		}
+		return err
 	}
 
 	if removedCount > 0 {
@@ -213,53 +241,7 @@ func (l *DecisionLogger) CleanOldRecords(days int) error {
 
 // GetStatistics 获取统计信息
 func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
-	files, err := ioutil.ReadDir(l.logDir)
-	if err != nil {
-		return nil, fmt.Errorf("读取日志目录失败: %w", err)
-	}
-
-	stats := &Statistics{}
-
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		filepath := filepath.Join(l.logDir, file.Name())
-		data, err := ioutil.ReadFile(filepath)
-		if err != nil {
-			continue
-		}
-
-		var record DecisionRecord
-		if err := json.Unmarshal(data, &record); err != nil {
-			continue
-		}
-
-		stats.TotalCycles++
-
-		for _, action := range record.Decisions {
-			if action.Success {
-				switch action.Action {
-				case "open_long", "open_short":
-					stats.TotalOpenPositions++
-				case "close_long", "close_short", "auto_close_long", "auto_close_short":
-					stats.TotalClosePositions++
-					// 🔧 BUG FIX：partial_close 不計入 TotalClosePositions，避免重複計數
-					// case "partial_close": // 不計數，因為只有完全平倉才算一次
-					// update_stop_loss 和 update_take_profit 不計入統計
-				}
-			}
-		}
-
-		if record.Success {
-			stats.SuccessfulCycles++
-		} else {
-			stats.FailedCycles++
-		}
-	}
-
-	return stats, nil
+	return l.store.Statistics()
 }
 
 // Statistics 统计信息
@@ -269,6 +251,14 @@ type Statistics struct {
 	FailedCycles        int `json:"failed_cycles"`
 	TotalOpenPositions  int `json:"total_open_positions"`
 	TotalClosePositions int `json:"total_close_positions"`
+
+	// 交易台账汇总（使用交易台账时提供）
+	RealizedPnL  float64 `json:"realized_pnl,omitempty"`  // 已实现价差盈亏
+	TotalFees    float64 `json:"total_fees,omitempty"`    // 累计手续费
+	TotalFunding float64 `json:"total_funding,omitempty"` // 累计资金费
+
+	PartialExits   int     `json:"partial_exits,omitempty"`    // 部分平仓成交次数（分批止盈、部分平仓）
+	PartialExitPnL float64 `json:"partial_exit_pnl,omitempty"` // 部分平仓已实现价差盈亏
 }
 
 // TradeOutcome 单笔交易结果
@@ -287,6 +277,12 @@ type TradeOutcome struct {
 	OpenTime      time.Time `json:"open_time"`      // 开仓时间
 	CloseTime     time.Time `json:"close_time"`     // 平仓时间
 	WasStopLoss   bool      `json:"was_stop_loss"`  // 是否止损
+
+	// 交易台账字段（从决策日志重建的交易没有这些数据）
+	Fee          float64 `json:"fee,omitempty"`           // 手续费（开仓+平仓，已从PnL中扣除）
+	FundingFee   float64 `json:"funding_fee,omitempty"`   // 资金费（正数为收入，已计入PnL）
+	ExitReason   string  `json:"exit_reason,omitempty"`   // 平仓原因: ai/stop_loss/take_profit/liquidation/drawdown/risk_flatten/exchange
+	PartialExits int     `json:"partial_exits,omitempty"` // 最终平仓前的部分平仓次数（分批止盈、部分平仓）
 }
 
 // PerformanceAnalysis 交易表现分析
@@ -681,6 +677,11 @@ func (l *DecisionLogger) calculateSharpeRatio(records []*DecisionRecord) float64
 		}
 	}
 
+	return SharpeRatio(equities)
+}
+
+// SharpeRatio 根据净值序列计算周期级别夏普比率（无风险利率为0，非年化）
+func SharpeRatio(equities []float64) float64 {
 	if len(equities) < 2 {
 		return 0.0
 	}
@@ -729,3 +730,74 @@ func (l *DecisionLogger) calculateSharpeRatio(records []*DecisionRecord) float64
 	sharpeRatio := meanReturn / stdDev
 	return sharpeRatio
 }
+
+// AnalyzeTrades 根据已完成交易（按平仓时间升序）计算交易表现，equities 为净值序列（用于夏普比率）
+// 统计口径与 AnalyzePerformance 一致：PnL>0 计为盈利，PnL<0 计为亏损，最近10笔交易倒序返回
+func AnalyzeTrades(trades []TradeOutcome, equities []float64) *PerformanceAnalysis {
+	analysis := &PerformanceAnalysis{
+		RecentTrades: []TradeOutcome{},
+		SymbolStats:  make(map[string]*SymbolPerformance),
+	}
+
+	totalWin, totalLoss := 0.0, 0.0
+	for _, trade := range trades {
+		analysis.TotalTrades++
+		if trade.PnL > 0 {
+			analysis.WinningTrades++
+			totalWin += trade.PnL
+		} else if trade.PnL < 0 {
+			analysis.LosingTrades++
+			totalLoss += trade.PnL
+		}
+
+		stats, exists := analysis.SymbolStats[trade.Symbol]
+		if !exists {
+			stats = &SymbolPerformance{Symbol: trade.Symbol}
+			analysis.SymbolStats[trade.Symbol] = stats
+		}
+		stats.TotalTrades++
+		stats.TotalPnL += trade.PnL
+		if trade.PnL > 0 {
+			stats.WinningTrades++
+		} else if trade.PnL < 0 {
+			stats.LosingTrades++
+		}
+	}
+
+	if analysis.TotalTrades > 0 {
+		analysis.WinRate = float64(analysis.WinningTrades) / float64(analysis.TotalTrades) * 100
+		if analysis.WinningTrades > 0 {
+			analysis.AvgWin = totalWin / float64(analysis.WinningTrades)
+		}
+		if analysis.LosingTrades > 0 {
+			analysis.AvgLoss = totalLoss / float64(analysis.LosingTrades)
+		}
+		if totalLoss != 0 {
+			analysis.ProfitFactor = totalWin / -totalLoss
+		} else if totalWin > 0 {
+			analysis.ProfitFactor = 999.0
+		}
+	}
+
+	bestPnL := -999999.0
+	worstPnL := 999999.0
+	for symbol, stats := range analysis.SymbolStats {
+		stats.WinRate = float64(stats.WinningTrades) / float64(stats.TotalTrades) * 100
+		stats.AvgPnL = stats.TotalPnL / float64(stats.TotalTrades)
+		if stats.TotalPnL > bestPnL {
+			bestPnL = stats.TotalPnL
+			analysis.BestSymbol = symbol
+		}
+		if stats.TotalPnL < worstPnL {
+			worstPnL = stats.TotalPnL
+			analysis.WorstSymbol = symbol
+		}
+	}
+
+	for i := len(trades) - 1; i >= 0 && len(analysis.RecentTrades) < 10; i-- {
+		analysis.RecentTrades = append(analysis.RecentTrades, trades[i])
+	}
+
+	analysis.SharpeRatio = SharpeRatio(equities)
+	return analysis
+}
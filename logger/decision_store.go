@@ -0,0 +1,285 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DecisionStore 决策记录存储后端
+type DecisionStore interface {
+	// Save 保存一条决策记录
+	Save(record *DecisionRecord) error
+	// Latest 获取最近N条记录（按时间正序：从旧到新）
+	Latest(n int) ([]*DecisionRecord, error)
+	// Query 按条件分页查询（按时间倒序：从新到旧，除非指定Ascending）
+	Query(query DecisionQuery) (*DecisionPage, error)
+	// EquityHistory 获取最近N个周期的账户净值（按时间正序，只读取账户快照，不解析完整记录）
+	EquityHistory(n int) ([]EquityPoint, error)
+	// Statistics 统计全部记录
+	Statistics() (*Statistics, error)
+	// DeleteBefore 删除指定时间之前的记录，返回删除条数
+	DeleteBefore(cutoff time.Time) (int, error)
+	// Close 释放存储资源
+	Close() error
+}
+
+// DecisionQuery 决策记录查询条件（零值表示不过滤）
+type DecisionQuery struct {
+	StartTime time.Time // 起始时间（含）
+	EndTime   time.Time // 结束时间（不含）
+	Symbol    string    // 决策动作涉及的币种
+	Action    string    // 决策动作类型（与Symbol同时指定时需命中同一个动作）
+	Success   *bool     // 周期是否成功
+	Offset    int
+	Limit     int
+	Ascending bool // 按时间正序返回
+}
+
+// EquityPoint 单个周期的账户快照（收益率曲线使用）
+type EquityPoint struct {
+	CycleNumber  int
+	Timestamp    time.Time
+	AccountState AccountSnapshot
+}
+
+// DecisionPage 分页查询结果
+type DecisionPage struct {
+	Records []*DecisionRecord `json:"records"`
+	Total   int               `json:"total"` // 满足条件的总条数
+	Offset  int               `json:"offset"`
+	Limit   int               `json:"limit"`
+}
+
+// Matches 判断记录是否满足查询条件（文件存储和迁移校验使用）
+func (q DecisionQuery) Matches(record *DecisionRecord) bool {
+	if !q.StartTime.IsZero() && record.Timestamp.Before(q.StartTime) {
+		return false
+	}
+	if !q.EndTime.IsZero() && !record.Timestamp.Before(q.EndTime) {
+		return false
+	}
+	if q.Success != nil && record.Success != *q.Success {
+		return false
+	}
+	if q.Symbol == "" && q.Action == "" {
+		return true
+	}
+	for _, action := range record.Decisions {
+		if q.Symbol != "" && !strings.EqualFold(action.Symbol, q.Symbol) {
+			continue
+		}
+		if q.Action != "" && action.Action != q.Action {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// countActions 统计记录中成功的开仓/平仓动作
+func countActions(stats *Statistics, record *DecisionRecord) {
+	for _, action := range record.Decisions {
+		if !action.Success {
+			continue
+		}
+		switch action.Action {
+		case "open_long", "open_short":
+			stats.TotalOpenPositions++
+		case "close_long", "close_short", "auto_close_long", "auto_close_short":
+			stats.TotalClosePositions++
+			// 🔧 BUG FIX：partial_close 不計入 TotalClosePositions，避免重複計數
+			// update_stop_loss 和 update_take_profit 不計入統計
+		}
+	}
+}
+
+// FileDecisionStore 基于目录的决策记录存储（每个周期一个JSON文件）
+type FileDecisionStore struct {
+	logDir string
+}
+
+// NewFileDecisionStore 创建文件存储
+func NewFileDecisionStore(logDir string) *FileDecisionStore {
+	return &FileDecisionStore{logDir: logDir}
+}
+
+// decisionFiles 按文件名（即时间）正序列出决策记录文件
+func decisionFiles(logDir string) ([]string, error) {
+	files, err := ioutil.ReadDir(logDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), "decision_") || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		names = append(names, filepath.Join(logDir, file.Name()))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// readDecisionFile 读取单个决策记录文件
+func readDecisionFile(path string) (*DecisionRecord, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var record DecisionRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Save 写入 decision_YYYYMMDD_HHMMSS_cycleN.json
+func (s *FileDecisionStore) Save(record *DecisionRecord) error {
+	filename := fmt.Sprintf("decision_%s_cycle%d.json",
+		record.Timestamp.Format("20060102_150405"),
+		record.CycleNumber)
+
+	// 序列化为JSON（带缩进，方便阅读）
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化决策记录失败: %w", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(s.logDir, filename), data, 0644); err != nil {
+		return fmt.Errorf("写入决策记录失败: %w", err)
+	}
+	return nil
+}
+
+// Latest 获取最近N条记录（按时间正序）
+func (s *FileDecisionStore) Latest(n int) ([]*DecisionRecord, error) {
+	files, err := decisionFiles(s.logDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先倒序收集（最新的在前）
+	var records []*DecisionRecord
+	for i := len(files) - 1; i >= 0 && len(records) < n; i-- {
+		record, err := readDecisionFile(files[i])
+		if err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	// 反转数组，让时间从旧到新排列（用于图表显示）
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// EquityHistory 读取最近N个文件的账户快照（文件存储无法只读部分字段）
+func (s *FileDecisionStore) EquityHistory(n int) ([]EquityPoint, error) {
+	records, err := s.Latest(n)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]EquityPoint, 0, len(records))
+	for _, record := range records {
+		points = append(points, EquityPoint{
+			CycleNumber:  record.CycleNumber,
+			Timestamp:    record.Timestamp,
+			AccountState: record.AccountState,
+		})
+	}
+	return points, nil
+}
+
+// Query 逐个读取文件并在内存中过滤
+func (s *FileDecisionStore) Query(query DecisionQuery) (*DecisionPage, error) {
+	files, err := decisionFiles(s.logDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []*DecisionRecord
+	for _, path := range files {
+		record, err := readDecisionFile(path)
+		if err != nil || !query.Matches(record) {
+			continue
+		}
+		matched = append(matched, record)
+	}
+
+	if !query.Ascending {
+		for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+			matched[i], matched[j] = matched[j], matched[i]
+		}
+	}
+
+	page := &DecisionPage{Records: []*DecisionRecord{}, Total: len(matched), Offset: query.Offset, Limit: query.Limit}
+	if query.Offset < len(matched) {
+		end := len(matched)
+		if query.Limit > 0 && query.Offset+query.Limit < end {
+			end = query.Offset + query.Limit
+		}
+		page.Records = matched[query.Offset:end]
+	}
+	return page, nil
+}
+
+// Statistics 统计全部记录
+func (s *FileDecisionStore) Statistics() (*Statistics, error) {
+	files, err := decisionFiles(s.logDir)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Statistics{}
+	for _, path := range files {
+		record, err := readDecisionFile(path)
+		if err != nil {
+			continue
+		}
+
+		stats.TotalCycles++
+		countActions(stats, record)
+		if record.Success {
+			stats.SuccessfulCycles++
+		} else {
+			stats.FailedCycles++
+		}
+	}
+	return stats, nil
+}
+
+// DeleteBefore 按文件修改时间删除旧记录
+func (s *FileDecisionStore) DeleteBefore(cutoff time.Time) (int, error) {
+	files, err := decisionFiles(s.logDir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			fmt.Printf("⚠ 删除旧记录失败 %s: %v\n", filepath.Base(path), err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// Close 文件存储无需释放资源
+func (s *FileDecisionStore) Close() error {
+	return nil
+}
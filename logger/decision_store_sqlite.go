@@ -0,0 +1,350 @@
+package logger
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// DecisionDBFile 每个交易员日志目录下的决策记录数据库文件名
+const DecisionDBFile = "decisions.db"
+
+// decisionMigrationKey 已导入JSON决策文件的标记
+const decisionMigrationKey = "json_files_imported"
+
+// SQLiteDecisionStore 基于SQLite的决策记录存储（每个交易员一个库，记录按时间/周期/币种建索引）
+type SQLiteDecisionStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteDecisionStore 打开（或创建）SQLite决策记录库
+func NewSQLiteDecisionStore(dbPath string) (*SQLiteDecisionStore, error) {
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("打开决策数据库失败: %w", err)
+	}
+	// 单连接写入，避免并发写导致 SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	store := &SQLiteDecisionStore{db: db}
+	if err := store.createTables(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("创建决策表失败: %w", err)
+	}
+	return store, nil
+}
+
+// createTables 创建决策记录表和索引
+func (s *SQLiteDecisionStore) createTables() error {
+	queries := []string{
+		// 决策记录表（data 保存完整的 DecisionRecord JSON）
+		`CREATE TABLE IF NOT EXISTS decision_records (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			cycle_number INTEGER NOT NULL,
+			timestamp INTEGER NOT NULL,
+			success BOOLEAN NOT NULL DEFAULT 0,
+			data TEXT NOT NULL
+		)`,
+		// 决策动作表（用于按币种/动作过滤和统计）
+		`CREATE TABLE IF NOT EXISTS decision_actions (
+			record_id INTEGER NOT NULL,
+			symbol TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL DEFAULT '',
+			success BOOLEAN NOT NULL DEFAULT 0
+		)`,
+		// 元信息表（迁移标记等）
+		`CREATE TABLE IF NOT EXISTS decision_meta (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_decision_records_timestamp ON decision_records(timestamp)`,
+		`CREATE INDEX IF NOT EXISTS idx_decision_records_cycle ON decision_records(cycle_number)`,
+		`CREATE INDEX IF NOT EXISTS idx_decision_actions_record ON decision_actions(record_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_decision_actions_symbol ON decision_actions(symbol, action)`,
+		`CREATE INDEX IF NOT EXISTS idx_decision_actions_action ON decision_actions(action)`,
+	}
+
+	for _, query := range queries {
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("执行SQL失败 [%s]: %w", query, err)
+		}
+	}
+	return nil
+}
+
+// sqlExecer 同时兼容 *sql.DB 和 *sql.Tx
+type sqlExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertRecord 写入一条记录及其决策动作
+func insertRecord(exec sqlExecer, record *DecisionRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("序列化决策记录失败: %w", err)
+	}
+
+	result, err := exec.Exec(`INSERT INTO decision_records (cycle_number, timestamp, success, data) VALUES (?, ?, ?, ?)`,
+		record.CycleNumber, record.Timestamp.UnixMilli(), record.Success, string(data))
+	if err != nil {
+		return fmt.Errorf("写入决策记录失败: %w", err)
+	}
+	recordID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取决策记录ID失败: %w", err)
+	}
+
+	for _, action := range record.Decisions {
+		if _, err := exec.Exec(`INSERT INTO decision_actions (record_id, symbol, action, success) VALUES (?, ?, ?, ?)`,
+			recordID, strings.ToUpper(action.Symbol), action.Action, action.Success); err != nil {
+			return fmt.Errorf("写入决策动作失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Save 保存一条决策记录
+func (s *SQLiteDecisionStore) Save(record *DecisionRecord) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	if err := insertRecord(tx, record); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// scanRecords 解析查询结果中的 data 列
+func scanRecords(rows *sql.Rows) ([]*DecisionRecord, error) {
+	defer rows.Close()
+
+	records := []*DecisionRecord{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("读取决策记录失败: %w", err)
+		}
+		var record DecisionRecord
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			continue
+		}
+		records = append(records, &record)
+	}
+	return records, rows.Err()
+}
+
+// Latest 获取最近N条记录（按时间正序）
+func (s *SQLiteDecisionStore) Latest(n int) ([]*DecisionRecord, error) {
+	rows, err := s.db.Query(`SELECT data FROM decision_records ORDER BY timestamp DESC, id DESC LIMIT ?`, n)
+	if err != nil {
+		return nil, fmt.Errorf("查询决策记录失败: %w", err)
+	}
+	records, err := scanRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// 反转数组，让时间从旧到新排列（用于图表显示）
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// EquityHistory 获取最近N个周期的账户快照（用 json_extract 只取账户字段，不反序列化完整记录）
+func (s *SQLiteDecisionStore) EquityHistory(n int) ([]EquityPoint, error) {
+	rows, err := s.db.Query(`SELECT cycle_number, timestamp,
+			COALESCE(json_extract(data, '$.account_state.total_balance'), 0),
+			COALESCE(json_extract(data, '$.account_state.available_balance'), 0),
+			COALESCE(json_extract(data, '$.account_state.total_unrealized_profit'), 0),
+			COALESCE(json_extract(data, '$.account_state.position_count'), 0),
+			COALESCE(json_extract(data, '$.account_state.margin_used_pct'), 0)
+		FROM decision_records ORDER BY timestamp DESC, id DESC LIMIT ?`, n)
+	if err != nil {
+		return nil, fmt.Errorf("查询净值历史失败: %w", err)
+	}
+	defer rows.Close()
+
+	var points []EquityPoint
+	for rows.Next() {
+		var point EquityPoint
+		var ts int64
+		state := &point.AccountState
+		if err := rows.Scan(&point.CycleNumber, &ts, &state.TotalBalance, &state.AvailableBalance,
+			&state.TotalUnrealizedProfit, &state.PositionCount, &state.MarginUsedPct); err != nil {
+			return nil, fmt.Errorf("解析净值历史失败: %w", err)
+		}
+		point.Timestamp = time.UnixMilli(ts)
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询净值历史失败: %w", err)
+	}
+
+	// 反转数组，让时间从旧到新排列（用于图表显示）
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+	return points, nil
+}
+
+// Query 按条件分页查询
+func (s *SQLiteDecisionStore) Query(query DecisionQuery) (*DecisionPage, error) {
+	var conditions []string
+	var args []interface{}
+
+	if !query.StartTime.IsZero() {
+		conditions = append(conditions, "r.timestamp >= ?")
+		args = append(args, query.StartTime.UnixMilli())
+	}
+	if !query.EndTime.IsZero() {
+		conditions = append(conditions, "r.timestamp < ?")
+		args = append(args, query.EndTime.UnixMilli())
+	}
+	if query.Success != nil {
+		conditions = append(conditions, "r.success = ?")
+		args = append(args, *query.Success)
+	}
+	if query.Symbol != "" || query.Action != "" {
+		// 币种和动作需命中同一个决策动作
+		sub := "EXISTS (SELECT 1 FROM decision_actions a WHERE a.record_id = r.id"
+		if query.Symbol != "" {
+			sub += " AND a.symbol = ?"
+			args = append(args, strings.ToUpper(query.Symbol))
+		}
+		if query.Action != "" {
+			sub += " AND a.action = ?"
+			args = append(args, query.Action)
+		}
+		conditions = append(conditions, sub+")")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	page := &DecisionPage{Offset: query.Offset, Limit: query.Limit}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM decision_records r`+where, args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("统计决策记录失败: %w", err)
+	}
+
+	order := "DESC"
+	if query.Ascending {
+		order = "ASC"
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = -1 // SQLite: 不限制条数
+	}
+	rows, err := s.db.Query(fmt.Sprintf(`SELECT r.data FROM decision_records r%s ORDER BY r.timestamp %s, r.id %s LIMIT ? OFFSET ?`, where, order, order),
+		append(args, limit, query.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("查询决策记录失败: %w", err)
+	}
+	page.Records, err = scanRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Statistics 统计全部记录
+func (s *SQLiteDecisionStore) Statistics() (*Statistics, error) {
+	stats := &Statistics{}
+	if err := s.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(success), 0) FROM decision_records`).
+		Scan(&stats.TotalCycles, &stats.SuccessfulCycles); err != nil {
+		return nil, fmt.Errorf("统计决策记录失败: %w", err)
+	}
+	stats.FailedCycles = stats.TotalCycles - stats.SuccessfulCycles
+
+	// partial_close 不计入平仓次数，update_stop_loss / update_take_profit 不计入统计
+	if err := s.db.QueryRow(`SELECT
+			COALESCE(SUM(CASE WHEN action IN ('open_long', 'open_short') THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN action IN ('close_long', 'close_short', 'auto_close_long', 'auto_close_short') THEN 1 ELSE 0 END), 0)
+		FROM decision_actions WHERE success = 1`).
+		Scan(&stats.TotalOpenPositions, &stats.TotalClosePositions); err != nil {
+		return nil, fmt.Errorf("统计决策动作失败: %w", err)
+	}
+	return stats, nil
+}
+
+// DeleteBefore 删除指定时间之前的记录
+func (s *SQLiteDecisionStore) DeleteBefore(cutoff time.Time) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	ts := cutoff.UnixMilli()
+	if _, err := tx.Exec(`DELETE FROM decision_actions WHERE record_id IN (SELECT id FROM decision_records WHERE timestamp < ?)`, ts); err != nil {
+		return 0, fmt.Errorf("删除决策动作失败: %w", err)
+	}
+	result, err := tx.Exec(`DELETE FROM decision_records WHERE timestamp < ?`, ts)
+	if err != nil {
+		return 0, fmt.Errorf("删除决策记录失败: %w", err)
+	}
+	removed, _ := result.RowsAffected()
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return int(removed), nil
+}
+
+// Close 关闭数据库
+func (s *SQLiteDecisionStore) Close() error {
+	return s.db.Close()
+}
+
+// ImportJSONFiles 将日志目录中旧版的 decision_*.json 文件导入数据库（仅执行一次，原文件保留不删除）
+func (s *SQLiteDecisionStore) ImportJSONFiles(logDir string) (int, error) {
+	var done string
+	err := s.db.QueryRow(`SELECT value FROM decision_meta WHERE key = ?`, decisionMigrationKey).Scan(&done)
+	if err == nil {
+		return 0, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("读取迁移标记失败: %w", err)
+	}
+
+	files, err := decisionFiles(logDir)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	imported := 0
+	for _, path := range files {
+		record, err := readDecisionFile(path)
+		if err != nil {
+			fmt.Printf("⚠ 跳过无法解析的决策文件 %s: %v\n", filepath.Base(path), err)
+			continue
+		}
+		if err := insertRecord(tx, record); err != nil {
+			return 0, err
+		}
+		imported++
+	}
+
+	if _, err := tx.Exec(`INSERT INTO decision_meta (key, value) VALUES (?, ?)`,
+		decisionMigrationKey, time.Now().Format(time.RFC3339)); err != nil {
+		return 0, fmt.Errorf("写入迁移标记失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return imported, nil
+}
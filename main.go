@@ -9,6 +9,7 @@ import (
 	"nofx/config"
 	"nofx/manager"
 	"nofx/market"
+	"nofx/mcp"
 	"nofx/pool"
 	"os"
 	"os/signal"
@@ -37,10 +38,31 @@ type ConfigFile struct {
 	MaxDailyLoss       float64           `json:"max_daily_loss"`
 	MaxDrawdown        float64           `json:"max_drawdown"`
 	StopTradingMinutes int               `json:"stop_trading_minutes"`
+	FlattenOnRiskTrip  bool              `json:"flatten_on_risk_trip"`
 	Leverage           LeverageConfig    `json:"leverage"`
 	JWTSecret          string            `json:"jwt_secret"`
 	DataKLineTime      string            `json:"data_k_line_time"`
 	Log                *config.LogConfig `json:"log"` // 日志配置
+
+	AIPrices map[string]mcp.ModelPrice `json:"ai_prices"` // AI模型价格覆盖（美元/百万token）
+
+	LocalScreener LocalScreenerConfig `json:"local_screener"` // 本地筛选器配置
+
+	SignalSources SignalSourcesConfig `json:"signal_sources"` // file/http 信号源访问限制
+}
+
+// SignalSourcesConfig 交易员自定义 file/http 信号源的访问限制（未配置时这两类信号源不可用）
+type SignalSourcesConfig struct {
+	FileDir        string   `json:"file_dir"`         // file 信号源只能读取该目录内的文件
+	HTTPAllowHosts []string `json:"http_allow_hosts"` // http 信号源允许访问的主机名（解析到内网地址的主机仍会被拒绝）
+}
+
+// LocalScreenerConfig 本地筛选器配置（未配置币种池API且关闭默认币种时使用）
+type LocalScreenerConfig struct {
+	Enabled        *bool    `json:"enabled"`
+	MinVolume      float64  `json:"min_volume"`       // 最小24h成交额（USDT）
+	MinListingDays int      `json:"min_listing_days"` // 最小上线天数（-1表示不限制）
+	Exclude        []string `json:"exclude"`          // 排除的币种
 }
 
 // loadConfigFile 读取并解析config.json文件
@@ -85,6 +107,7 @@ func syncConfigToDatabase(database *config.Database, configFile *ConfigFile) err
 		"max_daily_loss":       fmt.Sprintf("%.1f", configFile.MaxDailyLoss),
 		"max_drawdown":         fmt.Sprintf("%.1f", configFile.MaxDrawdown),
 		"stop_trading_minutes": strconv.Itoa(configFile.StopTradingMinutes),
+		"flatten_on_risk_trip": fmt.Sprintf("%t", configFile.FlattenOnRiskTrip),
 	}
 
 	// 同步default_coins（转换为JSON字符串存储）
@@ -103,6 +126,32 @@ func syncConfigToDatabase(database *config.Database, configFile *ConfigFile) err
 		configs["altcoin_leverage"] = strconv.Itoa(configFile.Leverage.AltcoinLeverage)
 	}
 
+	// 同步AI价格表覆盖（转换为JSON字符串存储）
+	if len(configFile.AIPrices) > 0 {
+		aiPricesJSON, err := json.Marshal(configFile.AIPrices)
+		if err == nil {
+			configs["ai_price_table"] = string(aiPricesJSON)
+		}
+	}
+
+	// 同步本地筛选器配置
+	if configFile.LocalScreener.Enabled != nil {
+		configs["local_screener"] = fmt.Sprintf("%t", *configFile.LocalScreener.Enabled)
+	}
+	if configFile.LocalScreener.MinVolume > 0 {
+		configs["screener_min_volume"] = fmt.Sprintf("%.0f", configFile.LocalScreener.MinVolume)
+	}
+	if configFile.LocalScreener.MinListingDays != 0 {
+		configs["screener_min_days"] = strconv.Itoa(configFile.LocalScreener.MinListingDays)
+	}
+	if len(configFile.LocalScreener.Exclude) > 0 {
+		configs["screener_exclude"] = strings.Join(configFile.LocalScreener.Exclude, ",")
+	}
+
+	// 同步信号源访问限制
+	configs["signal_file_dir"] = configFile.SignalSources.FileDir
+	configs["signal_http_allow_hosts"] = strings.Join(configFile.SignalSources.HTTPAllowHosts, ",")
+
 	// 如果JWT密钥不为空，也同步
 	if configFile.JWTSecret != "" {
 		configs["jwt_secret"] = configFile.JWTSecret
@@ -166,6 +215,11 @@ func main() {
 	// In Docker Compose, variables are injected by the runtime and this is harmless.
 	_ = godotenv.Load()
 
+	// 回测子命令：nofx backtest [flags]（离线运行，不需要数据库）
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		os.Exit(runBacktestCommand(os.Args[2:]))
+	}
+
 	// 初始化数据库配置
 	// V1.75版本：支持环境变量配置存储路径（用于 Hugging Face 等云平台部署）
 	dbPath := os.Getenv("NOFX_DB_PATH")
@@ -287,6 +341,13 @@ func main() {
 		log.Printf("✓ 已配置OI Top API")
 	}
 
+	// 设置 file/http 信号源访问限制（必须在加载交易员之前）
+	signalFileDir, _ := database.GetSystemConfig("signal_file_dir")
+	signalAllowHosts, _ := database.GetSystemConfig("signal_http_allow_hosts")
+	if err := pool.SetProviderSandbox(signalFileDir, strings.Split(signalAllowHosts, ",")); err != nil {
+		log.Printf("⚠️  信号源访问限制配置无效，file 类型信号源不可用: %v", err)
+	}
+
 	// 创建TraderManager
 	traderManager := manager.NewTraderManager()
 
@@ -349,7 +410,8 @@ func main() {
 	}()
 
 	// 启动流行情数据 - 默认使用所有交易员设置的币种 如果没有设置币种 则优先使用系统默认
-	go market.NewWSMonitor(150).Start(database.GetCustomCoins())
+	// K线周期为所有交易员配置周期的并集（后续新增的周期在首次使用时动态订阅）
+	go market.NewWSMonitor(150, database.GetTimeframes()...).Start(database.GetCustomCoins())
 	//go market.NewWSMonitor(150).Start([]string{}) //这里是一个使用方式 传入空的话 则使用market市场的所有币种
 	// 设置优雅退出
 	sigChan := make(chan os.Signal, 1)
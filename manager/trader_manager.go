@@ -6,6 +6,10 @@ import (
 	"fmt"
 	"log"
 	"nofx/config"
+	"nofx/decision"
+	"nofx/market"
+	"nofx/mcp"
+	"nofx/pool"
 	"nofx/trader"
 	"sort"
 	"strconv"
@@ -181,6 +185,167 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 	return nil
 }
 
+// getFlattenOnRiskTrip 读取风控熔断时是否强制平仓的系统配置
+func getFlattenOnRiskTrip(database *config.Database) bool {
+	if database == nil {
+		return false
+	}
+	val, _ := database.GetSystemConfig("flatten_on_risk_trip")
+	return val == "true"
+}
+
+// getAIPriceTable 读取AI价格表（系统配置 ai_price_table 覆盖内置价格）
+func getAIPriceTable(database *config.Database) mcp.PriceTable {
+	if database == nil {
+		return mcp.DefaultPriceTable()
+	}
+	val, _ := database.GetSystemConfig("ai_price_table")
+	table, err := mcp.ParsePriceTable(val)
+	if err != nil {
+		log.Printf("⚠️  %v，使用内置价格表", err)
+	}
+	return table
+}
+
+// riskLimitsFromRecord 从交易员配置读取开仓前风控参数
+func riskLimitsFromRecord(traderCfg *config.TraderRecord) decision.RiskLimits {
+	return decision.RiskLimits{
+		MaxPositionPct:      traderCfg.RiskMaxPositionPct,
+		MaxMarginUsagePct:   traderCfg.RiskMaxMarginUsagePct,
+		MaxPositions:        traderCfg.RiskMaxPositions,
+		MinRiskReward:       traderCfg.RiskMinRiskReward,
+		RiskUSDTolerancePct: traderCfg.RiskUSDTolerancePct,
+		ClampOversize:       traderCfg.RiskClampOversize,
+		MaxSlippagePct:      traderCfg.RiskMaxSlippagePct,
+	}
+}
+
+// breakEvenFromRecord 从交易员配置读取保本止损参数
+func breakEvenFromRecord(traderCfg *config.TraderRecord) trader.BreakEvenConfig {
+	return trader.BreakEvenConfig{
+		TriggerR:   traderCfg.BreakEvenTriggerR,
+		TriggerPct: traderCfg.BreakEvenTriggerPct,
+	}
+}
+
+// timeframesFromRecord 解析交易员配置的K线周期（配置无效时记录日志并使用默认周期）
+func timeframesFromRecord(traderCfg *config.TraderRecord) []string {
+	timeframes, err := market.ParseTimeframes(traderCfg.Timeframes)
+	if err != nil {
+		log.Printf("⚠️  交易员 %s 的K线周期配置无效，使用默认周期: %v", traderCfg.Name, err)
+		return append([]string(nil), market.DefaultTimeframes...)
+	}
+	return timeframes
+}
+
+// indicatorsFromRecord 解析交易员配置的注册指标（配置无效时记录日志并忽略，使用模板声明的指标）
+func indicatorsFromRecord(traderCfg *config.TraderRecord) []market.IndicatorRequest {
+	indicators, err := market.ParseIndicatorSpecs(traderCfg.Indicators)
+	if err != nil {
+		log.Printf("⚠️  交易员 %s 的指标配置无效，忽略: %v", traderCfg.Name, err)
+		return nil
+	}
+	return indicators
+}
+
+// signalProvidersFromRecord 解析交易员配置的可插拔信号源（配置无效时忽略并记录日志）
+// 未配置信号源、未指定交易币种且没有任何币种池/OI Top API时，使用本地筛选器代替静态默认币种
+func signalProvidersFromRecord(traderCfg *config.TraderRecord, database *config.Database, coinPoolURL, oiTopURL string) []pool.ProviderConfig {
+	providers, err := pool.ParseProviderConfigs(traderCfg.SignalProviders)
+	if err != nil {
+		log.Printf("⚠️  交易员 %s 的信号源配置无效，使用默认币种: %v", traderCfg.Name, err)
+		return nil
+	}
+	if len(providers) > 0 || strings.TrimSpace(traderCfg.TradingSymbols) != "" || database == nil {
+		return providers
+	}
+
+	if coinPoolURL == "" {
+		coinPoolURL, _ = database.GetSystemConfig("coin_pool_api_url")
+	}
+	if oiTopURL == "" {
+		oiTopURL, _ = database.GetSystemConfig("oi_top_api_url")
+	}
+	useDefaultCoins, _ := database.GetSystemConfig("use_default_coins")
+	localScreener, _ := database.GetSystemConfig("local_screener")
+	if coinPoolURL != "" || oiTopURL != "" || useDefaultCoins != "false" || localScreener == "false" {
+		return nil
+	}
+
+	screener := pool.ProviderConfig{Type: pool.ProviderScreener, Name: pool.ProviderScreener, Weight: 1, Limit: 20}
+	if val, _ := database.GetSystemConfig("screener_min_volume"); val != "" {
+		screener.MinVolume, _ = strconv.ParseFloat(val, 64)
+	}
+	if val, _ := database.GetSystemConfig("screener_min_days"); val != "" {
+		screener.MinListingDays, _ = strconv.Atoi(val)
+	}
+	if val, _ := database.GetSystemConfig("screener_exclude"); val != "" {
+		for _, symbol := range strings.Split(val, ",") {
+			if symbol = strings.TrimSpace(symbol); symbol != "" {
+				screener.Exclude = append(screener.Exclude, symbol)
+			}
+		}
+	}
+	log.Printf("🔍 交易员 %s 未配置币种池API，使用本地筛选器生成候选币种", traderCfg.Name)
+	return []pool.ProviderConfig{screener}
+}
+
+// aiFallbacksFromRecord 按交易员配置的顺序解析备用AI模型（跳过不存在、未启用或与主模型相同的配置）
+func aiFallbacksFromRecord(traderCfg *config.TraderRecord, database *config.Database) []trader.AIFallbackConfig {
+	if strings.TrimSpace(traderCfg.FallbackAIModelIDs) == "" {
+		return nil
+	}
+
+	aiModels, err := database.GetAIModels(traderCfg.UserID)
+	if err != nil {
+		log.Printf("⚠️  获取备用AI模型配置失败: %v", err)
+		return nil
+	}
+
+	var fallbacks []trader.AIFallbackConfig
+	for _, id := range strings.Split(traderCfg.FallbackAIModelIDs, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || id == traderCfg.AIModelID {
+			continue
+		}
+
+		var modelCfg *config.AIModelConfig
+		for _, model := range aiModels {
+			if model.ID == id {
+				modelCfg = model
+				break
+			}
+		}
+		// 兼容旧数据：按 provider 匹配
+		if modelCfg == nil {
+			for _, model := range aiModels {
+				if model.Provider == id {
+					modelCfg = model
+					break
+				}
+			}
+		}
+
+		if modelCfg == nil {
+			log.Printf("⚠️  交易员 %s 的备用AI模型 %s 不存在，跳过", traderCfg.Name, id)
+			continue
+		}
+		if !modelCfg.Enabled {
+			log.Printf("⚠️  交易员 %s 的备用AI模型 %s 未启用，跳过", traderCfg.Name, id)
+			continue
+		}
+
+		fallbacks = append(fallbacks, trader.AIFallbackConfig{
+			ID:              modelCfg.ID,
+			Provider:        modelCfg.Provider,
+			APIKey:          modelCfg.APIKey,
+			CustomAPIURL:    modelCfg.CustomAPIURL,
+			CustomModelName: modelCfg.CustomModelName,
+		})
+	}
+	return fallbacks
+}
+
 // addTraderFromConfig 内部方法：从配置添加交易员（不加锁，因为调用方已加锁）
 func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModelCfg *config.AIModelConfig, exchangeCfg *config.ExchangeConfig, coinPoolURL, oiTopURL string, maxDailyLoss, maxDrawdown float64, stopTradingMinutes int, defaultCoins []string, database *config.Database, userID string) error {
 	if _, exists := tm.traders[traderCfg.ID]; exists {
@@ -211,6 +376,11 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		effectiveCoinPoolURL = coinPoolURL
 		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
 	}
+	var effectiveOITopURL string
+	if traderCfg.UseOITop && oiTopURL != "" {
+		effectiveOITopURL = oiTopURL
+		log.Printf("✓ 交易员 %s 启用 OI TOP 信号源: %s", traderCfg.Name, oiTopURL)
+	}
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
@@ -223,6 +393,11 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		HyperliquidPrivateKey: "",
 		HyperliquidTestnet:    exchangeCfg.Testnet,
 		CoinPoolAPIURL:        effectiveCoinPoolURL,
+		OITopAPIURL:           effectiveOITopURL,
+		SignalProviders:       signalProvidersFromRecord(traderCfg, database, effectiveCoinPoolURL, effectiveOITopURL),
+		Timeframes:            timeframesFromRecord(traderCfg),
+		Indicators:            indicatorsFromRecord(traderCfg),
+		PaperPriceExchange:    traderCfg.PaperPriceExchange,
 		UseQwen:               aiModelCfg.Provider == "qwen",
 		DeepSeekKey:           "",
 		QwenKey:               "",
@@ -235,6 +410,11 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		MaxDailyLoss:          maxDailyLoss,
 		MaxDrawdown:           maxDrawdown,
 		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
+		FlattenOnRiskTrip:     getFlattenOnRiskTrip(database),
+		RiskLimits:            riskLimitsFromRecord(traderCfg),
+		BreakEven:             breakEvenFromRecord(traderCfg),
+		AIFallbacks:           aiFallbacksFromRecord(traderCfg, database),
+		AIPriceTable:          getAIPriceTable(database),
 		IsCrossMargin:         traderCfg.IsCrossMargin,
 		DefaultCoins:          defaultCoins,
 		TradingCoins:          tradingCoins,
@@ -257,6 +437,19 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.OKXSecretKey = exchangeCfg.SecretKey
 		traderConfig.OKXPassphrase = exchangeCfg.OKXPassphrase
 		traderConfig.OKXTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "bybit" {
+		traderConfig.BybitAPIKey = exchangeCfg.APIKey
+		traderConfig.BybitSecretKey = exchangeCfg.SecretKey
+		traderConfig.BybitTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "bitget" {
+		traderConfig.BitgetAPIKey = exchangeCfg.APIKey
+		traderConfig.BitgetSecretKey = exchangeCfg.SecretKey
+		traderConfig.BitgetPassphrase = exchangeCfg.OKXPassphrase // Bitget与OKX共用passphrase字段
+		traderConfig.BitgetTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "gate" {
+		traderConfig.GateAPIKey = exchangeCfg.APIKey
+		traderConfig.GateSecretKey = exchangeCfg.SecretKey
+		traderConfig.GateTestnet = exchangeCfg.Testnet
 	}
 
 	// 根据AI模型设置API密钥
@@ -268,6 +461,8 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.GoogleAIKey = aiModelCfg.APIKey
 	} else if aiModelCfg.Provider == "chatgpt" {
 		traderConfig.ChatGPTKey = aiModelCfg.APIKey
+	} else if aiModelCfg.Provider == "anthropic" {
+		traderConfig.AnthropicKey = aiModelCfg.APIKey
 	}
 
 	// 创建trader实例
@@ -327,6 +522,11 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		effectiveCoinPoolURL = coinPoolURL
 		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
 	}
+	var effectiveOITopURL string
+	if traderCfg.UseOITop && oiTopURL != "" {
+		effectiveOITopURL = oiTopURL
+		log.Printf("✓ 交易员 %s 启用 OI TOP 信号源: %s", traderCfg.Name, oiTopURL)
+	}
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
@@ -339,6 +539,11 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		HyperliquidPrivateKey: "",
 		HyperliquidTestnet:    exchangeCfg.Testnet,
 		CoinPoolAPIURL:        effectiveCoinPoolURL,
+		OITopAPIURL:           effectiveOITopURL,
+		SignalProviders:       signalProvidersFromRecord(traderCfg, database, effectiveCoinPoolURL, effectiveOITopURL),
+		Timeframes:            timeframesFromRecord(traderCfg),
+		Indicators:            indicatorsFromRecord(traderCfg),
+		PaperPriceExchange:    traderCfg.PaperPriceExchange,
 		UseQwen:               aiModelCfg.Provider == "qwen",
 		DeepSeekKey:           "",
 		QwenKey:               "",
@@ -351,6 +556,11 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		MaxDailyLoss:          maxDailyLoss,
 		MaxDrawdown:           maxDrawdown,
 		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
+		FlattenOnRiskTrip:     getFlattenOnRiskTrip(database),
+		RiskLimits:            riskLimitsFromRecord(traderCfg),
+		BreakEven:             breakEvenFromRecord(traderCfg),
+		AIFallbacks:           aiFallbacksFromRecord(traderCfg, database),
+		AIPriceTable:          getAIPriceTable(database),
 		IsCrossMargin:         traderCfg.IsCrossMargin,
 		DefaultCoins:          defaultCoins,
 		TradingCoins:          tradingCoins,
@@ -372,6 +582,19 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.OKXSecretKey = exchangeCfg.SecretKey
 		traderConfig.OKXPassphrase = exchangeCfg.OKXPassphrase
 		traderConfig.OKXTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "bybit" {
+		traderConfig.BybitAPIKey = exchangeCfg.APIKey
+		traderConfig.BybitSecretKey = exchangeCfg.SecretKey
+		traderConfig.BybitTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "bitget" {
+		traderConfig.BitgetAPIKey = exchangeCfg.APIKey
+		traderConfig.BitgetSecretKey = exchangeCfg.SecretKey
+		traderConfig.BitgetPassphrase = exchangeCfg.OKXPassphrase // Bitget与OKX共用passphrase字段
+		traderConfig.BitgetTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "gate" {
+		traderConfig.GateAPIKey = exchangeCfg.APIKey
+		traderConfig.GateSecretKey = exchangeCfg.SecretKey
+		traderConfig.GateTestnet = exchangeCfg.Testnet
 	}
 
 	// 根据AI模型设置API密钥
@@ -383,6 +606,8 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.GoogleAIKey = aiModelCfg.APIKey
 	} else if aiModelCfg.Provider == "chatgpt" {
 		traderConfig.ChatGPTKey = aiModelCfg.APIKey
+	} else if aiModelCfg.Provider == "anthropic" {
+		traderConfig.AnthropicKey = aiModelCfg.APIKey
 	}
 
 	// 创建trader实例
@@ -459,15 +684,36 @@ func (tm *TraderManager) StartAll() {
 	}
 }
 
-// StopAll 停止所有trader
+// StopAll 停止所有运行中的trader并释放资源（进程退出时调用）
 func (tm *TraderManager) StopAll() {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
 
 	log.Println("⏹  停止所有Trader...")
 	for _, t := range tm.traders {
+		stopAndClose(t)
+	}
+}
+
+// RemoveTrader 从内存中移除交易员（交易员被删除时调用），运行中的先停止，再释放资源
+func (tm *TraderManager) RemoveTrader(id string) {
+	tm.mu.Lock()
+	t, exists := tm.traders[id]
+	delete(tm.traders, id)
+	tm.mu.Unlock()
+
+	if exists {
+		stopAndClose(t)
+		log.Printf("🗑  交易员 %s 已从内存移除", t.GetName())
+	}
+}
+
+// stopAndClose 停止运行中的交易员并关闭其决策日志等资源
+func stopAndClose(t *trader.AutoTrader) {
+	if isRunning, ok := t.GetStatus()["is_running"].(bool); ok && isRunning {
 		t.Stop()
 	}
+	t.Close()
 }
 
 // GetComparisonData 获取对比数据
@@ -491,11 +737,11 @@ func (tm *TraderManager) GetComparisonData() (map[string]interface{}, error) {
 			"trader_name":     t.GetName(),
 			"ai_model":        t.GetAIModel(),
 			"exchange":        t.GetExchange(),
-			"total_equity":    account["total_equity"],
-			"total_pnl":       account["total_pnl"],
-			"total_pnl_pct":   account["total_pnl_pct"],
-			"position_count":  account["position_count"],
-			"margin_used_pct": account["margin_used_pct"],
+			"total_equity":    account.TotalEquity,
+			"total_pnl":       account.TotalPnL,
+			"total_pnl_pct":   account.TotalPnLPct,
+			"position_count":  account.PositionCount,
+			"margin_used_pct": account.MarginUsedPct,
 			"call_count":      status["call_count"],
 			"is_running":      status["is_running"],
 		})
@@ -583,17 +829,17 @@ func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) [
 
 	// 并发获取每个交易员的数据
 	for i, t := range traders {
-		go func(index int, trader *trader.AutoTrader) {
+		go func(index int, at *trader.AutoTrader) {
 			// 设置单个交易员的超时时间为3秒
 			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 			defer cancel()
 
 			// 使用通道来实现超时控制
-			accountChan := make(chan map[string]interface{}, 1)
+			accountChan := make(chan *trader.AccountInfo, 1)
 			errorChan := make(chan error, 1)
 
 			go func() {
-				account, err := trader.GetAccountInfo()
+				account, err := at.GetAccountInfo()
 				if err != nil {
 					errorChan <- err
 				} else {
@@ -601,32 +847,32 @@ func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) [
 				}
 			}()
 
-			status := trader.GetStatus()
+			status := at.GetStatus()
 			var traderData map[string]interface{}
 
 			select {
 			case account := <-accountChan:
 				// 成功获取账户信息
 				traderData = map[string]interface{}{
-					"trader_id":       trader.GetID(),
-					"trader_name":     trader.GetName(),
-					"ai_model":        trader.GetAIModel(),
-					"exchange":        trader.GetExchange(),
-					"total_equity":    account["total_equity"],
-					"total_pnl":       account["total_pnl"],
-					"total_pnl_pct":   account["total_pnl_pct"],
-					"position_count":  account["position_count"],
-					"margin_used_pct": account["margin_used_pct"],
+					"trader_id":       at.GetID(),
+					"trader_name":     at.GetName(),
+					"ai_model":        at.GetAIModel(),
+					"exchange":        at.GetExchange(),
+					"total_equity":    account.TotalEquity,
+					"total_pnl":       account.TotalPnL,
+					"total_pnl_pct":   account.TotalPnLPct,
+					"position_count":  account.PositionCount,
+					"margin_used_pct": account.MarginUsedPct,
 					"is_running":      status["is_running"],
 				}
 			case err := <-errorChan:
 				// 获取账户信息失败
-				log.Printf("⚠️ 获取交易员 %s 账户信息失败: %v", trader.GetID(), err)
+				log.Printf("⚠️ 获取交易员 %s 账户信息失败: %v", at.GetID(), err)
 				traderData = map[string]interface{}{
-					"trader_id":       trader.GetID(),
-					"trader_name":     trader.GetName(),
-					"ai_model":        trader.GetAIModel(),
-					"exchange":        trader.GetExchange(),
+					"trader_id":       at.GetID(),
+					"trader_name":     at.GetName(),
+					"ai_model":        at.GetAIModel(),
+					"exchange":        at.GetExchange(),
 					"total_equity":    0.0,
 					"total_pnl":       0.0,
 					"total_pnl_pct":   0.0,
@@ -637,12 +883,12 @@ func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) [
 				}
 			case <-ctx.Done():
 				// 超时
-				log.Printf("⏰ 获取交易员 %s 账户信息超时", trader.GetID())
+				log.Printf("⏰ 获取交易员 %s 账户信息超时", at.GetID())
 				traderData = map[string]interface{}{
-					"trader_id":       trader.GetID(),
-					"trader_name":     trader.GetName(),
-					"ai_model":        trader.GetAIModel(),
-					"exchange":        trader.GetExchange(),
+					"trader_id":       at.GetID(),
+					"trader_name":     at.GetName(),
+					"ai_model":        at.GetAIModel(),
+					"exchange":        at.GetExchange(),
 					"total_equity":    0.0,
 					"total_pnl":       0.0,
 					"total_pnl_pct":   0.0,
@@ -888,6 +1134,11 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		effectiveCoinPoolURL = coinPoolURL
 		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
 	}
+	var effectiveOITopURL string
+	if traderCfg.UseOITop && oiTopURL != "" {
+		effectiveOITopURL = oiTopURL
+		log.Printf("✓ 交易员 %s 启用 OI TOP 信号源: %s", traderCfg.Name, oiTopURL)
+	}
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
@@ -900,12 +1151,22 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		AltcoinLeverage:      traderCfg.AltcoinLeverage,
 		ScanInterval:         time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
 		CoinPoolAPIURL:       effectiveCoinPoolURL,
+		OITopAPIURL:          effectiveOITopURL,
+		SignalProviders:      signalProvidersFromRecord(traderCfg, database, effectiveCoinPoolURL, effectiveOITopURL),
+		Timeframes:           timeframesFromRecord(traderCfg),
+		Indicators:           indicatorsFromRecord(traderCfg),
+		PaperPriceExchange:   traderCfg.PaperPriceExchange,
 		CustomAPIURL:         aiModelCfg.CustomAPIURL,    // 自定义API URL
 		CustomModelName:      aiModelCfg.CustomModelName, // 自定义模型名称
 		UseQwen:              aiModelCfg.Provider == "qwen",
 		MaxDailyLoss:         maxDailyLoss,
 		MaxDrawdown:          maxDrawdown,
 		StopTradingTime:      time.Duration(stopTradingMinutes) * time.Minute,
+		FlattenOnRiskTrip:    getFlattenOnRiskTrip(database),
+		RiskLimits:           riskLimitsFromRecord(traderCfg),
+		BreakEven:            breakEvenFromRecord(traderCfg),
+		AIFallbacks:          aiFallbacksFromRecord(traderCfg, database),
+		AIPriceTable:         getAIPriceTable(database),
 		IsCrossMargin:        traderCfg.IsCrossMargin,
 		DefaultCoins:         defaultCoins,
 		TradingCoins:         tradingCoins,
@@ -929,6 +1190,19 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		traderConfig.OKXSecretKey = exchangeCfg.SecretKey
 		traderConfig.OKXPassphrase = exchangeCfg.OKXPassphrase
 		traderConfig.OKXTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "bybit" {
+		traderConfig.BybitAPIKey = exchangeCfg.APIKey
+		traderConfig.BybitSecretKey = exchangeCfg.SecretKey
+		traderConfig.BybitTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "bitget" {
+		traderConfig.BitgetAPIKey = exchangeCfg.APIKey
+		traderConfig.BitgetSecretKey = exchangeCfg.SecretKey
+		traderConfig.BitgetPassphrase = exchangeCfg.OKXPassphrase // Bitget与OKX共用passphrase字段
+		traderConfig.BitgetTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "gate" {
+		traderConfig.GateAPIKey = exchangeCfg.APIKey
+		traderConfig.GateSecretKey = exchangeCfg.SecretKey
+		traderConfig.GateTestnet = exchangeCfg.Testnet
 	}
 
 	// 根据AI模型设置API密钥
@@ -940,6 +1214,8 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		traderConfig.GoogleAIKey = aiModelCfg.APIKey
 	} else if aiModelCfg.Provider == "chatgpt" {
 		traderConfig.ChatGPTKey = aiModelCfg.APIKey
+	} else if aiModelCfg.Provider == "anthropic" {
+		traderConfig.AnthropicKey = aiModelCfg.APIKey
 	}
 
 	// 创建trader实例
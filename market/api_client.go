@@ -47,6 +47,30 @@ func (c *APIClient) GetExchangeInfo() (*ExchangeInfo, error) {
 	return &exchangeInfo, nil
 }
 
+// Get24hrTickers 获取所有合约的24小时行情
+func (c *APIClient) Get24hrTickers() ([]Ticker24hr, error) {
+	url := fmt.Sprintf("%s/fapi/v1/ticker/24hr", baseURL)
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var tickers []Ticker24hr
+	if err := json.Unmarshal(body, &tickers); err != nil {
+		return nil, err
+	}
+	return tickers, nil
+}
+
 func (c *APIClient) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
 	url := fmt.Sprintf("%s/fapi/v1/klines", baseURL)
 	req, err := http.NewRequest("GET", url, nil)
@@ -0,0 +1,244 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	bitgetBaseURL     = "https://api.bitget.com"
+	bitgetProductType = "USDT-FUTURES"
+)
+
+// bitgetGranularities 统一K线周期 -> Bitget granularity 参数（Bitget 不支持 8h）
+var bitgetGranularities = map[string]string{
+	"1m":  "1m",
+	"3m":  "3m",
+	"5m":  "5m",
+	"15m": "15m",
+	"30m": "30m",
+	"1h":  "1H",
+	"2h":  "2H",
+	"4h":  "4H",
+	"6h":  "6H",
+	"12h": "12H",
+	"1d":  "1D",
+	"3d":  "3D",
+	"1w":  "1W",
+}
+
+// BitgetAPIClient Bitget USDT永续合约行情客户端（V2公共接口）
+type BitgetAPIClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewBitgetAPIClient() *BitgetAPIClient {
+	return &BitgetAPIClient{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		baseURL: bitgetBaseURL,
+	}
+}
+
+// get 调用Bitget公共接口并解析data字段
+func (c *BitgetAPIClient) get(path string, params url.Values, result interface{}) error {
+	params.Set("productType", bitgetProductType)
+	resp, err := c.client.Get(c.baseURL + path + "?" + params.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var bitgetResponse struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &bitgetResponse); err != nil {
+		return fmt.Errorf("解析Bitget响应失败: %w, 原始响应: %s", err, string(body))
+	}
+	if bitgetResponse.Code != "00000" {
+		return fmt.Errorf("Bitget API错误: code=%s, msg=%s", bitgetResponse.Code, bitgetResponse.Msg)
+	}
+	if err := json.Unmarshal(bitgetResponse.Data, result); err != nil {
+		return fmt.Errorf("解析Bitget响应数据失败: %w", err)
+	}
+	return nil
+}
+
+// GetKlines 获取Bitget K线数据
+func (c *BitgetAPIClient) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	startTime := time.Now()
+	granularity, ok := bitgetGranularities[interval]
+	if !ok {
+		return nil, fmt.Errorf("Bitget不支持K线周期: %s", interval)
+	}
+
+	// 格式: [startTime, open, high, low, close, baseVolume, quoteVolume]
+	var rows [][]string
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("granularity", granularity)
+	params.Set("limit", strconv.Itoa(limit))
+	if err := c.get("/api/v2/mix/market/candles", params, &rows); err != nil {
+		log.Printf("❌ Bitget API [K线] %s %s: 请求失败: %v", symbol, interval, err)
+		return nil, err
+	}
+
+	closeTimeOffset := int64(0)
+	if d, ok := IntervalDuration(interval); ok {
+		closeTimeOffset = d.Milliseconds()
+	}
+
+	klines := make([]Kline, 0, len(rows))
+	for _, kr := range rows {
+		if len(kr) < 6 {
+			continue
+		}
+		kline := Kline{}
+		kline.OpenTime, _ = strconv.ParseInt(kr[0], 10, 64)
+		kline.Open, _ = strconv.ParseFloat(kr[1], 64)
+		kline.High, _ = strconv.ParseFloat(kr[2], 64)
+		kline.Low, _ = strconv.ParseFloat(kr[3], 64)
+		kline.Close, _ = strconv.ParseFloat(kr[4], 64)
+		kline.Volume, _ = strconv.ParseFloat(kr[5], 64)
+		kline.CloseTime = kline.OpenTime + closeTimeOffset - 1
+		klines = append(klines, kline)
+	}
+	// 按时间升序排列（与其他交易所保持一致）
+	sort.Slice(klines, func(i, j int) bool { return klines[i].OpenTime < klines[j].OpenTime })
+
+	log.Printf("✓ Bitget API [K线] %s %s: 获取 %d 根K线，总耗时 %v", symbol, interval, len(klines), time.Since(startTime))
+	return klines, nil
+}
+
+// bitgetTicker 行情快照（价格和资金费率共用）
+type bitgetTicker struct {
+	LastPr      string `json:"lastPr"`
+	MarkPrice   string `json:"markPrice"`
+	FundingRate string `json:"fundingRate"`
+}
+
+// getTicker 获取Bitget行情快照
+func (c *BitgetAPIClient) getTicker(symbol string) (*bitgetTicker, error) {
+	var tickers []bitgetTicker
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	if err := c.get("/api/v2/mix/market/ticker", params, &tickers); err != nil {
+		return nil, err
+	}
+	if len(tickers) == 0 {
+		return nil, fmt.Errorf("Bitget未返回 %s 的行情", symbol)
+	}
+	return &tickers[0], nil
+}
+
+// GetCurrentPrice 获取Bitget实时价格
+func (c *BitgetAPIClient) GetCurrentPrice(symbol string) (float64, error) {
+	ticker, err := c.getTicker(symbol)
+	if err != nil {
+		log.Printf("❌ Bitget API [价格] %s: 请求失败: %v", symbol, err)
+		return 0, err
+	}
+	price, err := strconv.ParseFloat(ticker.LastPr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析Bitget价格失败: %w", err)
+	}
+	return price, nil
+}
+
+// GetOpenInterest 获取Bitget持仓量（接口只提供当前值，平均值与最新值相同）
+func (c *BitgetAPIClient) GetOpenInterest(symbol string) (*OIData, error) {
+	var result struct {
+		OpenInterestList []struct {
+			Size string `json:"size"`
+		} `json:"openInterestList"`
+	}
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	if err := c.get("/api/v2/mix/market/open-interest", params, &result); err != nil {
+		log.Printf("❌ Bitget API [持仓量] %s: 请求失败: %v", symbol, err)
+		return nil, err
+	}
+	if len(result.OpenInterestList) == 0 {
+		return nil, fmt.Errorf("Bitget未返回 %s 的持仓量", symbol)
+	}
+
+	latest, _ := strconv.ParseFloat(result.OpenInterestList[0].Size, 64)
+	return &OIData{
+		Latest:  latest,
+		Average: latest,
+	}, nil
+}
+
+// GetFundingRate 获取Bitget当前资金费率
+func (c *BitgetAPIClient) GetFundingRate(symbol string) (float64, error) {
+	ticker, err := c.getTicker(symbol)
+	if err != nil {
+		log.Printf("❌ Bitget API [资金费率] %s: 请求失败: %v", symbol, err)
+		return 0, err
+	}
+	rate, _ := strconv.ParseFloat(ticker.FundingRate, 64)
+	return rate, nil
+}
+
+// GetOrderBook 获取Bitget永续合约盘口（数量为基础币数量）
+func (c *BitgetAPIClient) GetOrderBook(symbol string, limit int) (*OrderBook, error) {
+	// merge-depth 只支持 1/5/15/50/max 档
+	depthLimit := "max"
+	if limit <= 50 {
+		depthLimit = "50"
+	}
+	var result struct {
+		Bids [][]json.Number `json:"bids"`
+		Asks [][]json.Number `json:"asks"`
+		Ts   json.Number     `json:"ts"`
+	}
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("limit", depthLimit)
+	if err := c.get("/api/v2/mix/market/merge-depth", params, &result); err != nil {
+		return nil, err
+	}
+
+	book := &OrderBook{
+		Symbol: symbol,
+		Bids:   parseBookLevels(numberLevels(result.Bids, limit), 1),
+		Asks:   parseBookLevels(numberLevels(result.Asks, limit), 1),
+		Time:   time.Now(),
+	}
+	if ts, err := result.Ts.Int64(); err == nil && ts > 0 {
+		book.Time = time.UnixMilli(ts)
+	}
+	return book, nil
+}
+
+// numberLevels 将数字格式的盘口档位转换为字符串格式，并截取前 limit 档
+func numberLevels(raw [][]json.Number, limit int) [][]string {
+	if limit > 0 && len(raw) > limit {
+		raw = raw[:limit]
+	}
+	levels := make([][]string, 0, len(raw))
+	for _, entry := range raw {
+		level := make([]string, len(entry))
+		for i, v := range entry {
+			level[i] = v.String()
+		}
+		levels = append(levels, level)
+	}
+	return levels
+}
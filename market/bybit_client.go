@@ -0,0 +1,236 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	bybitBaseURL = "https://api.bybit.com"
+)
+
+// bybitIntervals 统一K线周期 -> Bybit interval 参数（Bybit 不支持 8h、3d）
+var bybitIntervals = map[string]string{
+	"1m":  "1",
+	"3m":  "3",
+	"5m":  "5",
+	"15m": "15",
+	"30m": "30",
+	"1h":  "60",
+	"2h":  "120",
+	"4h":  "240",
+	"6h":  "360",
+	"12h": "720",
+	"1d":  "D",
+	"1w":  "W",
+}
+
+// BybitAPIClient Bybit USDT永续合约行情客户端（V5公共接口）
+type BybitAPIClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewBybitAPIClient() *BybitAPIClient {
+	return &BybitAPIClient{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		baseURL: bybitBaseURL,
+	}
+}
+
+// get 调用Bybit公共接口并解析result字段
+func (c *BybitAPIClient) get(path string, params url.Values, result interface{}) error {
+	params.Set("category", "linear")
+	resp, err := c.client.Get(c.baseURL + path + "?" + params.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var bybitResponse struct {
+		RetCode int             `json:"retCode"`
+		RetMsg  string          `json:"retMsg"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &bybitResponse); err != nil {
+		return fmt.Errorf("解析Bybit响应失败: %w, 原始响应: %s", err, string(body))
+	}
+	if bybitResponse.RetCode != 0 {
+		return fmt.Errorf("Bybit API错误: retCode=%d, retMsg=%s", bybitResponse.RetCode, bybitResponse.RetMsg)
+	}
+	if err := json.Unmarshal(bybitResponse.Result, result); err != nil {
+		return fmt.Errorf("解析Bybit响应数据失败: %w", err)
+	}
+	return nil
+}
+
+// GetKlines 获取Bybit K线数据
+func (c *BybitAPIClient) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	startTime := time.Now()
+	bybitInterval, ok := bybitIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("Bybit不支持K线周期: %s", interval)
+	}
+
+	var result struct {
+		List [][]string `json:"list"`
+	}
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("interval", bybitInterval)
+	params.Set("limit", strconv.Itoa(limit))
+	if err := c.get("/v5/market/kline", params, &result); err != nil {
+		log.Printf("❌ Bybit API [K线] %s %s: 请求失败: %v", symbol, interval, err)
+		return nil, err
+	}
+
+	closeTimeOffset := int64(0)
+	if d, ok := IntervalDuration(interval); ok {
+		closeTimeOffset = d.Milliseconds()
+	}
+
+	// Bybit返回的数据是倒序的（最新的在前），需要反转
+	// 格式: [startTime, open, high, low, close, volume, turnover]
+	klines := make([]Kline, 0, len(result.List))
+	for i := len(result.List) - 1; i >= 0; i-- {
+		kr := result.List[i]
+		if len(kr) < 6 {
+			continue
+		}
+		kline := Kline{}
+		kline.OpenTime, _ = strconv.ParseInt(kr[0], 10, 64)
+		kline.Open, _ = strconv.ParseFloat(kr[1], 64)
+		kline.High, _ = strconv.ParseFloat(kr[2], 64)
+		kline.Low, _ = strconv.ParseFloat(kr[3], 64)
+		kline.Close, _ = strconv.ParseFloat(kr[4], 64)
+		kline.Volume, _ = strconv.ParseFloat(kr[5], 64)
+		kline.CloseTime = kline.OpenTime + closeTimeOffset - 1
+		klines = append(klines, kline)
+	}
+
+	log.Printf("✓ Bybit API [K线] %s %s: 获取 %d 根K线，总耗时 %v", symbol, interval, len(klines), time.Since(startTime))
+	return klines, nil
+}
+
+// bybitTicker 行情快照（价格和资金费率共用）
+type bybitTicker struct {
+	LastPrice   string `json:"lastPrice"`
+	MarkPrice   string `json:"markPrice"`
+	FundingRate string `json:"fundingRate"`
+}
+
+// getTicker 获取Bybit行情快照
+func (c *BybitAPIClient) getTicker(symbol string) (*bybitTicker, error) {
+	var result struct {
+		List []bybitTicker `json:"list"`
+	}
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	if err := c.get("/v5/market/tickers", params, &result); err != nil {
+		return nil, err
+	}
+	if len(result.List) == 0 {
+		return nil, fmt.Errorf("Bybit未返回 %s 的行情", symbol)
+	}
+	return &result.List[0], nil
+}
+
+// GetCurrentPrice 获取Bybit实时价格
+func (c *BybitAPIClient) GetCurrentPrice(symbol string) (float64, error) {
+	ticker, err := c.getTicker(symbol)
+	if err != nil {
+		log.Printf("❌ Bybit API [价格] %s: 请求失败: %v", symbol, err)
+		return 0, err
+	}
+	price, err := strconv.ParseFloat(ticker.LastPrice, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析Bybit价格失败: %w", err)
+	}
+	return price, nil
+}
+
+// GetOpenInterest 获取Bybit持仓量（最近一小时5分钟粒度的最新值和平均值）
+func (c *BybitAPIClient) GetOpenInterest(symbol string) (*OIData, error) {
+	var result struct {
+		List []struct {
+			OpenInterest string `json:"openInterest"`
+			Timestamp    string `json:"timestamp"`
+		} `json:"list"`
+	}
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("intervalTime", "5min")
+	params.Set("limit", "12")
+	if err := c.get("/v5/market/open-interest", params, &result); err != nil {
+		log.Printf("❌ Bybit API [持仓量] %s: 请求失败: %v", symbol, err)
+		return nil, err
+	}
+	if len(result.List) == 0 {
+		return nil, fmt.Errorf("Bybit未返回 %s 的持仓量", symbol)
+	}
+
+	// 最新的在前
+	latest, _ := strconv.ParseFloat(result.List[0].OpenInterest, 64)
+	sum := 0.0
+	for _, item := range result.List {
+		oi, _ := strconv.ParseFloat(item.OpenInterest, 64)
+		sum += oi
+	}
+	return &OIData{
+		Latest:  latest,
+		Average: sum / float64(len(result.List)),
+	}, nil
+}
+
+// GetFundingRate 获取Bybit当前资金费率
+func (c *BybitAPIClient) GetFundingRate(symbol string) (float64, error) {
+	ticker, err := c.getTicker(symbol)
+	if err != nil {
+		log.Printf("❌ Bybit API [资金费率] %s: 请求失败: %v", symbol, err)
+		return 0, err
+	}
+	rate, _ := strconv.ParseFloat(ticker.FundingRate, 64)
+	return rate, nil
+}
+
+// GetOrderBook 获取Bybit永续合约盘口（数量为基础币数量）
+func (c *BybitAPIClient) GetOrderBook(symbol string, limit int) (*OrderBook, error) {
+	if limit > 500 {
+		limit = 500
+	}
+	var result struct {
+		Bids [][]string `json:"b"`
+		Asks [][]string `json:"a"`
+		Ts   int64      `json:"ts"`
+	}
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("limit", strconv.Itoa(limit))
+	if err := c.get("/v5/market/orderbook", params, &result); err != nil {
+		return nil, err
+	}
+
+	book := &OrderBook{
+		Symbol: symbol,
+		Bids:   parseBookLevels(result.Bids, 1),
+		Asks:   parseBookLevels(result.Asks, 1),
+		Time:   time.Now(),
+	}
+	if result.Ts > 0 {
+		book.Time = time.UnixMilli(result.Ts)
+	}
+	return book, nil
+}
@@ -0,0 +1,131 @@
+package market
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// newTestBybitClient 创建连接到本地模拟服务的Bybit行情客户端，handler 返回 result 字段内容
+func newTestBybitClient(t *testing.T, handler func(path string, query url.Values) string) (*BybitAPIClient, *[]url.Values) {
+	t.Helper()
+	var queries []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		_, _ = io.WriteString(w, `{"retCode":0,"retMsg":"OK","result":`+handler(r.URL.Path, r.URL.Query())+`}`)
+	}))
+	t.Cleanup(server.Close)
+	return &BybitAPIClient{client: server.Client(), baseURL: server.URL}, &queries
+}
+
+func TestBybitClientKlines(t *testing.T) {
+	client, queries := newTestBybitClient(t, func(path string, query url.Values) string {
+		if path != "/v5/market/kline" {
+			t.Errorf("K线接口路径 = %s", path)
+		}
+		// Bybit返回倒序数据（最新的在前）
+		return `{"list":[
+			["1700003600000","101","103","100","102","20","2040"],
+			["1700000000000","100","102","99","101","10","1010"]]}`
+	})
+
+	klines, err := client.GetKlines("BTCUSDT", "1h", 2)
+	if err != nil {
+		t.Fatalf("获取K线失败: %v", err)
+	}
+	q := (*queries)[0]
+	if q.Get("category") != "linear" || q.Get("interval") != "60" || q.Get("symbol") != "BTCUSDT" || q.Get("limit") != "2" {
+		t.Errorf("K线请求参数 = %v", q)
+	}
+	if len(klines) != 2 {
+		t.Fatalf("应返回2根K线，实际 %d", len(klines))
+	}
+	if klines[0].OpenTime != 1700000000000 || klines[1].OpenTime != 1700003600000 {
+		t.Errorf("K线应按时间升序: %d, %d", klines[0].OpenTime, klines[1].OpenTime)
+	}
+	if k := klines[0]; k.Open != 100 || k.High != 102 || k.Low != 99 || k.Close != 101 || k.Volume != 10 {
+		t.Errorf("K线数据解析错误: %+v", k)
+	}
+	if klines[0].CloseTime != 1700000000000+3600000-1 {
+		t.Errorf("收盘时间 = %d", klines[0].CloseTime)
+	}
+}
+
+func TestBybitClientUnsupportedInterval(t *testing.T) {
+	client, queries := newTestBybitClient(t, func(string, url.Values) string { return `{}` })
+	if _, err := client.GetKlines("BTCUSDT", "8h", 10); err == nil {
+		t.Fatal("Bybit不支持8h周期，应返回错误")
+	}
+	if len(*queries) != 0 {
+		t.Error("不支持的周期不应发送请求")
+	}
+}
+
+func TestBybitClientTickerAndFunding(t *testing.T) {
+	client, _ := newTestBybitClient(t, func(path string, query url.Values) string {
+		return `{"list":[{"symbol":"BTCUSDT","lastPrice":"50123.5","markPrice":"50120","fundingRate":"0.0001"}]}`
+	})
+
+	price, err := client.GetCurrentPrice("BTCUSDT")
+	if err != nil || price != 50123.5 {
+		t.Errorf("GetCurrentPrice = %v, %v", price, err)
+	}
+	rate, err := client.GetFundingRate("BTCUSDT")
+	if err != nil || rate != 0.0001 {
+		t.Errorf("GetFundingRate = %v, %v", rate, err)
+	}
+}
+
+func TestBybitClientOpenInterest(t *testing.T) {
+	client, queries := newTestBybitClient(t, func(string, url.Values) string {
+		return `{"list":[{"openInterest":"300","timestamp":"3"},{"openInterest":"200","timestamp":"2"},{"openInterest":"100","timestamp":"1"}]}`
+	})
+
+	oi, err := client.GetOpenInterest("BTCUSDT")
+	if err != nil {
+		t.Fatalf("获取持仓量失败: %v", err)
+	}
+	if oi.Latest != 300 || oi.Average != 200 {
+		t.Errorf("持仓量 = %+v", oi)
+	}
+	if q := (*queries)[0]; q.Get("intervalTime") != "5min" {
+		t.Errorf("持仓量请求参数 = %v", q)
+	}
+}
+
+func TestBybitClientOrderBook(t *testing.T) {
+	client, queries := newTestBybitClient(t, func(string, url.Values) string {
+		return `{"b":[["50000","1.5"],["49999","2"]],"a":[["50001","0.5"]],"ts":1700000000000}`
+	})
+
+	book, err := client.GetOrderBook("BTCUSDT", 1000)
+	if err != nil {
+		t.Fatalf("获取盘口失败: %v", err)
+	}
+	if q := (*queries)[0]; q.Get("limit") != "500" {
+		t.Errorf("盘口深度应限制为500，实际 %s", q.Get("limit"))
+	}
+	if len(book.Bids) != 2 || len(book.Asks) != 1 {
+		t.Fatalf("盘口档位数 = %d/%d", len(book.Bids), len(book.Asks))
+	}
+	if book.Bids[0].Price != 50000 || book.Bids[0].Quantity != 1.5 || book.Asks[0].Price != 50001 {
+		t.Errorf("盘口数据解析错误: %+v %+v", book.Bids[0], book.Asks[0])
+	}
+	if book.Time.UnixMilli() != 1700000000000 {
+		t.Errorf("盘口时间 = %v", book.Time)
+	}
+}
+
+func TestBybitClientAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"retCode":10001,"retMsg":"params error","result":{}}`)
+	}))
+	defer server.Close()
+
+	client := &BybitAPIClient{client: server.Client(), baseURL: server.URL}
+	if _, err := client.GetCurrentPrice("BTCUSDT"); err == nil {
+		t.Fatal("retCode非0应返回错误")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // K线数据获取配置常量
@@ -24,48 +25,32 @@ func Get(symbol string) (*Data, error) {
 	return GetWithExchange(symbol, "binance")
 }
 
-// GetWithExchange 获取指定代币的市场数据（支持多交易所）
+// GetWithExchange 获取指定代币的市场数据（支持多交易所，使用默认K线周期）
 func GetWithExchange(symbol string, exchange string) (*Data, error) {
-	var klines3m, klines4h []Kline
-	var err error
+	return GetWithTimeframes(symbol, exchange, DefaultTimeframes)
+}
+
+// GetWithTimeframes 获取指定代币在指定K线周期下的市场数据（周期为空时使用默认周期）
+func GetWithTimeframes(symbol string, exchange string, timeframes []string) (*Data, error) {
+	timeframes, err := NormalizeTimeframes(timeframes)
+	if err != nil {
+		return nil, err
+	}
 	// 标准化symbol
 	symbol = Normalize(symbol)
-	
-	// 根据交易所选择K线数据源
-	if exchange == "okx" {
-		// OKX直接使用API客户端
-		okxClient := NewOKXAPIClient()
-		klines3m, err = okxClient.GetKlines(symbol, "3m", DefaultKlineLimit)
-		if err != nil {
-			return nil, fmt.Errorf("获取3分钟K线失败: %v", err)
-		}
-		klines4h, err = okxClient.GetKlines(symbol, "4h", DefaultKlineLimit)
-		if err != nil {
-			return nil, fmt.Errorf("获取4小时K线失败: %v", err)
-		}
-	} else {
-		// Binance优先使用WebSocket监控器（如果可用），否则使用API
-		if WSMonitorCli != nil {
-			klines3m, err = WSMonitorCli.GetCurrentKlines(symbol, "3m")
-			if err == nil {
-				klines4h, err = WSMonitorCli.GetCurrentKlines(symbol, "4h")
-				if err == nil {
-					goto gotKlines
-				}
-			}
-		}
-		// WebSocket失败，使用API客户端
-		binanceClient := NewAPIClient()
-		klines3m, err = binanceClient.GetKlines(symbol, "3m", DefaultKlineLimit)
-		if err != nil {
-			return nil, fmt.Errorf("获取3分钟K线失败: %v", err)
-		}
-		klines4h, err = binanceClient.GetKlines(symbol, "4h", DefaultKlineLimit)
+
+	klines := make(map[string][]Kline, len(timeframes))
+	for _, tf := range timeframes {
+		series, err := fetchKlines(symbol, exchange, tf)
 		if err != nil {
-			return nil, fmt.Errorf("获取4小时K线失败: %v", err)
+			return nil, err
 		}
+		klines[tf] = series
+	}
+	primary := klines[timeframes[0]]
+	if len(primary) == 0 {
+		return nil, fmt.Errorf("%s %s K线数据为空", symbol, timeframes[0])
 	}
-gotKlines:
 
 	// 根据交易所选择API客户端
 	var apiClient interface {
@@ -78,11 +63,10 @@ gotKlines:
 		GetFundingRate(string) (float64, error)
 	}
 	
-	if exchange == "okx" {
-		okxClient := NewOKXAPIClient()
-		apiClient = okxClient
-		oiClient = okxClient
-		fundingClient = okxClient
+	if venueClient := NewExchangeClient(exchange); venueClient != nil {
+		apiClient = venueClient
+		oiClient = venueClient
+		fundingClient = venueClient
 	} else {
 		binanceClient := NewAPIClient()
 		apiClient = binanceClient
@@ -95,35 +79,13 @@ gotKlines:
 	if err != nil {
 		// 如果获取实时价格失败，使用K线价格作为后备
 		log.Printf("⚠️  获取 %s 实时价格失败，使用K线价格: %v", symbol, err)
-		realTimePrice = klines3m[len(klines3m)-1].Close
+		realTimePrice = primary[len(primary)-1].Close
 	} else {
-		log.Printf("✓ 获取 %s 实时价格: %.4f (K线价格: %.4f)", symbol, realTimePrice, klines3m[len(klines3m)-1].Close)
+		log.Printf("✓ 获取 %s 实时价格: %.4f (K线价格: %.4f)", symbol, realTimePrice, primary[len(primary)-1].Close)
 	}
 	
 	// 使用实时价格
 	currentPrice := realTimePrice
-	currentEMA20 := calculateEMA(klines3m, 20)
-	currentMACD := calculateMACD(klines3m)
-	currentRSI7 := calculateRSI(klines3m, 7)
-
-	// 计算价格变化百分比
-	// 1小时价格变化 = 20个3分钟K线前的价格
-	priceChange1h := 0.0
-	if len(klines3m) >= 21 { // 至少需要21根K线 (当前 + 20根前)
-		price1hAgo := klines3m[len(klines3m)-21].Close
-		if price1hAgo > 0 {
-			priceChange1h = ((currentPrice - price1hAgo) / price1hAgo) * 100
-		}
-	}
-
-	// 4小时价格变化 = 1个4小时K线前的价格
-	priceChange4h := 0.0
-	if len(klines4h) >= 2 {
-		price4hAgo := klines4h[len(klines4h)-2].Close
-		if price4hAgo > 0 {
-			priceChange4h = ((currentPrice - price4hAgo) / price4hAgo) * 100
-		}
-	}
 
 	// 获取OI数据
 	var oiData *OIData
@@ -148,11 +110,88 @@ gotKlines:
 		fundingRate, _ = getFundingRate(symbol)
 	}
 
-	// 计算日内系列数据
-	intradayData := calculateIntradaySeries(klines3m)
+	data := BuildDataWithTimeframes(symbol, timeframes, klines, currentPrice, oiData, fundingRate)
+
+	// 获取盘口深度（失败不影响主流程）
+	if book, err := GetOrderBook(symbol, exchange, DefaultDepthLimit); err != nil {
+		log.Printf("⚠️  获取 %s 盘口失败: %v", symbol, err)
+	} else {
+		data.SetOrderBook(book)
+	}
+	return data, nil
+}
+
+// SetOrderBook 设置盘口快照并计算流动性特征
+func (d *Data) SetOrderBook(book *OrderBook) {
+	d.OrderBook = book
+	d.Depth = nil
+	if book != nil {
+		d.Depth = book.Features(nil)
+	}
+}
+
+// fetchKlines 根据交易所获取单个周期的K线
+func fetchKlines(symbol, exchange, interval string) ([]Kline, error) {
+	if venueClient := NewExchangeClient(exchange); venueClient != nil {
+		// OKX/Bybit/Bitget/Gate直接使用各自的API客户端
+		klines, err := venueClient.GetKlines(symbol, interval, DefaultKlineLimit)
+		if err != nil {
+			return nil, fmt.Errorf("获取%s K线失败: %v", interval, err)
+		}
+		return klines, nil
+	}
+
+	// Binance优先使用WebSocket监控器（如果可用），否则使用API
+	if WSMonitorCli != nil {
+		if klines, err := WSMonitorCli.GetCurrentKlines(symbol, interval); err == nil {
+			return klines, nil
+		}
+	}
+	klines, err := NewAPIClient().GetKlines(symbol, interval, DefaultKlineLimit)
+	if err != nil {
+		return nil, fmt.Errorf("获取%s K线失败: %v", interval, err)
+	}
+	return klines, nil
+}
+
+// BuildData 根据3分钟和4小时K线计算技术指标并组装市场数据（实时行情和回测共用）
+// klines3m/klines4h 需按时间升序排列且至少包含1根K线
+func BuildData(symbol string, klines3m, klines4h []Kline, currentPrice float64, oiData *OIData, fundingRate float64) *Data {
+	klines := map[string][]Kline{"3m": klines3m, "4h": klines4h}
+	return BuildDataWithTimeframes(symbol, DefaultTimeframes, klines, currentPrice, oiData, fundingRate)
+}
+
+// BuildDataWithTimeframes 根据多个周期的K线计算技术指标并组装市场数据
+// timeframes 需按周期从短到长排序（见 NormalizeTimeframes），各周期K线按时间升序排列
+// 当前值指标和日内序列使用最短周期，长期背景使用最长周期
+func BuildDataWithTimeframes(symbol string, timeframes []string, klines map[string][]Kline, currentPrice float64, oiData *OIData, fundingRate float64) *Data {
+	if oiData == nil {
+		oiData = &OIData{Latest: 0, Average: 0}
+	}
+	primary := klines[timeframes[0]]
+
+	currentEMA20 := calculateEMA(primary, 20)
+	currentMACD := calculateMACD(primary)
+	currentRSI7 := calculateRSI(primary, 7)
+
+	// 计算价格变化百分比（默认周期下：1小时 = 20根3分钟K线，4小时 = 1根4小时K线）
+	priceChange1h := priceChangeOver(timeframes, klines, currentPrice, time.Hour)
+	priceChange4h := priceChangeOver(timeframes, klines, currentPrice, 4*time.Hour)
+
+	// 计算各周期的序列数据和趋势背景
+	timeframeData := make([]*TimeframeData, 0, len(timeframes))
+	for _, tf := range timeframes {
+		timeframeData = append(timeframeData, &TimeframeData{
+			Interval: tf,
+			Series:   calculateIntradaySeries(klines[tf]),
+			Context:  calculateLongerTermData(klines[tf]),
+			Klines:   klines[tf],
+		})
+	}
 
-	// 计算长期数据
-	longerTermData := calculateLongerTermData(klines4h)
+	// 日内系列数据取最短周期，长期数据取最长周期
+	intradayData := timeframeData[0].Series
+	longerTermData := timeframeData[len(timeframeData)-1].Context
 
 	// V1.65新增：计算当前值的技术指标
 	var bollingerBands *BollingerBandsData
@@ -161,43 +200,43 @@ gotKlines:
 	var volumeMA *VolumeMAData
 	var obv float64
 
-	if len(klines3m) >= 20 {
-		bollingerBands = calculateBollingerBands(klines3m, 20, 2.0)
+	if len(primary) >= 20 {
+		bollingerBands = calculateBollingerBands(primary, 20, 2.0)
 	}
-	if len(klines3m) >= 9 {
-		kdj = calculateKDJ(klines3m, 9)
+	if len(primary) >= 9 {
+		kdj = calculateKDJ(primary, 9)
 	}
-	if len(klines3m) >= 5 {
+	if len(primary) >= 5 {
 		sma = &SMAData{}
-		if len(klines3m) >= 5 {
-			sma.SMA5 = calculateSMA(klines3m, 5)
+		if len(primary) >= 5 {
+			sma.SMA5 = calculateSMA(primary, 5)
 		}
-		if len(klines3m) >= 10 {
-			sma.SMA10 = calculateSMA(klines3m, 10)
+		if len(primary) >= 10 {
+			sma.SMA10 = calculateSMA(primary, 10)
 		}
-		if len(klines3m) >= 20 {
-			sma.SMA20 = calculateSMA(klines3m, 20)
+		if len(primary) >= 20 {
+			sma.SMA20 = calculateSMA(primary, 20)
 		}
-		if len(klines3m) >= 50 {
-			sma.SMA50 = calculateSMA(klines3m, 50)
+		if len(primary) >= 50 {
+			sma.SMA50 = calculateSMA(primary, 50)
 		}
-		if len(klines3m) >= 100 {
-			sma.SMA100 = calculateSMA(klines3m, 100)
+		if len(primary) >= 100 {
+			sma.SMA100 = calculateSMA(primary, 100)
 		}
 	}
-	if len(klines3m) >= 2 {
-		obv = calculateOBV(klines3m)
+	if len(primary) >= 2 {
+		obv = calculateOBV(primary)
 	}
-	if len(klines3m) >= 5 {
+	if len(primary) >= 5 {
 		volumeMA = &VolumeMAData{}
-		if len(klines3m) >= 5 {
-			volumeMA.MA5 = calculateVolumeMA(klines3m, 5)
+		if len(primary) >= 5 {
+			volumeMA.MA5 = calculateVolumeMA(primary, 5)
 		}
-		if len(klines3m) >= 20 {
-			volumeMA.MA20 = calculateVolumeMA(klines3m, 20)
+		if len(primary) >= 20 {
+			volumeMA.MA20 = calculateVolumeMA(primary, 20)
 		}
-		if len(klines3m) >= 50 {
-			volumeMA.MA50 = calculateVolumeMA(klines3m, 50)
+		if len(primary) >= 50 {
+			volumeMA.MA50 = calculateVolumeMA(primary, 50)
 		}
 	}
 
@@ -219,7 +258,8 @@ gotKlines:
 		SMA:            sma,
 		OBV:            obv,
 		VolumeMA:       volumeMA,
-	}, nil
+		Timeframes:     timeframeData,
+	}
 }
 
 // calculateEMA 计算EMA
@@ -635,54 +675,100 @@ func Format(data *Data) string {
 
 	sb.WriteString(fmt.Sprintf("Funding Rate: %.2e\n\n", data.FundingRate))
 
-	if data.IntradaySeries != nil {
-		sb.WriteString("Intraday series (3‑minute intervals, oldest → latest):\n\n")
-
-		if len(data.IntradaySeries.MidPrices) > 0 {
-			sb.WriteString(fmt.Sprintf("Mid prices: %s\n\n", formatFloatSlice(data.IntradaySeries.MidPrices)))
+	frames := data.Timeframes
+	if len(frames) == 0 {
+		// 兼容未填充 Timeframes 的数据（默认3分钟序列 + 4小时背景）
+		frames = []*TimeframeData{
+			{Interval: "3m", Series: data.IntradaySeries},
+			{Interval: "4h", Context: data.LongerTermContext},
 		}
+	}
 
-		if len(data.IntradaySeries.EMA20Values) > 0 {
-			sb.WriteString(fmt.Sprintf("EMA indicators (20‑period): %s\n\n", formatFloatSlice(data.IntradaySeries.EMA20Values)))
-		}
+	for _, frame := range frames {
+		if frame.Series != nil {
+			sb.WriteString(fmt.Sprintf("Series (%s intervals, oldest → latest):\n\n", frame.Interval))
 
-		if len(data.IntradaySeries.MACDValues) > 0 {
-			sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", formatFloatSlice(data.IntradaySeries.MACDValues)))
-		}
+			if len(frame.Series.MidPrices) > 0 {
+				sb.WriteString(fmt.Sprintf("Mid prices: %s\n\n", formatFloatSlice(frame.Series.MidPrices)))
+			}
+
+			if len(frame.Series.EMA20Values) > 0 {
+				sb.WriteString(fmt.Sprintf("EMA indicators (20‑period): %s\n\n", formatFloatSlice(frame.Series.EMA20Values)))
+			}
 
-		if len(data.IntradaySeries.RSI7Values) > 0 {
-			sb.WriteString(fmt.Sprintf("RSI indicators (7‑Period): %s\n\n", formatFloatSlice(data.IntradaySeries.RSI7Values)))
+			if len(frame.Series.MACDValues) > 0 {
+				sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", formatFloatSlice(frame.Series.MACDValues)))
+			}
+
+			if len(frame.Series.RSI7Values) > 0 {
+				sb.WriteString(fmt.Sprintf("RSI indicators (7‑Period): %s\n\n", formatFloatSlice(frame.Series.RSI7Values)))
+			}
+
+			if len(frame.Series.RSI14Values) > 0 {
+				sb.WriteString(fmt.Sprintf("RSI indicators (14‑Period): %s\n\n", formatFloatSlice(frame.Series.RSI14Values)))
+			}
 		}
 
-		if len(data.IntradaySeries.RSI14Values) > 0 {
-			sb.WriteString(fmt.Sprintf("RSI indicators (14‑Period): %s\n\n", formatFloatSlice(data.IntradaySeries.RSI14Values)))
+		if frame.Context != nil {
+			sb.WriteString(fmt.Sprintf("Context (%s timeframe):\n\n", frame.Interval))
+
+			sb.WriteString(fmt.Sprintf("20‑Period EMA: %.3f vs. 50‑Period EMA: %.3f\n\n",
+				frame.Context.EMA20, frame.Context.EMA50))
+
+			sb.WriteString(fmt.Sprintf("3‑Period ATR: %.3f vs. 14‑Period ATR: %.3f\n\n",
+				frame.Context.ATR3, frame.Context.ATR14))
+
+			sb.WriteString(fmt.Sprintf("Current Volume: %.3f vs. Average Volume: %.3f\n\n",
+				frame.Context.CurrentVolume, frame.Context.AverageVolume))
+
+			// 已输出序列数据时，MACD/RSI 序列不再重复
+			if frame.Series == nil {
+				if len(frame.Context.MACDValues) > 0 {
+					sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", formatFloatSlice(frame.Context.MACDValues)))
+				}
+
+				if len(frame.Context.RSI14Values) > 0 {
+					sb.WriteString(fmt.Sprintf("RSI indicators (14‑Period): %s\n\n", formatFloatSlice(frame.Context.RSI14Values)))
+				}
+			}
 		}
 	}
 
-	if data.LongerTermContext != nil {
-		sb.WriteString("Longer‑term context (4‑hour timeframe):\n\n")
+	if data.Depth != nil {
+		sb.WriteString("Order book liquidity:\n\n")
 
-		sb.WriteString(fmt.Sprintf("20‑Period EMA: %.3f vs. 50‑Period EMA: %.3f\n\n",
-			data.LongerTermContext.EMA20, data.LongerTermContext.EMA50))
+		sb.WriteString(fmt.Sprintf("Best bid: %.6g vs. Best ask: %.6g (spread %.4f%%)\n\n",
+			data.Depth.BestBid, data.Depth.BestAsk, data.Depth.SpreadPct))
 
-		sb.WriteString(fmt.Sprintf("3‑Period ATR: %.3f vs. 14‑Period ATR: %.3f\n\n",
-			data.LongerTermContext.ATR3, data.LongerTermContext.ATR14))
+		sb.WriteString(fmt.Sprintf("Depth within ±%.0f%%: bids %.0f USDT vs. asks %.0f USDT (imbalance %+.3f)\n\n",
+			DepthBandPct, data.Depth.BidDepth1Pct, data.Depth.AskDepth1Pct, data.Depth.Imbalance))
 
-		sb.WriteString(fmt.Sprintf("Current Volume: %.3f vs. Average Volume: %.3f\n\n",
-			data.LongerTermContext.CurrentVolume, data.LongerTermContext.AverageVolume))
-
-		if len(data.LongerTermContext.MACDValues) > 0 {
-			sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", formatFloatSlice(data.LongerTermContext.MACDValues)))
+		for _, fill := range data.Depth.Fills {
+			sb.WriteString(fmt.Sprintf("Cost to fill %.0f USDT: buy slippage %.4f%%%s, sell slippage %.4f%%%s\n\n",
+				fill.Notional, fill.BuySlippagePct, partialFillNote(fill.BuyFilled), fill.SellSlippagePct, partialFillNote(fill.SellFilled)))
 		}
+	}
 
-		if len(data.LongerTermContext.RSI14Values) > 0 {
-			sb.WriteString(fmt.Sprintf("RSI indicators (14‑Period): %s\n\n", formatFloatSlice(data.LongerTermContext.RSI14Values)))
+	for _, ind := range data.Indicators {
+		sb.WriteString(fmt.Sprintf("Indicator %s (%s timeframe, oldest → latest):\n\n", ind.Label, ind.Timeframe))
+		for _, output := range ind.Outputs {
+			if values := ind.Values[output]; len(values) > 0 {
+				sb.WriteString(fmt.Sprintf("%s: %s\n\n", output, formatFloatSlice(values)))
+			}
 		}
 	}
 
 	return sb.String()
 }
 
+// partialFillNote 盘口深度不足时的提示
+func partialFillNote(filled bool) string {
+	if filled {
+		return ""
+	}
+	return " (book too thin, partial)"
+}
+
 // formatFloatSlice 格式化float64切片为字符串
 func formatFloatSlice(values []float64) string {
 	strValues := make([]string, len(values))
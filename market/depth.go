@@ -0,0 +1,340 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultDepthLimit 默认拉取的盘口档位数
+const DefaultDepthLimit = 100
+
+// DepthBandPct 统计盘口深度的价格范围（中间价上下百分比）
+const DepthBandPct = 1.0
+
+// DefaultFillNotionals 默认估算成交成本的订单金额（USDT）
+var DefaultFillNotionals = []float64{10_000, 50_000, 100_000}
+
+// OrderBookLevel 盘口档位（数量为基础币数量）
+type OrderBookLevel struct {
+	Price    float64
+	Quantity float64
+}
+
+// OrderBook 盘口快照（买盘价格降序，卖盘价格升序）
+type OrderBook struct {
+	Symbol string
+	Bids   []OrderBookLevel
+	Asks   []OrderBookLevel
+	Time   time.Time
+}
+
+// FillEstimate 按盘口估算市价成交指定金额的成本（滑点相对中间价，包含半个价差）
+type FillEstimate struct {
+	Notional        float64 // 订单金额（USDT）
+	BuyAvgPrice     float64 // 市价买入成交均价
+	BuySlippagePct  float64 // 市价买入滑点（%）
+	BuyFilled       bool    // 盘口深度是否足够完全成交
+	SellAvgPrice    float64 // 市价卖出成交均价
+	SellSlippagePct float64 // 市价卖出滑点（%）
+	SellFilled      bool    // 盘口深度是否足够完全成交
+}
+
+// DepthFeatures 盘口流动性特征
+type DepthFeatures struct {
+	BestBid      float64
+	BestAsk      float64
+	MidPrice     float64
+	SpreadPct    float64        // 买卖价差 / 中间价（%）
+	BidDepth1Pct float64        // 中间价下方1%内的买盘金额（USDT）
+	AskDepth1Pct float64        // 中间价上方1%内的卖盘金额（USDT）
+	Imbalance    float64        // 1%内买卖盘失衡 (买-卖)/(买+卖)，范围-1~1，正数表示买盘更厚
+	Fills        []FillEstimate // 各订单金额的成交成本估算
+}
+
+// MidPrice 中间价（单边为空时返回0）
+func (ob *OrderBook) MidPrice() float64 {
+	if ob == nil || len(ob.Bids) == 0 || len(ob.Asks) == 0 {
+		return 0
+	}
+	return (ob.Bids[0].Price + ob.Asks[0].Price) / 2
+}
+
+// EstimateFill 估算市价成交指定金额的均价和滑点（side: "buy" 吃卖盘，"sell" 吃买盘）
+// 盘口深度不足时按可成交部分计算并返回 filled=false
+func (ob *OrderBook) EstimateFill(side string, notional float64) (avgPrice, slippagePct float64, filled bool) {
+	mid := ob.MidPrice()
+	if mid <= 0 || notional <= 0 {
+		return 0, 0, false
+	}
+	levels := ob.Asks
+	if side == "sell" {
+		levels = ob.Bids
+	}
+
+	remaining := notional
+	cost, qty := 0.0, 0.0
+	for _, level := range levels {
+		if level.Price <= 0 || level.Quantity <= 0 {
+			continue
+		}
+		levelNotional := level.Price * level.Quantity
+		take := math.Min(remaining, levelNotional)
+		cost += take
+		qty += take / level.Price
+		remaining -= take
+		if remaining <= 0 {
+			break
+		}
+	}
+	if qty <= 0 {
+		return 0, 0, false
+	}
+	avgPrice = cost / qty
+	slippagePct = math.Abs(avgPrice-mid) / mid * 100
+	return avgPrice, slippagePct, remaining <= 0
+}
+
+// MaxNotionalWithinSlippage 滑点不超过 maxSlippagePct 时可市价成交的最大金额（USDT）
+func (ob *OrderBook) MaxNotionalWithinSlippage(side string, maxSlippagePct float64) float64 {
+	mid := ob.MidPrice()
+	if mid <= 0 {
+		return 0
+	}
+	levels := ob.Asks
+	if side == "sell" {
+		levels = ob.Bids
+	}
+
+	// 均价偏离中间价的上限：买入为 mid*(1+x)，卖出为 mid*(1-x)
+	limit := mid * (1 + maxSlippagePct/100)
+	if side == "sell" {
+		limit = mid * (1 - maxSlippagePct/100)
+	}
+	cost, qty := 0.0, 0.0
+	for _, level := range levels {
+		if level.Price <= 0 || level.Quantity <= 0 {
+			continue
+		}
+		newCost := cost + level.Price*level.Quantity
+		newQty := qty + level.Quantity
+		if (side == "sell" && newCost/newQty >= limit) || (side != "sell" && newCost/newQty <= limit) {
+			cost, qty = newCost, newQty
+			continue
+		}
+		// 本档只能成交一部分：解 (cost + p*q) / (qty + q) = limit
+		if level.Price != limit {
+			q := (limit*qty - cost) / (level.Price - limit)
+			if q > 0 {
+				cost += level.Price * q
+			}
+		}
+		break
+	}
+	return cost
+}
+
+// Features 计算盘口流动性特征（notionals 为空时使用 DefaultFillNotionals）
+func (ob *OrderBook) Features(notionals []float64) *DepthFeatures {
+	mid := ob.MidPrice()
+	if mid <= 0 {
+		return nil
+	}
+	f := &DepthFeatures{
+		BestBid:   ob.Bids[0].Price,
+		BestAsk:   ob.Asks[0].Price,
+		MidPrice:  mid,
+		SpreadPct: (ob.Asks[0].Price - ob.Bids[0].Price) / mid * 100,
+	}
+
+	lower, upper := mid*(1-DepthBandPct/100), mid*(1+DepthBandPct/100)
+	for _, level := range ob.Bids {
+		if level.Price < lower {
+			break
+		}
+		f.BidDepth1Pct += level.Price * level.Quantity
+	}
+	for _, level := range ob.Asks {
+		if level.Price > upper {
+			break
+		}
+		f.AskDepth1Pct += level.Price * level.Quantity
+	}
+	if total := f.BidDepth1Pct + f.AskDepth1Pct; total > 0 {
+		f.Imbalance = (f.BidDepth1Pct - f.AskDepth1Pct) / total
+	}
+
+	if len(notionals) == 0 {
+		notionals = DefaultFillNotionals
+	}
+	for _, notional := range notionals {
+		est := FillEstimate{Notional: notional}
+		est.BuyAvgPrice, est.BuySlippagePct, est.BuyFilled = ob.EstimateFill("buy", notional)
+		est.SellAvgPrice, est.SellSlippagePct, est.SellFilled = ob.EstimateFill("sell", notional)
+		f.Fills = append(f.Fills, est)
+	}
+	return f
+}
+
+// GetOrderBook 获取盘口快照（okx/bybit/bitget/gate 使用各自盘口，其他交易所使用币安盘口作为流动性参考）
+func GetOrderBook(symbol, exchange string, limit int) (*OrderBook, error) {
+	symbol = Normalize(symbol)
+	if limit <= 0 {
+		limit = DefaultDepthLimit
+	}
+	if venueClient := NewExchangeClient(exchange); venueClient != nil {
+		return venueClient.GetOrderBook(symbol, limit)
+	}
+	return NewAPIClient().GetDepth(symbol, limit)
+}
+
+// GetDepth 获取币安合约盘口
+func (c *APIClient) GetDepth(symbol string, limit int) (*OrderBook, error) {
+	url := fmt.Sprintf("%s/fapi/v1/depth?symbol=%s&limit=%d", baseURL, symbol, limit)
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取盘口失败 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		T    int64      `json:"T"`
+		Bids [][]string `json:"bids"`
+		Asks [][]string `json:"asks"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析盘口响应失败: %w", err)
+	}
+
+	book := &OrderBook{
+		Symbol: symbol,
+		Bids:   parseBookLevels(result.Bids, 1),
+		Asks:   parseBookLevels(result.Asks, 1),
+		Time:   time.Now(),
+	}
+	if result.T > 0 {
+		book.Time = time.UnixMilli(result.T)
+	}
+	return book, nil
+}
+
+// okxContractValues OKX合约面值缓存（instId -> ctVal，盘口数量为张数）
+var okxContractValues sync.Map
+
+// GetOrderBook 获取OKX永续合约盘口（数量按合约面值换算为基础币数量）
+func (c *OKXAPIClient) GetOrderBook(symbol string, limit int) (*OrderBook, error) {
+	instID := convertSymbolToOKXInstID(symbol)
+	ctVal, err := c.getContractValue(instID)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/api/v5/market/books?instId=%s&sz=%d", okxBaseURL, instID, limit)
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var okxResponse struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			Asks [][]string `json:"asks"`
+			Bids [][]string `json:"bids"`
+			Ts   string     `json:"ts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &okxResponse); err != nil {
+		return nil, fmt.Errorf("解析OKX盘口响应失败: %w", err)
+	}
+	if okxResponse.Code != "0" || len(okxResponse.Data) == 0 {
+		return nil, fmt.Errorf("OKX API错误: code=%s, msg=%s", okxResponse.Code, okxResponse.Msg)
+	}
+
+	data := okxResponse.Data[0]
+	book := &OrderBook{
+		Symbol: symbol,
+		Bids:   parseBookLevels(data.Bids, ctVal),
+		Asks:   parseBookLevels(data.Asks, ctVal),
+		Time:   time.Now(),
+	}
+	if ts, err := strconv.ParseInt(data.Ts, 10, 64); err == nil && ts > 0 {
+		book.Time = time.UnixMilli(ts)
+	}
+	return book, nil
+}
+
+// getContractValue 获取OKX合约面值（缓存）
+func (c *OKXAPIClient) getContractValue(instID string) (float64, error) {
+	if v, ok := okxContractValues.Load(instID); ok {
+		return v.(float64), nil
+	}
+
+	url := fmt.Sprintf("%s/api/v5/public/instruments?instType=SWAP&instId=%s", okxBaseURL, instID)
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	var okxResponse struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			CtVal string `json:"ctVal"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &okxResponse); err != nil {
+		return 0, fmt.Errorf("解析OKX合约信息失败: %w", err)
+	}
+	if okxResponse.Code != "0" || len(okxResponse.Data) == 0 {
+		return 0, fmt.Errorf("OKX API错误: code=%s, msg=%s", okxResponse.Code, okxResponse.Msg)
+	}
+	ctVal, err := strconv.ParseFloat(okxResponse.Data[0].CtVal, 64)
+	if err != nil || ctVal <= 0 {
+		return 0, fmt.Errorf("OKX合约面值无效: %s", okxResponse.Data[0].CtVal)
+	}
+	okxContractValues.Store(instID, ctVal)
+	return ctVal, nil
+}
+
+// parseBookLevels 解析 [价格, 数量, ...] 格式的盘口档位，数量乘以 multiplier
+func parseBookLevels(raw [][]string, multiplier float64) []OrderBookLevel {
+	levels := make([]OrderBookLevel, 0, len(raw))
+	for _, entry := range raw {
+		if len(entry) < 2 {
+			continue
+		}
+		price, err1 := strconv.ParseFloat(entry[0], 64)
+		qty, err2 := strconv.ParseFloat(entry[1], 64)
+		if err1 != nil || err2 != nil || price <= 0 {
+			continue
+		}
+		levels = append(levels, OrderBookLevel{Price: price, Quantity: qty * multiplier})
+	}
+	return levels
+}
@@ -0,0 +1,354 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gateBaseURL = "https://api.gateio.ws/api/v4"
+	// gateMaxCandles Gate单次最多返回的K线数量
+	gateMaxCandles = 2000
+)
+
+// gateIntervals Gate原生支持的K线周期
+var gateIntervals = map[string]string{
+	"1m":  "1m",
+	"5m":  "5m",
+	"15m": "15m",
+	"30m": "30m",
+	"1h":  "1h",
+	"4h":  "4h",
+	"8h":  "8h",
+	"1d":  "1d",
+	"1w":  "7d",
+}
+
+// gateAggregatedIntervals Gate不支持的周期由短周期K线合成（周期 -> 基础周期和倍数）
+var gateAggregatedIntervals = map[string]struct {
+	base   string
+	factor int
+}{
+	"3m":  {"1m", 3},
+	"2h":  {"1h", 2},
+	"6h":  {"1h", 6},
+	"12h": {"4h", 3},
+	"3d":  {"1d", 3},
+}
+
+// gateContractMultipliers Gate合约面值缓存（contract -> quanto_multiplier，成交量和盘口数量为张数）
+var gateContractMultipliers sync.Map
+
+// GateAPIClient Gate.io USDT永续合约行情客户端（V4公共接口）
+type GateAPIClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewGateAPIClient() *GateAPIClient {
+	return &GateAPIClient{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		baseURL: gateBaseURL,
+	}
+}
+
+// convertSymbolToGateContract 转换交易对格式 (BTCUSDT -> BTC_USDT)
+func convertSymbolToGateContract(symbol string) string {
+	return strings.TrimSuffix(symbol, "USDT") + "_USDT"
+}
+
+// get 调用Gate公共接口并解析响应
+func (c *GateAPIClient) get(path string, params url.Values, result interface{}) error {
+	resp, err := c.client.Get(c.baseURL + path + "?" + params.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var gateErr struct {
+			Label   string `json:"label"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(body, &gateErr)
+		return fmt.Errorf("Gate API错误 (状态码: %d): label=%s, message=%s", resp.StatusCode, gateErr.Label, gateErr.Message)
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("解析Gate响应失败: %w, 原始响应: %s", err, string(body))
+	}
+	return nil
+}
+
+// getMultiplier 获取合约面值（缓存）
+func (c *GateAPIClient) getMultiplier(contract string) (float64, error) {
+	if v, ok := gateContractMultipliers.Load(contract); ok {
+		return v.(float64), nil
+	}
+
+	var info struct {
+		QuantoMultiplier string `json:"quanto_multiplier"`
+	}
+	if err := c.get("/futures/usdt/contracts/"+contract, url.Values{}, &info); err != nil {
+		return 0, err
+	}
+	multiplier, err := strconv.ParseFloat(info.QuantoMultiplier, 64)
+	if err != nil || multiplier <= 0 {
+		return 0, fmt.Errorf("Gate合约面值无效: %s", info.QuantoMultiplier)
+	}
+	gateContractMultipliers.Store(contract, multiplier)
+	return multiplier, nil
+}
+
+// GetKlines 获取Gate K线数据（成交量换算为基础币数量）
+func (c *GateAPIClient) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	startTime := time.Now()
+	if agg, ok := gateAggregatedIntervals[interval]; ok {
+		fetchLimit := (limit + 1) * agg.factor
+		if fetchLimit > gateMaxCandles {
+			fetchLimit = gateMaxCandles
+		}
+		base, err := c.GetKlines(symbol, agg.base, fetchLimit)
+		if err != nil {
+			return nil, err
+		}
+		klines := aggregateKlines(base, interval, agg.factor)
+		if len(klines) > limit {
+			klines = klines[len(klines)-limit:]
+		}
+		return klines, nil
+	}
+
+	gateInterval, ok := gateIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("Gate不支持K线周期: %s", interval)
+	}
+	contract := convertSymbolToGateContract(symbol)
+	multiplier, err := c.getMultiplier(contract)
+	if err != nil {
+		log.Printf("❌ Gate API [K线] %s %s: 获取合约面值失败: %v", symbol, interval, err)
+		return nil, err
+	}
+
+	var rows []struct {
+		T int64  `json:"t"` // 秒级时间戳
+		V int64  `json:"v"` // 成交张数
+		C string `json:"c"`
+		H string `json:"h"`
+		L string `json:"l"`
+		O string `json:"o"`
+	}
+	params := url.Values{}
+	params.Set("contract", contract)
+	params.Set("interval", gateInterval)
+	params.Set("limit", strconv.Itoa(limit))
+	if err := c.get("/futures/usdt/candlesticks", params, &rows); err != nil {
+		log.Printf("❌ Gate API [K线] %s %s: 请求失败: %v", symbol, interval, err)
+		return nil, err
+	}
+
+	closeTimeOffset := int64(0)
+	if d, ok := IntervalDuration(interval); ok {
+		closeTimeOffset = d.Milliseconds()
+	}
+
+	klines := make([]Kline, 0, len(rows))
+	for _, kr := range rows {
+		kline := Kline{OpenTime: kr.T * 1000}
+		kline.Open, _ = strconv.ParseFloat(kr.O, 64)
+		kline.High, _ = strconv.ParseFloat(kr.H, 64)
+		kline.Low, _ = strconv.ParseFloat(kr.L, 64)
+		kline.Close, _ = strconv.ParseFloat(kr.C, 64)
+		kline.Volume = float64(kr.V) * multiplier
+		kline.CloseTime = kline.OpenTime + closeTimeOffset - 1
+		klines = append(klines, kline)
+	}
+	sort.Slice(klines, func(i, j int) bool { return klines[i].OpenTime < klines[j].OpenTime })
+
+	log.Printf("✓ Gate API [K线] %s %s: 获取 %d 根K线，总耗时 %v", symbol, interval, len(klines), time.Since(startTime))
+	return klines, nil
+}
+
+// aggregateKlines 将短周期K线按对齐的时间窗口合成为长周期（丢弃开头不完整的窗口）
+func aggregateKlines(base []Kline, interval string, factor int) []Kline {
+	d, ok := IntervalDuration(interval)
+	if !ok || len(base) == 0 {
+		return nil
+	}
+	periodMs := d.Milliseconds()
+
+	var result []Kline
+	counts := []int{}
+	for _, k := range base {
+		openTime := k.OpenTime - k.OpenTime%periodMs
+		n := len(result)
+		if n > 0 && result[n-1].OpenTime == openTime {
+			last := &result[n-1]
+			if k.High > last.High {
+				last.High = k.High
+			}
+			if k.Low < last.Low {
+				last.Low = k.Low
+			}
+			last.Close = k.Close
+			last.Volume += k.Volume
+			counts[n-1]++
+			continue
+		}
+		result = append(result, Kline{
+			OpenTime:  openTime,
+			Open:      k.Open,
+			High:      k.High,
+			Low:       k.Low,
+			Close:     k.Close,
+			Volume:    k.Volume,
+			CloseTime: openTime + periodMs - 1,
+		})
+		counts = append(counts, 1)
+	}
+	if len(result) > 1 && counts[0] < factor {
+		result = result[1:]
+	}
+	return result
+}
+
+// gateTicker 行情快照（价格和资金费率共用）
+type gateTicker struct {
+	Last        string `json:"last"`
+	MarkPrice   string `json:"mark_price"`
+	FundingRate string `json:"funding_rate"`
+}
+
+// getTicker 获取Gate行情快照
+func (c *GateAPIClient) getTicker(symbol string) (*gateTicker, error) {
+	var tickers []gateTicker
+	params := url.Values{}
+	params.Set("contract", convertSymbolToGateContract(symbol))
+	if err := c.get("/futures/usdt/tickers", params, &tickers); err != nil {
+		return nil, err
+	}
+	if len(tickers) == 0 {
+		return nil, fmt.Errorf("Gate未返回 %s 的行情", symbol)
+	}
+	return &tickers[0], nil
+}
+
+// GetCurrentPrice 获取Gate实时价格
+func (c *GateAPIClient) GetCurrentPrice(symbol string) (float64, error) {
+	ticker, err := c.getTicker(symbol)
+	if err != nil {
+		log.Printf("❌ Gate API [价格] %s: 请求失败: %v", symbol, err)
+		return 0, err
+	}
+	price, err := strconv.ParseFloat(ticker.Last, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析Gate价格失败: %w", err)
+	}
+	return price, nil
+}
+
+// GetOpenInterest 获取Gate持仓量（最近一小时5分钟粒度的最新值和平均值，换算为基础币数量）
+func (c *GateAPIClient) GetOpenInterest(symbol string) (*OIData, error) {
+	contract := convertSymbolToGateContract(symbol)
+	multiplier, err := c.getMultiplier(contract)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []struct {
+		Time         int64 `json:"time"`
+		OpenInterest int64 `json:"open_interest"` // 张数
+	}
+	params := url.Values{}
+	params.Set("contract", contract)
+	params.Set("interval", "5m")
+	params.Set("limit", "12")
+	if err := c.get("/futures/usdt/contract_stats", params, &stats); err != nil {
+		log.Printf("❌ Gate API [持仓量] %s: 请求失败: %v", symbol, err)
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return nil, fmt.Errorf("Gate未返回 %s 的持仓量", symbol)
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Time < stats[j].Time })
+	sum := 0.0
+	for _, item := range stats {
+		sum += float64(item.OpenInterest) * multiplier
+	}
+	return &OIData{
+		Latest:  float64(stats[len(stats)-1].OpenInterest) * multiplier,
+		Average: sum / float64(len(stats)),
+	}, nil
+}
+
+// GetFundingRate 获取Gate当前资金费率
+func (c *GateAPIClient) GetFundingRate(symbol string) (float64, error) {
+	ticker, err := c.getTicker(symbol)
+	if err != nil {
+		log.Printf("❌ Gate API [资金费率] %s: 请求失败: %v", symbol, err)
+		return 0, err
+	}
+	rate, _ := strconv.ParseFloat(ticker.FundingRate, 64)
+	return rate, nil
+}
+
+// GetOrderBook 获取Gate永续合约盘口（数量按合约面值换算为基础币数量）
+func (c *GateAPIClient) GetOrderBook(symbol string, limit int) (*OrderBook, error) {
+	contract := convertSymbolToGateContract(symbol)
+	multiplier, err := c.getMultiplier(contract)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 300 {
+		limit = 300
+	}
+
+	type gateLevel struct {
+		P string `json:"p"`
+		S int64  `json:"s"`
+	}
+	var result struct {
+		Current float64     `json:"current"` // 秒级时间戳
+		Asks    []gateLevel `json:"asks"`
+		Bids    []gateLevel `json:"bids"`
+	}
+	params := url.Values{}
+	params.Set("contract", contract)
+	params.Set("limit", strconv.Itoa(limit))
+	if err := c.get("/futures/usdt/order_book", params, &result); err != nil {
+		return nil, err
+	}
+
+	toRaw := func(levels []gateLevel) [][]string {
+		raw := make([][]string, 0, len(levels))
+		for _, l := range levels {
+			raw = append(raw, []string{l.P, strconv.FormatInt(l.S, 10)})
+		}
+		return raw
+	}
+	book := &OrderBook{
+		Symbol: symbol,
+		Bids:   parseBookLevels(toRaw(result.Bids), multiplier),
+		Asks:   parseBookLevels(toRaw(result.Asks), multiplier),
+		Time:   time.Now(),
+	}
+	if result.Current > 0 {
+		book.Time = time.UnixMilli(int64(result.Current * 1000))
+	}
+	return book, nil
+}
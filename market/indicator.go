@@ -0,0 +1,290 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IndicatorSeriesLength 每个指标输出序列保留的数据点数（最后一个值对应最新K线）
+const IndicatorSeriesLength = 10
+
+// MaxIndicators 单个交易员或模板最多引用的指标数（控制 Prompt 长度）
+const MaxIndicators = 12
+
+// IndicatorParam 指标参数定义（按声明顺序对应 "name(a,b)" 中的位置参数）
+type IndicatorParam struct {
+	Name        string  `json:"name"`
+	Default     float64 `json:"default"`
+	Description string  `json:"description"`
+}
+
+// Indicator 技术指标，注册后即可在交易员配置或提示词模板中按名称引用
+type Indicator interface {
+	Name() string
+	Description() string
+	Params() []IndicatorParam
+	Outputs() []string
+	// Compute 计算各输出序列（按时间升序，最后一个值对应最新K线；数据不足时返回空序列）
+	Compute(klines []Kline, params map[string]float64) map[string][]float64
+}
+
+// IndicatorRequest 指标引用（如 "supertrend(10,3)@1h"）
+type IndicatorRequest struct {
+	Name      string    `json:"name"`
+	Params    []float64 `json:"params,omitempty"`    // 位置参数（未提供的使用默认值）
+	Timeframe string    `json:"timeframe,omitempty"` // K线周期（为空时使用最短周期）
+}
+
+// String 返回规范化的指标引用写法
+func (r IndicatorRequest) String() string {
+	s := r.Name
+	if len(r.Params) > 0 {
+		args := make([]string, len(r.Params))
+		for i, p := range r.Params {
+			args[i] = strconv.FormatFloat(p, 'f', -1, 64)
+		}
+		s += "(" + strings.Join(args, ",") + ")"
+	}
+	if r.Timeframe != "" {
+		s += "@" + r.Timeframe
+	}
+	return s
+}
+
+// IndicatorResult 指标计算结果
+type IndicatorResult struct {
+	Name      string               // 指标名称
+	Label     string               // 展示名称（如 "supertrend(10,3)@1h"）
+	Timeframe string               // 实际使用的K线周期
+	Outputs   []string             // 输出序列名称（按声明顺序）
+	Values    map[string][]float64 // 输出序列（最多 IndicatorSeriesLength 个点）
+}
+
+// Latest 返回指定输出的最新值
+func (r *IndicatorResult) Latest(output string) (float64, bool) {
+	values := r.Values[output]
+	if len(values) == 0 {
+		return 0, false
+	}
+	return values[len(values)-1], true
+}
+
+var (
+	indicatorMu       sync.RWMutex
+	indicatorRegistry = make(map[string]Indicator)
+)
+
+// RegisterIndicator 注册技术指标（名称不区分大小写，重复注册返回错误）
+func RegisterIndicator(ind Indicator) error {
+	name := strings.ToLower(ind.Name())
+	if name == "" {
+		return fmt.Errorf("指标名称不能为空")
+	}
+	indicatorMu.Lock()
+	defer indicatorMu.Unlock()
+	if _, exists := indicatorRegistry[name]; exists {
+		return fmt.Errorf("指标已注册: %s", name)
+	}
+	indicatorRegistry[name] = ind
+	return nil
+}
+
+// GetIndicator 按名称获取已注册的指标
+func GetIndicator(name string) (Indicator, bool) {
+	indicatorMu.RLock()
+	defer indicatorMu.RUnlock()
+	ind, ok := indicatorRegistry[strings.ToLower(name)]
+	return ind, ok
+}
+
+// Indicators 返回所有已注册的指标（按名称排序）
+func Indicators() []Indicator {
+	indicatorMu.RLock()
+	defer indicatorMu.RUnlock()
+	result := make([]Indicator, 0, len(indicatorRegistry))
+	for _, ind := range indicatorRegistry {
+		result = append(result, ind)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+	return result
+}
+
+// ParseIndicatorSpecs 解析指标引用列表（逗号、分号或换行分隔，如 "adx(14), vwap, supertrend(10,3)@1h"）
+func ParseIndicatorSpecs(s string) ([]IndicatorRequest, error) {
+	var requests []IndicatorRequest
+	seen := make(map[string]bool)
+	for _, spec := range splitIndicatorSpecs(s) {
+		req, err := parseIndicatorSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		key := req.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		requests = append(requests, req)
+	}
+	if len(requests) > MaxIndicators {
+		return nil, fmt.Errorf("指标最多%d个，当前%d个", MaxIndicators, len(requests))
+	}
+	return requests, nil
+}
+
+// FormatIndicatorSpecs 将指标引用列表格式化为规范写法
+func FormatIndicatorSpecs(requests []IndicatorRequest) string {
+	specs := make([]string, len(requests))
+	for i, req := range requests {
+		specs[i] = req.String()
+	}
+	return strings.Join(specs, ", ")
+}
+
+// splitIndicatorSpecs 按顶层分隔符拆分（括号内的逗号属于参数）
+func splitIndicatorSpecs(s string) []string {
+	var specs []string
+	var current strings.Builder
+	depth := 0
+	flush := func() {
+		if spec := strings.TrimSpace(current.String()); spec != "" {
+			specs = append(specs, spec)
+		}
+		current.Reset()
+	}
+	for _, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth > 0 {
+				depth--
+			}
+		case depth == 0 && (r == ',' || r == ';' || r == '\n'):
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	flush()
+	return specs
+}
+
+// parseIndicatorSpec 解析单个指标引用
+func parseIndicatorSpec(spec string) (IndicatorRequest, error) {
+	var req IndicatorRequest
+	body := spec
+	if at := strings.LastIndex(body, "@"); at >= 0 {
+		tf := strings.TrimSpace(body[at+1:])
+		if tf != "1M" {
+			tf = strings.ToLower(tf)
+		}
+		if _, ok := IntervalDuration(tf); !ok {
+			return req, fmt.Errorf("指标 %s 的K线周期无效: %s", spec, tf)
+		}
+		req.Timeframe = tf
+		body = body[:at]
+	}
+
+	name := strings.TrimSpace(body)
+	args := ""
+	if open := strings.Index(body, "("); open >= 0 {
+		if !strings.HasSuffix(strings.TrimSpace(body), ")") {
+			return req, fmt.Errorf("指标 %s 缺少右括号", spec)
+		}
+		name = strings.TrimSpace(body[:open])
+		args = strings.TrimSuffix(strings.TrimSpace(body[open+1:]), ")")
+	}
+	req.Name = strings.ToLower(name)
+
+	ind, ok := GetIndicator(req.Name)
+	if !ok {
+		return req, fmt.Errorf("未知指标: %s", name)
+	}
+
+	if strings.TrimSpace(args) != "" {
+		parts := strings.Split(args, ",")
+		if len(parts) > len(ind.Params()) {
+			return req, fmt.Errorf("指标 %s 最多%d个参数，当前%d个", req.Name, len(ind.Params()), len(parts))
+		}
+		for _, part := range parts {
+			v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || v < 0 {
+				return req, fmt.Errorf("指标 %s 的参数无效: %s", spec, part)
+			}
+			req.Params = append(req.Params, v)
+		}
+	}
+	return req, nil
+}
+
+// resolveParams 合并默认参数与位置参数
+func resolveParams(ind Indicator, positional []float64) map[string]float64 {
+	params := make(map[string]float64, len(ind.Params()))
+	for i, p := range ind.Params() {
+		params[p.Name] = p.Default
+		if i < len(positional) {
+			params[p.Name] = positional[i]
+		}
+	}
+	return params
+}
+
+// ComputeIndicator 在指定K线上计算单个指标
+func ComputeIndicator(req IndicatorRequest, klines []Kline) (*IndicatorResult, error) {
+	ind, ok := GetIndicator(req.Name)
+	if !ok {
+		return nil, fmt.Errorf("未知指标: %s", req.Name)
+	}
+	values := ind.Compute(klines, resolveParams(ind, req.Params))
+
+	result := &IndicatorResult{
+		Name:      req.Name,
+		Label:     req.String(),
+		Timeframe: req.Timeframe,
+		Outputs:   ind.Outputs(),
+		Values:    make(map[string][]float64, len(values)),
+	}
+	for name, series := range values {
+		if len(series) > IndicatorSeriesLength {
+			series = series[len(series)-IndicatorSeriesLength:]
+		}
+		result.Values[name] = series
+	}
+	return result, nil
+}
+
+// ComputeIndicators 按引用列表计算指标并写入 Data.Indicators（单个指标失败只记录日志）
+// 指标未指定周期时使用最短周期；指定的周期不在 Data.Timeframes 中时跳过
+func (d *Data) ComputeIndicators(requests []IndicatorRequest) {
+	d.Indicators = nil
+	if len(requests) == 0 || len(d.Timeframes) == 0 {
+		return
+	}
+	for _, req := range requests {
+		frame := d.Timeframes[0]
+		if req.Timeframe != "" {
+			frame = nil
+			for _, tf := range d.Timeframes {
+				if tf.Interval == req.Timeframe {
+					frame = tf
+					break
+				}
+			}
+			if frame == nil {
+				log.Printf("⚠️  %s 指标 %s 跳过：未配置 %s 周期", d.Symbol, req.String(), req.Timeframe)
+				continue
+			}
+		}
+		result, err := ComputeIndicator(req, frame.Klines)
+		if err != nil {
+			log.Printf("⚠️  %s 指标 %s 计算失败: %v", d.Symbol, req.String(), err)
+			continue
+		}
+		result.Timeframe = frame.Interval
+		d.Indicators = append(d.Indicators, result)
+	}
+}
@@ -0,0 +1,568 @@
+package market
+
+import (
+	"math"
+	"time"
+)
+
+// indicatorFunc 基于函数实现的指标（内置指标均使用此类型注册）
+type indicatorFunc struct {
+	name        string
+	description string
+	params      []IndicatorParam
+	outputs     []string
+	compute     func(klines []Kline, p map[string]float64) map[string][]float64
+}
+
+func (f *indicatorFunc) Name() string             { return f.name }
+func (f *indicatorFunc) Description() string      { return f.description }
+func (f *indicatorFunc) Params() []IndicatorParam { return f.params }
+func (f *indicatorFunc) Outputs() []string        { return f.outputs }
+func (f *indicatorFunc) Compute(klines []Kline, p map[string]float64) map[string][]float64 {
+	return f.compute(klines, p)
+}
+
+func init() {
+	builtins := []*indicatorFunc{
+		{
+			name: "ema", description: "指数移动平均",
+			params:  []IndicatorParam{{Name: "period", Default: 20, Description: "周期"}},
+			outputs: []string{"ema"},
+			compute: func(k []Kline, p map[string]float64) map[string][]float64 {
+				return map[string][]float64{"ema": emaValues(closeValues(k), int(p["period"]))}
+			},
+		},
+		{
+			name: "sma", description: "简单移动平均",
+			params:  []IndicatorParam{{Name: "period", Default: 20, Description: "周期"}},
+			outputs: []string{"sma"},
+			compute: func(k []Kline, p map[string]float64) map[string][]float64 {
+				return map[string][]float64{"sma": smaValues(closeValues(k), int(p["period"]))}
+			},
+		},
+		{
+			name: "macd", description: "MACD（快线EMA - 慢线EMA，信号线，柱状图）",
+			params: []IndicatorParam{
+				{Name: "fast", Default: 12, Description: "快线周期"},
+				{Name: "slow", Default: 26, Description: "慢线周期"},
+				{Name: "signal", Default: 9, Description: "信号线周期"},
+			},
+			outputs: []string{"macd", "signal", "histogram"},
+			compute: computeMACD,
+		},
+		{
+			name: "rsi", description: "相对强弱指标（Wilder平滑）",
+			params:  []IndicatorParam{{Name: "period", Default: 14, Description: "周期"}},
+			outputs: []string{"rsi"},
+			compute: func(k []Kline, p map[string]float64) map[string][]float64 {
+				return map[string][]float64{"rsi": rsiValues(closeValues(k), int(p["period"]))}
+			},
+		},
+		{
+			name: "atr", description: "平均真实波幅（Wilder平滑）",
+			params:  []IndicatorParam{{Name: "period", Default: 14, Description: "周期"}},
+			outputs: []string{"atr"},
+			compute: func(k []Kline, p map[string]float64) map[string][]float64 {
+				return map[string][]float64{"atr": atrValues(k, int(p["period"]))}
+			},
+		},
+		{
+			name: "bollinger", description: "布林带",
+			params: []IndicatorParam{
+				{Name: "period", Default: 20, Description: "周期"},
+				{Name: "stddev", Default: 2, Description: "标准差倍数"},
+			},
+			outputs: []string{"upper", "middle", "lower"},
+			compute: func(k []Kline, p map[string]float64) map[string][]float64 {
+				period := int(p["period"])
+				if period < 1 {
+					return nil
+				}
+				upper, middle, lower := calculateBollingerBandsValues(k, period, p["stddev"])
+				return map[string][]float64{"upper": upper, "middle": middle, "lower": lower}
+			},
+		},
+		{
+			name: "kdj", description: "KDJ随机指标",
+			params:  []IndicatorParam{{Name: "period", Default: 9, Description: "周期"}},
+			outputs: []string{"k", "d", "j"},
+			compute: func(k []Kline, p map[string]float64) map[string][]float64 {
+				period := int(p["period"])
+				if period < 1 {
+					return nil
+				}
+				kv, dv, jv := calculateKDJValues(k, period)
+				return map[string][]float64{"k": kv, "d": dv, "j": jv}
+			},
+		},
+		{
+			name: "obv", description: "能量潮",
+			outputs: []string{"obv"},
+			compute: func(k []Kline, p map[string]float64) map[string][]float64 {
+				return map[string][]float64{"obv": calculateOBVValues(k)}
+			},
+		},
+		{
+			name: "volume_ma", description: "成交量移动平均",
+			params:  []IndicatorParam{{Name: "period", Default: 20, Description: "周期"}},
+			outputs: []string{"volume_ma"},
+			compute: func(k []Kline, p map[string]float64) map[string][]float64 {
+				return map[string][]float64{"volume_ma": smaValues(volumeValues(k), int(p["period"]))}
+			},
+		},
+		{
+			name: "adx", description: "平均趋向指数（趋势强度，>25为强趋势）及 +DI/-DI",
+			params:  []IndicatorParam{{Name: "period", Default: 14, Description: "周期"}},
+			outputs: []string{"adx", "plus_di", "minus_di"},
+			compute: computeADX,
+		},
+		{
+			name: "vwap", description: "成交量加权均价（period=0 时按UTC日重置，否则为滚动窗口）",
+			params:  []IndicatorParam{{Name: "period", Default: 0, Description: "滚动周期（0=按UTC日重置）"}},
+			outputs: []string{"vwap"},
+			compute: computeVWAP,
+		},
+		{
+			name: "supertrend", description: "超级趋势（direction: 1=多头, -1=空头）",
+			params: []IndicatorParam{
+				{Name: "period", Default: 10, Description: "ATR周期"},
+				{Name: "multiplier", Default: 3, Description: "ATR倍数"},
+			},
+			outputs: []string{"supertrend", "direction"},
+			compute: computeSupertrend,
+		},
+		{
+			name: "ichimoku", description: "一目均衡表（span_a/span_b 为当前K线对应的云层）",
+			params: []IndicatorParam{
+				{Name: "tenkan", Default: 9, Description: "转换线周期"},
+				{Name: "kijun", Default: 26, Description: "基准线周期（云层位移）"},
+				{Name: "senkou", Default: 52, Description: "先行带B周期"},
+			},
+			outputs: []string{"tenkan", "kijun", "span_a", "span_b"},
+			compute: computeIchimoku,
+		},
+		{
+			name: "stoch_rsi", description: "随机RSI（0-100）",
+			params: []IndicatorParam{
+				{Name: "rsi", Default: 14, Description: "RSI周期"},
+				{Name: "stoch", Default: 14, Description: "随机周期"},
+				{Name: "k", Default: 3, Description: "K线平滑"},
+				{Name: "d", Default: 3, Description: "D线平滑"},
+			},
+			outputs: []string{"k", "d"},
+			compute: computeStochRSI,
+		},
+		{
+			name: "donchian", description: "唐奇安通道",
+			params:  []IndicatorParam{{Name: "period", Default: 20, Description: "周期"}},
+			outputs: []string{"upper", "middle", "lower"},
+			compute: computeDonchian,
+		},
+		{
+			name: "cvd", description: "累计成交量差（主动买入 - 主动卖出，无主动买入数据时按K线涨跌估算）",
+			outputs: []string{"cvd", "delta"},
+			compute: computeCVD,
+		},
+	}
+	for _, ind := range builtins {
+		if err := RegisterIndicator(ind); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func closeValues(klines []Kline) []float64 {
+	values := make([]float64, len(klines))
+	for i, k := range klines {
+		values[i] = k.Close
+	}
+	return values
+}
+
+func volumeValues(klines []Kline) []float64 {
+	values := make([]float64, len(klines))
+	for i, k := range klines {
+		values[i] = k.Volume
+	}
+	return values
+}
+
+// emaValues EMA序列（以前period个值的SMA为初始值，与 calculateEMA 一致）
+func emaValues(values []float64, period int) []float64 {
+	if period < 1 || len(values) < period {
+		return nil
+	}
+	sum := 0.0
+	for _, v := range values[:period] {
+		sum += v
+	}
+	ema := sum / float64(period)
+	result := []float64{ema}
+	multiplier := 2.0 / float64(period+1)
+	for _, v := range values[period:] {
+		ema = (v-ema)*multiplier + ema
+		result = append(result, ema)
+	}
+	return result
+}
+
+// smaValues 滚动简单平均序列
+func smaValues(values []float64, period int) []float64 {
+	if period < 1 || len(values) < period {
+		return nil
+	}
+	result := make([]float64, 0, len(values)-period+1)
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			result = append(result, sum/float64(period))
+		}
+	}
+	return result
+}
+
+// rsiValues RSI序列（Wilder平滑，与 calculateRSI 一致）
+func rsiValues(values []float64, period int) []float64 {
+	if period < 1 || len(values) <= period {
+		return nil
+	}
+	gains, losses := 0.0, 0.0
+	for i := 1; i <= period; i++ {
+		change := values[i] - values[i-1]
+		if change > 0 {
+			gains += change
+		} else {
+			losses -= change
+		}
+	}
+	avgGain := gains / float64(period)
+	avgLoss := losses / float64(period)
+	rsi := func() float64 {
+		if avgLoss == 0 {
+			return 100
+		}
+		return 100 - 100/(1+avgGain/avgLoss)
+	}
+	result := []float64{rsi()}
+	for i := period + 1; i < len(values); i++ {
+		change := values[i] - values[i-1]
+		gain, loss := math.Max(change, 0), math.Max(-change, 0)
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+		result = append(result, rsi())
+	}
+	return result
+}
+
+// trueRanges 真实波幅序列（第一个值为0，与 calculateATR 一致）
+func trueRanges(klines []Kline) []float64 {
+	trs := make([]float64, len(klines))
+	for i := 1; i < len(klines); i++ {
+		high, low, prevClose := klines[i].High, klines[i].Low, klines[i-1].Close
+		trs[i] = math.Max(high-low, math.Max(math.Abs(high-prevClose), math.Abs(low-prevClose)))
+	}
+	return trs
+}
+
+// atrValues ATR序列（Wilder平滑，与 calculateATR 一致）
+func atrValues(klines []Kline, period int) []float64 {
+	if period < 1 || len(klines) <= period {
+		return nil
+	}
+	trs := trueRanges(klines)
+	sum := 0.0
+	for i := 1; i <= period; i++ {
+		sum += trs[i]
+	}
+	atr := sum / float64(period)
+	result := []float64{atr}
+	for i := period + 1; i < len(klines); i++ {
+		atr = (atr*float64(period-1) + trs[i]) / float64(period)
+		result = append(result, atr)
+	}
+	return result
+}
+
+func computeMACD(klines []Kline, p map[string]float64) map[string][]float64 {
+	fast, slow, signal := int(p["fast"]), int(p["slow"]), int(p["signal"])
+	if fast < 1 || slow <= fast || signal < 1 {
+		return nil
+	}
+	closes := closeValues(klines)
+	fastEMA, slowEMA := emaValues(closes, fast), emaValues(closes, slow)
+	if len(slowEMA) == 0 {
+		return nil
+	}
+	offset := len(fastEMA) - len(slowEMA)
+	macd := make([]float64, len(slowEMA))
+	for i := range slowEMA {
+		macd[i] = fastEMA[offset+i] - slowEMA[i]
+	}
+	signalLine := emaValues(macd, signal)
+	histogram := make([]float64, len(signalLine))
+	for i := range signalLine {
+		histogram[i] = macd[len(macd)-len(signalLine)+i] - signalLine[i]
+	}
+	return map[string][]float64{"macd": macd, "signal": signalLine, "histogram": histogram}
+}
+
+func computeADX(klines []Kline, p map[string]float64) map[string][]float64 {
+	period := int(p["period"])
+	if period < 1 || len(klines) <= period {
+		return nil
+	}
+	trs := trueRanges(klines)
+	plusDM := make([]float64, len(klines))
+	minusDM := make([]float64, len(klines))
+	for i := 1; i < len(klines); i++ {
+		up := klines[i].High - klines[i-1].High
+		down := klines[i-1].Low - klines[i].Low
+		if up > down && up > 0 {
+			plusDM[i] = up
+		}
+		if down > up && down > 0 {
+			minusDM[i] = down
+		}
+	}
+
+	// Wilder平滑：首个值为前period个之和，之后 s = s - s/period + v
+	var sTR, sPlus, sMinus float64
+	for i := 1; i <= period; i++ {
+		sTR += trs[i]
+		sPlus += plusDM[i]
+		sMinus += minusDM[i]
+	}
+	var plusDI, minusDI, dx []float64
+	for i := period; i < len(klines); i++ {
+		if i > period {
+			sTR = sTR - sTR/float64(period) + trs[i]
+			sPlus = sPlus - sPlus/float64(period) + plusDM[i]
+			sMinus = sMinus - sMinus/float64(period) + minusDM[i]
+		}
+		pdi, mdi := 0.0, 0.0
+		if sTR > 0 {
+			pdi = 100 * sPlus / sTR
+			mdi = 100 * sMinus / sTR
+		}
+		plusDI = append(plusDI, pdi)
+		minusDI = append(minusDI, mdi)
+		if pdi+mdi > 0 {
+			dx = append(dx, 100*math.Abs(pdi-mdi)/(pdi+mdi))
+		} else {
+			dx = append(dx, 0)
+		}
+	}
+
+	var adx []float64
+	if len(dx) >= period {
+		sum := 0.0
+		for _, v := range dx[:period] {
+			sum += v
+		}
+		value := sum / float64(period)
+		adx = append(adx, value)
+		for _, v := range dx[period:] {
+			value = (value*float64(period-1) + v) / float64(period)
+			adx = append(adx, value)
+		}
+	}
+	return map[string][]float64{"adx": adx, "plus_di": plusDI, "minus_di": minusDI}
+}
+
+func computeVWAP(klines []Kline, p map[string]float64) map[string][]float64 {
+	period := int(p["period"])
+	result := make([]float64, len(klines))
+	var pv, vol float64
+	var day int64 = -1
+	for i, k := range klines {
+		typical := (k.High + k.Low + k.Close) / 3
+		if period > 0 {
+			if i >= period {
+				old := klines[i-period]
+				pv -= (old.High + old.Low + old.Close) / 3 * old.Volume
+				vol -= old.Volume
+			}
+		} else if k.OpenTime > 0 {
+			// 按UTC日重置
+			if d := k.OpenTime / int64(24*time.Hour/time.Millisecond); d != day {
+				day = d
+				pv, vol = 0, 0
+			}
+		}
+		pv += typical * k.Volume
+		vol += k.Volume
+		if vol > 0 {
+			result[i] = pv / vol
+		} else {
+			result[i] = typical
+		}
+	}
+	if period > 0 {
+		if len(result) < period {
+			return nil
+		}
+		result = result[period-1:]
+	}
+	return map[string][]float64{"vwap": result}
+}
+
+func computeSupertrend(klines []Kline, p map[string]float64) map[string][]float64 {
+	period, multiplier := int(p["period"]), p["multiplier"]
+	atr := atrValues(klines, period)
+	if len(atr) == 0 {
+		return nil
+	}
+	var trend, direction []float64
+	var finalUpper, finalLower float64
+	dir := 1.0
+	for j, a := range atr {
+		i := period + j
+		hl2 := (klines[i].High + klines[i].Low) / 2
+		basicUpper, basicLower := hl2+multiplier*a, hl2-multiplier*a
+		if j == 0 {
+			finalUpper, finalLower = basicUpper, basicLower
+		} else {
+			prevClose := klines[i-1].Close
+			if basicUpper < finalUpper || prevClose > finalUpper {
+				finalUpper = basicUpper
+			}
+			if basicLower > finalLower || prevClose < finalLower {
+				finalLower = basicLower
+			}
+		}
+		if dir > 0 && klines[i].Close < finalLower {
+			dir = -1
+		} else if dir < 0 && klines[i].Close > finalUpper {
+			dir = 1
+		}
+		if dir > 0 {
+			trend = append(trend, finalLower)
+		} else {
+			trend = append(trend, finalUpper)
+		}
+		direction = append(direction, dir)
+	}
+	return map[string][]float64{"supertrend": trend, "direction": direction}
+}
+
+// midpoints 滚动窗口 (最高价 + 最低价) / 2 序列，索引与 klines 对齐（不足period的位置为NaN）
+func midpoints(klines []Kline, period int) []float64 {
+	result := make([]float64, len(klines))
+	for i := range klines {
+		if period < 1 || i < period-1 {
+			result[i] = math.NaN()
+			continue
+		}
+		high, low := klines[i].High, klines[i].Low
+		for _, k := range klines[i-period+1 : i] {
+			high = math.Max(high, k.High)
+			low = math.Min(low, k.Low)
+		}
+		result[i] = (high + low) / 2
+	}
+	return result
+}
+
+// trimNaN 去掉序列开头的NaN
+func trimNaN(values []float64) []float64 {
+	for i, v := range values {
+		if !math.IsNaN(v) {
+			return values[i:]
+		}
+	}
+	return nil
+}
+
+func computeIchimoku(klines []Kline, p map[string]float64) map[string][]float64 {
+	tenkanPeriod, kijunPeriod, senkouPeriod := int(p["tenkan"]), int(p["kijun"]), int(p["senkou"])
+	if tenkanPeriod < 1 || kijunPeriod < 1 || senkouPeriod < 1 {
+		return nil
+	}
+	tenkan := midpoints(klines, tenkanPeriod)
+	kijun := midpoints(klines, kijunPeriod)
+	senkou := midpoints(klines, senkouPeriod)
+
+	// 先行带向前位移 kijun 根K线：当前K线的云层由 kijun 根之前的数据计算
+	spanA := make([]float64, len(klines))
+	spanB := make([]float64, len(klines))
+	for i := range klines {
+		src := i - kijunPeriod
+		if src < 0 {
+			spanA[i], spanB[i] = math.NaN(), math.NaN()
+			continue
+		}
+		spanA[i] = (tenkan[src] + kijun[src]) / 2
+		spanB[i] = senkou[src]
+	}
+	return map[string][]float64{
+		"tenkan": trimNaN(tenkan),
+		"kijun":  trimNaN(kijun),
+		"span_a": trimNaN(spanA),
+		"span_b": trimNaN(spanB),
+	}
+}
+
+func computeStochRSI(klines []Kline, p map[string]float64) map[string][]float64 {
+	stochPeriod := int(p["stoch"])
+	rsi := rsiValues(closeValues(klines), int(p["rsi"]))
+	if stochPeriod < 1 || len(rsi) < stochPeriod {
+		return nil
+	}
+	stoch := make([]float64, 0, len(rsi)-stochPeriod+1)
+	for i := stochPeriod - 1; i < len(rsi); i++ {
+		high, low := rsi[i], rsi[i]
+		for _, v := range rsi[i-stochPeriod+1 : i] {
+			high = math.Max(high, v)
+			low = math.Min(low, v)
+		}
+		value := 0.0
+		if high > low {
+			value = (rsi[i] - low) / (high - low) * 100
+		}
+		stoch = append(stoch, value)
+	}
+	k := smaValues(stoch, int(p["k"]))
+	return map[string][]float64{"k": k, "d": smaValues(k, int(p["d"]))}
+}
+
+func computeDonchian(klines []Kline, p map[string]float64) map[string][]float64 {
+	period := int(p["period"])
+	if period < 1 || len(klines) < period {
+		return nil
+	}
+	var upper, middle, lower []float64
+	for i := period - 1; i < len(klines); i++ {
+		high, low := klines[i].High, klines[i].Low
+		for _, k := range klines[i-period+1 : i] {
+			high = math.Max(high, k.High)
+			low = math.Min(low, k.Low)
+		}
+		upper = append(upper, high)
+		lower = append(lower, low)
+		middle = append(middle, (high+low)/2)
+	}
+	return map[string][]float64{"upper": upper, "middle": middle, "lower": lower}
+}
+
+func computeCVD(klines []Kline, _ map[string]float64) map[string][]float64 {
+	cvd := make([]float64, len(klines))
+	delta := make([]float64, len(klines))
+	total := 0.0
+	for i, k := range klines {
+		if k.TakerBuyBaseVolume > 0 {
+			// 主动买入量 - 主动卖出量
+			delta[i] = 2*k.TakerBuyBaseVolume - k.Volume
+		} else if k.Close > k.Open {
+			delta[i] = k.Volume
+		} else if k.Close < k.Open {
+			delta[i] = -k.Volume
+		}
+		total += delta[i]
+		cvd[i] = total
+	}
+	return map[string][]float64{"cvd": cvd, "delta": delta}
+}
@@ -15,13 +15,14 @@ type WSMonitor struct {
 	symbols        []string
 	featuresMap    sync.Map
 	alertsChan     chan Alert
-	klineDataMap3m sync.Map // 存储每个交易对的K线历史数据
-	klineDataMap4h sync.Map // 存储每个交易对的K线历史数据
+	klineDataMaps  sync.Map // K线周期 -> *sync.Map（存储每个交易对的K线历史数据）
 	tickerDataMap  sync.Map // 存储每个交易对的ticker数据
 	batchSize      int
 	filterSymbols  sync.Map // 使用sync.Map来存储需要监控的币种和其状态
 	symbolStats    sync.Map // 存储币种统计信息
 	FilterSymbol   []string //经过筛选的币种
+
+	intervals []string // 启动时订阅的K线周期（其他周期在首次使用时动态订阅）
 }
 type SymbolStats struct {
 	LastActiveTime   time.Time
@@ -32,14 +33,18 @@ type SymbolStats struct {
 }
 
 var WSMonitorCli *WSMonitor
-var subKlineTime = []string{"3m", "4h"} // 管理订阅流的K线周期
 
-func NewWSMonitor(batchSize int) *WSMonitor {
+// NewWSMonitor 创建WebSocket监控器，intervals 为启动时订阅的K线周期（为空时使用默认周期）
+func NewWSMonitor(batchSize int, intervals ...string) *WSMonitor {
+	if len(intervals) == 0 {
+		intervals = DefaultTimeframes
+	}
 	WSMonitorCli = &WSMonitor{
 		wsClient:       NewWSClient(),
 		combinedClient: NewCombinedStreamsClient(batchSize),
 		alertsChan:     make(chan Alert, 1000),
 		batchSize:      batchSize,
+		intervals:      append([]string(nil), intervals...),
 	}
 	return WSMonitorCli
 }
@@ -89,25 +94,17 @@ func (m *WSMonitor) initializeHistoricalData() error {
 			defer wg.Done()
 			defer func() { <-semaphore }()
 
-			// 获取历史K线数据（V1.65: 增加到300条以支持更准确的技术指标计算）
-			klines, err := apiClient.GetKlines(s, "3m", DefaultKlineLimit)
-			if err != nil {
-				log.Printf("获取 %s 历史数据失败: %v", s, err)
-				return
-			}
-			if len(klines) > 0 {
-				m.klineDataMap3m.Store(s, klines)
-				log.Printf("已加载 %s 的历史K线数据-3m: %d 条", s, len(klines))
-			}
-			// 获取历史K线数据（V1.65: 增加到300条以支持更准确的技术指标计算）
-			klines4h, err := apiClient.GetKlines(s, "4h", DefaultKlineLimit)
-			if err != nil {
-				log.Printf("获取 %s 历史数据失败: %v", s, err)
-				return
-			}
-			if len(klines4h) > 0 {
-				m.klineDataMap4h.Store(s, klines4h)
-				log.Printf("已加载 %s 的历史K线数据-4h: %d 条", s, len(klines4h))
+			for _, interval := range m.intervals {
+				// 获取历史K线数据（V1.65: 增加到300条以支持更准确的技术指标计算）
+				klines, err := apiClient.GetKlines(s, interval, DefaultKlineLimit)
+				if err != nil {
+					log.Printf("获取 %s 历史数据失败: %v", s, err)
+					return
+				}
+				if len(klines) > 0 {
+					m.getKlineDataMap(interval).Store(s, klines)
+					log.Printf("已加载 %s 的历史K线数据-%s: %d 条", s, interval, len(klines))
+				}
 			}
 		}(symbol)
 	}
@@ -164,11 +161,11 @@ func (m *WSMonitor) subscribeAll() error {
 	// 执行批量订阅
 	log.Println("开始订阅所有交易对...")
 	for _, symbol := range m.symbols {
-		for _, st := range subKlineTime {
+		for _, st := range m.intervals {
 			m.subscribeSymbol(symbol, st)
 		}
 	}
-	for _, st := range subKlineTime {
+	for _, st := range m.intervals {
 		err := m.combinedClient.BatchSubscribeKlines(m.symbols, st)
 		if err != nil {
 			log.Printf("❌ 订阅 %s K线失败: %v", st, err)
@@ -190,16 +187,10 @@ func (m *WSMonitor) handleKlineData(symbol string, ch <-chan []byte, _time strin
 	}
 }
 
+// getKlineDataMap 获取指定周期的K线缓存（不存在时创建）
 func (m *WSMonitor) getKlineDataMap(_time string) *sync.Map {
-	var klineDataMap *sync.Map
-	if _time == "3m" {
-		klineDataMap = &m.klineDataMap3m
-	} else if _time == "4h" {
-		klineDataMap = &m.klineDataMap4h
-	} else {
-		klineDataMap = &sync.Map{}
-	}
-	return klineDataMap
+	value, _ := m.klineDataMaps.LoadOrStore(_time, &sync.Map{})
+	return value.(*sync.Map)
 }
 func (m *WSMonitor) processKlineUpdate(symbol string, wsData KlineWSData, _time string) {
 	// 转换WebSocket数据为Kline结构
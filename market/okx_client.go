@@ -35,10 +35,10 @@ func (c *OKXAPIClient) GetKlines(symbol, interval string, limit int) ([]Kline, e
 	// 转换symbol格式：BTCUSDT -> BTC-USDT-SWAP
 	instID := convertSymbolToOKXInstID(symbol)
 	
-	// 转换时间间隔：3m -> 3m, 4h -> 4H
+	// 转换时间间隔：3m -> 3m, 4h -> 4H, 1d -> 1D, 1w -> 1W
 	okxInterval := interval
-	if interval == "4h" {
-		okxInterval = "4H"
+	if strings.HasSuffix(interval, "h") || strings.HasSuffix(interval, "d") || strings.HasSuffix(interval, "w") {
+		okxInterval = strings.ToUpper(interval)
 	}
 	
 	url := fmt.Sprintf("%s/api/v5/market/candles", okxBaseURL)
@@ -114,11 +114,8 @@ func (c *OKXAPIClient) GetKlines(symbol, interval string, limit int) ([]Kline, e
 		}
 		// 计算CloseTime（根据interval）
 		closeTimeOffset := int64(0)
-		switch interval {
-		case "3m":
-			closeTimeOffset = 3 * 60 * 1000 // 3分钟
-		case "4h":
-			closeTimeOffset = 4 * 60 * 60 * 1000 // 4小时
+		if d, ok := IntervalDuration(interval); ok {
+			closeTimeOffset = d.Milliseconds()
 		}
 		kline.CloseTime = ts + closeTimeOffset - 1
 		
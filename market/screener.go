@@ -0,0 +1,310 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScreenerWeights 本地筛选器各指标权重
+type ScreenerWeights struct {
+	Volume     float64 `json:"volume"`     // 24h成交额
+	Momentum   float64 `json:"momentum"`   // 24h涨跌幅绝对值
+	OIDelta    float64 `json:"oi_delta"`   // 持仓量变化（与上次筛选相比）
+	Funding    float64 `json:"funding"`    // 资金费率绝对值（极端费率往往意味着拥挤交易）
+	Volatility float64 `json:"volatility"` // ATR/价格 与 布林带宽度
+}
+
+// DefaultScreenerWeights 默认权重
+func DefaultScreenerWeights() ScreenerWeights {
+	return ScreenerWeights{Volume: 0.3, Momentum: 0.25, OIDelta: 0.2, Funding: 0.1, Volatility: 0.15}
+}
+
+// ScreenerConfig 本地筛选器配置
+type ScreenerConfig struct {
+	QuoteAsset     string          `json:"quote_asset"`      // 计价币种（默认USDT）
+	MinQuoteVolume float64         `json:"min_quote_volume"` // 最小24h成交额（默认5000万）
+	MinListingDays int             `json:"min_listing_days"` // 最小上线天数（默认30天，过滤新币）
+	ExcludeSymbols []string        `json:"exclude_symbols"`  // 排除的币种
+	PrefilterTop   int             `json:"prefilter_top"`    // 按成交额预筛前N个再拉取明细（默认40）
+	KlineInterval  string          `json:"kline_interval"`   // 计算波动率的K线周期（默认1h）
+	CacheTTL       time.Duration   `json:"-"`                // 结果缓存时间（默认5分钟）
+	Weights        ScreenerWeights `json:"weights"`
+
+	// Tradable 币种是否可交易（交易员所在交易所是否上市），为nil时不过滤
+	Tradable func(symbol string) bool `json:"-"`
+}
+
+// ScreenedCoin 筛选结果
+type ScreenedCoin struct {
+	Symbol            string  `json:"symbol"`
+	Score             float64 `json:"score"`               // 综合评分（0-100）
+	QuoteVolume       float64 `json:"quote_volume"`        // 24h成交额
+	PriceChangePct    float64 `json:"price_change_pct"`    // 24h涨跌幅（%）
+	OIDeltaPct        float64 `json:"oi_delta_pct"`        // 持仓量变化（%，首次筛选为0）
+	FundingRate       float64 `json:"funding_rate"`        // 当前资金费率
+	ATRPct            float64 `json:"atr_pct"`             // ATR14 / 价格（%）
+	BollingerWidthPct float64 `json:"bollinger_width_pct"` // 布林带宽度 / 中轨（%）
+	ListingDays       int     `json:"listing_days"`        // 上线天数
+}
+
+// Screener 本地币种筛选器：基于交易所永续合约全市场数据计算候选币种评分
+type Screener struct {
+	config ScreenerConfig
+	client *APIClient
+
+	mu       sync.Mutex
+	result   []ScreenedCoin
+	resultAt time.Time
+
+	oiMu   sync.Mutex
+	prevOI map[string]float64 // 上次筛选时的持仓量（用于计算变化）
+}
+
+// NewScreener 创建本地筛选器（未设置的参数使用默认值）
+func NewScreener(config ScreenerConfig) *Screener {
+	if config.QuoteAsset == "" {
+		config.QuoteAsset = "USDT"
+	}
+	if config.MinQuoteVolume <= 0 {
+		config.MinQuoteVolume = 50_000_000
+	}
+	if config.MinListingDays < 0 {
+		config.MinListingDays = 0
+	} else if config.MinListingDays == 0 {
+		config.MinListingDays = 30
+	}
+	if config.PrefilterTop <= 0 {
+		config.PrefilterTop = 40
+	}
+	if config.KlineInterval == "" {
+		config.KlineInterval = "1h"
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = 5 * time.Minute
+	}
+	if config.Weights == (ScreenerWeights{}) {
+		config.Weights = DefaultScreenerWeights()
+	}
+	return &Screener{
+		config: config,
+		client: NewAPIClient(),
+		prevOI: make(map[string]float64),
+	}
+}
+
+// Screen 返回按评分降序排列的候选币种（缓存期内直接返回上次结果）
+func (s *Screener) Screen() ([]ScreenedCoin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.result != nil && time.Since(s.resultAt) < s.config.CacheTTL {
+		return append([]ScreenedCoin(nil), s.result...), nil
+	}
+
+	coins, err := s.screen()
+	if err != nil {
+		if s.result != nil {
+			log.Printf("⚠️  本地筛选失败，使用上次结果: %v", err)
+			return append([]ScreenedCoin(nil), s.result...), nil
+		}
+		return nil, err
+	}
+	s.result = coins
+	s.resultAt = time.Now()
+	return append([]ScreenedCoin(nil), coins...), nil
+}
+
+// screen 执行一次完整筛选
+func (s *Screener) screen() ([]ScreenedCoin, error) {
+	log.Printf("🔍 本地筛选器开始扫描永续合约...")
+
+	info, err := s.client.GetExchangeInfo()
+	if err != nil {
+		return nil, fmt.Errorf("获取交易对信息失败: %w", err)
+	}
+	tickers, err := s.client.Get24hrTickers()
+	if err != nil {
+		return nil, fmt.Errorf("获取24h行情失败: %w", err)
+	}
+
+	excluded := make(map[string]bool, len(s.config.ExcludeSymbols))
+	for _, symbol := range s.config.ExcludeSymbols {
+		excluded[Normalize(symbol)] = true
+	}
+
+	// 1. 过滤：永续、交易中、计价币种、上线天数、排除列表
+	listingDays := make(map[string]int)
+	now := time.Now()
+	for _, sym := range info.Symbols {
+		if sym.ContractType != "PERPETUAL" || sym.Status != "TRADING" || sym.QuoteAsset != s.config.QuoteAsset {
+			continue
+		}
+		if excluded[sym.Symbol] {
+			continue
+		}
+		days := 0
+		if sym.OnboardDate > 0 {
+			days = int(now.Sub(time.UnixMilli(sym.OnboardDate)).Hours() / 24)
+		}
+		if days < s.config.MinListingDays {
+			continue
+		}
+		listingDays[sym.Symbol] = days
+	}
+
+	// 2. 按24h成交额过滤、剔除交易员所在交易所未上市的币种，再预筛前N个
+	var coins []ScreenedCoin
+	delisted := 0
+	for _, t := range tickers {
+		days, ok := listingDays[t.Symbol]
+		if !ok {
+			continue
+		}
+		volume, _ := strconv.ParseFloat(t.QuoteVolume, 64)
+		if volume < s.config.MinQuoteVolume {
+			continue
+		}
+		if s.config.Tradable != nil && !s.config.Tradable(t.Symbol) {
+			delisted++
+			continue
+		}
+		change, _ := strconv.ParseFloat(t.PriceChangePercent, 64)
+		coins = append(coins, ScreenedCoin{
+			Symbol:         t.Symbol,
+			QuoteVolume:    volume,
+			PriceChangePct: change,
+			ListingDays:    days,
+		})
+	}
+	if delisted > 0 {
+		log.Printf("🔍 本地筛选器剔除 %d 个当前交易所未上市的币种", delisted)
+	}
+	if len(coins) == 0 {
+		return nil, fmt.Errorf("没有满足条件的币种（最小成交额 %.0f，最小上线天数 %d）", s.config.MinQuoteVolume, s.config.MinListingDays)
+	}
+	sort.Slice(coins, func(i, j int) bool { return coins[i].QuoteVolume > coins[j].QuoteVolume })
+	if len(coins) > s.config.PrefilterTop {
+		coins = coins[:s.config.PrefilterTop]
+	}
+
+	// 3. 并发拉取持仓量、资金费率和K线
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for i := range coins {
+		wg.Add(1)
+		go func(coin *ScreenedCoin) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			s.fillDetails(coin)
+		}(&coins[i])
+	}
+	wg.Wait()
+
+	// 4. 各指标按百分位排名后加权求和
+	s.scoreCoins(coins)
+	sort.SliceStable(coins, func(i, j int) bool { return coins[i].Score > coins[j].Score })
+
+	log.Printf("✓ 本地筛选完成: %d个币种入选，前5: %s", len(coins), topSymbols(coins, 5))
+	return coins, nil
+}
+
+// fillDetails 补充持仓量变化、资金费率和波动率（单项失败不影响其他指标）
+func (s *Screener) fillDetails(coin *ScreenedCoin) {
+	if oi, err := getOpenInterestData(coin.Symbol); err == nil && oi.Latest > 0 {
+		s.recordOI(coin, oi.Latest)
+	}
+	if rate, err := getFundingRate(coin.Symbol); err == nil {
+		coin.FundingRate = rate
+	}
+	klines, err := s.client.GetKlines(coin.Symbol, s.config.KlineInterval, 50)
+	if err != nil || len(klines) == 0 {
+		return
+	}
+	price := klines[len(klines)-1].Close
+	if price <= 0 {
+		return
+	}
+	coin.ATRPct = calculateATR(klines, 14) / price * 100
+	if bb := calculateBollingerBands(klines, 20, 2); bb != nil && bb.Middle > 0 {
+		coin.BollingerWidthPct = (bb.Upper - bb.Lower) / bb.Middle * 100
+	}
+}
+
+// recordOI 计算与上次筛选相比的持仓量变化并记录本次持仓量
+func (s *Screener) recordOI(coin *ScreenedCoin, oi float64) {
+	s.oiMu.Lock()
+	defer s.oiMu.Unlock()
+	if prev, ok := s.prevOI[coin.Symbol]; ok && prev > 0 {
+		coin.OIDeltaPct = (oi - prev) / prev * 100
+	}
+	s.prevOI[coin.Symbol] = oi
+}
+
+// scoreCoins 计算综合评分（0-100）
+func (s *Screener) scoreCoins(coins []ScreenedCoin) {
+	w := s.config.Weights
+	total := w.Volume + w.Momentum + w.OIDelta + w.Funding + w.Volatility
+	if total <= 0 {
+		return
+	}
+
+	metric := func(f func(c ScreenedCoin) float64) []float64 {
+		values := make([]float64, len(coins))
+		for i, c := range coins {
+			values[i] = f(c)
+		}
+		return percentileRanks(values)
+	}
+	volume := metric(func(c ScreenedCoin) float64 { return c.QuoteVolume })
+	momentum := metric(func(c ScreenedCoin) float64 { return abs(c.PriceChangePct) })
+	oiDelta := metric(func(c ScreenedCoin) float64 { return c.OIDeltaPct })
+	funding := metric(func(c ScreenedCoin) float64 { return abs(c.FundingRate) })
+	atr := metric(func(c ScreenedCoin) float64 { return c.ATRPct })
+	width := metric(func(c ScreenedCoin) float64 { return c.BollingerWidthPct })
+
+	for i := range coins {
+		score := w.Volume*volume[i] + w.Momentum*momentum[i] + w.OIDelta*oiDelta[i] +
+			w.Funding*funding[i] + w.Volatility*(atr[i]+width[i])/2
+		coins[i].Score = score / total * 100
+	}
+}
+
+// percentileRanks 百分位排名（0-1，相同值取相同排名）
+func percentileRanks(values []float64) []float64 {
+	ranks := make([]float64, len(values))
+	if len(values) <= 1 {
+		return ranks
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	for i, v := range values {
+		below := sort.SearchFloat64s(sorted, v)
+		ranks[i] = float64(below) / float64(len(values)-1)
+	}
+	return ranks
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// topSymbols 前N个币种（用于日志）
+func topSymbols(coins []ScreenedCoin, n int) string {
+	if len(coins) < n {
+		n = len(coins)
+	}
+	symbols := make([]string, 0, n)
+	for _, c := range coins[:n] {
+		symbols = append(symbols, fmt.Sprintf("%s(%.0f)", c.Symbol, c.Score))
+	}
+	return strings.Join(symbols, ", ")
+}
@@ -0,0 +1,106 @@
+package market
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultTimeframes 默认K线周期（短周期用于当前指标和日内序列，长周期用于趋势背景）
+var DefaultTimeframes = []string{"3m", "4h"}
+
+// intervalDurations 支持的K线周期（与币安永续合约一致）
+var intervalDurations = map[string]time.Duration{
+	"1m":  time.Minute,
+	"3m":  3 * time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"2h":  2 * time.Hour,
+	"4h":  4 * time.Hour,
+	"6h":  6 * time.Hour,
+	"8h":  8 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+	"3d":  3 * 24 * time.Hour,
+	"1w":  7 * 24 * time.Hour,
+}
+
+// MaxTimeframes 单个交易员最多配置的K线周期数（每个周期每轮都要拉取K线）
+const MaxTimeframes = 5
+
+// IntervalDuration 返回K线周期对应的时长，不支持的周期返回false
+func IntervalDuration(interval string) (time.Duration, bool) {
+	d, ok := intervalDurations[interval]
+	return d, ok
+}
+
+// NormalizeTimeframes 校验、去重并按周期从短到长排序
+// 为空时返回默认周期
+func NormalizeTimeframes(timeframes []string) ([]string, error) {
+	seen := make(map[string]bool, len(timeframes))
+	result := make([]string, 0, len(timeframes))
+	for _, tf := range timeframes {
+		tf = strings.TrimSpace(tf)
+		if tf == "" {
+			continue
+		}
+		// 兼容 1H/4H/1D 等大写写法（1M 为月线，不支持）
+		if tf != "1M" {
+			tf = strings.ToLower(tf)
+		}
+		if _, ok := intervalDurations[tf]; !ok {
+			return nil, fmt.Errorf("不支持的K线周期: %s", tf)
+		}
+		if seen[tf] {
+			continue
+		}
+		seen[tf] = true
+		result = append(result, tf)
+	}
+	if len(result) == 0 {
+		return append([]string(nil), DefaultTimeframes...), nil
+	}
+	if len(result) > MaxTimeframes {
+		return nil, fmt.Errorf("K线周期最多%d个，当前%d个", MaxTimeframes, len(result))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return intervalDurations[result[i]] < intervalDurations[result[j]]
+	})
+	return result, nil
+}
+
+// ParseTimeframes 解析逗号分隔的K线周期（如 "15m,1h,1d"），为空时返回默认周期
+func ParseTimeframes(s string) ([]string, error) {
+	return NormalizeTimeframes(strings.Split(s, ","))
+}
+
+// priceChangeOver 计算指定时长内的价格变化百分比
+// 选择能整除该时长的最长周期（如1小时优先用1h，其次用15m/5m/3m），数据不足时返回0
+func priceChangeOver(timeframes []string, klines map[string][]Kline, currentPrice float64, d time.Duration) float64 {
+	best := ""
+	for _, tf := range timeframes {
+		td := intervalDurations[tf]
+		if td > d || d%td != 0 {
+			continue
+		}
+		if best == "" || td > intervalDurations[best] {
+			best = tf
+		}
+	}
+	if best == "" {
+		return 0
+	}
+	bars := int(d / intervalDurations[best])
+	series := klines[best]
+	if len(series) < bars+1 {
+		return 0
+	}
+	priceAgo := series[len(series)-1-bars].Close
+	if priceAgo <= 0 {
+		return 0
+	}
+	return (currentPrice - priceAgo) / priceAgo * 100
+}
@@ -21,6 +21,25 @@ type Data struct {
 	SMA               *SMAData            // 简单移动平均线（多周期）
 	OBV               float64             // 能量潮指标
 	VolumeMA          *VolumeMAData       // 成交量移动平均
+
+	// Timeframes 各K线周期的数据（按周期从短到长排序）
+	// IntradaySeries 与第一个周期相同，LongerTermContext 与最后一个周期相同
+	Timeframes []*TimeframeData
+
+	// Indicators 按交易员或提示词模板配置计算的注册指标（见 ComputeIndicators）
+	Indicators []*IndicatorResult
+
+	// OrderBook 盘口快照，Depth 为据此计算的流动性特征（获取失败或回测时为空）
+	OrderBook *OrderBook
+	Depth     *DepthFeatures
+}
+
+// TimeframeData 单个K线周期的序列数据和趋势背景
+type TimeframeData struct {
+	Interval string // K线周期（如 15m、1h、1d）
+	Series   *IntradayData
+	Context  *LongerTermData
+	Klines   []Kline // 原始K线（用于按需计算注册指标）
 }
 
 // OIData Open Interest数据
@@ -29,7 +48,7 @@ type OIData struct {
 	Average float64
 }
 
-// IntradayData 日内数据(3分钟间隔)（V1.65: 扩展以支持更多指标）
+// IntradayData 日内序列数据(默认3分钟间隔，取最短周期)（V1.65: 扩展以支持更多指标）
 type IntradayData struct {
 	MidPrices   []float64
 	EMA20Values []float64
@@ -52,7 +71,7 @@ type IntradayData struct {
 	VolumeMA20     []float64 // 成交量MA20序列
 }
 
-// LongerTermData 长期数据(4小时时间框架)（V1.65: 扩展以支持更多指标）
+// LongerTermData 长期数据(默认4小时时间框架，取最长周期)（V1.65: 扩展以支持更多指标）
 type LongerTermData struct {
 	EMA20         float64
 	EMA50         float64
@@ -113,6 +132,8 @@ type SymbolInfo struct {
 	ContractType      string `json:"contractType"`
 	PricePrecision    int    `json:"pricePrecision"`
 	QuantityPrecision int    `json:"quantityPrecision"`
+
+	OnboardDate int64 `json:"onboardDate"` // 上线时间（毫秒）
 }
 
 type Kline struct {
@@ -142,6 +163,7 @@ type Ticker24hr struct {
 	PriceChangePercent string `json:"priceChangePercent"`
 	Volume             string `json:"volume"`
 	QuoteVolume        string `json:"quoteVolume"`
+	LastPrice          string `json:"lastPrice"`
 }
 
 // 特征数据结构
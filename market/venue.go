@@ -0,0 +1,25 @@
+package market
+
+// ExchangeClient 非币安交易所的行情客户端（K线、价格、持仓量、资金费率、盘口）
+type ExchangeClient interface {
+	GetKlines(symbol, interval string, limit int) ([]Kline, error)
+	GetCurrentPrice(symbol string) (float64, error)
+	GetOpenInterest(symbol string) (*OIData, error)
+	GetFundingRate(symbol string) (float64, error)
+	GetOrderBook(symbol string, limit int) (*OrderBook, error)
+}
+
+// NewExchangeClient 按交易所创建行情客户端，币安及未接入独立行情的交易所（Aster、Hyperliquid等）返回nil，使用币安行情
+func NewExchangeClient(exchange string) ExchangeClient {
+	switch exchange {
+	case "okx":
+		return NewOKXAPIClient()
+	case "bybit":
+		return NewBybitAPIClient()
+	case "bitget":
+		return NewBitgetAPIClient()
+	case "gate":
+		return NewGateAPIClient()
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// anthropicVersion Anthropic Messages API 版本头
+const anthropicVersion = "2023-06-01"
+
+// APIError AI服务返回的HTTP错误（用于区分可重试错误）
+type APIError struct {
+	Provider   Provider
+	StatusCode int
+	Type       string        // 服务商返回的错误类型（如 rate_limit_error / overloaded_error）
+	Message    string        // 服务商返回的错误信息
+	RetryAfter time.Duration // 服务商建议的重试等待时间（retry-after 头）
+}
+
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("API返回错误 (status %d, %s): %s", e.StatusCode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("API返回错误 (status %d): %s", e.StatusCode, e.Message)
+}
+
+// Retryable 限流、过载和服务端错误可重试；鉴权、参数、权限等错误重试无意义
+func (e *APIError) Retryable() bool {
+	switch e.Type {
+	case "rate_limit_error", "overloaded_error", "api_error":
+		return true
+	case "invalid_request_error", "authentication_error", "permission_error", "not_found_error", "request_too_large", "billing_error":
+		return false
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// SetAnthropicAPIKey 设置Anthropic (Claude) API密钥（原生 Messages API）
+// customURL 为空时使用默认URL，customModel 为空时使用默认模型
+func (client *Client) SetAnthropicAPIKey(apiKey string, customURL string, customModel string) {
+	client.Provider = ProviderAnthropic
+	client.APIKey = apiKey
+	if customURL != "" {
+		client.BaseURL = strings.TrimSuffix(customURL, "/")
+		log.Printf("🔧 [MCP] Anthropic 使用自定义 BaseURL: %s", customURL)
+	} else {
+		client.BaseURL = "https://api.anthropic.com/v1"
+		log.Printf("🔧 [MCP] Anthropic 使用默认 BaseURL: %s", client.BaseURL)
+	}
+	if customModel != "" {
+		client.Model = customModel
+		log.Printf("🔧 [MCP] Anthropic 使用自定义 Model: %s", customModel)
+	} else {
+		client.Model = "claude-sonnet-4-5"
+		log.Printf("🔧 [MCP] Anthropic 使用默认 Model: %s", client.Model)
+	}
+	client.UseFullURL = false
+	// 打印 API Key 的前后各4位用于验证
+	if len(apiKey) > 8 {
+		log.Printf("🔧 [MCP] Anthropic API Key: %s...%s", apiKey[:4], apiKey[len(apiKey)-4:])
+	}
+}
+
+// callAnthropic 调用Anthropic Messages API
+// 与OpenAI格式的区别：system 为顶层字段、响应为 content 块数组、使用 x-api-key 认证
+func (client *Client) callAnthropic(systemPrompt, userPrompt string) (string, *Usage, error) {
+	url := client.BaseURL
+	if !strings.HasSuffix(url, "/messages") {
+		url = fmt.Sprintf("%s/messages", url)
+	}
+	log.Printf("📡 [MCP] Anthropic 请求 URL: %s", url)
+
+	requestBody := map[string]interface{}{
+		"model":      client.Model,
+		"max_tokens": client.MaxTokens,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": userPrompt},
+		},
+		"temperature": 0.5, // 降低temperature以提高JSON格式稳定性
+	}
+	if systemPrompt != "" {
+		requestBody["system"] = systemPrompt
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", client.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	// 发送请求
+	httpClient := &http.Client{Timeout: client.Timeout}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// 读取响应
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, parseAnthropicError(resp, body)
+	}
+
+	// 解析 Messages API 响应（content 为内容块数组）
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+		Model      string `json:"model"`
+		Usage      struct {
+			InputTokens              int `json:"input_tokens"` // 不含缓存读写部分
+			OutputTokens             int `json:"output_tokens"`
+			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	var text strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", nil, fmt.Errorf("API返回空响应 (stop_reason: %s)", result.StopReason)
+	}
+	if result.StopReason == "max_tokens" {
+		log.Printf("⚠️  [MCP] Anthropic 响应因达到 max_tokens (%d) 被截断，可通过 AI_MAX_TOKENS 调大", client.MaxTokens)
+	}
+
+	usage := &Usage{
+		Model:            result.Model,
+		PromptTokens:     result.Usage.InputTokens + result.Usage.CacheReadInputTokens + result.Usage.CacheCreationInputTokens,
+		CompletionTokens: result.Usage.OutputTokens,
+		CachedTokens:     result.Usage.CacheReadInputTokens,
+	}
+
+	return text.String(), usage, nil
+}
+
+// parseAnthropicError 解析 Anthropic 错误响应 {"type":"error","error":{"type":"...","message":"..."}}
+func parseAnthropicError(resp *http.Response, body []byte) error {
+	apiErr := &APIError{
+		Provider:   ProviderAnthropic,
+		StatusCode: resp.StatusCode,
+		Message:    string(body),
+	}
+
+	var errResp struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Type != "" {
+		apiErr.Type = errResp.Error.Type
+		apiErr.Message = errResp.Error.Message
+	}
+
+	if retryAfter := resp.Header.Get("retry-after"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return apiErr
+}
@@ -3,6 +3,7 @@ package mcp
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -22,6 +23,7 @@ const (
 	ProviderGoogleAI   Provider = "googleai"   // Google AI (Gemini)
 	ProviderChatGPT    Provider = "chatgpt"    // OpenAI ChatGPT (Chat Completions API)
 	ProviderGPTs       Provider = "gpts"       // OpenAI GPTs (Assistant API)
+	ProviderAnthropic  Provider = "anthropic"  // Anthropic Claude (Messages API)
 	ProviderCustom     Provider = "custom"
 )
 
@@ -36,6 +38,9 @@ type Client struct {
 	MaxTokens    int  // AI响应的最大token数
 	AssistantID  string // OpenAI Assistant ID (用于GPTs)
 	ThreadID     string // OpenAI Thread ID (用于GPTs，可选，为空则每次创建新thread)
+	MaxRetries   int    // 单次调用的最大尝试次数（0表示默认5次）
+
+	lastUsage *Usage // 最近一次成功调用的token用量
 }
 
 func New() *Client {
@@ -216,6 +221,54 @@ func (client *Client) SetCustomAPI(apiURL, apiKey, modelName string) {
 	client.Timeout = 120 * time.Second
 }
 
+// NewClientForProvider 根据提供商名称创建AI客户端（用于备用链和回测等按配置动态创建的场景）
+// GPTs 的 customModel 为 Assistant ID
+func NewClientForProvider(provider, apiKey, customURL, customModel string) (*Client, error) {
+	if apiKey == "" && provider != string(ProviderCustom) {
+		return nil, fmt.Errorf("AI提供商 %s 需要API Key", provider)
+	}
+
+	client := New()
+	switch Provider(provider) {
+	case ProviderDeepSeek:
+		client.SetDeepSeekAPIKey(apiKey, customURL, customModel)
+	case ProviderQwen:
+		client.SetQwenAPIKey(apiKey, customURL, customModel)
+	case ProviderGoogleAI:
+		client.SetGoogleAIAPIKey(apiKey, customURL, customModel)
+	case ProviderChatGPT:
+		client.SetChatGPTAPIKey(apiKey, customURL, customModel)
+	case ProviderAnthropic:
+		client.SetAnthropicAPIKey(apiKey, customURL, customModel)
+	case ProviderGPTs:
+		if customModel == "" {
+			return nil, fmt.Errorf("GPTs需要Assistant ID")
+		}
+		client.SetGPTsAPIKey(apiKey, customModel, "", customURL)
+	case ProviderCustom:
+		if customURL == "" || customModel == "" {
+			return nil, fmt.Errorf("自定义AI需要API URL和模型名称")
+		}
+		client.SetCustomAPI(customURL, apiKey, customModel)
+	default:
+		return nil, fmt.Errorf("不支持的AI提供商: %s", provider)
+	}
+	return client, nil
+}
+
+// ProviderName 提供商和模型名称（如 deepseek/deepseek-chat）
+func (client *Client) ProviderName() string {
+	if client.Provider == ProviderGPTs {
+		return fmt.Sprintf("%s/%s", client.Provider, client.AssistantID)
+	}
+	return fmt.Sprintf("%s/%s", client.Provider, client.Model)
+}
+
+// LastProvider 实现 ProviderReporter（单一提供商时即为自身）
+func (client *Client) LastProvider() string {
+	return client.ProviderName()
+}
+
 // SetClient 设置完整的AI配置（高级用户）
 func (client *Client) SetClient(Client Client) {
 	if Client.Timeout == 0 {
@@ -230,8 +283,13 @@ func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string,
 		return "", fmt.Errorf("AI API密钥未设置，请先调用相应的 SetXXXAPIKey() 方法")
 	}
 
+	client.lastUsage = nil
+
 	// 重试配置（增加重试次数和间隔）
 	maxRetries := 5
+	if client.MaxRetries > 0 {
+		maxRetries = client.MaxRetries
+	}
 	var lastErr error
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
@@ -239,11 +297,26 @@ func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string,
 			log.Printf("⚠️  AI API调用失败，正在重试 (%d/%d)...", attempt, maxRetries)
 		}
 
-		result, err := client.callOnce(systemPrompt, userPrompt)
+		start := time.Now()
+		result, usage, err := client.callOnce(systemPrompt, userPrompt)
 		if err == nil {
 			if attempt > 1 {
 				log.Printf("✓ AI API重试成功")
 			}
+			// 服务商未返回usage时仍记录耗时
+			if usage == nil {
+				usage = &Usage{}
+			}
+			usage.Provider = string(client.Provider)
+			if usage.Model == "" {
+				usage.Model = client.Model
+			}
+			usage.Latency = time.Since(start)
+			usage.Attempts = attempt
+			usage.fillTotal()
+			client.lastUsage = usage
+			log.Printf("📊 [MCP] token用量: 输入=%d (缓存命中=%d) 输出=%d 耗时=%v",
+				usage.PromptTokens, usage.CachedTokens, usage.CompletionTokens, usage.Latency.Round(time.Millisecond))
 			return result, nil
 		}
 
@@ -256,6 +329,11 @@ func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string,
 		// 重试前等待（指数退避：2秒、4秒、8秒、16秒）
 		if attempt < maxRetries {
 			waitTime := time.Duration(1<<uint(attempt-1)) * 2 * time.Second
+			// 服务商给出 retry-after 时按其建议等待
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.RetryAfter > waitTime {
+				waitTime = apiErr.RetryAfter
+			}
 			if waitTime > 30*time.Second {
 				waitTime = 30 * time.Second // 最大等待30秒
 			}
@@ -268,7 +346,7 @@ func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string,
 }
 
 // callOnce 单次调用AI API（内部使用）
-func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
+func (client *Client) callOnce(systemPrompt, userPrompt string) (string, *Usage, error) {
 	// 打印当前 AI 配置
 	log.Printf("📡 [MCP] AI 请求配置:")
 	log.Printf("   Provider: %s", client.Provider)
@@ -289,6 +367,11 @@ func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error)
 		return client.callGPTs(systemPrompt, userPrompt)
 	}
 
+	// Anthropic 使用原生 Messages API
+	if client.Provider == ProviderAnthropic {
+		return client.callAnthropic(systemPrompt, userPrompt)
+	}
+
 	// 构建 messages 数组
 	messages := []map[string]string{}
 
@@ -321,7 +404,7 @@ func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error)
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
+		return "", nil, fmt.Errorf("序列化请求失败: %w", err)
 	}
 
 	// 创建HTTP请求
@@ -337,7 +420,7 @@ func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error)
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		return "", nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -360,42 +443,63 @@ func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error)
 	httpClient := &http.Client{Timeout: client.Timeout}
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("发送请求失败: %w", err)
+		return "", nil, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
+		return "", nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return "", nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	// 解析响应
 	var result struct {
+		Model   string `json:"model"`
 		Choices []struct {
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage struct {
+			PromptTokens         int `json:"prompt_tokens"`
+			CompletionTokens     int `json:"completion_tokens"`
+			TotalTokens          int `json:"total_tokens"`
+			PromptCacheHitTokens int `json:"prompt_cache_hit_tokens"` // DeepSeek 缓存命中
+			PromptTokensDetails  struct {
+				CachedTokens int `json:"cached_tokens"` // OpenAI / Qwen 缓存命中
+			} `json:"prompt_tokens_details"`
+		} `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
+		return "", nil, fmt.Errorf("解析响应失败: %w", err)
 	}
 
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("API返回空响应")
+		return "", nil, fmt.Errorf("API返回空响应")
 	}
 
-	return result.Choices[0].Message.Content, nil
+	usage := &Usage{
+		Model:            result.Model,
+		PromptTokens:     result.Usage.PromptTokens,
+		CompletionTokens: result.Usage.CompletionTokens,
+		CachedTokens:     result.Usage.PromptTokensDetails.CachedTokens,
+		TotalTokens:      result.Usage.TotalTokens,
+	}
+	if result.Usage.PromptCacheHitTokens > 0 {
+		usage.CachedTokens = result.Usage.PromptCacheHitTokens
+	}
+
+	return result.Choices[0].Message.Content, usage, nil
 }
 
 // callGoogleAI 调用Google AI (Gemini) API
-func (client *Client) callGoogleAI(systemPrompt, userPrompt string) (string, error) {
+func (client *Client) callGoogleAI(systemPrompt, userPrompt string) (string, *Usage, error) {
 	// Google AI (Gemini) 使用不同的API格式
 	// URL格式: https://generativeai.googleapis.com/v1/models/{model}:generateContent?key={API_KEY}
 	// 注意：如果 BaseURL 已经包含完整路径，直接使用；否则构建完整路径
@@ -439,12 +543,12 @@ func (client *Client) callGoogleAI(systemPrompt, userPrompt string) (string, err
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
+		return "", nil, fmt.Errorf("序列化请求失败: %w", err)
 	}
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		return "", nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -453,18 +557,18 @@ func (client *Client) callGoogleAI(systemPrompt, userPrompt string) (string, err
 	httpClient := &http.Client{Timeout: client.Timeout}
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("发送请求失败: %w", err)
+		return "", nil, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
+		return "", nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return "", nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	// 解析 Google AI 响应格式
@@ -476,26 +580,42 @@ func (client *Client) callGoogleAI(systemPrompt, userPrompt string) (string, err
 				} `json:"parts"`
 			} `json:"content"`
 		} `json:"candidates"`
+		UsageMetadata struct {
+			PromptTokenCount        int `json:"promptTokenCount"`
+			CandidatesTokenCount    int `json:"candidatesTokenCount"`
+			ThoughtsTokenCount      int `json:"thoughtsTokenCount"` // 思考token按输出计费
+			CachedContentTokenCount int `json:"cachedContentTokenCount"`
+			TotalTokenCount         int `json:"totalTokenCount"`
+		} `json:"usageMetadata"`
+		ModelVersion string `json:"modelVersion"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
+		return "", nil, fmt.Errorf("解析响应失败: %w", err)
 	}
 
 	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("API返回空响应")
+		return "", nil, fmt.Errorf("API返回空响应")
+	}
+
+	usage := &Usage{
+		Model:            result.ModelVersion,
+		PromptTokens:     result.UsageMetadata.PromptTokenCount,
+		CompletionTokens: result.UsageMetadata.CandidatesTokenCount + result.UsageMetadata.ThoughtsTokenCount,
+		CachedTokens:     result.UsageMetadata.CachedContentTokenCount,
+		TotalTokens:      result.UsageMetadata.TotalTokenCount,
 	}
 
-	return result.Candidates[0].Content.Parts[0].Text, nil
+	return result.Candidates[0].Content.Parts[0].Text, usage, nil
 }
 
 // callGPTs 调用OpenAI GPTs (Assistant API)
-func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error) {
+func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, *Usage, error) {
 	// OpenAI GPTs 使用 Assistant API
 	// 流程：1. 创建或获取Thread 2. 添加消息 3. 运行Assistant 4. 获取响应
 
 	if client.AssistantID == "" {
-		return "", fmt.Errorf("GPTs Assistant ID 未设置")
+		return "", nil, fmt.Errorf("GPTs Assistant ID 未设置")
 	}
 
 	httpClient := &http.Client{Timeout: client.Timeout}
@@ -525,12 +645,12 @@ func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error)
 
 		jsonData, err := json.Marshal(createThreadBody)
 		if err != nil {
-			return "", fmt.Errorf("序列化Thread创建请求失败: %w", err)
+			return "", nil, fmt.Errorf("序列化Thread创建请求失败: %w", err)
 		}
 
 		req, err := http.NewRequest("POST", createThreadURL, bytes.NewBuffer(jsonData))
 		if err != nil {
-			return "", fmt.Errorf("创建Thread请求失败: %w", err)
+			return "", nil, fmt.Errorf("创建Thread请求失败: %w", err)
 		}
 
 		req.Header.Set("Content-Type", "application/json")
@@ -539,24 +659,24 @@ func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error)
 
 		resp, err := httpClient.Do(req)
 		if err != nil {
-			return "", fmt.Errorf("发送Thread创建请求失败: %w", err)
+			return "", nil, fmt.Errorf("发送Thread创建请求失败: %w", err)
 		}
 		defer resp.Body.Close()
 
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return "", fmt.Errorf("读取Thread创建响应失败: %w", err)
+			return "", nil, fmt.Errorf("读取Thread创建响应失败: %w", err)
 		}
 
 		if resp.StatusCode != http.StatusOK {
-			return "", fmt.Errorf("创建Thread失败 (status %d): %s\n请求URL: %s\n请求体: %s", resp.StatusCode, string(body), createThreadURL, string(jsonData))
+			return "", nil, fmt.Errorf("创建Thread失败 (status %d): %s\n请求URL: %s\n请求体: %s", resp.StatusCode, string(body), createThreadURL, string(jsonData))
 		}
 
 		var threadResult struct {
 			ID string `json:"id"`
 		}
 		if err := json.Unmarshal(body, &threadResult); err != nil {
-			return "", fmt.Errorf("解析Thread创建响应失败: %w\n响应体: %s", err, string(body))
+			return "", nil, fmt.Errorf("解析Thread创建响应失败: %w\n响应体: %s", err, string(body))
 		}
 
 		threadID = threadResult.ID
@@ -578,12 +698,12 @@ func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error)
 
 		jsonData, err := json.Marshal(addMessageBody)
 		if err != nil {
-			return "", fmt.Errorf("序列化消息添加请求失败: %w", err)
+			return "", nil, fmt.Errorf("序列化消息添加请求失败: %w", err)
 		}
 
 		req, err := http.NewRequest("POST", addMessageURL, bytes.NewBuffer(jsonData))
 		if err != nil {
-			return "", fmt.Errorf("创建消息添加请求失败: %w", err)
+			return "", nil, fmt.Errorf("创建消息添加请求失败: %w", err)
 		}
 
 		req.Header.Set("Content-Type", "application/json")
@@ -592,17 +712,17 @@ func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error)
 
 		resp, err := httpClient.Do(req)
 		if err != nil {
-			return "", fmt.Errorf("发送消息添加请求失败: %w", err)
+			return "", nil, fmt.Errorf("发送消息添加请求失败: %w", err)
 		}
 		defer resp.Body.Close()
 
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return "", fmt.Errorf("读取消息添加响应失败: %w", err)
+			return "", nil, fmt.Errorf("读取消息添加响应失败: %w", err)
 		}
 
 		if resp.StatusCode != http.StatusOK {
-			return "", fmt.Errorf("添加消息失败 (status %d): %s\n请求URL: %s\n请求体: %s", resp.StatusCode, string(body), addMessageURL, string(jsonData))
+			return "", nil, fmt.Errorf("添加消息失败 (status %d): %s\n请求URL: %s\n请求体: %s", resp.StatusCode, string(body), addMessageURL, string(jsonData))
 		}
 
 		log.Printf("📡 [MCP] GPTs 向现有Thread添加消息: %s", threadID)
@@ -616,12 +736,12 @@ func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error)
 
 	jsonData, err := json.Marshal(runBody)
 	if err != nil {
-		return "", fmt.Errorf("序列化Run创建请求失败: %w", err)
+		return "", nil, fmt.Errorf("序列化Run创建请求失败: %w", err)
 	}
 
 	req, err := http.NewRequest("POST", runURL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("创建Run请求失败: %w", err)
+		return "", nil, fmt.Errorf("创建Run请求失败: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -630,17 +750,17 @@ func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("发送Run创建请求失败: %w", err)
+		return "", nil, fmt.Errorf("发送Run创建请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取Run创建响应失败: %w", err)
+		return "", nil, fmt.Errorf("读取Run创建响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("创建Run失败 (status %d): %s\n请求URL: %s\n请求体: %s\nAssistant ID: %s", resp.StatusCode, string(body), runURL, string(jsonData), client.AssistantID)
+		return "", nil, fmt.Errorf("创建Run失败 (status %d): %s\n请求URL: %s\n请求体: %s\nAssistant ID: %s", resp.StatusCode, string(body), runURL, string(jsonData), client.AssistantID)
 	}
 
 	var runResult struct {
@@ -648,13 +768,14 @@ func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error)
 		Status string `json:"status"`
 	}
 	if err := json.Unmarshal(body, &runResult); err != nil {
-		return "", fmt.Errorf("解析Run创建响应失败: %w", err)
+		return "", nil, fmt.Errorf("解析Run创建响应失败: %w", err)
 	}
 
 	runID := runResult.ID
 	log.Printf("📡 [MCP] GPTs 创建Run: %s, 状态: %s", runID, runResult.Status)
 
 	// 3. 等待Run完成（轮询）
+	var usage *Usage
 	maxWaitTime := client.Timeout - 10*time.Second // 留10秒缓冲
 	pollInterval := 2 * time.Second
 	startTime := time.Now()
@@ -663,7 +784,7 @@ func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error)
 		checkRunURL := fmt.Sprintf("%s/threads/%s/runs/%s", client.BaseURL, threadID, runID)
 		req, err := http.NewRequest("GET", checkRunURL, nil)
 		if err != nil {
-			return "", fmt.Errorf("创建Run检查请求失败: %w", err)
+			return "", nil, fmt.Errorf("创建Run检查请求失败: %w", err)
 		}
 
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.APIKey))
@@ -671,32 +792,49 @@ func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error)
 
 		resp, err := httpClient.Do(req)
 		if err != nil {
-			return "", fmt.Errorf("发送Run检查请求失败: %w", err)
+			return "", nil, fmt.Errorf("发送Run检查请求失败: %w", err)
 		}
 
 		body, err := io.ReadAll(resp.Body)
 		resp.Body.Close()
 		if err != nil {
-			return "", fmt.Errorf("读取Run检查响应失败: %w", err)
+			return "", nil, fmt.Errorf("读取Run检查响应失败: %w", err)
 		}
 
 		if resp.StatusCode != http.StatusOK {
-			return "", fmt.Errorf("检查Run状态失败 (status %d): %s", resp.StatusCode, string(body))
+			return "", nil, fmt.Errorf("检查Run状态失败 (status %d): %s", resp.StatusCode, string(body))
 		}
 
 		var runStatus struct {
 			Status string `json:"status"`
+			Model  string `json:"model"`
+			Usage  *struct {
+				PromptTokens        int `json:"prompt_tokens"`
+				CompletionTokens    int `json:"completion_tokens"`
+				TotalTokens         int `json:"total_tokens"`
+				PromptTokensDetails struct {
+					CachedTokens int `json:"cached_tokens"`
+				} `json:"prompt_tokens_details"`
+			} `json:"usage"`
 		}
 		if err := json.Unmarshal(body, &runStatus); err != nil {
-			return "", fmt.Errorf("解析Run状态响应失败: %w", err)
+			return "", nil, fmt.Errorf("解析Run状态响应失败: %w", err)
 		}
 
 		log.Printf("📡 [MCP] GPTs Run状态: %s", runStatus.Status)
 
 		if runStatus.Status == "completed" {
+			// Run 完成后返回本次运行的token用量
+			usage = &Usage{Model: runStatus.Model}
+			if runStatus.Usage != nil {
+				usage.PromptTokens = runStatus.Usage.PromptTokens
+				usage.CompletionTokens = runStatus.Usage.CompletionTokens
+				usage.CachedTokens = runStatus.Usage.PromptTokensDetails.CachedTokens
+				usage.TotalTokens = runStatus.Usage.TotalTokens
+			}
 			break
 		} else if runStatus.Status == "failed" || runStatus.Status == "cancelled" || runStatus.Status == "expired" {
-			return "", fmt.Errorf("Run失败或取消: %s", runStatus.Status)
+			return "", nil, fmt.Errorf("Run失败或取消: %s", runStatus.Status)
 		}
 
 		// 等待后继续轮询
@@ -707,7 +845,7 @@ func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error)
 	messagesURL := fmt.Sprintf("%s/threads/%s/messages?order=desc&limit=10", client.BaseURL, threadID)
 	req, err = http.NewRequest("GET", messagesURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("创建消息获取请求失败: %w", err)
+		return "", nil, fmt.Errorf("创建消息获取请求失败: %w", err)
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.APIKey))
@@ -715,17 +853,17 @@ func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error)
 
 	resp, err = httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("发送消息获取请求失败: %w", err)
+		return "", nil, fmt.Errorf("发送消息获取请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err = io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取消息获取响应失败: %w", err)
+		return "", nil, fmt.Errorf("读取消息获取响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("获取消息失败 (status %d): %s\n请求URL: %s", resp.StatusCode, string(body), messagesURL)
+		return "", nil, fmt.Errorf("获取消息失败 (status %d): %s\n请求URL: %s", resp.StatusCode, string(body), messagesURL)
 	}
 
 	var messagesResult struct {
@@ -745,7 +883,7 @@ func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error)
 	}
 
 	if err := json.Unmarshal(body, &messagesResult); err != nil {
-		return "", fmt.Errorf("解析消息响应失败: %w\n响应体: %s", err, string(body))
+		return "", nil, fmt.Errorf("解析消息响应失败: %w\n响应体: %s", err, string(body))
 	}
 
 	// 找到Assistant的最新回复（第一条assistant角色的消息）
@@ -755,17 +893,23 @@ func (client *Client) callGPTs(systemPrompt, userPrompt string) (string, error)
 			for _, content := range message.Content {
 				if content.Type == "text" && content.Text.Value != "" {
 					log.Printf("📡 [MCP] GPTs 获取到响应 (Thread: %s, Message ID: %s)", threadID, message.ID)
-					return content.Text.Value, nil
+					return content.Text.Value, usage, nil
 				}
 			}
 		}
 	}
 
-	return "", fmt.Errorf("未找到Assistant的回复\n响应数据: %s", string(body))
+	return "", nil, fmt.Errorf("未找到Assistant的回复\n响应数据: %s", string(body))
 }
 
 // isRetryableError 判断错误是否可重试
 func isRetryableError(err error) bool {
+	// 带HTTP状态码和错误类型的服务商错误，按类型判断（限流/过载可重试，鉴权/参数错误不重试）
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+
 	errStr := strings.ToLower(err.Error())
 	// 网络错误、超时、EOF等可以重试
 	retryableErrors := []string{
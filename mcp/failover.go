@@ -0,0 +1,257 @@
+package mcp
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBreakerThreshold 连续失败多少次后熔断
+	DefaultBreakerThreshold = 2
+	// DefaultBreakerCooldown 熔断后多久允许再次试探
+	DefaultBreakerCooldown = 5 * time.Minute
+	// FailoverMaxRetries 备用链中每个提供商的重试次数（快速切换到下一个，而不是在同一提供商上退避30秒）
+	FailoverMaxRetries = 2
+)
+
+// ProviderReporter 可报告最近一次实际应答的AI提供商
+type ProviderReporter interface {
+	LastProvider() string
+}
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"    // 正常
+	BreakerOpen     = "open"      // 熔断中，跳过该提供商
+	BreakerHalfOpen = "half_open" // 冷却结束，允许试探一次
+)
+
+// CircuitBreaker 单个AI提供商的熔断器
+type CircuitBreaker struct {
+	Threshold int           // 连续失败次数阈值
+	Cooldown  time.Duration // 熔断冷却时间
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	lastError string
+}
+
+// NewCircuitBreaker 创建熔断器（参数<=0时使用默认值）
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = DefaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	return &CircuitBreaker{Threshold: threshold, Cooldown: cooldown}
+}
+
+// stateLocked 当前状态（调用方需持有锁）
+func (b *CircuitBreaker) stateLocked() string {
+	if b.failures < b.Threshold {
+		return BreakerClosed
+	}
+	if time.Now().Before(b.openUntil) {
+		return BreakerOpen
+	}
+	return BreakerHalfOpen
+}
+
+// State 当前状态
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked()
+}
+
+// openUntilTime 熔断结束时间
+func (b *CircuitBreaker) openUntilTime() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.openUntil
+}
+
+// Allow 是否允许调用（熔断中返回false，冷却结束后允许试探）
+func (b *CircuitBreaker) Allow() bool {
+	return b.State() != BreakerOpen
+}
+
+// RecordSuccess 调用成功，关闭熔断器
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.lastError = ""
+}
+
+// RecordFailure 调用失败，达到阈值（或试探失败）时重新进入熔断
+func (b *CircuitBreaker) RecordFailure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if err != nil {
+		b.lastError = err.Error()
+	}
+	if b.failures >= b.Threshold {
+		b.openUntil = time.Now().Add(b.Cooldown)
+	}
+}
+
+// ProviderStatus AI提供商健康状态（用于API展示）
+type ProviderStatus struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	Failures  int       `json:"failures"`
+	OpenUntil time.Time `json:"open_until,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// failoverEntry 备用链中的一个提供商
+type failoverEntry struct {
+	name    string
+	client  AIClient
+	breaker *CircuitBreaker
+}
+
+// FailoverClient 按顺序尝试多个AI提供商：失败或熔断时切换到下一个
+type FailoverClient struct {
+	threshold int
+	cooldown  time.Duration
+	entries   []*failoverEntry
+
+	mu           sync.Mutex
+	lastProvider string
+	lastUsage    *Usage
+}
+
+// NewFailoverClient 创建AI备用链（threshold/cooldown <=0 时使用默认值）
+func NewFailoverClient(threshold int, cooldown time.Duration) *FailoverClient {
+	return &FailoverClient{threshold: threshold, cooldown: cooldown}
+}
+
+// Add 追加一个提供商（按添加顺序尝试）
+// *Client 的重试次数会被限制为 FailoverMaxRetries，以便尽快切换
+func (f *FailoverClient) Add(name string, client AIClient) {
+	if c, ok := client.(*Client); ok {
+		c.MaxRetries = FailoverMaxRetries
+		if name == "" {
+			name = c.ProviderName()
+		}
+	}
+	f.entries = append(f.entries, &failoverEntry{
+		name:    name,
+		client:  client,
+		breaker: NewCircuitBreaker(f.threshold, f.cooldown),
+	})
+}
+
+// Len 提供商数量
+func (f *FailoverClient) Len() int {
+	return len(f.entries)
+}
+
+// CallWithMessages 依次调用各提供商，返回第一个成功的响应
+func (f *FailoverClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	if len(f.entries) == 0 {
+		return "", fmt.Errorf("AI备用链未配置任何提供商")
+	}
+
+	f.mu.Lock()
+	f.lastProvider = ""
+	f.lastUsage = nil
+	f.mu.Unlock()
+
+	var errs []string
+	attempted := false
+	for _, entry := range f.entries {
+		if !entry.breaker.Allow() {
+			log.Printf("⏭️  [MCP] %s 处于熔断状态，跳过", entry.name)
+			errs = append(errs, fmt.Sprintf("%s: 熔断中", entry.name))
+			continue
+		}
+		attempted = true
+		if resp, ok := f.try(entry, systemPrompt, userPrompt, &errs); ok {
+			return resp, nil
+		}
+	}
+
+	// 所有提供商都处于熔断状态：试探最早恢复的一个，避免整个周期无人应答
+	if !attempted {
+		entry := f.entries[0]
+		for _, e := range f.entries[1:] {
+			if e.breaker.openUntilTime().Before(entry.breaker.openUntilTime()) {
+				entry = e
+			}
+		}
+		log.Printf("⚠️  [MCP] 所有AI提供商均处于熔断状态，试探 %s", entry.name)
+		if resp, ok := f.try(entry, systemPrompt, userPrompt, &errs); ok {
+			return resp, nil
+		}
+	}
+
+	return "", fmt.Errorf("所有AI提供商均调用失败: %s", strings.Join(errs, "; "))
+}
+
+// try 调用单个提供商并更新熔断器
+func (f *FailoverClient) try(entry *failoverEntry, systemPrompt, userPrompt string, errs *[]string) (string, bool) {
+	resp, err := entry.client.CallWithMessages(systemPrompt, userPrompt)
+	if err != nil {
+		entry.breaker.RecordFailure(err)
+		log.Printf("❌ [MCP] %s 调用失败（熔断器: %s）: %v", entry.name, entry.breaker.State(), err)
+		*errs = append(*errs, fmt.Sprintf("%s: %v", entry.name, err))
+		return "", false
+	}
+
+	entry.breaker.RecordSuccess()
+	f.mu.Lock()
+	f.lastProvider = entry.name
+	if reporter, ok := entry.client.(UsageReporter); ok {
+		f.lastUsage = reporter.LastUsage()
+	}
+	f.mu.Unlock()
+	if entry != f.entries[0] {
+		log.Printf("🔀 [MCP] 已切换到备用AI提供商: %s", entry.name)
+	}
+	return resp, true
+}
+
+// LastProvider 最近一次成功应答的提供商名称
+func (f *FailoverClient) LastProvider() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lastProvider
+}
+
+// LastUsage 最近一次成功应答的token用量
+func (f *FailoverClient) LastUsage() *Usage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lastUsage
+}
+
+// Status 各提供商的熔断状态
+func (f *FailoverClient) Status() []ProviderStatus {
+	statuses := make([]ProviderStatus, 0, len(f.entries))
+	for _, entry := range f.entries {
+		b := entry.breaker
+		b.mu.Lock()
+		status := ProviderStatus{
+			Name:      entry.name,
+			State:     b.stateLocked(),
+			Failures:  b.failures,
+			LastError: b.lastError,
+		}
+		if status.State == BreakerOpen {
+			status.OpenUntil = b.openUntil
+		}
+		b.mu.Unlock()
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
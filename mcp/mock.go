@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"fmt"
+	"sync"
+)
+
+// AIClient AI调用接口（*Client 和 MockClient 都实现了该接口，决策引擎只依赖该接口）
+type AIClient interface {
+	CallWithMessages(systemPrompt, userPrompt string) (string, error)
+}
+
+// DefaultMockResponse MockClient 未配置响应时的默认返回（观望，不产生交易）
+const DefaultMockResponse = "模拟AI：离线模式，保持观望。\n\n```json\n[{\"symbol\": \"BTCUSDT\", \"action\": \"wait\", \"reasoning\": \"mock client\"}]\n```"
+
+// MockClient 离线模拟AI客户端（用于回测和调试，不发起网络请求）
+type MockClient struct {
+	// ResponseFunc 自定义响应函数（优先使用）
+	ResponseFunc func(systemPrompt, userPrompt string) (string, error)
+	// Responses 按顺序循环返回的响应列表
+	Responses []string
+
+	mu    sync.Mutex
+	calls int
+}
+
+// NewMockClient 创建模拟AI客户端，按顺序循环返回 responses（为空时返回 DefaultMockResponse）
+func NewMockClient(responses ...string) *MockClient {
+	return &MockClient{Responses: responses}
+}
+
+// CallWithMessages 返回预设响应
+func (m *MockClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	m.mu.Lock()
+	call := m.calls
+	m.calls++
+	m.mu.Unlock()
+
+	if m.ResponseFunc != nil {
+		resp, err := m.ResponseFunc(systemPrompt, userPrompt)
+		if err != nil {
+			return "", fmt.Errorf("模拟AI调用失败: %w", err)
+		}
+		return resp, nil
+	}
+	if len(m.Responses) == 0 {
+		return DefaultMockResponse, nil
+	}
+	return m.Responses[call%len(m.Responses)], nil
+}
+
+// Calls 返回已调用次数
+func (m *MockClient) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
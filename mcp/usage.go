@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Usage 单次AI调用的token用量和耗时
+type Usage struct {
+	Provider         string        `json:"provider"`
+	Model            string        `json:"model"`
+	PromptTokens     int           `json:"prompt_tokens"`     // 输入token（含缓存命中部分）
+	CompletionTokens int           `json:"completion_tokens"` // 输出token（含推理token）
+	CachedTokens     int           `json:"cached_tokens"`     // 缓存命中的输入token
+	TotalTokens      int           `json:"total_tokens"`
+	Latency          time.Duration `json:"latency"`  // 成功那次请求的耗时
+	Attempts         int           `json:"attempts"` // 尝试次数（含重试）
+}
+
+// UsageReporter 可报告最近一次成功调用的token用量
+type UsageReporter interface {
+	LastUsage() *Usage
+}
+
+// fillTotal 服务商未返回总数时按输入+输出计算
+func (u *Usage) fillTotal() {
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+}
+
+// LastUsage 最近一次成功调用的token用量（未成功调用过返回nil）
+func (client *Client) LastUsage() *Usage {
+	return client.lastUsage
+}
+
+// ModelPrice 模型单价（美元/百万token）
+type ModelPrice struct {
+	Input       float64 `json:"input"`        // 输入（未命中缓存）
+	Output      float64 `json:"output"`       // 输出
+	CachedInput float64 `json:"cached_input"` // 缓存命中的输入（0表示按 Input 计价）
+}
+
+// PriceTable 价格表：key 为模型名（支持前缀匹配，如 claude-sonnet-4-5 匹配 claude-sonnet-4-5-20250929）或提供商名
+type PriceTable map[string]ModelPrice
+
+// DefaultPriceTable 内置价格表（各服务商公开标价，价格变动时可在 config.json 的 ai_prices 中覆盖）
+func DefaultPriceTable() PriceTable {
+	return PriceTable{
+		"deepseek-chat":     {Input: 0.28, Output: 0.42, CachedInput: 0.028},
+		"deepseek-reasoner": {Input: 0.28, Output: 0.42, CachedInput: 0.028},
+		"qwen3-max":         {Input: 1.2, Output: 6.0, CachedInput: 0.24},
+		"gemini-1.5-flash":  {Input: 0.075, Output: 0.30, CachedInput: 0.01875},
+		"gemini-1.5-pro":    {Input: 1.25, Output: 5.0, CachedInput: 0.3125},
+		"gpt-4o-mini":       {Input: 0.15, Output: 0.60, CachedInput: 0.075},
+		"gpt-4o":            {Input: 2.5, Output: 10.0, CachedInput: 1.25},
+		"claude-sonnet-4-5": {Input: 3.0, Output: 15.0, CachedInput: 0.30},
+		"claude-haiku-4-5":  {Input: 1.0, Output: 5.0, CachedInput: 0.10},
+		"claude-opus-4-1":   {Input: 15.0, Output: 75.0, CachedInput: 1.50},
+	}
+}
+
+// ParsePriceTable 解析JSON价格表并覆盖到内置价格表上（为空时返回内置价格表）
+func ParsePriceTable(data string) (PriceTable, error) {
+	table := DefaultPriceTable()
+	if strings.TrimSpace(data) == "" {
+		return table, nil
+	}
+
+	var overrides PriceTable
+	if err := json.Unmarshal([]byte(data), &overrides); err != nil {
+		return table, fmt.Errorf("解析AI价格表失败: %w", err)
+	}
+	for model, price := range overrides {
+		table[strings.ToLower(model)] = price
+	}
+	return table, nil
+}
+
+// Lookup 查找模型单价：精确匹配 → 最长前缀匹配 → 提供商
+func (t PriceTable) Lookup(provider, model string) (ModelPrice, bool) {
+	model = strings.ToLower(model)
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	for _, key := range keys {
+		if strings.HasPrefix(model, strings.ToLower(key)) {
+			return t[key], true
+		}
+	}
+
+	price, ok := t[strings.ToLower(provider)]
+	return price, ok
+}
+
+// Cost 计算调用费用（美元），未配置价格的模型返回0
+func (t PriceTable) Cost(usage *Usage) float64 {
+	if usage == nil {
+		return 0
+	}
+	price, ok := t.Lookup(usage.Provider, usage.Model)
+	if !ok {
+		return 0
+	}
+
+	cachedPrice := price.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = price.Input
+	}
+	uncached := usage.PromptTokens - usage.CachedTokens
+	if uncached < 0 {
+		uncached = 0
+	}
+	return (float64(uncached)*price.Input + float64(usage.CachedTokens)*cachedPrice + float64(usage.CompletionTokens)*price.Output) / 1e6
+}
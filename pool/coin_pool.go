@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -24,19 +25,61 @@ var defaultMainstreamCoins = []string{
 	"HYPEUSDT",
 }
 
-// CoinPoolConfig 币种池配置
-type CoinPoolConfig struct {
-	APIURL          string
-	Timeout         time.Duration
-	CacheDir        string
-	UseDefaultCoins bool // 是否使用默认主流币种
+// DefaultCacheTTL 信号源内存缓存默认有效期
+const DefaultCacheTTL = time.Minute
+
+// SignalSourceConfig 信号源配置
+type SignalSourceConfig struct {
+	CoinPoolAPIURL  string        // AI500币种池API
+	OITopAPIURL     string        // OI Top API
+	CacheDir        string        // 文件缓存目录（API失败时使用历史数据）
+	CacheTTL        time.Duration // 内存缓存有效期（有效期内重复获取不再请求API）
+	Timeout         time.Duration // 请求超时
+	UseDefaultCoins bool          // 是否使用默认主流币种
+	DefaultCoins    []string      // 默认主流币种（为空时使用内置列表）
+}
+
+// SignalSource 候选币种信号源（AI500币种池 + OI Top），每个交易员持有独立实例
+type SignalSource struct {
+	config SignalSourceConfig
+
+	mu            sync.Mutex
+	coins         []CoinInfo
+	coinsAt       time.Time
+	oiPositions   []OIPosition
+	oiPositionsAt time.Time
 }
 
-var coinPoolConfig = CoinPoolConfig{
-	APIURL:          "",
-	Timeout:         30 * time.Second, // 增加到30秒
-	CacheDir:        "coin_pool_cache",
-	UseDefaultCoins: false, // 默认不使用
+// NewSignalSource 创建信号源（未设置的参数使用默认值）
+func NewSignalSource(config SignalSourceConfig) *SignalSource {
+	if config.CacheDir == "" {
+		config.CacheDir = "coin_pool_cache"
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = DefaultCacheTTL
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second // 增加到30秒
+	}
+	config.DefaultCoins = append([]string(nil), config.DefaultCoins...)
+	return &SignalSource{config: config}
+}
+
+// Config 信号源配置副本
+func (s *SignalSource) Config() SignalSourceConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	config := s.config
+	config.DefaultCoins = append([]string(nil), s.config.DefaultCoins...)
+	return config
+}
+
+// defaultSource 全局默认信号源（由系统配置初始化，供未注入独立信号源的调用方使用）
+var defaultSource = NewSignalSource(SignalSourceConfig{})
+
+// DefaultSignalSourceConfig 全局默认信号源配置，用于派生交易员的独立信号源
+func DefaultSignalSourceConfig() SignalSourceConfig {
+	return defaultSource.Config()
 }
 
 // CoinPoolCache 币种池缓存
@@ -68,41 +111,72 @@ type CoinPoolAPIResponse struct {
 	} `json:"data"`
 }
 
-// SetCoinPoolAPI 设置币种池API
+// SetCoinPoolAPI 设置默认信号源的币种池API
 func SetCoinPoolAPI(apiURL string) {
-	coinPoolConfig.APIURL = apiURL
+	defaultSource.mu.Lock()
+	defer defaultSource.mu.Unlock()
+	defaultSource.config.CoinPoolAPIURL = apiURL
+	defaultSource.coinsAt = time.Time{}
 }
 
-// SetOITopAPI 设置OI Top API
+// SetOITopAPI 设置默认信号源的OI Top API
 func SetOITopAPI(apiURL string) {
-	oiTopConfig.APIURL = apiURL
+	defaultSource.mu.Lock()
+	defer defaultSource.mu.Unlock()
+	defaultSource.config.OITopAPIURL = apiURL
+	defaultSource.oiPositionsAt = time.Time{}
 }
 
-// SetUseDefaultCoins 设置是否使用默认主流币种
+// SetUseDefaultCoins 设置默认信号源是否使用默认主流币种
 func SetUseDefaultCoins(useDefault bool) {
-	coinPoolConfig.UseDefaultCoins = useDefault
+	defaultSource.mu.Lock()
+	defer defaultSource.mu.Unlock()
+	defaultSource.config.UseDefaultCoins = useDefault
 }
 
 // SetDefaultCoins 设置默认主流币种列表
 func SetDefaultCoins(coins []string) {
 	if len(coins) > 0 {
-		defaultMainstreamCoins = coins
+		defaultSource.mu.Lock()
+		defaultSource.config.DefaultCoins = append([]string(nil), coins...)
+		defaultSource.mu.Unlock()
 		log.Printf("✓ 已设置默认币种池（共%d个币种）: %v", len(coins), coins)
 	}
 }
 
-// GetCoinPool 获取币种池列表（带重试和缓存机制）
+// GetCoinPool 使用默认信号源获取币种池列表
 func GetCoinPool() ([]CoinInfo, error) {
+	return defaultSource.GetCoinPool()
+}
+
+// defaultCoinList 默认主流币种列表
+func (s *SignalSource) defaultCoinList() []CoinInfo {
+	if len(s.config.DefaultCoins) > 0 {
+		return convertSymbolsToCoins(s.config.DefaultCoins)
+	}
+	return convertSymbolsToCoins(defaultMainstreamCoins)
+}
+
+// GetCoinPool 获取币种池列表（带重试和缓存机制）
+func (s *SignalSource) GetCoinPool() ([]CoinInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// 优先检查是否启用默认币种列表
-	if coinPoolConfig.UseDefaultCoins {
+	if s.config.UseDefaultCoins {
 		log.Printf("✓ 已启用默认主流币种列表")
-		return convertSymbolsToCoins(defaultMainstreamCoins), nil
+		return s.defaultCoinList(), nil
 	}
 
 	// 检查API URL是否配置
-	if strings.TrimSpace(coinPoolConfig.APIURL) == "" {
+	if strings.TrimSpace(s.config.CoinPoolAPIURL) == "" {
 		log.Printf("⚠️  未配置币种池API URL，使用默认主流币种列表")
-		return convertSymbolsToCoins(defaultMainstreamCoins), nil
+		return s.defaultCoinList(), nil
+	}
+
+	// 内存缓存未过期时直接返回
+	if s.coins != nil && time.Since(s.coinsAt) < s.config.CacheTTL {
+		return append([]CoinInfo(nil), s.coins...), nil
 	}
 
 	maxRetries := 3
@@ -115,16 +189,18 @@ func GetCoinPool() ([]CoinInfo, error) {
 			time.Sleep(2 * time.Second) // 重试前等待2秒
 		}
 
-		coins, err := fetchCoinPool()
+		coins, err := s.fetchCoinPool()
 		if err == nil {
 			if attempt > 1 {
 				log.Printf("✓ 第%d次重试成功", attempt)
 			}
 			// 成功获取后保存到缓存
-			if err := saveCoinPoolCache(coins); err != nil {
+			if err := s.saveCoinPoolCache(coins); err != nil {
 				log.Printf("⚠️  保存币种池缓存失败: %v", err)
 			}
-			return coins, nil
+			s.coins = coins
+			s.coinsAt = time.Now()
+			return append([]CoinInfo(nil), coins...), nil
 		}
 
 		lastErr = err
@@ -133,7 +209,7 @@ func GetCoinPool() ([]CoinInfo, error) {
 
 	// API获取失败，尝试使用缓存
 	log.Printf("⚠️  API请求全部失败，尝试使用历史缓存数据...")
-	cachedCoins, err := loadCoinPoolCache()
+	cachedCoins, err := s.loadCoinPoolCache()
 	if err == nil {
 		log.Printf("✓ 使用历史缓存数据（共%d个币种）", len(cachedCoins))
 		return cachedCoins, nil
@@ -141,18 +217,18 @@ func GetCoinPool() ([]CoinInfo, error) {
 
 	// 缓存也失败，使用默认主流币种
 	log.Printf("⚠️  无法加载缓存数据（最后错误: %v），使用默认主流币种列表", lastErr)
-	return convertSymbolsToCoins(defaultMainstreamCoins), nil
+	return s.defaultCoinList(), nil
 }
 
 // fetchCoinPool 实际执行币种池请求
-func fetchCoinPool() ([]CoinInfo, error) {
+func (s *SignalSource) fetchCoinPool() ([]CoinInfo, error) {
 	log.Printf("🔄 正在请求AI500币种池...")
 
 	client := &http.Client{
-		Timeout: coinPoolConfig.Timeout,
+		Timeout: s.config.Timeout,
 	}
 
-	resp, err := client.Get(coinPoolConfig.APIURL)
+	resp, err := client.Get(s.config.CoinPoolAPIURL)
 	if err != nil {
 		return nil, fmt.Errorf("请求币种池API失败: %w", err)
 	}
@@ -192,9 +268,9 @@ func fetchCoinPool() ([]CoinInfo, error) {
 }
 
 // saveCoinPoolCache 保存币种池到缓存文件
-func saveCoinPoolCache(coins []CoinInfo) error {
+func (s *SignalSource) saveCoinPoolCache(coins []CoinInfo) error {
 	// 确保缓存目录存在
-	if err := os.MkdirAll(coinPoolConfig.CacheDir, 0755); err != nil {
+	if err := os.MkdirAll(s.config.CacheDir, 0755); err != nil {
 		return fmt.Errorf("创建缓存目录失败: %w", err)
 	}
 
@@ -209,7 +285,7 @@ func saveCoinPoolCache(coins []CoinInfo) error {
 		return fmt.Errorf("序列化缓存数据失败: %w", err)
 	}
 
-	cachePath := filepath.Join(coinPoolConfig.CacheDir, "latest.json")
+	cachePath := filepath.Join(s.config.CacheDir, "latest.json")
 	if err := ioutil.WriteFile(cachePath, data, 0644); err != nil {
 		return fmt.Errorf("写入缓存文件失败: %w", err)
 	}
@@ -219,8 +295,8 @@ func saveCoinPoolCache(coins []CoinInfo) error {
 }
 
 // loadCoinPoolCache 从缓存文件加载币种池
-func loadCoinPoolCache() ([]CoinInfo, error) {
-	cachePath := filepath.Join(coinPoolConfig.CacheDir, "latest.json")
+func (s *SignalSource) loadCoinPoolCache() ([]CoinInfo, error) {
+	cachePath := filepath.Join(s.config.CacheDir, "latest.json")
 
 	// 检查文件是否存在
 	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
@@ -250,9 +326,14 @@ func loadCoinPoolCache() ([]CoinInfo, error) {
 	return cache.Coins, nil
 }
 
-// GetAvailableCoins 获取可用的币种列表（过滤不可用的）
+// GetAvailableCoins 使用默认信号源获取可用的币种列表
 func GetAvailableCoins() ([]string, error) {
-	coins, err := GetCoinPool()
+	return defaultSource.GetAvailableCoins()
+}
+
+// GetAvailableCoins 获取可用的币种列表（过滤不可用的）
+func (s *SignalSource) GetAvailableCoins() ([]string, error) {
+	coins, err := s.GetCoinPool()
 	if err != nil {
 		return nil, err
 	}
@@ -273,9 +354,14 @@ func GetAvailableCoins() ([]string, error) {
 	return symbols, nil
 }
 
-// GetTopRatedCoins 获取评分最高的N个币种（按评分从大到小排序）
+// GetTopRatedCoins 使用默认信号源获取评分最高的N个币种
 func GetTopRatedCoins(limit int) ([]string, error) {
-	coins, err := GetCoinPool()
+	return defaultSource.GetTopRatedCoins(limit)
+}
+
+// GetTopRatedCoins 获取评分最高的N个币种（按评分从大到小排序）
+func (s *SignalSource) GetTopRatedCoins(limit int) ([]string, error) {
+	coins, err := s.GetCoinPool()
 	if err != nil {
 		return nil, err
 	}
@@ -408,24 +494,27 @@ type OITopCache struct {
 	SourceType string       `json:"source_type"`
 }
 
-var oiTopConfig = struct {
-	APIURL   string
-	Timeout  time.Duration
-	CacheDir string
-}{
-	APIURL:   "",
-	Timeout:  30 * time.Second,
-	CacheDir: "coin_pool_cache",
+// GetOITopPositions 使用默认信号源获取持仓量增长Top20数据
+func GetOITopPositions() ([]OIPosition, error) {
+	return defaultSource.GetOITopPositions()
 }
 
 // GetOITopPositions 获取持仓量增长Top20数据（带重试和缓存）
-func GetOITopPositions() ([]OIPosition, error) {
+func (s *SignalSource) GetOITopPositions() ([]OIPosition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// 检查API URL是否配置
-	if strings.TrimSpace(oiTopConfig.APIURL) == "" {
+	if strings.TrimSpace(s.config.OITopAPIURL) == "" {
 		log.Printf("⚠️  未配置OI Top API URL，跳过OI Top数据获取")
 		return []OIPosition{}, nil // 返回空列表，不是错误
 	}
 
+	// 内存缓存未过期时直接返回
+	if s.oiPositions != nil && time.Since(s.oiPositionsAt) < s.config.CacheTTL {
+		return append([]OIPosition(nil), s.oiPositions...), nil
+	}
+
 	maxRetries := 3
 	var lastErr error
 
@@ -436,16 +525,18 @@ func GetOITopPositions() ([]OIPosition, error) {
 			time.Sleep(2 * time.Second)
 		}
 
-		positions, err := fetchOITop()
+		positions, err := s.fetchOITop()
 		if err == nil {
 			if attempt > 1 {
 				log.Printf("✓ 第%d次重试成功", attempt)
 			}
 			// 成功获取后保存到缓存
-			if err := saveOITopCache(positions); err != nil {
+			if err := s.saveOITopCache(positions); err != nil {
 				log.Printf("⚠️  保存OI Top缓存失败: %v", err)
 			}
-			return positions, nil
+			s.oiPositions = positions
+			s.oiPositionsAt = time.Now()
+			return append([]OIPosition(nil), positions...), nil
 		}
 
 		lastErr = err
@@ -454,7 +545,7 @@ func GetOITopPositions() ([]OIPosition, error) {
 
 	// API获取失败，尝试使用缓存
 	log.Printf("⚠️  OI Top API请求全部失败，尝试使用历史缓存数据...")
-	cachedPositions, err := loadOITopCache()
+	cachedPositions, err := s.loadOITopCache()
 	if err == nil {
 		log.Printf("✓ 使用历史OI Top缓存数据（共%d个币种）", len(cachedPositions))
 		return cachedPositions, nil
@@ -466,14 +557,14 @@ func GetOITopPositions() ([]OIPosition, error) {
 }
 
 // fetchOITop 实际执行OI Top请求
-func fetchOITop() ([]OIPosition, error) {
+func (s *SignalSource) fetchOITop() ([]OIPosition, error) {
 	log.Printf("🔄 正在请求OI Top数据...")
 
 	client := &http.Client{
-		Timeout: oiTopConfig.Timeout,
+		Timeout: s.config.Timeout,
 	}
 
-	resp, err := client.Get(oiTopConfig.APIURL)
+	resp, err := client.Get(s.config.OITopAPIURL)
 	if err != nil {
 		return nil, fmt.Errorf("请求OI Top API失败: %w", err)
 	}
@@ -508,8 +599,8 @@ func fetchOITop() ([]OIPosition, error) {
 }
 
 // saveOITopCache 保存OI Top数据到缓存
-func saveOITopCache(positions []OIPosition) error {
-	if err := os.MkdirAll(oiTopConfig.CacheDir, 0755); err != nil {
+func (s *SignalSource) saveOITopCache(positions []OIPosition) error {
+	if err := os.MkdirAll(s.config.CacheDir, 0755); err != nil {
 		return fmt.Errorf("创建缓存目录失败: %w", err)
 	}
 
@@ -524,7 +615,7 @@ func saveOITopCache(positions []OIPosition) error {
 		return fmt.Errorf("序列化OI Top缓存数据失败: %w", err)
 	}
 
-	cachePath := filepath.Join(oiTopConfig.CacheDir, "oi_top_latest.json")
+	cachePath := filepath.Join(s.config.CacheDir, "oi_top_latest.json")
 	if err := ioutil.WriteFile(cachePath, data, 0644); err != nil {
 		return fmt.Errorf("写入OI Top缓存文件失败: %w", err)
 	}
@@ -534,8 +625,8 @@ func saveOITopCache(positions []OIPosition) error {
 }
 
 // loadOITopCache 从缓存加载OI Top数据
-func loadOITopCache() ([]OIPosition, error) {
-	cachePath := filepath.Join(oiTopConfig.CacheDir, "oi_top_latest.json")
+func (s *SignalSource) loadOITopCache() ([]OIPosition, error) {
+	cachePath := filepath.Join(s.config.CacheDir, "oi_top_latest.json")
 
 	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("OI Top缓存文件不存在")
@@ -563,9 +654,14 @@ func loadOITopCache() ([]OIPosition, error) {
 	return cache.Positions, nil
 }
 
-// GetOITopSymbols 获取OI Top的币种符号列表
+// GetOITopSymbols 使用默认信号源获取OI Top的币种符号列表
 func GetOITopSymbols() ([]string, error) {
-	positions, err := GetOITopPositions()
+	return defaultSource.GetOITopSymbols()
+}
+
+// GetOITopSymbols 获取OI Top的币种符号列表
+func (s *SignalSource) GetOITopSymbols() ([]string, error) {
+	positions, err := s.GetOITopPositions()
 	if err != nil {
 		return nil, err
 	}
@@ -583,63 +679,59 @@ func GetOITopSymbols() ([]string, error) {
 type MergedCoinPool struct {
 	AI500Coins    []CoinInfo          // AI500评分币种
 	OITopCoins    []OIPosition        // 持仓量增长Top20
-	AllSymbols    []string            // 所有不重复的币种符号
+	AllSymbols    []string            // 所有不重复的币种符号（按综合评分降序）
 	SymbolSources map[string][]string // 每个币种的来源（"ai500"/"oi_top"）
+	Candidates    []MergedCandidate   // 合并后的候选币种（含各来源评分）
 }
 
-// GetMergedCoinPool 获取合并后的币种池（AI500 + OI Top，去重）
+// GetMergedCoinPool 使用默认信号源获取合并后的币种池
 func GetMergedCoinPool(ai500Limit int) (*MergedCoinPool, error) {
-	// 1. 获取AI500数据
-	ai500TopSymbols, err := GetTopRatedCoins(ai500Limit)
-	if err != nil {
-		log.Printf("⚠️  获取AI500数据失败: %v", err)
-		ai500TopSymbols = []string{} // 失败时用空列表
-	}
+	return defaultSource.GetMergedCoinPool(ai500Limit)
+}
 
-	// 2. 获取OI Top数据
-	oiTopSymbols, err := GetOITopSymbols()
-	if err != nil {
-		log.Printf("⚠️  获取OI Top数据失败: %v", err)
-		oiTopSymbols = []string{} // 失败时用空列表
+// DefaultProviders 默认信号源组合：AI500前N个 + OI Top，权重相同
+func (s *SignalSource) DefaultProviders(ai500Limit int) []WeightedProvider {
+	return []WeightedProvider{
+		{Provider: &AI500Provider{Source: s}, Weight: 1, Limit: ai500Limit},
+		{Provider: &OITopProvider{Source: s}, Weight: 1},
 	}
+}
 
-	// 3. 合并并去重
-	symbolSet := make(map[string]bool)
-	symbolSources := make(map[string][]string)
-
-	// 添加AI500币种
-	for _, symbol := range ai500TopSymbols {
-		symbolSet[symbol] = true
-		symbolSources[symbol] = append(symbolSources[symbol], "ai500")
+// GetMergedCoinPool 获取合并后的币种池（AI500 + OI Top，去重）
+func (s *SignalSource) GetMergedCoinPool(ai500Limit int) (*MergedCoinPool, error) {
+	candidates, err := MergeCandidates(s.DefaultProviders(ai500Limit))
+	if err != nil {
+		log.Printf("⚠️  获取币种池数据失败: %v", err)
 	}
 
-	// 添加OI Top币种
-	for _, symbol := range oiTopSymbols {
-		if !symbolSet[symbol] {
-			symbolSet[symbol] = true
+	allSymbols := make([]string, 0, len(candidates))
+	symbolSources := make(map[string][]string, len(candidates))
+	ai500Count, oiTopCount := 0, 0
+	for _, c := range candidates {
+		allSymbols = append(allSymbols, c.Symbol)
+		symbolSources[c.Symbol] = c.Sources
+		if _, ok := c.SourceScores[ProviderAI500]; ok {
+			ai500Count++
+		}
+		if _, ok := c.SourceScores[ProviderOITop]; ok {
+			oiTopCount++
 		}
-		symbolSources[symbol] = append(symbolSources[symbol], "oi_top")
-	}
-
-	// 转换为数组
-	var allSymbols []string
-	for symbol := range symbolSet {
-		allSymbols = append(allSymbols, symbol)
 	}
 
-	// 获取完整数据
-	ai500Coins, _ := GetCoinPool()
-	oiTopPositions, _ := GetOITopPositions()
+	// 获取完整数据（命中内存缓存，不会重复请求）
+	ai500Coins, _ := s.GetCoinPool()
+	oiTopPositions, _ := s.GetOITopPositions()
 
 	merged := &MergedCoinPool{
 		AI500Coins:    ai500Coins,
 		OITopCoins:    oiTopPositions,
 		AllSymbols:    allSymbols,
 		SymbolSources: symbolSources,
+		Candidates:    candidates,
 	}
 
 	log.Printf("📊 币种池合并完成: AI500=%d, OI_Top=%d, 总计(去重)=%d",
-		len(ai500TopSymbols), len(oiTopSymbols), len(allSymbols))
+		ai500Count, oiTopCount, len(allSymbols))
 
 	return merged, nil
 }
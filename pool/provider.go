@@ -0,0 +1,588 @@
+package pool
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 内置信号源类型
+const (
+	ProviderAI500  = "ai500"  // AI500评分币种池
+	ProviderOITop  = "oi_top" // 持仓量增长Top
+	ProviderStatic = "static" // 固定币种列表
+	ProviderFile   = "file"   // 本地CSV/JSON文件（修改后自动重新加载）
+	ProviderHTTP   = "http"   // 通用HTTP JSON接口（字段映射可配置）
+
+	ProviderScreener = "screener" // 本地筛选器（基于交易所全市场行情计算评分）
+)
+
+// Candidate 信号源输出的单个候选币种
+type Candidate struct {
+	Symbol string  `json:"symbol"`
+	Score  float64 `json:"score"` // 信号源原始评分（无评分时为0）
+}
+
+// CandidateProvider 候选币种信号源，按优先级从高到低返回候选币种
+type CandidateProvider interface {
+	Name() string
+	Candidates() ([]Candidate, error)
+}
+
+// WeightedProvider 带权重和数量限制的信号源
+type WeightedProvider struct {
+	Provider CandidateProvider
+	Weight   float64 // 合并时的权重
+	Limit    int     // 最多取前N个（0表示不限制）
+}
+
+// MergedCandidate 合并后的候选币种
+type MergedCandidate struct {
+	Symbol       string             `json:"symbol"`
+	Score        float64            `json:"score"`         // 综合评分（各信号源排名归一化后按权重累加）
+	Sources      []string           `json:"sources"`       // 来源信号源名称（按配置顺序）
+	SourceScores map[string]float64 `json:"source_scores"` // 各信号源的原始评分
+}
+
+// MergeCandidates 合并多个信号源的候选币种
+// 每个信号源内按排名归一化到 (0,1]（第1名为1），乘以权重后累加，按综合评分降序排列
+// 单个信号源失败只记录日志；全部失败时返回错误
+func MergeCandidates(providers []WeightedProvider) ([]MergedCandidate, error) {
+	merged := make(map[string]*MergedCandidate)
+	var order []string
+	succeeded := 0
+	var lastErr error
+
+	for _, wp := range providers {
+		name := wp.Provider.Name()
+		candidates, err := wp.Provider.Candidates()
+		if err != nil {
+			log.Printf("⚠️  信号源 %s 获取失败: %v", name, err)
+			lastErr = err
+			continue
+		}
+		succeeded++
+
+		candidates = dedupCandidates(candidates)
+		if wp.Limit > 0 && len(candidates) > wp.Limit {
+			candidates = candidates[:wp.Limit]
+		}
+
+		n := float64(len(candidates))
+		for i, c := range candidates {
+			m, ok := merged[c.Symbol]
+			if !ok {
+				m = &MergedCandidate{Symbol: c.Symbol, SourceScores: make(map[string]float64)}
+				merged[c.Symbol] = m
+				order = append(order, c.Symbol)
+			}
+			m.Score += wp.Weight * (n - float64(i)) / n
+			m.Sources = append(m.Sources, name)
+			m.SourceScores[name] = c.Score
+		}
+		log.Printf("📡 信号源 %s: %d个候选币种（权重 %.2f）", name, len(candidates), wp.Weight)
+	}
+
+	if succeeded == 0 && len(providers) > 0 {
+		return nil, fmt.Errorf("所有信号源均获取失败: %w", lastErr)
+	}
+
+	result := make([]MergedCandidate, 0, len(order))
+	for _, symbol := range order {
+		result = append(result, *merged[symbol])
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Score > result[j].Score })
+	return result, nil
+}
+
+// dedupCandidates 标准化币种符号并去重（保留首次出现）
+func dedupCandidates(candidates []Candidate) []Candidate {
+	seen := make(map[string]bool, len(candidates))
+	result := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		c.Symbol = normalizeSymbol(c.Symbol)
+		if c.Symbol == "USDT" || seen[c.Symbol] {
+			continue
+		}
+		seen[c.Symbol] = true
+		result = append(result, c)
+	}
+	return result
+}
+
+// ========== 内置信号源 ==========
+
+// AI500Provider AI500评分币种池（按评分降序）
+type AI500Provider struct {
+	Source *SignalSource
+}
+
+func (p *AI500Provider) Name() string { return ProviderAI500 }
+
+func (p *AI500Provider) Candidates() ([]Candidate, error) {
+	coins, err := p.Source.GetCoinPool()
+	if err != nil {
+		return nil, err
+	}
+	var candidates []Candidate
+	for _, coin := range coins {
+		if coin.IsAvailable {
+			candidates = append(candidates, Candidate{Symbol: coin.Pair, Score: coin.Score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	return candidates, nil
+}
+
+// OITopProvider 持仓量增长Top（按排名，评分为持仓量变化百分比）
+type OITopProvider struct {
+	Source *SignalSource
+}
+
+func (p *OITopProvider) Name() string { return ProviderOITop }
+
+func (p *OITopProvider) Candidates() ([]Candidate, error) {
+	positions, err := p.Source.GetOITopPositions()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(positions, func(i, j int) bool { return positions[i].Rank < positions[j].Rank })
+	candidates := make([]Candidate, 0, len(positions))
+	for _, pos := range positions {
+		candidates = append(candidates, Candidate{Symbol: pos.Symbol, Score: pos.OIDeltaPercent})
+	}
+	return candidates, nil
+}
+
+// StaticProvider 固定币种列表（按配置顺序）
+type StaticProvider struct {
+	ProviderName string
+	Symbols      []string
+}
+
+func (p *StaticProvider) Name() string { return p.ProviderName }
+
+func (p *StaticProvider) Candidates() ([]Candidate, error) {
+	candidates := make([]Candidate, 0, len(p.Symbols))
+	for _, symbol := range p.Symbols {
+		candidates = append(candidates, Candidate{Symbol: symbol})
+	}
+	return candidates, nil
+}
+
+// ScreenerProvider 本地筛选器信号源（无需第三方币种池API）
+type ScreenerProvider struct {
+	ProviderName string
+	Screener     *market.Screener
+}
+
+func (p *ScreenerProvider) Name() string { return p.ProviderName }
+
+func (p *ScreenerProvider) Candidates() ([]Candidate, error) {
+	coins, err := p.Screener.Screen()
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]Candidate, 0, len(coins))
+	for _, coin := range coins {
+		candidates = append(candidates, Candidate{Symbol: coin.Symbol, Score: coin.Score})
+	}
+	return candidates, nil
+}
+
+// FieldMapping JSON字段映射（JSONPath风格：data.items、result[0].list、$.coins）
+type FieldMapping struct {
+	ListPath    string // 候选列表所在路径（为空表示根节点即为列表）
+	SymbolField string // 列表元素中的币种字段（默认 symbol；元素为字符串时忽略）
+	ScoreField  string // 列表元素中的评分字段（为空表示无评分）
+}
+
+// Extract 按映射从JSON数据中提取候选币种（保持原顺序）
+func (m FieldMapping) Extract(data interface{}) ([]Candidate, error) {
+	node, ok := lookupPath(data, m.ListPath)
+	if !ok {
+		return nil, fmt.Errorf("找不到列表字段: %s", m.ListPath)
+	}
+	items, ok := node.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("字段 %s 不是数组", m.ListPath)
+	}
+
+	symbolField := m.SymbolField
+	if symbolField == "" {
+		symbolField = "symbol"
+	}
+
+	candidates := make([]Candidate, 0, len(items))
+	for _, item := range items {
+		var c Candidate
+		if s, ok := item.(string); ok {
+			c.Symbol = s
+		} else {
+			v, ok := lookupPath(item, symbolField)
+			if !ok {
+				continue
+			}
+			s, ok := v.(string)
+			if !ok || strings.TrimSpace(s) == "" {
+				continue
+			}
+			c.Symbol = s
+			if m.ScoreField != "" {
+				if v, ok := lookupPath(item, m.ScoreField); ok {
+					c.Score = toFloat(v)
+				}
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, nil
+}
+
+// lookupPath 按路径取值，支持 a.b.c、a[0].b 和 $ 前缀
+func lookupPath(data interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return data, true
+	}
+
+	current := data
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		var indexes []int
+		if i := strings.Index(part, "["); i >= 0 {
+			key = part[:i]
+			for _, idx := range strings.Split(strings.TrimSuffix(part[i+1:], "]"), "][") {
+				n, err := strconv.Atoi(idx)
+				if err != nil {
+					return nil, false
+				}
+				indexes = append(indexes, n)
+			}
+		}
+
+		if key != "" {
+			obj, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = obj[key]; !ok {
+				return nil, false
+			}
+		}
+		for _, idx := range indexes {
+			arr, ok := current.([]interface{})
+			if !ok || idx < 0 || idx >= len(arr) {
+				return nil, false
+			}
+			current = arr[idx]
+		}
+	}
+	return current, true
+}
+
+// toFloat 将JSON数值或数字字符串转换为float64
+func toFloat(v interface{}) float64 {
+	switch val := v.(type) {
+	case float64:
+		return val
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return f
+	}
+	return 0
+}
+
+// FileProvider 本地CSV/JSON文件信号源，文件修改后自动重新加载
+// CSV：每行 symbol[,score]，首行为表头时自动跳过
+// JSON：按 Mapping 提取（默认根节点为字符串数组或 {symbol,score} 对象数组）
+type FileProvider struct {
+	ProviderName string
+	Path         string
+	Mapping      FieldMapping
+
+	mu         sync.Mutex
+	modTime    time.Time
+	size       int64
+	candidates []Candidate
+}
+
+func (p *FileProvider) Name() string { return p.ProviderName }
+
+func (p *FileProvider) Candidates() ([]Candidate, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.Path)
+	if err != nil {
+		if p.candidates != nil {
+			log.Printf("⚠️  信号源文件 %s 不可用，使用上次加载的数据: %v", p.Path, err)
+			return p.candidates, nil
+		}
+		return nil, fmt.Errorf("读取信号源文件失败: %w", err)
+	}
+	if p.candidates != nil && info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return p.candidates, nil
+	}
+
+	candidates, err := p.load()
+	if err != nil {
+		if p.candidates != nil {
+			log.Printf("⚠️  信号源文件 %s 解析失败，使用上次加载的数据: %v", p.Path, err)
+			return p.candidates, nil
+		}
+		return nil, err
+	}
+	if p.candidates != nil {
+		log.Printf("🔄 信号源文件 %s 已更新，重新加载%d个币种", p.Path, len(candidates))
+	}
+	p.candidates = candidates
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	return candidates, nil
+}
+
+// load 读取并解析文件（每次读取前重新校验路径，防止文件被替换为指向目录外的符号链接）
+func (p *FileProvider) load() ([]Candidate, error) {
+	if _, err := resolveProviderFile(p.Path); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(p.Path)
+	if err != nil {
+		return nil, fmt.Errorf("读取信号源文件失败: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(p.Path), ".csv") {
+		return parseCSVCandidates(string(data))
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("解析信号源文件失败: %w", err)
+	}
+	mapping := p.Mapping
+	if mapping.ScoreField == "" && mapping.SymbolField == "" {
+		mapping.ScoreField = "score"
+	}
+	return mapping.Extract(parsed)
+}
+
+// parseCSVCandidates 解析 symbol[,score] 格式的CSV
+func parseCSVCandidates(data string) ([]Candidate, error) {
+	reader := csv.NewReader(strings.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析CSV失败: %w", err)
+	}
+
+	var candidates []Candidate
+	for i, row := range rows {
+		if len(row) == 0 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		if i == 0 && strings.EqualFold(strings.TrimSpace(row[0]), "symbol") {
+			continue // 表头
+		}
+		c := Candidate{Symbol: row[0]}
+		if len(row) > 1 {
+			c.Score, _ = strconv.ParseFloat(strings.TrimSpace(row[1]), 64)
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, nil
+}
+
+// HTTPProvider 通用HTTP JSON信号源（GET请求，按 Mapping 提取候选币种，只能访问白名单内的公网地址）
+type HTTPProvider struct {
+	ProviderName string
+	URL          string
+	Headers      map[string]string
+	Mapping      FieldMapping
+	Timeout      time.Duration
+}
+
+func (p *HTTPProvider) Name() string { return p.ProviderName }
+
+func (p *HTTPProvider) Candidates() ([]Candidate, error) {
+	req, err := http.NewRequest("GET", p.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	for key, value := range p.Headers {
+		req.Header.Set(key, value)
+	}
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	resp, err := sandboxedHTTPClient(timeout).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求信号源失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %w", err)
+	}
+	return p.Mapping.Extract(parsed)
+}
+
+// ========== 配置 ==========
+
+// ProviderConfig 交易员的信号源配置（JSON数组存储在交易员配置中）
+type ProviderConfig struct {
+	Type   string  `json:"type"`             // ai500 / oi_top / static / file / http / screener
+	Name   string  `json:"name,omitempty"`   // 来源标签（默认与type相同，需唯一）
+	Weight float64 `json:"weight,omitempty"` // 合并权重（默认1）
+	Limit  int     `json:"limit,omitempty"`  // 最多取前N个（0表示不限制）
+
+	Symbols []string          `json:"symbols,omitempty"` // static：币种列表
+	Path    string            `json:"path,omitempty"`    // file：文件路径（相对于管理员配置的信号源目录）
+	URL     string            `json:"url,omitempty"`     // http：接口地址（主机需在管理员白名单内）
+	Headers map[string]string `json:"headers,omitempty"` // http：请求头
+
+	ListPath    string `json:"list_path,omitempty"`    // file/http：候选列表路径（如 data.coins）
+	SymbolField string `json:"symbol_field,omitempty"` // file/http：币种字段（默认 symbol）
+	ScoreField  string `json:"score_field,omitempty"`  // file/http：评分字段
+
+	MinVolume      float64  `json:"min_volume,omitempty"`       // screener：最小24h成交额（USDT）
+	MinListingDays int      `json:"min_listing_days,omitempty"` // screener：最小上线天数（-1表示不限制）
+	Exclude        []string `json:"exclude,omitempty"`          // screener：排除的币种
+}
+
+// ParseProviderConfigs 解析并校验信号源配置（为空返回nil）
+// file/http 类型必须在管理员配置的目录和主机白名单内（见 SetProviderSandbox）
+func ParseProviderConfigs(data string) ([]ProviderConfig, error) {
+	if strings.TrimSpace(data) == "" {
+		return nil, nil
+	}
+
+	var configs []ProviderConfig
+	if err := json.Unmarshal([]byte(data), &configs); err != nil {
+		return nil, fmt.Errorf("解析信号源配置失败: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i := range configs {
+		cfg := &configs[i]
+		cfg.Type = strings.ToLower(strings.TrimSpace(cfg.Type))
+		if cfg.Name == "" {
+			cfg.Name = cfg.Type
+		}
+		if cfg.Weight == 0 {
+			cfg.Weight = 1
+		}
+
+		switch cfg.Type {
+		case ProviderAI500, ProviderOITop, ProviderScreener:
+		case ProviderStatic:
+			if len(cfg.Symbols) == 0 {
+				return nil, fmt.Errorf("信号源 %s: static 类型需要配置 symbols", cfg.Name)
+			}
+		case ProviderFile:
+			if cfg.Path == "" {
+				return nil, fmt.Errorf("信号源 %s: file 类型需要配置 path", cfg.Name)
+			}
+			if _, err := resolveProviderFile(cfg.Path); err != nil {
+				return nil, fmt.Errorf("信号源 %s: %w", cfg.Name, err)
+			}
+		case ProviderHTTP:
+			if cfg.URL == "" {
+				return nil, fmt.Errorf("信号源 %s: http 类型需要配置 url", cfg.Name)
+			}
+			if err := checkProviderURL(cfg.URL); err != nil {
+				return nil, fmt.Errorf("信号源 %s: %w", cfg.Name, err)
+			}
+		default:
+			return nil, fmt.Errorf("不支持的信号源类型: %s", cfg.Type)
+		}
+
+		if cfg.Weight < 0 {
+			return nil, fmt.Errorf("信号源 %s: 权重不能为负数", cfg.Name)
+		}
+		if cfg.Limit < 0 {
+			return nil, fmt.Errorf("信号源 %s: limit 不能为负数", cfg.Name)
+		}
+		if names[cfg.Name] {
+			return nil, fmt.Errorf("信号源名称重复: %s", cfg.Name)
+		}
+		names[cfg.Name] = true
+	}
+	return configs, nil
+}
+
+// BuildProviders 根据配置创建信号源（ai500/oi_top 使用传入的 SignalSource）
+// file/http 类型再次按当前访问限制校验，不满足的跳过；tradable 不为nil时，screener 只对交易员所在交易所上市的币种排名
+func BuildProviders(configs []ProviderConfig, source *SignalSource, tradable func(symbol string) bool) []WeightedProvider {
+	providers := make([]WeightedProvider, 0, len(configs))
+	for _, cfg := range configs {
+		mapping := FieldMapping{ListPath: cfg.ListPath, SymbolField: cfg.SymbolField, ScoreField: cfg.ScoreField}
+
+		var provider CandidateProvider
+		switch cfg.Type {
+		case ProviderAI500:
+			provider = &namedProvider{name: cfg.Name, CandidateProvider: &AI500Provider{Source: source}}
+		case ProviderOITop:
+			provider = &namedProvider{name: cfg.Name, CandidateProvider: &OITopProvider{Source: source}}
+		case ProviderStatic:
+			provider = &StaticProvider{ProviderName: cfg.Name, Symbols: cfg.Symbols}
+		case ProviderFile:
+			path, err := resolveProviderFile(cfg.Path)
+			if err != nil {
+				log.Printf("⚠️  信号源 %s 已禁用: %v", cfg.Name, err)
+				continue
+			}
+			provider = &FileProvider{ProviderName: cfg.Name, Path: path, Mapping: mapping}
+		case ProviderHTTP:
+			if err := checkProviderURL(cfg.URL); err != nil {
+				log.Printf("⚠️  信号源 %s 已禁用: %v", cfg.Name, err)
+				continue
+			}
+			provider = &HTTPProvider{ProviderName: cfg.Name, URL: cfg.URL, Headers: cfg.Headers, Mapping: mapping}
+		case ProviderScreener:
+			provider = &ScreenerProvider{ProviderName: cfg.Name, Screener: market.NewScreener(market.ScreenerConfig{
+				MinQuoteVolume: cfg.MinVolume,
+				MinListingDays: cfg.MinListingDays,
+				ExcludeSymbols: cfg.Exclude,
+				Tradable:       tradable,
+			})}
+		default:
+			continue
+		}
+		providers = append(providers, WeightedProvider{Provider: provider, Weight: cfg.Weight, Limit: cfg.Limit})
+	}
+	return providers
+}
+
+// namedProvider 为内置信号源指定自定义名称
+type namedProvider struct {
+	CandidateProvider
+	name string
+}
+
+func (p *namedProvider) Name() string { return p.name }
@@ -0,0 +1,173 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// file/http 信号源的访问限制（由管理员在 config.json 中配置，交易员配置只能在限制范围内选择）
+// 未配置时这两类信号源不可用，避免任意用户读取服务器文件或访问内网地址
+var providerSandbox struct {
+	sync.RWMutex
+	fileDir    string          // file 信号源允许读取的目录（绝对路径）
+	allowHosts map[string]bool // http 信号源允许访问的主机名（小写）
+}
+
+// SetProviderSandbox 设置 file 信号源的目录和 http 信号源的主机白名单（为空表示禁用对应类型）
+func SetProviderSandbox(fileDir string, allowHosts []string) error {
+	dir := ""
+	if strings.TrimSpace(fileDir) != "" {
+		abs, err := filepath.Abs(fileDir)
+		if err != nil {
+			return fmt.Errorf("解析信号源文件目录失败: %w", err)
+		}
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = resolved
+		}
+		dir = abs
+	}
+
+	hosts := make(map[string]bool)
+	for _, host := range allowHosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host != "" {
+			hosts[host] = true
+		}
+	}
+
+	providerSandbox.Lock()
+	defer providerSandbox.Unlock()
+	providerSandbox.fileDir = dir
+	providerSandbox.allowHosts = hosts
+	return nil
+}
+
+// resolveProviderFile 校验 file 信号源路径并返回绝对路径：相对路径基于信号源目录，不允许包含 ..，
+// 解析符号链接后仍必须位于信号源目录内
+func resolveProviderFile(path string) (string, error) {
+	providerSandbox.RLock()
+	dir := providerSandbox.fileDir
+	providerSandbox.RUnlock()
+
+	if dir == "" {
+		return "", fmt.Errorf("管理员未配置信号源文件目录，file 类型信号源不可用")
+	}
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return "", fmt.Errorf("信号源文件路径不能包含 ..: %s", path)
+		}
+	}
+
+	full := path
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(dir, full)
+	}
+	full = filepath.Clean(full)
+	if err := checkWithinDir(dir, full); err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(full); err == nil {
+		if err := checkWithinDir(dir, resolved); err != nil {
+			return "", err
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("解析信号源文件路径失败: %w", err)
+	}
+	return full, nil
+}
+
+// checkWithinDir 检查路径位于目录内
+func checkWithinDir(dir, path string) error {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return fmt.Errorf("信号源文件必须位于 %s 目录内: %s", dir, path)
+	}
+	return nil
+}
+
+// checkProviderURL 校验 http 信号源地址：仅允许 http/https，主机必须在白名单内，
+// 且DNS解析结果不能是回环、链路本地（含云元数据地址）或内网地址
+func checkProviderURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("信号源地址无效: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("信号源地址只支持 http/https: %s", rawURL)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return fmt.Errorf("信号源地址缺少主机名: %s", rawURL)
+	}
+
+	providerSandbox.RLock()
+	allowed := providerSandbox.allowHosts[host]
+	empty := len(providerSandbox.allowHosts) == 0
+	providerSandbox.RUnlock()
+	if empty {
+		return fmt.Errorf("管理员未配置信号源主机白名单，http 类型信号源不可用")
+	}
+	if !allowed {
+		return fmt.Errorf("信号源主机 %s 不在管理员配置的白名单内", host)
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return fmt.Errorf("解析信号源主机 %s 失败: %w", host, err)
+	}
+	for _, ip := range ips {
+		if isForbiddenIP(ip) {
+			return fmt.Errorf("信号源主机 %s 解析到内网地址 %s", host, ip)
+		}
+	}
+	return nil
+}
+
+// isForbiddenIP 是否为不允许访问的地址（回环、链路本地、内网、未指定地址、组播）
+func isForbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// sandboxedHTTPClient http 信号源使用的客户端：连接时再次检查实际IP（防止DNS重绑定），重定向目标也必须通过白名单校验
+func sandboxedHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isForbiddenIP(ip) {
+				return fmt.Errorf("禁止访问内网地址: %s", host)
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("重定向次数过多")
+			}
+			return checkProviderURL(req.URL.String())
+		},
+	}
+}
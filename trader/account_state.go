@@ -0,0 +1,185 @@
+package trader
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// accountResyncInterval 推送正常时用REST全量校正缓存的间隔（推送不含可用余额、杠杆等字段，会逐渐偏离）
+const accountResyncInterval = 5 * time.Minute
+
+// accountState 交易员账户状态缓存（私有推送实时更新，REST全量同步校正）
+type accountState struct {
+	mu         sync.RWMutex
+	balance    Balance
+	positions  map[string]Position // symbol_side -> 持仓
+	connected  bool                // 推送连接是否正常
+	synced     bool                // 连接后是否已完成全量同步
+	lastSync   time.Time           // 上次全量同步时间
+	lastEvent  time.Time           // 上次收到推送时间
+	eventCount int64               // 收到的推送数量
+}
+
+func newAccountState() *accountState {
+	return &accountState{positions: make(map[string]Position)}
+}
+
+// snapshot 返回缓存的余额和持仓副本（推送断开或尚未同步时返回false，调用方应回退到REST查询）
+func (s *accountState) snapshot() (*Balance, []Position, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.connected || !s.synced {
+		return nil, nil, false
+	}
+
+	balance := s.balance
+	keys := make([]string, 0, len(s.positions))
+	for key := range s.positions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	positions := make([]Position, 0, len(keys))
+	for _, key := range keys {
+		positions = append(positions, s.positions[key])
+	}
+	return &balance, positions, true
+}
+
+// setConnected 更新推送连接状态（断开后需要重新全量同步）
+func (s *accountState) setConnected(connected bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connected = connected
+	if !connected {
+		s.synced = false
+	}
+}
+
+// needsResync 推送正常但距上次全量同步超过 accountResyncInterval
+func (s *accountState) needsResync() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.connected && s.synced && time.Since(s.lastSync) >= accountResyncInterval
+}
+
+// reset 用REST全量查询结果重置缓存，返回同步前存在、同步后已消失的持仓
+func (s *accountState) reset(balance *Balance, positions []Position) []Position {
+	next := make(map[string]Position, len(positions))
+	for _, pos := range positions {
+		if pos.Quantity != 0 {
+			next[pos.Symbol+"_"+pos.Side] = pos
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var closed []Position
+	if s.synced {
+		for key, old := range s.positions {
+			if _, ok := next[key]; !ok {
+				closed = append(closed, old)
+			}
+		}
+	}
+	s.balance = *balance
+	s.positions = next
+	s.synced = true
+	s.lastSync = time.Now()
+	return closed
+}
+
+// apply 应用一次推送，返回本次推送中平仓（数量变为0）的持仓
+// 尚未完成全量同步时只记录推送时间（同步结果已包含这些变化）
+func (s *accountState) apply(update AccountUpdate) []Position {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastEvent = time.Now()
+	s.eventCount++
+	if !s.synced {
+		return nil
+	}
+
+	if update.Balance != nil {
+		balance := *update.Balance
+		if balance.AvailableBalance == 0 {
+			balance.AvailableBalance = s.balance.AvailableBalance + balance.TotalWalletBalance - s.balance.TotalWalletBalance
+		}
+		if update.UnrealizedExcluded {
+			balance.TotalUnrealizedProfit = s.balance.TotalUnrealizedProfit
+		}
+		s.balance = balance
+	}
+
+	var closed []Position
+	for _, pos := range update.Positions {
+		key := pos.Symbol + "_" + pos.Side
+		old, existed := s.positions[key]
+		if pos.Quantity == 0 {
+			if existed {
+				delete(s.positions, key)
+				closed = append(closed, old)
+				if update.UnrealizedExcluded {
+					s.balance.TotalUnrealizedProfit -= old.UnrealizedProfit
+				}
+			}
+			continue
+		}
+		// 推送不含的字段沿用缓存值
+		if pos.MarkPrice == 0 {
+			pos.MarkPrice = old.MarkPrice
+		}
+		if pos.Leverage == 0 {
+			pos.Leverage = old.Leverage
+		}
+		if pos.LiquidationPrice == 0 {
+			pos.LiquidationPrice = old.LiquidationPrice
+		}
+		if pos.Margin == 0 {
+			pos.Margin = old.Margin
+		}
+		if update.UnrealizedExcluded {
+			s.balance.TotalUnrealizedProfit += pos.UnrealizedProfit - old.UnrealizedProfit
+		}
+		s.positions[key] = pos
+	}
+
+	for symbol, mark := range update.MarkPrices {
+		for _, side := range []string{"long", "short"} {
+			key := symbol + "_" + side
+			pos, ok := s.positions[key]
+			if !ok || mark <= 0 {
+				continue
+			}
+			pnl := (mark - pos.EntryPrice) * pos.Quantity
+			if side == "short" {
+				pnl = -pnl
+			}
+			if update.UnrealizedExcluded {
+				s.balance.TotalUnrealizedProfit += pnl - pos.UnrealizedProfit
+			}
+			pos.MarkPrice = mark
+			pos.UnrealizedProfit = pnl
+			s.positions[key] = pos
+		}
+	}
+	return closed
+}
+
+// status 推送状态（用于API）
+func (s *accountState) status() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status := map[string]interface{}{
+		"connected":   s.connected,
+		"synced":      s.synced,
+		"event_count": s.eventCount,
+	}
+	if !s.lastEvent.IsZero() {
+		status["last_event"] = s.lastEvent.Format(time.RFC3339)
+	}
+	if !s.lastSync.IsZero() {
+		status["last_sync"] = s.lastSync.Format(time.RFC3339)
+	}
+	return status
+}
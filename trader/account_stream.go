@@ -0,0 +1,197 @@
+package trader
+
+import (
+	"log"
+	"nofx/config"
+	"time"
+)
+
+// accountStreamRetryDelay 账户推送断开后的重连间隔
+const accountStreamRetryDelay = 5 * time.Second
+
+// startAccountStream 交易所支持私有推送时启动账户推送（断线自动重连，随监控goroutine一起停止）
+func (at *AutoTrader) startAccountStream() {
+	streamer, ok := at.trader.(AccountStreamer)
+	if !ok {
+		return
+	}
+
+	at.monitorWg.Add(1)
+	go func() {
+		defer at.monitorWg.Done()
+		log.Printf("📡 [%s] 启动账户实时推送", at.name)
+
+		for {
+			err := streamer.StreamAccount(at.stopMonitorCh, at.handleAccountUpdate)
+			at.accountState.setConnected(false)
+			if err != nil {
+				log.Printf("⚠️ [%s] 账户推送断开，%v 后重连: %v", at.name, accountStreamRetryDelay, err)
+			}
+
+			select {
+			case <-at.stopMonitorCh:
+				log.Printf("⏹ [%s] 停止账户实时推送", at.name)
+				return
+			case <-time.After(accountStreamRetryDelay):
+			}
+		}
+	}()
+}
+
+// handleAccountUpdate 处理账户推送：更新缓存，止盈止损成交和交易所侧平仓立即写入交易台账
+func (at *AutoTrader) handleAccountUpdate(update AccountUpdate) {
+	var closed []Position
+	if update.Resync {
+		at.accountState.setConnected(true)
+		closed = at.resyncAccountState()
+	}
+	closed = append(closed, at.accountState.apply(update)...)
+
+	for _, order := range update.Orders {
+		if order.isExitTrigger() {
+			at.onTriggerFilled(order)
+		}
+	}
+	for _, pos := range closed {
+		at.onPositionClosed(pos)
+	}
+}
+
+// resyncAccountState 用REST全量同步账户缓存，返回同步后消失的持仓
+// 由推送触发时交易所实现应先清除REST缓存，保证同步结果不早于推送
+func (at *AutoTrader) resyncAccountState() []Position {
+	balance, err := at.trader.GetBalance()
+	if err != nil {
+		log.Printf("⚠️ [%s] 账户缓存同步余额失败: %v", at.name, err)
+		return nil
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️ [%s] 账户缓存同步持仓失败: %v", at.name, err)
+		return nil
+	}
+	return at.accountState.reset(balance, positions)
+}
+
+// getBalance 获取余额（账户推送正常时读缓存，否则调用交易所API）
+func (at *AutoTrader) getBalance() (*Balance, error) {
+	if balance, _, ok := at.accountState.snapshot(); ok {
+		return balance, nil
+	}
+	return at.trader.GetBalance()
+}
+
+// getPositions 获取持仓（账户推送正常时读缓存，否则调用交易所API）
+func (at *AutoTrader) getPositions() ([]Position, error) {
+	if _, positions, ok := at.accountState.snapshot(); ok {
+		return positions, nil
+	}
+	return at.trader.GetPositions()
+}
+
+// onTriggerFilled 止盈止损单成交：立即按成交明细写入交易台账（不等下个周期对账）
+func (at *AutoTrader) onTriggerFilled(order OrderUpdate) {
+	log.Printf("🎯 [%s] %s %s 条件单成交（%s）: 均价 %.4f 数量 %.8f",
+		at.name, order.Symbol, order.PositionSide, order.Type, order.AvgPrice, order.ExecutedQty)
+	if at.ledger == nil {
+		return
+	}
+
+	at.tradeMutex.Lock()
+	defer at.tradeMutex.Unlock()
+
+	if exists, err := at.ledger.TradeFillExists(at.id, order.OrderID); err != nil || exists {
+		return
+	}
+	trade := at.findOpenTrade(order.Symbol, order.PositionSide)
+	if trade == nil {
+		return
+	}
+
+	result := &OrderResult{
+		OrderID:     order.OrderID,
+		Symbol:      order.Symbol,
+		AvgPrice:    order.AvgPrice,
+		ExecutedQty: order.ExecutedQty,
+		Fee:         order.Fee,
+	}
+	at.resolveFill(order.Symbol, result)
+
+	reason := inferExitReason(trade, result.AvgPrice)
+	switch order.Type {
+	case OpenOrderTypeStopLoss:
+		reason = config.TradeExitStopLoss
+	case OpenOrderTypeTrailingStop:
+		reason = config.TradeExitTrailing
+	case OpenOrderTypeTakeProfit:
+		reason = config.TradeExitTakeProfit
+	}
+
+	closed, err := at.ledger.RecordTradeClose(at.id, order.Symbol, order.PositionSide, config.TradeFill{
+		OrderID:  result.OrderID,
+		Price:    result.AvgPrice,
+		Quantity: result.ExecutedQty,
+		Fee:      result.Fee,
+		Reason:   reason,
+		Time:     order.Time,
+	})
+	if err != nil || closed == nil {
+		log.Printf("⚠️ [%s] 记录 %s 条件单成交失败: %v", at.name, order.Symbol, err)
+		return
+	}
+	if closed.Status == config.TradeStatusClosed {
+		log.Printf("📒 [%s] %s %s 已由交易所%s平仓，均价 %.4f，净盈亏 %+.4f USDT",
+			at.name, closed.Symbol, closed.Side, closed.ExitReason, closed.ExitPrice, closed.NetPnL())
+		at.syncTradeCosts(closed)
+	}
+}
+
+// onPositionClosed 推送显示持仓已平仓：清理回撤缓存，台账仍未平仓（交易所侧平仓）时立即对账，
+// 并撤销该币种残留的止盈止损单（如止损成交后剩下的止盈单）
+func (at *AutoTrader) onPositionClosed(pos Position) {
+	log.Printf("📡 [%s] 推送显示 %s %s 已平仓", at.name, pos.Symbol, pos.Side)
+	at.ClearPeakPnLCache(pos.Symbol)
+	at.clearTrailingStop(pos.Symbol, pos.Side)
+
+	at.tradeMutex.Lock()
+	defer at.tradeMutex.Unlock()
+
+	if at.ledger != nil {
+		if trade := at.findOpenTrade(pos.Symbol, pos.Side); trade != nil {
+			at.reconcileClosedTrade(trade)
+		}
+	}
+
+	// 同币种仍有持仓或限价开仓挂单时保留挂单（推送顺序可能落后于本地执行，以交易所查询为准）
+	if at.hasPendingEntry(pos.Symbol, "long") || at.hasPendingEntry(pos.Symbol, "short") {
+		return
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️ [%s] 查询持仓失败，跳过清理 %s 残留挂单: %v", at.name, pos.Symbol, err)
+		return
+	}
+	for _, p := range positions {
+		if p.Symbol == pos.Symbol && p.Quantity != 0 {
+			return
+		}
+	}
+	if err := at.trader.CancelStopOrders(pos.Symbol); err != nil {
+		log.Printf("⚠️ [%s] 清理 %s 残留止盈止损单失败: %v", at.name, pos.Symbol, err)
+	}
+}
+
+// findOpenTrade 查找台账中指定币种方向的未平仓交易
+func (at *AutoTrader) findOpenTrade(symbol, side string) *config.TradeRecord {
+	trades, err := at.ledger.GetOpenTrades(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 查询未平仓交易失败: %v", at.name, err)
+		return nil
+	}
+	for _, trade := range trades {
+		if trade.Symbol == symbol && trade.Side == side {
+			return trade
+		}
+	}
+	return nil
+}
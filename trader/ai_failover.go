@@ -0,0 +1,62 @@
+package trader
+
+import (
+	"log"
+	"nofx/mcp"
+)
+
+// AIFallbackConfig 备用AI模型配置（主模型失败或熔断时按顺序切换）
+type AIFallbackConfig struct {
+	ID              string // AI模型配置ID
+	Provider        string // deepseek / qwen / googleai / chatgpt / anthropic / gpts / custom
+	APIKey          string
+	CustomAPIURL    string
+	CustomModelName string
+}
+
+// buildAIClient 未配置备用模型时直接使用主模型，否则组装 主模型 → 备用模型 的备用链（每个提供商独立熔断）
+func buildAIClient(config AutoTraderConfig, primary *mcp.Client) mcp.AIClient {
+	if len(config.AIFallbacks) == 0 {
+		return primary
+	}
+
+	chain := mcp.NewFailoverClient(0, 0)
+	chain.Add("", primary)
+	for _, fallback := range config.AIFallbacks {
+		client, err := mcp.NewClientForProvider(fallback.Provider, fallback.APIKey, fallback.CustomAPIURL, fallback.CustomModelName)
+		if err != nil {
+			log.Printf("⚠️ [%s] 备用AI模型 %s 配置无效，已跳过: %v", config.Name, fallback.ID, err)
+			continue
+		}
+		chain.Add("", client)
+	}
+
+	if chain.Len() == 1 {
+		// 没有可用的备用模型，恢复主模型的默认重试策略
+		primary.MaxRetries = 0
+		return primary
+	}
+
+	names := make([]string, 0, chain.Len())
+	for _, status := range chain.Status() {
+		names = append(names, status.Name)
+	}
+	log.Printf("🔀 [%s] AI备用链: %v", config.Name, names)
+	return chain
+}
+
+// aiProviderName 最近一次实际应答的AI提供商（provider/model）
+func (at *AutoTrader) aiProviderName() string {
+	if reporter, ok := at.mcpClient.(mcp.ProviderReporter); ok {
+		return reporter.LastProvider()
+	}
+	return ""
+}
+
+// GetAIProviderStatus 备用链中各AI提供商的熔断状态（未配置备用链时返回nil）
+func (at *AutoTrader) GetAIProviderStatus() []mcp.ProviderStatus {
+	if chain, ok := at.mcpClient.(*mcp.FailoverClient); ok {
+		return chain.Status()
+	}
+	return nil
+}
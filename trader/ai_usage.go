@@ -0,0 +1,104 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/config"
+	"nofx/logger"
+	"nofx/mcp"
+	"time"
+)
+
+// AIUsageStore AI用量存储（由 config.Database 实现）
+type AIUsageStore interface {
+	RecordAIUsage(traderID string, entry config.AIUsageEntry) error
+	GetAIUsage(traderID string, days int) (*config.AIUsageSummary, error)
+}
+
+// AIUsageReport AI用量汇总及单笔交易AI成本
+type AIUsageReport struct {
+	*config.AIUsageSummary
+	ClosedTrades  int     `json:"closed_trades"`    // 统计区间内已平仓交易数
+	CostPerTrade  float64 `json:"cost_per_trade"`   // 平均每笔已平仓交易的AI费用（美元）
+	CostPerCall   float64 `json:"cost_per_call"`    // 平均每次AI调用费用（美元）
+	NetPnL        float64 `json:"net_pnl"`          // 已平仓交易净盈亏（扣手续费、含资金费）
+	NetPnLAfterAI float64 `json:"net_pnl_after_ai"` // 扣除AI费用后的净盈亏
+}
+
+// recordAIUsage 将本周期AI调用的token用量和费用写入决策记录和用量表（失败只记录日志）
+func (at *AutoTrader) recordAIUsage(record *logger.DecisionRecord) {
+	reporter, ok := at.mcpClient.(mcp.UsageReporter)
+	if !ok {
+		return
+	}
+	usage := reporter.LastUsage()
+	if usage == nil {
+		return
+	}
+
+	cost := at.priceTable.Cost(usage)
+	record.AIUsage = &logger.AIUsage{
+		Model:            usage.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CachedTokens:     usage.CachedTokens,
+		TotalTokens:      usage.TotalTokens,
+		LatencyMs:        usage.Latency.Milliseconds(),
+		Attempts:         usage.Attempts,
+		CostUSD:          cost,
+	}
+
+	if at.usageStore == nil {
+		return
+	}
+	err := at.usageStore.RecordAIUsage(at.id, config.AIUsageEntry{
+		Time:             time.Now(),
+		Provider:         usage.Provider,
+		Model:            usage.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CachedTokens:     usage.CachedTokens,
+		CostUSD:          cost,
+		LatencyMs:        usage.Latency.Milliseconds(),
+	})
+	if err != nil {
+		log.Printf("⚠️ [%s] 记录AI用量失败: %v", at.name, err)
+	}
+}
+
+// GetAIUsage AI用量汇总（days<=0 表示全部），并结合交易台账计算单笔交易AI成本
+func (at *AutoTrader) GetAIUsage(days int) (*AIUsageReport, error) {
+	if at.usageStore == nil {
+		return nil, fmt.Errorf("数据库不支持AI用量统计")
+	}
+
+	summary, err := at.usageStore.GetAIUsage(at.id, days)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AIUsageReport{AIUsageSummary: summary}
+	if summary.Total.Calls > 0 {
+		report.CostPerCall = summary.Total.CostUSD / float64(summary.Total.Calls)
+	}
+
+	if at.ledger != nil {
+		trades, err := at.ledger.GetClosedTrades(at.id, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, trade := range trades {
+			if summary.Since != "" && trade.CloseTime != nil && trade.CloseTime.Format("2006-01-02") < summary.Since {
+				continue
+			}
+			report.ClosedTrades++
+			report.NetPnL += trade.NetPnL()
+		}
+	}
+	if report.ClosedTrades > 0 {
+		report.CostPerTrade = summary.Total.CostUSD / float64(report.ClosedTrades)
+	}
+	report.NetPnLAfterAI = report.NetPnL - summary.Total.CostUSD
+
+	return report, nil
+}
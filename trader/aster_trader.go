@@ -2,48 +2,27 @@ package trader
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"math"
-	"math/big"
 	"net/http"
 	"net/url"
-	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // AsterTrader Aster交易平台实现
 type AsterTrader struct {
-	ctx        context.Context
-	user       string            // 主钱包地址 (ERC20)
-	signer     string            // API钱包地址
-	privateKey *ecdsa.PrivateKey // API钱包私钥
-	client     *http.Client
-	baseURL    string
-
-	// 缓存交易对精度信息
-	symbolPrecision map[string]SymbolPrecision
-	mu              sync.RWMutex
-}
+	ctx  context.Context
+	rest *restClient // 签名需要主钱包地址、API钱包地址和私钥（见 newAsterSigner）
 
-// SymbolPrecision 交易对精度信息
-type SymbolPrecision struct {
-	PricePrecision    int
-	QuantityPrecision int
-	TickSize          float64 // 价格步进值
-	StepSize          float64 // 数量步进值
+	// 合约规则（exchangeInfo）
+	instruments *instrumentRegistry
 }
 
 // NewAsterTrader 创建Aster交易器
@@ -57,46 +36,28 @@ func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
 		return nil, fmt.Errorf("解析私钥失败: %w", err)
 	}
 
-	return &AsterTrader{
-		ctx:             context.Background(),
-		user:            user,
-		signer:          signer,
-		privateKey:      privKey,
-		symbolPrecision: make(map[string]SymbolPrecision),
-		client: &http.Client{
-			Timeout: 30 * time.Second, // 增加到30秒
-			Transport: &http.Transport{
-				TLSHandshakeTimeout:   10 * time.Second,
-				ResponseHeaderTimeout: 10 * time.Second,
-				IdleConnTimeout:       90 * time.Second,
-			},
-		},
-		baseURL: "https://fapi.asterdex.com",
-	}, nil
-}
-
-// genNonce 生成微秒时间戳
-func (t *AsterTrader) genNonce() uint64 {
-	return uint64(time.Now().UnixMicro())
-}
-
-// getPrecision 获取交易对精度信息
-func (t *AsterTrader) getPrecision(symbol string) (SymbolPrecision, error) {
-	t.mu.RLock()
-	if prec, ok := t.symbolPrecision[symbol]; ok {
-		t.mu.RUnlock()
-		return prec, nil
+	trader := &AsterTrader{
+		ctx:  context.Background(),
+		rest: newRESTClient("Aster", "https://fapi.asterdex.com", newAsterSigner(user, signer, privKey)),
+	}
+	trader.rest.form = true
+	trader.rest.httpClient.Transport = &http.Transport{
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
 	}
-	t.mu.RUnlock()
+	trader.rest.timestampRejected = isBinanceTimestampRejected
+	trader.rest.clock.sync("Aster", trader.fetchServerTime)
+	trader.instruments = sharedInstrumentRegistry("aster", "Aster", trader.loadInstruments)
+	return trader, nil
+}
 
-	// 获取交易所信息
-	resp, err := t.client.Get(t.baseURL + "/fapi/v3/exchangeInfo")
+// loadInstruments 加载全部交易对规则（exchangeInfo，无 tickSize 时按 pricePrecision 推算价格步进）
+func (t *AsterTrader) loadInstruments() (map[string]Instrument, error) {
+	body, err := t.rest.getPublic("/fapi/v3/exchangeInfo", nil)
 	if err != nil {
-		return SymbolPrecision{}, err
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
 	var info struct {
 		Symbols []struct {
 			Symbol            string                   `json:"symbol"`
@@ -105,43 +66,49 @@ func (t *AsterTrader) getPrecision(symbol string) (SymbolPrecision, error) {
 			Filters           []map[string]interface{} `json:"filters"`
 		} `json:"symbols"`
 	}
-
 	if err := json.Unmarshal(body, &info); err != nil {
-		return SymbolPrecision{}, err
+		return nil, err
 	}
 
-	// 缓存所有交易对的精度
-	t.mu.Lock()
+	specs := make(map[string]Instrument, len(info.Symbols))
 	for _, s := range info.Symbols {
-		prec := SymbolPrecision{
-			PricePrecision:    s.PricePrecision,
-			QuantityPrecision: s.QuantityPrecision,
-		}
+		spec := Instrument{Symbol: s.Symbol, ContractSize: 1}
 
-		// 解析filters获取tickSize和stepSize
+		// 解析filters获取tickSize、stepSize和最小下单量
 		for _, filter := range s.Filters {
 			filterType, _ := filter["filterType"].(string)
 			switch filterType {
 			case "PRICE_FILTER":
 				if tickSizeStr, ok := filter["tickSize"].(string); ok {
-					prec.TickSize, _ = strconv.ParseFloat(tickSizeStr, 64)
+					spec.TickSize, _ = strconv.ParseFloat(tickSizeStr, 64)
 				}
 			case "LOT_SIZE":
 				if stepSizeStr, ok := filter["stepSize"].(string); ok {
-					prec.StepSize, _ = strconv.ParseFloat(stepSizeStr, 64)
+					spec.QtyStep, _ = strconv.ParseFloat(stepSizeStr, 64)
+				}
+				if minQtyStr, ok := filter["minQty"].(string); ok {
+					spec.MinQty, _ = strconv.ParseFloat(minQtyStr, 64)
+				}
+			case "MIN_NOTIONAL":
+				if notionalStr, ok := filter["notional"].(string); ok {
+					spec.MinNotional, _ = strconv.ParseFloat(notionalStr, 64)
 				}
 			}
 		}
-
-		t.symbolPrecision[s.Symbol] = prec
-	}
-	t.mu.Unlock()
-
-	if prec, ok := t.symbolPrecision[symbol]; ok {
-		return prec, nil
+		if spec.TickSize <= 0 {
+			spec.TickSize = math.Pow10(-s.PricePrecision)
+		}
+		if spec.QtyStep <= 0 {
+			spec.QtyStep = math.Pow10(-s.QuantityPrecision)
+		}
+		specs[s.Symbol] = spec
 	}
+	return specs, nil
+}
 
-	return SymbolPrecision{}, fmt.Errorf("未找到交易对 %s 的精度信息", symbol)
+// GetInstrument 获取合约交易规则
+func (t *AsterTrader) GetInstrument(symbol string) (*Instrument, error) {
+	return t.instruments.get(symbol)
 }
 
 // roundToTickSize 将价格/数量四舍五入到tick size/step size的整数倍
@@ -157,276 +124,32 @@ func roundToTickSize(value float64, tickSize float64) float64 {
 	return roundedSteps * tickSize
 }
 
-// formatPrice 格式化价格到正确精度和tick size
-func (t *AsterTrader) formatPrice(symbol string, price float64) (float64, error) {
-	prec, err := t.getPrecision(symbol)
-	if err != nil {
-		return 0, err
-	}
-
-	// 优先使用tick size，确保价格是tick size的整数倍
-	if prec.TickSize > 0 {
-		return roundToTickSize(price, prec.TickSize), nil
-	}
-
-	// 如果没有tick size，则按精度四舍五入
-	multiplier := math.Pow10(prec.PricePrecision)
-	return math.Round(price*multiplier) / multiplier, nil
-}
-
-// formatQuantity 格式化数量到正确精度和step size
-func (t *AsterTrader) formatQuantity(symbol string, quantity float64) (float64, error) {
-	prec, err := t.getPrecision(symbol)
-	if err != nil {
-		return 0, err
-	}
-
-	// 优先使用step size，确保数量是step size的整数倍
-	if prec.StepSize > 0 {
-		return roundToTickSize(quantity, prec.StepSize), nil
-	}
-
-	// 如果没有step size，则按精度四舍五入
-	multiplier := math.Pow10(prec.QuantityPrecision)
-	return math.Round(quantity*multiplier) / multiplier, nil
-}
-
-// formatFloatWithPrecision 将浮点数格式化为指定精度的字符串（去除末尾的0）
-func (t *AsterTrader) formatFloatWithPrecision(value float64, precision int) string {
-	// 使用指定精度格式化
-	formatted := strconv.FormatFloat(value, 'f', precision, 64)
-
-	// 去除末尾的0和小数点（如果有）
-	formatted = strings.TrimRight(formatted, "0")
-	formatted = strings.TrimRight(formatted, ".")
-
-	return formatted
-}
-
-// normalizeAndStringify 对参数进行规范化并序列化为JSON字符串（按key排序）
-func (t *AsterTrader) normalizeAndStringify(params map[string]interface{}) (string, error) {
-	normalized, err := t.normalize(params)
-	if err != nil {
-		return "", err
-	}
-	bs, err := json.Marshal(normalized)
-	if err != nil {
-		return "", err
-	}
-	return string(bs), nil
-}
-
-// normalize 递归规范化参数（按key排序，所有值转为字符串）
-func (t *AsterTrader) normalize(v interface{}) (interface{}, error) {
-	switch val := v.(type) {
-	case map[string]interface{}:
-		keys := make([]string, 0, len(val))
-		for k := range val {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		newMap := make(map[string]interface{}, len(keys))
-		for _, k := range keys {
-			nv, err := t.normalize(val[k])
-			if err != nil {
-				return nil, err
-			}
-			newMap[k] = nv
-		}
-		return newMap, nil
-	case []interface{}:
-		out := make([]interface{}, 0, len(val))
-		for _, it := range val {
-			nv, err := t.normalize(it)
-			if err != nil {
-				return nil, err
-			}
-			out = append(out, nv)
-		}
-		return out, nil
-	case string:
-		return val, nil
-	case int:
-		return fmt.Sprintf("%d", val), nil
-	case int64:
-		return fmt.Sprintf("%d", val), nil
-	case float64:
-		return fmt.Sprintf("%v", val), nil
-	case bool:
-		return fmt.Sprintf("%v", val), nil
-	default:
-		// 其他类型转为字符串
-		return fmt.Sprintf("%v", val), nil
+// request 发送签名请求（参数按表单编码，签名、服务器时间校准和重试由 restClient 处理）
+func (t *AsterTrader) request(method, endpoint string, params map[string]interface{}) ([]byte, error) {
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, fmt.Sprintf("%v", v))
 	}
+	return t.rest.do(strings.ToUpper(method), endpoint, query, nil)
 }
 
-// sign 对请求参数进行签名
-func (t *AsterTrader) sign(params map[string]interface{}, nonce uint64) error {
-	// 添加时间戳和接收窗口
-	params["recvWindow"] = "50000"
-	params["timestamp"] = strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
-
-	// 规范化参数为JSON字符串
-	jsonStr, err := t.normalizeAndStringify(params)
-	if err != nil {
-		return err
-	}
-
-	// ABI编码: (string, address, address, uint256)
-	addrUser := common.HexToAddress(t.user)
-	addrSigner := common.HexToAddress(t.signer)
-	nonceBig := new(big.Int).SetUint64(nonce)
-
-	tString, _ := abi.NewType("string", "", nil)
-	tAddress, _ := abi.NewType("address", "", nil)
-	tUint256, _ := abi.NewType("uint256", "", nil)
-
-	arguments := abi.Arguments{
-		{Type: tString},
-		{Type: tAddress},
-		{Type: tAddress},
-		{Type: tUint256},
-	}
-
-	packed, err := arguments.Pack(jsonStr, addrUser, addrSigner, nonceBig)
+// fetchServerTime 获取Aster服务器时间
+func (t *AsterTrader) fetchServerTime() (time.Time, error) {
+	body, err := t.rest.getPublic("/fapi/v3/time", nil)
 	if err != nil {
-		return fmt.Errorf("ABI编码失败: %w", err)
+		return time.Time{}, err
 	}
-
-	// Keccak256哈希
-	hash := crypto.Keccak256(packed)
-
-	// 以太坊签名消息前缀
-	prefixedMsg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(hash), hash)
-	msgHash := crypto.Keccak256Hash([]byte(prefixedMsg))
-
-	// ECDSA签名
-	sig, err := crypto.Sign(msgHash.Bytes(), t.privateKey)
-	if err != nil {
-		return fmt.Errorf("签名失败: %w", err)
+	var resp struct {
+		ServerTime int64 `json:"serverTime"`
 	}
-
-	// 将v从0/1转换为27/28
-	if len(sig) != 65 {
-		return fmt.Errorf("签名长度异常: %d", len(sig))
-	}
-	sig[64] += 27
-
-	// 添加签名参数
-	params["user"] = t.user
-	params["signer"] = t.signer
-	params["signature"] = "0x" + hex.EncodeToString(sig)
-	params["nonce"] = nonce
-
-	return nil
-}
-
-// request 发送HTTP请求（带重试机制）
-func (t *AsterTrader) request(method, endpoint string, params map[string]interface{}) ([]byte, error) {
-	const maxRetries = 3
-	var lastErr error
-
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		// 每次重试都生成新的nonce和签名
-		nonce := t.genNonce()
-		paramsCopy := make(map[string]interface{})
-		for k, v := range params {
-			paramsCopy[k] = v
-		}
-
-		// 签名
-		if err := t.sign(paramsCopy, nonce); err != nil {
-			return nil, err
-		}
-
-		body, err := t.doRequest(method, endpoint, paramsCopy)
-		if err == nil {
-			return body, nil
-		}
-
-		lastErr = err
-
-		// 如果是网络超时或临时错误，重试
-		if strings.Contains(err.Error(), "timeout") ||
-			strings.Contains(err.Error(), "connection reset") ||
-			strings.Contains(err.Error(), "EOF") {
-			if attempt < maxRetries {
-				waitTime := time.Duration(attempt) * time.Second
-				time.Sleep(waitTime)
-				continue
-			}
-		}
-
-		// 其他错误（如400/401等）不重试
-		return nil, err
-	}
-
-	return nil, fmt.Errorf("请求失败（已重试%d次）: %w", maxRetries, lastErr)
-}
-
-// doRequest 执行实际的HTTP请求
-func (t *AsterTrader) doRequest(method, endpoint string, params map[string]interface{}) ([]byte, error) {
-	fullURL := t.baseURL + endpoint
-	method = strings.ToUpper(method)
-
-	switch method {
-	case "POST":
-		// POST请求：参数放在表单body中
-		form := url.Values{}
-		for k, v := range params {
-			form.Set(k, fmt.Sprintf("%v", v))
-		}
-		req, err := http.NewRequest("POST", fullURL, strings.NewReader(form.Encode()))
-		if err != nil {
-			return nil, err
-		}
-		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-		resp, err := t.client.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
-		}
-		return body, nil
-
-	case "GET", "DELETE":
-		// GET/DELETE请求：参数放在querystring中
-		q := url.Values{}
-		for k, v := range params {
-			q.Set(k, fmt.Sprintf("%v", v))
-		}
-		u, _ := url.Parse(fullURL)
-		u.RawQuery = q.Encode()
-
-		req, err := http.NewRequest(method, u.String(), nil)
-		if err != nil {
-			return nil, err
-		}
-
-		resp, err := t.client.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
-		}
-		return body, nil
-
-	default:
-		return nil, fmt.Errorf("不支持的HTTP方法: %s", method)
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return time.Time{}, fmt.Errorf("解析服务器时间失败: %w", err)
 	}
+	return time.UnixMilli(resp.ServerTime), nil
 }
 
 // GetBalance 获取账户余额
-func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
+func (t *AsterTrader) GetBalance() (*Balance, error) {
 	params := make(map[string]interface{})
 	body, err := t.request("GET", "/fapi/v3/balance", params)
 	if err != nil {
@@ -482,16 +205,15 @@ func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
 		crossUnPnl,
 		availableBalance)
 
-	// 返回与Binance相同的字段名，确保AutoTrader能正确解析
-	return map[string]interface{}{
-		"totalWalletBalance":    totalBalance,    // 钱包余额（不含未实现盈亏）
-		"availableBalance":      availableBalance,
-		"totalUnrealizedProfit": crossUnPnl,      // 未实现盈亏
+	return &Balance{
+		TotalWalletBalance:    totalBalance, // 钱包余额（不含未实现盈亏）
+		AvailableBalance:      availableBalance,
+		TotalUnrealizedProfit: crossUnPnl, // 未实现盈亏
 	}, nil
 }
 
 // GetPositions 获取持仓信息
-func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *AsterTrader) GetPositions() ([]Position, error) {
 	params := make(map[string]interface{})
 	body, err := t.request("GET", "/fapi/v3/positionRisk", params)
 	if err != nil {
@@ -503,7 +225,7 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 		return nil, err
 	}
 
-	result := []map[string]interface{}{}
+	result := []Position{}
 	for _, pos := range positions {
 		posAmtStr, ok := pos["positionAmt"].(string)
 		if !ok {
@@ -528,24 +250,47 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 			posAmt = -posAmt
 		}
 
-		// 返回与Binance相同的字段名
-		result = append(result, map[string]interface{}{
-			"symbol":           pos["symbol"],
-			"side":             side,
-			"positionAmt":      posAmt,
-			"entryPrice":       entryPrice,
-			"markPrice":        markPrice,
-			"unRealizedProfit": unRealizedProfit,
-			"leverage":         leverageVal,
-			"liquidationPrice": liquidationPrice,
+		symbol, _ := pos["symbol"].(string)
+		result = append(result, Position{
+			Symbol:           symbol,
+			Side:             side,
+			Quantity:         posAmt,
+			EntryPrice:       entryPrice,
+			MarkPrice:        markPrice,
+			UnrealizedProfit: unRealizedProfit,
+			Leverage:         int(leverageVal),
+			LiquidationPrice: liquidationPrice,
 		})
 	}
 
 	return result, nil
 }
 
+// parseAsterOrderResult 解析Aster下单响应（与Binance格式一致）
+func parseAsterOrderResult(body []byte) (*OrderResult, error) {
+	var resp struct {
+		OrderID     int64  `json:"orderId"`
+		Symbol      string `json:"symbol"`
+		Status      string `json:"status"`
+		AvgPrice    string `json:"avgPrice"`
+		ExecutedQty string `json:"executedQty"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	result := &OrderResult{
+		OrderID: formatOrderID(resp.OrderID),
+		Symbol:  resp.Symbol,
+		Status:  resp.Status,
+	}
+	result.AvgPrice, _ = strconv.ParseFloat(resp.AvgPrice, 64)
+	result.ExecutedQty, _ = strconv.ParseFloat(resp.ExecutedQty, 64)
+	return result, nil
+}
+
 // OpenLong 开多单（V1.57版本：添加stopLoss和takeProfit参数以兼容接口，Aster暂不支持下单时设置止盈止损）
-func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (map[string]interface{}, error) {
+func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
@@ -566,27 +311,14 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int, st
 	limitPrice := price * 1.01
 
 	// 格式化价格和数量到正确精度
-	formattedPrice, err := t.formatPrice(symbol, limitPrice)
-	if err != nil {
-		return nil, err
-	}
-	formattedQty, err := t.formatQuantity(symbol, quantity)
+	inst, err := t.instruments.get(symbol)
 	if err != nil {
 		return nil, err
 	}
+	priceStr := inst.FormatPrice(limitPrice)
+	qtyStr := inst.FormatQuantity(quantity)
 
-	// 获取精度信息
-	prec, err := t.getPrecision(symbol)
-	if err != nil {
-		return nil, err
-	}
-
-	// 转换为字符串，使用正确的精度格式
-	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
-	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
-
-	log.Printf("  📏 精度处理: 价格 %.8f -> %s (精度=%d), 数量 %.8f -> %s (精度=%d)",
-		limitPrice, priceStr, prec.PricePrecision, quantity, qtyStr, prec.QuantityPrecision)
+	log.Printf("  📏 精度处理: 价格 %.8f -> %s, 数量 %.8f -> %s", limitPrice, priceStr, quantity, qtyStr)
 
 	params := map[string]interface{}{
 		"symbol":       symbol,
@@ -603,8 +335,8 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int, st
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
+	result, err := parseAsterOrderResult(body)
+	if err != nil {
 		return nil, err
 	}
 
@@ -612,7 +344,7 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int, st
 }
 
 // OpenShort 开空单（V1.57版本：添加stopLoss和takeProfit参数以兼容接口，Aster暂不支持下单时设置止盈止损）
-func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (map[string]interface{}, error) {
+func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
@@ -633,27 +365,14 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int, s
 	limitPrice := price * 0.99
 
 	// 格式化价格和数量到正确精度
-	formattedPrice, err := t.formatPrice(symbol, limitPrice)
-	if err != nil {
-		return nil, err
-	}
-	formattedQty, err := t.formatQuantity(symbol, quantity)
+	inst, err := t.instruments.get(symbol)
 	if err != nil {
 		return nil, err
 	}
+	priceStr := inst.FormatPrice(limitPrice)
+	qtyStr := inst.FormatQuantity(quantity)
 
-	// 获取精度信息
-	prec, err := t.getPrecision(symbol)
-	if err != nil {
-		return nil, err
-	}
-
-	// 转换为字符串，使用正确的精度格式
-	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
-	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
-
-	log.Printf("  📏 精度处理: 价格 %.8f -> %s (精度=%d), 数量 %.8f -> %s (精度=%d)",
-		limitPrice, priceStr, prec.PricePrecision, quantity, qtyStr, prec.QuantityPrecision)
+	log.Printf("  📏 精度处理: 价格 %.8f -> %s, 数量 %.8f -> %s", limitPrice, priceStr, quantity, qtyStr)
 
 	params := map[string]interface{}{
 		"symbol":       symbol,
@@ -670,8 +389,8 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int, s
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
+	result, err := parseAsterOrderResult(body)
+	if err != nil {
 		return nil, err
 	}
 
@@ -679,7 +398,7 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int, s
 }
 
 // CloseLong 平多单
-func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *AsterTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -687,11 +406,8 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, "long"); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
@@ -708,27 +424,14 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 	limitPrice := price * 0.99
 
 	// 格式化价格和数量到正确精度
-	formattedPrice, err := t.formatPrice(symbol, limitPrice)
-	if err != nil {
-		return nil, err
-	}
-	formattedQty, err := t.formatQuantity(symbol, quantity)
+	inst, err := t.instruments.get(symbol)
 	if err != nil {
 		return nil, err
 	}
+	priceStr := inst.FormatPrice(limitPrice)
+	qtyStr := inst.FormatQuantity(quantity)
 
-	// 获取精度信息
-	prec, err := t.getPrecision(symbol)
-	if err != nil {
-		return nil, err
-	}
-
-	// 转换为字符串，使用正确的精度格式
-	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
-	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
-
-	log.Printf("  📏 精度处理: 价格 %.8f -> %s (精度=%d), 数量 %.8f -> %s (精度=%d)",
-		limitPrice, priceStr, prec.PricePrecision, quantity, qtyStr, prec.QuantityPrecision)
+	log.Printf("  📏 精度处理: 价格 %.8f -> %s, 数量 %.8f -> %s", limitPrice, priceStr, quantity, qtyStr)
 
 	params := map[string]interface{}{
 		"symbol":       symbol,
@@ -745,8 +448,8 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
+	result, err := parseAsterOrderResult(body)
+	if err != nil {
 		return nil, err
 	}
 
@@ -761,7 +464,7 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 }
 
 // CloseShort 平空单
-func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *AsterTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -769,12 +472,8 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				// Aster的GetPositions已经将空仓数量转换为正数，直接使用
-				quantity = pos["positionAmt"].(float64)
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, "short"); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
@@ -791,27 +490,14 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 	limitPrice := price * 1.01
 
 	// 格式化价格和数量到正确精度
-	formattedPrice, err := t.formatPrice(symbol, limitPrice)
-	if err != nil {
-		return nil, err
-	}
-	formattedQty, err := t.formatQuantity(symbol, quantity)
+	inst, err := t.instruments.get(symbol)
 	if err != nil {
 		return nil, err
 	}
+	priceStr := inst.FormatPrice(limitPrice)
+	qtyStr := inst.FormatQuantity(quantity)
 
-	// 获取精度信息
-	prec, err := t.getPrecision(symbol)
-	if err != nil {
-		return nil, err
-	}
-
-	// 转换为字符串，使用正确的精度格式
-	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
-	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
-
-	log.Printf("  📏 精度处理: 价格 %.8f -> %s (精度=%d), 数量 %.8f -> %s (精度=%d)",
-		limitPrice, priceStr, prec.PricePrecision, quantity, qtyStr, prec.QuantityPrecision)
+	log.Printf("  📏 精度处理: 价格 %.8f -> %s, 数量 %.8f -> %s", limitPrice, priceStr, quantity, qtyStr)
 
 	params := map[string]interface{}{
 		"symbol":       symbol,
@@ -828,8 +514,8 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
+	result, err := parseAsterOrderResult(body)
+	if err != nil {
 		return nil, err
 	}
 
@@ -892,6 +578,10 @@ func (t *AsterTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
 
 // SetLeverage 设置杠杆倍数
 func (t *AsterTrader) SetLeverage(symbol string, leverage int) error {
+	if inst, err := t.instruments.get(symbol); err == nil {
+		leverage = inst.ClampLeverage(leverage)
+	}
+
 	params := map[string]interface{}{
 		"symbol":   symbol,
 		"leverage": leverage,
@@ -904,16 +594,10 @@ func (t *AsterTrader) SetLeverage(symbol string, leverage int) error {
 // GetMarketPrice 获取市场价格
 func (t *AsterTrader) GetMarketPrice(symbol string) (float64, error) {
 	// 使用ticker接口获取当前价格
-	resp, err := t.client.Get(fmt.Sprintf("%s/fapi/v3/ticker/price?symbol=%s", t.baseURL, symbol))
+	body, err := t.rest.getPublic("/fapi/v3/ticker/price", url.Values{"symbol": {symbol}})
 	if err != nil {
 		return 0, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
-	}
 
 	var result map[string]interface{}
 	if err := json.Unmarshal(body, &result); err != nil {
@@ -936,24 +620,12 @@ func (t *AsterTrader) SetStopLoss(symbol string, positionSide string, quantity,
 	}
 
 	// 格式化价格和数量到正确精度
-	formattedPrice, err := t.formatPrice(symbol, stopPrice)
-	if err != nil {
-		return err
-	}
-	formattedQty, err := t.formatQuantity(symbol, quantity)
-	if err != nil {
-		return err
-	}
-
-	// 获取精度信息
-	prec, err := t.getPrecision(symbol)
+	inst, err := t.instruments.get(symbol)
 	if err != nil {
 		return err
 	}
-
-	// 转换为字符串，使用正确的精度格式
-	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
-	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
+	priceStr := inst.FormatPrice(stopPrice)
+	qtyStr := inst.FormatQuantity(quantity)
 
 	params := map[string]interface{}{
 		"symbol":       symbol,
@@ -977,24 +649,12 @@ func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity
 	}
 
 	// 格式化价格和数量到正确精度
-	formattedPrice, err := t.formatPrice(symbol, takeProfitPrice)
-	if err != nil {
-		return err
-	}
-	formattedQty, err := t.formatQuantity(symbol, quantity)
-	if err != nil {
-		return err
-	}
-
-	// 获取精度信息
-	prec, err := t.getPrecision(symbol)
+	inst, err := t.instruments.get(symbol)
 	if err != nil {
 		return err
 	}
-
-	// 转换为字符串，使用正确的精度格式
-	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
-	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
+	priceStr := inst.FormatPrice(takeProfitPrice)
+	qtyStr := inst.FormatQuantity(quantity)
 
 	params := map[string]interface{}{
 		"symbol":       symbol,
@@ -1003,6 +663,7 @@ func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity
 		"side":         side,
 		"stopPrice":    priceStr,
 		"quantity":     qtyStr,
+		"reduceOnly":   "true", // 单向持仓模式下只减仓（分批止盈各档不会反向开仓）
 		"timeInForce":  "GTC",
 	}
 
@@ -1012,6 +673,233 @@ func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity
 
 
 
+// PlaceEntryOrder 按订单类型开仓（与币安一致，post_only 使用 GTX）
+func (t *AsterTrader) PlaceEntryOrder(order EntryOrder) (*OrderResult, error) {
+	if err := order.validate(); err != nil {
+		return nil, err
+	}
+	if order.OrderType == OrderTypeMarket {
+		return order.openMarket(t)
+	}
+
+	if err := t.SetLeverage(order.Symbol, order.Leverage); err != nil {
+		return nil, fmt.Errorf("设置杠杆失败: %w", err)
+	}
+
+	inst, err := t.instruments.get(order.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	if inst.RoundQuantity(order.Quantity) <= 0 {
+		return nil, fmt.Errorf("开仓数量过小，按精度处理后为 0 (原始: %.8f)", order.Quantity)
+	}
+	priceStr := inst.FormatPrice(order.Price)
+	qtyStr := inst.FormatQuantity(order.Quantity)
+
+	side := "BUY"
+	if order.Side == "short" {
+		side = "SELL"
+	}
+	timeInForce := "GTC"
+	switch order.OrderType {
+	case OrderTypePostOnly:
+		timeInForce = "GTX"
+	case OrderTypeIOC:
+		timeInForce = "IOC"
+	}
+
+	params := map[string]interface{}{
+		"symbol":       order.Symbol,
+		"positionSide": "BOTH",
+		"type":         "LIMIT",
+		"side":         side,
+		"timeInForce":  timeInForce,
+		"quantity":     qtyStr,
+		"price":        priceStr,
+	}
+	body, err := t.request("POST", "/fapi/v3/order", params)
+	if err != nil {
+		return nil, fmt.Errorf("%s开仓失败: %w", OrderTypeLabel(order.OrderType), err)
+	}
+
+	result, err := parseAsterOrderResult(body)
+	if err != nil {
+		return nil, err
+	}
+	result.Status = asterOrderStatus(result.Status)
+	result.Price = inst.RoundPrice(order.Price)
+	result.Quantity = inst.RoundQuantity(order.Quantity)
+	log.Printf("✓ %s已提交: %s %s 价格: %s 数量: %s 状态: %s", OrderTypeLabel(order.OrderType), order.Symbol, order.Side, priceStr, qtyStr, result.Status)
+	return result, nil
+}
+
+// GetOrder 查询订单
+func (t *AsterTrader) GetOrder(symbol, orderID string) (*OrderResult, error) {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+	body, err := t.request("GET", "/fapi/v3/order", map[string]interface{}{
+		"symbol":  symbol,
+		"orderId": id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	var resp struct {
+		Price   string `json:"price"`
+		OrigQty string `json:"origQty"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("解析订单数据失败: %w", err)
+	}
+	result, err := parseAsterOrderResult(body)
+	if err != nil {
+		return nil, fmt.Errorf("解析订单数据失败: %w", err)
+	}
+	result.Status = asterOrderStatus(result.Status)
+	result.Price, _ = strconv.ParseFloat(resp.Price, 64)
+	result.Quantity, _ = strconv.ParseFloat(resp.OrigQty, 64)
+	return result, nil
+}
+
+// CancelOrder 撤销指定订单
+func (t *AsterTrader) CancelOrder(symbol, orderID string) error {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+	if _, err := t.request("DELETE", "/fapi/v3/order", map[string]interface{}{
+		"symbol":  symbol,
+		"orderId": id,
+	}); err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+	log.Printf("  ✓ 已撤销 %s 订单 %s", symbol, orderID)
+	return nil
+}
+
+// GetOpenOrders 查询未完成的挂单（止盈止损单同样在挂单列表中）
+func (t *AsterTrader) GetOpenOrders(symbol string) ([]OpenOrder, error) {
+	params := map[string]interface{}{}
+	if symbol != "" {
+		params["symbol"] = symbol
+	}
+	body, err := t.request("GET", "/fapi/v3/openOrders", params)
+	if err != nil {
+		return nil, fmt.Errorf("获取未完成订单失败: %w", err)
+	}
+
+	var raw []struct {
+		OrderID       int64  `json:"orderId"`
+		Symbol        string `json:"symbol"`
+		Side          string `json:"side"`
+		PositionSide  string `json:"positionSide"`
+		Type          string `json:"type"`
+		Price         string `json:"price"`
+		StopPrice     string `json:"stopPrice"`
+		OrigQty       string `json:"origQty"`
+		ExecutedQty   string `json:"executedQty"`
+		ReduceOnly    bool   `json:"reduceOnly"`
+		ClosePosition bool   `json:"closePosition"`
+		Time          int64  `json:"time"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("解析订单数据失败: %w", err)
+	}
+
+	orders := make([]OpenOrder, 0, len(raw))
+	for _, order := range raw {
+		open := OpenOrder{
+			OrderID:      formatOrderID(order.OrderID),
+			Symbol:       order.Symbol,
+			Side:         strings.ToLower(order.Side),
+			PositionSide: strings.ToLower(order.PositionSide),
+			Type:         openOrderTypeFromBinance(order.Type),
+			ReduceOnly:   order.ReduceOnly || order.ClosePosition,
+			Time:         time.UnixMilli(order.Time),
+		}
+		open.Price, _ = strconv.ParseFloat(order.Price, 64)
+		open.StopPrice, _ = strconv.ParseFloat(order.StopPrice, 64)
+		open.Quantity, _ = strconv.ParseFloat(order.OrigQty, 64)
+		open.ExecutedQty, _ = strconv.ParseFloat(order.ExecutedQty, 64)
+		open.IsAlgo = open.Type != OpenOrderTypeLimit && open.Type != OpenOrderTypeMarket
+		if open.PositionSide != "long" && open.PositionSide != "short" {
+			open.PositionSide = inferPositionSide(open.Side, open.ReduceOnly)
+		}
+		orders = append(orders, open)
+	}
+	return orders, nil
+}
+
+// GetUserTrades 获取成交明细（同一订单的多笔成交合并为一条，按时间升序）
+// Aster 使用单向持仓，有已实现盈亏的成交视为平仓
+func (t *AsterTrader) GetUserTrades(symbol string, startTime time.Time) ([]Fill, error) {
+	var fills []Fill
+
+	// 与币安一致，startTime/endTime 跨度不能超过7天，按窗口分段查询
+	for start := startTime; start.Before(time.Now()); start = start.Add(binanceHistoryWindow) {
+		body, err := t.request("GET", "/fapi/v3/userTrades", map[string]interface{}{
+			"symbol":    symbol,
+			"startTime": start.UnixMilli(),
+			"endTime":   start.Add(binanceHistoryWindow).UnixMilli(),
+			"limit":     1000,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("获取成交历史失败: %w", err)
+		}
+
+		var trades []struct {
+			OrderID     int64  `json:"orderId"`
+			Side        string `json:"side"`
+			Price       string `json:"price"`
+			Qty         string `json:"qty"`
+			RealizedPnl string `json:"realizedPnl"`
+			Commission  string `json:"commission"`
+			Time        int64  `json:"time"`
+		}
+		if err := json.Unmarshal(body, &trades); err != nil {
+			return nil, fmt.Errorf("解析成交历史失败: %w", err)
+		}
+
+		for _, trade := range trades {
+			price, _ := strconv.ParseFloat(trade.Price, 64)
+			qty, _ := strconv.ParseFloat(trade.Qty, 64)
+			fee, _ := strconv.ParseFloat(trade.Commission, 64)
+			pnl, _ := strconv.ParseFloat(trade.RealizedPnl, 64)
+
+			isClose := pnl != 0
+			fills = append(fills, Fill{
+				OrderID:     formatOrderID(trade.OrderID),
+				Symbol:      symbol,
+				Side:        inferPositionSide(strings.ToLower(trade.Side), isClose),
+				IsClose:     isClose,
+				Price:       price,
+				Quantity:    qty,
+				Fee:         fee,
+				RealizedPnL: pnl,
+				Time:        time.UnixMilli(trade.Time),
+			})
+		}
+	}
+	return mergeFills(fills), nil
+}
+
+// asterOrderStatus Aster订单状态（与币安一致）转换为统一状态
+func asterOrderStatus(status string) string {
+	switch status {
+	case "FILLED":
+		return OrderStatusFilled
+	case "PARTIALLY_FILLED":
+		return OrderStatusPartiallyFilled
+	case "NEW":
+		return OrderStatusOpen
+	default:
+		return OrderStatusCanceled
+	}
+}
+
 // CancelStopLossOrders 仅取消止损单（不影响止盈单）
 func (t *AsterTrader) CancelStopLossOrders(symbol string) error {
 	// 获取该币种的所有未完成订单
@@ -1197,9 +1085,9 @@ func (t *AsterTrader) CancelStopOrders(symbol string) error {
 
 // FormatQuantity 格式化数量（实现Trader接口）
 func (t *AsterTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
-	formatted, err := t.formatQuantity(symbol, quantity)
+	inst, err := t.instruments.get(symbol)
 	if err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("%v", formatted), nil
+	return inst.FormatQuantity(quantity), nil
 }
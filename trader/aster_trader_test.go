@@ -0,0 +1,154 @@
+package trader
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	testAsterUser   = "0x1111111111111111111111111111111111111111"
+	testAsterSigner = "0x2222222222222222222222222222222222222222"
+	testAsterKey    = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+)
+
+// newTestAsterTrader 创建连接到本地模拟服务的Aster交易器，handler 返回响应体（业务错误码按400返回）
+func newTestAsterTrader(t *testing.T, handler func(r *http.Request, params url.Values) string) *AsterTrader {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		params := r.URL.Query()
+		if len(raw) > 0 {
+			params, _ = url.ParseQuery(string(raw))
+		}
+		resp := handler(r, params)
+		if strings.Contains(resp, `"code":-`) {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_, _ = io.WriteString(w, resp)
+	}))
+	t.Cleanup(server.Close)
+
+	key, err := crypto.HexToECDSA(testAsterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trader := &AsterTrader{rest: newRESTClient("Aster", server.URL, newAsterSigner(testAsterUser, testAsterSigner, key))}
+	trader.rest.form = true
+	trader.rest.maxRetries = 1
+	return trader
+}
+
+// asterSignerAddress 从请求参数中恢复签名地址（按 newAsterSigner 的规则重新计算消息哈希）
+func asterSignerAddress(t *testing.T, params url.Values) common.Address {
+	t.Helper()
+	signed := make(map[string]string)
+	for k := range params {
+		switch k {
+		case "user", "signer", "signature", "nonce":
+		default:
+			signed[k] = params.Get(k)
+		}
+	}
+	jsonStr, _ := json.Marshal(signed)
+	nonce, _ := new(big.Int).SetString(params.Get("nonce"), 10)
+	arguments := abi.Arguments{{Type: mustABIType("string")}, {Type: mustABIType("address")}, {Type: mustABIType("address")}, {Type: mustABIType("uint256")}}
+	packed, err := arguments.Pack(string(jsonStr), common.HexToAddress(params.Get("user")), common.HexToAddress(params.Get("signer")), nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := crypto.Keccak256(packed)
+	msgHash := crypto.Keccak256Hash([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(hash), hash)))
+
+	sig, err := hex.DecodeString(strings.TrimPrefix(params.Get("signature"), "0x"))
+	if err != nil || len(sig) != 65 {
+		t.Fatalf("签名格式错误: %s", params.Get("signature"))
+	}
+	sig[64] -= 27
+	pub, err := crypto.SigToPub(msgHash.Bytes(), sig)
+	if err != nil {
+		t.Fatalf("恢复签名公钥失败: %v", err)
+	}
+	return crypto.PubkeyToAddress(*pub)
+}
+
+func TestAsterRequestSigning(t *testing.T) {
+	var gotMethod, gotQuery, gotContentType string
+	var gotParams url.Values
+	trader := newTestAsterTrader(t, func(r *http.Request, params url.Values) string {
+		gotMethod, gotQuery, gotContentType, gotParams = r.Method, r.URL.RawQuery, r.Header.Get("Content-Type"), params
+		return `{"orderId":1}`
+	})
+
+	if _, err := trader.request("POST", "/fapi/v3/order", map[string]interface{}{"symbol": "BTCUSDT", "quantity": 0.5}); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if gotMethod != http.MethodPost || gotQuery != "" || gotContentType != "application/x-www-form-urlencoded" {
+		t.Errorf("POST参数应以表单放在请求体: method=%s query=%q content-type=%s", gotMethod, gotQuery, gotContentType)
+	}
+	if gotParams.Get("symbol") != "BTCUSDT" || gotParams.Get("quantity") != "0.5" || gotParams.Get("recvWindow") != asterRecvWindow {
+		t.Errorf("请求参数 = %v", gotParams)
+	}
+	if gotParams.Get("user") != testAsterUser || gotParams.Get("signer") != testAsterSigner {
+		t.Errorf("签名地址参数 = %s / %s", gotParams.Get("user"), gotParams.Get("signer"))
+	}
+	if _, err := strconv.ParseInt(gotParams.Get("timestamp"), 10, 64); err != nil {
+		t.Errorf("timestamp = %q", gotParams.Get("timestamp"))
+	}
+
+	key, _ := crypto.HexToECDSA(testAsterKey)
+	if addr := asterSignerAddress(t, gotParams); addr != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("签名恢复出的地址 %s 与API钱包私钥不一致", addr.Hex())
+	}
+}
+
+func TestAsterGetParamsInQuery(t *testing.T) {
+	var gotQuery url.Values
+	trader := newTestAsterTrader(t, func(r *http.Request, params url.Values) string {
+		gotQuery = r.URL.Query()
+		return `[]`
+	})
+
+	if _, err := trader.request("GET", "/fapi/v3/openOrders", map[string]interface{}{"symbol": "ETHUSDT"}); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if gotQuery.Get("symbol") != "ETHUSDT" || gotQuery.Get("signature") == "" {
+		t.Errorf("GET参数和签名应在查询字符串中: %v", gotQuery)
+	}
+}
+
+func TestAsterTimestampRejectedResync(t *testing.T) {
+	timeCalls, orderCalls := 0, 0
+	trader := newTestAsterTrader(t, func(r *http.Request, params url.Values) string {
+		if r.URL.Path == "/fapi/v3/time" {
+			timeCalls++
+			return `{"serverTime":1700000000000}`
+		}
+		orderCalls++
+		if orderCalls == 1 {
+			return `{"code":-1021,"msg":"Timestamp for this request is outside of the recvWindow."}`
+		}
+		return `{"orderId":1}`
+	})
+	trader.rest.timestampRejected = isBinanceTimestampRejected
+	trader.rest.clock.sync("Aster", trader.fetchServerTime)
+
+	if _, err := trader.request("POST", "/fapi/v3/order", map[string]interface{}{"symbol": "BTCUSDT"}); err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if timeCalls != 2 || orderCalls != 2 {
+		t.Errorf("应同步2次服务器时间、发送2次请求，实际 %d/%d", timeCalls, orderCalls)
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"nofx/config"
 	"nofx/decision"
 	"nofx/logger"
 	"nofx/market"
 	"nofx/mcp"
 	"nofx/pool"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -24,7 +26,7 @@ type AutoTraderConfig struct {
 	AIModel string // AI模型: "qwen" 或 "deepseek"
 
 	// 交易平台选择
-	Exchange string // "binance", "hyperliquid", "aster" 或 "okx"
+	Exchange string // "binance", "hyperliquid", "aster", "okx", "bybit", "bitget", "gate" 或 "paper"（模拟盘）
 
 	// 币安API配置
 	BinanceAPIKey    string
@@ -46,7 +48,37 @@ type AutoTraderConfig struct {
 	OKXPassphrase string
 	OKXTestnet    bool
 
+	// Bybit配置
+	BybitAPIKey    string
+	BybitSecretKey string
+	BybitTestnet   bool
+
+	// Bitget配置
+	BitgetAPIKey     string
+	BitgetSecretKey  string
+	BitgetPassphrase string
+	BitgetTestnet    bool // 模拟盘（paptrading）
+
+	// Gate.io配置
+	GateAPIKey    string
+	GateSecretKey string
+	GateTestnet   bool
+
+	// 模拟盘配置
+	PaperPriceExchange string // 模拟盘行情来源（"binance"、"okx"、"bybit"、"bitget" 或 "gate"，默认binance）
+
 	CoinPoolAPIURL string
+	OITopAPIURL    string
+	SignalSource   *pool.SignalSource // 候选币种信号源（为空时按上面两个URL创建交易员独立的信号源）
+
+	// 可插拔信号源（配置后优先于默认币种和AI500+OI Top，按权重合并）
+	SignalProviders []pool.ProviderConfig
+
+	// K线周期（按周期从短到长排序，为空时使用默认 3m,4h）
+	Timeframes []string
+
+	// 注册指标（为空时使用提示词模板声明的指标）
+	Indicators []market.IndicatorRequest
 
 	// AI配置
 	UseQwen       bool
@@ -57,6 +89,7 @@ type AutoTraderConfig struct {
 	GPTsKey       string // OpenAI GPTs API密钥 (Assistant API)
 	GPTsAssistantID string // GPTs Assistant ID
 	GPTsThreadID  string // GPTs Thread ID (可选，为空则每次创建新thread)
+	AnthropicKey  string // Anthropic Claude API密钥 (Messages API)
 
 	// 自定义AI API配置
 	CustomAPIURL    string
@@ -73,14 +106,31 @@ type AutoTraderConfig struct {
 	BTCETHLeverage  int // BTC和ETH的杠杆倍数
 	AltcoinLeverage int // 山寨币的杠杆倍数
 
-	// 风险控制（仅作为提示，AI可自主决定）
-	MaxDailyLoss    float64       // 最大日亏损百分比（提示）
-	MaxDrawdown     float64       // 最大回撤百分比（提示）
-	StopTradingTime time.Duration // 触发风控后暂停时长
+	// 风险控制（硬性熔断，超过阈值后暂停交易，<=0 表示不启用）
+	MaxDailyLoss      float64       // 最大日亏损百分比（已实现+未实现，相对当日UTC零点起始净值）
+	MaxDrawdown       float64       // 最大回撤百分比（相对峰值净值）
+	StopTradingTime   time.Duration // 触发风控后暂停时长
+	FlattenOnRiskTrip bool          // 触发熔断时是否强制平掉所有持仓
+
+	// 开仓前风控（拒绝或缩减超限的开仓决策）
+	RiskLimits decision.RiskLimits
+
+	// 保本止损（浮盈达到阈值后自动将止损移到扣除手续费的保本价）
+	BreakEven BreakEvenConfig
+
+	// AI备用链（主模型失败或熔断时按顺序切换，为空表示只使用主模型）
+	AIFallbacks []AIFallbackConfig
+
+	// AI模型价格表（美元/百万token，为空时使用内置价格表）
+	AIPriceTable mcp.PriceTable
 
 	// 仓位模式
 	IsCrossMargin bool // true=全仓模式, false=逐仓模式
 
+	// 限价开仓挂单（AI给出 order_type=limit/post_only 时生效）
+	EntryOrderTimeout  time.Duration // 挂单超时时间（默认10分钟）
+	EntryOrderReprices int           // 超时后按盘口重新定价的次数（0使用默认值1次，<0表示超时直接撤单）
+
 	// 币种配置
 	DefaultCoins []string // 默认币种列表（从数据库获取）
 	TradingCoins []string // 实际交易币种列表
@@ -97,7 +147,7 @@ type AutoTrader struct {
 	exchange              string // 交易平台名称
 	config                AutoTraderConfig
 	trader                Trader // 使用Trader接口（支持多平台）
-	mcpClient             *mcp.Client
+	mcpClient             mcp.AIClient // 主模型或AI备用链
 	decisionLogger        *logger.DecisionLogger // 决策日志记录器
 	initialBalance        float64
 	dailyPnL              float64
@@ -117,8 +167,37 @@ type AutoTrader struct {
 	peakPnLCache      map[string]float64 	 // 最高收益缓存 (symbol -> 峰值盈亏百分比)
 	peakPnLCacheMutex sync.RWMutex // 缓存读写锁
 	lastBalanceSyncTime   time.Time        // 上次余额同步时间
+	// 未成交的限价开仓挂单（symbol_side -> 挂单，由 pendingMutex 保护）
+	pendingEntries map[string]*pendingEntry
+	pendingMutex   sync.Mutex
+	// 持仓跟踪止损（symbol_side -> 跟踪止损，由 trailingMutex 保护，参数和本地极值同步写入交易台账）
+	trailingStops map[string]*trailingStop
+	trailingMutex sync.Mutex
+	// 两个AI周期之间自动执行的动作（保本止损、风控熔断），合并进下一个周期的决策记录
+	deferred deferredRecord
+	// 账户推送缓存（交易所支持私有推送时由推送维护，否则各处回退到REST查询）
+	accountState *accountState
+	// 串行化下单执行与推送触发的台账更新（避免同一笔平仓被重复记入台账）
+	tradeMutex sync.Mutex
+	// 风控熔断状态（由 riskMutex 保护，stopUntil/dailyPnL/lastResetTime 同样受其保护）
+	riskMutex      sync.RWMutex
+	dayStartEquity float64          // 当日起始净值（日盈亏基准）
+	peakEquity     float64          // 峰值净值（回撤基准）
+	lastEquity     float64          // 最近一次检查时的净值
+	riskTripped    bool             // 当前暂停是否由风控熔断触发
+	riskTripCount  int              // 熔断触发次数
+	lastRiskTrip   *logger.RiskTrip // 最近一次熔断记录
 	database              interface{}      // 数据库引用（用于自动更新余额）
 	userID                string           // 用户ID
+
+	ledger TradeLedger // 交易台账（数据库支持时启用）
+
+	usageStore AIUsageStore   // AI用量表（数据库支持时启用）
+	riskStore  RiskStateStore // 风控熔断状态（数据库支持时启用，重启后恢复）
+	priceTable mcp.PriceTable // AI模型价格表（用于估算调用费用）
+
+	signalSource *pool.SignalSource // 候选币种信号源（每个交易员独立）
+	providers    []pool.WeightedProvider // 交易员配置的可插拔信号源
 }
 
 // NewAutoTrader 创建自动交易器
@@ -138,6 +217,13 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		}
 	}
 
+	if config.EntryOrderTimeout <= 0 {
+		config.EntryOrderTimeout = defaultEntryOrderTimeout
+	}
+	if config.EntryOrderReprices == 0 {
+		config.EntryOrderReprices = defaultEntryOrderReprices
+	}
+
 	mcpClient := mcp.New()
 
 	// 初始化AI
@@ -161,6 +247,14 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		} else {
 			log.Printf("🤖 [%s] 使用OpenAI ChatGPT", config.Name)
 		}
+	} else if config.AIModel == "anthropic" {
+		// 使用Anthropic Claude (原生 Messages API，支持自定义URL和Model)
+		mcpClient.SetAnthropicAPIKey(config.AnthropicKey, config.CustomAPIURL, config.CustomModelName)
+		if config.CustomAPIURL != "" || config.CustomModelName != "" {
+			log.Printf("🤖 [%s] 使用Anthropic Claude (自定义URL: %s, 模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
+		} else {
+			log.Printf("🤖 [%s] 使用Anthropic Claude", config.Name)
+		}
 	} else if config.AIModel == "gpts" {
 		// 使用OpenAI GPTs (Assistant API)
 		// custom_model_name 存储 Assistant ID
@@ -195,9 +289,10 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		}
 	}
 
-	// 初始化币种池API
-	if config.CoinPoolAPIURL != "" {
-		pool.SetCoinPoolAPI(config.CoinPoolAPIURL)
+	// 初始化交易员独立的信号源（避免多个交易员互相覆盖全局币种池配置）
+	signalSource := config.SignalSource
+	if signalSource == nil {
+		signalSource = newSignalSource(config)
 	}
 
 	// 设置默认交易平台
@@ -216,6 +311,23 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 	}
 	log.Printf("📊 [%s] 仓位模式: %s", config.Name, marginModeStr)
 
+	// 决策日志目录（使用trader ID创建独立目录，模拟盘账户状态也保存在此目录）
+	// V1.75版本：支持环境变量配置日志路径（用于 Hugging Face 等云平台部署）
+	baseLogDir := os.Getenv("NOFX_LOG_DIR")
+	if baseLogDir == "" {
+		// Hugging Face Spaces 使用持久化存储
+		if hfDataPath := os.Getenv("HF_HOME"); hfDataPath != "" {
+			if _, err := os.Stat("/data"); err == nil {
+				baseLogDir = "/data/decision_logs"
+				log.Printf("📦 检测到 Hugging Face 环境，使用持久化存储: %s", baseLogDir)
+			}
+		}
+		if baseLogDir == "" {
+			baseLogDir = "decision_logs"
+		}
+	}
+	logDir := fmt.Sprintf("%s/%s", baseLogDir, config.ID)
+
 	switch config.Exchange {
 	case "binance":
 		log.Printf("🏦 [%s] 使用币安合约交易", config.Name)
@@ -235,6 +347,21 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 	case "okx":
 		log.Printf("🏦 [%s] 使用OKX合约交易", config.Name)
 		trader = NewOKXTrader(config.OKXAPIKey, config.OKXSecretKey, config.OKXPassphrase, config.OKXTestnet)
+	case "bybit":
+		log.Printf("🏦 [%s] 使用Bybit合约交易", config.Name)
+		trader = NewBybitTrader(config.BybitAPIKey, config.BybitSecretKey, config.BybitTestnet)
+	case "bitget":
+		log.Printf("🏦 [%s] 使用Bitget合约交易", config.Name)
+		trader = NewBitgetTrader(config.BitgetAPIKey, config.BitgetSecretKey, config.BitgetPassphrase, config.BitgetTestnet)
+	case "gate":
+		log.Printf("🏦 [%s] 使用Gate.io合约交易", config.Name)
+		trader = NewGateTrader(config.GateAPIKey, config.GateSecretKey, config.GateTestnet)
+	case "paper":
+		log.Printf("🏦 [%s] 使用模拟盘交易（行情来源: %s）", config.Name, config.PaperPriceExchange)
+		trader, err = NewPaperTrader(config.InitialBalance, PaperStateFile(logDir), config.PaperPriceExchange)
+		if err != nil {
+			return nil, fmt.Errorf("初始化模拟盘交易器失败: %w", err)
+		}
 	default:
 		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
 	}
@@ -245,21 +372,6 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 	}
 
 	// 初始化决策日志记录器（使用trader ID创建独立目录）
-	// V1.75版本：支持环境变量配置日志路径（用于 Hugging Face 等云平台部署）
-	baseLogDir := os.Getenv("NOFX_LOG_DIR")
-	if baseLogDir == "" {
-		// Hugging Face Spaces 使用持久化存储
-		if hfDataPath := os.Getenv("HF_HOME"); hfDataPath != "" {
-			if _, err := os.Stat("/data"); err == nil {
-				baseLogDir = "/data/decision_logs"
-				log.Printf("📦 检测到 Hugging Face 环境，使用持久化存储: %s", baseLogDir)
-			}
-		}
-		if baseLogDir == "" {
-			baseLogDir = "decision_logs"
-		}
-	}
-	logDir := fmt.Sprintf("%s/%s", baseLogDir, config.ID)
 	decisionLogger := logger.NewDecisionLogger(logDir)
 
 	// 设置默认系统提示词模板
@@ -269,24 +381,27 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		systemPromptTemplate = "adaptive"
 	}
 
-	return &AutoTrader{
+	at := &AutoTrader{
 		id:                    config.ID,
 		name:                  config.Name,
 		aiModel:               config.AIModel,
 		exchange:              config.Exchange,
 		config:                config,
 		trader:                trader,
-		mcpClient:             mcpClient,
+		mcpClient:             buildAIClient(config, mcpClient),
 		decisionLogger:        decisionLogger,
 		initialBalance:        config.InitialBalance,
 		systemPromptTemplate:  systemPromptTemplate,
 		defaultCoins:          config.DefaultCoins,
 		tradingCoins:          config.TradingCoins,
-		lastResetTime:         time.Now(),
+		lastResetTime:         riskDayStart(time.Now()),
 		startTime:             time.Now(),
 		callCount:             0,
 		isRunning:             false,
 		positionFirstSeenTime: make(map[string]int64),
+		pendingEntries:        make(map[string]*pendingEntry),
+		trailingStops:         make(map[string]*trailingStop),
+		accountState:          newAccountState(),
 		stopMonitorCh:         make(chan struct{}),
 		monitorWg:             sync.WaitGroup{},
 		peakPnLCache:          make(map[string]float64),
@@ -294,7 +409,27 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		lastBalanceSyncTime:   time.Now(), // 初始化为当前时间
 		database:              database,
 		userID:                userID,
-	}, nil
+		signalSource:          signalSource,
+	}
+	// 本地筛选器基于币安全市场行情，只保留当前交易所上市的币种
+	at.providers = pool.BuildProviders(config.SignalProviders, signalSource, func(symbol string) bool {
+		return instrumentListed(at.trader, symbol)
+	})
+	if ledger, ok := database.(TradeLedger); ok && ledger != nil {
+		at.ledger = ledger
+	}
+	if usageStore, ok := database.(AIUsageStore); ok && usageStore != nil {
+		at.usageStore = usageStore
+	}
+	if riskStore, ok := database.(RiskStateStore); ok && riskStore != nil {
+		at.riskStore = riskStore
+		at.restoreRiskState()
+	}
+	at.priceTable = config.AIPriceTable
+	if at.priceTable == nil {
+		at.priceTable = mcp.DefaultPriceTable()
+	}
+	return at, nil
 }
 
 // Run 运行自动交易主循环
@@ -305,8 +440,14 @@ func (at *AutoTrader) Run() error {
 	log.Printf("⚙️  扫描间隔: %v", at.config.ScanInterval)
 	log.Println("🤖 AI将全权决定杠杆、仓位大小、止损止盈等参数")
 
+	// 恢复上次运行未处理完的开仓挂单
+	at.restorePendingEntries()
 	// 启动回撤监控
 	at.startDrawdownMonitor()
+	// 启动本地跟踪止损引擎（交易所不支持原生跟踪单时使用）
+	at.startTrailingMonitor()
+	// 启动账户实时推送（交易所支持时）
+	at.startAccountStream()
 
 	ticker := time.NewTicker(at.config.ScanInterval)
 	defer ticker.Stop()
@@ -333,9 +474,21 @@ func (at *AutoTrader) Stop() {
 	at.isRunning = false
 	close(at.stopMonitorCh) // 通知监控goroutine停止
 	at.monitorWg.Wait()     // 等待监控goroutine结束
+	at.cancelPendingEntries(nil, "交易员停止")
 	log.Println("⏹ 自动交易系统停止")
 }
 
+// Close 释放交易员占用的资源（模拟盘触发单监控、决策日志数据库），交易员被删除或进程退出时在 Stop 之后调用
+func (at *AutoTrader) Close() {
+	if paper, ok := at.trader.(*PaperTrader); ok {
+		paper.Close()
+	}
+	at.flushDeferredRecord()
+	if err := at.decisionLogger.Close(); err != nil {
+		log.Printf("⚠️ [%s] 关闭决策日志失败: %v", at.name, err)
+	}
+}
+
 // autoSyncBalanceIfNeeded 自动同步余额（每10分钟检查一次，变化>5%才更新）
 func (at *AutoTrader) autoSyncBalanceIfNeeded() {
 	// 距离上次同步不足10分钟，跳过
@@ -355,12 +508,10 @@ func (at *AutoTrader) autoSyncBalanceIfNeeded() {
 
 	// 提取可用余额
 	var actualBalance float64
-	if availableBalance, ok := balanceInfo["available_balance"].(float64); ok && availableBalance > 0 {
-		actualBalance = availableBalance
-	} else if availableBalance, ok := balanceInfo["availableBalance"].(float64); ok && availableBalance > 0 {
-		actualBalance = availableBalance
-	} else if totalBalance, ok := balanceInfo["balance"].(float64); ok && totalBalance > 0 {
-		actualBalance = totalBalance
+	if balanceInfo.AvailableBalance > 0 {
+		actualBalance = balanceInfo.AvailableBalance
+	} else if balanceInfo.TotalWalletBalance > 0 {
+		actualBalance = balanceInfo.TotalWalletBalance
 	} else {
 		log.Printf("⚠️ [%s] 无法提取可用余额", at.name)
 		at.lastBalanceSyncTime = time.Now()
@@ -444,23 +595,24 @@ func (at *AutoTrader) runCycle() error {
 		ExecutionLog: []string{},
 		Success:      true,
 	}
+	// 合并上个周期之后由监控goroutine自动执行的动作（保本止损等）
+	at.mergeDeferredRecord(record)
+
+	// 0. 跟踪上个周期留下的限价开仓挂单（成交后挂止盈止损，超时重新定价或撤单）
+	at.processPendingEntries(record)
 
 	// 1. 检查是否需要停止交易
-	if time.Now().Before(at.stopUntil) {
-		remaining := at.stopUntil.Sub(time.Now())
+	if stopUntil := at.getStopUntil(); time.Now().Before(stopUntil) {
+		remaining := stopUntil.Sub(time.Now())
 		log.Printf("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
+		at.cancelPendingEntries(record, "风控暂停交易")
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", remaining.Minutes())
 		at.decisionLogger.LogDecision(record)
 		return nil
 	}
 
-	// 2. 重置日盈亏（每天重置）
-	if time.Since(at.lastResetTime) > 24*time.Hour {
-		at.dailyPnL = 0
-		at.lastResetTime = time.Now()
-		log.Println("📅 日盈亏已重置")
-	}
+	// 2. 日盈亏每个UTC日零点重置（由风控熔断器在 updateRiskState 中统一处理）
 
 	// 3. 自动同步余额（每10分钟检查一次，充值/提现后自动更新）
 	at.autoSyncBalanceIfNeeded()
@@ -483,6 +635,13 @@ func (at *AutoTrader) runCycle() error {
 		MarginUsedPct:         ctx.Account.MarginUsedPct,
 	}
 
+	// 风控熔断检查：日亏损或回撤超过阈值时暂停交易（可选强制平仓），本周期不再请求AI
+	if trip := at.updateRiskState(ctx.Account.TotalEquity); trip != nil {
+		at.handleRiskTrip(trip, record)
+		at.decisionLogger.LogDecision(record)
+		return nil
+	}
+
 	// 保存持仓快照
 	for _, pos := range ctx.Positions {
 		record.Positions = append(record.Positions, logger.PositionSnapshot{
@@ -497,6 +656,9 @@ func (at *AutoTrader) runCycle() error {
 		})
 	}
 
+	// 保本止损：浮盈达到阈值的持仓把止损移到扣除手续费的保本价
+	at.manageBreakEvenStops(ctx.Positions, record)
+
 						log.Print(strings.Repeat("=", 70))
 	for _, coin := range ctx.CandidateCoins {
 		record.CandidateCoins = append(record.CandidateCoins, coin.Symbol)
@@ -509,6 +671,12 @@ func (at *AutoTrader) runCycle() error {
 	log.Printf("🤖 正在请求AI分析并决策... [模板: %s]", at.systemPromptTemplate)
 	decision, err := decision.GetFullDecisionWithCustomPrompt(ctx, at.mcpClient, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
 
+	// 记录实际应答的AI提供商（配置了备用链时可能不是主模型）
+	if err == nil || (decision != nil && decision.CoTTrace != "") {
+		record.AIProvider = at.aiProviderName()
+		at.recordAIUsage(record)
+	}
+
 	// 即使有错误，也保存思维链、决策和输入prompt（用于debug）
 	if decision != nil {
 		record.SystemPrompt = decision.SystemPrompt // 保存系统提示词
@@ -570,6 +738,9 @@ func (at *AutoTrader) runCycle() error {
 		if d.Action == "open_long" || d.Action == "open_short" {
 			log.Printf("      杠杆: %dx | 仓位价值: %.2f USDT | 止损: %.4f | 止盈: %.4f",
 				d.Leverage, d.PositionSizeUSD, d.StopLoss, d.TakeProfit)
+			if !d.IsMarketOrder() {
+				log.Printf("      委托: %s @ %.4f", OrderTypeLabel(d.OrderType), d.EntryPrice)
+			}
 			log.Printf("      信心度: %d | 理由: %s", d.Confidence, d.Reasoning)
 		} else if d.Action == "close_long" || d.Action == "close_short" {
 			log.Printf("      理由: %s", d.Reasoning)
@@ -596,14 +767,14 @@ func (at *AutoTrader) runCycle() error {
 
 	// V1.70版本：执行决策并记录结果（增强错误日志）
 	for _, d := range sortedDecisions {
-		log.Printf("\n" + strings.Repeat("-", 70))
+		log.Print("\n" + strings.Repeat("-", 70))
 		log.Printf("🔄 开始执行决策: %s %s", d.Symbol, d.Action)
 		if d.Action == "open_long" || d.Action == "open_short" {
 			log.Printf("   杠杆: %dx | 仓位价值: %.2f USDT | 止损: %.4f | 止盈: %.4f",
 				d.Leverage, d.PositionSizeUSD, d.StopLoss, d.TakeProfit)
 			log.Printf("   决策理由: %s", d.Reasoning)
 		}
-		log.Printf(strings.Repeat("-", 70))
+		log.Print(strings.Repeat("-", 70))
 		
 		actionRecord := logger.DecisionAction{
 			Action:    d.Action,
@@ -614,24 +785,42 @@ func (at *AutoTrader) runCycle() error {
 			Timestamp: time.Now(),
 			Success:   false,
 		}
+		for _, rc := range d.RiskChecks {
+			actionRecord.RiskChecks = append(actionRecord.RiskChecks, logger.RiskCheck{
+				Rule:    rc.Rule,
+				Action:  rc.Action,
+				Message: rc.Message,
+				Limit:   rc.Limit,
+				Value:   rc.Value,
+			})
+		}
+
+		// 开仓前风控拒绝：不执行，记录拒绝原因
+		if rejection := d.RiskRejection(); rejection != nil {
+			log.Printf("🛡️ 风控拒绝决策: %s %s（%s）", d.Symbol, d.Action, rejection.Message)
+			actionRecord.Error = fmt.Sprintf("风控拒绝[%s]: %s", rejection.Rule, rejection.Message)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🛡️ %s %s 被风控拒绝: %s", d.Symbol, d.Action, rejection.Message))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
 
 		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
 			// V1.70版本：增强错误日志输出
-			log.Printf("\n" + strings.Repeat("!", 70))
+			log.Print("\n" + strings.Repeat("!", 70))
 			log.Printf("❌ 执行决策失败: %s %s", d.Symbol, d.Action)
 			log.Printf("❌ 错误信息: %v", err)
-			log.Printf(strings.Repeat("!", 70) + "\n")
+			log.Print(strings.Repeat("!", 70) + "\n")
 			
 			actionRecord.Error = err.Error()
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
 			record.Success = false // 标记整个记录为失败
 		} else {
-			log.Printf("\n" + strings.Repeat("✓", 70))
+			log.Print("\n" + strings.Repeat("✓", 70))
 			log.Printf("✓ 执行决策成功: %s %s", d.Symbol, d.Action)
 			if d.Action == "open_long" || d.Action == "open_short" {
 				log.Printf("✓ 订单ID: %v | 数量: %.8f", actionRecord.OrderID, actionRecord.Quantity)
 			}
-			log.Printf(strings.Repeat("✓", 70) + "\n")
+			log.Print(strings.Repeat("✓", 70) + "\n")
 			
 			actionRecord.Success = true
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
@@ -653,31 +842,21 @@ func (at *AutoTrader) runCycle() error {
 // buildTradingContext 构建交易上下文
 func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	// 1. 获取账户信息
-	balance, err := at.trader.GetBalance()
+	balance, err := at.getBalance()
 	if err != nil {
 		return nil, fmt.Errorf("获取账户余额失败: %w", err)
 	}
 
 	// 获取账户字段
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
-	availableBalance := 0.0
-
-	if wallet, ok := balance["totalWalletBalance"].(float64); ok {
-		totalWalletBalance = wallet
-	}
-	if unrealized, ok := balance["totalUnrealizedProfit"].(float64); ok {
-		totalUnrealizedProfit = unrealized
-	}
-	if avail, ok := balance["availableBalance"].(float64); ok {
-		availableBalance = avail
-	}
+	totalWalletBalance := balance.TotalWalletBalance
+	totalUnrealizedProfit := balance.TotalUnrealizedProfit
+	availableBalance := balance.AvailableBalance
 
 	// Total Equity = 钱包余额 + 未实现盈亏
 	totalEquity := totalWalletBalance + totalUnrealizedProfit
 
 	// 2. 获取持仓信息
-	positions, err := at.trader.GetPositions()
+	positions, err := at.getPositions()
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
@@ -689,22 +868,19 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	currentPositionKeys := make(map[string]bool)
 
 	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity // 空仓数量为负，转为正数
-		}
+		symbol := pos.Symbol
+		side := pos.Side
+		entryPrice := pos.EntryPrice
+		markPrice := pos.MarkPrice
+		quantity := pos.Quantity
 
 		// 跳过已平仓的持仓（quantity = 0），防止"幽灵持仓"传递给AI
 		if quantity == 0 {
 			continue
 		}
 
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		liquidationPrice := pos["liquidationPrice"].(float64)
+		unrealizedPnl := pos.UnrealizedProfit
+		liquidationPrice := pos.LiquidationPrice
 
 		// 计算盈亏百分比
 		pnlPct := 0.0
@@ -716,8 +892,8 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 
 		// 计算占用保证金（估算）
 		leverage := 10 // 默认值，实际应该从持仓信息获取
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
+		if pos.Leverage > 0 {
+			leverage = pos.Leverage
 		}
 		marginUsed := (quantity * markPrice) / float64(leverage)
 		totalMarginUsed += marginUsed
@@ -731,7 +907,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		}
 		updateTime := at.positionFirstSeenTime[posKey]
 
-		positionInfos = append(positionInfos, decision.PositionInfo{
+		info := decision.PositionInfo{
 			Symbol:           symbol,
 			Side:             side,
 			EntryPrice:       entryPrice,
@@ -743,15 +919,21 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			LiquidationPrice: liquidationPrice,
 			MarginUsed:       marginUsed,
 			UpdateTime:       updateTime,
-		})
+		}
+		at.trailingStopInfo(&info)
+		positionInfos = append(positionInfos, info)
 	}
 
+	// 交易台账对账（补记交易所侧止盈止损/强平）
+	at.reconcileTradeLedger(positions)
+
 	// 清理已平仓的持仓记录
 	for key := range at.positionFirstSeenTime {
 		if !currentPositionKeys[key] {
 			delete(at.positionFirstSeenTime, key)
 		}
 	}
+	at.pruneTrailingStops(currentPositionKeys)
 
 	// 3. 获取交易员的候选币种池
 	candidateCoins, err := at.getCandidateCoins()
@@ -771,9 +953,8 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		marginUsedPct = (totalMarginUsed / totalEquity) * 100
 	}
 
-	// 5. 分析历史表现（最近100个周期，避免长期持仓的交易记录丢失）
-	// 假设每3分钟一个周期，100个周期 = 5小时，足够覆盖大部分交易
-	performance, err := at.decisionLogger.AnalyzePerformance(100)
+	// 5. 分析历史表现（优先使用交易台账，否则从最近100个周期的决策日志重建）
+	performance, err := at.AnalyzePerformance()
 	if err != nil {
 		log.Printf("⚠️  分析历史表现失败: %v", err)
 		// 不影响主流程，继续执行（但设置performance为nil以避免传递错误数据）
@@ -823,8 +1004,9 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	// 7. 构建上下文（使用北京时间）
 	beijingTZ, _ := time.LoadLocation("Asia/Shanghai")
 	beijingTime := time.Now().In(beijingTZ)
+	marketExchange := at.marketDataExchange()
 	ctx := &decision.Context{
-		Exchange: at.exchange, // 设置交易所ID
+		Exchange: marketExchange, // 设置交易所ID
 		CurrentTime:     beijingTime.Format("2006-01-02 15:04:05"),
 		RuntimeMinutes:  int(time.Since(at.startTime).Minutes()),
 		CallCount:       at.callCount,
@@ -840,9 +1022,15 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			PositionCount:    len(positionInfos),
 		},
 		Positions:       positionInfos,
+		PendingOrders:   at.pendingOrderInfos(),
 		CandidateCoins:  candidateCoins,
 		Performance:     performance,      // 添加历史表现分析
 		HistoryDecisions: historyDecisions, // 添加历史决策记录
+		RiskLimits:      at.config.RiskLimits,
+		MaxLeverage:     at.exchangeMaxLeverage(candidateCoins, positionInfos),
+		SignalSource:    at.signalSource,
+		Timeframes:      at.config.Timeframes,
+		Indicators:      at.config.Indicators,
 	}
 
 	return ctx, nil
@@ -850,6 +1038,11 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 
 // executeDecisionWithRecord 执行AI决策并记录详细信息
 func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	at.tradeMutex.Lock()
+	defer at.tradeMutex.Unlock()
+
+	at.roundDecisionPrices(decision)
+
 	switch decision.Action {
 	case "open_long":
 		return at.executeOpenLongWithRecord(decision, actionRecord)
@@ -865,6 +1058,8 @@ func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, act
 		return at.executeUpdateTakeProfitWithRecord(decision, actionRecord)
 	case "partial_close":
 		return at.executePartialCloseWithRecord(decision, actionRecord)
+	case "set_trailing_stop":
+		return at.executeSetTrailingStopWithRecord(decision, actionRecord)
 	case "hold", "wait":
 		// 无需执行，仅记录
 		return nil
@@ -873,6 +1068,81 @@ func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, act
 	}
 }
 
+// exchangeMaxLeverage 获取候选币种和持仓币种的交易所最大杠杆（未知的币种不填）
+func (at *AutoTrader) exchangeMaxLeverage(candidates []decision.CandidateCoin, positions []decision.PositionInfo) map[string]int {
+	result := make(map[string]int)
+	add := func(symbol string) {
+		if _, ok := result[symbol]; ok {
+			return
+		}
+		inst, err := at.trader.GetInstrument(symbol)
+		if err != nil || inst.MaxLeverage <= 0 {
+			return
+		}
+		result[symbol] = inst.MaxLeverage
+	}
+	for _, coin := range candidates {
+		add(coin.Symbol)
+	}
+	for _, pos := range positions {
+		add(pos.Symbol)
+	}
+	return result
+}
+
+// roundDecisionPrices 将决策中的委托价、止损价、止盈价统一按合约价格步进取整
+// 获取合约规则失败时保持原值，由各交易所实现自行处理
+func (at *AutoTrader) roundDecisionPrices(d *decision.Decision) {
+	if d.Action == "hold" || d.Action == "wait" {
+		return
+	}
+	inst, err := at.trader.GetInstrument(d.Symbol)
+	if err != nil {
+		return
+	}
+	for _, price := range []*float64{&d.EntryPrice, &d.StopLoss, &d.TakeProfit, &d.NewStopLoss, &d.NewTakeProfit, &d.ActivationPrice} {
+		if *price > 0 {
+			*price = inst.RoundPrice(*price)
+		}
+	}
+	for i := range d.TakeProfitLevels {
+		if d.TakeProfitLevels[i].Price > 0 {
+			d.TakeProfitLevels[i].Price = inst.RoundPrice(d.TakeProfitLevels[i].Price)
+		}
+	}
+}
+
+// marketDataExchange 行情数据来源交易所（模拟盘使用其行情来源交易所）
+func (at *AutoTrader) marketDataExchange() string {
+	if at.exchange == "paper" {
+		return at.config.PaperPriceExchange
+	}
+	return at.exchange
+}
+
+// checkSlippage 下单前按最新盘口估算市价成交滑点，超过交易员配置的上限时拒绝
+// 盘口获取失败时不阻止下单（决策阶段已用周期开始时的盘口检查过一次）
+func (at *AutoTrader) checkSlippage(symbol, side string, notional float64) error {
+	limit := at.config.RiskLimits.MaxSlippagePct
+	if limit <= 0 || notional <= 0 {
+		return nil
+	}
+	book, err := market.GetOrderBook(symbol, at.marketDataExchange(), market.DefaultDepthLimit)
+	if err != nil {
+		log.Printf("  ⚠️ 获取 %s 盘口失败，跳过滑点检查: %v", symbol, err)
+		return nil
+	}
+	_, slippage, filled := book.EstimateFill(side, notional)
+	if !filled {
+		return fmt.Errorf("%s 盘口深度不足以成交 %.2f USDT，拒绝开仓", symbol, notional)
+	}
+	if slippage > limit {
+		return fmt.Errorf("%s 成交 %.2f USDT 预计滑点 %.3f%% 超过上限 %.3f%%，拒绝开仓", symbol, notional, slippage, limit)
+	}
+	log.Printf("  ✓ 预计滑点 %.3f%%（上限 %.3f%%）", slippage, limit)
+	return nil
+}
+
 // executeOpenLongWithRecord 执行开多仓并记录详细信息
 // V1.70版本：增强日志输出，确保错误信息清晰可见
 func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
@@ -888,12 +1158,13 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 		return fmt.Errorf("获取持仓列表失败: %w", err)
 	}
 	
-	for _, pos := range positions {
-		if pos["symbol"] == decision.Symbol && pos["side"] == "long" {
-			errMsg := fmt.Sprintf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", decision.Symbol)
-			log.Printf("  %s", errMsg)
-			return fmt.Errorf(errMsg)
-		}
+	if _, exists := FindPosition(positions, decision.Symbol, "long"); exists {
+		errMsg := fmt.Sprintf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", decision.Symbol)
+		log.Printf("  %s", errMsg)
+		return fmt.Errorf("%s", errMsg)
+	}
+	if at.hasPendingEntry(decision.Symbol, "long") {
+		return fmt.Errorf("❌ %s 已有未成交的开多挂单，拒绝重复开仓", decision.Symbol)
 	}
 	log.Printf("  ✓ 未发现重复持仓，可以开仓")
 
@@ -906,6 +1177,17 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	}
 	log.Printf("  ✓ 当前价格: %.4f USDT", marketData.CurrentPrice)
 
+	// 限价类委托：挂单并跟踪成交（成交后再挂止盈止损）
+	if !decision.IsMarketOrder() {
+		return at.executeEntryOrder(decision, "long", marketData.CurrentPrice, actionRecord)
+	}
+
+	// 按最新盘口检查滑点
+	if err := at.checkSlippage(decision.Symbol, "buy", decision.PositionSizeUSD); err != nil {
+		log.Printf("  ❌ %v", err)
+		return err
+	}
+
 	// 计算数量
 	quantity := decision.PositionSizeUSD / marketData.CurrentPrice
 	actionRecord.Quantity = quantity
@@ -929,31 +1211,36 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	log.Printf("  📋 开仓参数: 币种=%s, 数量=%.8f, 杠杆=%dx, 止损=%.4f, 止盈=%.4f",
 		decision.Symbol, quantity, decision.Leverage, decision.StopLoss, decision.TakeProfit)
 	
-	order, err := at.trader.OpenLong(decision.Symbol, quantity, decision.Leverage, decision.StopLoss, decision.TakeProfit)
+	// 分批止盈在成交后按实际数量挂出，开仓时不附带止盈
+	takeProfit := decision.TakeProfit
+	if len(decision.TakeProfitLevels) > 0 {
+		takeProfit = 0
+	}
+	order, err := at.trader.OpenLong(decision.Symbol, quantity, decision.Leverage, decision.StopLoss, takeProfit)
 	if err != nil {
 		log.Printf("  ❌ 开仓API调用失败: %v", err)
 		return fmt.Errorf("开多仓失败: %w", err)
 	}
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	} else if orderIDStr, ok := order["orderId"].(string); ok {
-		// OKX可能返回字符串格式的订单ID
-		log.Printf("  ✓ 订单ID (字符串): %s", orderIDStr)
-	}
+	// 记录实际成交均价、手续费和滑点（OKX返回非数字订单ID时记录为0）
+	at.resolveFill(decision.Symbol, order)
+	recordFill(actionRecord, order, "buy", marketData.CurrentPrice)
 
-	log.Printf("  ✅ 开仓成功！订单ID: %v, 数量: %.8f", order["orderId"], quantity)
+	log.Printf("  ✅ 开仓成功！订单ID: %s, 数量: %.8f, 成交均价: %.4f, 滑点: %.3f%%", order.OrderID, actionRecord.Quantity, actionRecord.Price, actionRecord.SlippagePct)
 	if decision.StopLoss > 0 {
 		log.Printf("  ✓ 止损已设置: %.4f", decision.StopLoss)
 	}
-	if decision.TakeProfit > 0 {
-		log.Printf("  ✓ 止盈已设置: %.4f", decision.TakeProfit)
+	if takeProfit > 0 {
+		log.Printf("  ✓ 止盈已设置: %.4f", takeProfit)
 	}
 
 	// 记录开仓时间
 	posKey := decision.Symbol + "_long"
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	at.recordTradeOpen(decision.Symbol, "long", decision.Leverage, decision.StopLoss, decision.TakeProfit, quantity, marketData.CurrentPrice, order)
+	if len(decision.TakeProfitLevels) > 0 {
+		at.setupTakeProfitLadder(decision.Symbol, "long", actionRecord.Quantity, decision.TakeProfitLevels)
+	}
 
 	return nil
 }
@@ -965,12 +1252,13 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限）
 	positions, err := at.trader.GetPositions()
 	if err == nil {
-		for _, pos := range positions {
-			if pos["symbol"] == decision.Symbol && pos["side"] == "short" {
-				return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", decision.Symbol)
-			}
+		if _, exists := FindPosition(positions, decision.Symbol, "short"); exists {
+			return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", decision.Symbol)
 		}
 	}
+	if at.hasPendingEntry(decision.Symbol, "short") {
+		return fmt.Errorf("❌ %s 已有未成交的开空挂单，拒绝重复开仓", decision.Symbol)
+	}
 
 	// 获取当前价格
 	marketData, err := market.Get(decision.Symbol)
@@ -978,6 +1266,16 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 		return err
 	}
 
+	// 限价类委托：挂单并跟踪成交（成交后再挂止盈止损）
+	if !decision.IsMarketOrder() {
+		return at.executeEntryOrder(decision, "short", marketData.CurrentPrice, actionRecord)
+	}
+
+	// 按最新盘口检查滑点
+	if err := at.checkSlippage(decision.Symbol, "sell", decision.PositionSizeUSD); err != nil {
+		return err
+	}
+
 	// 计算数量
 	quantity := decision.PositionSizeUSD / marketData.CurrentPrice
 	actionRecord.Quantity = quantity
@@ -993,27 +1291,35 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	}
 
 	// V1.57版本：开仓时直接设置止盈止损（使用attachAlgoOrds参数）
-	order, err := at.trader.OpenShort(decision.Symbol, quantity, decision.Leverage, decision.StopLoss, decision.TakeProfit)
+	// 分批止盈在成交后按实际数量挂出，开仓时不附带止盈
+	takeProfit := decision.TakeProfit
+	if len(decision.TakeProfitLevels) > 0 {
+		takeProfit = 0
+	}
+	order, err := at.trader.OpenShort(decision.Symbol, quantity, decision.Leverage, decision.StopLoss, takeProfit)
 	if err != nil {
 		return err
 	}
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	// 记录实际成交均价、手续费和滑点
+	at.resolveFill(decision.Symbol, order)
+	recordFill(actionRecord, order, "sell", marketData.CurrentPrice)
 
-	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
+	log.Printf("  ✓ 开仓成功，订单ID: %s, 数量: %.4f, 成交均价: %.4f, 滑点: %.3f%%", order.OrderID, actionRecord.Quantity, actionRecord.Price, actionRecord.SlippagePct)
 	if decision.StopLoss > 0 {
 		log.Printf("  ✓ 止损已设置: %.4f", decision.StopLoss)
 	}
-	if decision.TakeProfit > 0 {
-		log.Printf("  ✓ 止盈已设置: %.4f", decision.TakeProfit)
+	if takeProfit > 0 {
+		log.Printf("  ✓ 止盈已设置: %.4f", takeProfit)
 	}
 
 	// 记录开仓时间
 	posKey := decision.Symbol + "_short"
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	at.recordTradeOpen(decision.Symbol, "short", decision.Leverage, decision.StopLoss, decision.TakeProfit, quantity, marketData.CurrentPrice, order)
+	if len(decision.TakeProfitLevels) > 0 {
+		at.setupTakeProfitLadder(decision.Symbol, "short", actionRecord.Quantity, decision.TakeProfitLevels)
+	}
 
 	return nil
 }
@@ -1035,10 +1341,11 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 		return err
 	}
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	// 记录实际成交均价、手续费和滑点
+	at.resolveFill(decision.Symbol, order)
+	recordFill(actionRecord, order, "sell", marketData.CurrentPrice)
+	at.recordTradeClose(decision.Symbol, "long", 0, marketData.CurrentPrice, order, config.TradeExitAI)
+	at.clearTrailingStop(decision.Symbol, "long")
 
 	log.Printf("  ✓ 平仓成功")
 	return nil
@@ -1061,10 +1368,11 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 		return err
 	}
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	// 记录实际成交均价、手续费和滑点
+	at.resolveFill(decision.Symbol, order)
+	recordFill(actionRecord, order, "buy", marketData.CurrentPrice)
+	at.recordTradeClose(decision.Symbol, "short", 0, marketData.CurrentPrice, order, config.TradeExitAI)
+	at.clearTrailingStop(decision.Symbol, "short")
 
 	log.Printf("  ✓ 平仓成功")
 	return nil
@@ -1088,12 +1396,10 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 	}
 
 	// 查找目标持仓
-	var targetPosition map[string]interface{}
-	for _, pos := range positions {
-		symbol, _ := pos["symbol"].(string)
-		posAmt, _ := pos["positionAmt"].(float64)
-		if symbol == decision.Symbol && posAmt != 0 {
-			targetPosition = pos
+	var targetPosition *Position
+	for i := range positions {
+		if positions[i].Symbol == decision.Symbol && positions[i].Quantity != 0 {
+			targetPosition = &positions[i]
 			break
 		}
 	}
@@ -1103,9 +1409,8 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 	}
 
 	// 获取持仓方向和数量
-	side, _ := targetPosition["side"].(string)
-	positionSide := strings.ToUpper(side)
-	positionAmt, _ := targetPosition["positionAmt"].(float64)
+	positionSide := strings.ToUpper(targetPosition.Side)
+	positionAmt := targetPosition.Quantity
 
 	// 验证新止损价格合理性
 	if positionSide == "LONG" && decision.NewStopLoss >= marketData.CurrentPrice {
@@ -1119,12 +1424,9 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 	var hasOppositePosition bool
 	oppositeSide := ""
 	for _, pos := range positions {
-		symbol, _ := pos["symbol"].(string)
-		posSide, _ := pos["side"].(string)
-		posAmt, _ := pos["positionAmt"].(float64)
-		if symbol == decision.Symbol && posAmt != 0 && strings.ToUpper(posSide) != positionSide {
+		if pos.Symbol == decision.Symbol && pos.Quantity != 0 && strings.ToUpper(pos.Side) != positionSide {
 			hasOppositePosition = true
-			oppositeSide = strings.ToUpper(posSide)
+			oppositeSide = strings.ToUpper(pos.Side)
 			break
 		}
 	}
@@ -1150,13 +1452,20 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 		return fmt.Errorf("修改止损失败: %w", err)
 	}
 
+	at.recordTradeStops(decision.Symbol, targetPosition.Side, decision.NewStopLoss, 0)
+
 	log.Printf("  ✓ 止损已调整: %.2f (当前价格: %.2f)", decision.NewStopLoss, marketData.CurrentPrice)
 	return nil
 }
 
 // executeUpdateTakeProfitWithRecord 执行调整止盈并记录详细信息
+// 设置 take_profit_levels 时按分批止盈阶梯整体替换原有止盈单
 func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
-	log.Printf("  🎯 调整止盈: %s → %.2f", decision.Symbol, decision.NewTakeProfit)
+	if len(decision.TakeProfitLevels) > 0 {
+		log.Printf("  🎯 调整分批止盈: %s → %s", decision.Symbol, takeProfitLadderSummary(decision.TakeProfitLevels))
+	} else {
+		log.Printf("  🎯 调整止盈: %s → %.2f", decision.Symbol, decision.NewTakeProfit)
+	}
 
 	// 获取当前价格
 	marketData, err := market.Get(decision.Symbol)
@@ -1172,12 +1481,10 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 	}
 
 	// 查找目标持仓
-	var targetPosition map[string]interface{}
-	for _, pos := range positions {
-		symbol, _ := pos["symbol"].(string)
-		posAmt, _ := pos["positionAmt"].(float64)
-		if symbol == decision.Symbol && posAmt != 0 {
-			targetPosition = pos
+	var targetPosition *Position
+	for i := range positions {
+		if positions[i].Symbol == decision.Symbol && positions[i].Quantity != 0 {
+			targetPosition = &positions[i]
 			break
 		}
 	}
@@ -1187,9 +1494,19 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 	}
 
 	// 获取持仓方向和数量
-	side, _ := targetPosition["side"].(string)
-	positionSide := strings.ToUpper(side)
-	positionAmt, _ := targetPosition["positionAmt"].(float64)
+	positionSide := strings.ToUpper(targetPosition.Side)
+	positionAmt := targetPosition.Quantity
+
+	// 分批止盈：按持仓方向由近到远排序，最远一档作为止盈价
+	if len(decision.TakeProfitLevels) > 0 {
+		decision.SortTakeProfitLevels(positionSide == "LONG")
+		for i, level := range decision.TakeProfitLevels {
+			if (positionSide == "LONG" && level.Price <= marketData.CurrentPrice) || (positionSide == "SHORT" && level.Price >= marketData.CurrentPrice) {
+				return fmt.Errorf("第%d档止盈 %.4f 在当前价格 %.4f 错误一侧", i+1, level.Price, marketData.CurrentPrice)
+			}
+		}
+		decision.NewTakeProfit = decision.TakeProfitLevels[len(decision.TakeProfitLevels)-1].Price
+	}
 
 	// 验证新止盈价格合理性
 	if positionSide == "LONG" && decision.NewTakeProfit <= marketData.CurrentPrice {
@@ -1203,12 +1520,9 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 	var hasOppositePosition bool
 	oppositeSide := ""
 	for _, pos := range positions {
-		symbol, _ := pos["symbol"].(string)
-		posSide, _ := pos["side"].(string)
-		posAmt, _ := pos["positionAmt"].(float64)
-		if symbol == decision.Symbol && posAmt != 0 && strings.ToUpper(posSide) != positionSide {
+		if pos.Symbol == decision.Symbol && pos.Quantity != 0 && strings.ToUpper(pos.Side) != positionSide {
 			hasOppositePosition = true
-			oppositeSide = strings.ToUpper(posSide)
+			oppositeSide = strings.ToUpper(pos.Side)
 			break
 		}
 	}
@@ -1227,13 +1541,27 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 		// 不中断执行，继续设置新止盈
 	}
 
-	// 调用交易所 API 修改止盈
+	// 调用交易所 API 修改止盈（分批止盈按当前持仓数量重新拆分各档）
 	quantity := math.Abs(positionAmt)
+	if len(decision.TakeProfitLevels) > 0 {
+		placed, err := at.placeTakeProfitLadder(decision.Symbol, targetPosition.Side, quantity, decision.TakeProfitLevels)
+		at.recordTradeStops(decision.Symbol, targetPosition.Side, 0, decision.NewTakeProfit)
+		at.recordTradeTakeProfitLevels(decision.Symbol, targetPosition.Side, placed)
+		if err != nil {
+			return fmt.Errorf("修改分批止盈失败: %w", err)
+		}
+		log.Printf("  ✓ 分批止盈已调整: %d 档 (当前价格: %.2f)", len(placed), marketData.CurrentPrice)
+		return nil
+	}
+
 	err = at.trader.SetTakeProfit(decision.Symbol, positionSide, quantity, decision.NewTakeProfit)
 	if err != nil {
 		return fmt.Errorf("修改止盈失败: %w", err)
 	}
 
+	at.recordTradeStops(decision.Symbol, targetPosition.Side, 0, decision.NewTakeProfit)
+	at.recordTradeTakeProfitLevels(decision.Symbol, targetPosition.Side, nil)
+
 	log.Printf("  ✓ 止盈已调整: %.2f (当前价格: %.2f)", decision.NewTakeProfit, marketData.CurrentPrice)
 	return nil
 }
@@ -1261,12 +1589,10 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	}
 
 	// 查找目标持仓
-	var targetPosition map[string]interface{}
-	for _, pos := range positions {
-		symbol, _ := pos["symbol"].(string)
-		posAmt, _ := pos["positionAmt"].(float64)
-		if symbol == decision.Symbol && posAmt != 0 {
-			targetPosition = pos
+	var targetPosition *Position
+	for i := range positions {
+		if positions[i].Symbol == decision.Symbol && positions[i].Quantity != 0 {
+			targetPosition = &positions[i]
 			break
 		}
 	}
@@ -1276,9 +1602,8 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	}
 
 	// 获取持仓方向和数量
-	side, _ := targetPosition["side"].(string)
-	positionSide := strings.ToUpper(side)
-	positionAmt, _ := targetPosition["positionAmt"].(float64)
+	positionSide := strings.ToUpper(targetPosition.Side)
+	positionAmt := targetPosition.Quantity
 
 	// 计算平仓数量
 	totalQuantity := math.Abs(positionAmt)
@@ -1286,7 +1611,7 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	actionRecord.Quantity = closeQuantity
 
 	// 执行平仓
-	var order map[string]interface{}
+	var order *OrderResult
 	if positionSide == "LONG" {
 		order, err = at.trader.CloseLong(decision.Symbol, closeQuantity)
 	} else {
@@ -1297,14 +1622,15 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 		return fmt.Errorf("部分平仓失败: %w", err)
 	}
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	// 记录实际成交均价、手续费和滑点
+	at.resolveFill(decision.Symbol, order)
+	recordFill(actionRecord, order, orderSide(targetPosition.Side, true), marketData.CurrentPrice)
+	at.recordTradeClose(decision.Symbol, targetPosition.Side, closeQuantity, marketData.CurrentPrice, order, config.TradeExitAI)
 
 	remainingQuantity := totalQuantity - closeQuantity
 	log.Printf("  ✓ 部分平仓成功: 平仓 %.4f (%.1f%%), 剩余 %.4f",
 		closeQuantity, decision.ClosePercentage, remainingQuantity)
+	at.resizeTrailingStop(decision.Symbol, targetPosition.Side, remainingQuantity)
 
 	return nil
 }
@@ -1363,6 +1689,8 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		aiProvider = "ChatGPT"
 	} else if at.config.AIModel == "gpts" {
 		aiProvider = "GPTs"
+	} else if at.config.AIModel == "anthropic" {
+		aiProvider = "Anthropic"
 	} else if at.config.UseQwen || at.config.AIModel == "qwen" {
 		aiProvider = "Qwen"
 	}
@@ -1378,44 +1706,36 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		"call_count":      at.callCount,
 		"initial_balance": at.initialBalance,
 		"scan_interval":   at.config.ScanInterval.String(),
-		"stop_until":      at.stopUntil.Format(time.RFC3339),
+		"stop_until":      at.getStopUntil().Format(time.RFC3339),
 		"last_reset_time": at.lastResetTime.Format(time.RFC3339),
 		"ai_provider":     aiProvider,
+		"risk_breaker":    at.GetRiskBreakerStatus(),
+		"ai_providers":    at.GetAIProviderStatus(),
+		"pending_entries": len(at.snapshotPendingEntries()),
+		"account_stream":  at.accountState.status(),
 	}
 }
 
 // GetAccountInfo 获取账户信息（用于API）
-func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
-	balance, err := at.trader.GetBalance()
+func (at *AutoTrader) GetAccountInfo() (*AccountInfo, error) {
+	balance, err := at.getBalance()
 	if err != nil {
 		return nil, fmt.Errorf("获取余额失败: %w", err)
 	}
 
 	// 获取账户字段
-	totalWalletBalance := 0.0
-	totalEquity := 0.0
-	availableBalance := 0.0
-	mgnRatio := 0.0 // OKX标准保证金率
+	totalWalletBalance := balance.TotalWalletBalance
+	availableBalance := balance.AvailableBalance
+	mgnRatio := balance.MarginRatio // OKX标准保证金率 / Bybit初始保证金率（其他交易所为0）
 
-	if wallet, ok := balance["totalWalletBalance"].(float64); ok {
-		totalWalletBalance = wallet
-	}
 	// OKX使用totalEquity字段（adjEq）
-	if equity, ok := balance["totalEquity"].(float64); ok && equity > 0 {
-		totalEquity = equity
-	} else if wallet, ok := balance["totalWalletBalance"].(float64); ok {
-		totalEquity = wallet // 回退到钱包余额
-	}
-	if avail, ok := balance["availableBalance"].(float64); ok {
-		availableBalance = avail
-	}
-	// 使用OKX标准保证金率（如果可用）
-	if ratio, ok := balance["mgnRatio"].(float64); ok {
-		mgnRatio = ratio
+	totalEquity := balance.TotalEquity
+	if totalEquity <= 0 {
+		totalEquity = totalWalletBalance // 回退到钱包余额
 	}
 
 	// 获取持仓计算总保证金和未实现盈亏
-	positions, err := at.trader.GetPositions()
+	positions, err := at.getPositions()
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
@@ -1423,26 +1743,20 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 	totalMarginUsed := 0.0
 	totalUnrealizedPnL := 0.0
 	for _, pos := range positions {
-		// 使用OKX返回的margin字段（如果可用）
-		if margin, ok := pos["margin"].(float64); ok && margin > 0 {
-			totalMarginUsed += margin
+		// 使用交易所返回的margin字段（如果可用）
+		if pos.Margin > 0 {
+			totalMarginUsed += pos.Margin
 		} else {
 			// 回退计算：保证金 = 名义价值 / 杠杆
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
-		totalMarginUsed += marginUsed
+			leverage := 10
+			if pos.Leverage > 0 {
+				leverage = pos.Leverage
+			}
+			marginUsed := (pos.Quantity * pos.MarkPrice) / float64(leverage)
+			totalMarginUsed += marginUsed
 		}
-		
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		totalUnrealizedPnL += unrealizedPnl
+
+		totalUnrealizedPnL += pos.UnrealizedProfit
 	}
 
 	// 如果totalEquity未设置，使用钱包余额+未实现盈亏
@@ -1463,50 +1777,44 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 		marginUsedPct = (totalMarginUsed / totalEquity) * 100
 	}
 
-	return map[string]interface{}{
-		// 核心字段
-		"total_equity":      totalEquity,           // 账户净值（OKX使用adjEq）
-		"wallet_balance":    totalWalletBalance,    // 钱包余额（不含未实现盈亏）
-		"unrealized_profit": totalUnrealizedPnL,   // 未实现盈亏（从持仓计算，更准确）
-		"available_balance": availableBalance,      // 可用余额
-
-		// 盈亏统计
-		"total_pnl":            totalPnL,           // 总盈亏 = equity - initial
-		"total_pnl_pct":        totalPnLPct,        // 总盈亏百分比
-		"total_unrealized_pnl": totalUnrealizedPnL, // 未实现盈亏（从持仓计算）
-		"initial_balance":      at.initialBalance,  // 初始余额
-		"daily_pnl":            at.dailyPnL,        // 日盈亏
-
-		// 持仓信息
-		"position_count":  len(positions),  // 持仓数量
-		"margin_used":     totalMarginUsed, // 保证金占用（使用OKX标准margin字段）
-		"margin_used_pct": marginUsedPct,   // 保证金使用率（使用OKX标准mgnRatio）
+	return &AccountInfo{
+		TotalEquity:      totalEquity,
+		WalletBalance:    totalWalletBalance,
+		UnrealizedProfit: totalUnrealizedPnL,
+		AvailableBalance: availableBalance,
+
+		TotalPnL:           totalPnL,
+		TotalPnLPct:        totalPnLPct,
+		TotalUnrealizedPnL: totalUnrealizedPnL,
+		InitialBalance:     at.initialBalance,
+		DailyPnL:           at.getDailyPnL(),
+
+		PositionCount: len(positions),
+		MarginUsed:    totalMarginUsed, // 优先使用交易所返回的margin字段
+		MarginUsedPct: marginUsedPct,   // 优先使用OKX标准mgnRatio
 	}, nil
 }
 
 // GetPositions 获取持仓列表（用于API）
 func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
-	positions, err := at.trader.GetPositions()
+	positions, err := at.getPositions()
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
 	var result []map[string]interface{}
 	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		liquidationPrice := pos["liquidationPrice"].(float64)
+		symbol := pos.Symbol
+		side := pos.Side
+		entryPrice := pos.EntryPrice
+		markPrice := pos.MarkPrice
+		quantity := pos.Quantity
+		unrealizedPnl := pos.UnrealizedProfit
+		liquidationPrice := pos.LiquidationPrice
 
 		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
+		if pos.Leverage > 0 {
+			leverage = pos.Leverage
 		}
 
 		// 计算占用保证金
@@ -1548,7 +1856,7 @@ func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision
 		switch action {
 		case "close_long", "close_short", "partial_close":
 			return 1 // 最高优先级：先平仓（包括部分平仓）
-		case "update_stop_loss", "update_take_profit":
+		case "update_stop_loss", "update_take_profit", "set_trailing_stop":
 			return 2 // 调整持仓止盈止损
 		case "open_long", "open_short":
 			return 3 // 次优先级：后开仓
@@ -1577,6 +1885,17 @@ func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision
 
 // getCandidateCoins 获取交易员的候选币种列表
 func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
+	// 配置了可插拔信号源时优先使用（全部失败或结果为空时回退到下面的逻辑）
+	if len(at.providers) > 0 {
+		merged, err := pool.MergeCandidates(at.providers)
+		if err == nil && len(merged) > 0 {
+			candidateCoins := candidatesFromMerged(merged)
+			log.Printf("📋 [%s] 使用%d个信号源合并候选币种: 总计%d个", at.name, len(at.providers), len(candidateCoins))
+			return candidateCoins, nil
+		}
+		log.Printf("⚠️  [%s] 信号源未返回候选币种（%v），回退到默认币种配置", at.name, err)
+	}
+
 	if len(at.tradingCoins) == 0 {
 		// 使用数据库配置的默认币种列表
 		var candidateCoins []decision.CandidateCoin
@@ -1597,19 +1916,13 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 			// 如果数据库中没有配置默认币种，则使用AI500+OI Top作为fallback
 			const ai500Limit = 20 // AI500取前20个评分最高的币种
 
-			mergedPool, err := pool.GetMergedCoinPool(ai500Limit)
+			mergedPool, err := at.signalSource.GetMergedCoinPool(ai500Limit)
 			if err != nil {
 				return nil, fmt.Errorf("获取合并币种池失败: %w", err)
 			}
 
-			// 构建候选币种列表（包含来源信息）
-			for _, symbol := range mergedPool.AllSymbols {
-				sources := mergedPool.SymbolSources[symbol]
-				candidateCoins = append(candidateCoins, decision.CandidateCoin{
-					Symbol:  symbol,
-					Sources: sources, // "ai500" 和/或 "oi_top"
-				})
-			}
+			// 构建候选币种列表（包含来源信息和各来源评分）
+			candidateCoins = candidatesFromMerged(mergedPool.Candidates)
 
 			log.Printf("📋 [%s] 数据库无默认币种配置，使用AI500+OI Top: AI500前%d + OI_Top20 = 总计%d个候选币种",
 				at.name, ai500Limit, len(candidateCoins))
@@ -1633,6 +1946,36 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 	}
 }
 
+// candidatesFromMerged 将合并后的信号源结果转换为决策候选币种（保留各来源评分）
+func candidatesFromMerged(merged []pool.MergedCandidate) []decision.CandidateCoin {
+	candidateCoins := make([]decision.CandidateCoin, 0, len(merged))
+	for _, c := range merged {
+		candidateCoins = append(candidateCoins, decision.CandidateCoin{
+			Symbol:       c.Symbol,
+			Sources:      c.Sources,
+			Score:        c.Score,
+			SourceScores: c.SourceScores,
+		})
+	}
+	return candidateCoins
+}
+
+// newSignalSource 基于系统默认信号源配置创建交易员独立的信号源
+// 交易员配置了自己的URL时覆盖默认值，缓存目录按交易员隔离
+func newSignalSource(config AutoTraderConfig) *pool.SignalSource {
+	sourceConfig := pool.DefaultSignalSourceConfig()
+	if config.CoinPoolAPIURL != "" {
+		sourceConfig.CoinPoolAPIURL = config.CoinPoolAPIURL
+	}
+	if config.OITopAPIURL != "" {
+		sourceConfig.OITopAPIURL = config.OITopAPIURL
+	}
+	if config.ID != "" {
+		sourceConfig.CacheDir = filepath.Join(sourceConfig.CacheDir, config.ID)
+	}
+	return pool.NewSignalSource(sourceConfig)
+}
+
 // normalizeSymbol 标准化币种符号（确保以USDT结尾）
 func normalizeSymbol(symbol string) string {
 	// 转为大写
@@ -1660,7 +2003,12 @@ func (at *AutoTrader) startDrawdownMonitor() {
 		for {
 			select {
 			case <-ticker.C:
+				// 推送缓存定期用REST全量校正（REST结果可能有几秒延迟，消失的持仓交给周期对账处理）
+				if at.accountState.needsResync() {
+					at.resyncAccountState()
+				}
 				at.checkPositionDrawdown()
+				at.checkRiskLimits()
 			case <-at.stopMonitorCh:
 				log.Println("⏹ 停止持仓回撤监控")
 				return
@@ -1671,27 +2019,29 @@ func (at *AutoTrader) startDrawdownMonitor() {
 
 // 检查持仓回撤情况
 func (at *AutoTrader) checkPositionDrawdown() {
-	// 获取当前持仓
-	positions, err := at.trader.GetPositions()
+	// 获取当前持仓（账户推送正常时读缓存）
+	positions, err := at.getPositions()
 	if err != nil {
 		log.Printf("❌ 回撤监控：获取持仓失败: %v", err)
 		return
 	}
 
 	for _, pos := range positions {
-		symbol := pos["symbol"].(string)
-		side := pos["side"].(string)
-		entryPrice := pos["entryPrice"].(float64)
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity // 空仓数量为负，转为正数
+		symbol := pos.Symbol
+		side := pos.Side
+		entryPrice := pos.EntryPrice
+		markPrice := pos.MarkPrice
+		if entryPrice <= 0 {
+			continue // 交易所未返回开仓价，无法计算收益
+		}
+		if at.getTrailingStop(symbol, side) != nil {
+			continue // 已设置跟踪止损，由跟踪止损负责保护利润
 		}
 
 		// 计算当前盈亏百分比
 		leverage := 10 // 默认值
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
+		if pos.Leverage > 0 {
+			leverage = pos.Leverage
 		}
 
 		var currentPnLPct float64
@@ -1727,7 +2077,7 @@ func (at *AutoTrader) checkPositionDrawdown() {
 				symbol, side, currentPnLPct, peakPnLPct, drawdownPct)
 
 			// 执行平仓
-			if err := at.emergencyClosePosition(symbol, side); err != nil {
+			if err := at.emergencyClosePosition(symbol, side, config.TradeExitDrawdown); err != nil {
 				log.Printf("❌ 回撤平仓失败 (%s %s): %v", symbol, side, err)
 			} else {
 				log.Printf("✅ 回撤平仓成功: %s %s", symbol, side)
@@ -1743,20 +2093,29 @@ func (at *AutoTrader) checkPositionDrawdown() {
 }
 
 // 紧急平仓函数
-func (at *AutoTrader) emergencyClosePosition(symbol, side string) error {
+func (at *AutoTrader) emergencyClosePosition(symbol, side, reason string) error {
+	at.tradeMutex.Lock()
+	defer at.tradeMutex.Unlock()
+
 	switch side {
 	case "long":
 		order, err := at.trader.CloseLong(symbol, 0) // 0 = 全部平仓
 		if err != nil {
 			return err
 		}
-		log.Printf("✅ 紧急平多仓成功，订单ID: %v", order["orderId"])
+		log.Printf("✅ 紧急平多仓成功，订单ID: %s", order.OrderID)
+		at.resolveFill(symbol, order)
+		at.recordTradeClose(symbol, side, 0, 0, order, reason)
+		at.clearTrailingStop(symbol, side)
 	case "short":
 		order, err := at.trader.CloseShort(symbol, 0) // 0 = 全部平仓
 		if err != nil {
 			return err
 		}
-		log.Printf("✅ 紧急平空仓成功，订单ID: %v", order["orderId"])
+		log.Printf("✅ 紧急平空仓成功，订单ID: %s", order.OrderID)
+		at.resolveFill(symbol, order)
+		at.recordTradeClose(symbol, side, 0, 0, order, reason)
+		at.clearTrailingStop(symbol, side)
 	default:
 		return fmt.Errorf("未知的持仓方向: %s", side)
 	}
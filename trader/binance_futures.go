@@ -1,9 +1,13 @@
 package trader
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,28 +21,39 @@ type FuturesTrader struct {
 	client *futures.Client
 
 	// 余额缓存
-	cachedBalance     map[string]interface{}
+	cachedBalance     *Balance
 	balanceCacheTime  time.Time
 	balanceCacheMutex sync.RWMutex
 
 	// 持仓缓存
-	cachedPositions     []map[string]interface{}
+	cachedPositions     []Position
 	positionsCacheTime  time.Time
 	positionsCacheMutex sync.RWMutex
 
 	// 缓存有效期（15秒）
 	cacheDuration time.Duration
+
+	// 合约规则（exchangeInfo + 杠杆分层）
+	instruments *instrumentRegistry
+
+	// 服务器时间偏移（同步后写入SDK客户端的 TimeOffset，定期或时间戳被拒绝后重新同步）
+	clock serverClock
 }
 
 // NewFuturesTrader 创建合约交易器
 func NewFuturesTrader(apiKey, secretKey string) *FuturesTrader {
 	client := futures.NewClient(apiKey, secretKey)
-	// 同步时间，避免 Timestamp ahead 错误
-	syncBinanceServerTime(client)
 	trader := &FuturesTrader{
 		client:        client,
 		cacheDuration: 15 * time.Second, // 15秒缓存
 	}
+	// 同步时间，避免 Timestamp ahead 错误
+	trader.clock.onSync = func(offset time.Duration) {
+		client.TimeOffset = -offset.Milliseconds()
+	}
+	client.HTTPClient = &http.Client{Transport: &binanceClockTransport{base: http.DefaultTransport, clock: &trader.clock}}
+	trader.clock.sync("Binance", trader.fetchServerTime)
+	trader.instruments = sharedInstrumentRegistry("binance", "Binance", trader.loadInstruments)
 
 	// 设置双向持仓模式（Hedge Mode）
 	// 这是必需的，因为代码中使用了 PositionSide (LONG/SHORT)
@@ -71,22 +86,55 @@ func (t *FuturesTrader) setDualSidePosition() error {
 	return nil
 }
 
-// syncBinanceServerTime 同步币安服务器时间，确保请求时间戳合法
-func syncBinanceServerTime(client *futures.Client) {
-	serverTime, err := client.NewServerTimeService().Do(context.Background())
+// binanceCodeTimestampRejected 币安风格接口（币安、Aster）的时间戳超出 recvWindow 错误码
+const binanceCodeTimestampRejected = -1021
+
+// isBinanceTimestampRejected 响应是否为时间戳被拒绝（需要重新同步服务器时间）
+func isBinanceTimestampRejected(body []byte) bool {
+	var resp struct {
+		Code int `json:"code"`
+	}
+	return json.Unmarshal(body, &resp) == nil && resp.Code == binanceCodeTimestampRejected
+}
+
+// fetchServerTime 获取币安服务器时间
+func (t *FuturesTrader) fetchServerTime() (time.Time, error) {
+	serverTime, err := t.client.NewServerTimeService().Do(context.Background())
 	if err != nil {
-		log.Printf("⚠️ 同步币安服务器时间失败: %v", err)
-		return
+		return time.Time{}, err
 	}
+	return time.UnixMilli(serverTime), nil
+}
 
-	now := time.Now().UnixMilli()
-	offset := now - serverTime
-	client.TimeOffset = offset
-	log.Printf("⏱ 已同步币安服务器时间，偏移 %dms", offset)
+// binanceClockTransport 币安SDK请求不经过 restClient，在HTTP层接入服务器时间校准：
+// 签名请求前按 clockResyncInterval 定期重新同步；返回 -1021 时立即重新同步，之后的请求使用新的偏移
+type binanceClockTransport struct {
+	base  http.RoundTripper
+	clock *serverClock
+}
+
+func (tr *binanceClockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	signed := strings.Contains(req.URL.RawQuery, "signature=")
+	if signed && tr.clock.stale() {
+		tr.clock.resync()
+	}
+	resp, err := tr.base.RoundTrip(req)
+	if err != nil || !signed || resp.StatusCode < 400 {
+		return resp, err
+	}
+
+	body, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if readErr == nil && isBinanceTimestampRejected(body) {
+		log.Printf("⏱ Binance 拒绝了请求时间戳，重新同步服务器时间")
+		tr.clock.resync()
+	}
+	return resp, nil
 }
 
 // GetBalance 获取账户余额（带缓存）
-func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
+func (t *FuturesTrader) GetBalance() (*Balance, error) {
 	// 先检查缓存是否有效
 	t.balanceCacheMutex.RLock()
 	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
@@ -105,10 +153,10 @@ func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("获取账户信息失败: %w", err)
 	}
 
-	result := make(map[string]interface{})
-	result["totalWalletBalance"], _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
-	result["availableBalance"], _ = strconv.ParseFloat(account.AvailableBalance, 64)
-	result["totalUnrealizedProfit"], _ = strconv.ParseFloat(account.TotalUnrealizedProfit, 64)
+	result := &Balance{}
+	result.TotalWalletBalance, _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
+	result.AvailableBalance, _ = strconv.ParseFloat(account.AvailableBalance, 64)
+	result.TotalUnrealizedProfit, _ = strconv.ParseFloat(account.TotalUnrealizedProfit, 64)
 
 	log.Printf("✓ 币安API返回: 总余额=%s, 可用=%s, 未实现盈亏=%s",
 		account.TotalWalletBalance,
@@ -125,7 +173,7 @@ func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
 }
 
 // GetPositions 获取所有持仓（带缓存）
-func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *FuturesTrader) GetPositions() ([]Position, error) {
 	// 先检查缓存是否有效
 	t.positionsCacheMutex.RLock()
 	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
@@ -143,30 +191,31 @@ func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
-	var result []map[string]interface{}
+	var result []Position
 	for _, pos := range positions {
 		posAmt, _ := strconv.ParseFloat(pos.PositionAmt, 64)
 		if posAmt == 0 {
 			continue // 跳过无持仓的
 		}
 
-		posMap := make(map[string]interface{})
-		posMap["symbol"] = pos.Symbol
-		posMap["positionAmt"], _ = strconv.ParseFloat(pos.PositionAmt, 64)
-		posMap["entryPrice"], _ = strconv.ParseFloat(pos.EntryPrice, 64)
-		posMap["markPrice"], _ = strconv.ParseFloat(pos.MarkPrice, 64)
-		posMap["unRealizedProfit"], _ = strconv.ParseFloat(pos.UnRealizedProfit, 64)
-		posMap["leverage"], _ = strconv.ParseFloat(pos.Leverage, 64)
-		posMap["liquidationPrice"], _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
+		p := Position{Symbol: pos.Symbol}
+		p.EntryPrice, _ = strconv.ParseFloat(pos.EntryPrice, 64)
+		p.MarkPrice, _ = strconv.ParseFloat(pos.MarkPrice, 64)
+		p.UnrealizedProfit, _ = strconv.ParseFloat(pos.UnRealizedProfit, 64)
+		p.LiquidationPrice, _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
+		leverage, _ := strconv.ParseFloat(pos.Leverage, 64)
+		p.Leverage = int(leverage)
 
-		// 判断方向
+		// 判断方向（币安空仓数量为负数，统一转换为正数）
 		if posAmt > 0 {
-			posMap["side"] = "long"
+			p.Side = "long"
+			p.Quantity = posAmt
 		} else {
-			posMap["side"] = "short"
+			p.Side = "short"
+			p.Quantity = -posAmt
 		}
 
-		result = append(result, posMap)
+		result = append(result, p)
 	}
 
 	// 更新缓存
@@ -231,16 +280,18 @@ func (t *FuturesTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
 
 // SetLeverage 设置杠杆（智能判断+冷却期）
 func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
+	if inst, err := t.instruments.get(symbol); err == nil {
+		leverage = inst.ClampLeverage(leverage)
+	}
+
 	// 先尝试获取当前杠杆（从持仓信息）
 	currentLeverage := 0
 	positions, err := t.GetPositions()
 	if err == nil {
 		for _, pos := range positions {
-			if pos["symbol"] == symbol {
-				if lev, ok := pos["leverage"].(float64); ok {
-					currentLeverage = int(lev)
-					break
-				}
+			if pos.Symbol == symbol && pos.Leverage > 0 {
+				currentLeverage = pos.Leverage
+				break
 			}
 		}
 	}
@@ -276,7 +327,7 @@ func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
 }
 
 // OpenLong 开多仓（V1.57版本：添加stopLoss和takeProfit参数以兼容接口，Binance暂不支持下单时设置止盈止损）
-func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (map[string]interface{}, error) {
+func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -322,15 +373,11 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int,
 	log.Printf("✓ 开多仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %d", order.OrderID)
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return newBinanceOrderResult(order), nil
 }
 
 // OpenShort 开空仓（V1.57版本：添加stopLoss和takeProfit参数以兼容接口，Binance暂不支持下单时设置止盈止损）
-func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (map[string]interface{}, error) {
+func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -376,15 +423,11 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int,
 	log.Printf("✓ 开空仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %d", order.OrderID)
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return newBinanceOrderResult(order), nil
 }
 
 // CloseLong 平多仓
-func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -392,11 +435,8 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, "long"); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
@@ -430,15 +470,11 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return newBinanceOrderResult(order), nil
 }
 
 // CloseShort 平空仓
-func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -446,11 +482,8 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				quantity = -pos["positionAmt"].(float64) // 空仓数量是负的，取绝对值
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, "short"); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
@@ -484,14 +517,188 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
+	return newBinanceOrderResult(order), nil
+}
+
+
+
+// newBinanceOrderResult 将币安下单响应转换为统一的下单结果
+func newBinanceOrderResult(order *futures.CreateOrderResponse) *OrderResult {
+	result := &OrderResult{
+		OrderID: formatOrderID(order.OrderID),
+		Symbol:  order.Symbol,
+		Status:  string(order.Status),
+	}
+	result.AvgPrice, _ = strconv.ParseFloat(order.AvgPrice, 64)
+	result.ExecutedQty, _ = strconv.ParseFloat(order.ExecutedQuantity, 64)
+	return result
+}
+
+// PlaceEntryOrder 按订单类型开仓（post_only 使用 GTX，会立即成交时交易所直接拒绝）
+func (t *FuturesTrader) PlaceEntryOrder(order EntryOrder) (*OrderResult, error) {
+	if err := order.validate(); err != nil {
+		return nil, err
+	}
+	if order.OrderType == OrderTypeMarket {
+		return order.openMarket(t)
+	}
+
+	if err := t.SetLeverage(order.Symbol, order.Leverage); err != nil {
+		return nil, err
+	}
+
+	quantityStr, err := t.FormatQuantity(order.Symbol, order.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	quantityFloat, parseErr := strconv.ParseFloat(quantityStr, 64)
+	if parseErr != nil || quantityFloat <= 0 {
+		return nil, fmt.Errorf("开仓数量过小，格式化后为 0 (原始: %.8f → 格式化: %s)", order.Quantity, quantityStr)
+	}
+	if err := t.CheckMinNotional(order.Symbol, quantityFloat); err != nil {
+		return nil, err
+	}
+
+	priceStr, err := t.formatPrice(order.Symbol, order.Price)
+	if err != nil {
+		return nil, err
+	}
+
+	side, posSide := futures.SideTypeBuy, futures.PositionSideTypeLong
+	if order.Side == "short" {
+		side, posSide = futures.SideTypeSell, futures.PositionSideTypeShort
+	}
+	timeInForce := futures.TimeInForceTypeGTC
+	switch order.OrderType {
+	case OrderTypePostOnly:
+		timeInForce = futures.TimeInForceTypeGTX
+	case OrderTypeIOC:
+		timeInForce = futures.TimeInForceTypeIOC
+	}
+
+	resp, err := t.client.NewCreateOrderService().
+		Symbol(order.Symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(futures.OrderTypeLimit).
+		TimeInForce(timeInForce).
+		Price(priceStr).
+		Quantity(quantityStr).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("%s开仓失败: %w", OrderTypeLabel(order.OrderType), err)
+	}
+
+	result := newBinanceOrderResult(resp)
+	result.Status = binanceOrderStatus(resp.Status)
+	result.Price, _ = strconv.ParseFloat(resp.Price, 64)
+	result.Quantity = quantityFloat
+	log.Printf("✓ %s已提交: %s %s 价格: %s 数量: %s 状态: %s", OrderTypeLabel(order.OrderType), order.Symbol, order.Side, priceStr, quantityStr, result.Status)
 	return result, nil
 }
 
+// GetOrder 查询订单
+func (t *FuturesTrader) GetOrder(symbol, orderID string) (*OrderResult, error) {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+	order, err := t.client.NewGetOrderService().Symbol(symbol).OrderID(id).Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
 
+	result := &OrderResult{
+		OrderID: formatOrderID(order.OrderID),
+		Symbol:  order.Symbol,
+		Status:  binanceOrderStatus(order.Status),
+	}
+	result.AvgPrice, _ = strconv.ParseFloat(order.AvgPrice, 64)
+	result.ExecutedQty, _ = strconv.ParseFloat(order.ExecutedQuantity, 64)
+	result.Price, _ = strconv.ParseFloat(order.Price, 64)
+	result.Quantity, _ = strconv.ParseFloat(order.OrigQuantity, 64)
+	return result, nil
+}
+
+// CancelOrder 撤销指定订单
+func (t *FuturesTrader) CancelOrder(symbol, orderID string) error {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+	if _, err := t.client.NewCancelOrderService().Symbol(symbol).OrderID(id).Do(context.Background()); err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+	log.Printf("  ✓ 已撤销 %s 订单 %s", symbol, orderID)
+	return nil
+}
+
+// GetOpenOrders 查询未完成的挂单（币安的止盈止损单同样在挂单列表中）
+func (t *FuturesTrader) GetOpenOrders(symbol string) ([]OpenOrder, error) {
+	service := t.client.NewListOpenOrdersService()
+	if symbol != "" {
+		service = service.Symbol(symbol)
+	}
+	orders, err := service.Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取未完成订单失败: %w", err)
+	}
+
+	result := make([]OpenOrder, 0, len(orders))
+	for _, order := range orders {
+		open := OpenOrder{
+			OrderID:      formatOrderID(order.OrderID),
+			Symbol:       order.Symbol,
+			Side:         strings.ToLower(string(order.Side)),
+			PositionSide: strings.ToLower(string(order.PositionSide)),
+			Type:         openOrderTypeFromBinance(string(order.Type)),
+			ReduceOnly:   order.ReduceOnly || order.ClosePosition,
+			Time:         time.UnixMilli(order.Time),
+		}
+		open.Price, _ = strconv.ParseFloat(order.Price, 64)
+		open.StopPrice, _ = strconv.ParseFloat(order.StopPrice, 64)
+		open.Quantity, _ = strconv.ParseFloat(order.OrigQuantity, 64)
+		open.ExecutedQty, _ = strconv.ParseFloat(order.ExecutedQuantity, 64)
+		open.IsAlgo = open.Type != OpenOrderTypeLimit && open.Type != OpenOrderTypeMarket
+		if open.PositionSide != "long" && open.PositionSide != "short" {
+			open.PositionSide = inferPositionSide(open.Side, open.ReduceOnly)
+		}
+		result = append(result, open)
+	}
+	return result, nil
+}
+
+// openOrderTypeFromBinance 币安（及兼容币安接口的交易所）订单类型转换为统一挂单类型
+func openOrderTypeFromBinance(orderType string) string {
+	switch orderType {
+	case "LIMIT":
+		return OpenOrderTypeLimit
+	case "MARKET":
+		return OpenOrderTypeMarket
+	case "STOP", "STOP_MARKET":
+		return OpenOrderTypeStopLoss
+	case "TAKE_PROFIT", "TAKE_PROFIT_MARKET":
+		return OpenOrderTypeTakeProfit
+	case "TRAILING_STOP_MARKET":
+		return OpenOrderTypeTrailingStop
+	default:
+		return OpenOrderTypeTrigger
+	}
+}
+
+// binanceOrderStatus 币安订单状态转换为统一状态
+func binanceOrderStatus(status futures.OrderStatusType) string {
+	switch status {
+	case futures.OrderStatusTypeFilled:
+		return OrderStatusFilled
+	case futures.OrderStatusTypePartiallyFilled:
+		return OrderStatusPartiallyFilled
+	case futures.OrderStatusTypeNew:
+		return OrderStatusOpen
+	default:
+		return OrderStatusCanceled
+	}
+}
 
 // CancelStopLossOrders 仅取消止损单（不影响止盈单）
 func (t *FuturesTrader) CancelStopLossOrders(symbol string) error {
@@ -694,18 +901,22 @@ func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity
 		posSide = futures.PositionSideTypeShort
 	}
 
-	// 格式化数量
+	// 格式化数量和触发价
 	quantityStr, err := t.FormatQuantity(symbol, quantity)
 	if err != nil {
 		return err
 	}
+	priceStr, err := t.formatPrice(symbol, stopPrice)
+	if err != nil {
+		return err
+	}
 
 	_, err = t.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(side).
 		PositionSide(posSide).
 		Type(futures.OrderTypeStopMarket).
-		StopPrice(fmt.Sprintf("%.8f", stopPrice)).
+		StopPrice(priceStr).
 		Quantity(quantityStr).
 		WorkingType(futures.WorkingTypeContractPrice).
 		ClosePosition(true).
@@ -732,18 +943,22 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 		posSide = futures.PositionSideTypeShort
 	}
 
-	// 格式化数量
+	// 格式化数量和触发价
 	quantityStr, err := t.FormatQuantity(symbol, quantity)
 	if err != nil {
 		return err
 	}
+	priceStr, err := t.formatPrice(symbol, takeProfitPrice)
+	if err != nil {
+		return err
+	}
 
 	_, err = t.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(side).
 		PositionSide(posSide).
 		Type(futures.OrderTypeTakeProfitMarket).
-		StopPrice(fmt.Sprintf("%.8f", takeProfitPrice)).
+		StopPrice(priceStr).
 		Quantity(quantityStr).
 		WorkingType(futures.WorkingTypeContractPrice).
 		ClosePosition(true).
@@ -757,10 +972,172 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 	return nil
 }
 
-// GetMinNotional 获取最小名义价值（Binance要求）
-func (t *FuturesTrader) GetMinNotional(symbol string) float64 {
-	// 使用保守的默认值 10 USDT，确保订单能够通过交易所验证
-	return 10.0
+// SetPartialTakeProfit 按数量设置止盈单（分批止盈使用，不带 closePosition，双向持仓模式下由 positionSide 保证只减仓）
+func (t *FuturesTrader) SetPartialTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	var side futures.SideType
+	var posSide futures.PositionSideType
+
+	if positionSide == "LONG" {
+		side = futures.SideTypeSell
+		posSide = futures.PositionSideTypeLong
+	} else {
+		side = futures.SideTypeBuy
+		posSide = futures.PositionSideTypeShort
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+	priceStr, err := t.formatPrice(symbol, takeProfitPrice)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(futures.OrderTypeTakeProfitMarket).
+		StopPrice(priceStr).
+		Quantity(quantityStr).
+		WorkingType(futures.WorkingTypeContractPrice).
+		Do(context.Background())
+
+	if err != nil {
+		return fmt.Errorf("设置分批止盈失败: %w", err)
+	}
+
+	log.Printf("  分批止盈设置: %.4f × %s", takeProfitPrice, quantityStr)
+	return nil
+}
+
+// SetTrailingStop 设置跟踪止损单（TRAILING_STOP_MARKET，回调比例0.1%-10%，精度0.1%）
+func (t *FuturesTrader) SetTrailingStop(symbol string, positionSide string, quantity, callbackRate, activationPrice float64) error {
+	var side futures.SideType
+	var posSide futures.PositionSideType
+
+	if positionSide == "LONG" {
+		side = futures.SideTypeSell
+		posSide = futures.PositionSideTypeLong
+	} else {
+		side = futures.SideTypeBuy
+		posSide = futures.PositionSideTypeShort
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+
+	service := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(futures.OrderTypeTrailingStopMarket).
+		Quantity(quantityStr).
+		CallbackRate(strconv.FormatFloat(callbackRate, 'f', 1, 64)).
+		WorkingType(futures.WorkingTypeContractPrice)
+	if activationPrice > 0 {
+		priceStr, err := t.formatPrice(symbol, activationPrice)
+		if err != nil {
+			return err
+		}
+		service = service.ActivationPrice(priceStr)
+	}
+
+	if _, err := service.Do(context.Background()); err != nil {
+		return fmt.Errorf("设置跟踪止损失败: %w", err)
+	}
+
+	log.Printf("  跟踪止损设置: 回调 %.1f%%, 激活价 %.4f", callbackRate, activationPrice)
+	return nil
+}
+
+// CancelTrailingStopOrders 仅取消跟踪止损单（不影响止盈止损单）
+func (t *FuturesTrader) CancelTrailingStopOrders(symbol string) error {
+	orders, err := t.client.NewListOpenOrdersService().
+		Symbol(symbol).
+		Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("获取未完成订单失败: %w", err)
+	}
+
+	canceledCount := 0
+	for _, order := range orders {
+		if order.Type != futures.OrderTypeTrailingStopMarket {
+			continue
+		}
+		_, err := t.client.NewCancelOrderService().
+			Symbol(symbol).
+			OrderID(order.OrderID).
+			Do(context.Background())
+		if err != nil {
+			log.Printf("  ⚠ 取消跟踪止损单 %d 失败: %v", order.OrderID, err)
+			continue
+		}
+		canceledCount++
+	}
+
+	if canceledCount > 0 {
+		log.Printf("  ✓ 已取消 %s 的 %d 个跟踪止损单", symbol, canceledCount)
+	}
+	return nil
+}
+
+// defaultBinanceMinNotional 未获取到合约规则时使用的保守最小名义价值
+const defaultBinanceMinNotional = 10.0
+
+// loadInstruments 加载全部USDT永续合约规则（exchangeInfo 过滤器 + 杠杆分层的最大杠杆）
+func (t *FuturesTrader) loadInstruments() (map[string]Instrument, error) {
+	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+
+	specs := make(map[string]Instrument, len(exchangeInfo.Symbols))
+	for _, s := range exchangeInfo.Symbols {
+		if s.QuoteAsset != "USDT" || s.ContractType != "PERPETUAL" {
+			continue
+		}
+		spec := Instrument{Symbol: s.Symbol, ContractSize: 1}
+		if f := s.PriceFilter(); f != nil {
+			spec.TickSize, _ = strconv.ParseFloat(f.TickSize, 64)
+		}
+		if f := s.LotSizeFilter(); f != nil {
+			spec.QtyStep, _ = strconv.ParseFloat(f.StepSize, 64)
+			spec.MinQty, _ = strconv.ParseFloat(f.MinQuantity, 64)
+		}
+		if f := s.MinNotionalFilter(); f != nil {
+			spec.MinNotional, _ = strconv.ParseFloat(f.Notional, 64)
+		}
+		specs[s.Symbol] = spec
+	}
+
+	// 杠杆分层需要签名接口，失败时不影响数量和价格精度
+	brackets, err := t.client.NewGetLeverageBracketService().Do(context.Background())
+	if err != nil {
+		log.Printf("⚠️ 获取币安杠杆分层失败，最大杠杆未知: %v", err)
+		return specs, nil
+	}
+	for _, b := range brackets {
+		spec, ok := specs[b.Symbol]
+		if !ok {
+			continue
+		}
+		for _, bracket := range b.Brackets {
+			if bracket.InitialLeverage > spec.MaxLeverage {
+				spec.MaxLeverage = bracket.InitialLeverage
+			}
+		}
+		specs[b.Symbol] = spec
+	}
+	return specs, nil
+}
+
+// GetInstrument 获取合约交易规则
+func (t *FuturesTrader) GetInstrument(symbol string) (*Instrument, error) {
+	return t.instruments.get(symbol)
 }
 
 // CheckMinNotional 检查订单是否满足最小名义价值要求
@@ -771,7 +1148,10 @@ func (t *FuturesTrader) CheckMinNotional(symbol string, quantity float64) error
 	}
 
 	notionalValue := quantity * price
-	minNotional := t.GetMinNotional(symbol)
+	minNotional := defaultBinanceMinNotional
+	if inst, err := t.instruments.get(symbol); err == nil && inst.MinNotional > 0 {
+		minNotional = inst.MinNotional
+	}
 
 	if notionalValue < minNotional {
 		return fmt.Errorf(
@@ -783,29 +1163,13 @@ func (t *FuturesTrader) CheckMinNotional(symbol string, quantity float64) error
 	return nil
 }
 
-// GetSymbolPrecision 获取交易对的数量精度
-func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
-	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
+// formatPrice 按 PRICE_FILTER 的 tickSize 格式化价格（限价、止损、止盈）
+func (t *FuturesTrader) formatPrice(symbol string, price float64) (string, error) {
+	inst, err := t.instruments.get(symbol)
 	if err != nil {
-		return 0, fmt.Errorf("获取交易规则失败: %w", err)
-	}
-
-	for _, s := range exchangeInfo.Symbols {
-		if s.Symbol == symbol {
-			// 从LOT_SIZE filter获取精度
-			for _, filter := range s.Filters {
-				if filter["filterType"] == "LOT_SIZE" {
-					stepSize := filter["stepSize"].(string)
-					precision := calculatePrecision(stepSize)
-					log.Printf("  %s 数量精度: %d (stepSize: %s)", symbol, precision, stepSize)
-					return precision, nil
-				}
-			}
-		}
+		return "", err
 	}
-
-	log.Printf("  ⚠ %s 未找到精度信息，使用默认精度3", symbol)
-	return 3, nil // 默认精度为3
+	return inst.FormatPrice(price), nil
 }
 
 // calculatePrecision 从stepSize计算精度
@@ -853,14 +1217,83 @@ func trimTrailingZeros(s string) string {
 
 // FormatQuantity 格式化数量到正确的精度
 func (t *FuturesTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
-	precision, err := t.GetSymbolPrecision(symbol)
+	inst, err := t.instruments.get(symbol)
 	if err != nil {
-		// 如果获取失败，使用默认格式
-		return fmt.Sprintf("%.3f", quantity), nil
+		return "", err
+	}
+	return inst.FormatQuantity(quantity), nil
+}
+
+// binanceHistoryWindow 币安成交历史单次查询的最大时间跨度
+const binanceHistoryWindow = 7 * 24 * time.Hour
+
+// GetUserTrades 获取成交明细（同一订单的多笔成交合并为一条，按时间升序）
+func (t *FuturesTrader) GetUserTrades(symbol string, startTime time.Time) ([]Fill, error) {
+	var fills []Fill
+
+	// 币安 userTrades 接口的 startTime/endTime 跨度不能超过7天，按窗口分段查询
+	for start := startTime; start.Before(time.Now()); start = start.Add(binanceHistoryWindow) {
+		end := start.Add(binanceHistoryWindow)
+		trades, err := t.client.NewListAccountTradeService().
+			Symbol(symbol).
+			StartTime(start.UnixMilli()).
+			EndTime(end.UnixMilli()).
+			Limit(1000).
+			Do(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("获取成交历史失败: %w", err)
+		}
+
+		for _, trade := range trades {
+			price, _ := strconv.ParseFloat(trade.Price, 64)
+			qty, _ := strconv.ParseFloat(trade.Quantity, 64)
+			fee, _ := strconv.ParseFloat(trade.Commission, 64)
+			pnl, _ := strconv.ParseFloat(trade.RealizedPnl, 64)
+
+			side := strings.ToLower(string(trade.PositionSide))
+			fills = append(fills, Fill{
+				OrderID:     formatOrderID(trade.OrderID),
+				Symbol:      trade.Symbol,
+				Side:        side,
+				IsClose:     (side == "long" && trade.Side == futures.SideTypeSell) || (side == "short" && trade.Side == futures.SideTypeBuy),
+				Price:       price,
+				Quantity:    qty,
+				Fee:         fee,
+				RealizedPnL: pnl,
+				Time:        time.UnixMilli(trade.Time),
+			})
+		}
 	}
 
-	format := fmt.Sprintf("%%.%df", precision)
-	return fmt.Sprintf(format, quantity), nil
+	return mergeFills(fills), nil
+}
+
+// GetFundingFees 获取累计资金费
+func (t *FuturesTrader) GetFundingFees(symbol string, startTime time.Time) (float64, error) {
+	total := 0.0
+	start := startTime.UnixMilli()
+	for {
+		incomes, err := t.client.NewGetIncomeHistoryService().
+			Symbol(symbol).
+			IncomeType("FUNDING_FEE").
+			StartTime(start).
+			Limit(1000).
+			Do(context.Background())
+		if err != nil {
+			return 0, fmt.Errorf("获取资金费流水失败: %w", err)
+		}
+
+		for _, income := range incomes {
+			amount, _ := strconv.ParseFloat(income.Income, 64)
+			total += amount
+			if income.Time >= start {
+				start = income.Time + 1
+			}
+		}
+		if len(incomes) < 1000 {
+			return total, nil
+		}
+	}
 }
 
 // 辅助函数
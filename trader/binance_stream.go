@@ -0,0 +1,206 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// binanceListenKeyKeepalive listenKey 续期间隔（60分钟无续期失效）
+const binanceListenKeyKeepalive = 30 * time.Minute
+
+// StreamAccount 订阅币安用户数据流（ACCOUNT_UPDATE / ORDER_TRADE_UPDATE）和全市场标记价格
+func (t *FuturesTrader) StreamAccount(stop <-chan struct{}, onUpdate func(AccountUpdate)) error {
+	listenKey, err := t.client.NewStartUserStreamService().Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("获取listenKey失败: %w", err)
+	}
+
+	errC := make(chan error, 2)
+	errHandler := func(err error) {
+		select {
+		case errC <- err:
+		default:
+		}
+	}
+
+	// 用户数据流回调在同一个goroutine中顺序执行，累计手续费无需加锁
+	fees := make(map[int64]*binanceOrderFee)
+	userDone, userStop, err := futures.WsUserDataServe(listenKey, func(event *futures.WsUserDataEvent) {
+		if event.Event == futures.UserDataEventTypeListenKeyExpired {
+			errHandler(fmt.Errorf("listenKey已过期"))
+			return
+		}
+		if update, ok := binanceAccountUpdate(event, fees); ok {
+			onUpdate(update)
+		}
+	}, errHandler)
+	if err != nil {
+		return fmt.Errorf("连接用户数据流失败: %w", err)
+	}
+	defer close(userStop)
+
+	markDone, markStop, err := futures.WsAllMarkPriceServe(func(event futures.WsAllMarkPriceEvent) {
+		prices := make(map[string]float64, len(event))
+		for _, e := range event {
+			if price, err := strconv.ParseFloat(e.MarkPrice, 64); err == nil && price > 0 {
+				prices[e.Symbol] = price
+			}
+		}
+		onUpdate(AccountUpdate{MarkPrices: prices, UnrealizedExcluded: true})
+	}, errHandler)
+	if err != nil {
+		return fmt.Errorf("连接标记价格推送失败: %w", err)
+	}
+	defer close(markStop)
+
+	t.clearCache()
+	onUpdate(AccountUpdate{Resync: true, UnrealizedExcluded: true})
+
+	keepalive := time.NewTicker(binanceListenKeyKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-keepalive.C:
+			if err := t.client.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(context.Background()); err != nil {
+				return fmt.Errorf("listenKey续期失败: %w", err)
+			}
+		case err := <-errC:
+			return err
+		case <-userDone:
+			return fmt.Errorf("用户数据流已断开")
+		case <-markDone:
+			return fmt.Errorf("标记价格推送已断开")
+		}
+	}
+}
+
+// clearCache 清除余额和持仓缓存（推送重连后全量同步需要最新数据）
+func (t *FuturesTrader) clearCache() {
+	t.balanceCacheMutex.Lock()
+	t.cachedBalance = nil
+	t.balanceCacheMutex.Unlock()
+
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = nil
+	t.positionsCacheMutex.Unlock()
+}
+
+// binanceOrderFee 订单已推送成交的累计手续费和数量
+type binanceOrderFee struct {
+	fee float64
+	qty float64
+}
+
+// binanceAccountUpdate 用户数据流事件转换为账户推送（无关事件返回false）
+// ORDER_TRADE_UPDATE 的手续费只是最近一笔成交的，按订单累计到 fees 中，订单结束后清除
+func binanceAccountUpdate(event *futures.WsUserDataEvent, fees map[int64]*binanceOrderFee) (AccountUpdate, bool) {
+	update := AccountUpdate{UnrealizedExcluded: true}
+	switch event.Event {
+	case futures.UserDataEventTypeAccountUpdate:
+		account := event.AccountUpdate
+		for _, b := range account.Balances {
+			if b.Asset != "USDT" {
+				continue
+			}
+			wallet, _ := strconv.ParseFloat(b.Balance, 64)
+			update.Balance = &Balance{TotalWalletBalance: wallet}
+		}
+		for _, p := range account.Positions {
+			amount, _ := strconv.ParseFloat(p.Amount, 64)
+			entryPrice, _ := strconv.ParseFloat(p.EntryPrice, 64)
+			markPrice, _ := strconv.ParseFloat(p.MarkPrice, 64)
+			pnl, _ := strconv.ParseFloat(p.UnrealizedPnL, 64)
+			pos := Position{
+				Symbol:           p.Symbol,
+				Side:             strings.ToLower(string(p.Side)),
+				EntryPrice:       entryPrice,
+				MarkPrice:        markPrice,
+				Quantity:         amount,
+				UnrealizedProfit: pnl,
+			}
+			// 单向持仓模式按数量正负判断方向，平仓时两个方向都可能需要清除
+			if p.Side == futures.PositionSideTypeBoth {
+				if amount == 0 {
+					long, short := pos, pos
+					long.Side, short.Side = "long", "short"
+					update.Positions = append(update.Positions, long, short)
+					continue
+				}
+				pos.Side = "long"
+				if amount < 0 {
+					pos.Side = "short"
+				}
+			}
+			if pos.Quantity < 0 {
+				pos.Quantity = -pos.Quantity
+			}
+			update.Positions = append(update.Positions, pos)
+		}
+		return update, true
+
+	case futures.UserDataEventTypeOrderTradeUpdate:
+		o := event.OrderTradeUpdate
+		orderType := o.OriginalType
+		if orderType == "" {
+			orderType = o.Type
+		}
+		avgPrice, _ := strconv.ParseFloat(o.AveragePrice, 64)
+		executedQty, _ := strconv.ParseFloat(o.AccumulatedFilledQty, 64)
+		fee := binanceAccumulateFee(fees, o, executedQty)
+		reduceOnly := o.IsReduceOnly || o.IsClosingPosition
+		side := strings.ToLower(string(o.Side))
+		positionSide := strings.ToLower(string(o.PositionSide))
+		if o.PositionSide == futures.PositionSideTypeBoth {
+			positionSide = inferPositionSide(side, reduceOnly)
+		}
+		update.Orders = append(update.Orders, OrderUpdate{
+			OrderID:      strconv.FormatInt(o.ID, 10),
+			Symbol:       o.Symbol,
+			Side:         side,
+			PositionSide: positionSide,
+			Type:         openOrderTypeFromBinance(string(orderType)),
+			Status:       binanceOrderStatus(o.Status),
+			AvgPrice:     avgPrice,
+			ExecutedQty:  executedQty,
+			Fee:          fee,
+			ReduceOnly:   reduceOnly,
+			Time:         time.UnixMilli(o.TradeTime),
+		})
+		return update, true
+	}
+	return update, false
+}
+
+// binanceAccumulateFee 累计订单各笔成交的手续费，返回订单目前为止的总手续费
+// 推送重连等原因漏掉部分成交时累计数量对不上，返回0，由调用方按成交明细（GetUserTrades）补全
+func binanceAccumulateFee(fees map[int64]*binanceOrderFee, o futures.WsOrderTradeUpdate, executedQty float64) float64 {
+	acc := fees[o.ID]
+	if o.ExecutionType == futures.OrderExecutionTypeTrade {
+		if acc == nil {
+			acc = &binanceOrderFee{}
+			fees[o.ID] = acc
+		}
+		fee, _ := strconv.ParseFloat(o.Commission, 64)
+		qty, _ := strconv.ParseFloat(o.LastFilledQty, 64)
+		acc.fee += fee
+		acc.qty += qty
+	}
+
+	switch o.Status {
+	case futures.OrderStatusTypeFilled, futures.OrderStatusTypeCanceled, futures.OrderStatusTypeExpired, futures.OrderStatusTypeRejected:
+		delete(fees, o.ID)
+	}
+
+	if acc == nil || executedQty <= 0 || math.Abs(acc.qty-executedQty) > executedQty*1e-9 {
+		return 0
+	}
+	return acc.fee
+}
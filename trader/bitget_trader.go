@@ -0,0 +1,1132 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"nofx/config"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bitget USDT-M 永续合约（V2接口）
+const (
+	bitgetBaseURL     = "https://api.bitget.com"
+	bitgetProductType = "USDT-FUTURES"
+	bitgetMarginCoin  = "USDT"
+	bitgetSuccessCode = "00000"
+
+	bitgetCodeTimestampExpired = "40008" // 请求时间戳过期
+)
+
+// isBitgetTimestampRejected 响应是否为时间戳被拒绝（需要重新同步服务器时间）
+func isBitgetTimestampRejected(body []byte) bool {
+	var resp struct {
+		Code string `json:"code"`
+	}
+	return json.Unmarshal(body, &resp) == nil && resp.Code == bitgetCodeTimestampExpired
+}
+
+// BitgetTrader Bitget USDT永续合约交易器（双向持仓模式）
+type BitgetTrader struct {
+	rest *restClient
+
+	// 余额缓存
+	cachedBalance     *Balance
+	balanceCacheTime  time.Time
+	balanceCacheMutex sync.RWMutex
+
+	// 持仓缓存
+	cachedPositions     []Position
+	positionsCacheTime  time.Time
+	positionsCacheMutex sync.RWMutex
+
+	// 缓存有效期（15秒）
+	cacheDuration time.Duration
+
+	// 合约规则缓存（contracts）
+	instruments *instrumentRegistry
+
+	// 交易对保证金模式（下单时必须携带，"crossed" / "isolated"）
+	marginModes     map[string]string
+	marginModeMutex sync.RWMutex
+}
+
+// bitgetAPIError Bitget接口返回的业务错误
+type bitgetAPIError struct {
+	Code string
+	Msg  string
+}
+
+func (e *bitgetAPIError) Error() string {
+	return fmt.Sprintf("Bitget API错误: code=%s, msg=%s", e.Code, e.Msg)
+}
+
+// NewBitgetTrader 创建Bitget合约交易器（testnet 使用模拟盘，需要模拟盘API Key）
+func NewBitgetTrader(apiKey, secretKey, passphrase string, testnet bool) *BitgetTrader {
+	trader := &BitgetTrader{
+		rest:          newRESTClient("Bitget", bitgetBaseURL, newBitgetSigner(apiKey, secretKey, passphrase)),
+		cacheDuration: 15 * time.Second,
+		marginModes:   make(map[string]string),
+	}
+	if testnet {
+		trader.rest.header.Set("paptrading", "1")
+	}
+	trader.instruments = sharedInstrumentRegistry(instrumentKey("bitget", testnet), "Bitget", trader.loadInstruments)
+	trader.rest.timestampRejected = isBitgetTimestampRejected
+	trader.rest.clock.sync("Bitget", trader.fetchServerTime)
+
+	// 设置双向持仓模式
+	if err := trader.setHedgeMode(); err != nil {
+		log.Printf("⚠️ 设置Bitget双向持仓模式失败: %v (如果已是双向模式则忽略此警告)", err)
+	}
+
+	log.Printf("✓ Bitget交易器初始化成功 (testnet=%v)", testnet)
+	return trader
+}
+
+// request 发送签名请求，返回data字段（GET参数放在查询字符串，POST参数为JSON请求体）
+func (t *BitgetTrader) request(method, path string, params map[string]interface{}) (json.RawMessage, error) {
+	return t.send(method, path, params, true)
+}
+
+// requestPublic 发送公共GET请求（合约规则等不需要签名的接口，供共享的合约规则注册表使用）
+func (t *BitgetTrader) requestPublic(path string, params map[string]interface{}) (json.RawMessage, error) {
+	return t.send(http.MethodGet, path, params, false)
+}
+
+// send 发送请求并解析Bitget标准响应
+func (t *BitgetTrader) send(method, path string, params map[string]interface{}, signed bool) (json.RawMessage, error) {
+	var respBody []byte
+	var err error
+	if method == http.MethodGet {
+		query := url.Values{}
+		for k, v := range params {
+			query.Set(k, fmt.Sprint(v))
+		}
+		respBody, err = t.rest.doRequest(method, path, query, nil, signed)
+	} else {
+		respBody, err = t.rest.doRequest(method, path, nil, params, signed)
+	}
+
+	// Bitget业务错误以4xx返回，响应体仍是标准格式
+	var httpErr *restHTTPError
+	if errors.As(err, &httpErr) {
+		respBody = httpErr.Body
+	} else if err != nil {
+		return nil, err
+	}
+
+	var bitgetResp struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if jsonErr := json.Unmarshal(respBody, &bitgetResp); jsonErr != nil || bitgetResp.Code == "" {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("解析响应失败: %s", string(respBody))
+	}
+	if bitgetResp.Code != bitgetSuccessCode {
+		return nil, &bitgetAPIError{Code: bitgetResp.Code, Msg: bitgetResp.Msg}
+	}
+	return bitgetResp.Data, nil
+}
+
+// fetchServerTime 获取Bitget服务器时间（时间戳与服务器相差超过30秒会被拒绝）
+func (t *BitgetTrader) fetchServerTime() (time.Time, error) {
+	body, err := t.rest.getPublic("/api/v2/public/time", nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var resp struct {
+		Data struct {
+			ServerTime string `json:"serverTime"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return time.Time{}, fmt.Errorf("解析服务器时间失败: %w", err)
+	}
+	ms, err := strconv.ParseInt(resp.Data.ServerTime, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("解析服务器时间失败: %w", err)
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// setHedgeMode 设置双向持仓模式（有持仓或挂单时交易所会拒绝）
+func (t *BitgetTrader) setHedgeMode() error {
+	if _, err := t.request(http.MethodPost, "/api/v2/mix/account/set-position-mode", map[string]interface{}{
+		"productType": bitgetProductType,
+		"posMode":     "hedge_mode",
+	}); err != nil {
+		return err
+	}
+	log.Printf("  ✓ Bitget账户已设置为双向持仓模式（Hedge Mode）")
+	return nil
+}
+
+// loadInstruments 加载全部USDT永续合约规则
+func (t *BitgetTrader) loadInstruments() (map[string]Instrument, error) {
+	data, err := t.requestPublic("/api/v2/mix/market/contracts", map[string]interface{}{
+		"productType": bitgetProductType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var contracts []struct {
+		Symbol         string `json:"symbol"`
+		PricePlace     string `json:"pricePlace"`
+		PriceEndStep   string `json:"priceEndStep"`
+		SizeMultiplier string `json:"sizeMultiplier"`
+		MinTradeNum    string `json:"minTradeNum"`
+		MinTradeUSDT   string `json:"minTradeUSDT"`
+		MaxLever       string `json:"maxLever"`
+	}
+	if err := json.Unmarshal(data, &contracts); err != nil {
+		return nil, fmt.Errorf("解析合约规则失败: %w", err)
+	}
+
+	specs := make(map[string]Instrument, len(contracts))
+	for _, c := range contracts {
+		spec := Instrument{Symbol: c.Symbol, ContractSize: 1}
+		pricePlace, _ := strconv.Atoi(c.PricePlace)
+		priceEndStep, _ := strconv.ParseFloat(c.PriceEndStep, 64)
+		if priceEndStep <= 0 {
+			priceEndStep = 1
+		}
+		spec.TickSize = priceEndStep / math.Pow10(pricePlace)
+		spec.QtyStep, _ = strconv.ParseFloat(c.SizeMultiplier, 64)
+		spec.MinQty, _ = strconv.ParseFloat(c.MinTradeNum, 64)
+		spec.MinNotional, _ = strconv.ParseFloat(c.MinTradeUSDT, 64)
+		spec.MaxLeverage = parseLeverage(c.MaxLever)
+		specs[c.Symbol] = spec
+	}
+	return specs, nil
+}
+
+// GetBalance 获取USDT合约账户余额（带缓存）
+func (t *BitgetTrader) GetBalance() (*Balance, error) {
+	t.balanceCacheMutex.RLock()
+	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
+		cacheAge := time.Since(t.balanceCacheTime)
+		t.balanceCacheMutex.RUnlock()
+		log.Printf("✓ 使用缓存的账户余额（缓存时间: %.1f秒前）", cacheAge.Seconds())
+		return t.cachedBalance, nil
+	}
+	t.balanceCacheMutex.RUnlock()
+
+	log.Printf("🔄 缓存过期，正在调用Bitget API获取账户余额...")
+	data, err := t.request(http.MethodGet, "/api/v2/mix/account/accounts", map[string]interface{}{
+		"productType": bitgetProductType,
+	})
+	if err != nil {
+		log.Printf("❌ Bitget API调用失败: %v", err)
+		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+	}
+
+	var accounts []struct {
+		MarginCoin          string `json:"marginCoin"`
+		Available           string `json:"available"`
+		CrossedMaxAvailable string `json:"crossedMaxAvailable"`
+		AccountEquity       string `json:"accountEquity"`
+		UnrealizedPL        string `json:"unrealizedPL"`
+	}
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return nil, fmt.Errorf("解析余额数据失败: %w", err)
+	}
+
+	for _, account := range accounts {
+		if account.MarginCoin != bitgetMarginCoin {
+			continue
+		}
+		balance := &Balance{}
+		balance.TotalEquity, _ = strconv.ParseFloat(account.AccountEquity, 64)
+		balance.TotalUnrealizedProfit, _ = strconv.ParseFloat(account.UnrealizedPL, 64)
+		balance.TotalWalletBalance = balance.TotalEquity - balance.TotalUnrealizedProfit
+		balance.AvailableBalance, _ = strconv.ParseFloat(account.CrossedMaxAvailable, 64)
+		if balance.AvailableBalance <= 0 {
+			balance.AvailableBalance, _ = strconv.ParseFloat(account.Available, 64)
+		}
+
+		log.Printf("✓ Bitget API返回: 总余额=%.2f, 可用=%.2f, 未实现盈亏=%.2f",
+			balance.TotalWalletBalance, balance.AvailableBalance, balance.TotalUnrealizedProfit)
+
+		t.balanceCacheMutex.Lock()
+		t.cachedBalance = balance
+		t.balanceCacheTime = time.Now()
+		t.balanceCacheMutex.Unlock()
+		return balance, nil
+	}
+	return nil, fmt.Errorf("未找到USDT余额信息")
+}
+
+// GetPositions 获取所有USDT永续持仓（带缓存）
+func (t *BitgetTrader) GetPositions() ([]Position, error) {
+	t.positionsCacheMutex.RLock()
+	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
+		cacheAge := time.Since(t.positionsCacheTime)
+		t.positionsCacheMutex.RUnlock()
+		log.Printf("✓ 使用缓存的持仓信息（缓存时间: %.1f秒前）", cacheAge.Seconds())
+		return t.cachedPositions, nil
+	}
+	t.positionsCacheMutex.RUnlock()
+
+	log.Printf("🔄 缓存过期，正在调用Bitget API获取持仓信息...")
+	data, err := t.request(http.MethodGet, "/api/v2/mix/position/all-position", map[string]interface{}{
+		"productType": bitgetProductType,
+		"marginCoin":  bitgetMarginCoin,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	var positions []struct {
+		Symbol           string `json:"symbol"`
+		HoldSide         string `json:"holdSide"` // long / short
+		Total            string `json:"total"`
+		OpenPriceAvg     string `json:"openPriceAvg"`
+		MarkPrice        string `json:"markPrice"`
+		UnrealizedPL     string `json:"unrealizedPL"`
+		Leverage         string `json:"leverage"`
+		LiquidationPrice string `json:"liquidationPrice"`
+		MarginSize       string `json:"marginSize"`
+		MarginMode       string `json:"marginMode"`
+	}
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return nil, fmt.Errorf("解析持仓数据失败: %w", err)
+	}
+
+	result := []Position{}
+	for _, pos := range positions {
+		size, _ := strconv.ParseFloat(pos.Total, 64)
+		if size == 0 {
+			continue
+		}
+		p := Position{Symbol: pos.Symbol, Side: pos.HoldSide, Quantity: size}
+		p.EntryPrice, _ = strconv.ParseFloat(pos.OpenPriceAvg, 64)
+		p.MarkPrice, _ = strconv.ParseFloat(pos.MarkPrice, 64)
+		p.UnrealizedProfit, _ = strconv.ParseFloat(pos.UnrealizedPL, 64)
+		p.LiquidationPrice, _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
+		p.Margin, _ = strconv.ParseFloat(pos.MarginSize, 64)
+		leverage, _ := strconv.ParseFloat(pos.Leverage, 64)
+		p.Leverage = int(leverage)
+		result = append(result, p)
+
+		if pos.MarginMode != "" {
+			t.marginModeMutex.Lock()
+			t.marginModes[pos.Symbol] = pos.MarginMode
+			t.marginModeMutex.Unlock()
+		}
+	}
+
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = result
+	t.positionsCacheTime = time.Now()
+	t.positionsCacheMutex.Unlock()
+
+	return result, nil
+}
+
+// clearCache 下单后清除余额和持仓缓存
+func (t *BitgetTrader) clearCache() {
+	t.balanceCacheMutex.Lock()
+	t.cachedBalance = nil
+	t.balanceCacheMutex.Unlock()
+
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = nil
+	t.positionsCacheMutex.Unlock()
+}
+
+// getMarginMode 获取交易对保证金模式（未缓存时查询单币种账户）
+func (t *BitgetTrader) getMarginMode(symbol string) string {
+	t.marginModeMutex.RLock()
+	mode, ok := t.marginModes[symbol]
+	t.marginModeMutex.RUnlock()
+	if ok {
+		return mode
+	}
+
+	mode = "crossed"
+	data, err := t.request(http.MethodGet, "/api/v2/mix/account/account", map[string]interface{}{
+		"symbol":      symbol,
+		"productType": bitgetProductType,
+		"marginCoin":  bitgetMarginCoin,
+	})
+	if err == nil {
+		var account struct {
+			MarginMode string `json:"marginMode"`
+		}
+		if json.Unmarshal(data, &account) == nil && account.MarginMode != "" {
+			mode = account.MarginMode
+		}
+	} else {
+		log.Printf("  ⚠️ 查询 %s 保证金模式失败，按全仓处理: %v", symbol, err)
+	}
+
+	t.marginModeMutex.Lock()
+	t.marginModes[symbol] = mode
+	t.marginModeMutex.Unlock()
+	return mode
+}
+
+// SetMarginMode 设置保证金模式（有持仓或挂单时交易所会拒绝）
+func (t *BitgetTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	mode := "isolated"
+	if isCrossMargin {
+		mode = "crossed"
+	}
+	if _, err := t.request(http.MethodPost, "/api/v2/mix/account/set-margin-mode", map[string]interface{}{
+		"symbol":      symbol,
+		"productType": bitgetProductType,
+		"marginCoin":  bitgetMarginCoin,
+		"marginMode":  mode,
+	}); err != nil {
+		return fmt.Errorf("设置保证金模式失败: %w", err)
+	}
+
+	t.marginModeMutex.Lock()
+	t.marginModes[symbol] = mode
+	t.marginModeMutex.Unlock()
+	log.Printf("  ✓ %s 保证金模式: %s", symbol, mode)
+	return nil
+}
+
+// SetLeverage 设置杠杆（逐仓模式下多空分别设置）
+func (t *BitgetTrader) SetLeverage(symbol string, leverage int) error {
+	if inst, err := t.instruments.get(symbol); err == nil {
+		leverage = inst.ClampLeverage(leverage)
+	}
+
+	holdSides := []string{""}
+	if t.getMarginMode(symbol) == "isolated" {
+		holdSides = []string{"long", "short"}
+	}
+	for _, holdSide := range holdSides {
+		params := map[string]interface{}{
+			"symbol":      symbol,
+			"productType": bitgetProductType,
+			"marginCoin":  bitgetMarginCoin,
+			"leverage":    strconv.Itoa(leverage),
+		}
+		if holdSide != "" {
+			params["holdSide"] = holdSide
+		}
+		if _, err := t.request(http.MethodPost, "/api/v2/mix/account/set-leverage", params); err != nil {
+			return fmt.Errorf("设置杠杆失败: %w", err)
+		}
+	}
+	log.Printf("  ✓ %s 杠杆已设置为 %dx", symbol, leverage)
+	return nil
+}
+
+// GetInstrument 获取合约交易规则
+func (t *BitgetTrader) GetInstrument(symbol string) (*Instrument, error) {
+	return t.instruments.get(symbol)
+}
+
+// FormatQuantity 格式化数量到合约数量步进
+func (t *BitgetTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	spec, err := t.instruments.get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatQuantity(quantity), nil
+}
+
+// formatPrice 格式化价格到合约价格步进
+func (t *BitgetTrader) formatPrice(symbol string, price float64) (string, error) {
+	spec, err := t.instruments.get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatPrice(price), nil
+}
+
+// CheckMinNotional 检查下单数量和金额是否满足合约最小要求
+func (t *BitgetTrader) CheckMinNotional(symbol string, quantity float64) error {
+	spec, err := t.instruments.get(symbol)
+	if err != nil {
+		return err
+	}
+	price := 0.0
+	if spec.MinNotional > 0 {
+		if price, err = t.GetMarketPrice(symbol); err != nil {
+			return err
+		}
+	}
+	return spec.CheckMin(quantity, price)
+}
+
+// GetMarketPrice 获取最新成交价
+func (t *BitgetTrader) GetMarketPrice(symbol string) (float64, error) {
+	data, err := t.request(http.MethodGet, "/api/v2/mix/market/ticker", map[string]interface{}{
+		"symbol":      symbol,
+		"productType": bitgetProductType,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+	var tickers []struct {
+		LastPr string `json:"lastPr"`
+	}
+	if err := json.Unmarshal(data, &tickers); err != nil {
+		return 0, fmt.Errorf("解析价格失败: %w", err)
+	}
+	if len(tickers) == 0 {
+		return 0, fmt.Errorf("未找到 %s 的价格", symbol)
+	}
+	price, err := strconv.ParseFloat(tickers[0].LastPr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析价格失败: %w", err)
+	}
+	return price, nil
+}
+
+// bitgetSide 双向持仓模式下 side 表示持仓方向（开多和平多都是 buy）
+func bitgetSide(positionSide string) string {
+	if positionSide == "short" {
+		return "sell"
+	}
+	return "buy"
+}
+
+// placeOrder 下单，返回订单ID
+func (t *BitgetTrader) placeOrder(params map[string]interface{}) (string, error) {
+	params["productType"] = bitgetProductType
+	params["marginCoin"] = bitgetMarginCoin
+	params["marginMode"] = t.getMarginMode(params["symbol"].(string))
+	data, err := t.request(http.MethodPost, "/api/v2/mix/order/place-order", params)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		OrderID string `json:"orderId"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("解析下单响应失败: %w", err)
+	}
+	t.clearCache()
+	return result.OrderID, nil
+}
+
+// OpenLong 开多仓（止盈止损随订单附加）
+func (t *BitgetTrader) OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
+	return t.openPosition(symbol, "long", quantity, leverage, stopLoss, takeProfit)
+}
+
+// OpenShort 开空仓（止盈止损随订单附加）
+func (t *BitgetTrader) OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
+	return t.openPosition(symbol, "short", quantity, leverage, stopLoss, takeProfit)
+}
+
+// openPosition 市价开仓
+func (t *BitgetTrader) openPosition(symbol, side string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
+	// 先取消该币种的所有委托单（清理旧的挂单）
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	quantityFloat, parseErr := strconv.ParseFloat(quantityStr, 64)
+	if parseErr != nil || quantityFloat <= 0 {
+		return nil, fmt.Errorf("开仓数量过小，格式化后为 0 (原始: %.8f → 格式化: %s)", quantity, quantityStr)
+	}
+	if err := t.CheckMinNotional(symbol, quantityFloat); err != nil {
+		return nil, err
+	}
+
+	params := map[string]interface{}{
+		"symbol":    symbol,
+		"size":      quantityStr,
+		"side":      bitgetSide(side),
+		"tradeSide": "open",
+		"orderType": "market",
+	}
+	if stopLoss > 0 {
+		priceStr, err := t.formatPrice(symbol, stopLoss)
+		if err != nil {
+			return nil, err
+		}
+		params["presetStopLossPrice"] = priceStr
+	}
+	if takeProfit > 0 {
+		priceStr, err := t.formatPrice(symbol, takeProfit)
+		if err != nil {
+			return nil, err
+		}
+		params["presetStopSurplusPrice"] = priceStr
+	}
+
+	orderID, err := t.placeOrder(params)
+	if err != nil {
+		return nil, fmt.Errorf("开%s仓失败: %w", sideLabel(side), err)
+	}
+	log.Printf("✓ 开%s仓成功: %s 数量: %s 订单ID: %s", sideLabel(side), symbol, quantityStr, orderID)
+
+	return &OrderResult{OrderID: orderID, Symbol: symbol, Quantity: quantityFloat}, nil
+}
+
+// CloseLong 平多仓
+func (t *BitgetTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
+	return t.closePosition(symbol, "long", quantity)
+}
+
+// CloseShort 平空仓
+func (t *BitgetTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
+	return t.closePosition(symbol, "short", quantity)
+}
+
+// closePosition 市价平仓（quantity=0表示全部平仓）
+// 持仓止盈止损在持仓全部平掉后由交易所自动撤销，不在这里撤单（避免误撤同币种另一方向的止损）
+func (t *BitgetTrader) closePosition(symbol, side string, quantity float64) (*OrderResult, error) {
+	if quantity == 0 {
+		positions, err := t.GetPositions()
+		if err != nil {
+			return nil, err
+		}
+		if pos, ok := FindPosition(positions, symbol, side); ok {
+			quantity = pos.Quantity
+		}
+		if quantity == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的%s仓", symbol, sideLabel(side))
+		}
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	orderID, err := t.placeOrder(map[string]interface{}{
+		"symbol":    symbol,
+		"size":      quantityStr,
+		"side":      bitgetSide(side),
+		"tradeSide": "close",
+		"orderType": "market",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("平%s仓失败: %w", sideLabel(side), err)
+	}
+	log.Printf("✓ 平%s仓成功: %s 数量: %s", sideLabel(side), symbol, quantityStr)
+
+	result := &OrderResult{OrderID: orderID, Symbol: symbol}
+	result.Quantity, _ = strconv.ParseFloat(quantityStr, 64)
+	return result, nil
+}
+
+// PlaceEntryOrder 按订单类型开仓（post_only 会立即成交时交易所直接撤单）
+func (t *BitgetTrader) PlaceEntryOrder(order EntryOrder) (*OrderResult, error) {
+	if err := order.validate(); err != nil {
+		return nil, err
+	}
+	if order.OrderType == OrderTypeMarket {
+		return order.openMarket(t)
+	}
+
+	if err := t.SetLeverage(order.Symbol, order.Leverage); err != nil {
+		return nil, err
+	}
+	quantityStr, err := t.FormatQuantity(order.Symbol, order.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	quantityFloat, parseErr := strconv.ParseFloat(quantityStr, 64)
+	if parseErr != nil || quantityFloat <= 0 {
+		return nil, fmt.Errorf("开仓数量过小，格式化后为 0 (原始: %.8f → 格式化: %s)", order.Quantity, quantityStr)
+	}
+	if err := t.CheckMinNotional(order.Symbol, quantityFloat); err != nil {
+		return nil, err
+	}
+	priceStr, err := t.formatPrice(order.Symbol, order.Price)
+	if err != nil {
+		return nil, err
+	}
+
+	force := "gtc"
+	switch order.OrderType {
+	case OrderTypePostOnly:
+		force = "post_only"
+	case OrderTypeIOC:
+		force = "ioc"
+	}
+
+	orderID, err := t.placeOrder(map[string]interface{}{
+		"symbol":    order.Symbol,
+		"size":      quantityStr,
+		"price":     priceStr,
+		"side":      bitgetSide(order.Side),
+		"tradeSide": "open",
+		"orderType": "limit",
+		"force":     force,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s开仓失败: %w", OrderTypeLabel(order.OrderType), err)
+	}
+
+	// 下单接口不返回成交状态，查询一次订单
+	result, err := t.GetOrder(order.Symbol, orderID)
+	if err != nil {
+		log.Printf("  ⚠️ 查询 %s 订单 %s 状态失败: %v", order.Symbol, orderID, err)
+		result = &OrderResult{OrderID: orderID, Symbol: order.Symbol, Status: OrderStatusOpen, Quantity: quantityFloat}
+		result.Price, _ = strconv.ParseFloat(priceStr, 64)
+	}
+	log.Printf("✓ %s已提交: %s %s 价格: %s 数量: %s 状态: %s", OrderTypeLabel(order.OrderType), order.Symbol, order.Side, priceStr, quantityStr, result.Status)
+	return result, nil
+}
+
+// GetOrder 查询订单
+func (t *BitgetTrader) GetOrder(symbol, orderID string) (*OrderResult, error) {
+	data, err := t.request(http.MethodGet, "/api/v2/mix/order/detail", map[string]interface{}{
+		"symbol":      symbol,
+		"productType": bitgetProductType,
+		"orderId":     orderID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	var o struct {
+		OrderID    string `json:"orderId"`
+		Symbol     string `json:"symbol"`
+		Size       string `json:"size"`
+		Price      string `json:"price"`
+		PriceAvg   string `json:"priceAvg"`
+		BaseVolume string `json:"baseVolume"`
+		Fee        string `json:"fee"`
+		State      string `json:"state"`
+	}
+	if err := json.Unmarshal(data, &o); err != nil {
+		return nil, fmt.Errorf("解析订单数据失败: %w", err)
+	}
+
+	result := &OrderResult{
+		OrderID: o.OrderID,
+		Symbol:  o.Symbol,
+		Status:  bitgetOrderStatus(o.State),
+	}
+	result.AvgPrice, _ = strconv.ParseFloat(o.PriceAvg, 64)
+	result.ExecutedQty, _ = strconv.ParseFloat(o.BaseVolume, 64)
+	result.Price, _ = strconv.ParseFloat(o.Price, 64)
+	result.Quantity, _ = strconv.ParseFloat(o.Size, 64)
+	fee, _ := strconv.ParseFloat(o.Fee, 64)
+	result.Fee = math.Abs(fee) // Bitget手续费为负数表示扣除
+	return result, nil
+}
+
+// bitgetOrderStatus Bitget订单状态转换为统一状态
+func bitgetOrderStatus(state string) string {
+	switch state {
+	case "live", "new", "init":
+		return OrderStatusOpen
+	case "partially_filled":
+		return OrderStatusPartiallyFilled
+	case "filled":
+		return OrderStatusFilled
+	default: // canceled
+		return OrderStatusCanceled
+	}
+}
+
+// CancelOrder 撤销指定订单
+func (t *BitgetTrader) CancelOrder(symbol, orderID string) error {
+	if _, err := t.request(http.MethodPost, "/api/v2/mix/order/cancel-order", map[string]interface{}{
+		"symbol":      symbol,
+		"productType": bitgetProductType,
+		"marginCoin":  bitgetMarginCoin,
+		"orderId":     orderID,
+	}); err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+	log.Printf("  ✓ 已撤销 %s 订单 %s", symbol, orderID)
+	return nil
+}
+
+// bitgetPlanOrder 止盈止损计划委托
+type bitgetPlanOrder struct {
+	OrderID      string `json:"orderId"`
+	Symbol       string `json:"symbol"`
+	PlanType     string `json:"planType"`
+	TriggerPrice string `json:"triggerPrice"`
+	Price        string `json:"price"`
+	Size         string `json:"size"`
+	PosSide      string `json:"posSide"`
+	CTime        string `json:"cTime"`
+}
+
+// getPlanOrders 查询止盈止损计划委托
+func (t *BitgetTrader) getPlanOrders(symbol string) ([]bitgetPlanOrder, error) {
+	params := map[string]interface{}{
+		"productType": bitgetProductType,
+		"planType":    "profit_loss",
+	}
+	if symbol != "" {
+		params["symbol"] = symbol
+	}
+	data, err := t.request(http.MethodGet, "/api/v2/mix/order/orders-plan-pending", params)
+	if err != nil {
+		return nil, fmt.Errorf("获取止盈止损单失败: %w", err)
+	}
+	var result struct {
+		EntrustedList []bitgetPlanOrder `json:"entrustedList"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析止盈止损单失败: %w", err)
+	}
+	return result.EntrustedList, nil
+}
+
+// bitgetPlanOrderType 计划委托类型转换为统一挂单类型
+func bitgetPlanOrderType(planType string) string {
+	switch planType {
+	case "pos_loss", "loss_plan":
+		return OpenOrderTypeStopLoss
+	case "pos_profit", "profit_plan":
+		return OpenOrderTypeTakeProfit
+	case "moving_plan":
+		return OpenOrderTypeTrailingStop
+	default:
+		return OpenOrderTypeTrigger
+	}
+}
+
+// GetOpenOrders 查询未完成的挂单（包含止盈止损计划委托）
+func (t *BitgetTrader) GetOpenOrders(symbol string) ([]OpenOrder, error) {
+	var result []OpenOrder
+	idLessThan := ""
+	for {
+		params := map[string]interface{}{"productType": bitgetProductType, "limit": 100}
+		if symbol != "" {
+			params["symbol"] = symbol
+		}
+		if idLessThan != "" {
+			params["idLessThan"] = idLessThan
+		}
+		data, err := t.request(http.MethodGet, "/api/v2/mix/order/orders-pending", params)
+		if err != nil {
+			return nil, fmt.Errorf("获取未完成订单失败: %w", err)
+		}
+		var page struct {
+			EntrustedList []struct {
+				OrderID    string `json:"orderId"`
+				Symbol     string `json:"symbol"`
+				Size       string `json:"size"`
+				Price      string `json:"price"`
+				BaseVolume string `json:"baseVolume"`
+				PosSide    string `json:"posSide"`
+				TradeSide  string `json:"tradeSide"`
+				OrderType  string `json:"orderType"`
+				CTime      string `json:"cTime"`
+			} `json:"entrustedList"`
+			EndID string `json:"endId"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("解析未完成订单失败: %w", err)
+		}
+
+		for _, o := range page.EntrustedList {
+			isClose := o.TradeSide == "close"
+			open := OpenOrder{
+				OrderID:      o.OrderID,
+				Symbol:       o.Symbol,
+				Side:         orderSide(o.PosSide, isClose),
+				PositionSide: o.PosSide,
+				Type:         OpenOrderTypeLimit,
+				ReduceOnly:   isClose,
+				Time:         parseOKXTime(o.CTime),
+			}
+			if o.OrderType == "market" {
+				open.Type = OpenOrderTypeMarket
+			}
+			open.Price, _ = strconv.ParseFloat(o.Price, 64)
+			open.Quantity, _ = strconv.ParseFloat(o.Size, 64)
+			open.ExecutedQty, _ = strconv.ParseFloat(o.BaseVolume, 64)
+			result = append(result, open)
+		}
+		if page.EndID == "" || len(page.EntrustedList) < 100 {
+			break
+		}
+		idLessThan = page.EndID
+	}
+
+	planOrders, err := t.getPlanOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range planOrders {
+		open := OpenOrder{
+			OrderID:      o.OrderID,
+			Symbol:       o.Symbol,
+			Side:         orderSide(o.PosSide, true),
+			PositionSide: o.PosSide,
+			Type:         bitgetPlanOrderType(o.PlanType),
+			ReduceOnly:   true,
+			IsAlgo:       true,
+			Time:         parseOKXTime(o.CTime),
+		}
+		open.Price, _ = strconv.ParseFloat(o.Price, 64)
+		open.StopPrice, _ = strconv.ParseFloat(o.TriggerPrice, 64)
+		open.Quantity, _ = strconv.ParseFloat(o.Size, 64) // 整仓止盈止损为0
+		result = append(result, open)
+	}
+	return result, nil
+}
+
+// GetUserTrades 获取成交明细（同一订单的多笔成交合并为一条，按时间升序）
+func (t *BitgetTrader) GetUserTrades(symbol string, startTime time.Time) ([]Fill, error) {
+	var fills []Fill
+
+	// fill-history 单次查询跨度不能超过7天，按窗口分段查询
+	for start := startTime; start.Before(time.Now()); start = start.Add(binanceHistoryWindow) {
+		idLessThan := ""
+		for {
+			params := map[string]interface{}{
+				"productType": bitgetProductType,
+				"symbol":      symbol,
+				"startTime":   start.UnixMilli(),
+				"endTime":     start.Add(binanceHistoryWindow).UnixMilli(),
+				"limit":       100,
+			}
+			if idLessThan != "" {
+				params["idLessThan"] = idLessThan
+			}
+			data, err := t.request(http.MethodGet, "/api/v2/mix/order/fill-history", params)
+			if err != nil {
+				return nil, fmt.Errorf("获取成交历史失败: %w", err)
+			}
+			var page struct {
+				FillList []struct {
+					OrderID    string `json:"orderId"`
+					Symbol     string `json:"symbol"`
+					Side       string `json:"side"`
+					Price      string `json:"price"`
+					BaseVolume string `json:"baseVolume"`
+					Profit     string `json:"profit"`
+					TradeSide  string `json:"tradeSide"`
+					CTime      string `json:"cTime"`
+					FeeDetail  []struct {
+						TotalFee string `json:"totalFee"`
+					} `json:"feeDetail"`
+				} `json:"fillList"`
+				EndID string `json:"endId"`
+			}
+			if err := json.Unmarshal(data, &page); err != nil {
+				return nil, fmt.Errorf("解析成交历史失败: %w", err)
+			}
+
+			for _, f := range page.FillList {
+				// tradeSide: open / close / burst_close_long / reduce_close_short 等
+				side := "long"
+				switch {
+				case strings.HasSuffix(f.TradeSide, "_short"):
+					side = "short"
+				case strings.HasSuffix(f.TradeSide, "_long"):
+				case f.Side == "sell":
+					side = "short"
+				}
+				fill := Fill{
+					OrderID: f.OrderID,
+					Symbol:  f.Symbol,
+					Side:    side,
+					IsClose: strings.Contains(f.TradeSide, "close"),
+					Time:    parseOKXTime(f.CTime),
+				}
+				if strings.HasPrefix(f.TradeSide, "burst_") {
+					fill.Reason = config.TradeExitLiquidation
+				}
+				fill.Price, _ = strconv.ParseFloat(f.Price, 64)
+				fill.Quantity, _ = strconv.ParseFloat(f.BaseVolume, 64)
+				fill.RealizedPnL, _ = strconv.ParseFloat(f.Profit, 64)
+				for _, fee := range f.FeeDetail {
+					v, _ := strconv.ParseFloat(fee.TotalFee, 64)
+					fill.Fee -= v // Bitget手续费为负数表示扣除
+				}
+				fills = append(fills, fill)
+			}
+			if page.EndID == "" || len(page.FillList) < 100 {
+				break
+			}
+			idLessThan = page.EndID
+		}
+	}
+
+	return mergeFills(fills), nil
+}
+
+// GetFundingFees 获取累计资金费（账单中的 contract_settle_fee 记录）
+func (t *BitgetTrader) GetFundingFees(symbol string, startTime time.Time) (float64, error) {
+	total := 0.0
+	for start := startTime; start.Before(time.Now()); start = start.Add(binanceHistoryWindow) {
+		idLessThan := ""
+		for {
+			params := map[string]interface{}{
+				"productType":  bitgetProductType,
+				"symbol":       symbol,
+				"businessType": "contract_settle_fee",
+				"startTime":    start.UnixMilli(),
+				"endTime":      start.Add(binanceHistoryWindow).UnixMilli(),
+				"limit":        100,
+			}
+			if idLessThan != "" {
+				params["idLessThan"] = idLessThan
+			}
+			data, err := t.request(http.MethodGet, "/api/v2/mix/account/bill", params)
+			if err != nil {
+				return 0, fmt.Errorf("获取资金费流水失败: %w", err)
+			}
+			var page struct {
+				Bills []struct {
+					Amount string `json:"amount"` // 正数为收入
+				} `json:"bills"`
+				EndID string `json:"endId"`
+			}
+			if err := json.Unmarshal(data, &page); err != nil {
+				return 0, fmt.Errorf("解析资金费流水失败: %w", err)
+			}
+			for _, bill := range page.Bills {
+				amount, _ := strconv.ParseFloat(bill.Amount, 64)
+				total += amount
+			}
+			if page.EndID == "" || len(page.Bills) < 100 {
+				break
+			}
+			idLessThan = page.EndID
+		}
+	}
+	return total, nil
+}
+
+// SetStopLoss 设置止损（quantity>0 时为部分止损，否则止损整个持仓）
+func (t *BitgetTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	planType := "pos_loss"
+	if quantity > 0 {
+		planType = "loss_plan"
+	}
+	return t.placeTPSL(symbol, positionSide, quantity, planType, stopPrice)
+}
+
+// SetTakeProfit 设置止盈（quantity>0 时为部分止盈，否则止盈整个持仓）
+func (t *BitgetTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	planType := "pos_profit"
+	if quantity > 0 {
+		planType = "profit_plan"
+	}
+	return t.placeTPSL(symbol, positionSide, quantity, planType, takeProfitPrice)
+}
+
+// placeTPSL 下止盈止损计划委托（按标记价格触发，市价成交）
+func (t *BitgetTrader) placeTPSL(symbol, positionSide string, quantity float64, planType string, price float64) error {
+	label := "止损"
+	if bitgetPlanOrderType(planType) == OpenOrderTypeTakeProfit {
+		label = "止盈"
+	}
+	priceStr, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return err
+	}
+	params := map[string]interface{}{
+		"symbol":       symbol,
+		"productType":  bitgetProductType,
+		"marginCoin":   bitgetMarginCoin,
+		"planType":     planType,
+		"triggerPrice": priceStr,
+		"triggerType":  "mark_price",
+		"holdSide":     strings.ToLower(positionSide),
+	}
+	if quantity > 0 {
+		sizeStr, err := t.FormatQuantity(symbol, quantity)
+		if err != nil {
+			return err
+		}
+		params["size"] = sizeStr
+	}
+
+	if _, err := t.request(http.MethodPost, "/api/v2/mix/order/place-tpsl-order", params); err != nil {
+		return fmt.Errorf("设置%s失败: %w", label, err)
+	}
+	log.Printf("  %s价设置: %s", label, priceStr)
+	return nil
+}
+
+// CancelStopLossOrders 仅取消止损单（不影响止盈单）
+func (t *BitgetTrader) CancelStopLossOrders(symbol string) error {
+	return t.cancelPlanOrders(symbol, OpenOrderTypeStopLoss)
+}
+
+// CancelTakeProfitOrders 仅取消止盈单（不影响止损单）
+func (t *BitgetTrader) CancelTakeProfitOrders(symbol string) error {
+	return t.cancelPlanOrders(symbol, OpenOrderTypeTakeProfit)
+}
+
+// cancelPlanOrders 取消指定类型的止盈止损计划委托
+func (t *BitgetTrader) cancelPlanOrders(symbol, orderType string) error {
+	orders, err := t.getPlanOrders(symbol)
+	if err != nil {
+		return err
+	}
+
+	label := "止损"
+	if orderType == OpenOrderTypeTakeProfit {
+		label = "止盈"
+	}
+	var ids []map[string]string
+	for _, o := range orders {
+		if bitgetPlanOrderType(o.PlanType) == orderType {
+			ids = append(ids, map[string]string{"orderId": o.OrderID})
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if _, err := t.request(http.MethodPost, "/api/v2/mix/order/cancel-plan-order", map[string]interface{}{
+		"symbol":      symbol,
+		"productType": bitgetProductType,
+		"marginCoin":  bitgetMarginCoin,
+		"orderIdList": ids,
+	}); err != nil {
+		return fmt.Errorf("取消%s单失败: %w", label, err)
+	}
+	log.Printf("  ✓ 已取消 %s 的 %d 个%s单", symbol, len(ids), label)
+	return nil
+}
+
+// CancelAllOrders 取消该币种的所有普通挂单
+func (t *BitgetTrader) CancelAllOrders(symbol string) error {
+	if _, err := t.request(http.MethodPost, "/api/v2/mix/order/batch-cancel-orders", map[string]interface{}{
+		"symbol":      symbol,
+		"productType": bitgetProductType,
+		"marginCoin":  bitgetMarginCoin,
+	}); err != nil {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// CancelStopOrders 取消该币种的所有止盈止损计划委托
+func (t *BitgetTrader) CancelStopOrders(symbol string) error {
+	if _, err := t.request(http.MethodPost, "/api/v2/mix/order/cancel-plan-order", map[string]interface{}{
+		"symbol":      symbol,
+		"productType": bitgetProductType,
+		"marginCoin":  bitgetMarginCoin,
+		"planType":    "profit_loss",
+	}); err != nil {
+		return fmt.Errorf("取消止盈止损单失败: %w", err)
+	}
+	log.Printf("  ✓ 已取消 %s 的止盈止损单", symbol)
+	return nil
+}
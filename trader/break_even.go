@@ -0,0 +1,240 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/config"
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+// BreakEvenConfig 保本止损配置：浮盈达到任一阈值后，自动将止损移到扣除开平仓手续费后的保本价
+type BreakEvenConfig struct {
+	TriggerR   float64 // 浮盈达到初始风险（入场价到开仓止损的距离）的R倍时触发，<=0 表示不按R触发
+	TriggerPct float64 // 浮盈达到入场价的百分比（价格变动%，不含杠杆）时触发，<=0 表示不按百分比触发
+}
+
+// Enabled 是否启用保本止损
+func (c BreakEvenConfig) Enabled() bool {
+	return c.TriggerR > 0 || c.TriggerPct > 0
+}
+
+// checkBreakEvenStops 跟踪止损引擎定时调用：按最新持仓检查保本止损，调整记录暂存到下一个周期的决策记录
+func (at *AutoTrader) checkBreakEvenStops() {
+	if !at.config.BreakEven.Enabled() || !at.isRunning {
+		return
+	}
+
+	positions, err := at.getPositions()
+	if err != nil {
+		log.Printf("⚠️ [%s] 保本止损获取持仓失败: %v", at.name, err)
+		return
+	}
+	infos := make([]decision.PositionInfo, 0, len(positions))
+	for _, pos := range positions {
+		info := decision.PositionInfo{
+			Symbol:     pos.Symbol,
+			Side:       pos.Side,
+			EntryPrice: pos.EntryPrice,
+			MarkPrice:  pos.MarkPrice,
+			Quantity:   pos.Quantity,
+		}
+		if pos.EntryPrice > 0 {
+			info.UnrealizedPnLPct = (pos.MarkPrice - pos.EntryPrice) / pos.EntryPrice * 100
+			if pos.Side == "short" {
+				info.UnrealizedPnLPct = -info.UnrealizedPnLPct
+			}
+		}
+		infos = append(infos, info)
+	}
+
+	record := &logger.DecisionRecord{ExecutionLog: []string{}}
+	at.manageBreakEvenStops(infos, record)
+	at.deferRecord(record)
+}
+
+// manageBreakEvenStops 检查持仓（每个周期请求AI之前，以及跟踪止损引擎的定时检查），浮盈达到阈值时把止损移到保本价，调整记录为 auto_update_stop_loss 动作
+// 以下持仓跳过：设置了跟踪止损（由跟踪止损接管）、同币种双向持仓（CancelStopLossOrders 会同时撤掉两个方向的止损）
+func (at *AutoTrader) manageBreakEvenStops(positions []decision.PositionInfo, record *logger.DecisionRecord) {
+	cfg := at.config.BreakEven
+	if !cfg.Enabled() || len(positions) == 0 {
+		return
+	}
+
+	at.tradeMutex.Lock()
+	defer at.tradeMutex.Unlock()
+
+	sideCount := make(map[string]int)
+	for _, pos := range positions {
+		if pos.Quantity != 0 {
+			sideCount[pos.Symbol]++
+		}
+	}
+
+	trades := make(map[string]*config.TradeRecord)
+	if at.ledger != nil {
+		openTrades, err := at.ledger.GetOpenTrades(at.id)
+		if err != nil {
+			log.Printf("⚠️ [%s] 保本止损读取交易台账失败: %v", at.name, err)
+		}
+		for _, trade := range openTrades {
+			trades[trade.Symbol+"_"+trade.Side] = trade
+		}
+	}
+
+	fees := at.feeSchedule()
+	for _, pos := range positions {
+		if pos.Quantity == 0 || pos.EntryPrice <= 0 || pos.MarkPrice <= 0 {
+			continue
+		}
+		if sideCount[pos.Symbol] > 1 || at.getTrailingStop(pos.Symbol, pos.Side) != nil {
+			continue
+		}
+
+		trade := trades[pos.Symbol+"_"+pos.Side]
+		breakEven := at.breakEvenStopPrice(pos, trade, fees)
+		// 价格尚未越过保本价时不需要查询当前止损（定时检查频繁，避免每次都请求挂单）
+		if breakEven <= 0 || (pos.Side == "long" && breakEven >= pos.MarkPrice) || (pos.Side != "long" && breakEven <= pos.MarkPrice) {
+			continue
+		}
+		currentStop, initialStop := at.currentStopLoss(pos, trade)
+		if !breakEvenTriggered(cfg, pos, currentStop, initialStop, breakEven) {
+			continue
+		}
+
+		actionRecord := logger.DecisionAction{
+			Action:    "auto_update_stop_loss",
+			Symbol:    pos.Symbol,
+			Quantity:  math.Abs(pos.Quantity),
+			Price:     pos.MarkPrice,
+			StopLoss:  breakEven,
+			Timestamp: time.Now(),
+		}
+
+		log.Printf("🛡️ [%s] %s %s 浮盈 %.2f%%，止损 %.4f → 保本价 %.4f", at.name, pos.Symbol, pos.Side, pos.UnrealizedPnLPct, currentStop, breakEven)
+		if err := at.moveStopLoss(pos, currentStop, breakEven); err != nil {
+			log.Printf("❌ [%s] %s %s 保本止损设置失败: %v", at.name, pos.Symbol, pos.Side, err)
+			actionRecord.Error = err.Error()
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ 保本止损 %s %s 失败: %v", pos.Symbol, pos.Side, err))
+		} else {
+			actionRecord.Success = true
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ 保本止损 %s %s → %.4f", pos.Symbol, pos.Side, breakEven))
+			at.recordTradeStops(pos.Symbol, pos.Side, breakEven, 0)
+		}
+		record.Decisions = append(record.Decisions, actionRecord)
+	}
+}
+
+// currentStopLoss 持仓当前止损价和开仓时的初始止损价（优先使用交易台账，台账没有记录时从交易所挂单读取，无止损返回0）
+func (at *AutoTrader) currentStopLoss(pos decision.PositionInfo, trade *config.TradeRecord) (current, initial float64) {
+	if trade != nil && trade.StopLoss > 0 {
+		initial = trade.InitialStopLoss
+		if initial <= 0 {
+			initial = trade.StopLoss
+		}
+		return trade.StopLoss, initial
+	}
+
+	orders, err := at.trader.GetOpenOrders(pos.Symbol)
+	if err != nil {
+		log.Printf("⚠️ [%s] 查询 %s 止损单失败: %v", at.name, pos.Symbol, err)
+		return 0, 0
+	}
+	for _, order := range orders {
+		if order.Type == OpenOrderTypeStopLoss && order.PositionSide == pos.Side && order.StopPrice > 0 {
+			return order.StopPrice, order.StopPrice
+		}
+	}
+	return 0, 0
+}
+
+// breakEvenStopPrice 按交易所费率计算保本止损价，并按价格步进向不亏损的方向取整
+// 开仓费率优先使用台账记录的实际开仓手续费，止损触发后为市价平仓，平仓按吃单费率计算
+func (at *AutoTrader) breakEvenStopPrice(pos decision.PositionInfo, trade *config.TradeRecord, fees FeeSchedule) float64 {
+	openFeeRate := fees.Taker
+	if trade != nil && trade.OpenFee > 0 && trade.Quantity > 0 && trade.EntryPrice > 0 {
+		openFeeRate = trade.OpenFee / (trade.Quantity * trade.EntryPrice)
+	}
+
+	isLong := pos.Side == "long"
+	price := decision.CalculateBreakEvenPrice(pos.EntryPrice, isLong, openFeeRate, fees.Taker)
+
+	inst, err := at.trader.GetInstrument(pos.Symbol)
+	if err != nil {
+		return price
+	}
+	return roundBreakEvenPrice(inst, price, isLong)
+}
+
+// roundBreakEvenPrice 保本价按价格步进取整，取整结果不能让止损落到亏损一侧（多单向上、空单向下补一个步进）
+func roundBreakEvenPrice(inst *Instrument, price float64, isLong bool) float64 {
+	rounded := inst.RoundPrice(price)
+	if inst.TickSize > 0 {
+		if isLong && rounded < price {
+			rounded = inst.RoundPrice(rounded + inst.TickSize)
+		} else if !isLong && rounded > price {
+			rounded = inst.RoundPrice(rounded - inst.TickSize)
+		}
+	}
+	return rounded
+}
+
+// breakEvenTriggered 判断是否需要把止损移到保本价：
+// 止损尚未达到保本价、浮盈达到R倍或百分比阈值，且保本价位于当前价的保护侧（否则新止损会立即触发）
+func breakEvenTriggered(cfg BreakEvenConfig, pos decision.PositionInfo, currentStop, initialStop, breakEven float64) bool {
+	if breakEven <= 0 {
+		return false
+	}
+
+	isLong := pos.Side == "long"
+	profit := pos.MarkPrice - pos.EntryPrice
+	if !isLong {
+		profit = -profit
+	}
+	if profit <= 0 {
+		return false
+	}
+
+	if currentStop > 0 && ((isLong && currentStop >= breakEven) || (!isLong && currentStop <= breakEven)) {
+		return false
+	}
+	if (isLong && breakEven >= pos.MarkPrice) || (!isLong && breakEven <= pos.MarkPrice) {
+		return false
+	}
+
+	if cfg.TriggerPct > 0 && profit/pos.EntryPrice*100 >= cfg.TriggerPct {
+		return true
+	}
+	if cfg.TriggerR > 0 && initialStop > 0 {
+		risk := pos.EntryPrice - initialStop
+		if !isLong {
+			risk = -risk
+		}
+		if risk > 0 && profit >= cfg.TriggerR*risk {
+			return true
+		}
+	}
+	return false
+}
+
+// moveStopLoss 撤掉旧止损并挂出新止损（新止损设置失败时尝试恢复旧止损，避免持仓失去保护）
+func (at *AutoTrader) moveStopLoss(pos decision.PositionInfo, oldStop, newStop float64) error {
+	positionSide := strings.ToUpper(pos.Side)
+	quantity := math.Abs(pos.Quantity)
+
+	if err := at.trader.CancelStopLossOrders(pos.Symbol); err != nil {
+		log.Printf("  ⚠ 取消旧止损单失败: %v", err)
+	}
+	if err := at.trader.SetStopLoss(pos.Symbol, positionSide, quantity, newStop); err != nil {
+		if oldStop > 0 {
+			if restoreErr := at.trader.SetStopLoss(pos.Symbol, positionSide, quantity, oldStop); restoreErr != nil {
+				log.Printf("  🚨 %s 恢复原止损 %.4f 失败，持仓当前没有止损保护: %v", pos.Symbol, oldStop, restoreErr)
+			}
+		}
+		return fmt.Errorf("设置保本止损失败: %w", err)
+	}
+	return nil
+}
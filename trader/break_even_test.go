@@ -0,0 +1,75 @@
+package trader
+
+import (
+	"math"
+	"nofx/decision"
+	"testing"
+)
+
+func TestRoundBreakEvenPrice(t *testing.T) {
+	tests := []struct {
+		name   string
+		inst   Instrument
+		price  float64
+		isLong bool
+		want   float64
+	}{
+		{name: "多单向下取整时补一个步进", inst: Instrument{TickSize: 0.1}, price: 100.03, isLong: true, want: 100.1},
+		{name: "多单向上取整保持不变", inst: Instrument{TickSize: 0.1}, price: 100.07, isLong: true, want: 100.1},
+		{name: "空单向下取整保持不变", inst: Instrument{TickSize: 0.1}, price: 99.93, isLong: false, want: 99.9},
+		{name: "空单向上取整时减一个步进", inst: Instrument{TickSize: 0.1}, price: 99.97, isLong: false, want: 99.9},
+		{name: "无价格步进时原样返回", inst: Instrument{}, price: 100.0337, isLong: true, want: 100.0337},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := roundBreakEvenPrice(&tt.inst, tt.price, tt.isLong)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("roundBreakEvenPrice(%v, long=%v) = %v, 期望 %v", tt.price, tt.isLong, got, tt.want)
+			}
+			if tt.isLong && got < tt.price || !tt.isLong && got > tt.price {
+				t.Errorf("取整结果 %v 落到了亏损一侧（原价 %v）", got, tt.price)
+			}
+		})
+	}
+}
+
+func TestBreakEvenTriggered(t *testing.T) {
+	long := func(mark float64) decision.PositionInfo {
+		return decision.PositionInfo{Symbol: "BTCUSDT", Side: "long", EntryPrice: 100, MarkPrice: mark, Quantity: 1}
+	}
+	short := func(mark float64) decision.PositionInfo {
+		return decision.PositionInfo{Symbol: "BTCUSDT", Side: "short", EntryPrice: 100, MarkPrice: mark, Quantity: 1}
+	}
+
+	tests := []struct {
+		name        string
+		cfg         BreakEvenConfig
+		pos         decision.PositionInfo
+		currentStop float64
+		initialStop float64
+		breakEven   float64
+		want        bool
+	}{
+		{name: "多单盈利达到百分比触发", cfg: BreakEvenConfig{TriggerPct: 2}, pos: long(103), currentStop: 98, initialStop: 98, breakEven: 100.2, want: true},
+		{name: "多单止损已在保本价", cfg: BreakEvenConfig{TriggerPct: 2}, pos: long(103), currentStop: 100.2, initialStop: 98, breakEven: 100.2, want: false},
+		{name: "多单止损已越过保本价", cfg: BreakEvenConfig{TriggerPct: 2}, pos: long(103), currentStop: 101, initialStop: 98, breakEven: 100.2, want: false},
+		{name: "空单盈利达到百分比触发", cfg: BreakEvenConfig{TriggerPct: 2}, pos: short(97), currentStop: 102, initialStop: 102, breakEven: 99.8, want: true},
+		{name: "空单止损已在保本价", cfg: BreakEvenConfig{TriggerPct: 2}, pos: short(97), currentStop: 99.8, initialStop: 102, breakEven: 99.8, want: false},
+		{name: "空单止损已越过保本价", cfg: BreakEvenConfig{TriggerPct: 2}, pos: short(97), currentStop: 99, initialStop: 102, breakEven: 99.8, want: false},
+		{name: "盈利未达百分比阈值", cfg: BreakEvenConfig{TriggerPct: 2}, pos: long(101.5), currentStop: 98, initialStop: 98, breakEven: 100.2, want: false},
+		{name: "多单盈利达到1R触发", cfg: BreakEvenConfig{TriggerR: 1}, pos: long(102), currentStop: 98, initialStop: 98, breakEven: 100.2, want: true},
+		{name: "多单盈利未达1R", cfg: BreakEvenConfig{TriggerR: 1}, pos: long(101.5), currentStop: 98, initialStop: 98, breakEven: 100.2, want: false},
+		{name: "空单盈利达到1R触发", cfg: BreakEvenConfig{TriggerR: 1}, pos: short(98), currentStop: 102, initialStop: 102, breakEven: 99.8, want: true},
+		{name: "保本价不在标记价保护一侧", cfg: BreakEvenConfig{TriggerPct: 0.05}, pos: long(100.1), currentStop: 98, initialStop: 98, breakEven: 100.2, want: false},
+		{name: "亏损持仓不触发", cfg: BreakEvenConfig{TriggerPct: 2}, pos: long(97), currentStop: 95, initialStop: 95, breakEven: 100.2, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := breakEvenTriggered(tt.cfg, tt.pos, tt.currentStop, tt.initialStop, tt.breakEven); got != tt.want {
+				t.Errorf("breakEvenTriggered() = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,1127 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"nofx/config"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bybit API地址
+const (
+	bybitMainnetURL  = "https://api.bybit.com"
+	bybitTestnetURL  = "https://api-testnet.bybit.com"
+	bybitRecvWindow  = "5000"
+	bybitHistoryPage = 100 // 成交/流水接口单页最大条数
+)
+
+// Bybit 错误码
+const (
+	bybitCodePositionModeNotModified = 110025 // 持仓模式未修改
+	bybitCodeLeverageNotModified     = 110043 // 杠杆未修改
+	bybitCodeTimestampRejected       = 10002  // 请求时间戳超出 recv_window
+)
+
+// isBybitTimestampRejected 响应是否为时间戳被拒绝（需要重新同步服务器时间）
+func isBybitTimestampRejected(body []byte) bool {
+	var resp struct {
+		RetCode int `json:"retCode"`
+	}
+	return json.Unmarshal(body, &resp) == nil && resp.RetCode == bybitCodeTimestampRejected
+}
+
+// bybitPositionIdx 双向持仓模式下的 positionIdx（1=多，2=空）
+var bybitPositionIdx = map[string]int{"long": 1, "short": 2}
+
+// BybitTrader Bybit USDT永续合约交易器（V5统一账户接口，双向持仓模式）
+type BybitTrader struct {
+	rest *restClient
+
+	// 余额缓存
+	cachedBalance     *Balance
+	balanceCacheTime  time.Time
+	balanceCacheMutex sync.RWMutex
+
+	// 持仓缓存
+	cachedPositions     []Position
+	positionsCacheTime  time.Time
+	positionsCacheMutex sync.RWMutex
+
+	// 缓存有效期（15秒）
+	cacheDuration time.Duration
+
+	// 合约规则缓存（instruments-info）
+	instruments *instrumentRegistry
+}
+
+// bybitAPIError Bybit接口返回的业务错误
+type bybitAPIError struct {
+	Code int
+	Msg  string
+}
+
+func (e *bybitAPIError) Error() string {
+	return fmt.Sprintf("Bybit API错误: retCode=%d, retMsg=%s", e.Code, e.Msg)
+}
+
+// isBybitError 判断是否为指定错误码的Bybit业务错误
+func isBybitError(err error, code int) bool {
+	var apiErr *bybitAPIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// NewBybitTrader 创建Bybit合约交易器
+func NewBybitTrader(apiKey, secretKey string, testnet bool) *BybitTrader {
+	baseURL := bybitMainnetURL
+	if testnet {
+		baseURL = bybitTestnetURL
+	}
+	trader := &BybitTrader{
+		rest:          newRESTClient("Bybit", baseURL, newBybitSigner(apiKey, secretKey)),
+		cacheDuration: 15 * time.Second,
+	}
+	trader.instruments = sharedInstrumentRegistry(instrumentKey("bybit", testnet), "Bybit", trader.loadInstruments)
+	trader.rest.timestampRejected = isBybitTimestampRejected
+	trader.rest.clock.sync("Bybit", trader.fetchServerTime)
+
+	// 设置双向持仓模式（代码按 positionIdx 区分多空）
+	if err := trader.setHedgeMode(); err != nil {
+		log.Printf("⚠️ 设置Bybit双向持仓模式失败: %v (如果已是双向模式则忽略此警告)", err)
+	}
+
+	log.Printf("✓ Bybit交易器初始化成功 (testnet=%v)", testnet)
+	return trader
+}
+
+// request 发送签名请求，返回result字段（GET参数放在查询字符串，POST参数为JSON请求体）
+func (t *BybitTrader) request(method, path string, params map[string]interface{}) (json.RawMessage, error) {
+	return t.send(method, path, params, true)
+}
+
+// requestPublic 发送公共GET请求（合约规则等不需要签名的接口，供共享的合约规则注册表使用）
+func (t *BybitTrader) requestPublic(path string, params map[string]interface{}) (json.RawMessage, error) {
+	return t.send(http.MethodGet, path, params, false)
+}
+
+// send 发送请求并解析Bybit标准响应
+func (t *BybitTrader) send(method, path string, params map[string]interface{}, signed bool) (json.RawMessage, error) {
+	var respBody []byte
+	var err error
+	if method == http.MethodGet {
+		query := url.Values{}
+		for k, v := range params {
+			query.Set(k, fmt.Sprint(v))
+		}
+		respBody, err = t.rest.doRequest(method, path, query, nil, signed)
+	} else {
+		respBody, err = t.rest.doRequest(method, path, nil, params, signed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var bybitResp struct {
+		RetCode int             `json:"retCode"`
+		RetMsg  string          `json:"retMsg"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &bybitResp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if bybitResp.RetCode != 0 {
+		return nil, &bybitAPIError{Code: bybitResp.RetCode, Msg: bybitResp.RetMsg}
+	}
+	return bybitResp.Result, nil
+}
+
+// fetchServerTime 获取Bybit服务器时间（请求时间戳与服务器相差超过 recv_window 会被拒绝）
+func (t *BybitTrader) fetchServerTime() (time.Time, error) {
+	body, err := t.rest.getPublic("/v5/market/time", nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var resp struct {
+		Result struct {
+			TimeNano string `json:"timeNano"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return time.Time{}, fmt.Errorf("解析服务器时间失败: %w", err)
+	}
+	nano, err := strconv.ParseInt(resp.Result.TimeNano, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("解析服务器时间失败: %w", err)
+	}
+	return time.Unix(0, nano), nil
+}
+
+// setHedgeMode 设置USDT合约为双向持仓模式（初始化时调用）
+func (t *BybitTrader) setHedgeMode() error {
+	_, err := t.request(http.MethodPost, "/v5/position/switch-mode", map[string]interface{}{
+		"category": "linear",
+		"coin":     "USDT",
+		"mode":     3, // 3 = 双向持仓
+	})
+	if isBybitError(err, bybitCodePositionModeNotModified) {
+		log.Printf("  ✓ Bybit账户已是双向持仓模式（Hedge Mode）")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("  ✓ Bybit账户已切换为双向持仓模式（Hedge Mode）")
+	return nil
+}
+
+// GetBalance 获取统一账户余额（带缓存，金额为USD口径）
+func (t *BybitTrader) GetBalance() (*Balance, error) {
+	t.balanceCacheMutex.RLock()
+	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
+		cacheAge := time.Since(t.balanceCacheTime)
+		t.balanceCacheMutex.RUnlock()
+		log.Printf("✓ 使用缓存的账户余额（缓存时间: %.1f秒前）", cacheAge.Seconds())
+		return t.cachedBalance, nil
+	}
+	t.balanceCacheMutex.RUnlock()
+
+	log.Printf("🔄 缓存过期，正在调用Bybit API获取账户余额...")
+	data, err := t.request(http.MethodGet, "/v5/account/wallet-balance", map[string]interface{}{
+		"accountType": "UNIFIED",
+	})
+	if err != nil {
+		log.Printf("❌ Bybit API调用失败: %v", err)
+		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+	}
+
+	var result struct {
+		List []struct {
+			TotalEquity           string `json:"totalEquity"`
+			TotalWalletBalance    string `json:"totalWalletBalance"`
+			TotalAvailableBalance string `json:"totalAvailableBalance"`
+			TotalPerpUPL          string `json:"totalPerpUPL"`
+			AccountIMRate         string `json:"accountIMRate"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析余额数据失败: %w", err)
+	}
+	if len(result.List) == 0 {
+		return nil, fmt.Errorf("未找到余额信息")
+	}
+
+	account := result.List[0]
+	balance := &Balance{}
+	balance.TotalWalletBalance, _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
+	balance.AvailableBalance, _ = strconv.ParseFloat(account.TotalAvailableBalance, 64)
+	balance.TotalUnrealizedProfit, _ = strconv.ParseFloat(account.TotalPerpUPL, 64)
+	balance.TotalEquity, _ = strconv.ParseFloat(account.TotalEquity, 64)
+	balance.MarginRatio, _ = strconv.ParseFloat(account.AccountIMRate, 64) // 初始保证金率 = 占用保证金 / 保证金余额
+
+	log.Printf("✓ Bybit API返回: 总余额=%.2f, 可用=%.2f, 未实现盈亏=%.2f",
+		balance.TotalWalletBalance, balance.AvailableBalance, balance.TotalUnrealizedProfit)
+
+	t.balanceCacheMutex.Lock()
+	t.cachedBalance = balance
+	t.balanceCacheTime = time.Now()
+	t.balanceCacheMutex.Unlock()
+
+	return balance, nil
+}
+
+// GetPositions 获取所有USDT永续持仓（带缓存）
+func (t *BybitTrader) GetPositions() ([]Position, error) {
+	t.positionsCacheMutex.RLock()
+	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
+		cacheAge := time.Since(t.positionsCacheTime)
+		t.positionsCacheMutex.RUnlock()
+		log.Printf("✓ 使用缓存的持仓信息（缓存时间: %.1f秒前）", cacheAge.Seconds())
+		return t.cachedPositions, nil
+	}
+	t.positionsCacheMutex.RUnlock()
+
+	log.Printf("🔄 缓存过期，正在调用Bybit API获取持仓信息...")
+	result := []Position{}
+	cursor := ""
+	for {
+		params := map[string]interface{}{
+			"category":   "linear",
+			"settleCoin": "USDT",
+			"limit":      200,
+		}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		data, err := t.request(http.MethodGet, "/v5/position/list", params)
+		if err != nil {
+			return nil, fmt.Errorf("获取持仓失败: %w", err)
+		}
+
+		var page struct {
+			List []struct {
+				Symbol        string `json:"symbol"`
+				Side          string `json:"side"` // Buy / Sell，无持仓时为空
+				Size          string `json:"size"`
+				AvgPrice      string `json:"avgPrice"`
+				MarkPrice     string `json:"markPrice"`
+				UnrealisedPnl string `json:"unrealisedPnl"`
+				Leverage      string `json:"leverage"`
+				LiqPrice      string `json:"liqPrice"`
+				PositionIM    string `json:"positionIM"`
+				PositionIdx   int    `json:"positionIdx"`
+			} `json:"list"`
+			NextPageCursor string `json:"nextPageCursor"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("解析持仓数据失败: %w", err)
+		}
+
+		for _, pos := range page.List {
+			size, _ := strconv.ParseFloat(pos.Size, 64)
+			if size == 0 {
+				continue
+			}
+			p := Position{Symbol: pos.Symbol, Quantity: size}
+			p.EntryPrice, _ = strconv.ParseFloat(pos.AvgPrice, 64)
+			p.MarkPrice, _ = strconv.ParseFloat(pos.MarkPrice, 64)
+			p.UnrealizedProfit, _ = strconv.ParseFloat(pos.UnrealisedPnl, 64)
+			p.LiquidationPrice, _ = strconv.ParseFloat(pos.LiqPrice, 64)
+			p.Margin, _ = strconv.ParseFloat(pos.PositionIM, 64)
+			leverage, _ := strconv.ParseFloat(pos.Leverage, 64)
+			p.Leverage = int(leverage)
+
+			// 双向持仓按 positionIdx 判断方向，单向持仓按 side 判断
+			switch pos.PositionIdx {
+			case 1:
+				p.Side = "long"
+			case 2:
+				p.Side = "short"
+			default:
+				p.Side = "long"
+				if pos.Side == "Sell" {
+					p.Side = "short"
+				}
+			}
+			result = append(result, p)
+		}
+
+		if page.NextPageCursor == "" || len(page.List) == 0 {
+			break
+		}
+		cursor = page.NextPageCursor
+	}
+
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = result
+	t.positionsCacheTime = time.Now()
+	t.positionsCacheMutex.Unlock()
+
+	return result, nil
+}
+
+// clearPositionsCache 下单后清除缓存（平仓查询持仓数量时需要最新数据）
+func (t *BybitTrader) clearPositionsCache() {
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = nil
+	t.positionsCacheMutex.Unlock()
+
+	t.balanceCacheMutex.Lock()
+	t.cachedBalance = nil
+	t.balanceCacheMutex.Unlock()
+}
+
+// SetMarginMode 设置保证金模式（统一账户的保证金模式为账户级别，会影响所有合约）
+func (t *BybitTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	mode := "ISOLATED_MARGIN"
+	if isCrossMargin {
+		mode = "REGULAR_MARGIN"
+	}
+	if _, err := t.request(http.MethodPost, "/v5/account/set-margin-mode", map[string]interface{}{
+		"setMarginMode": mode,
+	}); err != nil {
+		return fmt.Errorf("设置保证金模式失败: %w", err)
+	}
+	log.Printf("  ✓ Bybit账户保证金模式: %s", mode)
+	return nil
+}
+
+// SetLeverage 设置杠杆（多空使用相同杠杆）
+func (t *BybitTrader) SetLeverage(symbol string, leverage int) error {
+	if inst, err := t.instruments.get(symbol); err == nil {
+		leverage = inst.ClampLeverage(leverage)
+	}
+	_, err := t.request(http.MethodPost, "/v5/position/set-leverage", map[string]interface{}{
+		"category":     "linear",
+		"symbol":       symbol,
+		"buyLeverage":  strconv.Itoa(leverage),
+		"sellLeverage": strconv.Itoa(leverage),
+	})
+	if err != nil && !isBybitError(err, bybitCodeLeverageNotModified) {
+		return fmt.Errorf("设置杠杆失败: %w", err)
+	}
+	log.Printf("  ✓ %s 杠杆已设置为 %dx", symbol, leverage)
+	return nil
+}
+
+// loadInstruments 加载全部USDT永续合约规则
+func (t *BybitTrader) loadInstruments() (map[string]Instrument, error) {
+	specs := make(map[string]Instrument)
+	cursor := ""
+	for {
+		params := map[string]interface{}{"category": "linear", "limit": 1000}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		data, err := t.requestPublic("/v5/market/instruments-info", params)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			List []struct {
+				Symbol      string `json:"symbol"`
+				SettleCoin  string `json:"settleCoin"`
+				PriceFilter struct {
+					TickSize string `json:"tickSize"`
+				} `json:"priceFilter"`
+				LotSizeFilter struct {
+					QtyStep          string `json:"qtyStep"`
+					MinOrderQty      string `json:"minOrderQty"`
+					MinNotionalValue string `json:"minNotionalValue"`
+				} `json:"lotSizeFilter"`
+				LeverageFilter struct {
+					MaxLeverage string `json:"maxLeverage"`
+				} `json:"leverageFilter"`
+			} `json:"list"`
+			NextPageCursor string `json:"nextPageCursor"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("解析合约规则失败: %w", err)
+		}
+
+		for _, info := range page.List {
+			if info.SettleCoin != "USDT" {
+				continue
+			}
+			spec := Instrument{Symbol: info.Symbol, ContractSize: 1}
+			spec.TickSize, _ = strconv.ParseFloat(info.PriceFilter.TickSize, 64)
+			spec.QtyStep, _ = strconv.ParseFloat(info.LotSizeFilter.QtyStep, 64)
+			spec.MinQty, _ = strconv.ParseFloat(info.LotSizeFilter.MinOrderQty, 64)
+			spec.MinNotional, _ = strconv.ParseFloat(info.LotSizeFilter.MinNotionalValue, 64)
+			spec.MaxLeverage = parseLeverage(info.LeverageFilter.MaxLeverage)
+			specs[info.Symbol] = spec
+		}
+		if page.NextPageCursor == "" || len(page.List) == 0 {
+			return specs, nil
+		}
+		cursor = page.NextPageCursor
+	}
+}
+
+// GetInstrument 获取合约交易规则
+func (t *BybitTrader) GetInstrument(symbol string) (*Instrument, error) {
+	return t.instruments.get(symbol)
+}
+
+// FormatQuantity 格式化数量到合约数量步进（向下取整，避免超出可用保证金或持仓数量）
+func (t *BybitTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	spec, err := t.instruments.get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatQuantity(quantity), nil
+}
+
+// formatPrice 格式化价格到合约价格步进
+func (t *BybitTrader) formatPrice(symbol string, price float64) (string, error) {
+	spec, err := t.instruments.get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatPrice(price), nil
+}
+
+// CheckMinNotional 检查下单数量和金额是否满足合约最小要求
+func (t *BybitTrader) CheckMinNotional(symbol string, quantity float64) error {
+	spec, err := t.instruments.get(symbol)
+	if err != nil {
+		return err
+	}
+	price := 0.0
+	if spec.MinNotional > 0 {
+		if price, err = t.GetMarketPrice(symbol); err != nil {
+			return err
+		}
+	}
+	return spec.CheckMin(quantity, price)
+}
+
+// GetMarketPrice 获取最新成交价
+func (t *BybitTrader) GetMarketPrice(symbol string) (float64, error) {
+	data, err := t.request(http.MethodGet, "/v5/market/tickers", map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+	var result struct {
+		List []struct {
+			LastPrice string `json:"lastPrice"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return 0, fmt.Errorf("解析价格失败: %w", err)
+	}
+	if len(result.List) == 0 {
+		return 0, fmt.Errorf("未找到 %s 的价格", symbol)
+	}
+	price, err := strconv.ParseFloat(result.List[0].LastPrice, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析价格失败: %w", err)
+	}
+	return price, nil
+}
+
+// createOrder 下单，返回订单ID
+func (t *BybitTrader) createOrder(params map[string]interface{}) (string, error) {
+	params["category"] = "linear"
+	data, err := t.request(http.MethodPost, "/v5/order/create", params)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		OrderID string `json:"orderId"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("解析下单响应失败: %w", err)
+	}
+	t.clearPositionsCache()
+	return result.OrderID, nil
+}
+
+// OpenLong 开多仓（止盈止损随订单附加，按标记价格触发）
+func (t *BybitTrader) OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
+	return t.openPosition(symbol, "long", quantity, leverage, stopLoss, takeProfit)
+}
+
+// OpenShort 开空仓（止盈止损随订单附加，按标记价格触发）
+func (t *BybitTrader) OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
+	return t.openPosition(symbol, "short", quantity, leverage, stopLoss, takeProfit)
+}
+
+// openPosition 市价开仓
+func (t *BybitTrader) openPosition(symbol, side string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
+	// 先取消该币种的所有委托单（清理旧的挂单）
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	quantityFloat, parseErr := strconv.ParseFloat(quantityStr, 64)
+	if parseErr != nil || quantityFloat <= 0 {
+		return nil, fmt.Errorf("开仓数量过小，格式化后为 0 (原始: %.8f → 格式化: %s)", quantity, quantityStr)
+	}
+	if err := t.CheckMinNotional(symbol, quantityFloat); err != nil {
+		return nil, err
+	}
+
+	params := map[string]interface{}{
+		"symbol":      symbol,
+		"side":        bybitOrderSide(side, false),
+		"orderType":   "Market",
+		"qty":         quantityStr,
+		"positionIdx": bybitPositionIdx[side],
+	}
+	if stopLoss > 0 || takeProfit > 0 {
+		params["tpslMode"] = "Full"
+	}
+	if stopLoss > 0 {
+		priceStr, err := t.formatPrice(symbol, stopLoss)
+		if err != nil {
+			return nil, err
+		}
+		params["stopLoss"] = priceStr
+		params["slTriggerBy"] = "MarkPrice"
+	}
+	if takeProfit > 0 {
+		priceStr, err := t.formatPrice(symbol, takeProfit)
+		if err != nil {
+			return nil, err
+		}
+		params["takeProfit"] = priceStr
+		params["tpTriggerBy"] = "MarkPrice"
+	}
+
+	orderID, err := t.createOrder(params)
+	if err != nil {
+		return nil, fmt.Errorf("开%s仓失败: %w", sideLabel(side), err)
+	}
+	log.Printf("✓ 开%s仓成功: %s 数量: %s 订单ID: %s", sideLabel(side), symbol, quantityStr, orderID)
+
+	return &OrderResult{OrderID: orderID, Symbol: symbol, Quantity: quantityFloat}, nil
+}
+
+// CloseLong 平多仓
+func (t *BybitTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
+	return t.closePosition(symbol, "long", quantity)
+}
+
+// CloseShort 平空仓
+func (t *BybitTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
+	return t.closePosition(symbol, "short", quantity)
+}
+
+// closePosition 市价平仓（quantity=0表示全部平仓）
+// 随开仓附加的止盈止损绑定在持仓上，全部平仓后由交易所自动撤销
+func (t *BybitTrader) closePosition(symbol, side string, quantity float64) (*OrderResult, error) {
+	if quantity == 0 {
+		positions, err := t.GetPositions()
+		if err != nil {
+			return nil, err
+		}
+		if pos, ok := FindPosition(positions, symbol, side); ok {
+			quantity = pos.Quantity
+		}
+		if quantity == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的%s仓", symbol, sideLabel(side))
+		}
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	orderID, err := t.createOrder(map[string]interface{}{
+		"symbol":      symbol,
+		"side":        bybitOrderSide(side, true),
+		"orderType":   "Market",
+		"qty":         quantityStr,
+		"positionIdx": bybitPositionIdx[side],
+		"reduceOnly":  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("平%s仓失败: %w", sideLabel(side), err)
+	}
+	log.Printf("✓ 平%s仓成功: %s 数量: %s", sideLabel(side), symbol, quantityStr)
+
+	result := &OrderResult{OrderID: orderID, Symbol: symbol}
+	result.Quantity, _ = strconv.ParseFloat(quantityStr, 64)
+	return result, nil
+}
+
+// bybitOrderSide 持仓方向转换为Bybit买卖方向（Buy / Sell）
+func bybitOrderSide(positionSide string, isClose bool) string {
+	if orderSide(positionSide, isClose) == "buy" {
+		return "Buy"
+	}
+	return "Sell"
+}
+
+// PlaceEntryOrder 按订单类型开仓（post_only 使用 PostOnly，会立即成交时交易所直接撤单）
+func (t *BybitTrader) PlaceEntryOrder(order EntryOrder) (*OrderResult, error) {
+	if err := order.validate(); err != nil {
+		return nil, err
+	}
+	if order.OrderType == OrderTypeMarket {
+		return order.openMarket(t)
+	}
+
+	if err := t.SetLeverage(order.Symbol, order.Leverage); err != nil {
+		return nil, err
+	}
+	quantityStr, err := t.FormatQuantity(order.Symbol, order.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	quantityFloat, parseErr := strconv.ParseFloat(quantityStr, 64)
+	if parseErr != nil || quantityFloat <= 0 {
+		return nil, fmt.Errorf("开仓数量过小，格式化后为 0 (原始: %.8f → 格式化: %s)", order.Quantity, quantityStr)
+	}
+	if err := t.CheckMinNotional(order.Symbol, quantityFloat); err != nil {
+		return nil, err
+	}
+	priceStr, err := t.formatPrice(order.Symbol, order.Price)
+	if err != nil {
+		return nil, err
+	}
+
+	timeInForce := "GTC"
+	switch order.OrderType {
+	case OrderTypePostOnly:
+		timeInForce = "PostOnly"
+	case OrderTypeIOC:
+		timeInForce = "IOC"
+	}
+
+	orderID, err := t.createOrder(map[string]interface{}{
+		"symbol":      order.Symbol,
+		"side":        bybitOrderSide(order.Side, false),
+		"orderType":   "Limit",
+		"qty":         quantityStr,
+		"price":       priceStr,
+		"timeInForce": timeInForce,
+		"positionIdx": bybitPositionIdx[order.Side],
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s开仓失败: %w", OrderTypeLabel(order.OrderType), err)
+	}
+
+	// 下单接口不返回成交状态，查询一次订单
+	result, err := t.GetOrder(order.Symbol, orderID)
+	if err != nil {
+		log.Printf("  ⚠️ 查询 %s 订单 %s 状态失败: %v", order.Symbol, orderID, err)
+		result = &OrderResult{OrderID: orderID, Symbol: order.Symbol, Status: OrderStatusOpen, Quantity: quantityFloat}
+		result.Price, _ = strconv.ParseFloat(priceStr, 64)
+	}
+	log.Printf("✓ %s已提交: %s %s 价格: %s 数量: %s 状态: %s", OrderTypeLabel(order.OrderType), order.Symbol, order.Side, priceStr, quantityStr, result.Status)
+	return result, nil
+}
+
+// bybitOrder 订单信息（order/realtime、order/history 共用）
+type bybitOrder struct {
+	OrderID       string `json:"orderId"`
+	Symbol        string `json:"symbol"`
+	Side          string `json:"side"`
+	OrderType     string `json:"orderType"`
+	StopOrderType string `json:"stopOrderType"`
+	Price         string `json:"price"`
+	TriggerPrice  string `json:"triggerPrice"`
+	Qty           string `json:"qty"`
+	AvgPrice      string `json:"avgPrice"`
+	CumExecQty    string `json:"cumExecQty"`
+	CumExecFee    string `json:"cumExecFee"`
+	OrderStatus   string `json:"orderStatus"`
+	ReduceOnly    bool   `json:"reduceOnly"`
+	PositionIdx   int    `json:"positionIdx"`
+	CreatedTime   string `json:"createdTime"`
+}
+
+// queryOrders 查询订单列表
+func (t *BybitTrader) queryOrders(path string, params map[string]interface{}) ([]bybitOrder, string, error) {
+	params["category"] = "linear"
+	data, err := t.request(http.MethodGet, path, params)
+	if err != nil {
+		return nil, "", err
+	}
+	var result struct {
+		List           []bybitOrder `json:"list"`
+		NextPageCursor string       `json:"nextPageCursor"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, "", fmt.Errorf("解析订单数据失败: %w", err)
+	}
+	return result.List, result.NextPageCursor, nil
+}
+
+// GetOrder 查询订单（先查实时订单，查不到再查历史订单）
+func (t *BybitTrader) GetOrder(symbol, orderID string) (*OrderResult, error) {
+	params := map[string]interface{}{"symbol": symbol, "orderId": orderID}
+	orders, _, err := t.queryOrders("/v5/order/realtime", params)
+	if err == nil && len(orders) == 0 {
+		orders, _, err = t.queryOrders("/v5/order/history", map[string]interface{}{"symbol": symbol, "orderId": orderID})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("未找到订单: %s", orderID)
+	}
+
+	o := orders[0]
+	result := &OrderResult{
+		OrderID: o.OrderID,
+		Symbol:  o.Symbol,
+		Status:  bybitOrderStatus(o.OrderStatus),
+	}
+	result.AvgPrice, _ = strconv.ParseFloat(o.AvgPrice, 64)
+	result.ExecutedQty, _ = strconv.ParseFloat(o.CumExecQty, 64)
+	result.Fee, _ = strconv.ParseFloat(o.CumExecFee, 64)
+	result.Price, _ = strconv.ParseFloat(o.Price, 64)
+	result.Quantity, _ = strconv.ParseFloat(o.Qty, 64)
+	return result, nil
+}
+
+// CancelOrder 撤销指定订单
+func (t *BybitTrader) CancelOrder(symbol, orderID string) error {
+	if _, err := t.request(http.MethodPost, "/v5/order/cancel", map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+		"orderId":  orderID,
+	}); err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+	log.Printf("  ✓ 已撤销 %s 订单 %s", symbol, orderID)
+	return nil
+}
+
+// GetOpenOrders 查询未完成的挂单（包含随持仓设置的止盈止损条件单）
+func (t *BybitTrader) GetOpenOrders(symbol string) ([]OpenOrder, error) {
+	var result []OpenOrder
+	cursor := ""
+	for {
+		params := map[string]interface{}{"openOnly": 0, "limit": 50}
+		if symbol != "" {
+			params["symbol"] = symbol
+		} else {
+			params["settleCoin"] = "USDT"
+		}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		orders, next, err := t.queryOrders("/v5/order/realtime", params)
+		if err != nil {
+			return nil, fmt.Errorf("获取未完成订单失败: %w", err)
+		}
+
+		for _, o := range orders {
+			open := OpenOrder{
+				OrderID:    o.OrderID,
+				Symbol:     o.Symbol,
+				Side:       strings.ToLower(o.Side),
+				Type:       bybitOpenOrderType(o.OrderType, o.StopOrderType),
+				ReduceOnly: o.ReduceOnly || o.StopOrderType != "",
+				IsAlgo:     o.StopOrderType != "",
+				Time:       parseOKXTime(o.CreatedTime),
+			}
+			open.Price, _ = strconv.ParseFloat(o.Price, 64)
+			open.StopPrice, _ = strconv.ParseFloat(o.TriggerPrice, 64)
+			open.Quantity, _ = strconv.ParseFloat(o.Qty, 64)
+			open.ExecutedQty, _ = strconv.ParseFloat(o.CumExecQty, 64)
+			switch o.PositionIdx {
+			case 1:
+				open.PositionSide = "long"
+			case 2:
+				open.PositionSide = "short"
+			default:
+				open.PositionSide = inferPositionSide(open.Side, open.ReduceOnly)
+			}
+			result = append(result, open)
+		}
+
+		if next == "" || len(orders) == 0 {
+			return result, nil
+		}
+		cursor = next
+	}
+}
+
+// bybitOpenOrderType Bybit订单类型转换为统一挂单类型
+func bybitOpenOrderType(orderType, stopOrderType string) string {
+	switch stopOrderType {
+	case "":
+		if orderType == "Market" {
+			return OpenOrderTypeMarket
+		}
+		return OpenOrderTypeLimit
+	case "StopLoss", "PartialStopLoss":
+		return OpenOrderTypeStopLoss
+	case "TakeProfit", "PartialTakeProfit":
+		return OpenOrderTypeTakeProfit
+	case "TrailingStop":
+		return OpenOrderTypeTrailingStop
+	default:
+		return OpenOrderTypeTrigger
+	}
+}
+
+// bybitOrderStatus Bybit订单状态转换为统一状态
+func bybitOrderStatus(status string) string {
+	switch status {
+	case "New", "Untriggered":
+		return OrderStatusOpen
+	case "PartiallyFilled":
+		return OrderStatusPartiallyFilled
+	case "Filled":
+		return OrderStatusFilled
+	default: // Cancelled / Rejected / PartiallyFilledCanceled / Deactivated
+		return OrderStatusCanceled
+	}
+}
+
+// GetUserTrades 获取成交明细（同一订单的多笔成交合并为一条，按时间升序）
+func (t *BybitTrader) GetUserTrades(symbol string, startTime time.Time) ([]Fill, error) {
+	var fills []Fill
+
+	// execution/list 的 startTime/endTime 跨度不能超过7天，按窗口分段查询
+	for start := startTime; start.Before(time.Now()); start = start.Add(binanceHistoryWindow) {
+		end := start.Add(binanceHistoryWindow)
+		cursor := ""
+		for {
+			params := map[string]interface{}{
+				"category":  "linear",
+				"symbol":    symbol,
+				"startTime": start.UnixMilli(),
+				"endTime":   end.UnixMilli(),
+				"limit":     bybitHistoryPage,
+			}
+			if cursor != "" {
+				params["cursor"] = cursor
+			}
+			data, err := t.request(http.MethodGet, "/v5/execution/list", params)
+			if err != nil {
+				return nil, fmt.Errorf("获取成交历史失败: %w", err)
+			}
+			var page struct {
+				List []struct {
+					OrderID       string `json:"orderId"`
+					Symbol        string `json:"symbol"`
+					Side          string `json:"side"`
+					ExecPrice     string `json:"execPrice"`
+					ExecQty       string `json:"execQty"`
+					ExecFee       string `json:"execFee"`
+					ExecType      string `json:"execType"`
+					ExecTime      string `json:"execTime"`
+					ClosedSize    string `json:"closedSize"`
+					StopOrderType string `json:"stopOrderType"`
+				} `json:"list"`
+				NextPageCursor string `json:"nextPageCursor"`
+			}
+			if err := json.Unmarshal(data, &page); err != nil {
+				return nil, fmt.Errorf("解析成交历史失败: %w", err)
+			}
+
+			for _, e := range page.List {
+				if e.ExecType != "Trade" && e.ExecType != "BustTrade" {
+					continue // 资金费等非成交记录
+				}
+				closedSize, _ := strconv.ParseFloat(e.ClosedSize, 64)
+				isClose := closedSize > 0
+				// 平仓：买入平空、卖出平多；开仓：买入开多、卖出开空
+				side := "long"
+				if (e.Side == "Buy") == isClose {
+					side = "short"
+				}
+				fill := Fill{
+					OrderID: e.OrderID,
+					Symbol:  e.Symbol,
+					Side:    side,
+					IsClose: isClose,
+					Reason:  bybitExitReason(e.ExecType, e.StopOrderType),
+					Time:    parseOKXTime(e.ExecTime),
+				}
+				fill.Price, _ = strconv.ParseFloat(e.ExecPrice, 64)
+				fill.Quantity, _ = strconv.ParseFloat(e.ExecQty, 64)
+				fill.Fee, _ = strconv.ParseFloat(e.ExecFee, 64)
+				fills = append(fills, fill)
+			}
+			if page.NextPageCursor == "" || len(page.List) == 0 {
+				break
+			}
+			cursor = page.NextPageCursor
+		}
+	}
+
+	return mergeFills(fills), nil
+}
+
+// bybitExitReason 根据成交类型和条件单类型判断平仓原因
+func bybitExitReason(execType, stopOrderType string) string {
+	if execType == "BustTrade" {
+		return config.TradeExitLiquidation
+	}
+	switch stopOrderType {
+	case "StopLoss", "PartialStopLoss", "TrailingStop":
+		return config.TradeExitStopLoss
+	case "TakeProfit", "PartialTakeProfit":
+		return config.TradeExitTakeProfit
+	}
+	return ""
+}
+
+// GetFundingFees 获取累计资金费（统一账户交易流水中的 SETTLEMENT 记录）
+func (t *BybitTrader) GetFundingFees(symbol string, startTime time.Time) (float64, error) {
+	total := 0.0
+	for start := startTime; start.Before(time.Now()); start = start.Add(binanceHistoryWindow) {
+		cursor := ""
+		for {
+			params := map[string]interface{}{
+				"accountType": "UNIFIED",
+				"category":    "linear",
+				"currency":    "USDT",
+				"type":        "SETTLEMENT",
+				"startTime":   start.UnixMilli(),
+				"endTime":     start.Add(binanceHistoryWindow).UnixMilli(),
+				"limit":       50,
+			}
+			if cursor != "" {
+				params["cursor"] = cursor
+			}
+			data, err := t.request(http.MethodGet, "/v5/account/transaction-log", params)
+			if err != nil {
+				return 0, fmt.Errorf("获取资金费流水失败: %w", err)
+			}
+			var page struct {
+				List []struct {
+					Symbol string `json:"symbol"`
+					Change string `json:"change"` // 余额变化（正数为收入）
+				} `json:"list"`
+				NextPageCursor string `json:"nextPageCursor"`
+			}
+			if err := json.Unmarshal(data, &page); err != nil {
+				return 0, fmt.Errorf("解析资金费流水失败: %w", err)
+			}
+			for _, item := range page.List {
+				if item.Symbol != symbol {
+					continue
+				}
+				change, _ := strconv.ParseFloat(item.Change, 64)
+				total += change
+			}
+			if page.NextPageCursor == "" || len(page.List) == 0 {
+				break
+			}
+			cursor = page.NextPageCursor
+		}
+	}
+	return total, nil
+}
+
+// SetStopLoss 设置止损（quantity>0 时为部分止损，否则止损整个持仓）
+func (t *BybitTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	return t.setTradingStop(symbol, positionSide, quantity, "stopLoss", stopPrice)
+}
+
+// SetTakeProfit 设置止盈（quantity>0 时为部分止盈，否则止盈整个持仓）
+func (t *BybitTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	return t.setTradingStop(symbol, positionSide, quantity, "takeProfit", takeProfitPrice)
+}
+
+// setTradingStop 设置持仓止盈止损（kind: "stopLoss" / "takeProfit"，按标记价格触发、市价成交）
+func (t *BybitTrader) setTradingStop(symbol, positionSide string, quantity float64, kind string, price float64) error {
+	side := strings.ToLower(positionSide)
+	idx, ok := bybitPositionIdx[side]
+	if !ok {
+		return fmt.Errorf("无效的持仓方向: %s", positionSide)
+	}
+	priceStr, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return err
+	}
+
+	prefix := "sl"
+	label := "止损"
+	if kind == "takeProfit" {
+		prefix, label = "tp", "止盈"
+	}
+	params := map[string]interface{}{
+		"category":           "linear",
+		"symbol":             symbol,
+		"positionIdx":        idx,
+		"tpslMode":           "Full",
+		kind:                 priceStr,
+		prefix + "TriggerBy": "MarkPrice",
+	}
+	if quantity > 0 {
+		sizeStr, err := t.FormatQuantity(symbol, quantity)
+		if err != nil {
+			return err
+		}
+		params["tpslMode"] = "Partial"
+		params[prefix+"Size"] = sizeStr
+		params[prefix+"OrderType"] = "Market"
+	}
+
+	if _, err := t.request(http.MethodPost, "/v5/position/trading-stop", params); err != nil {
+		return fmt.Errorf("设置%s失败: %w", label, err)
+	}
+	log.Printf("  %s价设置: %s", label, priceStr)
+	return nil
+}
+
+// CancelStopLossOrders 仅取消止损单（不影响止盈单）
+func (t *BybitTrader) CancelStopLossOrders(symbol string) error {
+	return t.cancelTradingStops(symbol, OpenOrderTypeStopLoss)
+}
+
+// CancelTakeProfitOrders 仅取消止盈单（不影响止损单）
+func (t *BybitTrader) CancelTakeProfitOrders(symbol string) error {
+	return t.cancelTradingStops(symbol, OpenOrderTypeTakeProfit)
+}
+
+// cancelTradingStops 取消指定类型的止盈止损
+// 整仓止盈止损（Full模式）通过把持仓的止盈/止损价设为0取消，部分止盈止损按订单撤销
+func (t *BybitTrader) cancelTradingStops(symbol, orderType string) error {
+	orders, _, err := t.queryOrders("/v5/order/realtime", map[string]interface{}{"symbol": symbol, "orderFilter": "StopOrder"})
+	if err != nil {
+		return fmt.Errorf("获取条件单失败: %w", err)
+	}
+	tpslOrders, _, err := t.queryOrders("/v5/order/realtime", map[string]interface{}{"symbol": symbol, "orderFilter": "tpslOrder"})
+	if err != nil {
+		return fmt.Errorf("获取止盈止损单失败: %w", err)
+	}
+	orders = append(orders, tpslOrders...)
+
+	kind, label := "stopLoss", "止损"
+	if orderType == OpenOrderTypeTakeProfit {
+		kind, label = "takeProfit", "止盈"
+	}
+	canceledCount := 0
+	clearedIdx := make(map[int]bool)
+	for _, o := range orders {
+		if bybitOpenOrderType(o.OrderType, o.StopOrderType) != orderType {
+			continue
+		}
+		if o.StopOrderType == "StopLoss" || o.StopOrderType == "TakeProfit" {
+			if clearedIdx[o.PositionIdx] {
+				continue
+			}
+			clearedIdx[o.PositionIdx] = true
+			_, err = t.request(http.MethodPost, "/v5/position/trading-stop", map[string]interface{}{
+				"category":    "linear",
+				"symbol":      symbol,
+				"positionIdx": o.PositionIdx,
+				"tpslMode":    "Full",
+				kind:          "0",
+			})
+		} else {
+			_, err = t.request(http.MethodPost, "/v5/order/cancel", map[string]interface{}{
+				"category": "linear",
+				"symbol":   symbol,
+				"orderId":  o.OrderID,
+			})
+		}
+		if err != nil {
+			log.Printf("  ⚠ 取消%s单 %s 失败: %v", label, o.OrderID, err)
+			continue
+		}
+		canceledCount++
+	}
+
+	if canceledCount > 0 {
+		log.Printf("  ✓ 已取消 %s 的 %d 个%s单", symbol, canceledCount, label)
+	}
+	return nil
+}
+
+// CancelAllOrders 取消该币种的所有挂单
+func (t *BybitTrader) CancelAllOrders(symbol string) error {
+	if _, err := t.request(http.MethodPost, "/v5/order/cancel-all", map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+	}); err != nil {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// CancelStopOrders 取消该币种的止盈止损单
+func (t *BybitTrader) CancelStopOrders(symbol string) error {
+	if err := t.CancelStopLossOrders(symbol); err != nil {
+		return err
+	}
+	return t.CancelTakeProfitOrders(symbol)
+}
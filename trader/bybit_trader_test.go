@@ -0,0 +1,425 @@
+package trader
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testBybitAPIKey    = "test-key"
+	testBybitSecretKey = "test-secret"
+)
+
+// bybitCall 本地Bybit模拟服务收到的请求
+type bybitCall struct {
+	Method string
+	Path   string
+	Query  string
+	Body   map[string]interface{}
+	Raw    string
+	Header http.Header
+}
+
+// fakeBybit 本地Bybit模拟服务：按路径返回预设的result，未设置的路径返回空result
+type fakeBybit struct {
+	mu        sync.Mutex
+	calls     []bybitCall
+	responses map[string]func(r *http.Request) string
+}
+
+func (f *fakeBybit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	raw, _ := io.ReadAll(r.Body)
+	call := bybitCall{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Raw: string(raw), Header: r.Header.Clone()}
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &call.Body)
+	}
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+
+	result := "{}"
+	if fn, ok := f.responses[r.URL.Path]; ok {
+		result = fn(r)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = io.WriteString(w, `{"retCode":0,"retMsg":"OK","result":`+result+`}`)
+}
+
+// callsTo 返回发往指定路径的请求
+func (f *fakeBybit) callsTo(path string) []bybitCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []bybitCall
+	for _, c := range f.calls {
+		if c.Path == path {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// newTestBybitTrader 创建连接到本地模拟服务的交易器（不走 NewBybitTrader，避免访问真实接口和共享合约规则注册表）
+func newTestBybitTrader(t *testing.T, responses map[string]func(r *http.Request) string) (*BybitTrader, *fakeBybit) {
+	t.Helper()
+	fake := &fakeBybit{responses: responses}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	trader := &BybitTrader{
+		rest:          newRESTClient("Bybit", server.URL, newBybitSigner(testBybitAPIKey, testBybitSecretKey)),
+		cacheDuration: 15 * time.Second,
+	}
+	trader.rest.maxRetries = 1
+	trader.instruments = &instrumentRegistry{name: "Bybit", ttl: time.Hour, load: trader.loadInstruments}
+	return trader, fake
+}
+
+// bybitTestInstruments 两页合约规则，第二页包含非USDT结算合约（应被过滤）
+func bybitTestInstruments(r *http.Request) string {
+	if r.URL.Query().Get("cursor") == "" {
+		return `{"list":[{"symbol":"BTCUSDT","settleCoin":"USDT",
+			"priceFilter":{"tickSize":"0.10"},
+			"lotSizeFilter":{"qtyStep":"0.001","minOrderQty":"0.001","minNotionalValue":"5"},
+			"leverageFilter":{"maxLeverage":"100.00"}}],"nextPageCursor":"page2"}`
+	}
+	return `{"list":[{"symbol":"ETHUSDT","settleCoin":"USDT",
+			"priceFilter":{"tickSize":"0.01"},
+			"lotSizeFilter":{"qtyStep":"0.01","minOrderQty":"0.01","minNotionalValue":"5"},
+			"leverageFilter":{"maxLeverage":"50"}},
+		{"symbol":"BTCPERP","settleCoin":"USDC",
+			"priceFilter":{"tickSize":"0.5"},
+			"lotSizeFilter":{"qtyStep":"0.001","minOrderQty":"0.001"},
+			"leverageFilter":{"maxLeverage":"100"}}],"nextPageCursor":""}`
+}
+
+func bybitTestTicker(*http.Request) string {
+	return `{"list":[{"symbol":"BTCUSDT","lastPrice":"50000"}]}`
+}
+
+func TestBybitRequestSigning(t *testing.T) {
+	trader, fake := newTestBybitTrader(t, nil)
+
+	if _, err := trader.request(http.MethodGet, "/v5/account/wallet-balance", map[string]interface{}{"accountType": "UNIFIED"}); err != nil {
+		t.Fatalf("GET请求失败: %v", err)
+	}
+	if _, err := trader.request(http.MethodPost, "/v5/position/set-leverage", map[string]interface{}{"symbol": "BTCUSDT"}); err != nil {
+		t.Fatalf("POST请求失败: %v", err)
+	}
+
+	for _, call := range fake.calls {
+		payload := call.Query
+		if call.Method == http.MethodPost {
+			payload = call.Raw
+		}
+		timestamp := call.Header.Get("X-BAPI-TIMESTAMP")
+		if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+			t.Fatalf("%s %s 时间戳无效: %q", call.Method, call.Path, timestamp)
+		}
+		if got := call.Header.Get("X-BAPI-API-KEY"); got != testBybitAPIKey {
+			t.Errorf("%s API-KEY = %q", call.Path, got)
+		}
+		if got := call.Header.Get("X-BAPI-RECV-WINDOW"); got != bybitRecvWindow {
+			t.Errorf("%s RECV-WINDOW = %q", call.Path, got)
+		}
+		want := hex.EncodeToString(hmacSHA256(testBybitSecretKey, timestamp+testBybitAPIKey+bybitRecvWindow+payload))
+		if got := call.Header.Get("X-BAPI-SIGN"); got != want {
+			t.Errorf("%s %s 签名 = %s, want %s", call.Method, call.Path, got, want)
+		}
+	}
+	if got := fake.calls[0].Query; got != "accountType=UNIFIED" {
+		t.Errorf("GET参数应放在查询字符串: %q", got)
+	}
+	if got := fake.calls[1].Body["symbol"]; got != "BTCUSDT" {
+		t.Errorf("POST参数应放在JSON请求体: %v", fake.calls[1].Body)
+	}
+}
+
+func TestBybitPublicRequestUnsigned(t *testing.T) {
+	trader, fake := newTestBybitTrader(t, nil)
+	if _, err := trader.requestPublic("/v5/market/instruments-info", map[string]interface{}{"category": "linear"}); err != nil {
+		t.Fatalf("公共请求失败: %v", err)
+	}
+	if sign := fake.calls[0].Header.Get("X-BAPI-SIGN"); sign != "" {
+		t.Errorf("公共请求不应签名，得到 %q", sign)
+	}
+}
+
+func TestBybitAPIErrorCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"retCode":110043,"retMsg":"leverage not modified","result":{}}`)
+	}))
+	defer server.Close()
+
+	trader := &BybitTrader{rest: newRESTClient("Bybit", server.URL, newBybitSigner(testBybitAPIKey, testBybitSecretKey))}
+	trader.rest.maxRetries = 1
+	trader.instruments = &instrumentRegistry{name: "Bybit", ttl: time.Hour, load: func() (map[string]Instrument, error) {
+		return map[string]Instrument{}, nil
+	}}
+
+	_, err := trader.request(http.MethodPost, "/v5/position/set-leverage", nil)
+	if !isBybitError(err, bybitCodeLeverageNotModified) {
+		t.Fatalf("应返回 retCode=110043 的业务错误，得到 %v", err)
+	}
+	if err := trader.SetLeverage("BTCUSDT", 10); err != nil {
+		t.Errorf("杠杆未修改不应视为失败: %v", err)
+	}
+}
+
+func TestBybitTimestampRejectedResync(t *testing.T) {
+	var mu sync.Mutex
+	timeCalls, orderCalls := 0, 0
+	serverOffset := 3 * time.Second
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/v5/market/time":
+			timeCalls++
+			nano := time.Now().Add(serverOffset).UnixNano()
+			_, _ = io.WriteString(w, `{"retCode":0,"retMsg":"OK","result":{"timeNano":"`+strconv.FormatInt(nano, 10)+`"}}`)
+		default:
+			orderCalls++
+			if orderCalls == 1 {
+				_, _ = io.WriteString(w, `{"retCode":10002,"retMsg":"invalid request, please check your server timestamp or recv_window param","result":{}}`)
+				return
+			}
+			_, _ = io.WriteString(w, `{"retCode":0,"retMsg":"OK","result":{}}`)
+		}
+	}))
+	defer server.Close()
+
+	trader := &BybitTrader{rest: newRESTClient("Bybit", server.URL, newBybitSigner(testBybitAPIKey, testBybitSecretKey))}
+	trader.rest.maxRetries = 1
+	trader.rest.timestampRejected = isBybitTimestampRejected
+	trader.rest.clock.sync("Bybit", trader.fetchServerTime)
+
+	// 模拟本地时钟漂移：服务器时间继续前移
+	mu.Lock()
+	serverOffset = 10 * time.Second
+	mu.Unlock()
+
+	if _, err := trader.request(http.MethodPost, "/v5/order/create", map[string]interface{}{"symbol": "BTCUSDT"}); err != nil {
+		t.Fatalf("时间戳被拒绝后应重新同步并重试成功: %v", err)
+	}
+	if timeCalls != 2 || orderCalls != 2 {
+		t.Errorf("应同步2次服务器时间、发送2次请求，实际 %d/%d", timeCalls, orderCalls)
+	}
+	if offset := trader.rest.clock.now().Sub(time.Now()); offset < 9*time.Second {
+		t.Errorf("重新同步后的时钟偏移 = %v，应约为10s", offset)
+	}
+}
+
+func TestServerClockPeriodicResync(t *testing.T) {
+	syncs := 0
+	var clock serverClock
+	clock.sync("Test", func() (time.Time, error) {
+		syncs++
+		return time.Now(), nil
+	})
+	if clock.stale() {
+		t.Fatal("刚同步的时钟不应过期")
+	}
+	clock.syncedAt = time.Now().Add(-clockResyncInterval)
+	if !clock.stale() {
+		t.Fatal("超过同步间隔后应重新同步")
+	}
+	clock.resync()
+	if syncs != 2 || clock.stale() {
+		t.Errorf("同步次数 = %d, stale = %v", syncs, clock.stale())
+	}
+}
+
+func TestBybitHedgeModePositionIdx(t *testing.T) {
+	trader, fake := newTestBybitTrader(t, map[string]func(r *http.Request) string{
+		"/v5/market/instruments-info": bybitTestInstruments,
+		"/v5/market/tickers":          bybitTestTicker,
+		"/v5/order/create": func(*http.Request) string {
+			return `{"orderId":"1001"}`
+		},
+	})
+
+	if err := trader.setHedgeMode(); err != nil {
+		t.Fatalf("设置双向持仓失败: %v", err)
+	}
+	switchMode := fake.callsTo("/v5/position/switch-mode")
+	if len(switchMode) != 1 || switchMode[0].Body["mode"] != float64(3) {
+		t.Fatalf("双向持仓模式应为 mode=3: %+v", switchMode)
+	}
+
+	if _, err := trader.OpenLong("BTCUSDT", 0.01, 10, 0, 0); err != nil {
+		t.Fatalf("开多失败: %v", err)
+	}
+	if _, err := trader.OpenShort("BTCUSDT", 0.01, 10, 0, 0); err != nil {
+		t.Fatalf("开空失败: %v", err)
+	}
+	if err := trader.SetStopLoss("BTCUSDT", "SHORT", 0, 51000); err != nil {
+		t.Fatalf("设置止损失败: %v", err)
+	}
+
+	orders := fake.callsTo("/v5/order/create")
+	if len(orders) != 2 {
+		t.Fatalf("应下2个订单，实际 %d", len(orders))
+	}
+	cases := []struct {
+		call     bybitCall
+		side     string
+		wantIdx  float64
+		wantSide string
+	}{
+		{orders[0], "long", 1, "Buy"},
+		{orders[1], "short", 2, "Sell"},
+	}
+	for _, c := range cases {
+		if got := c.call.Body["positionIdx"]; got != c.wantIdx {
+			t.Errorf("%s positionIdx = %v, want %v", c.side, got, c.wantIdx)
+		}
+		if got := c.call.Body["side"]; got != c.wantSide {
+			t.Errorf("%s side = %v, want %v", c.side, got, c.wantSide)
+		}
+		if _, ok := c.call.Body["tpslMode"]; ok {
+			t.Errorf("%s 未设置止盈止损时不应带 tpslMode", c.side)
+		}
+	}
+
+	stops := fake.callsTo("/v5/position/trading-stop")
+	if len(stops) != 1 || stops[0].Body["positionIdx"] != float64(2) {
+		t.Fatalf("空单止损应使用 positionIdx=2: %+v", stops)
+	}
+}
+
+func TestBybitOpenWithAttachedTPSL(t *testing.T) {
+	trader, fake := newTestBybitTrader(t, map[string]func(r *http.Request) string{
+		"/v5/market/instruments-info": bybitTestInstruments,
+		"/v5/market/tickers":          bybitTestTicker,
+		"/v5/order/create": func(*http.Request) string {
+			return `{"orderId":"2002"}`
+		},
+	})
+
+	result, err := trader.OpenLong("BTCUSDT", 0.0127, 20, 49876.56, 51234.44)
+	if err != nil {
+		t.Fatalf("开多失败: %v", err)
+	}
+	if result.OrderID != "2002" || result.Quantity != 0.012 {
+		t.Errorf("开仓结果 = %+v", result)
+	}
+
+	orders := fake.callsTo("/v5/order/create")
+	if len(orders) != 1 {
+		t.Fatalf("应下1个订单，实际 %d", len(orders))
+	}
+	body := orders[0].Body
+	want := map[string]interface{}{
+		"category":    "linear",
+		"symbol":      "BTCUSDT",
+		"side":        "Buy",
+		"orderType":   "Market",
+		"qty":         "0.012",
+		"positionIdx": float64(1),
+		"tpslMode":    "Full",
+		"stopLoss":    "49876.6",
+		"slTriggerBy": "MarkPrice",
+		"takeProfit":  "51234.4",
+		"tpTriggerBy": "MarkPrice",
+	}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("下单参数 %s = %v, want %v", key, body[key], value)
+		}
+	}
+
+	leverage := fake.callsTo("/v5/position/set-leverage")
+	if len(leverage) != 1 || leverage[0].Body["buyLeverage"] != "20" || leverage[0].Body["sellLeverage"] != "20" {
+		t.Errorf("开仓前应设置杠杆为20: %+v", leverage)
+	}
+}
+
+func TestBybitOpenRejectsTooSmallQuantity(t *testing.T) {
+	trader, fake := newTestBybitTrader(t, map[string]func(r *http.Request) string{
+		"/v5/market/instruments-info": bybitTestInstruments,
+		"/v5/market/tickers":          bybitTestTicker,
+	})
+
+	if _, err := trader.OpenLong("BTCUSDT", 0.0004, 10, 0, 0); err == nil {
+		t.Fatal("数量取整后为0应拒绝下单")
+	}
+	if orders := fake.callsTo("/v5/order/create"); len(orders) != 0 {
+		t.Errorf("不应发送下单请求: %+v", orders)
+	}
+}
+
+func TestBybitInstrumentsPrecision(t *testing.T) {
+	trader, fake := newTestBybitTrader(t, map[string]func(r *http.Request) string{
+		"/v5/market/instruments-info": bybitTestInstruments,
+	})
+
+	btc, err := trader.GetInstrument("BTCUSDT")
+	if err != nil {
+		t.Fatalf("获取BTCUSDT合约规则失败: %v", err)
+	}
+	if btc.TickSize != 0.1 || btc.QtyStep != 0.001 || btc.MinQty != 0.001 || btc.MinNotional != 5 || btc.MaxLeverage != 100 {
+		t.Errorf("BTCUSDT 合约规则 = %+v", btc)
+	}
+
+	eth, err := trader.GetInstrument("ETHUSDT")
+	if err != nil {
+		t.Fatalf("第二页的ETHUSDT应被加载: %v", err)
+	}
+	if eth.MaxLeverage != 50 {
+		t.Errorf("ETHUSDT 最大杠杆 = %d", eth.MaxLeverage)
+	}
+	if _, err := trader.GetInstrument("BTCPERP"); err == nil {
+		t.Error("非USDT结算合约应被过滤")
+	}
+
+	pages := fake.callsTo("/v5/market/instruments-info")
+	if len(pages) != 2 {
+		t.Fatalf("应分页请求2次，实际 %d", len(pages))
+	}
+	if pages[1].Header.Get("X-BAPI-SIGN") != "" {
+		t.Error("合约规则为公共接口，不应签名")
+	}
+
+	quantityCases := []struct {
+		symbol   string
+		quantity float64
+		want     string
+	}{
+		{"BTCUSDT", 0.0129, "0.012"},
+		{"BTCUSDT", 1, "1.000"},
+		{"ETHUSDT", 0.129, "0.12"},
+	}
+	for _, c := range quantityCases {
+		got, err := trader.FormatQuantity(c.symbol, c.quantity)
+		if err != nil || got != c.want {
+			t.Errorf("FormatQuantity(%s, %v) = %q, %v; want %q", c.symbol, c.quantity, got, err, c.want)
+		}
+	}
+
+	priceCases := []struct {
+		symbol string
+		price  float64
+		want   string
+	}{
+		{"BTCUSDT", 50000.04, "50000.0"},
+		{"BTCUSDT", 50000.06, "50000.1"},
+		{"ETHUSDT", 3000.126, "3000.13"},
+	}
+	for _, c := range priceCases {
+		got, err := trader.formatPrice(c.symbol, c.price)
+		if err != nil || got != c.want {
+			t.Errorf("formatPrice(%s, %v) = %q, %v; want %q", c.symbol, c.price, got, err, c.want)
+		}
+	}
+
+	if err := btc.CheckMin(0.001, 1000); err == nil {
+		t.Error("下单金额 1 USDT 低于最小金额 5 USDT 应被拒绝")
+	}
+}
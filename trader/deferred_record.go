@@ -0,0 +1,58 @@
+package trader
+
+import (
+	"log"
+	"nofx/logger"
+	"sync"
+)
+
+// deferredRecord 两个AI周期之间由监控goroutine自动执行的动作（保本止损、风控熔断）
+// 不单独写决策日志（单独写会占用周期编号，挤掉AI历史决策），合并进下一个周期的决策记录
+type deferredRecord struct {
+	mu           sync.Mutex
+	decisions    []logger.DecisionAction
+	executionLog []string
+	riskTrip     *logger.RiskTrip
+}
+
+// deferRecord 暂存监控goroutine产生的记录内容，等待下一个周期合并
+func (at *AutoTrader) deferRecord(record *logger.DecisionRecord) {
+	if len(record.Decisions) == 0 && len(record.ExecutionLog) == 0 && record.RiskTrip == nil {
+		return
+	}
+
+	at.deferred.mu.Lock()
+	defer at.deferred.mu.Unlock()
+	at.deferred.decisions = append(at.deferred.decisions, record.Decisions...)
+	at.deferred.executionLog = append(at.deferred.executionLog, record.ExecutionLog...)
+	if record.RiskTrip != nil {
+		at.deferred.riskTrip = record.RiskTrip
+	}
+}
+
+// mergeDeferredRecord 把周期之间暂存的动作合并到当前周期的决策记录（放在本周期动作之前）
+func (at *AutoTrader) mergeDeferredRecord(record *logger.DecisionRecord) {
+	at.deferred.mu.Lock()
+	decisions, executionLog, riskTrip := at.deferred.decisions, at.deferred.executionLog, at.deferred.riskTrip
+	at.deferred.decisions, at.deferred.executionLog, at.deferred.riskTrip = nil, nil, nil
+	at.deferred.mu.Unlock()
+
+	record.Decisions = append(decisions, record.Decisions...)
+	record.ExecutionLog = append(executionLog, record.ExecutionLog...)
+	if record.RiskTrip == nil {
+		record.RiskTrip = riskTrip
+	}
+}
+
+// flushDeferredRecord 交易员关闭时把尚未合并的暂存动作单独写入决策日志（之后不会再有AI周期）
+func (at *AutoTrader) flushDeferredRecord() {
+	record := &logger.DecisionRecord{ExecutionLog: []string{}}
+	at.mergeDeferredRecord(record)
+	if len(record.Decisions) == 0 && len(record.ExecutionLog) == 0 && record.RiskTrip == nil {
+		return
+	}
+	record.Success = record.RiskTrip == nil
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠️ [%s] 保存周期外自动操作记录失败: %v", at.name, err)
+	}
+}
@@ -0,0 +1,429 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// 限价开仓挂单默认参数
+const (
+	defaultEntryOrderTimeout  = 10 * time.Minute // 挂单超时时间
+	defaultEntryOrderReprices = 1                // 超时后按盘口重新定价的次数
+)
+
+// pendingEntry 已挂出、尚未成交的限价开仓单（成交后再挂止盈止损）
+// 挂单同时写入交易台账，进程重启后恢复跟踪，避免重启期间成交的持仓没有止盈止损
+type pendingEntry struct {
+	Symbol     string    `json:"symbol"`
+	Side       string    `json:"side"` // "long" or "short"
+	OrderType  string    `json:"order_type"`
+	OrderID    string    `json:"order_id"`
+	Price      float64   `json:"price"`
+	Quantity   float64   `json:"quantity"`
+	Leverage   int       `json:"leverage"`
+	StopLoss   float64   `json:"stop_loss"`
+	TakeProfit float64   `json:"take_profit"`
+	PlacedAt   time.Time `json:"placed_at"` // 挂单时间（重新定价后刷新）
+	Reprices   int       `json:"reprices"`  // 已重新定价次数
+
+	TakeProfitLevels []decision.TakeProfitLevel `json:"take_profit_levels,omitempty"` // 分批止盈档位（设置后代替 TakeProfit）
+}
+
+// key 挂单键（symbol_side，与持仓键一致）
+func (e *pendingEntry) key() string {
+	return e.Symbol + "_" + e.Side
+}
+
+// action 生成挂单相关的决策动作记录（prefix 为空表示开仓成交）
+func (e *pendingEntry) action(prefix string, order *OrderResult) logger.DecisionAction {
+	action := logger.DecisionAction{
+		Action:    prefix + "open_" + e.Side,
+		Symbol:    e.Symbol,
+		Quantity:  e.Quantity,
+		Leverage:  e.Leverage,
+		Price:     e.Price,
+		OrderType: e.OrderType,
+		Timestamp: time.Now(),
+		Success:   true,
+	}
+	if order != nil {
+		// 滑点相对委托价计算
+		recordFill(&action, order, orderSide(e.Side, false), e.Price)
+	}
+	return action
+}
+
+// hasPendingEntry 同币种同方向是否有未成交的开仓挂单
+func (at *AutoTrader) hasPendingEntry(symbol, side string) bool {
+	at.pendingMutex.Lock()
+	defer at.pendingMutex.Unlock()
+	_, exists := at.pendingEntries[symbol+"_"+side]
+	return exists
+}
+
+// snapshotPendingEntries 返回当前挂单的副本
+func (at *AutoTrader) snapshotPendingEntries() []pendingEntry {
+	at.pendingMutex.Lock()
+	defer at.pendingMutex.Unlock()
+	entries := make([]pendingEntry, 0, len(at.pendingEntries))
+	for _, entry := range at.pendingEntries {
+		entries = append(entries, *entry)
+	}
+	return entries
+}
+
+// pendingOrderInfos 未成交挂单（传给AI和风控）
+func (at *AutoTrader) pendingOrderInfos() []decision.PendingOrderInfo {
+	var infos []decision.PendingOrderInfo
+	for _, entry := range at.snapshotPendingEntries() {
+		infos = append(infos, decision.PendingOrderInfo{
+			Symbol:          entry.Symbol,
+			Side:            entry.Side,
+			OrderType:       entry.OrderType,
+			Price:           entry.Price,
+			Quantity:        entry.Quantity,
+			Leverage:        entry.Leverage,
+			PositionSizeUSD: entry.Price * entry.Quantity,
+			StopLoss:        entry.StopLoss,
+			TakeProfit:      entry.TakeProfit,
+			AgeMinutes:      int(time.Since(entry.PlacedAt).Minutes()),
+		})
+	}
+	return infos
+}
+
+// executeEntryOrder 提交限价类开仓委托（limit/post_only/ioc）
+// 立即成交时挂止盈止损；未成交的挂单记录为 pending_open_long/pending_open_short，由后续周期跟踪
+func (at *AutoTrader) executeEntryOrder(d *decision.Decision, side string, currentPrice float64, actionRecord *logger.DecisionAction) error {
+	quantity := d.PositionSizeUSD / d.EntryPrice
+	actionRecord.OrderType = d.OrderType
+	actionRecord.Quantity = quantity
+	actionRecord.Price = d.EntryPrice
+
+	if err := at.trader.SetMarginMode(d.Symbol, at.config.IsCrossMargin); err != nil {
+		log.Printf("  ⚠️ 设置仓位模式失败: %v (继续执行)", err)
+	}
+
+	log.Printf("  📤 提交%s: %s 开%s 限价 %.4f 数量 %.8f（当前价 %.4f）",
+		OrderTypeLabel(d.OrderType), d.Symbol, sideLabel(side), d.EntryPrice, quantity, currentPrice)
+	order, err := at.trader.PlaceEntryOrder(EntryOrder{
+		Symbol:    d.Symbol,
+		Side:      side,
+		OrderType: d.OrderType,
+		Quantity:  quantity,
+		Price:     d.EntryPrice,
+		Leverage:  d.Leverage,
+	})
+	if err != nil {
+		return fmt.Errorf("提交%s失败: %w", OrderTypeLabel(d.OrderType), err)
+	}
+	actionRecord.OrderID = order.NumericOrderID()
+
+	entry := &pendingEntry{
+		Symbol:     d.Symbol,
+		Side:       side,
+		OrderType:  d.OrderType,
+		OrderID:    order.OrderID,
+		Price:      d.EntryPrice,
+		Quantity:   quantity,
+		Leverage:   d.Leverage,
+		StopLoss:   d.StopLoss,
+		TakeProfit: d.TakeProfit,
+		PlacedAt:   time.Now(),
+
+		TakeProfitLevels: d.TakeProfitLevels,
+	}
+
+	switch {
+	case order.Status == OrderStatusFilled || (order.Status == OrderStatusCanceled && order.ExecutedQty > 0):
+		at.onEntryFilled(entry, order)
+		recordFill(actionRecord, order, orderSide(side, false), d.EntryPrice)
+		return nil
+	case order.IsOpen():
+		at.storePendingEntry(entry)
+		actionRecord.Action = "pending_" + actionRecord.Action
+		log.Printf("  ⏳ 挂单成功，订单ID: %s（%v 内未成交将%s）", order.OrderID, at.config.EntryOrderTimeout, at.expireLabel(entry))
+		return nil
+	default:
+		return fmt.Errorf("%s未成交，已被撤销", OrderTypeLabel(d.OrderType))
+	}
+}
+
+// onEntryFilled 开仓挂单成交：按成交数量挂止盈止损并记录交易台账
+func (at *AutoTrader) onEntryFilled(entry *pendingEntry, order *OrderResult) {
+	at.resolveFill(entry.Symbol, order)
+	quantity, price := entry.Quantity, entry.Price
+	if order.ExecutedQty > 0 {
+		quantity = order.ExecutedQty
+	}
+	if order.AvgPrice > 0 {
+		price = order.AvgPrice
+	}
+	log.Printf("  ✅ %s %s 开%s成交: 均价 %.4f 数量 %.8f", entry.Symbol, OrderTypeLabel(entry.OrderType), sideLabel(entry.Side), price, quantity)
+
+	positionSide := strings.ToUpper(entry.Side)
+	if entry.StopLoss > 0 {
+		if err := at.trader.SetStopLoss(entry.Symbol, positionSide, quantity, entry.StopLoss); err != nil {
+			log.Printf("  ⚠️ %s 设置止损失败: %v", entry.Symbol, err)
+		} else {
+			log.Printf("  ✓ 止损已设置: %.4f", entry.StopLoss)
+		}
+	}
+	if len(entry.TakeProfitLevels) == 0 && entry.TakeProfit > 0 {
+		if err := at.trader.SetTakeProfit(entry.Symbol, positionSide, quantity, entry.TakeProfit); err != nil {
+			log.Printf("  ⚠️ %s 设置止盈失败: %v", entry.Symbol, err)
+		} else {
+			log.Printf("  ✓ 止盈已设置: %.4f", entry.TakeProfit)
+		}
+	}
+
+	at.positionFirstSeenTime[entry.key()] = time.Now().UnixMilli()
+	at.recordTradeOpen(entry.Symbol, entry.Side, entry.Leverage, entry.StopLoss, entry.TakeProfit, quantity, price, order)
+	if len(entry.TakeProfitLevels) > 0 {
+		at.setupTakeProfitLadder(entry.Symbol, entry.Side, quantity, entry.TakeProfitLevels)
+	}
+}
+
+// processPendingEntries 每个周期开始时检查挂单状态：成交则挂止盈止损，超时则重新定价或撤单
+func (at *AutoTrader) processPendingEntries(record *logger.DecisionRecord) {
+	for _, snapshot := range at.snapshotPendingEntries() {
+		entry := snapshot
+		order, err := at.trader.GetOrder(entry.Symbol, entry.OrderID)
+		if err != nil {
+			log.Printf("⚠️ [%s] 查询挂单 %s %s 失败: %v", at.name, entry.Symbol, entry.OrderID, err)
+			continue
+		}
+
+		switch {
+		case order.Status == OrderStatusFilled || (order.Status == OrderStatusCanceled && order.ExecutedQty > 0):
+			at.removePendingEntry(&entry)
+			at.onEntryFilled(&entry, order)
+			record.Decisions = append(record.Decisions, entry.action("", order))
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 挂单成交", entry.Symbol, OrderTypeLabel(entry.OrderType)))
+		case order.Status == OrderStatusCanceled:
+			at.removePendingEntry(&entry)
+			action := entry.action("cancel_", nil)
+			action.Error = "挂单已被交易所撤销"
+			record.Decisions = append(record.Decisions, action)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠️ %s %s 已被交易所撤销", entry.Symbol, OrderTypeLabel(entry.OrderType)))
+		case time.Since(entry.PlacedAt) >= at.config.EntryOrderTimeout:
+			at.expirePendingEntry(&entry, record)
+		}
+	}
+}
+
+// expireLabel 挂单超时后的处理方式（日志用）
+func (at *AutoTrader) expireLabel(entry *pendingEntry) string {
+	if entry.Reprices < at.config.EntryOrderReprices {
+		return "按盘口重新定价"
+	}
+	return "撤单"
+}
+
+// expirePendingEntry 撤销超时挂单；还有重新定价次数时按最新盘口重新挂单
+func (at *AutoTrader) expirePendingEntry(entry *pendingEntry, record *logger.DecisionRecord) {
+	log.Printf("⏰ [%s] %s %s 挂单超时（限价 %.4f，已挂 %.0f 分钟），%s",
+		at.name, entry.Symbol, OrderTypeLabel(entry.OrderType), entry.Price, time.Since(entry.PlacedAt).Minutes(), at.expireLabel(entry))
+
+	order, err := at.cancelEntryOrder(entry)
+	if err != nil {
+		log.Printf("⚠️ [%s] 撤销挂单 %s 失败: %v", at.name, entry.OrderID, err)
+		return
+	}
+	at.removePendingEntry(entry)
+
+	// 撤单前已部分成交：按已成交数量处理，不再重新挂单
+	if order != nil && order.ExecutedQty > 0 {
+		at.onEntryFilled(entry, order)
+		record.Decisions = append(record.Decisions, entry.action("", order))
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s 挂单部分成交 %.8f，剩余已撤销", entry.Symbol, order.ExecutedQty))
+		return
+	}
+
+	if entry.Reprices < at.config.EntryOrderReprices {
+		err := at.repriceEntry(entry, record)
+		if err == nil {
+			return
+		}
+		log.Printf("⚠️ [%s] %s 重新定价失败: %v", at.name, entry.Symbol, err)
+	}
+
+	action := entry.action("cancel_", nil)
+	action.Error = "挂单超时未成交"
+	record.Decisions = append(record.Decisions, action)
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏰ %s %s 超时未成交，已撤单", entry.Symbol, OrderTypeLabel(entry.OrderType)))
+}
+
+// cancelEntryOrder 撤销挂单并返回撤单后的订单状态（用于识别撤单前的部分成交）
+func (at *AutoTrader) cancelEntryOrder(entry *pendingEntry) (*OrderResult, error) {
+	if err := at.trader.CancelOrder(entry.Symbol, entry.OrderID); err != nil {
+		// 撤单失败可能是刚好成交，以订单状态为准
+		order, queryErr := at.trader.GetOrder(entry.Symbol, entry.OrderID)
+		if queryErr != nil || order.IsOpen() {
+			return nil, err
+		}
+		if order.Status == OrderStatusFilled {
+			return order, nil
+		}
+	}
+	order, err := at.trader.GetOrder(entry.Symbol, entry.OrderID)
+	if err != nil {
+		log.Printf("⚠️ [%s] 查询已撤销挂单 %s 失败: %v", at.name, entry.OrderID, err)
+		return nil, nil
+	}
+	return order, nil
+}
+
+// repriceEntry 按最新盘口同侧最优价（做多取买一，做空取卖一）重新挂单
+// 新价格越过止损或止盈时放弃
+func (at *AutoTrader) repriceEntry(entry *pendingEntry, record *logger.DecisionRecord) error {
+	price, err := at.bestQuote(entry.Symbol, entry.Side)
+	if err != nil {
+		return err
+	}
+	isLong := entry.Side == "long"
+	if entry.StopLoss > 0 && ((isLong && price <= entry.StopLoss) || (!isLong && price >= entry.StopLoss)) {
+		return fmt.Errorf("最新价格 %.4f 已越过止损 %.4f", price, entry.StopLoss)
+	}
+	if entry.TakeProfit > 0 && ((isLong && price >= entry.TakeProfit) || (!isLong && price <= entry.TakeProfit)) {
+		return fmt.Errorf("最新价格 %.4f 已越过止盈 %.4f", price, entry.TakeProfit)
+	}
+
+	order, err := at.trader.PlaceEntryOrder(EntryOrder{
+		Symbol:    entry.Symbol,
+		Side:      entry.Side,
+		OrderType: entry.OrderType,
+		Quantity:  entry.Quantity,
+		Price:     price,
+		Leverage:  entry.Leverage,
+	})
+	if err != nil {
+		return err
+	}
+
+	oldPrice := entry.Price
+	entry.OrderID = order.OrderID
+	entry.Price = price
+	entry.PlacedAt = time.Now()
+	entry.Reprices++
+
+	switch {
+	case order.Status == OrderStatusFilled || (order.Status == OrderStatusCanceled && order.ExecutedQty > 0):
+		at.onEntryFilled(entry, order)
+		record.Decisions = append(record.Decisions, entry.action("", order))
+	case order.IsOpen():
+		at.storePendingEntry(entry)
+		record.Decisions = append(record.Decisions, entry.action("pending_", order))
+	default:
+		return fmt.Errorf("重新定价的%s未成交，已被撤销", OrderTypeLabel(entry.OrderType))
+	}
+	log.Printf("🔁 [%s] %s %s 重新定价: %.4f → %.4f（第%d次），订单ID: %s",
+		at.name, entry.Symbol, OrderTypeLabel(entry.OrderType), oldPrice, price, entry.Reprices, order.OrderID)
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔁 %s 挂单重新定价 %.4f → %.4f", entry.Symbol, oldPrice, price))
+	return nil
+}
+
+// bestQuote 盘口同侧最优价（获取盘口失败时使用最新价）
+func (at *AutoTrader) bestQuote(symbol, side string) (float64, error) {
+	if book, err := market.GetOrderBook(symbol, at.marketDataExchange(), 5); err == nil && len(book.Bids) > 0 && len(book.Asks) > 0 {
+		if side == "long" {
+			return book.Bids[0].Price, nil
+		}
+		return book.Asks[0].Price, nil
+	}
+	return at.trader.GetMarketPrice(symbol)
+}
+
+// storePendingEntry 记录挂单并写入交易台账
+func (at *AutoTrader) storePendingEntry(entry *pendingEntry) {
+	at.pendingMutex.Lock()
+	at.pendingEntries[entry.key()] = entry
+	at.pendingMutex.Unlock()
+
+	if at.ledger == nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("⚠️ [%s] 序列化挂单 %s 失败: %v", at.name, entry.OrderID, err)
+		return
+	}
+	if err := at.ledger.SavePendingEntry(at.id, entry.Symbol, entry.Side, entry.OrderID, string(data)); err != nil {
+		log.Printf("⚠️ [%s] 挂单 %s 写入交易台账失败: %v", at.name, entry.OrderID, err)
+	}
+}
+
+// removePendingEntry 移除挂单记录（订单ID不一致时说明已被重新挂单，不移除）
+func (at *AutoTrader) removePendingEntry(entry *pendingEntry) {
+	at.pendingMutex.Lock()
+	if current, ok := at.pendingEntries[entry.key()]; ok && current.OrderID == entry.OrderID {
+		delete(at.pendingEntries, entry.key())
+	}
+	at.pendingMutex.Unlock()
+
+	if at.ledger != nil {
+		if err := at.ledger.DeletePendingEntry(at.id, entry.Symbol, entry.Side, entry.OrderID); err != nil {
+			log.Printf("⚠️ [%s] 从交易台账删除挂单 %s 失败: %v", at.name, entry.OrderID, err)
+		}
+	}
+}
+
+// restorePendingEntries 启动时从交易台账恢复上次运行未处理完的挂单
+// 恢复后由第一个周期的 processPendingEntries 查询订单状态：重启期间已成交的补挂止盈止损，已撤销的移除
+func (at *AutoTrader) restorePendingEntries() {
+	if at.ledger == nil {
+		return
+	}
+	entries, err := at.ledger.GetPendingEntries(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 恢复开仓挂单失败: %v", at.name, err)
+		return
+	}
+
+	at.pendingMutex.Lock()
+	defer at.pendingMutex.Unlock()
+	for _, data := range entries {
+		var entry pendingEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil || entry.OrderID == "" {
+			log.Printf("⚠️ [%s] 解析开仓挂单失败: %v", at.name, err)
+			continue
+		}
+		at.pendingEntries[entry.key()] = &entry
+		log.Printf("📥 [%s] 恢复开仓挂单: %s %s 限价 %.4f（订单ID: %s）",
+			at.name, entry.Symbol, OrderTypeLabel(entry.OrderType), entry.Price, entry.OrderID)
+	}
+}
+
+// cancelPendingEntries 撤销所有未成交的开仓挂单（风控暂停或交易员停止时调用，record 可为nil）
+func (at *AutoTrader) cancelPendingEntries(record *logger.DecisionRecord, reason string) {
+	for _, snapshot := range at.snapshotPendingEntries() {
+		entry := snapshot
+		order, err := at.cancelEntryOrder(&entry)
+		if err != nil {
+			log.Printf("⚠️ [%s] %s，撤销挂单 %s %s 失败: %v", at.name, reason, entry.Symbol, entry.OrderID, err)
+			continue
+		}
+		at.removePendingEntry(&entry)
+		log.Printf("🗑 [%s] %s，已撤销 %s %s（订单ID: %s）", at.name, reason, entry.Symbol, OrderTypeLabel(entry.OrderType), entry.OrderID)
+
+		if order != nil && order.ExecutedQty > 0 {
+			at.onEntryFilled(&entry, order)
+			if record != nil {
+				record.Decisions = append(record.Decisions, entry.action("", order))
+			}
+			continue
+		}
+		if record != nil {
+			action := entry.action("cancel_", nil)
+			action.Error = reason
+			record.Decisions = append(record.Decisions, action)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🗑 %s，撤销 %s 挂单", reason, entry.Symbol))
+		}
+	}
+}
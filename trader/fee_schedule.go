@@ -0,0 +1,37 @@
+package trader
+
+// FeeSchedule 合约手续费率（小数，如 0.0005 表示 0.05%）
+type FeeSchedule struct {
+	Maker float64 // 挂单手续费率
+	Taker float64 // 吃单手续费率（市价单、止损触发后的市价平仓）
+}
+
+// defaultFeeSchedule 未知交易所使用的保守费率
+var defaultFeeSchedule = FeeSchedule{Maker: 0.0002, Taker: 0.0006}
+
+// exchangeFeeSchedules 各交易所USDT永续合约的默认费率（普通用户最低档，VIP或返佣账户实际费率更低，保本价会略偏保守）
+var exchangeFeeSchedules = map[string]FeeSchedule{
+	"binance":     {Maker: 0.0002, Taker: 0.0005},
+	"okx":         {Maker: OKXMakerFeeRate, Taker: OKXTakerFeeRate},
+	"bybit":       {Maker: 0.0002, Taker: 0.00055},
+	"bitget":      {Maker: 0.0002, Taker: 0.0006},
+	"gate":        {Maker: 0.0002, Taker: 0.0005},
+	"hyperliquid": {Maker: 0.00015, Taker: 0.00045},
+	"aster":       {Maker: 0.0001, Taker: 0.00035},
+}
+
+// ExchangeFeeSchedule 获取交易所的默认费率（未知交易所返回保守费率）
+func ExchangeFeeSchedule(exchange string) FeeSchedule {
+	if schedule, ok := exchangeFeeSchedules[exchange]; ok {
+		return schedule
+	}
+	return defaultFeeSchedule
+}
+
+// feeSchedule 当前交易员使用的费率：交易器自带费率时（模拟盘）优先使用，否则按交易所默认费率
+func (at *AutoTrader) feeSchedule() FeeSchedule {
+	if provider, ok := at.trader.(FeeScheduleProvider); ok {
+		return provider.FeeSchedule()
+	}
+	return ExchangeFeeSchedule(at.exchange)
+}
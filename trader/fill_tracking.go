@@ -0,0 +1,111 @@
+package trader
+
+import (
+	"log"
+	"nofx/logger"
+	"sort"
+	"time"
+)
+
+// fillLookback 按订单ID匹配成交明细时回溯的时间范围
+const fillLookback = 10 * time.Minute
+
+// resolveFill 用交易所成交明细补全订单的成交均价、成交数量和手续费
+// 市价单的下单响应通常不含手续费，部分交易所也不含成交均价；查不到成交明细时退回查询订单
+func (at *AutoTrader) resolveFill(symbol string, order *OrderResult) {
+	if order == nil || order.OrderID == "" || (order.AvgPrice > 0 && order.Fee > 0) {
+		return
+	}
+
+	fills, err := at.trader.GetUserTrades(symbol, time.Now().Add(-fillLookback))
+	if err != nil {
+		log.Printf("⚠️ [%s] 查询 %s 成交明细失败: %v", at.name, symbol, err)
+	}
+	for _, fill := range fills {
+		if fill.OrderID == order.OrderID {
+			order.AvgPrice = fill.Price
+			order.ExecutedQty = fill.Quantity
+			order.Fee = fill.Fee
+			return
+		}
+	}
+
+	if order.AvgPrice > 0 {
+		return
+	}
+	latest, err := at.trader.GetOrder(symbol, order.OrderID)
+	if err != nil {
+		log.Printf("⚠️ [%s] 查询 %s 订单 %s 失败: %v", at.name, symbol, order.OrderID, err)
+		return
+	}
+	if latest.AvgPrice > 0 {
+		order.AvgPrice = latest.AvgPrice
+	}
+	if latest.ExecutedQty > 0 {
+		order.ExecutedQty = latest.ExecutedQty
+	}
+	if order.Fee == 0 {
+		order.Fee = latest.Fee
+	}
+}
+
+// recordFill 将实际成交写入决策动作记录（expectedPrice 为下单时的参考价，用于计算滑点）
+func recordFill(action *logger.DecisionAction, order *OrderResult, side string, expectedPrice float64) {
+	action.OrderID = order.NumericOrderID()
+	action.Fee = order.Fee
+	action.ExpectedPrice = expectedPrice
+	if order.ExecutedQty > 0 {
+		action.Quantity = order.ExecutedQty
+	}
+	if order.AvgPrice <= 0 {
+		action.Price = expectedPrice
+		return
+	}
+	action.Price = order.AvgPrice
+	action.SlippagePct = slippagePct(side, expectedPrice, order.AvgPrice)
+}
+
+// slippagePct 成交滑点百分比（side: "buy" / "sell"），正数表示成交价比参考价更差
+func slippagePct(side string, expectedPrice, avgPrice float64) float64 {
+	if expectedPrice <= 0 || avgPrice <= 0 {
+		return 0
+	}
+	if side == "sell" {
+		return (expectedPrice - avgPrice) / expectedPrice * 100
+	}
+	return (avgPrice - expectedPrice) / expectedPrice * 100
+}
+
+// orderSide 按持仓方向和开平仓推断买卖方向
+func orderSide(positionSide string, isClose bool) string {
+	if (positionSide == "long") != isClose {
+		return "buy"
+	}
+	return "sell"
+}
+
+// mergeFills 合并同一订单的多笔成交（数量加权均价，累加数量、手续费和已实现盈亏），按时间升序返回
+func mergeFills(fills []Fill) []Fill {
+	sort.SliceStable(fills, func(i, j int) bool { return fills[i].Time.Before(fills[j].Time) })
+	merged := make([]Fill, 0, len(fills))
+	byOrder := make(map[string]int)
+	for _, fill := range fills {
+		idx, ok := byOrder[fill.OrderID]
+		if !ok {
+			byOrder[fill.OrderID] = len(merged)
+			merged = append(merged, fill)
+			continue
+		}
+		f := &merged[idx]
+		if qty := f.Quantity + fill.Quantity; qty > 0 {
+			f.Price = (f.Price*f.Quantity + fill.Price*fill.Quantity) / qty
+		}
+		f.Quantity += fill.Quantity
+		f.Fee += fill.Fee
+		f.RealizedPnL += fill.RealizedPnL
+		if f.Reason == "" {
+			f.Reason = fill.Reason
+		}
+	}
+	return merged
+}
@@ -0,0 +1,1011 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"nofx/config"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Gate.io USDT本位永续合约（V4接口）
+const (
+	gateMainnetURL = "https://api.gateio.ws"
+	gateTestnetURL = "https://fx-api-testnet.gateio.ws"
+	gateAPIPrefix  = "/api/v4"
+	gateOrderText  = "t-nofx" // 自定义订单标记（必须以 t- 开头）
+
+	gateLabelRequestExpired = "REQUEST_EXPIRED" // 请求时间戳与服务器时间相差过大
+)
+
+// isGateTimestampRejected 响应是否为时间戳被拒绝（需要重新同步服务器时间）
+func isGateTimestampRejected(body []byte) bool {
+	var resp struct {
+		Label string `json:"label"`
+	}
+	return json.Unmarshal(body, &resp) == nil && resp.Label == gateLabelRequestExpired
+}
+
+// GateTrader Gate.io USDT永续合约交易器（双仓模式，下单单位为张）
+type GateTrader struct {
+	rest *restClient
+
+	// 余额缓存
+	cachedBalance     *Balance
+	balanceCacheTime  time.Time
+	balanceCacheMutex sync.RWMutex
+
+	// 持仓缓存
+	cachedPositions     []Position
+	positionsCacheTime  time.Time
+	positionsCacheMutex sync.RWMutex
+
+	// 缓存有效期（15秒）
+	cacheDuration time.Duration
+
+	// 合约规则缓存（contracts，包含每张合约面值）
+	instruments *instrumentRegistry
+
+	// 交易对是否全仓（Gate通过杠杆设置切换：leverage=0 表示全仓）
+	crossMargin      map[string]bool
+	crossMarginMutex sync.RWMutex
+}
+
+// gateAPIError Gate.io接口返回的业务错误
+type gateAPIError struct {
+	Label   string
+	Message string
+}
+
+func (e *gateAPIError) Error() string {
+	return fmt.Sprintf("Gate API错误: label=%s, message=%s", e.Label, e.Message)
+}
+
+// NewGateTrader 创建Gate.io合约交易器
+func NewGateTrader(apiKey, secretKey string, testnet bool) *GateTrader {
+	baseURL := gateMainnetURL
+	if testnet {
+		baseURL = gateTestnetURL
+	}
+	trader := &GateTrader{
+		rest:          newRESTClient("Gate", baseURL+gateAPIPrefix, newGateSigner(apiKey, secretKey)),
+		cacheDuration: 15 * time.Second,
+		crossMargin:   make(map[string]bool),
+	}
+	trader.instruments = sharedInstrumentRegistry(instrumentKey("gate", testnet), "Gate", trader.loadInstruments)
+	trader.rest.timestampRejected = isGateTimestampRejected
+	trader.rest.clock.sync("Gate", trader.fetchServerTime)
+
+	// 开启双仓模式（代码按 dual_long / dual_short 区分多空）
+	if err := trader.setDualMode(); err != nil {
+		log.Printf("⚠️ 设置Gate双仓模式失败: %v (如果已是双仓模式则忽略此警告)", err)
+	}
+
+	log.Printf("✓ Gate交易器初始化成功 (testnet=%v)", testnet)
+	return trader
+}
+
+// request 发送签名请求（业务错误以4xx返回 {label, message}）
+func (t *GateTrader) request(method, path string, query url.Values, body interface{}) ([]byte, error) {
+	respBody, err := t.rest.do(method, path, query, body)
+	var httpErr *restHTTPError
+	if errors.As(err, &httpErr) {
+		var gateErr struct {
+			Label   string `json:"label"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(httpErr.Body, &gateErr) == nil && gateErr.Label != "" {
+			return nil, &gateAPIError{Label: gateErr.Label, Message: gateErr.Message}
+		}
+	}
+	return respBody, err
+}
+
+// gateContract 转换交易对格式 (BTCUSDT -> BTC_USDT)
+func gateContract(symbol string) string {
+	return strings.TrimSuffix(symbol, "USDT") + "_USDT"
+}
+
+// gateSymbol 转换交易对格式 (BTC_USDT -> BTCUSDT)
+func gateSymbol(contract string) string {
+	return strings.ReplaceAll(contract, "_", "")
+}
+
+// gateTime 转换Gate秒级浮点时间戳
+func gateTime(ts float64) time.Time {
+	sec, frac := math.Modf(ts)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// fetchServerTime 获取Gate服务器时间
+func (t *GateTrader) fetchServerTime() (time.Time, error) {
+	body, err := t.rest.getPublic("/spot/time", nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var resp struct {
+		ServerTime int64 `json:"server_time"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return time.Time{}, fmt.Errorf("解析服务器时间失败: %w", err)
+	}
+	return time.UnixMilli(resp.ServerTime), nil
+}
+
+// setDualMode 开启双仓模式（有持仓或挂单时交易所会拒绝）
+func (t *GateTrader) setDualMode() error {
+	if _, err := t.request(http.MethodPost, "/futures/usdt/dual_mode", url.Values{"dual_mode": {"true"}}, nil); err != nil {
+		return err
+	}
+	log.Printf("  ✓ Gate账户已开启双仓模式（Hedge Mode）")
+	return nil
+}
+
+// loadInstruments 加载全部USDT永续合约规则（下单数量步进为1张）
+func (t *GateTrader) loadInstruments() (map[string]Instrument, error) {
+	body, err := t.rest.getPublic("/futures/usdt/contracts", nil)
+	if err != nil {
+		return nil, err
+	}
+	var contracts []struct {
+		Name             string `json:"name"`
+		QuantoMultiplier string `json:"quanto_multiplier"`
+		OrderPriceRound  string `json:"order_price_round"`
+		OrderSizeMin     int64  `json:"order_size_min"`
+		LeverageMax      string `json:"leverage_max"`
+	}
+	if err := json.Unmarshal(body, &contracts); err != nil {
+		return nil, fmt.Errorf("解析合约规则失败: %w", err)
+	}
+
+	specs := make(map[string]Instrument, len(contracts))
+	for _, c := range contracts {
+		spec := Instrument{Symbol: gateSymbol(c.Name)}
+		spec.ContractSize, _ = strconv.ParseFloat(c.QuantoMultiplier, 64)
+		if spec.ContractSize <= 0 {
+			spec.ContractSize = 1
+		}
+		spec.TickSize, _ = strconv.ParseFloat(c.OrderPriceRound, 64)
+		spec.QtyStep = spec.ContractSize
+		spec.MinQty = float64(c.OrderSizeMin) * spec.ContractSize
+		spec.MaxLeverage = parseLeverage(c.LeverageMax)
+		specs[spec.Symbol] = spec
+	}
+	return specs, nil
+}
+
+// toContracts 基础币数量转换为张数（向下取整）
+func (t *GateTrader) toContracts(symbol string, quantity float64) (int64, error) {
+	spec, err := t.instruments.get(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return int64(math.Floor(quantity/spec.ContractSize + 1e-9)), nil
+}
+
+// fromContracts 张数转换为基础币数量
+func (t *GateTrader) fromContracts(symbol string, contracts int64) float64 {
+	spec, err := t.instruments.get(symbol)
+	if err != nil {
+		return float64(contracts)
+	}
+	return float64(contracts) * spec.ContractSize
+}
+
+// GetBalance 获取USDT合约账户余额（带缓存）
+func (t *GateTrader) GetBalance() (*Balance, error) {
+	t.balanceCacheMutex.RLock()
+	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
+		cacheAge := time.Since(t.balanceCacheTime)
+		t.balanceCacheMutex.RUnlock()
+		log.Printf("✓ 使用缓存的账户余额（缓存时间: %.1f秒前）", cacheAge.Seconds())
+		return t.cachedBalance, nil
+	}
+	t.balanceCacheMutex.RUnlock()
+
+	log.Printf("🔄 缓存过期，正在调用Gate API获取账户余额...")
+	body, err := t.request(http.MethodGet, "/futures/usdt/accounts", nil, nil)
+	if err != nil {
+		log.Printf("❌ Gate API调用失败: %v", err)
+		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+	}
+	var account struct {
+		Total         string `json:"total"` // 钱包余额（不含未实现盈亏）
+		UnrealisedPnl string `json:"unrealised_pnl"`
+		Available     string `json:"available"`
+	}
+	if err := json.Unmarshal(body, &account); err != nil {
+		return nil, fmt.Errorf("解析余额数据失败: %w", err)
+	}
+
+	balance := &Balance{}
+	balance.TotalWalletBalance, _ = strconv.ParseFloat(account.Total, 64)
+	balance.TotalUnrealizedProfit, _ = strconv.ParseFloat(account.UnrealisedPnl, 64)
+	balance.AvailableBalance, _ = strconv.ParseFloat(account.Available, 64)
+	balance.TotalEquity = balance.TotalWalletBalance + balance.TotalUnrealizedProfit
+
+	log.Printf("✓ Gate API返回: 总余额=%.2f, 可用=%.2f, 未实现盈亏=%.2f",
+		balance.TotalWalletBalance, balance.AvailableBalance, balance.TotalUnrealizedProfit)
+
+	t.balanceCacheMutex.Lock()
+	t.cachedBalance = balance
+	t.balanceCacheTime = time.Now()
+	t.balanceCacheMutex.Unlock()
+
+	return balance, nil
+}
+
+// GetPositions 获取所有USDT永续持仓（带缓存）
+func (t *GateTrader) GetPositions() ([]Position, error) {
+	t.positionsCacheMutex.RLock()
+	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
+		cacheAge := time.Since(t.positionsCacheTime)
+		t.positionsCacheMutex.RUnlock()
+		log.Printf("✓ 使用缓存的持仓信息（缓存时间: %.1f秒前）", cacheAge.Seconds())
+		return t.cachedPositions, nil
+	}
+	t.positionsCacheMutex.RUnlock()
+
+	log.Printf("🔄 缓存过期，正在调用Gate API获取持仓信息...")
+	body, err := t.request(http.MethodGet, "/futures/usdt/positions", url.Values{"holding": {"true"}}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	var positions []struct {
+		Contract           string `json:"contract"`
+		Size               int64  `json:"size"` // 张数，单仓模式下负数为空仓
+		EntryPrice         string `json:"entry_price"`
+		MarkPrice          string `json:"mark_price"`
+		UnrealisedPnl      string `json:"unrealised_pnl"`
+		Leverage           string `json:"leverage"` // 0 表示全仓
+		CrossLeverageLimit string `json:"cross_leverage_limit"`
+		LiqPrice           string `json:"liq_price"`
+		Margin             string `json:"margin"`
+		Mode               string `json:"mode"` // single / dual_long / dual_short
+	}
+	if err := json.Unmarshal(body, &positions); err != nil {
+		return nil, fmt.Errorf("解析持仓数据失败: %w", err)
+	}
+
+	result := []Position{}
+	for _, pos := range positions {
+		if pos.Size == 0 {
+			continue
+		}
+		symbol := gateSymbol(pos.Contract)
+		size := pos.Size
+		side := "long"
+		switch {
+		case pos.Mode == "dual_short":
+			side = "short"
+		case pos.Mode != "dual_long" && size < 0:
+			side = "short"
+		}
+		if size < 0 {
+			size = -size
+		}
+
+		p := Position{Symbol: symbol, Side: side, Quantity: t.fromContracts(symbol, size)}
+		p.EntryPrice, _ = strconv.ParseFloat(pos.EntryPrice, 64)
+		p.MarkPrice, _ = strconv.ParseFloat(pos.MarkPrice, 64)
+		p.UnrealizedProfit, _ = strconv.ParseFloat(pos.UnrealisedPnl, 64)
+		p.LiquidationPrice, _ = strconv.ParseFloat(pos.LiqPrice, 64)
+		p.Margin, _ = strconv.ParseFloat(pos.Margin, 64)
+		leverage, _ := strconv.ParseFloat(pos.Leverage, 64)
+		if leverage == 0 {
+			leverage, _ = strconv.ParseFloat(pos.CrossLeverageLimit, 64)
+		}
+		p.Leverage = int(leverage)
+		result = append(result, p)
+	}
+
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = result
+	t.positionsCacheTime = time.Now()
+	t.positionsCacheMutex.Unlock()
+
+	return result, nil
+}
+
+// clearCache 下单后清除余额和持仓缓存
+func (t *GateTrader) clearCache() {
+	t.balanceCacheMutex.Lock()
+	t.cachedBalance = nil
+	t.balanceCacheMutex.Unlock()
+
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = nil
+	t.positionsCacheMutex.Unlock()
+}
+
+// SetMarginMode 设置保证金模式（Gate的全仓/逐仓由杠杆设置决定，在下一次 SetLeverage 时生效）
+func (t *GateTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	t.crossMarginMutex.Lock()
+	t.crossMargin[symbol] = isCrossMargin
+	t.crossMarginMutex.Unlock()
+
+	mode := "逐仓"
+	if isCrossMargin {
+		mode = "全仓"
+	}
+	log.Printf("  ✓ %s 保证金模式: %s（随杠杆设置生效）", symbol, mode)
+	return nil
+}
+
+// SetLeverage 设置杠杆（全仓时 leverage=0，杠杆上限通过 cross_leverage_limit 设置）
+func (t *GateTrader) SetLeverage(symbol string, leverage int) error {
+	if inst, err := t.instruments.get(symbol); err == nil {
+		leverage = inst.ClampLeverage(leverage)
+	}
+
+	t.crossMarginMutex.RLock()
+	isCross, ok := t.crossMargin[symbol]
+	t.crossMarginMutex.RUnlock()
+
+	query := url.Values{"leverage": {strconv.Itoa(leverage)}}
+	if !ok || isCross {
+		query.Set("leverage", "0")
+		query.Set("cross_leverage_limit", strconv.Itoa(leverage))
+	}
+	if _, err := t.request(http.MethodPost, "/futures/usdt/dual_comp/positions/"+gateContract(symbol)+"/leverage", query, nil); err != nil {
+		return fmt.Errorf("设置杠杆失败: %w", err)
+	}
+	log.Printf("  ✓ %s 杠杆已设置为 %dx", symbol, leverage)
+	return nil
+}
+
+// GetInstrument 获取合约交易规则
+func (t *GateTrader) GetInstrument(symbol string) (*Instrument, error) {
+	return t.instruments.get(symbol)
+}
+
+// FormatQuantity 格式化数量为整张对应的基础币数量
+func (t *GateTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	spec, err := t.instruments.get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatQuantity(quantity), nil
+}
+
+// formatPrice 格式化价格到合约价格步进
+func (t *GateTrader) formatPrice(symbol string, price float64) (string, error) {
+	spec, err := t.instruments.get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatPrice(price), nil
+}
+
+// CheckMinNotional 检查下单数量是否满足最小张数
+func (t *GateTrader) CheckMinNotional(symbol string, quantity float64) error {
+	spec, err := t.instruments.get(symbol)
+	if err != nil {
+		return err
+	}
+	return spec.CheckMin(quantity, 0)
+}
+
+// GetMarketPrice 获取最新成交价
+func (t *GateTrader) GetMarketPrice(symbol string) (float64, error) {
+	body, err := t.rest.getPublic("/futures/usdt/tickers", url.Values{"contract": {gateContract(symbol)}})
+	if err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+	var tickers []struct {
+		Last string `json:"last"`
+	}
+	if err := json.Unmarshal(body, &tickers); err != nil {
+		return 0, fmt.Errorf("解析价格失败: %w", err)
+	}
+	if len(tickers) == 0 {
+		return 0, fmt.Errorf("未找到 %s 的价格", symbol)
+	}
+	price, err := strconv.ParseFloat(tickers[0].Last, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析价格失败: %w", err)
+	}
+	return price, nil
+}
+
+// gateOrder 订单信息
+type gateOrder struct {
+	ID           int64   `json:"id"`
+	Contract     string  `json:"contract"`
+	Size         int64   `json:"size"` // 正数买入，负数卖出
+	Left         int64   `json:"left"` // 未成交张数
+	Price        string  `json:"price"`
+	FillPrice    string  `json:"fill_price"`
+	Status       string  `json:"status"`    // open / finished
+	FinishAs     string  `json:"finish_as"` // filled / cancelled / ioc / ...
+	IsReduceOnly bool    `json:"is_reduce_only"`
+	CreateTime   float64 `json:"create_time"`
+}
+
+// toOrderResult 转换为统一订单结果
+func (t *GateTrader) toOrderResult(o gateOrder) *OrderResult {
+	symbol := gateSymbol(o.Contract)
+	size, left := o.Size, o.Left
+	if size < 0 {
+		size, left = -size, -left
+	}
+	filled := size - left
+
+	result := &OrderResult{
+		OrderID:     strconv.FormatInt(o.ID, 10),
+		Symbol:      symbol,
+		Quantity:    t.fromContracts(symbol, size),
+		ExecutedQty: t.fromContracts(symbol, filled),
+	}
+	result.Price, _ = strconv.ParseFloat(o.Price, 64)
+	if filled > 0 {
+		result.AvgPrice, _ = strconv.ParseFloat(o.FillPrice, 64)
+	}
+	switch {
+	case o.Status == "open" && filled > 0:
+		result.Status = OrderStatusPartiallyFilled
+	case o.Status == "open":
+		result.Status = OrderStatusOpen
+	case left == 0:
+		result.Status = OrderStatusFilled
+	default:
+		result.Status = OrderStatusCanceled
+	}
+	return result
+}
+
+// placeOrder 下单（size 为张数，正数买入、负数卖出）
+func (t *GateTrader) placeOrder(params map[string]interface{}) (*OrderResult, error) {
+	params["text"] = gateOrderText
+	body, err := t.request(http.MethodPost, "/futures/usdt/orders", nil, params)
+	if err != nil {
+		return nil, err
+	}
+	var order gateOrder
+	if err := json.Unmarshal(body, &order); err != nil {
+		return nil, fmt.Errorf("解析下单响应失败: %w", err)
+	}
+	t.clearCache()
+	return t.toOrderResult(order), nil
+}
+
+// OpenLong 开多仓（Gate不支持下单时附带止盈止损，成交后单独设置）
+func (t *GateTrader) OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
+	return t.openPosition(symbol, "long", quantity, leverage, stopLoss, takeProfit)
+}
+
+// OpenShort 开空仓（Gate不支持下单时附带止盈止损，成交后单独设置）
+func (t *GateTrader) OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
+	return t.openPosition(symbol, "short", quantity, leverage, stopLoss, takeProfit)
+}
+
+// openPosition 市价开仓（IOC价格为0）
+func (t *GateTrader) openPosition(symbol, side string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
+	// 先取消该币种的所有委托单（清理旧的挂单）
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	contracts, err := t.toContracts(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if contracts <= 0 {
+		return nil, fmt.Errorf("开仓数量过小，不足1张 (原始: %.8f)", quantity)
+	}
+	if err := t.CheckMinNotional(symbol, t.fromContracts(symbol, contracts)); err != nil {
+		return nil, err
+	}
+
+	size := contracts
+	if side == "short" {
+		size = -size
+	}
+	result, err := t.placeOrder(map[string]interface{}{
+		"contract": gateContract(symbol),
+		"size":     size,
+		"price":    "0",
+		"tif":      "ioc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("开%s仓失败: %w", sideLabel(side), err)
+	}
+	log.Printf("✓ 开%s仓成功: %s 数量: %.8f (%d张) 订单ID: %s", sideLabel(side), symbol, result.ExecutedQty, contracts, result.OrderID)
+
+	if stopLoss > 0 {
+		if err := t.SetStopLoss(symbol, side, 0, stopLoss); err != nil {
+			log.Printf("  ⚠ 设置止损失败: %v", err)
+		}
+	}
+	if takeProfit > 0 {
+		if err := t.SetTakeProfit(symbol, side, 0, takeProfit); err != nil {
+			log.Printf("  ⚠ 设置止盈失败: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// CloseLong 平多仓
+func (t *GateTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
+	return t.closePosition(symbol, "long", quantity)
+}
+
+// CloseShort 平空仓
+func (t *GateTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
+	return t.closePosition(symbol, "short", quantity)
+}
+
+// closePosition 市价平仓（quantity=0表示全部平仓，全部平仓后取消该方向的止盈止损）
+func (t *GateTrader) closePosition(symbol, side string, quantity float64) (*OrderResult, error) {
+	closeAll := quantity == 0
+	if closeAll {
+		positions, err := t.GetPositions()
+		if err != nil {
+			return nil, err
+		}
+		if pos, ok := FindPosition(positions, symbol, side); ok {
+			quantity = pos.Quantity
+		}
+		if quantity == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的%s仓", symbol, sideLabel(side))
+		}
+	}
+
+	contracts, err := t.toContracts(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if contracts <= 0 {
+		return nil, fmt.Errorf("平仓数量过小，不足1张 (原始: %.8f)", quantity)
+	}
+	size := -contracts
+	if side == "short" {
+		size = contracts
+	}
+	result, err := t.placeOrder(map[string]interface{}{
+		"contract":    gateContract(symbol),
+		"size":        size,
+		"price":       "0",
+		"tif":         "ioc",
+		"reduce_only": true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("平%s仓失败: %w", sideLabel(side), err)
+	}
+	log.Printf("✓ 平%s仓成功: %s 数量: %.8f (%d张)", sideLabel(side), symbol, result.ExecutedQty, contracts)
+
+	if closeAll {
+		if err := t.cancelPriceOrders(symbol, side, ""); err != nil {
+			log.Printf("  ⚠ 取消止盈止损单失败: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// PlaceEntryOrder 按订单类型开仓（post_only 对应 poc，会立即成交时交易所直接撤单）
+func (t *GateTrader) PlaceEntryOrder(order EntryOrder) (*OrderResult, error) {
+	if err := order.validate(); err != nil {
+		return nil, err
+	}
+	if order.OrderType == OrderTypeMarket {
+		return order.openMarket(t)
+	}
+
+	if err := t.SetLeverage(order.Symbol, order.Leverage); err != nil {
+		return nil, err
+	}
+	contracts, err := t.toContracts(order.Symbol, order.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	if contracts <= 0 {
+		return nil, fmt.Errorf("开仓数量过小，不足1张 (原始: %.8f)", order.Quantity)
+	}
+	if err := t.CheckMinNotional(order.Symbol, t.fromContracts(order.Symbol, contracts)); err != nil {
+		return nil, err
+	}
+	priceStr, err := t.formatPrice(order.Symbol, order.Price)
+	if err != nil {
+		return nil, err
+	}
+
+	tif := "gtc"
+	switch order.OrderType {
+	case OrderTypePostOnly:
+		tif = "poc"
+	case OrderTypeIOC:
+		tif = "ioc"
+	}
+	size := contracts
+	if order.Side == "short" {
+		size = -size
+	}
+
+	result, err := t.placeOrder(map[string]interface{}{
+		"contract": gateContract(order.Symbol),
+		"size":     size,
+		"price":    priceStr,
+		"tif":      tif,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s开仓失败: %w", OrderTypeLabel(order.OrderType), err)
+	}
+	log.Printf("✓ %s已提交: %s %s 价格: %s 数量: %d张 状态: %s", OrderTypeLabel(order.OrderType), order.Symbol, order.Side, priceStr, contracts, result.Status)
+	return result, nil
+}
+
+// GetOrder 查询订单
+func (t *GateTrader) GetOrder(symbol, orderID string) (*OrderResult, error) {
+	body, err := t.request(http.MethodGet, "/futures/usdt/orders/"+orderID, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	var order gateOrder
+	if err := json.Unmarshal(body, &order); err != nil {
+		return nil, fmt.Errorf("解析订单数据失败: %w", err)
+	}
+	return t.toOrderResult(order), nil
+}
+
+// CancelOrder 撤销指定订单
+func (t *GateTrader) CancelOrder(symbol, orderID string) error {
+	if _, err := t.request(http.MethodDelete, "/futures/usdt/orders/"+orderID, nil, nil); err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+	log.Printf("  ✓ 已撤销 %s 订单 %s", symbol, orderID)
+	return nil
+}
+
+// gatePriceOrder 价格触发单
+type gatePriceOrder struct {
+	ID      int64 `json:"id"`
+	Initial struct {
+		Contract string `json:"contract"`
+		Size     int64  `json:"size"`
+		Price    string `json:"price"`
+		AutoSize string `json:"auto_size"` // close_long / close_short 表示平掉整个持仓
+	} `json:"initial"`
+	Trigger struct {
+		Price string `json:"price"`
+		Rule  int    `json:"rule"` // 1: 价格>=触发价，2: 价格<=触发价
+	} `json:"trigger"`
+	CreateTime float64 `json:"create_time"`
+}
+
+// positionSide 触发单对应的持仓方向
+func (o gatePriceOrder) positionSide() string {
+	switch {
+	case o.Initial.AutoSize == "close_short":
+		return "short"
+	case o.Initial.AutoSize == "close_long":
+		return "long"
+	case o.Initial.Size > 0: // 买入平空
+		return "short"
+	default:
+		return "long"
+	}
+}
+
+// orderType 按触发方向判断止损/止盈（多仓跌破触发为止损，空仓涨破触发为止损）
+func (o gatePriceOrder) orderType() string {
+	if (o.positionSide() == "long") == (o.Trigger.Rule == 2) {
+		return OpenOrderTypeStopLoss
+	}
+	return OpenOrderTypeTakeProfit
+}
+
+// getPriceOrders 查询未触发的价格触发单
+func (t *GateTrader) getPriceOrders(symbol string) ([]gatePriceOrder, error) {
+	query := url.Values{"status": {"open"}}
+	if symbol != "" {
+		query.Set("contract", gateContract(symbol))
+	}
+	body, err := t.request(http.MethodGet, "/futures/usdt/price_orders", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取触发单失败: %w", err)
+	}
+	var orders []gatePriceOrder
+	if err := json.Unmarshal(body, &orders); err != nil {
+		return nil, fmt.Errorf("解析触发单失败: %w", err)
+	}
+	return orders, nil
+}
+
+// GetOpenOrders 查询未完成的挂单（包含止盈止损触发单）
+func (t *GateTrader) GetOpenOrders(symbol string) ([]OpenOrder, error) {
+	query := url.Values{"status": {"open"}}
+	if symbol != "" {
+		query.Set("contract", gateContract(symbol))
+	}
+	body, err := t.request(http.MethodGet, "/futures/usdt/orders", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取未完成订单失败: %w", err)
+	}
+	var orders []gateOrder
+	if err := json.Unmarshal(body, &orders); err != nil {
+		return nil, fmt.Errorf("解析未完成订单失败: %w", err)
+	}
+
+	var result []OpenOrder
+	for _, o := range orders {
+		sym := gateSymbol(o.Contract)
+		side := "buy"
+		size, left := o.Size, o.Left
+		if size < 0 {
+			side, size, left = "sell", -size, -left
+		}
+		open := OpenOrder{
+			OrderID:      strconv.FormatInt(o.ID, 10),
+			Symbol:       sym,
+			Side:         side,
+			PositionSide: inferPositionSide(side, o.IsReduceOnly),
+			Type:         OpenOrderTypeLimit,
+			Quantity:     t.fromContracts(sym, size),
+			ExecutedQty:  t.fromContracts(sym, size-left),
+			ReduceOnly:   o.IsReduceOnly,
+			Time:         gateTime(o.CreateTime),
+		}
+		open.Price, _ = strconv.ParseFloat(o.Price, 64)
+		result = append(result, open)
+	}
+
+	priceOrders, err := t.getPriceOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range priceOrders {
+		sym := gateSymbol(o.Initial.Contract)
+		positionSide := o.positionSide()
+		size := o.Initial.Size
+		if size < 0 {
+			size = -size
+		}
+		open := OpenOrder{
+			OrderID:      strconv.FormatInt(o.ID, 10),
+			Symbol:       sym,
+			Side:         orderSide(positionSide, true),
+			PositionSide: positionSide,
+			Type:         o.orderType(),
+			Quantity:     t.fromContracts(sym, size), // 整仓触发单为0
+			ReduceOnly:   true,
+			IsAlgo:       true,
+			Time:         gateTime(o.CreateTime),
+		}
+		open.Price, _ = strconv.ParseFloat(o.Initial.Price, 64)
+		open.StopPrice, _ = strconv.ParseFloat(o.Trigger.Price, 64)
+		result = append(result, open)
+	}
+	return result, nil
+}
+
+// GetUserTrades 获取成交明细（同一订单的多笔成交合并为一条，按时间升序）
+func (t *GateTrader) GetUserTrades(symbol string, startTime time.Time) ([]Fill, error) {
+	const pageSize = 1000
+	var fills []Fill
+	for start := startTime; start.Before(time.Now()); start = start.Add(binanceHistoryWindow) {
+		for offset := 0; ; offset += pageSize {
+			query := url.Values{
+				"contract": {gateContract(symbol)},
+				"from":     {strconv.FormatInt(start.Unix(), 10)},
+				"to":       {strconv.FormatInt(start.Add(binanceHistoryWindow).Unix(), 10)},
+				"limit":    {strconv.Itoa(pageSize)},
+				"offset":   {strconv.Itoa(offset)},
+			}
+			body, err := t.request(http.MethodGet, "/futures/usdt/my_trades_timerange", query, nil)
+			if err != nil {
+				return nil, fmt.Errorf("获取成交历史失败: %w", err)
+			}
+			var trades []struct {
+				OrderID    string  `json:"order_id"`
+				Contract   string  `json:"contract"`
+				Size       int64   `json:"size"`       // 正数买入，负数卖出
+				CloseSize  int64   `json:"close_size"` // 平仓张数，0表示开仓
+				Price      string  `json:"price"`
+				Fee        string  `json:"fee"`
+				Text       string  `json:"text"`
+				CreateTime float64 `json:"create_time"`
+			}
+			if err := json.Unmarshal(body, &trades); err != nil {
+				return nil, fmt.Errorf("解析成交历史失败: %w", err)
+			}
+
+			for _, tr := range trades {
+				isClose := tr.CloseSize != 0
+				size := tr.Size
+				// 平仓：买入平空、卖出平多；开仓：买入开多、卖出开空
+				side := "long"
+				if (size > 0) == isClose {
+					side = "short"
+				}
+				if size < 0 {
+					size = -size
+				}
+				fill := Fill{
+					OrderID:  tr.OrderID,
+					Symbol:   symbol,
+					Side:     side,
+					IsClose:  isClose,
+					Quantity: t.fromContracts(symbol, size),
+					Time:     gateTime(tr.CreateTime),
+				}
+				if strings.Contains(tr.Text, "liquidation") {
+					fill.Reason = config.TradeExitLiquidation
+				}
+				fill.Price, _ = strconv.ParseFloat(tr.Price, 64)
+				fill.Fee, _ = strconv.ParseFloat(tr.Fee, 64)
+				fills = append(fills, fill)
+			}
+			if len(trades) < pageSize {
+				break
+			}
+		}
+	}
+	return mergeFills(fills), nil
+}
+
+// GetFundingFees 获取累计资金费（账户流水中的 fund 记录）
+func (t *GateTrader) GetFundingFees(symbol string, startTime time.Time) (float64, error) {
+	total := 0.0
+	for start := startTime; start.Before(time.Now()); start = start.Add(binanceHistoryWindow) {
+		query := url.Values{
+			"contract": {gateContract(symbol)},
+			"type":     {"fund"},
+			"from":     {strconv.FormatInt(start.Unix(), 10)},
+			"to":       {strconv.FormatInt(start.Add(binanceHistoryWindow).Unix(), 10)},
+			"limit":    {"1000"},
+		}
+		body, err := t.request(http.MethodGet, "/futures/usdt/account_book", query, nil)
+		if err != nil {
+			return 0, fmt.Errorf("获取资金费流水失败: %w", err)
+		}
+		var records []struct {
+			Change string `json:"change"` // 正数为收入
+		}
+		if err := json.Unmarshal(body, &records); err != nil {
+			return 0, fmt.Errorf("解析资金费流水失败: %w", err)
+		}
+		for _, r := range records {
+			change, _ := strconv.ParseFloat(r.Change, 64)
+			total += change
+		}
+	}
+	return total, nil
+}
+
+// SetStopLoss 设置止损（quantity>0 时为部分止损，否则止损整个持仓）
+func (t *GateTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	side := strings.ToLower(positionSide)
+	rule := 2 // 多仓：价格<=止损价触发
+	if side == "short" {
+		rule = 1
+	}
+	return t.placePriceOrder(symbol, side, quantity, stopPrice, rule, "止损")
+}
+
+// SetTakeProfit 设置止盈（quantity>0 时为部分止盈，否则止盈整个持仓）
+func (t *GateTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	side := strings.ToLower(positionSide)
+	rule := 1 // 多仓：价格>=止盈价触发
+	if side == "short" {
+		rule = 2
+	}
+	return t.placePriceOrder(symbol, side, quantity, takeProfitPrice, rule, "止盈")
+}
+
+// placePriceOrder 下平仓触发单（按标记价格触发，市价IOC成交）
+func (t *GateTrader) placePriceOrder(symbol, side string, quantity, price float64, rule int, label string) error {
+	if side != "long" && side != "short" {
+		return fmt.Errorf("无效的持仓方向: %s", side)
+	}
+	priceStr, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return err
+	}
+
+	initial := map[string]interface{}{
+		"contract":    gateContract(symbol),
+		"price":       "0",
+		"tif":         "ioc",
+		"reduce_only": true,
+		"text":        gateOrderText,
+	}
+	orderType := "close-" + side + "-position"
+	if quantity > 0 {
+		contracts, err := t.toContracts(symbol, quantity)
+		if err != nil {
+			return err
+		}
+		if contracts <= 0 {
+			return fmt.Errorf("%s数量过小，不足1张 (原始: %.8f)", label, quantity)
+		}
+		if side == "long" {
+			contracts = -contracts
+		}
+		initial["size"] = contracts
+		orderType = "plan-close-" + side + "-position"
+	} else {
+		initial["size"] = 0
+		initial["auto_size"] = "close_" + side
+	}
+
+	if _, err := t.request(http.MethodPost, "/futures/usdt/price_orders", nil, map[string]interface{}{
+		"initial": initial,
+		"trigger": map[string]interface{}{
+			"strategy_type": 0,
+			"price_type":    1, // 标记价格
+			"price":         priceStr,
+			"rule":          rule,
+		},
+		"order_type": orderType,
+	}); err != nil {
+		return fmt.Errorf("设置%s失败: %w", label, err)
+	}
+	log.Printf("  %s价设置: %s", label, priceStr)
+	return nil
+}
+
+// CancelStopLossOrders 仅取消止损单（不影响止盈单）
+func (t *GateTrader) CancelStopLossOrders(symbol string) error {
+	return t.cancelPriceOrders(symbol, "", OpenOrderTypeStopLoss)
+}
+
+// CancelTakeProfitOrders 仅取消止盈单（不影响止损单）
+func (t *GateTrader) CancelTakeProfitOrders(symbol string) error {
+	return t.cancelPriceOrders(symbol, "", OpenOrderTypeTakeProfit)
+}
+
+// cancelPriceOrders 取消触发单（positionSide / orderType 为空表示不过滤）
+func (t *GateTrader) cancelPriceOrders(symbol, positionSide, orderType string) error {
+	orders, err := t.getPriceOrders(symbol)
+	if err != nil {
+		return err
+	}
+
+	canceledCount := 0
+	for _, o := range orders {
+		if positionSide != "" && o.positionSide() != positionSide {
+			continue
+		}
+		if orderType != "" && o.orderType() != orderType {
+			continue
+		}
+		if _, err := t.request(http.MethodDelete, "/futures/usdt/price_orders/"+strconv.FormatInt(o.ID, 10), nil, nil); err != nil {
+			log.Printf("  ⚠ 取消触发单 %d 失败: %v", o.ID, err)
+			continue
+		}
+		canceledCount++
+	}
+
+	if canceledCount > 0 {
+		log.Printf("  ✓ 已取消 %s 的 %d 个止盈止损单", symbol, canceledCount)
+	}
+	return nil
+}
+
+// CancelAllOrders 取消该币种的所有普通挂单
+func (t *GateTrader) CancelAllOrders(symbol string) error {
+	if _, err := t.request(http.MethodDelete, "/futures/usdt/orders", url.Values{"contract": {gateContract(symbol)}}, nil); err != nil {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// CancelStopOrders 取消该币种的所有止盈止损触发单
+func (t *GateTrader) CancelStopOrders(symbol string) error {
+	if _, err := t.request(http.MethodDelete, "/futures/usdt/price_orders", url.Values{"contract": {gateContract(symbol)}}, nil); err != nil {
+		return fmt.Errorf("取消止盈止损单失败: %w", err)
+	}
+	log.Printf("  ✓ 已取消 %s 的止盈止损单", symbol)
+	return nil
+}
@@ -0,0 +1,125 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Hyperliquid 推送地址
+const (
+	hyperliquidWSURL        = "wss://api.hyperliquid.xyz/ws"
+	hyperliquidWSTestnetURL = "wss://api.hyperliquid-testnet.xyz/ws"
+	hyperliquidPingInterval = 30 * time.Second // 60秒无消息服务端断开连接
+	hyperliquidMidsInterval = 3 * time.Second  // 中间价推送频率很高，按此间隔更新持仓盈亏
+)
+
+// hyperliquidWSMessage Hyperliquid推送消息
+type hyperliquidWSMessage struct {
+	Channel string          `json:"channel"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// StreamAccount 订阅Hyperliquid userEvents（成交、强平）和 allMids（中间价）
+// userEvents 只推送成交明细不含持仓，收到成交后要求调用方用REST全量同步
+func (t *HyperliquidTrader) StreamAccount(stop <-chan struct{}, onUpdate func(AccountUpdate)) error {
+	url := hyperliquidWSURL
+	if t.testnet {
+		url = hyperliquidWSTestnetURL
+	}
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		return fmt.Errorf("连接Hyperliquid推送失败: %w", err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	subscriptions := []map[string]string{
+		{"type": "userEvents", "user": t.walletAddr},
+		{"type": "allMids"},
+	}
+	for _, sub := range subscriptions {
+		if err := conn.WriteJSON(map[string]interface{}{"method": "subscribe", "subscription": sub}); err != nil {
+			return fmt.Errorf("发送Hyperliquid订阅请求失败: %w", err)
+		}
+	}
+
+	// 心跳（订阅完成后只有心跳goroutine写入）
+	go func() {
+		ticker := time.NewTicker(hyperliquidPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := conn.WriteJSON(map[string]string{"method": "ping"}); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	onUpdate(AccountUpdate{Resync: true, UnrealizedExcluded: true})
+
+	var lastMids time.Time
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+				return fmt.Errorf("Hyperliquid推送读取失败: %w", err)
+			}
+		}
+
+		var msg hyperliquidWSMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			continue
+		}
+		switch msg.Channel {
+		case "user":
+			var events struct {
+				Fills       []json.RawMessage `json:"fills"`
+				Liquidation json.RawMessage   `json:"liquidation"`
+			}
+			if err := json.Unmarshal(msg.Data, &events); err != nil {
+				return fmt.Errorf("解析Hyperliquid userEvents失败: %w", err)
+			}
+			if len(events.Fills) > 0 || len(events.Liquidation) > 0 {
+				onUpdate(AccountUpdate{Resync: true, UnrealizedExcluded: true})
+			}
+
+		case "allMids":
+			if time.Since(lastMids) < hyperliquidMidsInterval {
+				continue
+			}
+			lastMids = time.Now()
+			var mids struct {
+				Mids map[string]string `json:"mids"`
+			}
+			if err := json.Unmarshal(msg.Data, &mids); err != nil {
+				return fmt.Errorf("解析Hyperliquid allMids失败: %w", err)
+			}
+			prices := make(map[string]float64, len(mids.Mids))
+			for coin, px := range mids.Mids {
+				if price, err := strconv.ParseFloat(px, 64); err == nil && price > 0 {
+					prices[coin+"USDT"] = price
+				}
+			}
+			onUpdate(AccountUpdate{MarkPrices: prices, UnrealizedExcluded: true})
+		}
+	}
+}
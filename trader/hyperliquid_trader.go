@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sonirico/go-hyperliquid"
@@ -18,8 +20,9 @@ type HyperliquidTrader struct {
 	exchange      *hyperliquid.Exchange
 	ctx           context.Context
 	walletAddr    string
-	meta          *hyperliquid.Meta // 缓存meta信息（包含精度等）
+	instruments   *instrumentRegistry // 合约规则（来自meta：szDecimals、最大杠杆）
 	isCrossMargin bool              // 是否为全仓模式
+	testnet       bool
 }
 
 // NewHyperliquidTrader 创建Hyperliquid交易器
@@ -67,23 +70,25 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 
 	log.Printf("✓ Hyperliquid交易器初始化成功 (testnet=%v, wallet=%s)", testnet, walletAddr)
 
-	// 获取meta信息（包含精度等配置）
-	meta, err := exchange.Info().Meta(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("获取meta信息失败: %w", err)
-	}
-
-	return &HyperliquidTrader{
+	trader := &HyperliquidTrader{
 		exchange:      exchange,
 		ctx:           ctx,
 		walletAddr:    walletAddr,
-		meta:          meta,
 		isCrossMargin: true, // 默认使用全仓模式
-	}, nil
+		testnet:       testnet,
+	}
+
+	// 获取meta信息（包含精度等配置）
+	trader.instruments = sharedInstrumentRegistry(instrumentKey("hyperliquid", testnet), "Hyperliquid", trader.loadInstruments)
+	if err := trader.instruments.refresh(); err != nil {
+		return nil, fmt.Errorf("获取meta信息失败: %w", err)
+	}
+
+	return trader, nil
 }
 
 // GetBalance 获取账户余额
-func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
+func (t *HyperliquidTrader) GetBalance() (*Balance, error) {
 	log.Printf("🔄 正在调用Hyperliquid API获取账户余额...")
 
 	// ✅ Step 1: 查询 Spot 现货账户余额
@@ -109,8 +114,6 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 	}
 
 	// 解析余额信息（MarginSummary字段都是string）
-	result := make(map[string]interface{})
-
 	// ✅ Step 3: 根据保证金模式动态选择正确的摘要（CrossMarginSummary 或 MarginSummary）
 	var accountValue, totalMarginUsed float64
 	var summaryType string
@@ -175,10 +178,11 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 	//      原因：Spot 和 Perpetuals 是獨立帳戶，需手動 ClassTransfer 才能轉帳
 	totalWalletBalance := walletBalanceWithoutUnrealized + spotUSDCBalance
 
-	result["totalWalletBalance"] = totalWalletBalance      // 總資產（Perp + Spot）
-	result["availableBalance"] = availableBalance          // 可用餘額（僅 Perpetuals，不含 Spot）
-	result["totalUnrealizedProfit"] = totalUnrealizedPnl   // 未實現盈虧（僅來自 Perpetuals）
-	result["spotBalance"] = spotUSDCBalance                // Spot 現貨餘額（單獨返回）
+	result := &Balance{
+		TotalWalletBalance:    totalWalletBalance, // 總資產（Perp + Spot）
+		AvailableBalance:      availableBalance,   // 可用餘額（僅 Perpetuals，不含 Spot）
+		TotalUnrealizedProfit: totalUnrealizedPnl, // 未實現盈虧（僅來自 Perpetuals）
+	}
 
 	log.Printf("✓ Hyperliquid 完整账户:")
 	log.Printf("  • Spot 现货余额: %.2f USDC （需手动转账到 Perpetuals 才能开仓）", spotUSDCBalance)
@@ -196,14 +200,14 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 }
 
 // GetPositions 获取所有持仓
-func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *HyperliquidTrader) GetPositions() ([]Position, error) {
 	// 获取账户状态
 	accountState, err := t.exchange.Info().UserState(t.ctx, t.walletAddr)
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
-	var result []map[string]interface{}
+	var result []Position
 
 	// 遍历所有持仓
 	for _, assetPos := range accountState.AssetPositions {
@@ -216,19 +220,16 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 			continue // 跳过无持仓的
 		}
 
-		posMap := make(map[string]interface{})
-
 		// 标准化symbol格式（Hyperliquid使用如"BTC"，我们转换为"BTCUSDT"）
-		symbol := position.Coin + "USDT"
-		posMap["symbol"] = symbol
+		p := Position{Symbol: position.Coin + "USDT"}
 
 		// 持仓数量和方向
 		if posAmt > 0 {
-			posMap["side"] = "long"
-			posMap["positionAmt"] = posAmt
+			p.Side = "long"
+			p.Quantity = posAmt
 		} else {
-			posMap["side"] = "short"
-			posMap["positionAmt"] = -posAmt // 转为正数
+			p.Side = "short"
+			p.Quantity = -posAmt // 转为正数
 		}
 
 		// 价格信息（EntryPx和LiquidationPx是指针类型）
@@ -249,13 +250,14 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 			markPrice = positionValue / absFloat(posAmt)
 		}
 
-		posMap["entryPrice"] = entryPrice
-		posMap["markPrice"] = markPrice
-		posMap["unRealizedProfit"] = unrealizedPnl
-		posMap["leverage"] = float64(position.Leverage.Value)
-		posMap["liquidationPrice"] = liquidationPx
+		p.EntryPrice = entryPrice
+		p.MarkPrice = markPrice
+		p.UnrealizedProfit = unrealizedPnl
+		p.Leverage = position.Leverage.Value
+		p.LiquidationPrice = liquidationPx
+		p.Margin, _ = strconv.ParseFloat(position.MarginUsed, 64)
 
-		result = append(result, posMap)
+		result = append(result, p)
 	}
 
 	return result, nil
@@ -275,6 +277,8 @@ func (t *HyperliquidTrader) SetMarginMode(symbol string, isCrossMargin bool) err
 
 // SetLeverage 设置杠杆
 func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
+	leverage = t.instrument(symbol).ClampLeverage(leverage)
+
 	// Hyperliquid symbol格式（去掉USDT后缀）
 	coin := convertSymbolToHyperliquid(symbol)
 
@@ -290,7 +294,7 @@ func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
 }
 
 // OpenLong 开多仓（V1.57版本：添加stopLoss和takeProfit参数以兼容接口，Hyperliquid暂不支持下单时设置止盈止损）
-func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
@@ -310,12 +314,12 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 		return nil, err
 	}
 
-	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
+	// ⚠️ 关键：根据币种精度要求，处理数量和价格（价格最多5位有效数字）
+	inst := t.instrument(symbol)
+	roundedQuantity := inst.RoundQuantity(quantity)
+	log.Printf("  📏 数量精度处理: %.8f -> %.8f (步进=%g)", quantity, roundedQuantity, inst.QtyStep)
 
-	// ⚠️ 关键：价格也需要处理为5位有效数字
-	aggressivePrice := t.roundPriceToSigfigs(price * 1.01)
+	aggressivePrice := inst.RoundPrice(price * 1.01)
 	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*1.01, aggressivePrice)
 
 	// 创建市价买入订单（使用IOC limit order with aggressive price）
@@ -332,23 +336,21 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 		ReduceOnly: false,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
 		return nil, fmt.Errorf("开多仓失败: %w", err)
 	}
+	if status.Error != nil {
+		return nil, fmt.Errorf("开多仓失败: %s", *status.Error)
+	}
 
 	log.Printf("✓ 开多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0 // Hyperliquid没有返回order ID
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
-	return result, nil
+	return marketOrderResult(symbol, roundedQuantity, status), nil
 }
 
 // OpenShort 开空仓（V1.57版本：添加stopLoss和takeProfit参数以兼容接口，Hyperliquid暂不支持下单时设置止盈止损）
-func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
@@ -368,12 +370,12 @@ func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage
 		return nil, err
 	}
 
-	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
+	// ⚠️ 关键：根据币种精度要求，处理数量和价格（价格最多5位有效数字）
+	inst := t.instrument(symbol)
+	roundedQuantity := inst.RoundQuantity(quantity)
+	log.Printf("  📏 数量精度处理: %.8f -> %.8f (步进=%g)", quantity, roundedQuantity, inst.QtyStep)
 
-	// ⚠️ 关键：价格也需要处理为5位有效数字
-	aggressivePrice := t.roundPriceToSigfigs(price * 0.99)
+	aggressivePrice := inst.RoundPrice(price * 0.99)
 	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*0.99, aggressivePrice)
 
 	// 创建市价卖出订单
@@ -390,23 +392,21 @@ func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage
 		ReduceOnly: false,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
 		return nil, fmt.Errorf("开空仓失败: %w", err)
 	}
+	if status.Error != nil {
+		return nil, fmt.Errorf("开空仓失败: %s", *status.Error)
+	}
 
 	log.Printf("✓ 开空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
-	return result, nil
+	return marketOrderResult(symbol, roundedQuantity, status), nil
 }
 
 // CloseLong 平多仓
-func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -414,11 +414,8 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, "long"); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
@@ -435,12 +432,12 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		return nil, err
 	}
 
-	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
+	// ⚠️ 关键：根据币种精度要求，处理数量和价格（价格最多5位有效数字）
+	inst := t.instrument(symbol)
+	roundedQuantity := inst.RoundQuantity(quantity)
+	log.Printf("  📏 数量精度处理: %.8f -> %.8f (步进=%g)", quantity, roundedQuantity, inst.QtyStep)
 
-	// ⚠️ 关键：价格也需要处理为5位有效数字
-	aggressivePrice := t.roundPriceToSigfigs(price * 0.99)
+	aggressivePrice := inst.RoundPrice(price * 0.99)
 	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*0.99, aggressivePrice)
 
 	// 创建平仓订单（卖出 + ReduceOnly）
@@ -457,10 +454,13 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		ReduceOnly: true, // 只平仓，不开新仓
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
 		return nil, fmt.Errorf("平多仓失败: %w", err)
 	}
+	if status.Error != nil {
+		return nil, fmt.Errorf("平多仓失败: %s", *status.Error)
+	}
 
 	log.Printf("✓ 平多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
@@ -469,16 +469,11 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
-	return result, nil
+	return marketOrderResult(symbol, roundedQuantity, status), nil
 }
 
 // CloseShort 平空仓
-func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -486,11 +481,8 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				quantity = pos["positionAmt"].(float64)
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, "short"); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
@@ -507,12 +499,12 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		return nil, err
 	}
 
-	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
+	// ⚠️ 关键：根据币种精度要求，处理数量和价格（价格最多5位有效数字）
+	inst := t.instrument(symbol)
+	roundedQuantity := inst.RoundQuantity(quantity)
+	log.Printf("  📏 数量精度处理: %.8f -> %.8f (步进=%g)", quantity, roundedQuantity, inst.QtyStep)
 
-	// ⚠️ 关键：价格也需要处理为5位有效数字
-	aggressivePrice := t.roundPriceToSigfigs(price * 1.01)
+	aggressivePrice := inst.RoundPrice(price * 1.01)
 	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*1.01, aggressivePrice)
 
 	// 创建平仓订单（买入 + ReduceOnly）
@@ -529,10 +521,13 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		ReduceOnly: true,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
 		return nil, fmt.Errorf("平空仓失败: %w", err)
 	}
+	if status.Error != nil {
+		return nil, fmt.Errorf("平空仓失败: %s", *status.Error)
+	}
 
 	log.Printf("✓ 平空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
@@ -541,31 +536,305 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
+	return marketOrderResult(symbol, roundedQuantity, status), nil
+}
+
+// CancelStopOrders 取消该币种的止盈/止
+
+
+// PlaceEntryOrder 按订单类型开仓（tif: Gtc/Alo/Ioc，Alo 会立即成交时被交易所拒绝）
+func (t *HyperliquidTrader) PlaceEntryOrder(order EntryOrder) (*OrderResult, error) {
+	if err := order.validate(); err != nil {
+		return nil, err
+	}
+	if order.OrderType == OrderTypeMarket {
+		return order.openMarket(t)
+	}
+
+	if err := t.SetLeverage(order.Symbol, order.Leverage); err != nil {
+		return nil, err
+	}
+
+	coin := convertSymbolToHyperliquid(order.Symbol)
+	inst := t.instrument(order.Symbol)
+	roundedQuantity := inst.RoundQuantity(order.Quantity)
+	if roundedQuantity <= 0 {
+		return nil, fmt.Errorf("开仓数量过小，按精度处理后为 0 (原始: %.8f)", order.Quantity)
+	}
+	limitPrice := inst.RoundPrice(order.Price)
+
+	tif := hyperliquid.TifGtc
+	switch order.OrderType {
+	case OrderTypePostOnly:
+		tif = hyperliquid.TifAlo
+	case OrderTypeIOC:
+		tif = hyperliquid.TifIoc
+	}
+
+	status, err := t.exchange.Order(t.ctx, hyperliquid.CreateOrderRequest{
+		Coin:  coin,
+		IsBuy: order.Side == "long",
+		Size:  roundedQuantity,
+		Price: limitPrice,
+		OrderType: hyperliquid.OrderType{
+			Limit: &hyperliquid.LimitOrderType{Tif: tif},
+		},
+		ReduceOnly: false,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s开仓失败: %w", OrderTypeLabel(order.OrderType), err)
+	}
+	if status.Error != nil {
+		return nil, fmt.Errorf("%s开仓失败: %s", OrderTypeLabel(order.OrderType), *status.Error)
+	}
+
+	result := &OrderResult{
+		Symbol:   order.Symbol,
+		Price:    limitPrice,
+		Quantity: roundedQuantity,
+	}
+	switch {
+	case status.Filled != nil:
+		result.OrderID = strconv.Itoa(status.Filled.Oid)
+		result.AvgPrice, _ = strconv.ParseFloat(status.Filled.AvgPx, 64)
+		result.ExecutedQty, _ = strconv.ParseFloat(status.Filled.TotalSz, 64)
+		result.Status = OrderStatusFilled
+		if result.ExecutedQty < roundedQuantity {
+			// IOC 部分成交后剩余部分已撤销
+			result.Status = OrderStatusCanceled
+		}
+	case status.Resting != nil:
+		result.OrderID = strconv.FormatInt(status.Resting.Oid, 10)
+		result.Status = OrderStatusOpen
+	default:
+		result.Status = OrderStatusCanceled
+	}
 
+	log.Printf("✓ %s已提交: %s %s 价格: %.8f 数量: %.8f 状态: %s", OrderTypeLabel(order.OrderType), order.Symbol, order.Side, limitPrice, roundedQuantity, result.Status)
 	return result, nil
 }
 
-// CancelStopOrders 取消该币种的止盈/止
+// GetOrder 查询订单（Hyperliquid 不返回成交均价，已成交部分按委托价估算）
+func (t *HyperliquidTrader) GetOrder(symbol, orderID string) (*OrderResult, error) {
+	oid, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+	resp, err := t.exchange.Info().QueryOrderByOid(t.ctx, t.walletAddr, oid)
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	if resp.Status != hyperliquid.OrderQueryStatusSuccess {
+		return nil, fmt.Errorf("订单不存在: %s", orderID)
+	}
+
+	order := resp.Order.Order
+	result := &OrderResult{
+		OrderID: orderID,
+		Symbol:  symbol,
+	}
+	result.Price, _ = strconv.ParseFloat(order.LimitPx, 64)
+	result.Quantity, _ = strconv.ParseFloat(order.OrigSz, 64)
+	remaining, _ := strconv.ParseFloat(order.Sz, 64)
+	if result.Quantity > remaining {
+		result.ExecutedQty = result.Quantity - remaining
+		result.AvgPrice = result.Price
+	}
+
+	switch resp.Order.Status {
+	case hyperliquid.OrderStatusValueOpen:
+		result.Status = OrderStatusOpen
+		if result.ExecutedQty > 0 {
+			result.Status = OrderStatusPartiallyFilled
+		}
+	case hyperliquid.OrderStatusValueFilled:
+		result.Status = OrderStatusFilled
+		result.ExecutedQty = result.Quantity
+		result.AvgPrice = result.Price
+	default:
+		result.Status = OrderStatusCanceled
+	}
+	return result, nil
+}
+
+// CancelOrder 撤销指定订单
+func (t *HyperliquidTrader) CancelOrder(symbol, orderID string) error {
+	oid, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+	if _, err := t.exchange.Cancel(t.ctx, convertSymbolToHyperliquid(symbol), oid); err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+	log.Printf("  ✓ 已撤销 %s 订单 %s", symbol, orderID)
+	return nil
+}
+
+// marketOrderResult 将IOC市价单的下单结果转换为 OrderResult（包含订单ID和成交均价）
+func marketOrderResult(symbol string, quantity float64, status hyperliquid.OrderStatus) *OrderResult {
+	result := &OrderResult{
+		Symbol:   symbol,
+		Quantity: quantity,
+		Status:   OrderStatusFilled,
+	}
+	if status.Filled != nil {
+		result.OrderID = strconv.Itoa(status.Filled.Oid)
+		result.AvgPrice, _ = strconv.ParseFloat(status.Filled.AvgPx, 64)
+		result.ExecutedQty, _ = strconv.ParseFloat(status.Filled.TotalSz, 64)
+	}
+	return result
+}
+
+// GetOpenOrders 查询未完成的挂单（包含止盈止损触发单）
+func (t *HyperliquidTrader) GetOpenOrders(symbol string) ([]OpenOrder, error) {
+	coin := ""
+	if symbol != "" {
+		coin = convertSymbolToHyperliquid(symbol)
+	}
+
+	openOrders, err := t.exchange.Info().FrontendOpenOrders(t.ctx, t.walletAddr)
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	var orders []OpenOrder
+	for _, order := range openOrders {
+		if coin != "" && order.Coin != coin {
+			continue
+		}
+		open := OpenOrder{
+			OrderID:     strconv.FormatInt(order.Oid, 10),
+			Symbol:      order.Coin + "USDT",
+			Side:        "sell",
+			Type:        OpenOrderTypeLimit,
+			Price:       order.LimitPx,
+			Quantity:    order.OrigSz,
+			ExecutedQty: order.OrigSz - order.Sz,
+			ReduceOnly:  order.ReduceOnly || order.IsPositionTpSl,
+			IsAlgo:      order.IsTrigger,
+			Time:        time.UnixMilli(order.Timestamp),
+		}
+		if order.Side == hyperliquid.OrderSideBid {
+			open.Side = "buy"
+		}
+		if order.IsTrigger {
+			open.StopPrice = order.TriggerPx
+			switch {
+			case strings.HasPrefix(order.OrderType, "Stop"):
+				open.Type = OpenOrderTypeStopLoss
+			case strings.HasPrefix(order.OrderType, "Take Profit"):
+				open.Type = OpenOrderTypeTakeProfit
+			default:
+				open.Type = OpenOrderTypeTrigger
+			}
+		}
+		if open.ExecutedQty < 0 {
+			open.ExecutedQty = 0
+		}
+		open.PositionSide = inferPositionSide(open.Side, open.ReduceOnly)
+		orders = append(orders, open)
+	}
+	return orders, nil
+}
 
+// GetUserTrades 获取成交明细（同一订单的多笔成交合并为一条，按时间升序）
+func (t *HyperliquidTrader) GetUserTrades(symbol string, startTime time.Time) ([]Fill, error) {
+	coin := convertSymbolToHyperliquid(symbol)
+	trades, err := t.exchange.Info().UserFillsByTime(t.ctx, t.walletAddr, startTime.UnixMilli(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取成交历史失败: %w", err)
+	}
 
-// CancelStopLossOrders 仅取消止损单（Hyperliquid 暂无法区分止损和止盈，取消所有）
+	var fills []Fill
+	for _, trade := range trades {
+		if trade.Coin != coin {
+			continue
+		}
+		price, _ := strconv.ParseFloat(trade.Price, 64)
+		qty, _ := strconv.ParseFloat(trade.Size, 64)
+		fee, _ := strconv.ParseFloat(trade.Fee, 64)
+		pnl, _ := strconv.ParseFloat(trade.ClosedPnl, 64)
+
+		// Dir 形如 "Open Long" / "Close Short"
+		side := "long"
+		if strings.Contains(trade.Dir, "Short") {
+			side = "short"
+		}
+		isClose := strings.HasPrefix(trade.Dir, "Close")
+		// 反手成交（如 "Long > Short"）按平掉原方向记录
+		if strings.Contains(trade.Dir, ">") {
+			isClose = true
+			if strings.HasPrefix(trade.Dir, "Long") {
+				side = "long"
+			}
+		}
+		fills = append(fills, Fill{
+			OrderID:     strconv.FormatInt(trade.Oid, 10),
+			Symbol:      symbol,
+			Side:        side,
+			IsClose:     isClose,
+			Price:       price,
+			Quantity:    qty,
+			Fee:         fee,
+			RealizedPnL: pnl,
+			Time:        time.UnixMilli(trade.Time),
+		})
+	}
+	return mergeFills(fills), nil
+}
+
+// CancelStopLossOrders 仅取消止损单（通过 frontendOpenOrders 的订单类型区分，保留止盈单）
 func (t *HyperliquidTrader) CancelStopLossOrders(symbol string) error {
-	// Hyperliquid SDK 的 OpenOrder 结构不暴露 trigger 字段
-	// 无法区分止损和止盈单，因此取消该币种的所有挂单
-	log.Printf("  ⚠️ Hyperliquid 无法区分止损/止盈单，将取消所有挂单")
-	return t.CancelStopOrders(symbol)
+	orders, err := t.GetOpenOrders(symbol)
+	if err != nil {
+		return err
+	}
+
+	canceledCount := 0
+	for _, order := range orders {
+		if order.Type != OpenOrderTypeStopLoss {
+			continue
+		}
+		if err := t.CancelOrder(symbol, order.OrderID); err != nil {
+			log.Printf("  ⚠ 取消止损单失败: %v", err)
+			continue
+		}
+		canceledCount++
+	}
+
+	if canceledCount == 0 {
+		log.Printf("  ℹ %s 没有止损单需要取消", symbol)
+	} else {
+		log.Printf("  ✓ 已取消 %s 的 %d 个止损单", symbol, canceledCount)
+	}
+	return nil
 }
 
-// CancelTakeProfitOrders 仅取消止盈单（Hyperliquid 暂无法区分止损和止盈，取消所有）
+// CancelTakeProfitOrders 仅取消止盈单（通过 frontendOpenOrders 的订单类型区分，分批止盈各档一并取消）
 func (t *HyperliquidTrader) CancelTakeProfitOrders(symbol string) error {
-	// Hyperliquid SDK 的 OpenOrder 结构不暴露 trigger 字段
-	// 无法区分止损和止盈单，因此取消该币种的所有挂单
-	log.Printf("  ⚠️ Hyperliquid 无法区分止损/止盈单，将取消所有挂单")
-	return t.CancelStopOrders(symbol)
+	orders, err := t.GetOpenOrders(symbol)
+	if err != nil {
+		return err
+	}
+
+	canceledCount := 0
+	for _, order := range orders {
+		if order.Type != OpenOrderTypeTakeProfit {
+			continue
+		}
+		if err := t.CancelOrder(symbol, order.OrderID); err != nil {
+			log.Printf("  ⚠ 取消止盈单失败: %v", err)
+			continue
+		}
+		canceledCount++
+	}
+
+	if canceledCount == 0 {
+		log.Printf("  ℹ %s 没有止盈单需要取消", symbol)
+	} else {
+		log.Printf("  ✓ 已取消 %s 的 %d 个止盈单", symbol, canceledCount)
+	}
+	return nil
 }
 
 // CancelAllOrders 取消该币种的所有挂单
@@ -654,11 +923,10 @@ func (t *HyperliquidTrader) SetStopLoss(symbol string, positionSide string, quan
 
 	isBuy := positionSide == "SHORT" // 空仓止损=买入，多仓止损=卖出
 
-	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-
-	// ⚠️ 关键：价格也需要处理为5位有效数字
-	roundedStopPrice := t.roundPriceToSigfigs(stopPrice)
+	// ⚠️ 关键：根据币种精度要求，处理数量和价格（价格最多5位有效数字）
+	inst := t.instrument(symbol)
+	roundedQuantity := inst.RoundQuantity(quantity)
+	roundedStopPrice := inst.RoundPrice(stopPrice)
 
 	// 创建止损单（Trigger Order）
 	order := hyperliquid.CreateOrderRequest{
@@ -691,11 +959,10 @@ func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, qu
 
 	isBuy := positionSide == "SHORT" // 空仓止盈=买入，多仓止盈=卖出
 
-	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-
-	// ⚠️ 关键：价格也需要处理为5位有效数字
-	roundedTakeProfitPrice := t.roundPriceToSigfigs(takeProfitPrice)
+	// ⚠️ 关键：根据币种精度要求，处理数量和价格（价格最多5位有效数字）
+	inst := t.instrument(symbol)
+	roundedQuantity := inst.RoundQuantity(quantity)
+	roundedTakeProfitPrice := inst.RoundPrice(takeProfitPrice)
 
 	// 创建止盈单（Trigger Order）
 	order := hyperliquid.CreateOrderRequest{
@@ -722,84 +989,51 @@ func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, qu
 	return nil
 }
 
-// FormatQuantity 格式化数量到正确的精度
-func (t *HyperliquidTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
-	coin := convertSymbolToHyperliquid(symbol)
-	szDecimals := t.getSzDecimals(coin)
-
-	// 使用szDecimals格式化数量
-	formatStr := fmt.Sprintf("%%.%df", szDecimals)
-	return fmt.Sprintf(formatStr, quantity), nil
-}
-
-// getSzDecimals 获取币种的数量精度
-func (t *HyperliquidTrader) getSzDecimals(coin string) int {
-	if t.meta == nil {
-		log.Printf("⚠️  meta信息为空，使用默认精度4")
-		return 4 // 默认精度
+// loadInstruments 从meta加载全部永续合约规则
+// Hyperliquid 数量精度为 szDecimals，价格最多5位有效数字且小数位不超过 6-szDecimals
+func (t *HyperliquidTrader) loadInstruments() (map[string]Instrument, error) {
+	meta, err := t.exchange.Info().Meta(t.ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// 在meta.Universe中查找对应的币种
-	for _, asset := range t.meta.Universe {
-		if asset.Name == coin {
-			return asset.SzDecimals
+	specs := make(map[string]Instrument, len(meta.Universe))
+	for _, asset := range meta.Universe {
+		if asset.IsDelisted {
+			continue
+		}
+		symbol := asset.Name + "USDT"
+		specs[symbol] = Instrument{
+			Symbol:       symbol,
+			TickSize:     math.Pow10(-(6 - asset.SzDecimals)),
+			PriceSigFigs: 5,
+			QtyStep:      math.Pow10(-asset.SzDecimals),
+			MinNotional:  10, // Hyperliquid 最小下单金额 $10
+			MaxLeverage:  asset.MaxLeverage,
+			ContractSize: 1,
 		}
 	}
-
-	log.Printf("⚠️  未找到 %s 的精度信息，使用默认精度4", coin)
-	return 4 // 默认精度
+	return specs, nil
 }
 
-// roundToSzDecimals 将数量四舍五入到正确的精度
-func (t *HyperliquidTrader) roundToSzDecimals(coin string, quantity float64) float64 {
-	szDecimals := t.getSzDecimals(coin)
-
-	// 计算倍数（10^szDecimals）
-	multiplier := 1.0
-	for i := 0; i < szDecimals; i++ {
-		multiplier *= 10.0
-	}
-
-	// 四舍五入
-	return float64(int(quantity*multiplier+0.5)) / multiplier
+// GetInstrument 获取合约交易规则
+func (t *HyperliquidTrader) GetInstrument(symbol string) (*Instrument, error) {
+	return t.instruments.get(symbol)
 }
 
-// roundPriceToSigfigs 将价格四舍五入到5位有效数字
-// Hyperliquid要求价格使用5位有效数字（significant figures）
-func (t *HyperliquidTrader) roundPriceToSigfigs(price float64) float64 {
-	if price == 0 {
-		return 0
-	}
-
-	const sigfigs = 5 // Hyperliquid标准：5位有效数字
-
-	// 计算价格的数量级
-	var magnitude float64
-	if price < 0 {
-		magnitude = -price
-	} else {
-		magnitude = price
-	}
-
-	// 计算需要的倍数
-	multiplier := 1.0
-	for magnitude >= 10 {
-		magnitude /= 10
-		multiplier /= 10
-	}
-	for magnitude < 1 {
-		magnitude *= 10
-		multiplier *= 10
-	}
-
-	// 应用有效数字精度
-	for i := 0; i < sigfigs-1; i++ {
-		multiplier *= 10
+// instrument 获取合约交易规则，未找到时使用默认精度（数量4位小数，价格5位有效数字）
+func (t *HyperliquidTrader) instrument(symbol string) *Instrument {
+	inst, err := t.instruments.get(symbol)
+	if err != nil {
+		log.Printf("⚠️  %v，使用默认精度4", err)
+		return &Instrument{Symbol: symbol, PriceSigFigs: 5, QtyStep: 0.0001, ContractSize: 1}
 	}
+	return inst
+}
 
-	// 四舍五入
-	rounded := float64(int(price*multiplier+0.5)) / multiplier
-	return rounded
+// FormatQuantity 格式化数量到正确的精度
+func (t *HyperliquidTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	return t.instrument(symbol).FormatQuantity(quantity), nil
 }
 
 // convertSymbolToHyperliquid 将标准symbol转换为Hyperliquid格式
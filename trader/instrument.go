@@ -0,0 +1,224 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// instrumentRefreshInterval 合约规则刷新周期（交易所调整步进、杠杆上限或上新币后自动生效）
+	instrumentRefreshInterval = time.Hour
+	// instrumentMissRetry 查询未上线的交易对时，距上次加载超过该时间才重新加载（避免每个周期都全量请求）
+	instrumentMissRetry = 10 * time.Minute
+)
+
+// Instrument 合约交易规则（各交易所统一表示，由 instrumentRegistry 加载和缓存）
+type Instrument struct {
+	Symbol       string
+	TickSize     float64 // 价格步进，0表示不限制
+	PriceSigFigs int     // 价格最大有效数字（Hyperliquid为5，整数价格不受限制），0表示不限制
+	QtyStep      float64 // 数量步进（基础币）
+	MinQty       float64 // 最小下单数量（基础币）
+	MinNotional  float64 // 最小下单金额（USDT），0表示无限制
+	MaxLeverage  int     // 最大杠杆，0表示未知
+	ContractSize float64 // 每张合约对应的基础币数量（按张下单的交易所使用，按币下单为1）
+}
+
+// RoundPrice 价格四舍五入到有效数字和价格步进（限价、止损、止盈统一使用）
+func (i *Instrument) RoundPrice(price float64) float64 {
+	if i.PriceSigFigs > 0 && price > 0 {
+		decimals := i.PriceSigFigs - 1 - int(math.Floor(math.Log10(price)))
+		if decimals < 0 {
+			decimals = 0
+		}
+		multiplier := math.Pow10(decimals)
+		price = math.Round(price*multiplier) / multiplier
+	}
+	if i.TickSize > 0 {
+		price = math.Round(price/i.TickSize) * i.TickSize
+	}
+	return price
+}
+
+// FormatPrice 格式化价格（按价格步进的小数位输出）
+func (i *Instrument) FormatPrice(price float64) string {
+	rounded := i.RoundPrice(price)
+	if i.TickSize <= 0 {
+		return strconv.FormatFloat(rounded, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(rounded, 'f', stepPrecision(i.TickSize), 64)
+}
+
+// RoundQuantity 数量向下取整到数量步进（避免超出可用保证金或持仓数量）
+func (i *Instrument) RoundQuantity(quantity float64) float64 {
+	if i.QtyStep <= 0 {
+		return quantity
+	}
+	return math.Floor(quantity/i.QtyStep+1e-9) * i.QtyStep
+}
+
+// FormatQuantity 格式化数量（按数量步进的小数位输出）
+func (i *Instrument) FormatQuantity(quantity float64) string {
+	rounded := i.RoundQuantity(quantity)
+	if i.QtyStep <= 0 {
+		return strconv.FormatFloat(rounded, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(rounded, 'f', stepPrecision(i.QtyStep), 64)
+}
+
+// CheckMin 检查下单数量和金额是否满足最小要求（price<=0 时不检查金额）
+func (i *Instrument) CheckMin(quantity, price float64) error {
+	if i.MinQty > 0 && quantity < i.MinQty {
+		return fmt.Errorf("下单数量 %.8f 小于 %s 最小下单数量 %.8f", quantity, i.Symbol, i.MinQty)
+	}
+	if i.MinNotional > 0 && price > 0 {
+		if notional := quantity * price; notional < i.MinNotional {
+			return fmt.Errorf("下单金额 %.2f USDT 小于 %s 最小下单金额 %.2f USDT", notional, i.Symbol, i.MinNotional)
+		}
+	}
+	return nil
+}
+
+// ClampLeverage 将杠杆限制在交易所允许的最大杠杆内
+func (i *Instrument) ClampLeverage(leverage int) int {
+	if i.MaxLeverage > 0 && leverage > i.MaxLeverage {
+		log.Printf("  ⚠️ %s 杠杆 %dx 超过交易所上限，调整为 %dx", i.Symbol, leverage, i.MaxLeverage)
+		return i.MaxLeverage
+	}
+	return leverage
+}
+
+// parseLeverage 解析交易所返回的杠杆倍数（如 "100"、"100.00"），向下取整
+func parseLeverage(s string) int {
+	leverage, _ := strconv.ParseFloat(s, 64)
+	return int(leverage)
+}
+
+// instrumentKey 注册表键（同一交易所主网和测试网的合约规则分开缓存）
+func instrumentKey(exchange string, testnet bool) string {
+	if testnet {
+		return exchange + "-testnet"
+	}
+	return exchange
+}
+
+// stepPrecision 步进对应的小数位数
+func stepPrecision(step float64) int {
+	return calculatePrecision(strconv.FormatFloat(step, 'f', -1, 64))
+}
+
+// instrumentRegistry 合约规则注册表（全量加载，定期刷新；刷新失败时继续使用旧数据）
+type instrumentRegistry struct {
+	name string // 交易所名称（日志用）
+	ttl  time.Duration
+	load func() (map[string]Instrument, error)
+
+	mu       sync.RWMutex
+	specs    map[string]Instrument
+	loadedAt time.Time
+}
+
+var (
+	instrumentRegistriesMu sync.Mutex
+	// instrumentRegistries 按交易所共享的注册表（同一交易所的多个交易员共用一份合约规则）
+	instrumentRegistries = make(map[string]*instrumentRegistry)
+)
+
+// sharedInstrumentRegistry 获取交易所的合约规则注册表，首次创建时启动后台定期刷新
+// key 区分主网/测试网（如 "okx-testnet"），load 只在首次创建时生效
+func sharedInstrumentRegistry(key, name string, load func() (map[string]Instrument, error)) *instrumentRegistry {
+	instrumentRegistriesMu.Lock()
+	defer instrumentRegistriesMu.Unlock()
+
+	if r, ok := instrumentRegistries[key]; ok {
+		return r
+	}
+	r := &instrumentRegistry{name: name, ttl: instrumentRefreshInterval, load: load}
+	instrumentRegistries[key] = r
+	go r.refreshLoop()
+	return r
+}
+
+// refreshLoop 定期刷新已加载过的合约规则
+func (r *instrumentRegistry) refreshLoop() {
+	ticker := time.NewTicker(r.ttl)
+	defer ticker.Stop()
+	for range ticker.C {
+		r.mu.RLock()
+		loaded := r.specs != nil
+		r.mu.RUnlock()
+		if !loaded {
+			continue
+		}
+		if err := r.refresh(); err != nil {
+			log.Printf("⚠️ 定期刷新%s合约规则失败，继续使用缓存: %v", r.name, err)
+		}
+	}
+}
+
+// instrumentNotFoundError 交易所没有该交易对（区别于加载合约规则失败）
+type instrumentNotFoundError struct {
+	symbol string
+}
+
+func (e *instrumentNotFoundError) Error() string {
+	return fmt.Sprintf("未找到交易对 %s 的合约规则", e.symbol)
+}
+
+// instrumentListed 交易对是否在交易所上市（加载合约规则失败时无法判断，视为上市，由下单时再校验）
+func instrumentListed(t Trader, symbol string) bool {
+	_, err := t.GetInstrument(symbol)
+	var notFound *instrumentNotFoundError
+	return !errors.As(err, &notFound)
+}
+
+// get 获取交易对规则（未加载或已过期时同步加载）
+func (r *instrumentRegistry) get(symbol string) (*Instrument, error) {
+	r.mu.RLock()
+	spec, ok := r.specs[symbol]
+	age := time.Since(r.loadedAt)
+	r.mu.RUnlock()
+	if ok && age < r.ttl {
+		return &spec, nil
+	}
+	// 刚加载过仍找不到，不重复请求
+	if !ok && age < instrumentMissRetry {
+		return nil, &instrumentNotFoundError{symbol: symbol}
+	}
+
+	// 过期或新上线的交易对：重新加载
+	if err := r.refresh(); err != nil {
+		if ok {
+			log.Printf("⚠️ 刷新%s合约规则失败，继续使用缓存: %v", r.name, err)
+			return &spec, nil
+		}
+		return nil, err
+	}
+
+	r.mu.RLock()
+	spec, ok = r.specs[symbol]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, &instrumentNotFoundError{symbol: symbol}
+	}
+	return &spec, nil
+}
+
+// refresh 全量加载合约规则
+func (r *instrumentRegistry) refresh() error {
+	specs, err := r.load()
+	if err != nil {
+		return fmt.Errorf("获取%s合约规则失败: %w", r.name, err)
+	}
+	r.mu.Lock()
+	r.specs = specs
+	r.loadedAt = time.Now()
+	r.mu.Unlock()
+	log.Printf("✓ 已加载%s合约规则: %d 个交易对", r.name, len(specs))
+	return nil
+}
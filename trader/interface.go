@@ -1,27 +1,29 @@
 package trader
 
+import "time"
+
 // Trader 交易器统一接口
 // 支持多个交易平台（币安、Hyperliquid等）
 type Trader interface {
 	// GetBalance 获取账户余额
-	GetBalance() (map[string]interface{}, error)
+	GetBalance() (*Balance, error)
 
 	// GetPositions 获取所有持仓
-	GetPositions() ([]map[string]interface{}, error)
+	GetPositions() ([]Position, error)
 
 	// OpenLong 开多仓
 	// stopLoss和takeProfit为可选参数（0表示不设置）
-	OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (map[string]interface{}, error)
+	OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error)
 
 	// OpenShort 开空仓
 	// stopLoss和takeProfit为可选参数（0表示不设置）
-	OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (map[string]interface{}, error)
+	OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error)
 
 	// CloseLong 平多仓（quantity=0表示全部平仓）
-	CloseLong(symbol string, quantity float64) (map[string]interface{}, error)
+	CloseLong(symbol string, quantity float64) (*OrderResult, error)
 
 	// CloseShort 平空仓（quantity=0表示全部平仓）
-	CloseShort(symbol string, quantity float64) (map[string]interface{}, error)
+	CloseShort(symbol string, quantity float64) (*OrderResult, error)
 
 	// SetLeverage 设置杠杆
 	SetLeverage(symbol string, leverage int) error
@@ -52,4 +54,60 @@ type Trader interface {
 
 	// FormatQuantity 格式化数量到正确的精度
 	FormatQuantity(symbol string, quantity float64) (string, error)
+
+	// GetInstrument 获取合约交易规则（价格步进、数量步进、最小下单量、最大杠杆，由共享注册表定期刷新）
+	GetInstrument(symbol string) (*Instrument, error)
+
+	// PlaceEntryOrder 按订单类型开仓（market/limit/post_only/ioc），不设置止盈止损
+	// 限价单可能挂单未成交，由调用方通过 GetOrder 跟踪，成交后再设置止盈止损
+	PlaceEntryOrder(order EntryOrder) (*OrderResult, error)
+
+	// GetOrder 查询订单（Status 统一为 OrderStatus* 常量）
+	GetOrder(symbol, orderID string) (*OrderResult, error)
+
+	// CancelOrder 撤销指定订单
+	CancelOrder(symbol, orderID string) error
+
+	// GetOpenOrders 查询未完成的挂单，包含止盈止损等条件单（symbol为空表示全部币种）
+	GetOpenOrders(symbol string) ([]OpenOrder, error)
+
+	// GetUserTrades 获取指定币种自 startTime 起的成交明细（含手续费，同一订单的多笔成交合并，按时间升序）
+	GetUserTrades(symbol string, startTime time.Time) ([]Fill, error)
+}
+
+// AccountStreamer 可选接口：支持私有WebSocket推送账户变化的交易所实现该接口
+// 推送维护的账户缓存供回撤监控、API和决策上下文使用，并实时发现止盈止损成交
+type AccountStreamer interface {
+	// StreamAccount 连接私有推送并持续读取，直到 stop 关闭（返回nil）或连接断开（返回错误，由调用方重连）
+	// 连接建立后先清除REST缓存并推送一次 Resync，调用方据此用REST全量同步
+	StreamAccount(stop <-chan struct{}, onUpdate func(AccountUpdate)) error
+}
+
+// FundingFeeProvider 可选接口：支持查询资金费流水的交易所实现该接口
+// 交易台账用于统计每笔交易的资金费
+type FundingFeeProvider interface {
+	// GetFundingFees 获取指定币种自 startTime 起的累计资金费（正数为收入，负数为支出）
+	GetFundingFees(symbol string, startTime time.Time) (float64, error)
+}
+
+// TrailingStopper 可选接口：支持原生跟踪止损单的交易所实现该接口（币安 TRAILING_STOP_MARKET、OKX move_order_stop）
+// 不支持的交易所由 AutoTrader 的本地跟踪引擎按最新价维护极值并市价平仓
+type TrailingStopper interface {
+	// SetTrailingStop 设置跟踪止损单（callbackRate 为回调比例%，activationPrice 为激活价，0 表示立即激活）
+	SetTrailingStop(symbol string, positionSide string, quantity, callbackRate, activationPrice float64) error
+
+	// CancelTrailingStopOrders 仅取消跟踪止损单（不影响普通止盈止损单）
+	CancelTrailingStopOrders(symbol string) error
+}
+
+// PartialTakeProfitSetter 可选接口：SetTakeProfit 不适合按数量分批挂单的交易所（币安 closePosition、OKX 策略委托）实现该接口，
+// 按数量挂只减仓止盈单，用于分批止盈；未实现的交易所直接按档位数量调用 SetTakeProfit
+type PartialTakeProfitSetter interface {
+	SetPartialTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error
+}
+
+// FeeScheduleProvider 可选接口：手续费率可配置的交易器（模拟盘）实现该接口
+// 未实现的交易所使用 exchangeFeeSchedules 中的默认费率
+type FeeScheduleProvider interface {
+	FeeSchedule() FeeSchedule
 }
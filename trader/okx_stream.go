@@ -0,0 +1,320 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// OKX 私有频道地址
+const (
+	okxPrivateWSURL        = "wss://ws.okx.com:8443/ws/v5/private"
+	okxPrivateWSTestnetURL = "wss://wspap.okx.com:8443/ws/v5/private?brokerId=9999"
+	okxWSPingInterval      = 25 * time.Second // 30秒无消息服务端断开连接
+)
+
+// okxWSMessage OKX推送消息（事件响应或频道数据）
+type okxWSMessage struct {
+	Event string `json:"event"`
+	Code  string `json:"code"`
+	Msg   string `json:"msg"`
+	Arg   struct {
+		Channel string `json:"channel"`
+	} `json:"arg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// StreamAccount 订阅OKX私有频道（account / positions / orders）
+func (t *OKXTrader) StreamAccount(stop <-chan struct{}, onUpdate func(AccountUpdate)) error {
+	url := okxPrivateWSURL
+	if t.testnet {
+		url = okxPrivateWSTestnetURL
+	}
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		return fmt.Errorf("连接OKX私有频道失败: %w", err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	// 登录
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	login := map[string]interface{}{
+		"op": "login",
+		"args": []map[string]string{{
+			"apiKey":     t.apiKey,
+			"passphrase": t.passphrase,
+			"timestamp":  timestamp,
+			"sign":       t.signRequest("GET", "/users/self/verify", "", timestamp),
+		}},
+	}
+	if err := conn.WriteJSON(login); err != nil {
+		return fmt.Errorf("发送OKX登录请求失败: %w", err)
+	}
+	if err := t.waitWSEvent(conn, "login"); err != nil {
+		return err
+	}
+
+	subscribe := map[string]interface{}{
+		"op": "subscribe",
+		"args": []map[string]string{
+			{"channel": "account", "ccy": "USDT"},
+			{"channel": "positions", "instType": "SWAP"},
+			{"channel": "orders", "instType": "SWAP"},
+		},
+	}
+	if err := conn.WriteJSON(subscribe); err != nil {
+		return fmt.Errorf("发送OKX订阅请求失败: %w", err)
+	}
+
+	// 心跳（gorilla 连接只允许一个并发写入者，登录和订阅完成后只有心跳goroutine写入）
+	go func() {
+		ticker := time.NewTicker(okxWSPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	t.clearCache()
+	onUpdate(AccountUpdate{Resync: true})
+
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+				return fmt.Errorf("OKX私有频道读取失败: %w", err)
+			}
+		}
+		if string(raw) == "pong" {
+			continue
+		}
+
+		var msg okxWSMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			continue
+		}
+		if msg.Event == "error" {
+			return fmt.Errorf("OKX私有频道错误: code=%s, msg=%s", msg.Code, msg.Msg)
+		}
+		if msg.Event != "" || len(msg.Data) == 0 {
+			continue
+		}
+
+		var update AccountUpdate
+		switch msg.Arg.Channel {
+		case "account":
+			update, err = parseOKXAccountPush(msg.Data)
+		case "positions":
+			update, err = parseOKXPositionsPush(msg.Data)
+		case "orders":
+			update, err = parseOKXOrdersPush(msg.Data)
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("解析OKX %s 推送失败: %w", msg.Arg.Channel, err)
+		}
+		onUpdate(update)
+	}
+}
+
+// waitWSEvent 等待指定事件的响应（如登录结果）
+func (t *OKXTrader) waitWSEvent(conn *websocket.Conn, event string) error {
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		var msg okxWSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return fmt.Errorf("等待OKX %s 响应失败: %w", event, err)
+		}
+		if msg.Event == "error" {
+			return fmt.Errorf("OKX %s 失败: code=%s, msg=%s", event, msg.Code, msg.Msg)
+		}
+		if msg.Event == event {
+			return nil
+		}
+	}
+}
+
+// clearCache 清除余额和持仓缓存（推送重连后全量同步需要最新数据）
+func (t *OKXTrader) clearCache() {
+	t.balanceCacheMutex.Lock()
+	t.cachedBalance = nil
+	t.balanceCacheMutex.Unlock()
+
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = nil
+	t.positionsCacheMutex.Unlock()
+}
+
+// parseOKXAccountPush 解析account频道（字段与REST余额接口一致）
+func parseOKXAccountPush(data json.RawMessage) (AccountUpdate, error) {
+	var accounts []struct {
+		TotalEq  string `json:"totalEq"`
+		AdjEq    string `json:"adjEq"`
+		MgnRatio string `json:"mgnRatio"`
+		Details  []struct {
+			Currency  string `json:"ccy"`
+			Available string `json:"availEq"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return AccountUpdate{}, err
+	}
+
+	var update AccountUpdate
+	for _, account := range accounts {
+		balance := &Balance{}
+		balance.TotalWalletBalance, _ = strconv.ParseFloat(account.TotalEq, 64)
+		balance.TotalEquity, _ = strconv.ParseFloat(account.AdjEq, 64)
+		balance.MarginRatio, _ = strconv.ParseFloat(account.MgnRatio, 64)
+		for _, detail := range account.Details {
+			if detail.Currency == "USDT" {
+				balance.AvailableBalance, _ = strconv.ParseFloat(detail.Available, 64)
+			}
+		}
+		update.Balance = balance
+	}
+	return update, nil
+}
+
+// parseOKXPositionsPush 解析positions频道（字段与REST持仓接口一致，pos为0表示已平仓）
+func parseOKXPositionsPush(data json.RawMessage) (AccountUpdate, error) {
+	var positions []struct {
+		InstID  string `json:"instId"`
+		Pos     string `json:"pos"`
+		AvgPx   string `json:"avgPx"`
+		MarkPx  string `json:"markPx"`
+		Upl     string `json:"upl"`
+		Lever   string `json:"lever"`
+		LiqPx   string `json:"liqPx"`
+		PosSide string `json:"posSide"`
+		Margin  string `json:"margin"`
+	}
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return AccountUpdate{}, err
+	}
+
+	var update AccountUpdate
+	for _, pos := range positions {
+		if !strings.HasSuffix(pos.InstID, "-SWAP") {
+			continue
+		}
+		amount, _ := strconv.ParseFloat(pos.Pos, 64)
+		p := Position{Symbol: okxSymbolFromInstID(pos.InstID), Side: pos.PosSide}
+		p.EntryPrice, _ = strconv.ParseFloat(pos.AvgPx, 64)
+		p.MarkPrice, _ = strconv.ParseFloat(pos.MarkPx, 64)
+		p.UnrealizedProfit, _ = strconv.ParseFloat(pos.Upl, 64)
+		p.LiquidationPrice, _ = strconv.ParseFloat(pos.LiqPx, 64)
+		p.Margin, _ = strconv.ParseFloat(pos.Margin, 64)
+		leverage, _ := strconv.ParseFloat(pos.Lever, 64)
+		p.Leverage = int(leverage)
+
+		// 单向持仓模式（posSide=net）按数量正负判断方向，平仓时两个方向都可能需要清除
+		if p.Side != "long" && p.Side != "short" {
+			if amount == 0 {
+				long, short := p, p
+				long.Side, short.Side = "long", "short"
+				update.Positions = append(update.Positions, long, short)
+				continue
+			}
+			p.Side = "long"
+			if amount < 0 {
+				p.Side = "short"
+			}
+		}
+		if amount < 0 {
+			amount = -amount
+		}
+		p.Quantity = amount
+		update.Positions = append(update.Positions, p)
+	}
+	return update, nil
+}
+
+// parseOKXOrdersPush 解析orders频道（条件单触发后生成的委托带有algoId）
+func parseOKXOrdersPush(data json.RawMessage) (AccountUpdate, error) {
+	var orders []struct {
+		OrdID      string `json:"ordId"`
+		InstID     string `json:"instId"`
+		Side       string `json:"side"`
+		PosSide    string `json:"posSide"`
+		OrdType    string `json:"ordType"`
+		State      string `json:"state"`
+		AvgPx      string `json:"avgPx"`
+		AccFillSz  string `json:"accFillSz"`
+		Fee        string `json:"fee"`
+		ReduceOnly string `json:"reduceOnly"`
+		AlgoID     string `json:"algoId"`
+		UTime      string `json:"uTime"`
+	}
+	if err := json.Unmarshal(data, &orders); err != nil {
+		return AccountUpdate{}, err
+	}
+
+	var update AccountUpdate
+	for _, o := range orders {
+		if !strings.HasSuffix(o.InstID, "-SWAP") {
+			continue
+		}
+		order := OrderUpdate{
+			OrderID:      o.OrdID,
+			Symbol:       okxSymbolFromInstID(o.InstID),
+			Side:         o.Side,
+			PositionSide: o.PosSide,
+			Status:       okxOrderStatus(o.State),
+			Time:         parseOKXTime(o.UTime),
+		}
+		order.AvgPrice, _ = strconv.ParseFloat(o.AvgPx, 64)
+		order.ExecutedQty, _ = strconv.ParseFloat(o.AccFillSz, 64)
+		fee, _ := strconv.ParseFloat(o.Fee, 64)
+		order.Fee = -fee // OKX手续费为负数表示扣除
+
+		// 双向持仓模式下与持仓方向相反的委托为平仓
+		order.ReduceOnly = o.ReduceOnly == "true" ||
+			(o.PosSide == "long" && o.Side == "sell") || (o.PosSide == "short" && o.Side == "buy")
+		if o.PosSide != "long" && o.PosSide != "short" {
+			order.PositionSide = inferPositionSide(o.Side, order.ReduceOnly)
+		}
+
+		switch {
+		case o.AlgoID != "":
+			order.Type = OpenOrderTypeTrigger
+		case o.OrdType == "market":
+			order.Type = OpenOrderTypeMarket
+		default:
+			order.Type = OpenOrderTypeLimit
+		}
+		update.Orders = append(update.Orders, order)
+	}
+	return update, nil
+}
+
+// okxSymbolFromInstID 转换OKX交易对格式 (BTC-USDT-SWAP -> BTCUSDT)
+func okxSymbolFromInstID(instID string) string {
+	symbol := strings.ReplaceAll(instID, "-USDT-SWAP", "USDT")
+	return strings.ReplaceAll(symbol, "-", "")
+}
@@ -1,15 +1,11 @@
 package trader
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"math"
-	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -27,29 +23,24 @@ type OKXTrader struct {
 	apiKey     string
 	secretKey  string
 	passphrase string
-	baseURL    string
-	client     *http.Client
+	testnet    bool
+	rest       *restClient
 
 	// 余额缓存
-	cachedBalance     map[string]interface{}
+	cachedBalance     *Balance
 	balanceCacheTime  time.Time
 	balanceCacheMutex sync.RWMutex
 
 	// 持仓缓存
-	cachedPositions     []map[string]interface{}
+	cachedPositions     []Position
 	positionsCacheTime  time.Time
 	positionsCacheMutex sync.RWMutex
 
 	// 缓存有效期（15秒）
 	cacheDuration time.Duration
 
-	// 交易对精度缓存
-	symbolPrecision map[string]int
-	precisionMutex  sync.RWMutex
-	
-	// 交易对lotSz缓存（V1.66版本：新增）
-	symbolLotSz map[string]float64
-	lotSzMutex  sync.RWMutex
+	// 合约规则（lotSz、tickSz、ctVal、最大杠杆）
+	instruments *instrumentRegistry
 }
 
 // NewOKXTrader 创建OKX合约交易器
@@ -63,147 +54,70 @@ func NewOKXTrader(apiKey, secretKey, passphrase string, testnet bool) *OKXTrader
 		apiKey:      apiKey,
 		secretKey:  secretKey,
 		passphrase: passphrase,
-		baseURL:    baseURL,
-		client: &http.Client{
-			Timeout: 60 * time.Second, // 增加到60秒，避免超时
-		},
+		testnet:    testnet,
+		rest:       newRESTClient("OKX", baseURL, newOKXSigner(apiKey, secretKey, passphrase)),
 		cacheDuration:  10 * time.Second, // 降低到10秒，提高实时性
-		symbolPrecision: make(map[string]int),
-		symbolLotSz:      make(map[string]float64), // V1.66版本：初始化lotSz缓存
 	}
+	trader.instruments = sharedInstrumentRegistry(instrumentKey("okx", testnet), "OKX", trader.loadInstruments)
+
+	trader.rest.httpClient.Timeout = 60 * time.Second // 增加到60秒，避免超时
 
 	log.Printf("✓ OKX交易器初始化成功 (testnet=%v)", testnet)
 	return trader
 }
 
-// signRequest 生成OKX API签名
+// signRequest 生成OKX API签名（私有频道登录使用）
 func (t *OKXTrader) signRequest(method, path, body string, timestamp string) string {
-	// OKX签名格式: timestamp + method + path + body
-	message := timestamp + method + path + body
-	
-	// HMAC-SHA256签名
-	mac := hmac.New(sha256.New, []byte(t.secretKey))
-	mac.Write([]byte(message))
-	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
-	
-	return signature
+	return okxSignature(t.secretKey, timestamp, method, path, body)
 }
 
-// makeRequest 发送API请求（带重试机制）
+// makeRequest 发送API请求（签名和重试由 restClient 处理，path 可带查询字符串）
 func (t *OKXTrader) makeRequest(method, path string, body interface{}) ([]byte, error) {
-	maxRetries := 3
-	var lastErr error
-	
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		var bodyStr string
-		if body != nil {
-			bodyBytes, err := json.Marshal(body)
-			if err != nil {
-				return nil, fmt.Errorf("序列化请求体失败: %w", err)
-			}
-			bodyStr = string(bodyBytes)
-		}
-
-		url := t.baseURL + path
-		timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
-		signature := t.signRequest(method, path, bodyStr, timestamp)
-
-		req, err := http.NewRequest(method, url, strings.NewReader(bodyStr))
-		if err != nil {
-			return nil, fmt.Errorf("创建请求失败: %w", err)
-		}
-
-		// 设置请求头
-		req.Header.Set("OK-ACCESS-KEY", t.apiKey)
-		req.Header.Set("OK-ACCESS-SIGN", signature)
-		req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
-		req.Header.Set("OK-ACCESS-PASSPHRASE", t.passphrase)
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err := t.client.Do(req)
-		if err != nil {
-			lastErr = fmt.Errorf("请求失败: %w", err)
-			// 检查是否是超时或网络错误，可以重试
-			if strings.Contains(err.Error(), "timeout") || 
-			   strings.Contains(err.Error(), "deadline exceeded") ||
-			   strings.Contains(err.Error(), "connection") {
-				if attempt < maxRetries {
-					waitTime := time.Duration(attempt) * 2 * time.Second
-					log.Printf("⚠️  OKX API请求失败（尝试 %d/%d），%v后重试: %v", attempt, maxRetries, waitTime, err)
-					time.Sleep(waitTime)
-					continue
-				}
-			}
-			return nil, lastErr
-		}
-		defer resp.Body.Close()
-
-		respBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			lastErr = fmt.Errorf("读取响应失败: %w", err)
-			if attempt < maxRetries {
-				time.Sleep(time.Duration(attempt) * 2 * time.Second)
-				continue
-			}
-			return nil, lastErr
-		}
+	respBody, err := t.rest.do(method, path, nil, body)
+	if err != nil {
+		return nil, err
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			lastErr = fmt.Errorf("API错误 (状态码: %d): %s", resp.StatusCode, string(respBody))
-			// 4xx错误不重试，5xx错误可以重试
-			if resp.StatusCode >= 500 && attempt < maxRetries {
-				waitTime := time.Duration(attempt) * 2 * time.Second
-				log.Printf("⚠️  OKX API服务器错误（尝试 %d/%d），%v后重试: %v", attempt, maxRetries, waitTime, lastErr)
-				time.Sleep(waitTime)
-				continue
-			}
-			return nil, lastErr
-		}
+	// 解析OKX响应格式
+	var okxResp struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
 
-		// 解析OKX响应格式
-		var okxResp struct {
-			Code string          `json:"code"`
-			Msg  string          `json:"msg"`
-			Data json.RawMessage `json:"data"`
-		}
+	if err := json.Unmarshal(respBody, &okxResp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
 
-		if err := json.Unmarshal(respBody, &okxResp); err != nil {
-			return nil, fmt.Errorf("解析响应失败: %w", err)
+	if okxResp.Code != "0" {
+		// V1.68版本：增强错误日志，记录完整的API响应和请求信息
+		log.Printf("  ❌ OKX API错误: code=%s, msg=%s", okxResp.Code, okxResp.Msg)
+		log.Printf("  📋 请求路径: %s %s", method, path)
+		if body != nil {
+			bodyBytes, _ := json.Marshal(body)
+			log.Printf("  📋 请求体: %s", string(bodyBytes))
 		}
-
-		if okxResp.Code != "0" {
-			// V1.68版本：增强错误日志，记录完整的API响应和请求信息
-			log.Printf("  ❌ OKX API错误: code=%s, msg=%s", okxResp.Code, okxResp.Msg)
-			log.Printf("  📋 请求路径: %s %s", method, path)
-			if body != nil {
-				bodyBytes, _ := json.Marshal(body)
-				log.Printf("  📋 请求体: %s", string(bodyBytes))
+		log.Printf("  📋 完整响应: %s", string(respBody))
+		
+		// 解析响应数据（如果有详细信息）
+		if len(okxResp.Data) > 0 {
+			var errorData []struct {
+				SCode string `json:"sCode"`
+				SMsg  string `json:"sMsg"`
 			}
-			log.Printf("  📋 完整响应: %s", string(respBody))
-			
-			// 解析响应数据（如果有详细信息）
-			if len(okxResp.Data) > 0 {
-				var errorData []struct {
-					SCode string `json:"sCode"`
-					SMsg  string `json:"sMsg"`
-				}
-				if err := json.Unmarshal(okxResp.Data, &errorData); err == nil && len(errorData) > 0 {
-					log.Printf("  📋 错误详情: sCode=%s, sMsg=%s", errorData[0].SCode, errorData[0].SMsg)
-				}
+			if err := json.Unmarshal(okxResp.Data, &errorData); err == nil && len(errorData) > 0 {
+				log.Printf("  📋 错误详情: sCode=%s, sMsg=%s", errorData[0].SCode, errorData[0].SMsg)
 			}
-			
-			return nil, fmt.Errorf("OKX API错误: %s - %s", okxResp.Code, okxResp.Msg)
 		}
-
-		return okxResp.Data, nil
+		
+		return nil, fmt.Errorf("OKX API错误: %s - %s", okxResp.Code, okxResp.Msg)
 	}
-	
-	// 所有重试都失败
-	return nil, fmt.Errorf("OKX API请求失败（已重试%d次）: %w", maxRetries, lastErr)
+
+	return okxResp.Data, nil
 }
 
 // GetBalance 获取账户余额（带缓存）
-func (t *OKXTrader) GetBalance() (map[string]interface{}, error) {
+func (t *OKXTrader) GetBalance() (*Balance, error) {
 	// 先检查缓存是否有效
 	t.balanceCacheMutex.RLock()
 	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
@@ -263,14 +177,13 @@ func (t *OKXTrader) GetBalance() (map[string]interface{}, error) {
 	}
 
 	// 计算未实现盈亏（需要从持仓中获取，这里先设为0，后续在GetAccountInfo中计算）
-	result := make(map[string]interface{})
-	result["totalWalletBalance"] = totalEq
-	result["totalEquity"] = adjEq // 使用adjEq作为总权益（美金层面）
-	result["availableBalance"] = availableEq
-	result["totalUnrealizedProfit"] = 0.0 // 需要从持仓计算
-	result["mgnRatio"] = mgnRatio         // OKX标准保证金率
-	result["notionalUsd"] = notional      // 持仓名义价值
-	result["isoEq"] = balance.IsoEq       // 逐仓权益
+	result := &Balance{
+		TotalWalletBalance:    totalEq,
+		TotalEquity:           adjEq, // 使用adjEq作为总权益（美金层面）
+		AvailableBalance:      availableEq,
+		TotalUnrealizedProfit: 0.0,      // 需要从持仓计算
+		MarginRatio:           mgnRatio, // OKX标准保证金率
+	}
 
 	log.Printf("✓ OKX API返回: 总权益=%.2f, 可用=%.2f, 保证金率=%.4f, 名义价值=%.2f", adjEq, availableEq, mgnRatio, notional)
 
@@ -284,7 +197,7 @@ func (t *OKXTrader) GetBalance() (map[string]interface{}, error) {
 }
 
 // GetPositions 获取所有持仓（带缓存）
-func (t *OKXTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *OKXTrader) GetPositions() ([]Position, error) {
 	// 先检查缓存是否有效
 	t.positionsCacheMutex.RLock()
 	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
@@ -328,7 +241,7 @@ func (t *OKXTrader) GetPositions() ([]map[string]interface{}, error) {
 		return nil, fmt.Errorf("解析持仓数据失败: %w", err)
 	}
 
-	var result []map[string]interface{}
+	var result []Position
 	for _, pos := range positions {
 		posAmt, _ := strconv.ParseFloat(pos.Pos, 64)
 		if posAmt == 0 {
@@ -343,52 +256,44 @@ func (t *OKXTrader) GetPositions() ([]map[string]interface{}, error) {
 		entryPrice, _ := strconv.ParseFloat(pos.AvgPx, 64)
 		markPrice, _ := strconv.ParseFloat(pos.MarkPx, 64)
 		unRealizedProfit, _ := strconv.ParseFloat(pos.Upl, 64)
-		unRealizedProfitRatio, _ := strconv.ParseFloat(pos.UplRatio, 64)
 		leverage, _ := strconv.ParseFloat(pos.Lever, 64)
 		liquidationPrice, _ := strconv.ParseFloat(pos.LiqPx, 64)
 		margin, _ := strconv.ParseFloat(pos.Margin, 64)
-		notionalUsd, _ := strconv.ParseFloat(pos.NotionalUsd, 64)
-		imr, _ := strconv.ParseFloat(pos.Imr, 64)
-		mmr, _ := strconv.ParseFloat(pos.Mmr, 64)
 
 		// 确保posAmt为正数（使用绝对值）
 		if posAmt < 0 {
 			posAmt = -posAmt
 		}
 
-		posMap := make(map[string]interface{})
-		posMap["symbol"] = symbol
-		posMap["positionAmt"] = posAmt
-		posMap["entryPrice"] = entryPrice
-		posMap["markPrice"] = markPrice
-		posMap["unRealizedProfit"] = unRealizedProfit
-		posMap["unRealizedProfitRatio"] = unRealizedProfitRatio
-		posMap["leverage"] = leverage
-		posMap["liquidationPrice"] = liquidationPrice
-		posMap["margin"] = margin
-		posMap["notionalUsd"] = notionalUsd
-		posMap["marginMode"] = pos.MgnMode
-		posMap["imr"] = imr
-		posMap["mmr"] = mmr
+		p := Position{
+			Symbol:           symbol,
+			Quantity:         posAmt,
+			EntryPrice:       entryPrice,
+			MarkPrice:        markPrice,
+			UnrealizedProfit: unRealizedProfit,
+			Leverage:         int(leverage),
+			LiquidationPrice: liquidationPrice,
+			Margin:           margin,
+		}
 
 		// 判断方向：直接使用OKX API返回的posSide字段（"long"或"short"）
 		// OKX标准：posSide字段明确标识方向
 		if pos.PosSide == "long" {
-			posMap["side"] = "long"
+			p.Side = "long"
 		} else if pos.PosSide == "short" {
-			posMap["side"] = "short"
+			p.Side = "short"
 		} else {
 			// 兼容处理：如果posSide为空，根据pos数量判断
 			originalPos, _ := strconv.ParseFloat(pos.Pos, 64)
 			if originalPos > 0 {
-				posMap["side"] = "long"
+				p.Side = "long"
 			} else {
-				posMap["side"] = "short"
+				p.Side = "short"
 			}
-			log.Printf("⚠️  OKX持仓方向未知(posSide=%s)，使用数量判断: %s", pos.PosSide, posMap["side"])
+			log.Printf("⚠️  OKX持仓方向未知(posSide=%s)，使用数量判断: %s", pos.PosSide, p.Side)
 		}
 
-		result = append(result, posMap)
+		result = append(result, p)
 	}
 
 	// 更新缓存
@@ -445,6 +350,10 @@ func (t *OKXTrader) SetLeverage(symbol string, leverage int) error {
 
 // SetLeverageWithPosSide 设置杠杆（带posSide参数，用于逐仓模式）
 func (t *OKXTrader) SetLeverageWithPosSide(symbol string, leverage int, posSide string) error {
+	if inst, err := t.instruments.get(symbol); err == nil {
+		leverage = inst.ClampLeverage(leverage)
+	}
+
 	// 转换交易对格式
 	instID := t.convertSymbolToInstID(symbol)
 
@@ -498,7 +407,7 @@ func (t *OKXTrader) SetLeverageWithPosSide(symbol string, leverage int, posSide
 }
 
 // OpenLong 开多仓（V1.57版本：支持下单时设置止盈止损）
-func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (map[string]interface{}, error) {
+func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -524,7 +433,7 @@ func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int, stop
 	if balanceErr != nil {
 		return nil, fmt.Errorf("获取账户余额失败: %w", balanceErr)
 	}
-	availableBalance, _ := balance["availableBalance"].(float64)
+	availableBalance := balance.AvailableBalance
 	
 	// 格式化数量
 	quantityStr, err := t.FormatQuantity(symbol, quantity)
@@ -551,7 +460,7 @@ func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int, stop
 	// 检查格式化后的数量是否导致保证金不足
 	if formattedMarginRequired > availableBalance {
 		// 获取lotSz以提供更详细的错误信息
-		lotSz, _ := t.GetSymbolLotSz(symbol)
+		lotSz := t.getLotSz(symbol)
 		minPositionValue := lotSz * currentPrice
 		minMarginRequired := minPositionValue / float64(leverage)
 		
@@ -612,7 +521,7 @@ func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int, stop
 		if stopLoss > 0 {
 			stopLossOrder := map[string]interface{}{
 				"attachAlgoClOrdId": fmt.Sprintf("sl_%s_%d", symbol, time.Now().UnixMilli()),
-				"slTriggerPx": fmt.Sprintf("%.8f", stopLoss),
+				"slTriggerPx": t.formatTriggerPrice(symbol, stopLoss),
 				"slTriggerPxType": "last",  // 触发价格类型：last表示最新价
 				"slOrdPx": "-1",            // -1表示市价单（止损时立即以市价成交）
 				"sz": quantityStr,
@@ -626,7 +535,7 @@ func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int, stop
 		if takeProfit > 0 {
 			takeProfitOrder := map[string]interface{}{
 				"attachAlgoClOrdId": fmt.Sprintf("tp_%s_%d", symbol, time.Now().UnixMilli()),
-				"tpTriggerPx": fmt.Sprintf("%.8f", takeProfit),
+				"tpTriggerPx": t.formatTriggerPrice(symbol, takeProfit),
 				"tpTriggerPxType": "last",  // 触发价格类型：last表示最新价
 				"tpOrdPx": "-1",            // -1表示市价单（止盈时立即以市价成交）
 				"sz": quantityStr,
@@ -667,13 +576,10 @@ func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int, stop
 		// 检查账户余额
 		balance, balanceErr := t.GetBalance()
 		if balanceErr == nil {
-			if totalEq, ok := balance["totalEquity"].(float64); ok {
-				log.Printf("  💰 账户净值: %.2f USDT", totalEq)
-			}
-			if available, ok := balance["availableBalance"].(float64); ok {
-				log.Printf("  💰 可用余额: %.2f USDT", available)
-				log.Printf("  💰 格式化后所需保证金: %.2f USDT", formattedMarginRequired)
-			}
+			log.Printf("  💰 账户净值: %.2f USDT", balance.TotalEquity)
+			available := balance.AvailableBalance
+			log.Printf("  💰 可用余额: %.2f USDT", available)
+			log.Printf("  💰 格式化后所需保证金: %.2f USDT", formattedMarginRequired)
 		}
 		
 		return nil, fmt.Errorf("开多仓失败: %w", err)
@@ -779,31 +685,28 @@ func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int, stop
 		// 检查账户余额
 		balance, balanceErr := t.GetBalance()
 		if balanceErr == nil {
-			if totalEq, ok := balance["totalEquity"].(float64); ok {
-				log.Printf("  💰 账户净值: %.2f USDT", totalEq)
-			}
-			if available, ok := balance["availableBalance"].(float64); ok {
-				log.Printf("  💰 可用余额: %.2f USDT", available)
-				// 计算所需保证金（如果获取到当前价格）
-				if priceErr == nil && currentPrice > 0 {
-					positionValue := quantity * currentPrice
-					marginRequired := positionValue / float64(leverage)
-					log.Printf("  💰 所需保证金: %.2f USDT (仓位价值=%.2f / 杠杆=%d)", 
-						marginRequired, positionValue, leverage)
-					if available < marginRequired {
-						log.Printf("  ⚠️ 可用余额不足！需要 %.2f USDT，但只有 %.2f USDT", marginRequired, available)
-					}
-					
-					// 检查止损是否合理（如果设置了止损）
-					if stopLoss > 0 {
-						// 计算爆仓价
-						liquidationPrice := currentPrice * (1 - 1.0/float64(leverage))
-						log.Printf("  💰 当前价格: %.4f, 爆仓价: %.4f, 止损价: %.4f", 
-							currentPrice, liquidationPrice, stopLoss)
-						if stopLoss <= liquidationPrice {
-							log.Printf("  ⚠️ 止损价低于或等于爆仓价！止损价必须在爆仓价上方")
-							log.Printf("     做多时: 止损价必须 > 爆仓价 (%.4f)", liquidationPrice)
-						}
+			log.Printf("  💰 账户净值: %.2f USDT", balance.TotalEquity)
+			available := balance.AvailableBalance
+			log.Printf("  💰 可用余额: %.2f USDT", available)
+			// 计算所需保证金（如果获取到当前价格）
+			if priceErr == nil && currentPrice > 0 {
+				positionValue := quantity * currentPrice
+				marginRequired := positionValue / float64(leverage)
+				log.Printf("  💰 所需保证金: %.2f USDT (仓位价值=%.2f / 杠杆=%d)", 
+					marginRequired, positionValue, leverage)
+				if available < marginRequired {
+					log.Printf("  ⚠️ 可用余额不足！需要 %.2f USDT，但只有 %.2f USDT", marginRequired, available)
+				}
+				
+				// 检查止损是否合理（如果设置了止损）
+				if stopLoss > 0 {
+					// 计算爆仓价
+					liquidationPrice := currentPrice * (1 - 1.0/float64(leverage))
+					log.Printf("  💰 当前价格: %.4f, 爆仓价: %.4f, 止损价: %.4f", 
+						currentPrice, liquidationPrice, stopLoss)
+					if stopLoss <= liquidationPrice {
+						log.Printf("  ⚠️ 止损价低于或等于爆仓价！止损价必须在爆仓价上方")
+						log.Printf("     做多时: 止损价必须 > 爆仓价 (%.4f)", liquidationPrice)
 					}
 				}
 			}
@@ -835,15 +738,15 @@ func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int, stop
 	log.Printf("✓ 开多仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %s", order.OrdID)
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrdID
-	result["symbol"] = symbol
-	result["status"] = "filled"
-	return result, nil
+	return &OrderResult{
+		OrderID: order.OrdID,
+		Symbol:  symbol,
+		Status:  "filled",
+	}, nil
 }
 
 // OpenShort 开空仓（V1.57版本：支持下单时设置止盈止损）
-func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (map[string]interface{}, error) {
+func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -869,7 +772,7 @@ func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int, sto
 	if balanceErr != nil {
 		return nil, fmt.Errorf("获取账户余额失败: %w", balanceErr)
 	}
-	availableBalance, _ := balance["availableBalance"].(float64)
+	availableBalance := balance.AvailableBalance
 	
 	// 格式化数量
 	quantityStr, err := t.FormatQuantity(symbol, quantity)
@@ -896,7 +799,7 @@ func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int, sto
 	// 检查格式化后的数量是否导致保证金不足
 	if formattedMarginRequired > availableBalance {
 		// 获取lotSz以提供更详细的错误信息
-		lotSz, _ := t.GetSymbolLotSz(symbol)
+		lotSz := t.getLotSz(symbol)
 		minPositionValue := lotSz * currentPrice
 		minMarginRequired := minPositionValue / float64(leverage)
 		
@@ -957,7 +860,7 @@ func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int, sto
 		if stopLoss > 0 {
 			stopLossOrder := map[string]interface{}{
 				"attachAlgoClOrdId": fmt.Sprintf("sl_%s_%d", symbol, time.Now().UnixMilli()),
-				"slTriggerPx": fmt.Sprintf("%.8f", stopLoss),
+				"slTriggerPx": t.formatTriggerPrice(symbol, stopLoss),
 				"slTriggerPxType": "last",  // 触发价格类型：last表示最新价
 				"slOrdPx": "-1",            // -1表示市价单（止损时立即以市价成交）
 				"sz": quantityStr,
@@ -971,7 +874,7 @@ func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int, sto
 		if takeProfit > 0 {
 			takeProfitOrder := map[string]interface{}{
 				"attachAlgoClOrdId": fmt.Sprintf("tp_%s_%d", symbol, time.Now().UnixMilli()),
-				"tpTriggerPx": fmt.Sprintf("%.8f", takeProfit),
+				"tpTriggerPx": t.formatTriggerPrice(symbol, takeProfit),
 				"tpTriggerPxType": "last",  // 触发价格类型：last表示最新价
 				"tpOrdPx": "-1",            // -1表示市价单（止盈时立即以市价成交）
 				"sz": quantityStr,
@@ -1007,12 +910,9 @@ func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int, sto
 		// 检查账户余额
 		balance, balanceErr := t.GetBalance()
 		if balanceErr == nil {
-			if totalEq, ok := balance["totalEquity"].(float64); ok {
-				log.Printf("  💰 账户净值: %.2f USDT", totalEq)
-			}
-			if available, ok := balance["availableBalance"].(float64); ok {
-				log.Printf("  💰 可用余额: %.2f USDT", available)
-			}
+			log.Printf("  💰 账户净值: %.2f USDT", balance.TotalEquity)
+			available := balance.AvailableBalance
+			log.Printf("  💰 可用余额: %.2f USDT", available)
 		}
 		
 		return nil, fmt.Errorf("开空仓失败: %w", err)
@@ -1053,31 +953,28 @@ func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int, sto
 		// 检查账户余额
 		balance, balanceErr := t.GetBalance()
 		if balanceErr == nil {
-			if totalEq, ok := balance["totalEquity"].(float64); ok {
-				log.Printf("  💰 账户净值: %.2f USDT", totalEq)
-			}
-			if available, ok := balance["availableBalance"].(float64); ok {
-				log.Printf("  💰 可用余额: %.2f USDT", available)
-				// 计算所需保证金（如果获取到当前价格）
-				if priceErr == nil && currentPrice > 0 {
-					positionValue := quantity * currentPrice
-					marginRequired := positionValue / float64(leverage)
-					log.Printf("  💰 所需保证金: %.2f USDT (仓位价值=%.2f / 杠杆=%d)", 
-						marginRequired, positionValue, leverage)
-					if available < marginRequired {
-						log.Printf("  ⚠️ 可用余额不足！需要 %.2f USDT，但只有 %.2f USDT", marginRequired, available)
-					}
-					
-					// 检查止损是否合理（如果设置了止损）
-					if stopLoss > 0 {
-						// 计算爆仓价（做空）
-						liquidationPrice := currentPrice * (1 + 1.0/float64(leverage))
-						log.Printf("  💰 当前价格: %.4f, 爆仓价: %.4f, 止损价: %.4f", 
-							currentPrice, liquidationPrice, stopLoss)
-						if stopLoss >= liquidationPrice {
-							log.Printf("  ⚠️ 止损价高于或等于爆仓价！止损价必须在爆仓价下方")
-							log.Printf("     做空时: 止损价必须 < 爆仓价 (%.4f)", liquidationPrice)
-						}
+			log.Printf("  💰 账户净值: %.2f USDT", balance.TotalEquity)
+			available := balance.AvailableBalance
+			log.Printf("  💰 可用余额: %.2f USDT", available)
+			// 计算所需保证金（如果获取到当前价格）
+			if priceErr == nil && currentPrice > 0 {
+				positionValue := quantity * currentPrice
+				marginRequired := positionValue / float64(leverage)
+				log.Printf("  💰 所需保证金: %.2f USDT (仓位价值=%.2f / 杠杆=%d)", 
+					marginRequired, positionValue, leverage)
+				if available < marginRequired {
+					log.Printf("  ⚠️ 可用余额不足！需要 %.2f USDT，但只有 %.2f USDT", marginRequired, available)
+				}
+				
+				// 检查止损是否合理（如果设置了止损）
+				if stopLoss > 0 {
+					// 计算爆仓价（做空）
+					liquidationPrice := currentPrice * (1 + 1.0/float64(leverage))
+					log.Printf("  💰 当前价格: %.4f, 爆仓价: %.4f, 止损价: %.4f", 
+						currentPrice, liquidationPrice, stopLoss)
+					if stopLoss >= liquidationPrice {
+						log.Printf("  ⚠️ 止损价高于或等于爆仓价！止损价必须在爆仓价下方")
+						log.Printf("     做空时: 止损价必须 < 爆仓价 (%.4f)", liquidationPrice)
 					}
 				}
 			}
@@ -1115,15 +1012,15 @@ func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int, sto
 	log.Printf("✓ 开空仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %s", order.OrdID)
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrdID
-	result["symbol"] = symbol
-	result["status"] = "filled"
-	return result, nil
+	return &OrderResult{
+		OrderID: order.OrdID,
+		Symbol:  symbol,
+		Status:  "filled",
+	}, nil
 }
 
 // CloseLong 平多仓
-func (t *OKXTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *OKXTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -1131,11 +1028,8 @@ func (t *OKXTrader) CloseLong(symbol string, quantity float64) (map[string]inter
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, "long"); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
@@ -1196,15 +1090,15 @@ func (t *OKXTrader) CloseLong(symbol string, quantity float64) (map[string]inter
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrdID
-	result["symbol"] = symbol
-	result["status"] = "filled"
-	return result, nil
+	return &OrderResult{
+		OrderID: order.OrdID,
+		Symbol:  symbol,
+		Status:  "filled",
+	}, nil
 }
 
 // CloseShort 平空仓
-func (t *OKXTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *OKXTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -1212,11 +1106,8 @@ func (t *OKXTrader) CloseShort(symbol string, quantity float64) (map[string]inte
 			return nil, err
 		}
 
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				quantity = -pos["positionAmt"].(float64) // 空仓数量是负的，取绝对值
-				break
-			}
+		if pos, ok := FindPosition(positions, symbol, "short"); ok {
+			quantity = pos.Quantity
 		}
 
 		if quantity == 0 {
@@ -1277,13 +1168,362 @@ func (t *OKXTrader) CloseShort(symbol string, quantity float64) (map[string]inte
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrdID
-	result["symbol"] = symbol
-	result["status"] = "filled"
+	return &OrderResult{
+		OrderID: order.OrdID,
+		Symbol:  symbol,
+		Status:  "filled",
+	}, nil
+}
+
+// PlaceEntryOrder 按订单类型开仓（ordType: limit/post_only/ioc，post_only 会立即成交时被交易所撤单）
+func (t *OKXTrader) PlaceEntryOrder(order EntryOrder) (*OrderResult, error) {
+	if err := order.validate(); err != nil {
+		return nil, err
+	}
+	if order.OrderType == OrderTypeMarket {
+		return order.openMarket(t)
+	}
+
+	if err := t.SetLeverageWithPosSide(order.Symbol, order.Leverage, order.Side); err != nil {
+		return nil, err
+	}
+
+	instID := t.convertSymbolToInstID(order.Symbol)
+	quantityStr, err := t.FormatQuantity(order.Symbol, order.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	priceStr, err := t.formatPrice(order.Symbol, order.Price)
+	if err != nil {
+		return nil, err
+	}
+
+	side := "buy"
+	if order.Side == "short" {
+		side = "sell"
+	}
+	reqBody := map[string]interface{}{
+		"instId":  instID,
+		"tdMode":  "isolated",
+		"side":    side,
+		"ordType": order.OrderType, // limit / post_only / ioc 与OKX ordType 一致
+		"sz":      quantityStr,
+		"px":      priceStr,
+		"posSide": order.Side,
+	}
+
+	data, err := t.makeRequest("POST", "/api/v5/trade/order", reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("%s开仓失败: %w", OrderTypeLabel(order.OrderType), err)
+	}
+
+	var orderResp []struct {
+		OrdID string `json:"ordId"`
+		SCode string `json:"sCode"`
+		SMsg  string `json:"sMsg"`
+	}
+	if err := json.Unmarshal(data, &orderResp); err != nil {
+		return nil, fmt.Errorf("解析订单响应失败: %w", err)
+	}
+	if len(orderResp) == 0 {
+		return nil, fmt.Errorf("订单响应为空，原始响应: %s", string(data))
+	}
+	if orderResp[0].SCode != "0" {
+		return nil, fmt.Errorf("%s开仓失败: %s - %s", OrderTypeLabel(order.OrderType), orderResp[0].SCode, orderResp[0].SMsg)
+	}
+
+	log.Printf("✓ %s已提交: %s %s 价格: %s 数量: %s 订单ID: %s", OrderTypeLabel(order.OrderType), order.Symbol, order.Side, priceStr, quantityStr, orderResp[0].OrdID)
+
+	// 下单响应不包含成交信息，查询一次订单状态（IOC/post_only 可能已成交或被撤单）
+	result, err := t.GetOrder(order.Symbol, orderResp[0].OrdID)
+	if err != nil {
+		log.Printf("  ⚠ 查询订单状态失败，按挂单处理: %v", err)
+		price, _ := strconv.ParseFloat(priceStr, 64)
+		quantity, _ := strconv.ParseFloat(quantityStr, 64)
+		return &OrderResult{
+			OrderID:  orderResp[0].OrdID,
+			Symbol:   order.Symbol,
+			Status:   OrderStatusOpen,
+			Price:    price,
+			Quantity: quantity,
+		}, nil
+	}
+	return result, nil
+}
+
+// GetOrder 查询订单
+func (t *OKXTrader) GetOrder(symbol, orderID string) (*OrderResult, error) {
+	instID := t.convertSymbolToInstID(symbol)
+	data, err := t.makeRequest("GET", fmt.Sprintf("/api/v5/trade/order?instId=%s&ordId=%s", instID, orderID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	var orders []struct {
+		OrdID     string `json:"ordId"`
+		State     string `json:"state"`
+		Px        string `json:"px"`
+		Sz        string `json:"sz"`
+		AvgPx     string `json:"avgPx"`
+		AccFillSz string `json:"accFillSz"`
+		Fee       string `json:"fee"`
+	}
+	if err := json.Unmarshal(data, &orders); err != nil {
+		return nil, fmt.Errorf("解析订单数据失败: %w", err)
+	}
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("订单不存在: %s", orderID)
+	}
+
+	order := orders[0]
+	result := &OrderResult{
+		OrderID: order.OrdID,
+		Symbol:  symbol,
+		Status:  okxOrderStatus(order.State),
+	}
+	result.Price, _ = strconv.ParseFloat(order.Px, 64)
+	result.Quantity, _ = strconv.ParseFloat(order.Sz, 64)
+	result.AvgPrice, _ = strconv.ParseFloat(order.AvgPx, 64)
+	result.ExecutedQty, _ = strconv.ParseFloat(order.AccFillSz, 64)
+	// OKX手续费为负数表示扣除
+	if fee, err := strconv.ParseFloat(order.Fee, 64); err == nil {
+		result.Fee = -fee
+	}
 	return result, nil
 }
 
+// CancelOrder 撤销指定订单
+func (t *OKXTrader) CancelOrder(symbol, orderID string) error {
+	cancelBody := map[string]interface{}{
+		"instId": t.convertSymbolToInstID(symbol),
+		"ordId":  orderID,
+	}
+	data, err := t.makeRequest("POST", "/api/v5/trade/cancel-order", cancelBody)
+	if err != nil {
+		return fmt.Errorf("撤销订单失败: %w", err)
+	}
+
+	var cancelResp []struct {
+		SCode string `json:"sCode"`
+		SMsg  string `json:"sMsg"`
+	}
+	if err := json.Unmarshal(data, &cancelResp); err == nil && len(cancelResp) > 0 && cancelResp[0].SCode != "0" {
+		return fmt.Errorf("撤销订单失败: %s - %s", cancelResp[0].SCode, cancelResp[0].SMsg)
+	}
+	log.Printf("  ✓ 已撤销 %s 订单 %s", symbol, orderID)
+	return nil
+}
+
+// GetOpenOrders 查询未完成的挂单（普通委托和止盈止损等策略委托）
+func (t *OKXTrader) GetOpenOrders(symbol string) ([]OpenOrder, error) {
+	query := "instType=SWAP"
+	if symbol != "" {
+		query = "instId=" + t.convertSymbolToInstID(symbol)
+	}
+
+	data, err := t.makeRequest("GET", "/api/v5/trade/orders-pending?"+query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取未完成订单失败: %w", err)
+	}
+	var pending []struct {
+		OrdID      string `json:"ordId"`
+		InstID     string `json:"instId"`
+		Side       string `json:"side"`
+		PosSide    string `json:"posSide"`
+		OrdType    string `json:"ordType"`
+		Px         string `json:"px"`
+		Sz         string `json:"sz"`
+		AccFillSz  string `json:"accFillSz"`
+		ReduceOnly string `json:"reduceOnly"`
+		CTime      string `json:"cTime"`
+	}
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("解析订单列表失败: %w", err)
+	}
+
+	var orders []OpenOrder
+	for _, order := range pending {
+		open := OpenOrder{
+			OrderID:      order.OrdID,
+			Symbol:       strings.ReplaceAll(order.InstID, "-USDT-SWAP", "USDT"),
+			Side:         order.Side,
+			PositionSide: order.PosSide,
+			Type:         OpenOrderTypeLimit,
+			ReduceOnly:   order.ReduceOnly == "true",
+			Time:         parseOKXTime(order.CTime),
+		}
+		if order.OrdType == "market" {
+			open.Type = OpenOrderTypeMarket
+		}
+		open.Price, _ = strconv.ParseFloat(order.Px, 64)
+		open.Quantity, _ = strconv.ParseFloat(order.Sz, 64)
+		open.ExecutedQty, _ = strconv.ParseFloat(order.AccFillSz, 64)
+		if open.PositionSide != "long" && open.PositionSide != "short" {
+			open.PositionSide = inferPositionSide(open.Side, open.ReduceOnly)
+		}
+		orders = append(orders, open)
+	}
+
+	// 策略委托需要按类型分别查询
+	for _, ordType := range []string{"conditional,oco", "trigger", "move_order_stop"} {
+		data, err := t.makeRequest("GET", fmt.Sprintf("/api/v5/trade/orders-algo-pending?ordType=%s&%s", ordType, query), nil)
+		if err != nil {
+			return nil, fmt.Errorf("获取策略委托失败: %w", err)
+		}
+		var algos []struct {
+			AlgoID        string `json:"algoId"`
+			InstID        string `json:"instId"`
+			Side          string `json:"side"`
+			PosSide       string `json:"posSide"`
+			OrdType       string `json:"ordType"`
+			Sz            string `json:"sz"`
+			SlTriggerPx   string `json:"slTriggerPx"`
+			TpTriggerPx   string `json:"tpTriggerPx"`
+			TriggerPx     string `json:"triggerPx"`
+			OrdPx         string `json:"ordPx"`
+			MoveTriggerPx string `json:"moveTriggerPx"`
+			ReduceOnly    string `json:"reduceOnly"`
+			CTime         string `json:"cTime"`
+		}
+		if err := json.Unmarshal(data, &algos); err != nil {
+			return nil, fmt.Errorf("解析策略委托失败: %w", err)
+		}
+
+		for _, algo := range algos {
+			base := OpenOrder{
+				OrderID:      algo.AlgoID,
+				Symbol:       strings.ReplaceAll(algo.InstID, "-USDT-SWAP", "USDT"),
+				Side:         algo.Side,
+				PositionSide: algo.PosSide,
+				ReduceOnly:   algo.ReduceOnly == "true" || algo.OrdType != "trigger",
+				IsAlgo:       true,
+				Time:         parseOKXTime(algo.CTime),
+			}
+			base.Quantity, _ = strconv.ParseFloat(algo.Sz, 64)
+			if base.PositionSide != "long" && base.PositionSide != "short" {
+				base.PositionSide = inferPositionSide(base.Side, base.ReduceOnly)
+			}
+
+			switch algo.OrdType {
+			case "move_order_stop":
+				open := base
+				open.Type = OpenOrderTypeTrailingStop
+				open.StopPrice, _ = strconv.ParseFloat(algo.MoveTriggerPx, 64)
+				orders = append(orders, open)
+			case "trigger":
+				open := base
+				open.Type = OpenOrderTypeTrigger
+				open.StopPrice, _ = strconv.ParseFloat(algo.TriggerPx, 64)
+				open.Price, _ = strconv.ParseFloat(algo.OrdPx, 64)
+				orders = append(orders, open)
+			default:
+				// 止盈止损/OCO单可能同时带止损和止盈触发价，拆成两条（订单ID相同）
+				if sl, _ := strconv.ParseFloat(algo.SlTriggerPx, 64); sl > 0 {
+					open := base
+					open.Type = OpenOrderTypeStopLoss
+					open.StopPrice = sl
+					orders = append(orders, open)
+				}
+				if tp, _ := strconv.ParseFloat(algo.TpTriggerPx, 64); tp > 0 {
+					open := base
+					open.Type = OpenOrderTypeTakeProfit
+					open.StopPrice = tp
+					orders = append(orders, open)
+				}
+			}
+		}
+	}
+	return orders, nil
+}
+
+// GetUserTrades 获取成交明细（同一订单的多笔成交合并为一条，按时间升序）
+func (t *OKXTrader) GetUserTrades(symbol string, startTime time.Time) ([]Fill, error) {
+	instID := t.convertSymbolToInstID(symbol)
+	var raw []struct {
+		OrdID   string `json:"ordId"`
+		BillID  string `json:"billId"`
+		FillPx  string `json:"fillPx"`
+		FillSz  string `json:"fillSz"`
+		Side    string `json:"side"`
+		PosSide string `json:"posSide"`
+		Fee     string `json:"fee"`
+		FillPnl string `json:"fillPnl"`
+		Ts      string `json:"ts"`
+	}
+
+	// 接口按时间倒序返回，每页最多100条，用 after=billId 向前翻页
+	after := ""
+	for {
+		path := fmt.Sprintf("/api/v5/trade/fills-history?instType=SWAP&instId=%s&begin=%d&limit=100", instID, startTime.UnixMilli())
+		if after != "" {
+			path += "&after=" + after
+		}
+		data, err := t.makeRequest("GET", path, nil)
+		if err != nil {
+			return nil, fmt.Errorf("获取成交历史失败: %w", err)
+		}
+		page := raw[:0:0]
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("解析成交历史失败: %w", err)
+		}
+		raw = append(raw, page...)
+		if len(page) < 100 {
+			break
+		}
+		after = page[len(page)-1].BillID
+	}
+
+	var fills []Fill
+	for _, trade := range raw {
+		price, _ := strconv.ParseFloat(trade.FillPx, 64)
+		qty, _ := strconv.ParseFloat(trade.FillSz, 64)
+		fee, _ := strconv.ParseFloat(trade.Fee, 64)
+		pnl, _ := strconv.ParseFloat(trade.FillPnl, 64)
+		// OKX手续费为负数表示扣除
+		fee = -fee
+
+		side := trade.PosSide
+		isClose := (side == "long" && trade.Side == "sell") || (side == "short" && trade.Side == "buy")
+		if side != "long" && side != "short" {
+			// 单向持仓模式：有已实现盈亏的成交视为平仓
+			isClose = pnl != 0
+			side = inferPositionSide(trade.Side, isClose)
+		}
+		fills = append(fills, Fill{
+			OrderID:     trade.OrdID,
+			Symbol:      symbol,
+			Side:        side,
+			IsClose:     isClose,
+			Price:       price,
+			Quantity:    qty,
+			Fee:         fee,
+			RealizedPnL: pnl,
+			Time:        parseOKXTime(trade.Ts),
+		})
+	}
+	return mergeFills(fills), nil
+}
+
+// parseOKXTime 解析OKX毫秒时间戳
+func parseOKXTime(ms string) time.Time {
+	ts, _ := strconv.ParseInt(ms, 10, 64)
+	return time.UnixMilli(ts)
+}
+
+// okxOrderStatus OKX订单状态转换为统一状态
+func okxOrderStatus(state string) string {
+	switch state {
+	case "live":
+		return OrderStatusOpen
+	case "partially_filled":
+		return OrderStatusPartiallyFilled
+	case "filled":
+		return OrderStatusFilled
+	default: // canceled / mmp_canceled
+		return OrderStatusCanceled
+	}
+}
+
 // CancelStopLossOrders 仅取消止损单
 func (t *OKXTrader) CancelStopLossOrders(symbol string) error {
 	instID := t.convertSymbolToInstID(symbol)
@@ -1377,6 +1617,9 @@ func (t *OKXTrader) CancelTakeProfitOrders(symbol string) error {
 		}
 	}
 
+	// 分批止盈单为策略委托（conditional，只有止盈触发价）
+	canceledCount += t.cancelTakeProfitAlgos(instID)
+
 	if canceledCount == 0 {
 		log.Printf("  ℹ %s 没有止盈单需要取消", symbol)
 	} else {
@@ -1386,6 +1629,43 @@ func (t *OKXTrader) CancelTakeProfitOrders(symbol string) error {
 	return nil
 }
 
+// cancelTakeProfitAlgos 取消只带止盈触发价的策略委托（SetPartialTakeProfit 挂出的分批止盈），返回取消数量
+func (t *OKXTrader) cancelTakeProfitAlgos(instID string) int {
+	data, err := t.makeRequest("GET", fmt.Sprintf("/api/v5/trade/orders-algo-pending?ordType=conditional&instId=%s", instID), nil)
+	if err != nil {
+		log.Printf("  ⚠ 获取止盈策略委托失败: %v", err)
+		return 0
+	}
+
+	var algos []struct {
+		AlgoID      string `json:"algoId"`
+		SlTriggerPx string `json:"slTriggerPx"`
+		TpTriggerPx string `json:"tpTriggerPx"`
+	}
+	if err := json.Unmarshal(data, &algos); err != nil {
+		log.Printf("  ⚠ 解析止盈策略委托失败: %v", err)
+		return 0
+	}
+
+	var cancelBody []map[string]interface{}
+	for _, algo := range algos {
+		if algo.TpTriggerPx != "" && algo.SlTriggerPx == "" {
+			cancelBody = append(cancelBody, map[string]interface{}{
+				"algoId": algo.AlgoID,
+				"instId": instID,
+			})
+		}
+	}
+	if len(cancelBody) == 0 {
+		return 0
+	}
+	if _, err := t.makeRequest("POST", "/api/v5/trade/cancel-algos", cancelBody); err != nil {
+		log.Printf("  ⚠ 取消止盈策略委托失败: %v", err)
+		return 0
+	}
+	return len(cancelBody)
+}
+
 // CancelAllOrders 取消该币种的所有挂单
 func (t *OKXTrader) CancelAllOrders(symbol string) error {
 	instID := t.convertSymbolToInstID(symbol)
@@ -1475,6 +1755,7 @@ func (t *OKXTrader) CancelStopOrders(symbol string) error {
 			log.Printf("  ✓ 已取消 %s 的止盈/止损单 (订单ID: %s, 类型: %s)", symbol, order.OrdID, order.OrdType)
 		}
 	}
+	canceledCount += t.cancelTakeProfitAlgos(instID)
 
 	if canceledCount == 0 {
 		log.Printf("  ℹ %s 没有止盈/止损单需要取消", symbol)
@@ -1536,7 +1817,7 @@ func (t *OKXTrader) SetStopLoss(symbol string, positionSide string, quantity, st
 		"side":    side,
 		"ordType": "stop_market",
 		"sz":      quantityStr,
-		"slTriggerPx": fmt.Sprintf("%.8f", stopPrice),
+		"slTriggerPx": t.formatTriggerPrice(symbol, stopPrice),
 		"slTriggerPxType": "last",
 		"posSide": positionSide,
 		"reduceOnly": true,
@@ -1573,7 +1854,7 @@ func (t *OKXTrader) SetTakeProfit(symbol string, positionSide string, quantity,
 		"side":    side,
 		"ordType": "take_profit_market",
 		"sz":      quantityStr,
-		"tpTriggerPx": fmt.Sprintf("%.8f", takeProfitPrice),
+		"tpTriggerPx": t.formatTriggerPrice(symbol, takeProfitPrice),
 		"tpTriggerPxType": "last",
 		"posSide": positionSide,
 		"reduceOnly": true,
@@ -1588,6 +1869,111 @@ func (t *OKXTrader) SetTakeProfit(symbol string, positionSide string, quantity,
 	return nil
 }
 
+// SetPartialTakeProfit 按数量设置止盈单（分批止盈使用，策略委托 conditional，触发后市价只减仓）
+func (t *OKXTrader) SetPartialTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	instID := t.convertSymbolToInstID(symbol)
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+
+	side := "sell"
+	if positionSide == "SHORT" {
+		side = "buy"
+	}
+
+	reqBody := map[string]interface{}{
+		"instId":          instID,
+		"tdMode":          "isolated",
+		"side":            side,
+		"posSide":         strings.ToLower(positionSide),
+		"ordType":         "conditional",
+		"sz":              quantityStr,
+		"tpTriggerPx":     t.formatTriggerPrice(symbol, takeProfitPrice),
+		"tpOrdPx":         "-1",
+		"tpTriggerPxType": "last",
+		"reduceOnly":      true,
+	}
+
+	if _, err := t.makeRequest("POST", "/api/v5/trade/order-algo", reqBody); err != nil {
+		return fmt.Errorf("设置分批止盈失败: %w", err)
+	}
+
+	log.Printf("  分批止盈设置: %.4f × %s", takeProfitPrice, quantityStr)
+	return nil
+}
+
+// SetTrailingStop 设置跟踪止损单（策略委托 move_order_stop，callbackRatio 为小数比例）
+func (t *OKXTrader) SetTrailingStop(symbol string, positionSide string, quantity, callbackRate, activationPrice float64) error {
+	instID := t.convertSymbolToInstID(symbol)
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+
+	side := "sell"
+	if positionSide == "SHORT" {
+		side = "buy"
+	}
+
+	reqBody := map[string]interface{}{
+		"instId":        instID,
+		"tdMode":        "isolated",
+		"side":          side,
+		"posSide":       strings.ToLower(positionSide),
+		"ordType":       "move_order_stop",
+		"sz":            quantityStr,
+		"callbackRatio": strconv.FormatFloat(callbackRate/100, 'f', 4, 64),
+		"reduceOnly":    true,
+	}
+	if activationPrice > 0 {
+		reqBody["activePx"] = t.formatTriggerPrice(symbol, activationPrice)
+	}
+
+	if _, err := t.makeRequest("POST", "/api/v5/trade/order-algo", reqBody); err != nil {
+		return fmt.Errorf("设置跟踪止损失败: %w", err)
+	}
+
+	log.Printf("  跟踪止损设置: 回调 %.2f%%, 激活价 %.4f", callbackRate, activationPrice)
+	return nil
+}
+
+// CancelTrailingStopOrders 仅取消跟踪止损策略委托
+func (t *OKXTrader) CancelTrailingStopOrders(symbol string) error {
+	instID := t.convertSymbolToInstID(symbol)
+
+	data, err := t.makeRequest("GET", fmt.Sprintf("/api/v5/trade/orders-algo-pending?ordType=move_order_stop&instId=%s", instID), nil)
+	if err != nil {
+		return fmt.Errorf("获取策略委托失败: %w", err)
+	}
+
+	var algos []struct {
+		AlgoID string `json:"algoId"`
+	}
+	if err := json.Unmarshal(data, &algos); err != nil {
+		return fmt.Errorf("解析策略委托失败: %w", err)
+	}
+	if len(algos) == 0 {
+		return nil
+	}
+
+	cancelBody := make([]map[string]interface{}, 0, len(algos))
+	for _, algo := range algos {
+		cancelBody = append(cancelBody, map[string]interface{}{
+			"algoId": algo.AlgoID,
+			"instId": instID,
+		})
+	}
+	if _, err := t.makeRequest("POST", "/api/v5/trade/cancel-algos", cancelBody); err != nil {
+		return fmt.Errorf("取消跟踪止损单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 的 %d 个跟踪止损单", symbol, len(algos))
+	return nil
+}
+
 // GetMinNotional 获取最小名义价值（OKX要求）
 func (t *OKXTrader) GetMinNotional(symbol string) float64 {
 	// OKX合约最小名义价值通常是 5 USDT
@@ -1608,10 +1994,10 @@ func (t *OKXTrader) CheckMinNotional(symbol string, quantity float64) error {
 	balance, err := t.GetBalance()
 	if err == nil {
 		totalEquity := 0.0
-		if equity, ok := balance["totalEquity"].(float64); ok && equity > 0 {
-			totalEquity = equity
-		} else if wallet, ok := balance["totalWalletBalance"].(float64); ok {
-			totalEquity = wallet
+		if balance.TotalEquity > 0 {
+			totalEquity = balance.TotalEquity
+		} else {
+			totalEquity = balance.TotalWalletBalance
 		}
 
 		// V1.50版本：小账户（<10 USDT）放宽最小订单金额限制
@@ -1651,137 +2037,90 @@ func (t *OKXTrader) CheckMinNotional(symbol string, quantity float64) error {
 	return nil
 }
 
-// GetSymbolPrecision 获取交易对的数量精度
-func (t *OKXTrader) GetSymbolPrecision(symbol string) (int, error) {
-	// 先检查缓存
-	t.precisionMutex.RLock()
-	if precision, ok := t.symbolPrecision[symbol]; ok {
-		t.precisionMutex.RUnlock()
-		return precision, nil
-	}
-	t.precisionMutex.RUnlock()
-
-	instID := t.convertSymbolToInstID(symbol)
-	
-	// 获取交易对信息
-	data, err := t.makeRequest("GET", fmt.Sprintf("/api/v5/public/instruments?instType=SWAP&instId=%s", instID), nil)
+// loadInstruments 加载全部USDT永续合约规则（公共接口，数量单位为张）
+func (t *OKXTrader) loadInstruments() (map[string]Instrument, error) {
+	body, err := t.rest.getPublic("/api/v5/public/instruments", url.Values{"instType": {"SWAP"}})
 	if err != nil {
-		log.Printf("  ⚠ %s 获取精度信息失败，使用默认精度3: %v", symbol, err)
-		return 3, nil
+		return nil, err
 	}
+	var resp struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			InstID    string `json:"instId"`
+			SettleCcy string `json:"settleCcy"`
+			TickSz    string `json:"tickSz"`
+			LotSz     string `json:"lotSz"`
+			MinSz     string `json:"minSz"`
+			CtVal     string `json:"ctVal"`
+			Lever     string `json:"lever"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if resp.Code != "0" {
+		return nil, fmt.Errorf("OKX API错误: %s - %s", resp.Code, resp.Msg)
+	}
+
+	specs := make(map[string]Instrument, len(resp.Data))
+	for _, info := range resp.Data {
+		if info.SettleCcy != "USDT" {
+			continue
+		}
+		symbol := strings.TrimSuffix(info.InstID, "-USDT-SWAP") + "USDT"
+		spec := Instrument{Symbol: symbol}
+		spec.TickSize, _ = strconv.ParseFloat(info.TickSz, 64)
+		spec.QtyStep, _ = strconv.ParseFloat(info.LotSz, 64)
+		spec.MinQty, _ = strconv.ParseFloat(info.MinSz, 64)
+		spec.ContractSize, _ = strconv.ParseFloat(info.CtVal, 64)
+		spec.MaxLeverage = parseLeverage(info.Lever)
+		specs[symbol] = spec
+	}
+	return specs, nil
+}
 
-	var instruments []struct {
-		LotSz string `json:"lotSz"` // 数量精度
-	}
+// GetInstrument 获取合约交易规则
+func (t *OKXTrader) GetInstrument(symbol string) (*Instrument, error) {
+	return t.instruments.get(symbol)
+}
 
-	if err := json.Unmarshal(data, &instruments); err != nil {
-		log.Printf("  ⚠ %s 解析精度信息失败，使用默认精度3: %v", symbol, err)
-		return 3, nil
-	}
+// defaultOKXLotSz 未获取到合约规则时使用的默认lotSz
+const defaultOKXLotSz = 0.0001
 
-	if len(instruments) == 0 {
-		log.Printf("  ⚠ %s 未找到精度信息，使用默认精度3", symbol)
-		return 3, nil
+// getLotSz 获取交易对的lotSz（最小数量单位），获取失败时使用默认值
+func (t *OKXTrader) getLotSz(symbol string) float64 {
+	inst, err := t.instruments.get(symbol)
+	if err != nil || inst.QtyStep <= 0 {
+		log.Printf("  ⚠️ %s 获取lotSz失败，使用默认值%.4f", symbol, defaultOKXLotSz)
+		return defaultOKXLotSz
 	}
-
-	// 从lotSz计算精度（例如 "0.001" -> 3）
-	lotSz := instruments[0].LotSz
-	precision := calculatePrecisionFromStepSize(lotSz)
-
-	// 更新缓存
-	t.precisionMutex.Lock()
-	t.symbolPrecision[symbol] = precision
-	t.precisionMutex.Unlock()
-
-	log.Printf("  %s 数量精度: %d (lotSz: %s)", symbol, precision, lotSz)
-	return precision, nil
+	return inst.QtyStep
 }
 
-// GetSymbolLotSz 获取交易对的实际lotSz（最小数量单位）
-// V1.66版本：新增函数，用于获取实际的lotSz值，而不是精度
-// 带缓存机制，避免重复API调用
-func (t *OKXTrader) GetSymbolLotSz(symbol string) (float64, error) {
-	// 先检查缓存
-	t.lotSzMutex.RLock()
-	if lotSz, ok := t.symbolLotSz[symbol]; ok {
-		t.lotSzMutex.RUnlock()
-		return lotSz, nil
-	}
-	t.lotSzMutex.RUnlock()
-
-	instID := t.convertSymbolToInstID(symbol)
-	
-	// 获取交易对信息
-	data, err := t.makeRequest("GET", fmt.Sprintf("/api/v5/public/instruments?instType=SWAP&instId=%s", instID), nil)
+// formatPrice 按tickSz格式化限价
+func (t *OKXTrader) formatPrice(symbol string, price float64) (string, error) {
+	inst, err := t.instruments.get(symbol)
 	if err != nil {
-		log.Printf("  ⚠ %s 获取lotSz失败，使用默认值0.0001: %v", symbol, err)
-		// 缓存默认值，避免重复请求
-		t.lotSzMutex.Lock()
-		t.symbolLotSz[symbol] = 0.0001
-		t.lotSzMutex.Unlock()
-		return 0.0001, nil
-	}
-
-	var instruments []struct {
-		LotSz string `json:"lotSz"` // 数量精度
-	}
-
-	if err := json.Unmarshal(data, &instruments); err != nil {
-		log.Printf("  ⚠ %s 解析lotSz失败，使用默认值0.0001: %v", symbol, err)
-		// 缓存默认值
-		t.lotSzMutex.Lock()
-		t.symbolLotSz[symbol] = 0.0001
-		t.lotSzMutex.Unlock()
-		return 0.0001, nil
-	}
-
-	if len(instruments) == 0 {
-		log.Printf("  ⚠ %s 未找到lotSz信息，使用默认值0.0001", symbol)
-		// 缓存默认值
-		t.lotSzMutex.Lock()
-		t.symbolLotSz[symbol] = 0.0001
-		t.lotSzMutex.Unlock()
-		return 0.0001, nil
+		return "", err
 	}
+	return inst.FormatPrice(price), nil
+}
 
-	// 解析lotSz字符串为浮点数
-	lotSz, err := strconv.ParseFloat(instruments[0].LotSz, 64)
+// formatTriggerPrice 按tickSz格式化止盈止损触发价（获取规则失败时保留8位小数）
+func (t *OKXTrader) formatTriggerPrice(symbol string, price float64) string {
+	priceStr, err := t.formatPrice(symbol, price)
 	if err != nil {
-		log.Printf("  ⚠ %s 解析lotSz值失败 (%s)，使用默认值0.0001: %v", symbol, instruments[0].LotSz, err)
-		// 缓存默认值
-		t.lotSzMutex.Lock()
-		t.symbolLotSz[symbol] = 0.0001
-		t.lotSzMutex.Unlock()
-		return 0.0001, nil
+		return fmt.Sprintf("%.8f", price)
 	}
-
-	// 更新缓存
-	t.lotSzMutex.Lock()
-	t.symbolLotSz[symbol] = lotSz
-	t.lotSzMutex.Unlock()
-
-	log.Printf("  %s lotSz: %s (%.8f)", symbol, instruments[0].LotSz, lotSz)
-	return lotSz, nil
+	return priceStr
 }
 
 // FormatQuantity 格式化数量到正确的精度
 // V1.66版本：使用实际的lotSz进行向上取整，避免数量格式化后为0
 // 每个币种使用其实际的lotSz（最小数量单位）进行向上取整
 func (t *OKXTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
-	precision, err := t.GetSymbolPrecision(symbol)
-	if err != nil {
-		// 如果获取失败，使用默认格式（保留更多小数位，避免丢失精度）
-		return fmt.Sprintf("%.8f", quantity), nil
-	}
-
-	// V1.66版本：获取实际的lotSz（最小数量单位），而不是使用固定的0.0001
-	// 这样可以针对每个币种使用正确的精度
-	lotSz, err := t.GetSymbolLotSz(symbol)
-	if err != nil {
-		// 如果获取失败，使用默认值0.0001
-		lotSz = 0.0001
-		log.Printf("  ⚠️ %s 获取lotSz失败，使用默认值0.0001", symbol)
-	}
+	lotSz := t.getLotSz(symbol)
 
 	// 使用实际的lotSz进行向上取整
 	// 逻辑：
@@ -1798,17 +2137,16 @@ func (t *OKXTrader) FormatQuantity(symbol string, quantity float64) (string, err
 			quantity = lotSz
 		} else {
 			// 数量大于等于lotSz，向上取整到lotSz的倍数
-			rounded := math.Ceil(quantity / lotSz) * lotSz
-			if rounded != quantity {
+			rounded := math.Ceil(quantity / lotSz - 1e-9) * lotSz
+			if math.Abs(rounded-quantity) > 1e-12 {
 				log.Printf("  ⚠️ %s 数量 %.8f 向上取整到 lotSz %.8f 的倍数: %.8f", symbol, quantity, lotSz, rounded)
 			}
 			quantity = rounded
 		}
 	}
 
-	// 使用精度格式化
-	format := fmt.Sprintf("%%.%df", precision)
-	return fmt.Sprintf(format, quantity), nil
+	// 使用lotSz的精度格式化
+	return strconv.FormatFloat(quantity, 'f', stepPrecision(lotSz), 64), nil
 }
 
 // convertSymbolToInstID 转换交易对格式 (BTCUSDT -> BTC-USDT-SWAP)
@@ -1819,19 +2157,3 @@ func (t *OKXTrader) convertSymbolToInstID(symbol string) string {
 	return base + "-USDT-SWAP"
 }
 
-// calculatePrecisionFromStepSize 从stepSize计算精度
-func calculatePrecisionFromStepSize(stepSize string) int {
-	// 去除尾部的0
-	stepSize = strings.TrimRight(stepSize, "0")
-	stepSize = strings.TrimRight(stepSize, ".")
-
-	// 查找小数点
-	dotIndex := strings.Index(stepSize, ".")
-	if dotIndex == -1 {
-		return 0
-	}
-
-	// 返回小数点后的位数
-	return len(stepSize) - dotIndex - 1
-}
-
@@ -0,0 +1,1327 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 模拟盘默认参数
+const (
+	paperDefaultTakerFeeRate   = 0.0005 // 默认Taker费率 0.05%（与币安普通用户一致）
+	paperDefaultMakerFeeRate   = 0.0002 // 默认Maker费率 0.02%（限价挂单成交）
+	paperMaintenanceMarginRate = 0.005  // 维持保证金率 0.5%
+	paperTriggerCheckInterval  = 10 * time.Second
+	paperDefaultLeverage       = 5
+	paperDefaultPriceExchange  = "binance"
+	paperOrderTypeStopLoss     = "STOP_MARKET"
+	paperOrderTypeTakeProfit   = "TAKE_PROFIT_MARKET"
+	paperOrderTypeTrailingStop = "TRAILING_STOP_MARKET"
+	paperOrderTypeLimit        = "LIMIT" // 限价开仓挂单
+	paperExitReasonLiquidation = "liquidation"
+	paperExitReasonStopLoss    = "stop_loss"
+	paperExitReasonTakeProfit  = "take_profit"
+	paperExitReasonTrailing    = "trailing_stop"
+	paperExitReasonMarketClose = "market"
+	paperMaxFills              = 1000 // 保留的成交明细数量（用于交易台账对账）
+)
+
+// paperPosition 模拟持仓
+type paperPosition struct {
+	Symbol     string    `json:"symbol"`
+	Side       string    `json:"side"` // "long" or "short"
+	Quantity   float64   `json:"quantity"`
+	EntryPrice float64   `json:"entry_price"`
+	Leverage   int       `json:"leverage"`
+	Margin     float64   `json:"margin"`   // 占用的初始保证金
+	IsCross    bool      `json:"is_cross"` // 是否全仓
+	MarkPrice  float64   `json:"mark_price"`
+	OpenTime   time.Time `json:"open_time"`
+}
+
+// paperOrder 模拟挂单（止盈/止损触发单，或限价开仓单）
+type paperOrder struct {
+	OrderID      int64     `json:"order_id"`
+	Symbol       string    `json:"symbol"`
+	PositionSide string    `json:"position_side"` // "LONG" or "SHORT"
+	Type         string    `json:"type"`          // STOP_MARKET / TAKE_PROFIT_MARKET / TRAILING_STOP_MARKET / LIMIT
+	StopPrice    float64   `json:"stop_price"`    // 触发价（LIMIT 为限价，跟踪止损为当前触发价，未激活为0）
+	Quantity     float64   `json:"quantity"`      // 0 表示平掉全部持仓
+	CreateTime   time.Time `json:"create_time"`
+
+	Leverage int `json:"leverage,omitempty"` // 限价开仓单的杠杆
+
+	// 跟踪止损参数
+	CallbackRate    float64 `json:"callback_rate,omitempty"`    // 回调比例（%）
+	ActivationPrice float64 `json:"activation_price,omitempty"` // 激活价，0表示立即激活
+	PeakPrice       float64 `json:"peak_price,omitempty"`       // 激活后的极值价（多单最高价/空单最低价）
+}
+
+// paperState 模拟账户持久化状态
+type paperState struct {
+	WalletBalance float64                   `json:"wallet_balance"`
+	TotalFees     float64                   `json:"total_fees"`
+	RealizedPnL   float64                   `json:"realized_pnl"`
+	NextOrderID   int64                     `json:"next_order_id"`
+	Positions     map[string]*paperPosition `json:"positions"` // key: symbol_side
+	Orders        []*paperOrder             `json:"orders"`
+	Leverage      map[string]int            `json:"leverage"`     // symbol -> 杠杆
+	CrossMargin   map[string]bool           `json:"cross_margin"` // symbol -> 是否全仓
+	UpdatedAt     time.Time                 `json:"updated_at"`
+
+	Fills []Fill `json:"fills,omitempty"` // 最近的成交明细（开仓和平仓，含止盈止损/强平触发）
+}
+
+// PaperTrade 模拟盘平仓成交记录（部分平仓会产生多条，FullyClosed 标记持仓是否已全部平掉）
+type PaperTrade struct {
+	Symbol      string    `json:"symbol"`
+	Side        string    `json:"side"`
+	Quantity    float64   `json:"quantity"`
+	Leverage    int       `json:"leverage"`
+	EntryPrice  float64   `json:"entry_price"`
+	ExitPrice   float64   `json:"exit_price"`
+	PnL         float64   `json:"pnl"` // 已实现盈亏（不含手续费）
+	Fee         float64   `json:"fee"` // 平仓手续费
+	Reason      string    `json:"reason"`
+	FullyClosed bool      `json:"fully_closed"`
+	OpenTime    time.Time `json:"open_time"`
+	CloseTime   time.Time `json:"close_time"`
+}
+
+// PaperTrader 模拟盘交易器（本地撮合，不连接真实交易所）
+// 使用实时行情价格成交市价单，模拟手续费、杠杆、保证金、爆仓以及止盈止损触发
+type PaperTrader struct {
+	stateFile     string
+	priceExchange string  // 行情来源交易所（binance/okx/bybit/bitget/gate）
+	takerFeeRate  float64 // Taker手续费率
+	makerFeeRate  float64 // Maker手续费率（限价挂单成交）
+
+	state   *paperState
+	mu      sync.Mutex
+	monitor bool          // 触发单监控goroutine是否在运行
+	closed  bool          // 已关闭（交易员被删除），不再启动监控goroutine
+	stopCh  chan struct{} // 通知监控goroutine退出
+
+	// priceFunc 成交价格来源（默认使用 market.GetWithExchange）
+	priceFunc func(symbol string) (float64, error)
+	// tickerFunc 触发单检查使用的轻量价格来源（默认使用ticker接口）
+	tickerFunc func(symbol string) (float64, error)
+	// clock 成交时间来源（默认time.Now，回测时使用模拟时间）
+	clock func() time.Time
+	// manualPrices 价格由调用方通过 OnPrice 推送，不启动监控goroutine（回测使用）
+	manualPrices bool
+	// onTrade 平仓成交回调
+	onTrade func(trade PaperTrade)
+}
+
+// NewPaperTrader 创建模拟盘交易器
+// stateFile 为账户状态持久化文件，不存在时使用 initialBalance 初始化
+func NewPaperTrader(initialBalance float64, stateFile string, priceExchange string) (*PaperTrader, error) {
+	if priceExchange == "" || priceExchange == "paper" {
+		priceExchange = paperDefaultPriceExchange
+	}
+
+	t := &PaperTrader{
+		stateFile:     stateFile,
+		priceExchange: priceExchange,
+		takerFeeRate:  paperDefaultTakerFeeRate,
+		makerFeeRate:  paperDefaultMakerFeeRate,
+		stopCh:        make(chan struct{}),
+	}
+	t.priceFunc = t.fetchFillPrice
+	t.tickerFunc = t.fetchTickerPrice
+	t.clock = time.Now
+
+	state, err := loadPaperState(stateFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载模拟盘账户状态失败: %w", err)
+	}
+	if state == nil {
+		if initialBalance <= 0 {
+			return nil, fmt.Errorf("模拟盘初始资金必须大于0")
+		}
+		state = &paperState{
+			WalletBalance: initialBalance,
+			NextOrderID:   1,
+		}
+		log.Printf("🧪 创建新的模拟盘账户: 初始资金 %.2f USDT (状态文件: %s)", initialBalance, stateFile)
+	} else {
+		log.Printf("🧪 恢复模拟盘账户: 钱包余额 %.2f USDT, 持仓 %d 个, 挂单 %d 个", state.WalletBalance, len(state.Positions), len(state.Orders))
+	}
+	if state.Positions == nil {
+		state.Positions = make(map[string]*paperPosition)
+	}
+	if state.Leverage == nil {
+		state.Leverage = make(map[string]int)
+	}
+	if state.CrossMargin == nil {
+		state.CrossMargin = make(map[string]bool)
+	}
+	t.state = state
+
+	if err := t.saveLocked(); err != nil {
+		log.Printf("⚠️ 保存模拟盘账户状态失败: %v", err)
+	}
+
+	// 恢复时如果有持仓或挂单，启动触发单监控
+	if len(state.Positions) > 0 || len(state.Orders) > 0 {
+		t.ensureMonitorLocked()
+	}
+
+	log.Printf("✓ 模拟盘交易器初始化成功 (行情来源=%s, Taker费率=%.4f%%)", priceExchange, t.takerFeeRate*100)
+	return t, nil
+}
+
+// NewBacktestPaperTrader 创建回测使用的模拟交易器（不持久化、不拉取实时行情）
+// priceFunc 返回当前模拟时刻的成交价，clock 返回当前模拟时间；止盈止损由调用方通过 OnPrice 推送价格触发
+func NewBacktestPaperTrader(initialBalance float64, priceFunc func(symbol string) (float64, error), clock func() time.Time) *PaperTrader {
+	return &PaperTrader{
+		priceExchange: paperDefaultPriceExchange,
+		takerFeeRate:  paperDefaultTakerFeeRate,
+		makerFeeRate:  paperDefaultMakerFeeRate,
+		state: &paperState{
+			WalletBalance: initialBalance,
+			NextOrderID:   1,
+			Positions:     make(map[string]*paperPosition),
+			Leverage:      make(map[string]int),
+			CrossMargin:   make(map[string]bool),
+		},
+		priceFunc:    priceFunc,
+		tickerFunc:   priceFunc,
+		clock:        clock,
+		manualPrices: true,
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// PaperPriceExchanges 模拟盘支持的行情来源交易所
+var PaperPriceExchanges = []string{"binance", "okx", "bybit", "bitget", "gate"}
+
+// NormalizePaperPriceExchange 校验并规范化模拟盘行情来源（为空返回空字符串，表示使用默认的binance）
+func NormalizePaperPriceExchange(exchange string) (string, error) {
+	exchange = strings.ToLower(strings.TrimSpace(exchange))
+	if exchange == "" {
+		return "", nil
+	}
+	for _, supported := range PaperPriceExchanges {
+		if exchange == supported {
+			return exchange, nil
+		}
+	}
+	return "", fmt.Errorf("不支持的模拟盘行情来源: %s（支持 %s）", exchange, strings.Join(PaperPriceExchanges, "/"))
+}
+
+// Close 停止触发单监控goroutine（交易员被删除或进程退出时调用），账户状态已在每次变更时持久化
+func (t *PaperTrader) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	t.closed = true
+	close(t.stopCh)
+}
+
+// SetTradeHandler 设置平仓成交回调（回测统计使用）
+// 注意：回调在持锁时同步执行，回调内不能再调用 PaperTrader 的方法
+func (t *PaperTrader) SetTradeHandler(fn func(trade PaperTrade)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onTrade = fn
+}
+
+// PaperStateFile 模拟盘账户状态文件路径
+// 放在决策日志目录的子目录中：日志读取会跳过子目录，避免状态文件被当成决策记录解析或被过期清理删除
+func PaperStateFile(logDir string) string {
+	return filepath.Join(logDir, "paper", "account.json")
+}
+
+// SetTakerFeeRate 设置模拟Taker手续费率（如 0.0005 表示 0.05%）
+func (t *PaperTrader) SetTakerFeeRate(rate float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if rate >= 0 {
+		t.takerFeeRate = rate
+	}
+}
+
+// FeeSchedule 模拟盘手续费率（保本止损按实际模拟费率计算）
+func (t *PaperTrader) FeeSchedule() FeeSchedule {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return FeeSchedule{Maker: t.makerFeeRate, Taker: t.takerFeeRate}
+}
+
+// loadPaperState 从文件加载模拟盘状态（文件不存在时返回nil）
+func loadPaperState(stateFile string) (*paperState, error) {
+	if stateFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var state paperState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("解析状态文件失败: %w", err)
+	}
+	return &state, nil
+}
+
+// saveLocked 持久化账户状态（调用方需持有锁）
+func (t *PaperTrader) saveLocked() error {
+	if t.stateFile == "" {
+		return nil
+	}
+	t.state.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(t.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化状态失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.stateFile), 0755); err != nil {
+		return fmt.Errorf("创建状态目录失败: %w", err)
+	}
+	// 先写临时文件再重命名，避免写入中断导致状态文件损坏
+	tmpFile := t.stateFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	return os.Rename(tmpFile, t.stateFile)
+}
+
+// fetchFillPrice 获取成交价格（使用 market.GetWithExchange 的实时价格）
+func (t *PaperTrader) fetchFillPrice(symbol string) (float64, error) {
+	data, err := market.GetWithExchange(symbol, t.priceExchange)
+	if err != nil {
+		return 0, err
+	}
+	if data.CurrentPrice <= 0 {
+		return 0, fmt.Errorf("%s 价格无效: %.8f", symbol, data.CurrentPrice)
+	}
+	return data.CurrentPrice, nil
+}
+
+// fetchTickerPrice 获取最新成交价（轻量接口，用于触发单检查）
+func (t *PaperTrader) fetchTickerPrice(symbol string) (float64, error) {
+	symbol = market.Normalize(symbol)
+	if venueClient := market.NewExchangeClient(t.priceExchange); venueClient != nil {
+		return venueClient.GetCurrentPrice(symbol)
+	}
+	return market.NewAPIClient().GetCurrentPrice(symbol)
+}
+
+// sideLabel 持仓方向中文名称
+func sideLabel(side string) string {
+	if side == "short" {
+		return "空"
+	}
+	return "多"
+}
+
+// paperPositionKey 持仓key
+func paperPositionKey(symbol, side string) string {
+	return symbol + "_" + side
+}
+
+// unrealizedPnL 计算持仓未实现盈亏
+func (p *paperPosition) unrealizedPnL(price float64) float64 {
+	if p.Side == "long" {
+		return (price - p.EntryPrice) * p.Quantity
+	}
+	return (p.EntryPrice - price) * p.Quantity
+}
+
+// totalsLocked 计算账户汇总（未实现盈亏、占用保证金、维持保证金）
+func (t *PaperTrader) totalsLocked() (unrealized, margin, maintenance float64) {
+	for _, pos := range t.state.Positions {
+		unrealized += pos.unrealizedPnL(pos.MarkPrice)
+		margin += pos.Margin
+		maintenance += pos.Quantity * pos.MarkPrice * paperMaintenanceMarginRate
+	}
+	return
+}
+
+// liquidationPriceLocked 估算强平价格
+// 逐仓：仅使用仓位保证金；全仓：使用账户可用保证金作为缓冲
+func (t *PaperTrader) liquidationPriceLocked(pos *paperPosition) float64 {
+	if pos.Quantity <= 0 {
+		return 0
+	}
+	buffer := pos.Margin
+	if pos.IsCross {
+		unrealized, margin, _ := t.totalsLocked()
+		free := t.state.WalletBalance + unrealized - margin
+		// 其他持仓的未实现盈亏已计入free，这里扣除本仓位的部分避免重复计算
+		free -= pos.unrealizedPnL(pos.MarkPrice)
+		buffer = pos.Margin + math.Max(free, 0)
+	}
+	var liq float64
+	if pos.Side == "long" {
+		liq = (pos.EntryPrice*pos.Quantity - buffer) / (pos.Quantity * (1 - paperMaintenanceMarginRate))
+	} else {
+		liq = (pos.EntryPrice*pos.Quantity + buffer) / (pos.Quantity * (1 + paperMaintenanceMarginRate))
+	}
+	if liq < 0 {
+		return 0
+	}
+	return liq
+}
+
+// GetBalance 获取账户余额
+func (t *PaperTrader) GetBalance() (*Balance, error) {
+	t.refreshMarks()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	unrealized, margin, _ := t.totalsLocked()
+	available := t.state.WalletBalance + unrealized - margin
+	if available < 0 {
+		available = 0
+	}
+
+	return &Balance{
+		TotalWalletBalance:    t.state.WalletBalance,
+		AvailableBalance:      available,
+		TotalUnrealizedProfit: unrealized,
+	}, nil
+}
+
+// GetPositions 获取所有持仓
+func (t *PaperTrader) GetPositions() ([]Position, error) {
+	t.refreshMarks()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var result []Position
+	for _, pos := range t.state.Positions {
+		result = append(result, Position{
+			Symbol:           pos.Symbol,
+			Side:             pos.Side,
+			Quantity:         pos.Quantity,
+			EntryPrice:       pos.EntryPrice,
+			MarkPrice:        pos.MarkPrice,
+			UnrealizedProfit: pos.unrealizedPnL(pos.MarkPrice),
+			Leverage:         pos.Leverage,
+			LiquidationPrice: t.liquidationPriceLocked(pos),
+			Margin:           pos.Margin,
+		})
+	}
+	return result, nil
+}
+
+// OpenLong 开多仓
+func (t *PaperTrader) OpenLong(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
+	return t.openPosition(symbol, "long", quantity, leverage, stopLoss, takeProfit)
+}
+
+// OpenShort 开空仓
+func (t *PaperTrader) OpenShort(symbol string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
+	return t.openPosition(symbol, "short", quantity, leverage, stopLoss, takeProfit)
+}
+
+// openPosition 按市价开仓（同方向已有持仓时加仓并重新计算均价）
+func (t *PaperTrader) openPosition(symbol, side string, quantity float64, leverage int, stopLoss, takeProfit float64) (*OrderResult, error) {
+	symbol = market.Normalize(symbol)
+	if quantity <= 0 {
+		return nil, fmt.Errorf("开仓数量必须大于0: %.8f", quantity)
+	}
+
+	price, err := t.priceFunc(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 成交价格失败: %w", symbol, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 开仓前清理该方向的旧触发单（与真实交易所行为保持一致）
+	t.markLocked(symbol, price)
+	positionSide := strings.ToUpper(side)
+	orderID := t.nextOrderIDLocked()
+	fee, margin, err := t.openLocked(symbol, side, quantity, price, leverage, t.takerFeeRate, orderID, func() {
+		t.cancelOrdersLocked(symbol, positionSide, paperOrderTypeStopLoss)
+		t.cancelOrdersLocked(symbol, positionSide, paperOrderTypeTakeProfit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	leverage = t.state.Leverage[symbol]
+
+	log.Printf("🧪 [模拟盘] 开%s成功: %s 数量: %.8f 成交价: %.4f 杠杆: %dx 保证金: %.2f 手续费: %.4f",
+		sideLabel(side), symbol, quantity, price, leverage, margin, fee)
+
+	if stopLoss > 0 {
+		t.placeOrderLocked(symbol, positionSide, paperOrderTypeStopLoss, stopLoss, 0)
+	}
+	if takeProfit > 0 {
+		t.placeOrderLocked(symbol, positionSide, paperOrderTypeTakeProfit, takeProfit, 0)
+	}
+
+	if err := t.saveLocked(); err != nil {
+		log.Printf("⚠️ 保存模拟盘账户状态失败: %v", err)
+	}
+	t.ensureMonitorLocked()
+
+	return &OrderResult{
+		OrderID:     formatOrderID(orderID),
+		Symbol:      symbol,
+		Status:      "FILLED",
+		AvgPrice:    price,
+		ExecutedQty: quantity,
+		Fee:         fee,
+	}, nil
+}
+
+// openLocked 按指定价格开仓或加仓（调用方需持有锁，并已更新标记价格），返回手续费和占用保证金
+// beforeFill 在保证金校验通过后、成交前执行（用于清理旧触发单）
+func (t *PaperTrader) openLocked(symbol, side string, quantity, price float64, leverage int, feeRate float64, orderID int64, beforeFill func()) (float64, float64, error) {
+	if leverage <= 0 {
+		leverage = t.state.Leverage[symbol]
+		if leverage <= 0 {
+			leverage = paperDefaultLeverage
+		}
+	}
+	t.state.Leverage[symbol] = leverage
+
+	isCross, ok := t.state.CrossMargin[symbol]
+	if !ok {
+		isCross = true
+	}
+
+	notional := quantity * price
+	margin := notional / float64(leverage)
+	fee := notional * feeRate
+
+	unrealized, usedMargin, _ := t.totalsLocked()
+	available := t.state.WalletBalance + unrealized - usedMargin
+	if margin+fee > available {
+		return 0, 0, fmt.Errorf("模拟盘保证金不足: 需要 %.2f USDT（保证金 %.2f + 手续费 %.2f），可用 %.2f USDT", margin+fee, margin, fee, available)
+	}
+	if beforeFill != nil {
+		beforeFill()
+	}
+
+	key := paperPositionKey(symbol, side)
+	pos, exists := t.state.Positions[key]
+	if exists {
+		totalQty := pos.Quantity + quantity
+		pos.EntryPrice = (pos.EntryPrice*pos.Quantity + price*quantity) / totalQty
+		pos.Quantity = totalQty
+		pos.Margin += margin
+		pos.Leverage = leverage
+	} else {
+		pos = &paperPosition{
+			Symbol:     symbol,
+			Side:       side,
+			Quantity:   quantity,
+			EntryPrice: price,
+			Leverage:   leverage,
+			Margin:     margin,
+			IsCross:    isCross,
+			MarkPrice:  price,
+			OpenTime:   t.clock(),
+		}
+		t.state.Positions[key] = pos
+	}
+
+	t.state.WalletBalance -= fee
+	t.state.TotalFees += fee
+	t.recordFillLocked(Fill{
+		OrderID:  formatOrderID(orderID),
+		Symbol:   symbol,
+		Side:     side,
+		Price:    price,
+		Quantity: quantity,
+		Fee:      fee,
+		Time:     t.clock(),
+	})
+	return fee, margin, nil
+}
+
+// PlaceEntryOrder 按订单类型开仓
+// 限价价格可立即成交时：limit/ioc 按当前价以Taker费率成交，post_only 拒绝；否则 limit/post_only 挂单，ioc 直接撤销
+// 挂单在价格触及限价时按限价以Maker费率成交
+func (t *PaperTrader) PlaceEntryOrder(order EntryOrder) (*OrderResult, error) {
+	if err := order.validate(); err != nil {
+		return nil, err
+	}
+	if order.OrderType == OrderTypeMarket {
+		return order.openMarket(t)
+	}
+	symbol := market.Normalize(order.Symbol)
+
+	price, err := t.priceFunc(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 成交价格失败: %w", symbol, err)
+	}
+	marketable := (order.Side == "long" && price <= order.Price) || (order.Side == "short" && price >= order.Price)
+	if marketable {
+		switch order.OrderType {
+		case OrderTypePostOnly:
+			return nil, fmt.Errorf("只做Maker订单会立即成交，已被拒绝（当前价 %.4f，限价 %.4f）", price, order.Price)
+		default:
+			result, err := t.openPosition(symbol, order.Side, order.Quantity, order.Leverage, 0, 0)
+			if err != nil {
+				return nil, err
+			}
+			result.Status = OrderStatusFilled
+			result.Price = order.Price
+			result.Quantity = order.Quantity
+			return result, nil
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	orderID := t.nextOrderIDLocked()
+	result := &OrderResult{
+		OrderID:  formatOrderID(orderID),
+		Symbol:   symbol,
+		Status:   OrderStatusCanceled,
+		Price:    order.Price,
+		Quantity: order.Quantity,
+	}
+	if order.OrderType == OrderTypeIOC {
+		log.Printf("🧪 [模拟盘] IOC限价单未成交已撤销: %s 当前价 %.4f 限价 %.4f", symbol, price, order.Price)
+		return result, nil
+	}
+
+	t.state.Orders = append(t.state.Orders, &paperOrder{
+		OrderID:      orderID,
+		Symbol:       symbol,
+		PositionSide: strings.ToUpper(order.Side),
+		Type:         paperOrderTypeLimit,
+		StopPrice:    order.Price,
+		Quantity:     order.Quantity,
+		CreateTime:   t.clock(),
+		Leverage:     order.Leverage,
+	})
+	if err := t.saveLocked(); err != nil {
+		log.Printf("⚠️ 保存模拟盘账户状态失败: %v", err)
+	}
+	t.ensureMonitorLocked()
+
+	log.Printf("🧪 [模拟盘] %s挂单: %s 开%s 限价 %.4f 数量 %.8f（当前价 %.4f）",
+		OrderTypeLabel(order.OrderType), symbol, sideLabel(order.Side), order.Price, order.Quantity, price)
+	result.Status = OrderStatusOpen
+	return result, nil
+}
+
+// GetOrder 查询订单（挂单中的限价单，或按成交明细判断是否已成交）
+func (t *PaperTrader) GetOrder(symbol, orderID string) (*OrderResult, error) {
+	symbol = market.Normalize(symbol)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, order := range t.state.Orders {
+		if formatOrderID(order.OrderID) == orderID {
+			return &OrderResult{
+				OrderID:  orderID,
+				Symbol:   order.Symbol,
+				Status:   OrderStatusOpen,
+				Price:    order.StopPrice,
+				Quantity: order.Quantity,
+			}, nil
+		}
+	}
+	for _, fill := range t.state.Fills {
+		if fill.OrderID == orderID && !fill.IsClose {
+			return &OrderResult{
+				OrderID:     orderID,
+				Symbol:      fill.Symbol,
+				Status:      OrderStatusFilled,
+				AvgPrice:    fill.Price,
+				ExecutedQty: fill.Quantity,
+				Fee:         fill.Fee,
+				Quantity:    fill.Quantity,
+			}, nil
+		}
+	}
+	return &OrderResult{OrderID: orderID, Symbol: symbol, Status: OrderStatusCanceled}, nil
+}
+
+// CancelOrder 撤销指定挂单
+func (t *PaperTrader) CancelOrder(symbol, orderID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, order := range t.state.Orders {
+		if formatOrderID(order.OrderID) == orderID {
+			t.removeOrderLocked(order.OrderID)
+			log.Printf("  ✓ [模拟盘] 已撤销 %s 订单 %s", order.Symbol, orderID)
+			return t.saveLocked()
+		}
+	}
+	return fmt.Errorf("订单不存在或已完成: %s", orderID)
+}
+
+// GetOpenOrders 查询未完成的挂单（限价开仓单和止盈止损触发单）
+func (t *PaperTrader) GetOpenOrders(symbol string) ([]OpenOrder, error) {
+	if symbol != "" {
+		symbol = market.Normalize(symbol)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var orders []OpenOrder
+	for _, order := range t.state.Orders {
+		if symbol != "" && order.Symbol != symbol {
+			continue
+		}
+		open := OpenOrder{
+			OrderID:      formatOrderID(order.OrderID),
+			Symbol:       order.Symbol,
+			PositionSide: strings.ToLower(order.PositionSide),
+			Quantity:     order.Quantity,
+			Time:         order.CreateTime,
+		}
+		switch order.Type {
+		case paperOrderTypeLimit:
+			open.Type = OpenOrderTypeLimit
+			open.Price = order.StopPrice
+		case paperOrderTypeTakeProfit:
+			open.Type = OpenOrderTypeTakeProfit
+			open.StopPrice = order.StopPrice
+		case paperOrderTypeTrailingStop:
+			open.Type = OpenOrderTypeTrailingStop
+			open.StopPrice = order.StopPrice
+		default:
+			open.Type = OpenOrderTypeStopLoss
+			open.StopPrice = order.StopPrice
+		}
+		open.IsAlgo = open.Type != OpenOrderTypeLimit
+		open.ReduceOnly = open.IsAlgo
+		// 开仓单与持仓同向，触发单（平仓）与持仓反向
+		open.Side = "buy"
+		if (open.PositionSide == "long") == open.ReduceOnly {
+			open.Side = "sell"
+		}
+		orders = append(orders, open)
+	}
+	return orders, nil
+}
+
+// CloseLong 平多仓（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
+	return t.closePosition(symbol, "long", quantity)
+}
+
+// CloseShort 平空仓（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
+	return t.closePosition(symbol, "short", quantity)
+}
+
+// closePosition 按市价平仓
+func (t *PaperTrader) closePosition(symbol, side string, quantity float64) (*OrderResult, error) {
+	symbol = market.Normalize(symbol)
+
+	t.mu.Lock()
+	_, exists := t.state.Positions[paperPositionKey(symbol, side)]
+	t.mu.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("没有找到 %s 的%s仓", symbol, sideLabel(side))
+	}
+
+	price, err := t.priceFunc(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 成交价格失败: %w", symbol, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	orderID, closedQty, fee, err := t.closeLocked(symbol, side, quantity, price, paperExitReasonMarketClose)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.saveLocked(); err != nil {
+		log.Printf("⚠️ 保存模拟盘账户状态失败: %v", err)
+	}
+
+	return &OrderResult{
+		OrderID:     formatOrderID(orderID),
+		Symbol:      symbol,
+		Status:      "FILLED",
+		AvgPrice:    price,
+		ExecutedQty: closedQty,
+		Fee:         fee,
+	}, nil
+}
+
+// closeLocked 以指定价格平仓并结算盈亏，返回订单ID、平仓数量和手续费（调用方需持有锁）
+func (t *PaperTrader) closeLocked(symbol, side string, quantity, price float64, reason string) (int64, float64, float64, error) {
+	key := paperPositionKey(symbol, side)
+	pos, exists := t.state.Positions[key]
+	if !exists {
+		return 0, 0, 0, fmt.Errorf("没有找到 %s 的%s仓", symbol, sideLabel(side))
+	}
+
+	if quantity <= 0 || quantity > pos.Quantity {
+		quantity = pos.Quantity
+	}
+
+	ratio := quantity / pos.Quantity
+	pnl := 0.0
+	if side == "long" {
+		pnl = (price - pos.EntryPrice) * quantity
+	} else {
+		pnl = (pos.EntryPrice - price) * quantity
+	}
+	fee := quantity * price * t.takerFeeRate
+	releasedMargin := pos.Margin * ratio
+
+	if reason == paperExitReasonLiquidation {
+		// 强平：逐仓最多损失仓位保证金；全仓损失由钱包余额承担
+		if !pos.IsCross && pnl < -releasedMargin {
+			pnl = -releasedMargin
+		}
+	}
+
+	t.state.WalletBalance += pnl - fee
+	t.state.RealizedPnL += pnl
+	t.state.TotalFees += fee
+	if t.state.WalletBalance < 0 {
+		t.state.WalletBalance = 0
+	}
+
+	pos.Quantity -= quantity
+	pos.Margin -= releasedMargin
+	pos.MarkPrice = price
+
+	// 剩余数量极小视为全部平仓
+	fullyClosed := pos.Quantity <= 0 || pos.Quantity*price < 1e-8
+	if fullyClosed {
+		delete(t.state.Positions, key)
+		// 持仓全部平掉后取消该方向的所有触发单（限价开仓挂单保留）
+		t.cancelOrdersLocked(symbol, strings.ToUpper(side), paperOrderTypeStopLoss)
+		t.cancelOrdersLocked(symbol, strings.ToUpper(side), paperOrderTypeTakeProfit)
+		t.cancelOrdersLocked(symbol, strings.ToUpper(side), paperOrderTypeTrailingStop)
+	}
+
+	if t.onTrade != nil {
+		t.onTrade(PaperTrade{
+			Symbol:      symbol,
+			Side:        side,
+			Quantity:    quantity,
+			Leverage:    pos.Leverage,
+			EntryPrice:  pos.EntryPrice,
+			ExitPrice:   price,
+			PnL:         pnl,
+			Fee:         fee,
+			Reason:      reason,
+			FullyClosed: fullyClosed,
+			OpenTime:    pos.OpenTime,
+			CloseTime:   t.clock(),
+		})
+	}
+
+	orderID := t.nextOrderIDLocked()
+	t.recordFillLocked(Fill{
+		OrderID:     formatOrderID(orderID),
+		Symbol:      symbol,
+		Side:        side,
+		IsClose:     true,
+		Price:       price,
+		Quantity:    quantity,
+		Fee:         fee,
+		RealizedPnL: pnl,
+		Reason:      reason,
+		Time:        t.clock(),
+	})
+	log.Printf("🧪 [模拟盘] 平%s(%s): %s 数量: %.8f 成交价: %.4f 已实现盈亏: %+.4f 手续费: %.4f 钱包余额: %.2f",
+		sideLabel(side), reason, symbol, quantity, price, pnl, fee, t.state.WalletBalance)
+	return orderID, quantity, fee, nil
+}
+
+// recordFillLocked 记录成交明细（只保留最近 paperMaxFills 条，调用方需持有锁）
+func (t *PaperTrader) recordFillLocked(fill Fill) {
+	t.state.Fills = append(t.state.Fills, fill)
+	if len(t.state.Fills) > paperMaxFills {
+		t.state.Fills = t.state.Fills[len(t.state.Fills)-paperMaxFills:]
+	}
+}
+
+// GetUserTrades 获取成交明细（按时间升序）
+func (t *PaperTrader) GetUserTrades(symbol string, startTime time.Time) ([]Fill, error) {
+	symbol = market.Normalize(symbol)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var fills []Fill
+	for _, fill := range t.state.Fills {
+		if fill.Symbol == symbol && !fill.Time.Before(startTime) {
+			fills = append(fills, fill)
+		}
+	}
+	return fills, nil
+}
+
+// GetFundingFees 模拟盘不模拟资金费
+func (t *PaperTrader) GetFundingFees(symbol string, startTime time.Time) (float64, error) {
+	return 0, nil
+}
+
+// SetLeverage 设置杠杆
+func (t *PaperTrader) SetLeverage(symbol string, leverage int) error {
+	if leverage <= 0 {
+		return fmt.Errorf("杠杆必须大于0: %d", leverage)
+	}
+	symbol = market.Normalize(symbol)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Leverage[symbol] = leverage
+	log.Printf("  ✓ [模拟盘] %s 杠杆已设置为 %dx", symbol, leverage)
+	return t.saveLocked()
+}
+
+// SetMarginMode 设置仓位模式 (true=全仓, false=逐仓)
+func (t *PaperTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	symbol = market.Normalize(symbol)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 与真实交易所一致：有持仓时不允许切换仓位模式
+	for _, pos := range t.state.Positions {
+		if pos.Symbol == symbol && pos.IsCross != isCrossMargin {
+			return fmt.Errorf("%s 有持仓，无法切换仓位模式", symbol)
+		}
+	}
+	t.state.CrossMargin[symbol] = isCrossMargin
+	return t.saveLocked()
+}
+
+// GetMarketPrice 获取市场价格
+func (t *PaperTrader) GetMarketPrice(symbol string) (float64, error) {
+	return t.priceFunc(market.Normalize(symbol))
+}
+
+// SetStopLoss 设置止损单
+func (t *PaperTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	return t.placeTriggerOrder(symbol, positionSide, paperOrderTypeStopLoss, stopPrice, quantity)
+}
+
+// SetTakeProfit 设置止盈单
+func (t *PaperTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	return t.placeTriggerOrder(symbol, positionSide, paperOrderTypeTakeProfit, takeProfitPrice, quantity)
+}
+
+// placeTriggerOrder 挂止盈/止损触发单
+func (t *PaperTrader) placeTriggerOrder(symbol, positionSide, orderType string, stopPrice, quantity float64) error {
+	if stopPrice <= 0 {
+		return fmt.Errorf("触发价格必须大于0: %.8f", stopPrice)
+	}
+	symbol = market.Normalize(symbol)
+	positionSide = strings.ToUpper(positionSide)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.state.Positions[paperPositionKey(symbol, strings.ToLower(positionSide))]; !exists {
+		return fmt.Errorf("没有找到 %s 的 %s 持仓，无法设置触发单", symbol, positionSide)
+	}
+
+	t.placeOrderLocked(symbol, positionSide, orderType, stopPrice, quantity)
+	if err := t.saveLocked(); err != nil {
+		log.Printf("⚠️ 保存模拟盘账户状态失败: %v", err)
+	}
+	t.ensureMonitorLocked()
+	return nil
+}
+
+// placeOrderLocked 新增触发单（调用方需持有锁）
+func (t *PaperTrader) placeOrderLocked(symbol, positionSide, orderType string, stopPrice, quantity float64) {
+	order := &paperOrder{
+		OrderID:      t.nextOrderIDLocked(),
+		Symbol:       symbol,
+		PositionSide: positionSide,
+		Type:         orderType,
+		StopPrice:    stopPrice,
+		Quantity:     quantity,
+		CreateTime:   t.clock(),
+	}
+	t.state.Orders = append(t.state.Orders, order)
+
+	label := "止损"
+	if orderType == paperOrderTypeTakeProfit {
+		label = "止盈"
+	}
+	log.Printf("  ✓ [模拟盘] %s %s %s价设置: %.4f", symbol, positionSide, label, stopPrice)
+}
+
+// SetTrailingStop 设置跟踪止损单（按推送价格维护极值，从极值回撤达到回调比例时按市价平仓）
+func (t *PaperTrader) SetTrailingStop(symbol string, positionSide string, quantity, callbackRate, activationPrice float64) error {
+	if callbackRate <= 0 {
+		return fmt.Errorf("回调比例必须大于0: %.2f", callbackRate)
+	}
+	symbol = market.Normalize(symbol)
+	positionSide = strings.ToUpper(positionSide)
+	side := strings.ToLower(positionSide)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pos, exists := t.state.Positions[paperPositionKey(symbol, side)]
+	if !exists {
+		return fmt.Errorf("没有找到 %s 的 %s 持仓，无法设置跟踪止损", symbol, positionSide)
+	}
+
+	order := &paperOrder{
+		OrderID:         t.nextOrderIDLocked(),
+		Symbol:          symbol,
+		PositionSide:    positionSide,
+		Type:            paperOrderTypeTrailingStop,
+		Quantity:        quantity,
+		CreateTime:      t.clock(),
+		CallbackRate:    callbackRate,
+		ActivationPrice: activationPrice,
+	}
+	// 未设置激活价时从当前标记价格开始跟踪
+	order.PeakPrice = trailingPeak(side, 0, activationPrice, pos.MarkPrice)
+	order.StopPrice = trailingStopPrice(side, order.PeakPrice, callbackRate)
+	t.state.Orders = append(t.state.Orders, order)
+	log.Printf("  ✓ [模拟盘] %s %s 跟踪止损设置: 回调 %.2f%%, 激活价 %.4f", symbol, positionSide, callbackRate, activationPrice)
+
+	if err := t.saveLocked(); err != nil {
+		log.Printf("⚠️ 保存模拟盘账户状态失败: %v", err)
+	}
+	t.ensureMonitorLocked()
+	return nil
+}
+
+// CancelTrailingStopOrders 仅取消跟踪止损单
+func (t *PaperTrader) CancelTrailingStopOrders(symbol string) error {
+	return t.cancelOrders(symbol, paperOrderTypeTrailingStop)
+}
+
+// CancelStopLossOrders 仅取消止损单
+func (t *PaperTrader) CancelStopLossOrders(symbol string) error {
+	return t.cancelOrders(symbol, paperOrderTypeStopLoss)
+}
+
+// CancelTakeProfitOrders 仅取消止盈单
+func (t *PaperTrader) CancelTakeProfitOrders(symbol string) error {
+	return t.cancelOrders(symbol, paperOrderTypeTakeProfit)
+}
+
+// CancelAllOrders 取消该币种的所有挂单
+func (t *PaperTrader) CancelAllOrders(symbol string) error {
+	return t.cancelOrders(symbol, "")
+}
+
+// CancelStopOrders 取消该币种的止盈/止损单（不影响限价开仓挂单）
+func (t *PaperTrader) CancelStopOrders(symbol string) error {
+	if err := t.cancelOrders(symbol, paperOrderTypeStopLoss); err != nil {
+		return err
+	}
+	return t.cancelOrders(symbol, paperOrderTypeTakeProfit)
+}
+
+// cancelOrders 取消指定类型的触发单（orderType为空表示全部）
+func (t *PaperTrader) cancelOrders(symbol, orderType string) error {
+	symbol = market.Normalize(symbol)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	canceled := t.cancelOrdersLocked(symbol, "", orderType)
+	if canceled > 0 {
+		log.Printf("  ✓ [模拟盘] 已取消 %s 的 %d 个触发单", symbol, canceled)
+	}
+	return t.saveLocked()
+}
+
+// cancelOrdersLocked 按条件取消触发单，返回取消数量（空字符串表示不过滤）
+func (t *PaperTrader) cancelOrdersLocked(symbol, positionSide, orderType string) int {
+	kept := t.state.Orders[:0]
+	canceled := 0
+	for _, order := range t.state.Orders {
+		if order.Symbol == symbol &&
+			(positionSide == "" || order.PositionSide == positionSide) &&
+			(orderType == "" || order.Type == orderType) {
+			canceled++
+			continue
+		}
+		kept = append(kept, order)
+	}
+	t.state.Orders = kept
+	return canceled
+}
+
+// GetInstrument 获取合约交易规则（模拟盘不限制价格步进和杠杆，数量保留8位小数）
+func (t *PaperTrader) GetInstrument(symbol string) (*Instrument, error) {
+	return &Instrument{Symbol: symbol, QtyStep: 1e-8, ContractSize: 1}, nil
+}
+
+// FormatQuantity 格式化数量（模拟盘不限制步长，保留8位小数）
+func (t *PaperTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	return strconv.FormatFloat(quantity, 'f', 8, 64), nil
+}
+
+// nextOrderIDLocked 生成模拟订单ID
+func (t *PaperTrader) nextOrderIDLocked() int64 {
+	if t.state.NextOrderID <= 0 {
+		t.state.NextOrderID = 1
+	}
+	id := t.state.NextOrderID
+	t.state.NextOrderID++
+	return id
+}
+
+// markLocked 更新指定币种所有持仓的标记价格
+func (t *PaperTrader) markLocked(symbol string, price float64) {
+	for _, pos := range t.state.Positions {
+		if pos.Symbol == symbol {
+			pos.MarkPrice = price
+		}
+	}
+}
+
+// refreshMarks 刷新持仓标记价格并检查触发单/强平
+func (t *PaperTrader) refreshMarks() {
+	t.mu.Lock()
+	symbols := t.activeSymbolsLocked()
+	t.mu.Unlock()
+
+	for _, symbol := range symbols {
+		price, err := t.tickerFunc(symbol)
+		if err != nil {
+			log.Printf("⚠️ [模拟盘] 获取 %s 最新价格失败: %v", symbol, err)
+			continue
+		}
+		t.OnPrice(symbol, price)
+	}
+}
+
+// activeSymbolsLocked 获取有持仓或挂单的币种
+func (t *PaperTrader) activeSymbolsLocked() []string {
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, pos := range t.state.Positions {
+		if !seen[pos.Symbol] {
+			seen[pos.Symbol] = true
+			symbols = append(symbols, pos.Symbol)
+		}
+	}
+	for _, order := range t.state.Orders {
+		if !seen[order.Symbol] {
+			seen[order.Symbol] = true
+			symbols = append(symbols, order.Symbol)
+		}
+	}
+	return symbols
+}
+
+// OnPrice 推送最新价格：更新标记价格、触发止盈止损、检查强平
+func (t *PaperTrader) OnPrice(symbol string, price float64) {
+	if price <= 0 {
+		return
+	}
+	symbol = market.Normalize(symbol)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.markLocked(symbol, price)
+	changed := t.checkTriggersLocked(symbol, price)
+	if t.checkLiquidationLocked() {
+		changed = true
+	}
+	if changed {
+		if err := t.saveLocked(); err != nil {
+			log.Printf("⚠️ 保存模拟盘账户状态失败: %v", err)
+		}
+	}
+}
+
+// checkTriggersLocked 检查止盈止损触发（触发后按触发价市价成交）
+func (t *PaperTrader) checkTriggersLocked(symbol string, price float64) bool {
+	changed := false
+	// 复制一份，成交过程中会修改 t.state.Orders
+	orders := append([]*paperOrder(nil), t.state.Orders...)
+	for _, order := range orders {
+		if order.Symbol != symbol {
+			continue
+		}
+		side := strings.ToLower(order.PositionSide)
+		if order.Type == paperOrderTypeLimit {
+			if t.fillLimitLocked(order, side, price) {
+				changed = true
+			}
+			continue
+		}
+		if _, exists := t.state.Positions[paperPositionKey(symbol, side)]; !exists {
+			continue
+		}
+
+		if order.Type == paperOrderTypeTrailingStop {
+			// 跟踪止损：先用最新价更新极值和触发价（极值变化需要持久化）
+			if peak := trailingPeak(side, order.PeakPrice, order.ActivationPrice, price); peak != order.PeakPrice {
+				order.PeakPrice = peak
+				order.StopPrice = trailingStopPrice(side, peak, order.CallbackRate)
+				changed = true
+			}
+		}
+
+		triggered := false
+		switch {
+		case order.Type == paperOrderTypeTrailingStop && order.StopPrice <= 0:
+			// 未激活
+		case order.Type == paperOrderTypeTrailingStop && side == "long":
+			triggered = price <= order.StopPrice
+		case order.Type == paperOrderTypeTrailingStop && side == "short":
+			triggered = price >= order.StopPrice
+		case order.Type == paperOrderTypeStopLoss && side == "long":
+			triggered = price <= order.StopPrice
+		case order.Type == paperOrderTypeStopLoss && side == "short":
+			triggered = price >= order.StopPrice
+		case order.Type == paperOrderTypeTakeProfit && side == "long":
+			triggered = price >= order.StopPrice
+		case order.Type == paperOrderTypeTakeProfit && side == "short":
+			triggered = price <= order.StopPrice
+		}
+		if !triggered {
+			continue
+		}
+
+		reason, label := paperExitReasonStopLoss, "止损"
+		switch order.Type {
+		case paperOrderTypeTakeProfit:
+			reason, label = paperExitReasonTakeProfit, "止盈"
+		case paperOrderTypeTrailingStop:
+			reason, label = paperExitReasonTrailing, "跟踪止损"
+		}
+		log.Printf("🔔 [模拟盘] %s %s 触发%s: 最新价 %.4f, 触发价 %.4f", symbol, order.PositionSide, label, price, order.StopPrice)
+
+		// 先移除已触发的订单，再平仓（全部平仓时会清理其余触发单）
+		t.removeOrderLocked(order.OrderID)
+		if _, _, _, err := t.closeLocked(symbol, side, order.Quantity, price, reason); err != nil {
+			log.Printf("⚠️ [模拟盘] 触发单成交失败: %v", err)
+		}
+		changed = true
+	}
+	return changed
+}
+
+// fillLimitLocked 价格触及限价时按限价以Maker费率成交限价开仓单（保证金不足时撤单）
+func (t *PaperTrader) fillLimitLocked(order *paperOrder, side string, price float64) bool {
+	if (side == "long" && price > order.StopPrice) || (side == "short" && price < order.StopPrice) {
+		return false
+	}
+	t.removeOrderLocked(order.OrderID)
+	fee, margin, err := t.openLocked(order.Symbol, side, order.Quantity, order.StopPrice, order.Leverage, t.makerFeeRate, order.OrderID, nil)
+	if err != nil {
+		log.Printf("⚠️ [模拟盘] %s 限价单成交失败，已撤单: %v", order.Symbol, err)
+		return true
+	}
+	log.Printf("🔔 [模拟盘] %s 限价开%s成交: 限价 %.4f 数量 %.8f 保证金 %.2f 手续费 %.4f",
+		order.Symbol, sideLabel(side), order.StopPrice, order.Quantity, margin, fee)
+	return true
+}
+
+// checkLiquidationLocked 检查强平
+// 逐仓：价格触及仓位强平价即强平；全仓：账户净值低于维持保证金时强平所有全仓持仓
+func (t *PaperTrader) checkLiquidationLocked() bool {
+	changed := false
+
+	for _, pos := range t.state.Positions {
+		if pos.IsCross {
+			continue
+		}
+		liq := t.liquidationPriceLocked(pos)
+		if (pos.Side == "long" && pos.MarkPrice <= liq) || (pos.Side == "short" && pos.MarkPrice >= liq) {
+			log.Printf("💥 [模拟盘] %s %s 逐仓强平: 标记价 %.4f, 强平价 %.4f", pos.Symbol, pos.Side, pos.MarkPrice, liq)
+			t.closeLocked(pos.Symbol, pos.Side, 0, pos.MarkPrice, paperExitReasonLiquidation)
+			changed = true
+		}
+	}
+
+	crossUnrealized, crossMaintenance := 0.0, 0.0
+	isolatedMargin := 0.0
+	hasCross := false
+	for _, pos := range t.state.Positions {
+		if pos.IsCross {
+			hasCross = true
+			crossUnrealized += pos.unrealizedPnL(pos.MarkPrice)
+			crossMaintenance += pos.Quantity * pos.MarkPrice * paperMaintenanceMarginRate
+		} else {
+			isolatedMargin += pos.Margin
+		}
+	}
+	if hasCross && t.state.WalletBalance-isolatedMargin+crossUnrealized <= crossMaintenance {
+		log.Printf("💥 [模拟盘] 全仓账户净值 %.2f 低于维持保证金 %.2f，强平所有全仓持仓",
+			t.state.WalletBalance-isolatedMargin+crossUnrealized, crossMaintenance)
+		for _, pos := range t.state.Positions {
+			if pos.IsCross {
+				t.closeLocked(pos.Symbol, pos.Side, 0, pos.MarkPrice, paperExitReasonLiquidation)
+			}
+		}
+		changed = true
+	}
+
+	return changed
+}
+
+// removeOrderLocked 按订单ID移除触发单
+func (t *PaperTrader) removeOrderLocked(orderID int64) {
+	for i, order := range t.state.Orders {
+		if order.OrderID == orderID {
+			t.state.Orders = append(t.state.Orders[:i], t.state.Orders[i+1:]...)
+			return
+		}
+	}
+}
+
+// ensureMonitorLocked 启动触发单监控goroutine（无持仓和挂单时自动退出）
+func (t *PaperTrader) ensureMonitorLocked() {
+	if t.monitor || t.manualPrices || t.closed {
+		return
+	}
+	t.monitor = true
+	go t.monitorLoop()
+}
+
+// monitorLoop 定期拉取价格检查止盈止损和强平
+func (t *PaperTrader) monitorLoop() {
+	ticker := time.NewTicker(paperTriggerCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.stopCh:
+			t.mu.Lock()
+			t.monitor = false
+			t.mu.Unlock()
+			return
+		}
+
+		t.mu.Lock()
+		if len(t.state.Positions) == 0 && len(t.state.Orders) == 0 {
+			t.monitor = false
+			t.mu.Unlock()
+			return
+		}
+		t.mu.Unlock()
+
+		t.refreshMarks()
+	}
+}
@@ -0,0 +1,291 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 交易所REST公共工具：签名策略、服务器时间校准、重试退避
+// 新接入的交易所只需提供签名函数和响应解析，不再各自实现一遍HTTP细节
+
+// restRequest 待签名的请求（签名函数按交易所规则写入请求头）
+type restRequest struct {
+	Method string
+	Path   string // 请求路径（不含域名和查询字符串）
+	Query  string // 编码后的查询字符串（不含?）
+	Body   string // JSON请求体
+	Header http.Header
+	Time   time.Time // 已按服务器时间校准的请求时间
+}
+
+// restSigner 签名策略（见 rest_signers.go），可以改写 Query/Body（如把签名作为参数附加）
+type restSigner func(req *restRequest) error
+
+// restHTTPError 非2xx响应（保留响应体，交给调用方解析业务错误码）
+type restHTTPError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *restHTTPError) Error() string {
+	return fmt.Sprintf("API错误 (状态码: %d): %s", e.StatusCode, string(e.Body))
+}
+
+// restClient 带签名、时间校准和重试的REST客户端
+type restClient struct {
+	name       string // 交易所名称（日志用）
+	baseURL    string
+	httpClient *http.Client
+	signer     restSigner // 为nil时发送公共请求
+	header     http.Header
+	clock      serverClock
+	// form 参数按表单编码发送（Aster等币安风格接口）：调用方通过 query 传参，签名后POST请求的参数移到请求体
+	form bool
+
+	// timestampRejected 判断响应是否为时间戳超出 recvWindow 的业务错误（为nil时不检查），命中时重新同步服务器时间后重试一次
+	timestampRejected func(body []byte) bool
+
+	maxRetries int
+	retryDelay time.Duration // 首次重试等待时间，之后按指数退避
+}
+
+// newRESTClient 创建REST客户端（默认30秒超时、最多3次尝试）
+func newRESTClient(name, baseURL string, signer restSigner) *restClient {
+	return &restClient{
+		name:    name,
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		signer:     signer,
+		header:     make(http.Header),
+		maxRetries: 3,
+		retryDelay: time.Second,
+	}
+}
+
+// do 发送签名请求并返回响应体
+// GET/DELETE 请求在网络错误、429和5xx时重试；下单等非幂等请求只在429（请求未被处理）时重试，避免重复下单
+func (c *restClient) do(method, path string, query url.Values, body interface{}) ([]byte, error) {
+	return c.doRequest(method, path, query, body, true)
+}
+
+// getPublic 发送不签名的公共GET请求（服务器时间、行情等）
+func (c *restClient) getPublic(path string, query url.Values) ([]byte, error) {
+	return c.doRequest(http.MethodGet, path, query, nil, false)
+}
+
+// doRequest 按需签名并带重试地发送请求
+// 签名请求前按 clockResyncInterval 定期重新同步服务器时间；交易所因时间戳拒绝请求时立即同步并重试一次
+func (c *restClient) doRequest(method, path string, query url.Values, body interface{}, signed bool) ([]byte, error) {
+	if signed && c.clock.stale() {
+		c.clock.resync()
+	}
+	respBody, err := c.sendWithRetry(method, path, query, body, signed)
+	if signed && c.isTimestampRejected(respBody, err) {
+		log.Printf("⏱ %s 拒绝了请求时间戳，重新同步服务器时间后重试", c.name)
+		c.clock.resync()
+		respBody, err = c.sendWithRetry(method, path, query, body, signed)
+	}
+	return respBody, err
+}
+
+// isTimestampRejected 响应（含非2xx响应体）是否为时间戳被拒绝
+func (c *restClient) isTimestampRejected(respBody []byte, err error) bool {
+	if c.timestampRejected == nil {
+		return false
+	}
+	var httpErr *restHTTPError
+	if errors.As(err, &httpErr) {
+		return c.timestampRejected(httpErr.Body)
+	}
+	return err == nil && c.timestampRejected(respBody)
+}
+
+// sendWithRetry 序列化请求体并按退避策略发送
+func (c *restClient) sendWithRetry(method, path string, query url.Values, body interface{}, signed bool) ([]byte, error) {
+	var bodyStr string
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("序列化请求体失败: %w", err)
+		}
+		bodyStr = string(data)
+	}
+	queryStr := ""
+	if len(query) > 0 {
+		queryStr = query.Encode()
+	}
+	idempotent := method == http.MethodGet || method == http.MethodDelete
+
+	var respBody []byte
+	err := withBackoff(c.name, c.maxRetries, c.retryDelay, func() error {
+		var err error
+		respBody, err = c.send(method, path, queryStr, bodyStr, signed)
+		return err
+	}, func(err error) bool {
+		var httpErr *restHTTPError
+		if errors.As(err, &httpErr) {
+			return httpErr.StatusCode == http.StatusTooManyRequests ||
+				(idempotent && httpErr.StatusCode >= 500)
+		}
+		return idempotent && isTransientNetError(err)
+	})
+	return respBody, err
+}
+
+// send 签名并发送一次请求（每次重试都重新生成时间戳和签名）
+func (c *restClient) send(method, path, query, body string, signed bool) ([]byte, error) {
+	req := &restRequest{
+		Method: method,
+		Path:   path,
+		Query:  query,
+		Body:   body,
+		Header: c.header.Clone(),
+		Time:   c.clock.now(),
+	}
+	if signed && c.signer != nil {
+		if err := c.signer(req); err != nil {
+			return nil, fmt.Errorf("签名失败: %w", err)
+		}
+	}
+	contentType := "application/json"
+	if c.form && method == http.MethodPost {
+		req.Body, req.Query = req.Query, ""
+		contentType = "application/x-www-form-urlencoded"
+	}
+
+	fullURL := c.baseURL + path
+	if req.Query != "" {
+		fullURL += "?" + req.Query
+	}
+	var reader io.Reader
+	if req.Body != "" {
+		reader = strings.NewReader(req.Body)
+	}
+	httpReq, err := http.NewRequest(method, fullURL, reader)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	httpReq.Header = req.Header
+	httpReq.Header.Set("Content-Type", contentType)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &restHTTPError{StatusCode: resp.StatusCode, Body: respBody}
+	}
+	return respBody, nil
+}
+
+// withBackoff 按指数退避重试（retryable 判断错误是否值得重试）
+func withBackoff(name string, maxRetries int, delay time.Duration, call func() error, retryable func(error) bool) error {
+	var err error
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if err = call(); err == nil {
+			return nil
+		}
+		if attempt == maxRetries || !retryable(err) {
+			break
+		}
+		log.Printf("⚠️  %s API请求失败（尝试 %d/%d），%v后重试: %v", name, attempt, maxRetries, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+	return err
+}
+
+// isTransientNetError 判断是否为超时、连接重置等临时网络错误
+func isTransientNetError(err error) bool {
+	msg := err.Error()
+	for _, s := range []string{"timeout", "deadline exceeded", "connection reset", "connection refused", "EOF"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// clockResyncInterval 服务器时间重新同步间隔（本地时钟长期运行会漂移，最终超出 recvWindow）
+const clockResyncInterval = 30 * time.Minute
+
+// serverClock 本地时钟与交易所服务器时间的偏移
+type serverClock struct {
+	mu       sync.RWMutex
+	offset   time.Duration
+	name     string
+	fetch    func() (time.Time, error) // 获取服务器时间（sync 时设置，为nil表示不校准）
+	syncedAt time.Time                 // 上次同步时间（含失败的尝试，避免频繁请求）
+
+	// onSync 同步成功后回调（不经过 restClient 的SDK客户端用它更新自身的时间偏移，如币安）
+	onSync func(offset time.Duration)
+}
+
+// now 返回按服务器时间校准后的当前时间
+func (c *serverClock) now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Now().Add(c.offset)
+}
+
+// sync 设置服务器时间来源并立即同步，之后由 restClient 定期或在时间戳被拒绝时重新同步
+func (c *serverClock) sync(name string, fetch func() (time.Time, error)) {
+	c.mu.Lock()
+	c.name = name
+	c.fetch = fetch
+	c.mu.Unlock()
+	c.resync()
+}
+
+// stale 距上次同步是否超过 clockResyncInterval
+func (c *serverClock) stale() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.fetch != nil && time.Since(c.syncedAt) >= clockResyncInterval
+}
+
+// resync 获取服务器时间并更新偏移（失败时保留原偏移）
+func (c *serverClock) resync() {
+	c.mu.Lock()
+	name, fetch := c.name, c.fetch
+	c.syncedAt = time.Now()
+	c.mu.Unlock()
+	if fetch == nil {
+		return
+	}
+
+	before := time.Now()
+	serverTime, err := fetch()
+	if err != nil {
+		log.Printf("⚠️ 同步%s服务器时间失败: %v", name, err)
+		return
+	}
+	// 以请求往返的中点作为服务器时间对应的本地时间
+	local := before.Add(time.Since(before) / 2)
+	offset := serverTime.Sub(local)
+
+	c.mu.Lock()
+	c.offset = offset
+	onSync := c.onSync
+	c.mu.Unlock()
+	if onSync != nil {
+		onSync(offset)
+	}
+	log.Printf("⏱ 已同步%s服务器时间，偏移 %dms", name, offset.Milliseconds())
+}
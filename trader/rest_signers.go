@@ -0,0 +1,163 @@
+package trader
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// 各交易所REST签名策略
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(secret, message string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// requestPathWithQuery 请求路径加查询字符串（OKX、Bitget签名使用）
+func requestPathWithQuery(req *restRequest) string {
+	if req.Query == "" {
+		return req.Path
+	}
+	return req.Path + "?" + req.Query
+}
+
+// okxSignature OKX签名: Base64(HMAC_SHA256(timestamp + method + requestPath + body))
+func okxSignature(secretKey, timestamp, method, requestPath, body string) string {
+	return base64.StdEncoding.EncodeToString(hmacSHA256(secretKey, timestamp+method+requestPath+body))
+}
+
+// newOKXSigner OKX签名（ISO8601毫秒时间戳）
+func newOKXSigner(apiKey, secretKey, passphrase string) restSigner {
+	return func(req *restRequest) error {
+		timestamp := req.Time.UTC().Format("2006-01-02T15:04:05.000Z")
+		req.Header.Set("OK-ACCESS-KEY", apiKey)
+		req.Header.Set("OK-ACCESS-SIGN", okxSignature(secretKey, timestamp, req.Method, requestPathWithQuery(req), req.Body))
+		req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+		req.Header.Set("OK-ACCESS-PASSPHRASE", passphrase)
+		return nil
+	}
+}
+
+// newBybitSigner Bybit V5签名: Hex(HMAC_SHA256(timestamp + apiKey + recvWindow + 查询字符串或请求体))
+func newBybitSigner(apiKey, secretKey string) restSigner {
+	return func(req *restRequest) error {
+		timestamp := strconv.FormatInt(req.Time.UnixMilli(), 10)
+		payload := req.Query
+		if req.Body != "" {
+			payload = req.Body
+		}
+		req.Header.Set("X-BAPI-API-KEY", apiKey)
+		req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
+		req.Header.Set("X-BAPI-RECV-WINDOW", bybitRecvWindow)
+		req.Header.Set("X-BAPI-SIGN", hex.EncodeToString(hmacSHA256(secretKey, timestamp+apiKey+bybitRecvWindow+payload)))
+		return nil
+	}
+}
+
+// newBitgetSigner Bitget V2签名: Base64(HMAC_SHA256(timestamp + method + requestPath + body))，毫秒时间戳
+func newBitgetSigner(apiKey, secretKey, passphrase string) restSigner {
+	return func(req *restRequest) error {
+		timestamp := strconv.FormatInt(req.Time.UnixMilli(), 10)
+		sign := hmacSHA256(secretKey, timestamp+req.Method+requestPathWithQuery(req)+req.Body)
+		req.Header.Set("ACCESS-KEY", apiKey)
+		req.Header.Set("ACCESS-SIGN", base64.StdEncoding.EncodeToString(sign))
+		req.Header.Set("ACCESS-TIMESTAMP", timestamp)
+		req.Header.Set("ACCESS-PASSPHRASE", passphrase)
+		req.Header.Set("locale", "en-US")
+		return nil
+	}
+}
+
+// newGateSigner Gate.io V4签名:
+// Hex(HMAC_SHA512(method + "\n" + /api/v4路径 + "\n" + 查询字符串 + "\n" + Hex(SHA512(body)) + "\n" + 秒级时间戳))
+func newGateSigner(apiKey, secretKey string) restSigner {
+	return func(req *restRequest) error {
+		timestamp := strconv.FormatInt(req.Time.Unix(), 10)
+		bodyHash := sha512.Sum512([]byte(req.Body))
+		message := req.Method + "\n" + gateAPIPrefix + req.Path + "\n" + req.Query + "\n" + hex.EncodeToString(bodyHash[:]) + "\n" + timestamp
+		mac := hmac.New(sha512.New, []byte(secretKey))
+		mac.Write([]byte(message))
+		req.Header.Set("KEY", apiKey)
+		req.Header.Set("Timestamp", timestamp)
+		req.Header.Set("SIGN", hex.EncodeToString(mac.Sum(nil)))
+		return nil
+	}
+}
+
+// asterRecvWindow Aster签名请求的 recvWindow（毫秒）
+const asterRecvWindow = "50000"
+
+// newAsterSigner Aster V3签名：查询参数（含 recvWindow/timestamp，按key排序的JSON）与 user、signer、nonce 做ABI编码，
+// Keccak256后按以太坊消息格式用API钱包私钥签名，签名和地址作为参数附加到查询字符串
+func newAsterSigner(user, signer string, privateKey *ecdsa.PrivateKey) restSigner {
+	arguments := abi.Arguments{
+		{Type: mustABIType("string")},
+		{Type: mustABIType("address")},
+		{Type: mustABIType("address")},
+		{Type: mustABIType("uint256")},
+	}
+	return func(req *restRequest) error {
+		params, err := url.ParseQuery(req.Query)
+		if err != nil {
+			return fmt.Errorf("解析请求参数失败: %w", err)
+		}
+		params.Set("recvWindow", asterRecvWindow)
+		params.Set("timestamp", strconv.FormatInt(req.Time.UnixMilli(), 10))
+
+		// 所有参数值都是字符串，json.Marshal 按key排序
+		flat := make(map[string]string, len(params))
+		for k := range params {
+			flat[k] = params.Get(k)
+		}
+		jsonStr, err := json.Marshal(flat)
+		if err != nil {
+			return err
+		}
+
+		nonce := uint64(req.Time.UnixMicro())
+		packed, err := arguments.Pack(string(jsonStr), common.HexToAddress(user), common.HexToAddress(signer), new(big.Int).SetUint64(nonce))
+		if err != nil {
+			return fmt.Errorf("ABI编码失败: %w", err)
+		}
+		hash := crypto.Keccak256(packed)
+		prefixedMsg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(hash), hash)
+		sig, err := crypto.Sign(crypto.Keccak256Hash([]byte(prefixedMsg)).Bytes(), privateKey)
+		if err != nil {
+			return err
+		}
+		if len(sig) != 65 {
+			return fmt.Errorf("签名长度异常: %d", len(sig))
+		}
+		sig[64] += 27 // v从0/1转换为27/28
+
+		params.Set("user", user)
+		params.Set("signer", signer)
+		params.Set("signature", "0x"+hex.EncodeToString(sig))
+		params.Set("nonce", strconv.FormatUint(nonce, 10))
+		req.Query = params.Encode()
+		return nil
+	}
+}
+
+// mustABIType 创建ABI基础类型（类型名固定，不会失败）
+func mustABIType(name string) abi.Type {
+	t, err := abi.NewType(name, "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
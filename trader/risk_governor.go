@@ -0,0 +1,333 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/config"
+	"nofx/logger"
+	"time"
+)
+
+// 风控熔断原因
+const (
+	RiskTripMaxDailyLoss = "max_daily_loss"
+	RiskTripMaxDrawdown  = "max_drawdown"
+)
+
+// defaultStopTradingTime 未配置暂停时长时的默认值
+const defaultStopTradingTime = 60 * time.Minute
+
+// RiskStateStore 风控状态存储（由 config.Database 实现）
+type RiskStateStore interface {
+	SaveRiskState(traderID string, state config.RiskState) error
+	GetRiskState(traderID string) (*config.RiskState, error)
+}
+
+// riskDayStart 日盈亏统计周期起点（UTC零点）
+func riskDayStart(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// restoreRiskState 恢复上次运行保存的风控状态：熔断暂停和峰值净值始终恢复，日起始净值只在同一UTC日内恢复
+func (at *AutoTrader) restoreRiskState() {
+	if at.riskStore == nil {
+		return
+	}
+	state, err := at.riskStore.GetRiskState(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 读取风控状态失败: %v", at.name, err)
+		return
+	}
+	if state == nil {
+		return
+	}
+
+	at.riskMutex.Lock()
+	defer at.riskMutex.Unlock()
+	at.peakEquity = state.PeakEquity
+	if state.DayStart.Equal(riskDayStart(time.Now())) {
+		at.dayStartEquity = state.DayStartEquity
+		at.lastResetTime = state.DayStart
+	}
+	// 暂停已在停机期间结束时同样恢复熔断标记，由 updateRiskState 按熔断结束处理（重置回撤基准）
+	if state.RiskTripped {
+		at.riskTripped = true
+		at.stopUntil = state.StopUntil
+		if time.Now().Before(state.StopUntil) {
+			log.Printf("⏸ [%s] 恢复风控熔断暂停，暂停交易至 %s", at.name, state.StopUntil.Local().Format("2006-01-02 15:04:05"))
+		}
+	}
+}
+
+// saveRiskStateLocked 保存风控状态（调用方持有 riskMutex）
+func (at *AutoTrader) saveRiskStateLocked() {
+	if at.riskStore == nil {
+		return
+	}
+	state := config.RiskState{
+		DayStart:       at.lastResetTime,
+		DayStartEquity: at.dayStartEquity,
+		PeakEquity:     at.peakEquity,
+		RiskTripped:    at.riskTripped,
+		StopUntil:      at.stopUntil,
+	}
+	if err := at.riskStore.SaveRiskState(at.id, state); err != nil {
+		log.Printf("⚠️ [%s] %v", at.name, err)
+	}
+}
+
+// updateRiskState 使用最新账户净值更新风控状态（日盈亏、峰值回撤），触发熔断时返回熔断记录
+// 日盈亏 = 当前净值 - 当日起始净值（同时包含已实现和未实现盈亏）
+// 回撤 = (峰值净值 - 当前净值) / 峰值净值
+func (at *AutoTrader) updateRiskState(equity float64) *logger.RiskTrip {
+	if equity <= 0 {
+		return nil
+	}
+
+	at.riskMutex.Lock()
+	defer at.riskMutex.Unlock()
+
+	now := time.Now()
+	changed := false
+	defer func() {
+		if changed {
+			at.saveRiskStateLocked()
+		}
+	}()
+
+	// 每个UTC日零点重置日盈亏基准
+	if dayStart := riskDayStart(now); at.dayStartEquity <= 0 || !at.lastResetTime.Equal(dayStart) {
+		if at.dayStartEquity > 0 {
+			log.Printf("📅 [%s] 日盈亏已重置（昨日盈亏: %+.2f USDT）", at.name, at.dailyPnL)
+		}
+		at.dayStartEquity = equity
+		at.dailyPnL = 0
+		at.lastResetTime = dayStart
+		changed = true
+	}
+
+	// 熔断暂停结束后，以当前净值作为新的回撤基准，避免恢复后立即再次触发
+	if at.riskTripped && !now.Before(at.stopUntil) {
+		log.Printf("✅ [%s] 风控熔断暂停结束，恢复交易（回撤基准重置为 %.2f USDT）", at.name, equity)
+		at.riskTripped = false
+		at.peakEquity = equity
+		changed = true
+	}
+
+	if equity > at.peakEquity {
+		at.peakEquity = equity
+		changed = true
+	}
+	at.lastEquity = equity
+	at.dailyPnL = equity - at.dayStartEquity
+
+	if at.riskTripped {
+		return nil
+	}
+
+	dailyLossPct := 0.0
+	if at.dayStartEquity > 0 {
+		dailyLossPct = -at.dailyPnL / at.dayStartEquity * 100
+	}
+	drawdownPct := 0.0
+	if at.peakEquity > 0 {
+		drawdownPct = (at.peakEquity - equity) / at.peakEquity * 100
+	}
+
+	stopDuration := at.config.StopTradingTime
+	if stopDuration <= 0 {
+		stopDuration = defaultStopTradingTime
+	}
+
+	var trip *logger.RiskTrip
+	if at.config.MaxDailyLoss > 0 && dailyLossPct >= at.config.MaxDailyLoss {
+		// 日亏损熔断：至少暂停到当日统计周期结束（下一个UTC零点）
+		stopUntil := now.Add(stopDuration)
+		if dayEnd := at.lastResetTime.Add(24 * time.Hour); dayEnd.After(stopUntil) {
+			stopUntil = dayEnd
+		}
+		trip = &logger.RiskTrip{
+			Reason:          RiskTripMaxDailyLoss,
+			LimitPct:        at.config.MaxDailyLoss,
+			ValuePct:        dailyLossPct,
+			Equity:          equity,
+			ReferenceEquity: at.dayStartEquity,
+			StopUntil:       stopUntil,
+			Timestamp:       now,
+		}
+	} else if at.config.MaxDrawdown > 0 && drawdownPct >= at.config.MaxDrawdown {
+		trip = &logger.RiskTrip{
+			Reason:          RiskTripMaxDrawdown,
+			LimitPct:        at.config.MaxDrawdown,
+			ValuePct:        drawdownPct,
+			Equity:          equity,
+			ReferenceEquity: at.peakEquity,
+			StopUntil:       now.Add(stopDuration),
+			Timestamp:       now,
+		}
+	}
+
+	if trip == nil {
+		return nil
+	}
+
+	at.riskTripped = true
+	at.stopUntil = trip.StopUntil
+	at.lastRiskTrip = trip
+	at.riskTripCount++
+	changed = true
+	return trip
+}
+
+// handleRiskTrip 处理风控熔断：按配置强制平仓，并将熔断信息写入决策记录
+func (at *AutoTrader) handleRiskTrip(trip *logger.RiskTrip, record *logger.DecisionRecord) {
+	reasonText := "日亏损"
+	if trip.Reason == RiskTripMaxDrawdown {
+		reasonText = "最大回撤"
+	}
+
+	log.Println("🚨🚨🚨 风控熔断触发 🚨🚨🚨")
+	log.Printf("🚨 [%s] %s %.2f%% ≥ 阈值 %.2f%%（净值 %.2f / 参考净值 %.2f USDT）",
+		at.name, reasonText, trip.ValuePct, trip.LimitPct, trip.Equity, trip.ReferenceEquity)
+	log.Printf("⏸ [%s] 暂停交易至 %s", at.name, trip.StopUntil.Format("2006-01-02 15:04:05"))
+
+	at.cancelPendingEntries(record, "风控熔断")
+	if at.config.FlattenOnRiskTrip {
+		log.Printf("🚨 [%s] 熔断配置为强制平仓，开始平掉所有持仓...", at.name)
+		trip.Flattened = at.flattenAllPositions(record)
+	}
+
+	record.RiskTrip = trip
+	record.Success = false
+	record.ErrorMessage = fmt.Sprintf("风控熔断: %s %.2f%% 超过阈值 %.2f%%，暂停交易至 %s",
+		reasonText, trip.ValuePct, trip.LimitPct, trip.StopUntil.Format("2006-01-02 15:04:05"))
+	record.ExecutionLog = append(record.ExecutionLog, "🚨 "+record.ErrorMessage)
+}
+
+// flattenAllPositions 熔断时平掉所有持仓，平仓动作记录为 auto_close_long/auto_close_short
+// 返回是否全部平仓成功
+func (at *AutoTrader) flattenAllPositions(record *logger.DecisionRecord) bool {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("❌ [%s] 熔断平仓获取持仓失败: %v", at.name, err)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ 熔断平仓获取持仓失败: %v", err))
+		return false
+	}
+
+	allClosed := true
+	for _, pos := range positions {
+		symbol, side := pos.Symbol, pos.Side
+		if symbol == "" || pos.Quantity == 0 {
+			continue
+		}
+
+		actionRecord := logger.DecisionAction{
+			Action:    "auto_close_" + side,
+			Symbol:    symbol,
+			Quantity:  pos.Quantity,
+			Price:     pos.MarkPrice,
+			Timestamp: time.Now(),
+		}
+
+		if err := at.emergencyClosePosition(symbol, side, config.TradeExitRiskFlatten); err != nil {
+			log.Printf("❌ [%s] 熔断平仓失败 %s %s: %v", at.name, symbol, side, err)
+			actionRecord.Error = err.Error()
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ 熔断平仓 %s %s 失败: %v", symbol, side, err))
+			allClosed = false
+		} else {
+			actionRecord.Success = true
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ 熔断平仓 %s %s 成功", symbol, side))
+			at.ClearPeakPnLCache(symbol)
+		}
+		record.Decisions = append(record.Decisions, actionRecord)
+	}
+
+	return allClosed
+}
+
+// checkRiskLimits 周期外的风控检查（由监控goroutine每分钟调用），在两次AI决策之间也能及时熔断
+func (at *AutoTrader) checkRiskLimits() {
+	if !at.isRunning || at.isRiskPaused() {
+		return
+	}
+
+	balance, err := at.getBalance()
+	if err != nil {
+		log.Printf("⚠️ [%s] 风控检查获取余额失败: %v", at.name, err)
+		return
+	}
+
+	totalUnrealizedProfit := balance.TotalUnrealizedProfit
+	availableBalance := balance.AvailableBalance
+	totalEquity := balance.TotalWalletBalance + totalUnrealizedProfit
+
+	trip := at.updateRiskState(totalEquity)
+	if trip == nil {
+		return
+	}
+
+	record := &logger.DecisionRecord{
+		ExecutionLog: []string{},
+		AccountState: logger.AccountSnapshot{
+			TotalBalance:          totalEquity,
+			AvailableBalance:      availableBalance,
+			TotalUnrealizedProfit: totalUnrealizedProfit,
+		},
+	}
+	at.handleRiskTrip(trip, record)
+	// 监控goroutine不单独写决策日志（会占用AI周期编号），熔断记录合并进下一个周期（暂停中）的决策记录
+	at.deferRecord(record)
+}
+
+// isRiskPaused 是否处于暂停交易期间
+func (at *AutoTrader) isRiskPaused() bool {
+	at.riskMutex.RLock()
+	defer at.riskMutex.RUnlock()
+	return time.Now().Before(at.stopUntil)
+}
+
+// getStopUntil 获取暂停交易截止时间
+func (at *AutoTrader) getStopUntil() time.Time {
+	at.riskMutex.RLock()
+	defer at.riskMutex.RUnlock()
+	return at.stopUntil
+}
+
+// getDailyPnL 获取当日盈亏（已实现 + 未实现）
+func (at *AutoTrader) getDailyPnL() float64 {
+	at.riskMutex.RLock()
+	defer at.riskMutex.RUnlock()
+	return at.dailyPnL
+}
+
+// GetRiskBreakerStatus 获取风控熔断器状态（用于API）
+func (at *AutoTrader) GetRiskBreakerStatus() map[string]interface{} {
+	at.riskMutex.RLock()
+	defer at.riskMutex.RUnlock()
+
+	dailyPnLPct := 0.0
+	if at.dayStartEquity > 0 {
+		dailyPnLPct = at.dailyPnL / at.dayStartEquity * 100
+	}
+
+	status := map[string]interface{}{
+		"tripped":           at.riskTripped && time.Now().Before(at.stopUntil),
+		"stop_until":        at.stopUntil.Format(time.RFC3339),
+		"max_daily_loss":    at.config.MaxDailyLoss,
+		"max_drawdown":      at.config.MaxDrawdown,
+		"stop_trading_time": at.config.StopTradingTime.String(),
+		"flatten_on_trip":   at.config.FlattenOnRiskTrip,
+		"day_start_equity":  at.dayStartEquity,
+		"daily_pnl":         at.dailyPnL,
+		"daily_pnl_pct":     dailyPnLPct,
+		"peak_equity":       at.peakEquity,
+		"current_drawdown":  0.0,
+		"trip_count":        at.riskTripCount,
+		"last_trip":         at.lastRiskTrip,
+		"last_reset_time":   at.lastResetTime.Format(time.RFC3339),
+	}
+	if at.peakEquity > 0 {
+		status["current_drawdown"] = (at.peakEquity - at.lastEquity) / at.peakEquity * 100
+	}
+	return status
+}
@@ -0,0 +1,106 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/config"
+	"nofx/decision"
+	"strings"
+)
+
+// SplitTakeProfitLevels 按比例拆分各档止盈数量（levels 需已按离入场价由近到远排序，回测与实盘共用）
+// 数量向下取整到数量步进，取整余量和不足最小下单量的档位并入下一档；
+// 比例合计100%时最后一档使用剩余全部数量（不足最小下单量时并入上一档），避免取整残留的尾仓没有止盈单
+func SplitTakeProfitLevels(inst *Instrument, quantity float64, levels []decision.TakeProfitLevel) []config.TakeProfitLevel {
+	total := 0.0
+	for _, level := range levels {
+		total += level.Percentage
+	}
+
+	var result []config.TakeProfitLevel
+	remaining := quantity
+	carry := 0.0
+	for i, level := range levels {
+		raw := quantity*level.Percentage/100 + carry
+		if i == len(levels)-1 && total >= 99.99 {
+			raw = remaining
+		}
+		if raw > remaining {
+			raw = remaining
+		}
+		qty := inst.RoundQuantity(raw)
+		if qty <= 0 || inst.CheckMin(qty, level.Price) != nil {
+			// 最后一档的剩余数量不足最小下单量时并入上一档，避免尾仓没有止盈单
+			if i == len(levels)-1 && total >= 99.99 && qty > 0 && len(result) > 0 {
+				result[len(result)-1].Quantity += qty
+				break
+			}
+			log.Printf("  ⚠️ %s 第%d档止盈数量 %.8f 不足最小下单量，并入下一档", inst.Symbol, i+1, raw)
+			carry = raw
+			continue
+		}
+		carry = raw - qty
+		remaining -= qty
+		result = append(result, config.TakeProfitLevel{Price: level.Price, Quantity: qty})
+	}
+	return result
+}
+
+// placeTakeProfitLadder 按阶梯为持仓挂出多个只减仓止盈单，返回实际挂出的档位
+// 部分档位失败时保留已挂出的档位并返回错误，全部失败时返回nil档位
+func (at *AutoTrader) placeTakeProfitLadder(symbol, side string, quantity float64, levels []decision.TakeProfitLevel) ([]config.TakeProfitLevel, error) {
+	inst, err := at.trader.GetInstrument(symbol)
+	if err != nil {
+		log.Printf("  ⚠️ 获取 %s 合约规则失败，分批止盈数量不取整: %v", symbol, err)
+		inst = &Instrument{Symbol: symbol}
+	}
+
+	split := SplitTakeProfitLevels(inst, quantity, levels)
+	if len(split) == 0 {
+		return nil, fmt.Errorf("持仓数量 %.8f 不足以拆分分批止盈", quantity)
+	}
+
+	positionSide := strings.ToUpper(side)
+	setter, partial := at.trader.(PartialTakeProfitSetter)
+
+	var placed []config.TakeProfitLevel
+	var failed []string
+	for i, level := range split {
+		if partial {
+			err = setter.SetPartialTakeProfit(symbol, positionSide, level.Quantity, level.Price)
+		} else {
+			err = at.trader.SetTakeProfit(symbol, positionSide, level.Quantity, level.Price)
+		}
+		if err != nil {
+			log.Printf("  ⚠️ 第%d档止盈 %.4f 设置失败: %v", i+1, level.Price, err)
+			failed = append(failed, fmt.Sprintf("%.4f", level.Price))
+			continue
+		}
+		placed = append(placed, level)
+		log.Printf("  ✓ 第%d档止盈已设置: %.4f × %.8f (%.1f%%)", i+1, level.Price, level.Quantity, level.Quantity/quantity*100)
+	}
+
+	if len(failed) > 0 {
+		return placed, fmt.Errorf("分批止盈 %d/%d 档设置失败: %s", len(failed), len(split), strings.Join(failed, ", "))
+	}
+	return placed, nil
+}
+
+// setupTakeProfitLadder 开仓成交后挂出分批止盈并写入交易台账（失败只记录日志，止损仍然有效）
+func (at *AutoTrader) setupTakeProfitLadder(symbol, side string, quantity float64, levels []decision.TakeProfitLevel) {
+	log.Printf("  🎯 分批止盈: %s", takeProfitLadderSummary(levels))
+	placed, err := at.placeTakeProfitLadder(symbol, side, quantity, levels)
+	if err != nil {
+		log.Printf("  ⚠️ %s %v", symbol, err)
+	}
+	at.recordTradeTakeProfitLevels(symbol, side, placed)
+}
+
+// takeProfitLadderSummary 分批止盈档位摘要（日志用）
+func takeProfitLadderSummary(levels []decision.TakeProfitLevel) string {
+	parts := make([]string, 0, len(levels))
+	for _, level := range levels {
+		parts = append(parts, fmt.Sprintf("%.4f(%.0f%%)", level.Price, level.Percentage))
+	}
+	return strings.Join(parts, " / ")
+}
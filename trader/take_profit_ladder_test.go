@@ -0,0 +1,87 @@
+package trader
+
+import (
+	"math"
+	"nofx/config"
+	"nofx/decision"
+	"testing"
+)
+
+func TestSplitTakeProfitLevels(t *testing.T) {
+	tests := []struct {
+		name     string
+		inst     Instrument
+		quantity float64
+		levels   []decision.TakeProfitLevel
+		want     []config.TakeProfitLevel
+	}{
+		{
+			name:     "按比例拆分",
+			inst:     Instrument{Symbol: "BTCUSDT", QtyStep: 0.01},
+			quantity: 1,
+			levels:   []decision.TakeProfitLevel{{Price: 110, Percentage: 50}, {Price: 120, Percentage: 30}, {Price: 130, Percentage: 20}},
+			want:     []config.TakeProfitLevel{{Price: 110, Quantity: 0.5}, {Price: 120, Quantity: 0.3}, {Price: 130, Quantity: 0.2}},
+		},
+		{
+			name:     "取整余量并入下一档，最后一档使用剩余数量",
+			inst:     Instrument{Symbol: "BTCUSDT", QtyStep: 0.1},
+			quantity: 1,
+			levels:   []decision.TakeProfitLevel{{Price: 110, Percentage: 33}, {Price: 120, Percentage: 33}, {Price: 130, Percentage: 34}},
+			want:     []config.TakeProfitLevel{{Price: 110, Quantity: 0.3}, {Price: 120, Quantity: 0.3}, {Price: 130, Quantity: 0.4}},
+		},
+		{
+			name:     "不足最小下单量的档位并入下一档",
+			inst:     Instrument{Symbol: "BTCUSDT", QtyStep: 0.01, MinQty: 0.2},
+			quantity: 1,
+			levels:   []decision.TakeProfitLevel{{Price: 110, Percentage: 10}, {Price: 120, Percentage: 40}, {Price: 130, Percentage: 50}},
+			want:     []config.TakeProfitLevel{{Price: 120, Quantity: 0.5}, {Price: 130, Quantity: 0.5}},
+		},
+		{
+			name:     "不足最小下单金额的档位并入下一档",
+			inst:     Instrument{Symbol: "ETHUSDT", QtyStep: 0.01, MinNotional: 5},
+			quantity: 1,
+			levels:   []decision.TakeProfitLevel{{Price: 10, Percentage: 20}, {Price: 12, Percentage: 80}},
+			want:     []config.TakeProfitLevel{{Price: 12, Quantity: 1}},
+		},
+		{
+			name:     "比例合计不足100%时不补足剩余数量",
+			inst:     Instrument{Symbol: "BTCUSDT", QtyStep: 0.01},
+			quantity: 1,
+			levels:   []decision.TakeProfitLevel{{Price: 110, Percentage: 30}, {Price: 120, Percentage: 30}},
+			want:     []config.TakeProfitLevel{{Price: 110, Quantity: 0.3}, {Price: 120, Quantity: 0.3}},
+		},
+		{
+			name:     "最后一档剩余数量不足最小下单量时并入上一档",
+			inst:     Instrument{Symbol: "BTCUSDT", QtyStep: 0.01, MinQty: 0.2},
+			quantity: 1,
+			levels:   []decision.TakeProfitLevel{{Price: 110, Percentage: 90}, {Price: 120, Percentage: 10}},
+			want:     []config.TakeProfitLevel{{Price: 110, Quantity: 1}},
+		},
+		{
+			name:     "持仓数量不足任何一档",
+			inst:     Instrument{Symbol: "BTCUSDT", QtyStep: 0.01, MinQty: 0.05},
+			quantity: 0.04,
+			levels:   []decision.TakeProfitLevel{{Price: 110, Percentage: 50}, {Price: 120, Percentage: 50}},
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitTakeProfitLevels(&tt.inst, tt.quantity, tt.levels)
+			if len(got) != len(tt.want) {
+				t.Fatalf("档位数 = %d，期望 %d: %+v", len(got), len(tt.want), got)
+			}
+			total := 0.0
+			for i := range got {
+				if got[i].Price != tt.want[i].Price || math.Abs(got[i].Quantity-tt.want[i].Quantity) > 1e-9 {
+					t.Errorf("第%d档 = %+v，期望 %+v", i+1, got[i], tt.want[i])
+				}
+				total += got[i].Quantity
+			}
+			if total > tt.quantity+1e-9 {
+				t.Errorf("拆分总量 %.8f 超过持仓数量 %.8f", total, tt.quantity)
+			}
+		})
+	}
+}
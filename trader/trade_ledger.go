@@ -0,0 +1,456 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/config"
+	"nofx/logger"
+	"time"
+)
+
+// TradeLedger 交易台账存储（由 config.Database 实现）
+type TradeLedger interface {
+	RecordTradeOpen(traderID, symbol, side string, leverage int, stopLoss, takeProfit float64, fill config.TradeFill) (*config.TradeRecord, error)
+	RecordTradeClose(traderID, symbol, side string, fill config.TradeFill) (*config.TradeRecord, error)
+	UpdateTradeStops(traderID, symbol, side string, stopLoss, takeProfit float64) error
+	UpdateTradeTrailing(traderID, symbol, side string, callback, activate, peak float64, native bool) error
+	UpdateTradeTakeProfitLevels(traderID, symbol, side string, levels []config.TakeProfitLevel) error
+	SetTradeCosts(tradeID int64, openFee, closeFee, funding float64) error
+	GetOpenTrades(traderID string) ([]*config.TradeRecord, error)
+	GetClosedTrades(traderID string, limit int) ([]*config.TradeRecord, error)
+	GetTrades(traderID string, limit int) ([]*config.TradeRecord, error)
+	GetTradeFills(tradeID int64) ([]config.TradeFill, error)
+	TradeFillExists(traderID, orderID string) (bool, error)
+	GetTradeStats(traderID string) (*config.TradeStats, error)
+	SavePendingEntry(traderID, symbol, side, orderID, data string) error
+	DeletePendingEntry(traderID, symbol, side, orderID string) error
+	GetPendingEntries(traderID string) ([]string, error)
+}
+
+// performanceTradeLimit AI表现反馈和 /performance 使用的最近已平仓交易数量
+const performanceTradeLimit = 200
+
+// recordTradeOpen 开仓成交写入交易台账（失败只记录日志，不影响交易）
+func (at *AutoTrader) recordTradeOpen(symbol, side string, leverage int, stopLoss, takeProfit, quantity, price float64, order *OrderResult) {
+	if at.ledger == nil {
+		return
+	}
+	fill := config.TradeFill{
+		OrderID:  order.OrderID,
+		Price:    price,
+		Quantity: quantity,
+		Fee:      order.Fee,
+		Time:     time.Now(),
+	}
+	if order.AvgPrice > 0 {
+		fill.Price = order.AvgPrice
+	}
+	if order.ExecutedQty > 0 {
+		fill.Quantity = order.ExecutedQty
+	}
+	if _, err := at.ledger.RecordTradeOpen(at.id, symbol, side, leverage, stopLoss, takeProfit, fill); err != nil {
+		log.Printf("⚠️ [%s] 记录交易台账（开仓）失败: %v", at.name, err)
+	}
+}
+
+// recordTradeClose 平仓成交写入交易台账（quantity=0 表示全部平仓，price为0时使用市场价）
+func (at *AutoTrader) recordTradeClose(symbol, side string, quantity, price float64, order *OrderResult, reason string) {
+	if at.ledger == nil {
+		return
+	}
+	fill := config.TradeFill{
+		Price:    price,
+		Quantity: quantity,
+		Reason:   reason,
+		Time:     time.Now(),
+	}
+	if order != nil {
+		fill.OrderID = order.OrderID
+		fill.Fee = order.Fee
+		if order.AvgPrice > 0 {
+			fill.Price = order.AvgPrice
+		}
+		if quantity > 0 && order.ExecutedQty > 0 {
+			fill.Quantity = order.ExecutedQty
+		}
+	}
+	if fill.Price <= 0 {
+		if p, err := at.trader.GetMarketPrice(symbol); err == nil {
+			fill.Price = p
+		}
+	}
+
+	trade, err := at.ledger.RecordTradeClose(at.id, symbol, side, fill)
+	if err != nil {
+		log.Printf("⚠️ [%s] 记录交易台账（平仓）失败: %v", at.name, err)
+		return
+	}
+	if trade != nil && trade.Status == config.TradeStatusClosed {
+		at.syncTradeCosts(trade)
+	}
+}
+
+// recordTradeStops 止盈止损调整写入交易台账（用于推断交易所侧平仓原因）
+func (at *AutoTrader) recordTradeStops(symbol, side string, stopLoss, takeProfit float64) {
+	if at.ledger == nil {
+		return
+	}
+	if err := at.ledger.UpdateTradeStops(at.id, symbol, side, stopLoss, takeProfit); err != nil {
+		log.Printf("⚠️ [%s] 更新交易台账止盈止损失败: %v", at.name, err)
+	}
+}
+
+// recordTradeTakeProfitLevels 分批止盈档位写入交易台账（levels为空表示改回单一止盈）
+func (at *AutoTrader) recordTradeTakeProfitLevels(symbol, side string, levels []config.TakeProfitLevel) {
+	if at.ledger == nil {
+		return
+	}
+	if err := at.ledger.UpdateTradeTakeProfitLevels(at.id, symbol, side, levels); err != nil {
+		log.Printf("⚠️ [%s] 更新交易台账分批止盈失败: %v", at.name, err)
+	}
+}
+
+// reconcileTradeLedger 将交易台账与交易所持仓对账
+//  1. 交易所有持仓但台账没有记录（如升级前开的仓、手动开仓）：按持仓补记开仓
+//  2. 台账未平仓但交易所已无持仓（交易所侧止盈止损/强平）：从成交历史补记平仓，
+//     无成交历史时按当前市场价记为交易所侧平仓
+//  3. 持仓数量小于台账剩余数量（分批止盈等交易所侧部分平仓）：从成交历史补记部分平仓
+func (at *AutoTrader) reconcileTradeLedger(positions []Position) {
+	if at.ledger == nil {
+		return
+	}
+	at.tradeMutex.Lock()
+	defer at.tradeMutex.Unlock()
+
+	openTrades, err := at.ledger.GetOpenTrades(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 交易台账对账失败: %v", at.name, err)
+		return
+	}
+
+	ledgerKeys := make(map[string]bool)
+	for _, trade := range openTrades {
+		ledgerKeys[trade.Symbol+"_"+trade.Side] = true
+	}
+	positionKeys := make(map[string]bool)
+	positionQty := make(map[string]float64)
+	for _, pos := range positions {
+		if pos.Quantity == 0 {
+			continue
+		}
+		key := pos.Symbol + "_" + pos.Side
+		positionKeys[key] = true
+		positionQty[key] = math.Abs(pos.Quantity)
+		if ledgerKeys[key] || pos.EntryPrice <= 0 {
+			continue
+		}
+		openTime := time.Now()
+		if ms, ok := at.positionFirstSeenTime[key]; ok {
+			openTime = time.UnixMilli(ms)
+		}
+		_, err := at.ledger.RecordTradeOpen(at.id, pos.Symbol, pos.Side, pos.Leverage, 0, 0, config.TradeFill{
+			Price:    pos.EntryPrice,
+			Quantity: pos.Quantity,
+			Reason:   "reconcile",
+			Time:     openTime,
+		})
+		if err != nil {
+			log.Printf("⚠️ [%s] 补记 %s %s 开仓失败: %v", at.name, pos.Symbol, pos.Side, err)
+			continue
+		}
+		log.Printf("📒 [%s] 交易台账补记持仓: %s %s 数量 %.8f 均价 %.4f", at.name, pos.Symbol, pos.Side, pos.Quantity, pos.EntryPrice)
+	}
+
+	for _, trade := range openTrades {
+		key := trade.Symbol + "_" + trade.Side
+		if positionKeys[key] {
+			if qty := positionQty[key]; qty < trade.RemainingQuantity*(1-partialExitTolerance) {
+				at.reconcilePartialExits(trade, qty)
+			}
+			continue
+		}
+		at.reconcileClosedTrade(trade)
+	}
+}
+
+// partialExitTolerance 持仓数量比台账剩余数量少超过该比例时才对账部分平仓（避免数量取整误差）
+const partialExitTolerance = 0.001
+
+// reconcilePartialExits 补记交易所侧部分平仓（如分批止盈档位成交），直到台账剩余数量与持仓数量一致
+// 成交历史不可用时保留台账，等持仓全部平仓后一并对账
+func (at *AutoTrader) reconcilePartialExits(trade *config.TradeRecord, positionQty float64) {
+	fills, err := at.trader.GetUserTrades(trade.Symbol, trade.OpenTime)
+	if err != nil {
+		log.Printf("⚠️ [%s] 获取 %s 成交历史失败: %v", at.name, trade.Symbol, err)
+		return
+	}
+	remaining := trade.RemainingQuantity
+	for _, fill := range fills {
+		if remaining <= positionQty*(1+partialExitTolerance) {
+			break
+		}
+		if !fill.IsClose || fill.Side != trade.Side {
+			continue
+		}
+		if exists, err := at.ledger.TradeFillExists(at.id, fill.OrderID); err != nil || exists {
+			continue
+		}
+		reason := fill.Reason
+		if reason == "" || reason == paperExitReasonMarketClose {
+			reason = inferExitReason(trade, fill.Price)
+		}
+		updated, err := at.ledger.RecordTradeClose(at.id, trade.Symbol, trade.Side, config.TradeFill{
+			OrderID:     fill.OrderID,
+			Price:       fill.Price,
+			Quantity:    fill.Quantity,
+			Fee:         fill.Fee,
+			RealizedPnL: fill.RealizedPnL,
+			Reason:      reason,
+			Time:        fill.Time,
+		})
+		if err != nil || updated == nil {
+			log.Printf("⚠️ [%s] 补记 %s 部分平仓失败: %v", at.name, trade.Symbol, err)
+			return
+		}
+		remaining = updated.RemainingQuantity
+		log.Printf("📒 [%s] 交易台账对账: %s %s 部分平仓（%s）%.8f @ %.4f，剩余 %.8f",
+			at.name, trade.Symbol, trade.Side, reason, fill.Quantity, fill.Price, remaining)
+		if updated.Status == config.TradeStatusClosed {
+			at.syncTradeCosts(updated)
+			return
+		}
+	}
+}
+
+// reconcileClosedTrade 补记交易所侧已平仓交易
+func (at *AutoTrader) reconcileClosedTrade(trade *config.TradeRecord) {
+	var closed *config.TradeRecord
+	fills, err := at.trader.GetUserTrades(trade.Symbol, trade.OpenTime)
+	if err != nil {
+		log.Printf("⚠️ [%s] 获取 %s 成交历史失败: %v", at.name, trade.Symbol, err)
+	}
+	for _, fill := range fills {
+		if !fill.IsClose || fill.Side != trade.Side {
+			continue
+		}
+		if exists, err := at.ledger.TradeFillExists(at.id, fill.OrderID); err != nil || exists {
+			continue
+		}
+		reason := fill.Reason
+		if reason == "" || reason == paperExitReasonMarketClose {
+			reason = inferExitReason(trade, fill.Price)
+		}
+		closed, err = at.ledger.RecordTradeClose(at.id, trade.Symbol, trade.Side, config.TradeFill{
+			OrderID:     fill.OrderID,
+			Price:       fill.Price,
+			Quantity:    fill.Quantity,
+			Fee:         fill.Fee,
+			RealizedPnL: fill.RealizedPnL,
+			Reason:      reason,
+			Time:        fill.Time,
+		})
+		if err != nil {
+			log.Printf("⚠️ [%s] 补记 %s 平仓成交失败: %v", at.name, trade.Symbol, err)
+			return
+		}
+		if closed == nil || closed.Status == config.TradeStatusClosed {
+			break
+		}
+	}
+
+	// 成交历史不可用或不完整：按当前市场价平掉剩余数量
+	if closed == nil || closed.Status != config.TradeStatusClosed {
+		price, err := at.trader.GetMarketPrice(trade.Symbol)
+		if err != nil {
+			log.Printf("⚠️ [%s] 获取 %s 价格失败，稍后重试对账: %v", at.name, trade.Symbol, err)
+			return
+		}
+		closed, err = at.ledger.RecordTradeClose(at.id, trade.Symbol, trade.Side, config.TradeFill{
+			Price:  price,
+			Reason: inferExitReason(trade, price),
+		})
+		if err != nil || closed == nil {
+			log.Printf("⚠️ [%s] 补记 %s 平仓失败: %v", at.name, trade.Symbol, err)
+			return
+		}
+	}
+
+	log.Printf("📒 [%s] 交易台账对账: %s %s 已在交易所平仓（%s），均价 %.4f，净盈亏 %+.4f USDT",
+		at.name, closed.Symbol, closed.Side, closed.ExitReason, closed.ExitPrice, closed.NetPnL())
+	at.syncTradeCosts(closed)
+}
+
+// syncTradeCosts 用交易所成交历史和资金费流水校正已平仓交易的手续费和资金费（不支持资金费查询的交易所保留原值）
+func (at *AutoTrader) syncTradeCosts(trade *config.TradeRecord) {
+	if trade.CloseTime == nil {
+		return
+	}
+
+	openFee, closeFee := trade.OpenFee, trade.CloseFee
+	fills, err := at.trader.GetUserTrades(trade.Symbol, trade.OpenTime.Add(-time.Minute))
+	if err == nil {
+		recorded, err := at.ledger.GetTradeFills(trade.ID)
+		if err == nil {
+			orderIDs := make(map[string]string)
+			for _, f := range recorded {
+				if f.OrderID != "" {
+					orderIDs[f.OrderID] = f.Type
+				}
+			}
+			exchangeOpenFee, exchangeCloseFee := 0.0, 0.0
+			matched := false
+			for _, fill := range fills {
+				fillType, ok := orderIDs[fill.OrderID]
+				if !ok {
+					continue
+				}
+				matched = true
+				if fillType == config.TradeFillOpen {
+					exchangeOpenFee += fill.Fee
+				} else {
+					exchangeCloseFee += fill.Fee
+				}
+			}
+			if matched {
+				openFee = math.Max(openFee, exchangeOpenFee)
+				closeFee = math.Max(closeFee, exchangeCloseFee)
+			}
+		}
+	}
+
+	funding := trade.FundingFee
+	if provider, ok := at.trader.(FundingFeeProvider); ok {
+		if funding, err = provider.GetFundingFees(trade.Symbol, trade.OpenTime); err != nil {
+			log.Printf("⚠️ [%s] 获取 %s 资金费失败: %v", at.name, trade.Symbol, err)
+			funding = trade.FundingFee
+		}
+	}
+
+	if openFee == trade.OpenFee && closeFee == trade.CloseFee && funding == trade.FundingFee {
+		return
+	}
+	if err := at.ledger.SetTradeCosts(trade.ID, openFee, closeFee, funding); err != nil {
+		log.Printf("⚠️ [%s] 更新交易台账费用失败: %v", at.name, err)
+	}
+}
+
+// inferExitReason 根据平仓价与止盈止损价的距离推断交易所侧平仓原因
+func inferExitReason(trade *config.TradeRecord, price float64) string {
+	if price <= 0 {
+		return config.TradeExitExchange
+	}
+	slDist, tpDist := math.Inf(1), math.Inf(1)
+	if trade.StopLoss > 0 {
+		slDist = math.Abs(price-trade.StopLoss) / price
+	}
+	if trade.TakeProfit > 0 {
+		tpDist = math.Abs(price-trade.TakeProfit) / price
+	}
+	// 分批止盈：取最近一档
+	for _, level := range trade.TakeProfitLevels {
+		tpDist = math.Min(tpDist, math.Abs(price-level.Price)/price)
+	}
+	// 平仓价偏离触发价超过1%时无法确定原因
+	const maxTriggerSlippage = 0.01
+	switch {
+	case slDist <= tpDist && slDist <= maxTriggerSlippage:
+		return config.TradeExitStopLoss
+	case tpDist < slDist && tpDist <= maxTriggerSlippage:
+		return config.TradeExitTakeProfit
+	case trade.TrailingCallback > 0:
+		// 设置了跟踪止损且不在固定止盈止损附近，视为跟踪止损触发
+		return config.TradeExitTrailing
+	}
+	return config.TradeExitExchange
+}
+
+// tradeOutcome 交易台账记录转换为交易结果（PnL为扣除手续费、计入资金费后的净盈亏）
+func tradeOutcome(trade *config.TradeRecord) logger.TradeOutcome {
+	outcome := logger.TradeOutcome{
+		Symbol:        trade.Symbol,
+		Side:          trade.Side,
+		Quantity:      trade.Quantity,
+		Leverage:      trade.Leverage,
+		OpenPrice:     trade.EntryPrice,
+		ClosePrice:    trade.ExitPrice,
+		PositionValue: trade.Quantity * trade.EntryPrice,
+		PnL:           trade.NetPnL(),
+		OpenTime:      trade.OpenTime,
+		WasStopLoss:   trade.ExitReason == config.TradeExitStopLoss || trade.ExitReason == config.TradeExitLiquidation,
+		Fee:           trade.OpenFee + trade.CloseFee,
+		FundingFee:    trade.FundingFee,
+		ExitReason:    trade.ExitReason,
+		PartialExits:  trade.PartialExits,
+	}
+	if trade.Leverage > 0 {
+		outcome.MarginUsed = outcome.PositionValue / float64(trade.Leverage)
+	}
+	if outcome.MarginUsed > 0 {
+		outcome.PnLPct = outcome.PnL / outcome.MarginUsed * 100
+	}
+	if trade.CloseTime != nil {
+		outcome.CloseTime = *trade.CloseTime
+		outcome.Duration = trade.CloseTime.Sub(trade.OpenTime).String()
+	}
+	return outcome
+}
+
+// AnalyzePerformance 分析交易表现
+// 有交易台账时使用台账中的已平仓交易（含手续费、资金费和交易所侧平仓），否则从决策日志重建
+func (at *AutoTrader) AnalyzePerformance() (*logger.PerformanceAnalysis, error) {
+	if at.ledger == nil {
+		// 分析最近100个周期的交易表现（假设每3分钟一个周期，100个周期 = 5小时）
+		return at.decisionLogger.AnalyzePerformance(100)
+	}
+
+	closed, err := at.ledger.GetClosedTrades(at.id, performanceTradeLimit)
+	if err != nil {
+		return nil, fmt.Errorf("读取交易台账失败: %w", err)
+	}
+	trades := make([]logger.TradeOutcome, 0, len(closed))
+	for _, trade := range closed {
+		trades = append(trades, tradeOutcome(trade))
+	}
+
+	// 夏普比率仍基于决策周期的净值序列
+	var equities []float64
+	if records, err := at.decisionLogger.GetLatestRecords(100); err == nil {
+		for _, record := range records {
+			equities = append(equities, record.AccountState.TotalBalance)
+		}
+	}
+	return logger.AnalyzeTrades(trades, equities), nil
+}
+
+// GetStatistics 获取统计信息（决策周期来自决策日志，开平仓数量和盈亏来自交易台账）
+func (at *AutoTrader) GetStatistics() (*logger.Statistics, error) {
+	stats, err := at.decisionLogger.GetStatistics()
+	if err != nil {
+		return nil, err
+	}
+	if at.ledger == nil {
+		return stats, nil
+	}
+
+	tradeStats, err := at.ledger.GetTradeStats(at.id)
+	if err != nil {
+		return nil, fmt.Errorf("读取交易台账失败: %w", err)
+	}
+	stats.TotalOpenPositions = tradeStats.OpenedTrades
+	stats.TotalClosePositions = tradeStats.ClosedTrades
+	stats.RealizedPnL = tradeStats.RealizedPnL
+	stats.TotalFees = tradeStats.TotalFees
+	stats.TotalFunding = tradeStats.TotalFunding
+	stats.PartialExits = tradeStats.PartialExits
+	stats.PartialExitPnL = tradeStats.PartialExitPnL
+	return stats, nil
+}
+
+// GetTrades 获取交易台账记录（含成交明细，按开仓时间倒序）
+func (at *AutoTrader) GetTrades(limit int) ([]*config.TradeRecord, error) {
+	if at.ledger == nil {
+		return nil, fmt.Errorf("交易台账不可用")
+	}
+	return at.ledger.GetTrades(at.id, limit)
+}
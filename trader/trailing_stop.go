@@ -0,0 +1,356 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/config"
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+// trailingCheckInterval 本地跟踪止损检查间隔（只有本地跟踪止损存在时才拉取价格）
+const trailingCheckInterval = 5 * time.Second
+
+// trailingStop 持仓的跟踪止损（原生跟踪单由交易所触发，本地只记录参数；本地跟踪单由 checkTrailingStops 维护极值并平仓）
+type trailingStop struct {
+	Symbol          string
+	Side            string  // long / short
+	CallbackRate    float64 // 回调比例（%）
+	ActivationPrice float64 // 激活价，0表示立即激活
+	Peak            float64 // 激活后的极值价（多单最高价/空单最低价），0表示尚未激活
+	Native          bool    // 是否为交易所原生跟踪单
+}
+
+// trailingPeak 用最新价更新跟踪极值：未激活时价格越过激活价才开始跟踪，返回新的极值（未激活为0）
+func trailingPeak(side string, peak, activationPrice, price float64) float64 {
+	if price <= 0 {
+		return peak
+	}
+	if peak <= 0 {
+		if activationPrice > 0 && ((side == "long" && price < activationPrice) || (side == "short" && price > activationPrice)) {
+			return 0
+		}
+		return price
+	}
+	if (side == "long" && price > peak) || (side == "short" && price < peak) {
+		return price
+	}
+	return peak
+}
+
+// trailingStopPrice 跟踪止损当前触发价（未激活返回0）
+func trailingStopPrice(side string, peak, callbackRate float64) float64 {
+	if peak <= 0 {
+		return 0
+	}
+	if side == "long" {
+		return peak * (1 - callbackRate/100)
+	}
+	return peak * (1 + callbackRate/100)
+}
+
+// executeSetTrailingStopWithRecord 执行设置跟踪止损：交易所支持时挂原生跟踪单，否则（或下单失败）由本地跟踪引擎维护
+func (at *AutoTrader) executeSetTrailingStopWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+	log.Printf("  🎯 设置跟踪止损: %s 回调 %.2f%% 激活价 %.4f", decision.Symbol, decision.CallbackRate, decision.ActivationPrice)
+
+	// 使用交易所自身的价格校验激活价和初始化极值（与本地跟踪引擎一致）
+	currentPrice, err := at.trader.GetMarketPrice(decision.Symbol)
+	if err != nil {
+		return fmt.Errorf("获取价格失败: %w", err)
+	}
+	actionRecord.Price = currentPrice
+
+	// 获取当前持仓
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return fmt.Errorf("获取持仓失败: %w", err)
+	}
+	// 按决策指定的方向选择持仓；未指定方向且同币种双向持仓时无法确定作用的仓位，拒绝执行
+	var targetPosition *Position
+	sideCount := 0
+	for i := range positions {
+		if positions[i].Symbol != decision.Symbol || positions[i].Quantity == 0 {
+			continue
+		}
+		sideCount++
+		if decision.PositionSide == "" || positions[i].Side == decision.PositionSide {
+			targetPosition = &positions[i]
+		}
+	}
+	if decision.PositionSide == "" && sideCount > 1 {
+		return fmt.Errorf("%s 同时持有多空双向仓位，请通过 position_side 指定跟踪止损的方向", decision.Symbol)
+	}
+	if targetPosition == nil {
+		if decision.PositionSide != "" {
+			return fmt.Errorf("持仓不存在: %s %s", decision.Symbol, decision.PositionSide)
+		}
+		return fmt.Errorf("持仓不存在: %s", decision.Symbol)
+	}
+
+	side := targetPosition.Side
+	positionSide := strings.ToUpper(side)
+	quantity := math.Abs(targetPosition.Quantity)
+
+	// 激活价必须在尚未到达的盈利方向，否则交易所会拒绝（立即触发）
+	if decision.ActivationPrice > 0 {
+		if side == "long" && decision.ActivationPrice <= currentPrice {
+			return fmt.Errorf("多单跟踪止损激活价必须高于当前价格 (当前: %.4f, 激活价: %.4f)", currentPrice, decision.ActivationPrice)
+		}
+		if side == "short" && decision.ActivationPrice >= currentPrice {
+			return fmt.Errorf("空单跟踪止损激活价必须低于当前价格 (当前: %.4f, 激活价: %.4f)", currentPrice, decision.ActivationPrice)
+		}
+	}
+
+	stop := &trailingStop{
+		Symbol:          decision.Symbol,
+		Side:            side,
+		CallbackRate:    decision.CallbackRate,
+		ActivationPrice: decision.ActivationPrice,
+	}
+	// 双向持仓时 CancelTrailingStopOrders 会撤掉另一方向的原生跟踪单，改用本地跟踪
+	if sideCount == 1 {
+		stop.Native = at.placeNativeTrailingStop(stop, quantity)
+	}
+	if !stop.Native {
+		stop.Peak = trailingPeak(side, 0, stop.ActivationPrice, currentPrice)
+	}
+	at.saveTrailingStop(stop)
+
+	mode := "本地跟踪"
+	if stop.Native {
+		mode = "交易所原生"
+	}
+	log.Printf("  ✓ 跟踪止损已设置（%s）: %s %s 回调 %.2f%% (当前价格: %.4f)",
+		mode, decision.Symbol, positionSide, decision.CallbackRate, currentPrice)
+	return nil
+}
+
+// placeNativeTrailingStop 交易所支持时替换原生跟踪单，成功返回true（不支持或失败时由本地引擎接管）
+func (at *AutoTrader) placeNativeTrailingStop(stop *trailingStop, quantity float64) bool {
+	stopper, ok := at.trader.(TrailingStopper)
+	if !ok {
+		return false
+	}
+	if err := stopper.CancelTrailingStopOrders(stop.Symbol); err != nil {
+		log.Printf("  ⚠ 取消旧跟踪止损单失败: %v", err)
+	}
+	if err := stopper.SetTrailingStop(stop.Symbol, strings.ToUpper(stop.Side), quantity, stop.CallbackRate, stop.ActivationPrice); err != nil {
+		log.Printf("  ⚠ 交易所跟踪止损单设置失败，改用本地跟踪: %v", err)
+		return false
+	}
+	return true
+}
+
+// saveTrailingStop 保存跟踪止损状态并写入交易台账（重启后恢复）
+func (at *AutoTrader) saveTrailingStop(stop *trailingStop) {
+	at.trailingMutex.Lock()
+	at.trailingStops[stop.Symbol+"_"+stop.Side] = stop
+	at.trailingMutex.Unlock()
+	at.recordTradeTrailing(stop)
+}
+
+// recordTradeTrailing 跟踪止损参数和极值写入交易台账（失败只记录日志）
+func (at *AutoTrader) recordTradeTrailing(stop *trailingStop) {
+	if at.ledger == nil {
+		return
+	}
+	if err := at.ledger.UpdateTradeTrailing(at.id, stop.Symbol, stop.Side, stop.CallbackRate, stop.ActivationPrice, stop.Peak, stop.Native); err != nil {
+		log.Printf("⚠️ [%s] 更新交易台账跟踪止损失败: %v", at.name, err)
+	}
+}
+
+// getTrailingStop 获取持仓的跟踪止损（副本），不存在返回nil
+func (at *AutoTrader) getTrailingStop(symbol, side string) *trailingStop {
+	at.trailingMutex.Lock()
+	defer at.trailingMutex.Unlock()
+	stop, ok := at.trailingStops[symbol+"_"+side]
+	if !ok {
+		return nil
+	}
+	copied := *stop
+	return &copied
+}
+
+// clearTrailingStop 持仓平掉后清除跟踪止损（原生跟踪单同时撤单，避免残留挂单）
+func (at *AutoTrader) clearTrailingStop(symbol, side string) {
+	key := symbol + "_" + side
+	at.trailingMutex.Lock()
+	stop, ok := at.trailingStops[key]
+	delete(at.trailingStops, key)
+	at.trailingMutex.Unlock()
+	if !ok || !stop.Native {
+		return
+	}
+	if stopper, ok := at.trader.(TrailingStopper); ok {
+		if err := stopper.CancelTrailingStopOrders(symbol); err != nil {
+			log.Printf("⚠️ [%s] 清理 %s 残留跟踪止损单失败: %v", at.name, symbol, err)
+		}
+	}
+}
+
+// pruneTrailingStops 清除已不存在持仓的跟踪止损（openKeys 为当前持仓的 symbol_side 集合）
+func (at *AutoTrader) pruneTrailingStops(openKeys map[string]bool) {
+	at.trailingMutex.Lock()
+	var stale []*trailingStop
+	for key, stop := range at.trailingStops {
+		if !openKeys[key] {
+			stale = append(stale, stop)
+		}
+	}
+	at.trailingMutex.Unlock()
+	for _, stop := range stale {
+		at.clearTrailingStop(stop.Symbol, stop.Side)
+	}
+}
+
+// resizeTrailingStop 部分平仓后按剩余数量重挂原生跟踪单（部分交易所平仓时会撤销全部挂单），失败时改为本地跟踪
+func (at *AutoTrader) resizeTrailingStop(symbol, side string, quantity float64) {
+	stop := at.getTrailingStop(symbol, side)
+	if stop == nil || !stop.Native || quantity <= 0 {
+		return
+	}
+	if at.placeNativeTrailingStop(stop, quantity) {
+		return
+	}
+	stop.Native = false
+	if price, err := at.trader.GetMarketPrice(symbol); err == nil {
+		stop.Peak = trailingPeak(side, 0, stop.ActivationPrice, price)
+	}
+	at.saveTrailingStop(stop)
+}
+
+// restoreTrailingStops 从交易台账恢复未平仓交易的跟踪止损（本地跟踪的极值也一并恢复）
+func (at *AutoTrader) restoreTrailingStops() {
+	if at.ledger == nil {
+		return
+	}
+	trades, err := at.ledger.GetOpenTrades(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 恢复跟踪止损失败: %v", at.name, err)
+		return
+	}
+
+	at.trailingMutex.Lock()
+	defer at.trailingMutex.Unlock()
+	for _, trade := range trades {
+		if trade.TrailingCallback <= 0 {
+			continue
+		}
+		at.trailingStops[trade.Symbol+"_"+trade.Side] = &trailingStop{
+			Symbol:          trade.Symbol,
+			Side:            trade.Side,
+			CallbackRate:    trade.TrailingCallback,
+			ActivationPrice: trade.TrailingActivate,
+			Peak:            trade.TrailingPeak,
+			Native:          trade.TrailingNative,
+		}
+		log.Printf("🔁 [%s] 恢复跟踪止损: %s %s 回调 %.2f%% 极值 %.4f", at.name, trade.Symbol, trade.Side, trade.TrailingCallback, trade.TrailingPeak)
+	}
+}
+
+// startTrailingMonitor 启动本地跟踪止损引擎（交易所不支持原生跟踪单时按最新价维护极值并触发平仓），
+// 同一个定时器也负责检查保本止损，避免价格在两次AI周期之间触及保本阈值又回落
+func (at *AutoTrader) startTrailingMonitor() {
+	at.restoreTrailingStops()
+
+	at.monitorWg.Add(1)
+	go func() {
+		defer at.monitorWg.Done()
+
+		ticker := time.NewTicker(trailingCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				at.checkTrailingStops()
+				at.checkBreakEvenStops()
+			case <-at.stopMonitorCh:
+				return
+			}
+		}
+	}()
+}
+
+// checkTrailingStops 检查本地跟踪止损：更新极值（变化时写入台账），价格回撤达到回调比例时市价平仓
+func (at *AutoTrader) checkTrailingStops() {
+	at.trailingMutex.Lock()
+	var local []trailingStop
+	for _, stop := range at.trailingStops {
+		if !stop.Native {
+			local = append(local, *stop)
+		}
+	}
+	at.trailingMutex.Unlock()
+	if len(local) == 0 {
+		return
+	}
+
+	positions, err := at.getPositions()
+	if err != nil {
+		log.Printf("❌ 跟踪止损：获取持仓失败: %v", err)
+		return
+	}
+
+	for i := range local {
+		stop := &local[i]
+		if pos, ok := FindPosition(positions, stop.Symbol, stop.Side); !ok || pos.Quantity == 0 {
+			at.clearTrailingStop(stop.Symbol, stop.Side)
+			continue
+		}
+		price, err := at.trader.GetMarketPrice(stop.Symbol)
+		if err != nil {
+			log.Printf("⚠️ 跟踪止损：获取 %s 价格失败: %v", stop.Symbol, err)
+			continue
+		}
+
+		if peak := trailingPeak(stop.Side, stop.Peak, stop.ActivationPrice, price); peak != stop.Peak {
+			if stop.Peak == 0 {
+				log.Printf("📈 跟踪止损已激活: %s %s 价格 %.4f", stop.Symbol, stop.Side, price)
+			}
+			stop.Peak = peak
+			at.updateTrailingPeak(stop)
+		}
+
+		stopPrice := trailingStopPrice(stop.Side, stop.Peak, stop.CallbackRate)
+		if stopPrice <= 0 || (stop.Side == "long" && price > stopPrice) || (stop.Side == "short" && price < stopPrice) {
+			continue
+		}
+
+		log.Printf("🚨 触发跟踪止损: %s %s | 当前价: %.4f | 极值: %.4f | 回调: %.2f%% | 触发价: %.4f",
+			stop.Symbol, stop.Side, price, stop.Peak, stop.CallbackRate, stopPrice)
+		if err := at.emergencyClosePosition(stop.Symbol, stop.Side, config.TradeExitTrailing); err != nil {
+			log.Printf("❌ 跟踪止损平仓失败 (%s %s): %v", stop.Symbol, stop.Side, err)
+			continue
+		}
+		log.Printf("✅ 跟踪止损平仓成功: %s %s", stop.Symbol, stop.Side)
+	}
+}
+
+// updateTrailingPeak 更新本地跟踪极值（状态已被替换或清除时忽略）
+func (at *AutoTrader) updateTrailingPeak(stop *trailingStop) {
+	at.trailingMutex.Lock()
+	current, ok := at.trailingStops[stop.Symbol+"_"+stop.Side]
+	if !ok || current.Native || current.CallbackRate != stop.CallbackRate || current.ActivationPrice != stop.ActivationPrice {
+		at.trailingMutex.Unlock()
+		return
+	}
+	current.Peak = stop.Peak
+	at.trailingMutex.Unlock()
+	at.recordTradeTrailing(stop)
+}
+
+// trailingStopInfo 填充决策上下文中持仓的跟踪止损信息
+func (at *AutoTrader) trailingStopInfo(info *decision.PositionInfo) {
+	stop := at.getTrailingStop(info.Symbol, info.Side)
+	if stop == nil {
+		return
+	}
+	info.TrailingCallbackRate = stop.CallbackRate
+	info.TrailingActivationPrice = stop.ActivationPrice
+	info.TrailingStopPrice = trailingStopPrice(stop.Side, stop.Peak, stop.CallbackRate)
+	info.TrailingNative = stop.Native
+}
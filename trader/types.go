@@ -0,0 +1,263 @@
+package trader
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Balance 账户余额（各交易所统一结构）
+type Balance struct {
+	TotalWalletBalance    float64 // 钱包余额（OKX为totalEq）
+	AvailableBalance      float64 // 可用余额
+	TotalUnrealizedProfit float64 // 未实现盈亏
+	TotalEquity           float64 // 交易所直接返回的总权益（OKX为adjEq，Bybit为totalEquity），0表示交易所未提供
+	MarginRatio           float64 // 保证金率（OKX为mgnRatio，Bybit为accountIMRate，小数形式），0表示交易所未提供
+}
+
+// Position 持仓信息（各交易所统一结构）
+type Position struct {
+	Symbol           string  // 交易对（统一为BTCUSDT格式）
+	Side             string  // 持仓方向: "long" 或 "short"
+	Quantity         float64 // 持仓数量（始终为正数，方向由Side表示）
+	EntryPrice       float64 // 开仓均价
+	MarkPrice        float64 // 标记价格
+	UnrealizedProfit float64 // 未实现盈亏
+	Leverage         int     // 杠杆倍数，0表示交易所未返回
+	LiquidationPrice float64 // 强平价格
+	Margin           float64 // 占用保证金，0表示交易所未返回（由调用方按杠杆估算）
+}
+
+// OrderResult 下单结果（各交易所统一结构）
+type OrderResult struct {
+	OrderID     string  // 订单ID（币安为数字ID，OKX为字符串ID，Hyperliquid不返回订单ID时为空）
+	Symbol      string  // 交易对
+	Status      string  // 订单状态
+	AvgPrice    float64 // 成交均价，0表示交易所未返回
+	ExecutedQty float64 // 成交数量，0表示交易所未返回
+	Fee         float64 // 手续费，0表示交易所未返回
+
+	Price    float64 // 委托价格（限价单），0表示市价单或交易所未返回
+	Quantity float64 // 委托数量，0表示交易所未返回
+}
+
+// 开仓订单类型
+const (
+	OrderTypeMarket   = "market"    // 市价单（Taker）
+	OrderTypeLimit    = "limit"     // 限价单（GTC，价格可成交时部分或全部按Taker成交）
+	OrderTypePostOnly = "post_only" // 只做Maker，会立即成交时交易所拒绝或撤单
+	OrderTypeIOC      = "ioc"       // 限价立即成交，剩余部分立即撤销（不挂单）
+)
+
+// 统一订单状态（GetOrder/PlaceEntryOrder 返回）
+const (
+	OrderStatusOpen            = "open"             // 挂单中，未成交
+	OrderStatusPartiallyFilled = "partially_filled" // 部分成交，剩余部分挂单中
+	OrderStatusFilled          = "filled"           // 全部成交
+	OrderStatusCanceled        = "canceled"         // 已撤销/拒绝/过期（可能有部分成交，见 ExecutedQty）
+)
+
+// EntryOrder 开仓订单（支持市价、限价、只做Maker、IOC）
+type EntryOrder struct {
+	Symbol    string
+	Side      string  // 持仓方向: "long" 或 "short"
+	OrderType string  // OrderType* 常量，为空表示市价
+	Quantity  float64 // 开仓数量（基础币）
+	Price     float64 // 限价（市价单忽略）
+	Leverage  int
+}
+
+// OpenOrder 未完成的挂单（普通委托和止盈止损等条件单）
+type OpenOrder struct {
+	OrderID      string    `json:"order_id"`
+	Symbol       string    `json:"symbol"`
+	Side         string    `json:"side"`          // 买卖方向: "buy" 或 "sell"
+	PositionSide string    `json:"position_side"` // 持仓方向: "long" 或 "short"，单向持仓模式下按买卖方向和是否只减仓推断
+	Type         string    `json:"type"`          // OpenOrderType* 常量
+	Price        float64   `json:"price"`         // 委托价，市价条件单为0
+	StopPrice    float64   `json:"stop_price"`    // 触发价，普通委托为0
+	Quantity     float64   `json:"quantity"`      // 委托数量，0表示平掉全部持仓
+	ExecutedQty  float64   `json:"executed_qty"`  // 已成交数量
+	ReduceOnly   bool      `json:"reduce_only"`
+	IsAlgo       bool      `json:"is_algo"` // 是否为条件单（触发前不进入订单簿）
+	Time         time.Time `json:"time"`
+}
+
+// 挂单类型（GetOpenOrders 返回）
+const (
+	OpenOrderTypeLimit        = "limit"         // 限价单
+	OpenOrderTypeMarket       = "market"        // 市价单
+	OpenOrderTypeStopLoss     = "stop_loss"     // 止损单
+	OpenOrderTypeTakeProfit   = "take_profit"   // 止盈单
+	OpenOrderTypeTrailingStop = "trailing_stop" // 移动止损单
+	OpenOrderTypeTrigger      = "trigger"       // 其他条件单
+)
+
+// AccountUpdate 私有WebSocket推送的账户变化（为空的部分表示本次推送不包含）
+type AccountUpdate struct {
+	Balance    *Balance           // 余额（AvailableBalance为0时按钱包余额变化量调整可用余额）
+	Positions  []Position         // 发生变化的持仓（数量为0表示已平仓）
+	MarkPrices map[string]float64 // 标记价格（symbol -> 价格），用于刷新持仓未实现盈亏
+	Orders     []OrderUpdate      // 订单状态变化
+
+	// UnrealizedExcluded 钱包余额不含未实现盈亏（币安、Hyperliquid），持仓盈亏变化同步计入余额的未实现盈亏
+	UnrealizedExcluded bool
+	// Resync 推送连接建立，或推送内容不足以更新持仓（如只有成交明细），需要用REST全量同步
+	Resync bool
+}
+
+// OrderUpdate 订单状态推送
+type OrderUpdate struct {
+	OrderID      string
+	Symbol       string
+	Side         string  // "buy" / "sell"
+	PositionSide string  // "long" / "short"
+	Type         string  // 挂单类型（OpenOrderType*），条件单触发后仍为原类型
+	Status       string  // 统一订单状态（OrderStatus*）
+	AvgPrice     float64 // 成交均价
+	ExecutedQty  float64 // 累计成交数量
+	Fee          float64 // 累计手续费，0表示未知（需按成交明细补全）
+	ReduceOnly   bool
+	Time         time.Time
+}
+
+// isExitTrigger 是否为止盈止损等条件单触发的平仓成交
+func (o OrderUpdate) isExitTrigger() bool {
+	if o.Status != OrderStatusFilled {
+		return false
+	}
+	switch o.Type {
+	case OpenOrderTypeStopLoss, OpenOrderTypeTakeProfit, OpenOrderTypeTrailingStop:
+		return true
+	case OpenOrderTypeTrigger:
+		return o.ReduceOnly
+	}
+	return false
+}
+
+// Fill 成交明细（交易所成交历史，用于交易台账对账）
+type Fill struct {
+	OrderID     string    `json:"order_id"`
+	Symbol      string    `json:"symbol"`
+	Side        string    `json:"side"`         // 持仓方向: "long" 或 "short"
+	IsClose     bool      `json:"is_close"`     // true=平仓成交, false=开仓成交
+	Price       float64   `json:"price"`        // 成交价
+	Quantity    float64   `json:"quantity"`     // 成交数量
+	Fee         float64   `json:"fee"`          // 手续费（USDT）
+	RealizedPnL float64   `json:"realized_pnl"` // 已实现盈亏（不含手续费），0表示交易所未返回
+	Reason      string    `json:"reason"`       // 平仓原因（stop_loss/take_profit/liquidation/market），交易所未返回时为空
+	Time        time.Time `json:"time"`
+}
+
+// AccountInfo 交易员账户概览（用于API和交易员对比）
+type AccountInfo struct {
+	// 核心字段
+	TotalEquity      float64 `json:"total_equity"`      // 账户净值（OKX使用adjEq）
+	WalletBalance    float64 `json:"wallet_balance"`    // 钱包余额（不含未实现盈亏）
+	UnrealizedProfit float64 `json:"unrealized_profit"` // 未实现盈亏（从持仓计算，更准确）
+	AvailableBalance float64 `json:"available_balance"` // 可用余额
+
+	// 盈亏统计
+	TotalPnL           float64 `json:"total_pnl"`            // 总盈亏 = equity - initial
+	TotalPnLPct        float64 `json:"total_pnl_pct"`        // 总盈亏百分比
+	TotalUnrealizedPnL float64 `json:"total_unrealized_pnl"` // 未实现盈亏（从持仓计算）
+	InitialBalance     float64 `json:"initial_balance"`      // 初始余额
+	DailyPnL           float64 `json:"daily_pnl"`            // 日盈亏
+
+	// 持仓信息
+	PositionCount int     `json:"position_count"`  // 持仓数量
+	MarginUsed    float64 `json:"margin_used"`     // 保证金占用
+	MarginUsedPct float64 `json:"margin_used_pct"` // 保证金使用率
+}
+
+// NumericOrderID 返回数字形式的订单ID（用于决策日志），非数字ID返回0
+func (o *OrderResult) NumericOrderID() int64 {
+	if o == nil || o.OrderID == "" {
+		return 0
+	}
+	id, err := strconv.ParseInt(o.OrderID, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// FindPosition 在持仓列表中查找指定币种和方向的持仓
+func FindPosition(positions []Position, symbol, side string) (Position, bool) {
+	for _, pos := range positions {
+		if pos.Symbol == symbol && pos.Side == side {
+			return pos, true
+		}
+	}
+	return Position{}, false
+}
+
+// inferPositionSide 单向持仓模式下按买卖方向和是否只减仓推断持仓方向
+func inferPositionSide(side string, reduceOnly bool) string {
+	isBuy := side == "buy"
+	if isBuy != reduceOnly {
+		return "long"
+	}
+	return "short"
+}
+
+// formatOrderID 将交易所返回的数字订单ID转换为字符串
+func formatOrderID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+// IsOpen 订单是否仍在挂单中（未成交或部分成交）
+func (o *OrderResult) IsOpen() bool {
+	return o.Status == OrderStatusOpen || o.Status == OrderStatusPartiallyFilled
+}
+
+// IsValidOrderType 是否为支持的开仓订单类型
+func IsValidOrderType(orderType string) bool {
+	switch orderType {
+	case OrderTypeMarket, OrderTypeLimit, OrderTypePostOnly, OrderTypeIOC:
+		return true
+	}
+	return false
+}
+
+// OrderTypeLabel 订单类型的中文说明（用于日志）
+func OrderTypeLabel(orderType string) string {
+	switch orderType {
+	case OrderTypeLimit:
+		return "限价单"
+	case OrderTypePostOnly:
+		return "只做Maker限价单"
+	case OrderTypeIOC:
+		return "IOC限价单"
+	default:
+		return "市价单"
+	}
+}
+
+// validate 校验开仓订单参数
+func (o EntryOrder) validate() error {
+	if o.Side != "long" && o.Side != "short" {
+		return fmt.Errorf("无效的持仓方向: %s", o.Side)
+	}
+	if !IsValidOrderType(o.OrderType) {
+		return fmt.Errorf("不支持的订单类型: %s", o.OrderType)
+	}
+	if o.Quantity <= 0 {
+		return fmt.Errorf("开仓数量必须大于0: %.8f", o.Quantity)
+	}
+	if o.OrderType != OrderTypeMarket && o.Price <= 0 {
+		return fmt.Errorf("%s价格必须大于0: %.8f", OrderTypeLabel(o.OrderType), o.Price)
+	}
+	return nil
+}
+
+// openMarket 市价开仓订单转调 OpenLong/OpenShort（不设置止盈止损）
+func (o EntryOrder) openMarket(t Trader) (*OrderResult, error) {
+	if o.Side == "long" {
+		return t.OpenLong(o.Symbol, o.Quantity, o.Leverage, 0, 0)
+	}
+	return t.OpenShort(o.Symbol, o.Quantity, o.Leverage, 0, 0)
+}
@@ -41,6 +41,8 @@ function getModelDisplayName(modelId: string): string {
       return 'ChatGPT'
     case 'gpts':
       return 'GPTs (Assistant API)'
+    case 'anthropic':
+      return 'Anthropic Claude'
     case 'claude':
       return 'Claude'
     default:
@@ -819,7 +821,7 @@ function TraderDetailsPage({
               </h2>
               {decisions && decisions.length > 0 && (
                 <div className="text-xs" style={{ color: '#848E9C' }}>
-                  {language === 'zh' ? `所有历史周期 (${decisions.length}个)` : `All History Periods (${decisions.length})`}
+                  {language === 'zh' ? `最近周期 (${decisions.length}个)` : `Recent Periods (${decisions.length})`}
                 </div>
               )}
             </div>
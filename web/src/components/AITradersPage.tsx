@@ -40,6 +40,8 @@ function getModelDisplayName(modelId: string): string {
       return 'ChatGPT'
     case 'gpts':
       return 'GPTs (Assistant API)'
+    case 'anthropic':
+      return 'Anthropic Claude'
     case 'claude':
       return 'Claude'
     default:
@@ -1946,7 +1948,8 @@ function ExchangeConfigModal({
         asterSigner.trim(),
         asterPrivateKey.trim()
       )
-    } else if (selectedExchange?.id === 'okx') {
+    } else if (selectedExchange?.id === 'okx' || selectedExchange?.id === 'bitget') {
+      // OKX、Bitget 需要 passphrase
       if (!apiKey.trim() || !secretKey.trim() || !passphrase.trim()) return
       await onSave(selectedExchangeId, apiKey.trim(), secretKey.trim(), testnet, undefined, undefined, undefined, undefined, passphrase.trim())
     } else {
@@ -2208,7 +2211,8 @@ function ExchangeConfigModal({
                       />
                     </div>
 
-                    {selectedExchange.id === 'okx' && (
+                    {(selectedExchange.id === 'okx' ||
+                      selectedExchange.id === 'bitget') && (
                       <div>
                         <label
                           className="block text-sm font-semibold mb-2"
@@ -2469,7 +2473,8 @@ function ExchangeConfigModal({
                 !selectedExchange ||
                 (selectedExchange.id === 'binance' &&
                   (!apiKey.trim() || !secretKey.trim())) ||
-                (selectedExchange.id === 'okx' &&
+                ((selectedExchange.id === 'okx' ||
+                  selectedExchange.id === 'bitget') &&
                   (!apiKey.trim() ||
                     !secretKey.trim() ||
                     !passphrase.trim())) ||
@@ -2483,6 +2488,7 @@ function ExchangeConfigModal({
                   selectedExchange.id !== 'aster' &&
                   selectedExchange.id !== 'binance' &&
                   selectedExchange.id !== 'okx' &&
+                  selectedExchange.id !== 'bitget' &&
                   (!apiKey.trim() || !secretKey.trim()))
               }
               className="flex-1 px-4 py-2 rounded text-sm font-semibold disabled:opacity-50"
@@ -3,6 +3,8 @@ import type {
   AccountInfo,
   Position,
   DecisionRecord,
+  DecisionPage,
+  DecisionQuery,
   Statistics,
   TraderInfo,
   AIModel,
@@ -207,11 +209,18 @@ export const api = {
     return res.json()
   },
 
-  // 获取决策日志（支持trader_id）
-  async getDecisions(traderId?: string): Promise<DecisionRecord[]> {
-    const url = traderId
-      ? `${API_BASE}/decisions?trader_id=${traderId}`
-      : `${API_BASE}/decisions`
+  // 分页查询决策日志（支持trader_id和过滤条件，返回当前页记录和总条数）
+  async getDecisions(
+    traderId?: string,
+    query: DecisionQuery = {}
+  ): Promise<DecisionPage> {
+    const params = new URLSearchParams()
+    if (traderId) params.set('trader_id', traderId)
+    Object.entries(query).forEach(([key, value]) => {
+      if (value !== undefined && value !== '') params.set(key, String(value))
+    })
+    const qs = params.toString()
+    const url = qs ? `${API_BASE}/decisions?${qs}` : `${API_BASE}/decisions`
     const res = await fetch(url, {
       headers: getAuthHeaders(),
     })
@@ -219,11 +228,11 @@ export const api = {
     return res.json()
   },
 
-  // 获取最新决策（支持trader_id）
-  async getLatestDecisions(traderId?: string): Promise<DecisionRecord[]> {
+  // 获取最新决策（支持trader_id，服务端最多返回200条，更早的记录通过 getDecisions 分页查询）
+  async getLatestDecisions(traderId?: string, limit = 50): Promise<DecisionRecord[]> {
     const url = traderId
-      ? `${API_BASE}/decisions/latest?trader_id=${traderId}`
-      : `${API_BASE}/decisions/latest`
+      ? `${API_BASE}/decisions/latest?trader_id=${traderId}&limit=${limit}`
+      : `${API_BASE}/decisions/latest?limit=${limit}`
     const res = await fetch(url, {
       headers: getAuthHeaders(),
     })
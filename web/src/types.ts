@@ -76,6 +76,26 @@ export interface DecisionRecord {
   error_message?: string
 }
 
+// 决策日志分页查询结果（GET /api/decisions）
+export interface DecisionPage {
+  records: DecisionRecord[]
+  total: number
+  offset: number
+  limit: number
+}
+
+// 决策日志查询条件（均为可选，start/end 支持 RFC3339、日期或Unix秒）
+export interface DecisionQuery {
+  start?: string
+  end?: string
+  symbol?: string
+  action?: string
+  success?: boolean
+  offset?: number
+  limit?: number
+  order?: 'asc' | 'desc'
+}
+
 export interface Statistics {
   total_cycles: number
   successful_cycles: number